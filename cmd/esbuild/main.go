@@ -42,12 +42,13 @@ var helpText = func(colors logger.Colors) string {
                         is browser and cjs when platform is node)
   --loader:X=L          Use loader L to load file extension X, where L is
                         one of: js | jsx | ts | tsx | css | json | text |
-                        base64 | file | dataurl | binary | copy
+                        base64 | file | dataurl | binary | copy | html |
+                        wasm | wasm-file | napi
   --minify              Minify the output (sets all --minify-* flags)
   --outdir=...          The output directory (for multiple entry points)
   --outfile=...         The output file (for one entry point)
-  --platform=...        Platform target (browser | node | neutral,
-                        default browser)
+  --platform=...        Platform target (browser | node | neutral |
+                        deno, default browser)
   --serve=...           Start a local HTTP server on this host:port for outputs
   --sourcemap           Emit a source map
   --splitting           Enable code splitting (currently only for esm)
@@ -59,64 +60,213 @@ var helpText = func(colors logger.Colors) string {
   --allow-overwrite         Allow output files to overwrite input files
   --analyze                 Print a report about the contents of the bundle
                             (use "--analyze=verbose" for a detailed report)
+  --asset-inline-limit=...  Use the "dataurl" loader instead of the "file"
+                            loader for files smaller than this many bytes
   --asset-names=...         Path template to use for "file" loader files
-                            (default "[name]-[hash]")
+                            (default "[name]-[hash]", can also use "[pkg]")
+  --asset-outdir=...        Write "file" loader files to this directory
+                            instead of to --outdir
+  --asset-root=...          Make "[dir]" in --asset-names relative to this
+                            directory instead of to --outbase
+  --atomic-write            Write the output files to a temporary directory
+                            and then replace the output directory with it
+                            (requires --outdir)
   --banner:T=...            Text to be prepended to each output file of type T
-                            where T is one of: css | js
+                            where T is one of: css | js (can use "[name]" and
+                            "[hash]")
+  --boundary-package:M      Put the code from package M in its own chunk when
+                            code splitting, no matter which entry points use it
+  --charset=bmp             Only escape code points outside the BMP and
+                            invisible formatting characters
   --charset=utf8            Do not escape UTF-8 code points
   --chunk-names=...         Path template to use for code splitting chunks
-                            (default "[name]-[hash]")
+                            (default "[name]-[hash]", can also use "[pkg]")
+  --clean                   Remove files in the output directory that this
+                            build didn't generate (requires --outdir)
+  --clean-retain=N          Keep the N most recent older versions of each
+                            hashed output file when cleaning (default 0)
   --color=...               Force use of color terminal escapes (true | false)
-  --drop:...                Remove certain constructs (console | debugger)
+  --concat-report           Write a JSON file per output file that lists which
+                            input files had to be wrapped in a closure and why
+  --copy=SRC:DEST           Copy the file or directory SRC to DEST inside the
+                            output directory (DEST can be omitted)
+  --declarations            Generate a .d.ts file next to the output for each
+                            TypeScript input file
+  --debug-id                Embed a unique debug ID in each output file and its
+                            source map for crash reporting tools
+  --deterministic           Avoid machine-specific details in the output, such
+                            as absolute paths from plugins
+  --disallow-license:L      Warn if a bundled package uses license L (an SPDX
+                            identifier such as GPL-3.0, wildcards allowed)
+  --drop:...                Remove certain constructs (console | debugger |
+                            dead-imports)
+  --drop-calls=...          Remove calls to these comma-separated names, keeping
+                            any side effects in the arguments
+  --dry-run                 Do everything except write files, then list the
+                            files that would have been written
+  --dynamic-require=...     What to do with require() calls that have a
+                            non-constant argument (keep | error | context,
+                            default keep)
+  --entry-conditions:E=C    Resolve entry point E and everything it imports
+                            with the extra comma-separated conditions C
+  --entry-inject:E=F        Inject the file F into entry point E and
+                            everything it imports
   --entry-names=...         Path template to use for entry point output paths
-                            (default "[dir]/[name]", can also use "[hash]")
+                            (default "[dir]/[name]", can also use "[hash]"
+                            and "[pkg]" for the name of the owning package)
+  --error-recovery          Skip statements with syntax errors and report all
+                            of them instead of stopping at the first one
+                            (transform only)
+  --external-global:M=G     Bundle module M as a module that exports the global
+                            variable G (e.g. "react=React")
   --footer:T=...            Text to be appended to each output file of type T
-                            where T is one of: css | js
+                            where T is one of: css | js (can use "[name]" and
+                            "[hash]")
   --global-name=...         The name of the global for the IIFE format
+  --graph-only              Resolve and scan all files but don't generate any
+                            code, then print the import graph as JSON
+  --hash-salt=...           Mix this text into the "[hash]" of every output
+                            file, which otherwise only depends on the contents
   --ignore-annotations      Enable this to work with packages that have
                             incorrect tree-shaking annotations
+  --import-map=...          Apply this import map to imports before searching
+                            node_modules
+  --import-map-external     Leave bare imports matched by --import-map external
+                            and write the import map to the output directory
   --inject:F                Import the file F into all input files and
                             automatically replace matching globals with imports
+  --inject-if-used          Only include injected files when one of their
+                            exports is used
+  --integrity               Add subresource integrity hashes to the metafile,
+                            the manifest, and the tags in HTML entry points
+  --isolated-modules-check  Warn about TypeScript code that can't be compiled
+                            one file at a time
+  --jsx-dev                 Use React's automatic runtime in development mode
   --jsx-factory=...         What to use for JSX instead of React.createElement
+  --jsx-factory:P=...       Use a different JSX factory for files in path P
   --jsx-fragment=...        What to use for JSX instead of React.Fragment
-  --jsx=...                 Set to "preserve" to disable transforming JSX to JS
+  --jsx-fragment:P=...      Use a different JSX fragment for files in path P
+  --jsx-import-source=...   Override the package name for the automatic runtime
+                            (default "react")
+  --jsx-import-source:P=... Use a different automatic runtime package for
+                            files in path P
+  --jsx=...                 Set to "automatic" to use React's automatic runtime
+                            or to "preserve" to disable transforming JSX to JS
   --keep-names              Preserve "name" on functions and classes
+  --keep-names-filter=...   Only preserve names matching a regular expression
+  --keep-names-only=...     Only preserve some names (classes | functions |
+                            exports, comma-separated)
   --legal-comments=...      Where to place legal comments (none | inline |
-                            eof | linked | external, default eof when bundling
-                            and inline otherwise)
+                            eof | linked | external | combined, default eof
+                            when bundling and inline otherwise)
+  --log-format=...          Format of log messages on stderr (text | json,
+                            default text)
   --log-level=...           Disable logging (verbose | debug | info | warning |
                             error | silent, default info)
   --log-limit=...           Maximum message count or 0 to disable (default 6)
   --log-override:X=Y        Use log level Y for log messages with identifier X
+  --low-memory              Use less memory at the cost of speed by generating
+                            output files one at a time and keeping printed
+                            code and source map contents in a temporary file
   --main-fields=...         Override the main file order in package.json
                             (default "browser,module,main" when platform is
                             browser and "main,module" when platform is node)
   --mangle-cache=...        Save "mangle props" decisions to a JSON file
   --mangle-props=...        Rename all properties matching a regular expression
   --mangle-quoted=...       Enable renaming of quoted properties (true | false)
+  --manifest=...            Write a JSON file that maps entry points and assets
+                            to their output paths (relative to --outdir)
+  --max-open-files=...      Maximum number of files to have open at once
+                            (default 32, shared by all builds in the process)
+  --max-workers=...         Maximum number of files to parse or print at once
+                            (default is the number of CPU cores)
+  --memory-limit=...        Flush caches when memory usage exceeds this many
+                            megabytes (approximate, default 0 for no limit)
   --metafile=...            Write metadata about the build to a JSON file
   --minify-whitespace       Remove whitespace in output files
   --minify-identifiers      Shorten identifiers in output files
   --minify-syntax           Use equivalent but shorter syntax in output files
+  --name-map                Write a JSON file per output file that maps
+                            original names to minified and mangled names
+  --name-seed=...           Derive minified names from this seed instead of
+                            from the input, which keeps them stable across
+                            builds (at a small cost to gzip compression)
   --out-extension:.js=.mjs  Use a custom output extension instead of ".js"
   --outbase=...             The base path used to determine entry point output
                             paths (for multiple entry points)
+  --platform-inject:P=F     Like --inject:F but only when building for
+                            platform P
+  --polyfill-report=...     Write a JSON file listing the runtime APIs that
+                            aren't available in the target environment
+  --preserve-comments       Keep all comments before statements instead of
+                            only legal comments
+  --preserve-formatting     Keep the original quote style of strings and the
+                            blank lines between statements
   --preserve-symlinks       Disable symlink resolution for module lookup
   --public-path=...         Set the base URL for the "file" loader
+  --public-path:.E=...      Set the base URL for "file" loader files with the
+                            extension .E (e.g. --public-path:.woff2=...)
+  --publish-package-json    Write a copy of package.json to the output
+                            directory with paths rewritten to output files
   --pure:N                  Mark the name N as a pure function for tree shaking
+  --purge-css               Remove CSS rules with class and id selectors that
+                            don't appear in any bundled JS or HTML file
+  --purge-css-safelist:R    Never remove selectors with class or id names
+                            matching the regular expression R
+  --refresh-metadata        Add the component exports of each module to the
+                            metafile for fast refresh (requires --metafile)
+  --remote-cache-dir=...    Where to store downloaded remote modules (default
+                            is a directory in the user's cache directory)
+  --remote-imports          Download and bundle "http://" and "https://"
+                            imports instead of leaving them external
+  --remote-lock-file=...    Verify remote modules against the integrity hashes
+                            in this file and add new modules to it
+  --remote-offline          Only use remote modules that are already cached
   --reserve-props=...       Do not mangle these properties
   --resolve-extensions=...  A comma-separated list of implicit extensions
                             (default ".tsx,.ts,.jsx,.js,.css,.json")
+  --runtime-prefix=...      Prefix the names of generated helper functions to
+                            avoid collisions with other bundles on the page
+  --scan-secrets            Fail the build if an output file contains something
+                            that looks like a secret (e.g. an AWS key)
   --servedir=...            What to serve in addition to generated output files
+  --shared:M                Use a compatible version of module M from the shared
+                            scope at run-time, falling back to the bundled copy
+  --shared-scope=...        The expression for the shared scope object (default
+                            "globalThis.__esbuild_shared__")
+  --side-effects-override:P=...
+                            Replace "sideEffects" in package.json for package
+                            P (e.g. "--side-effects-override:pkg=false")
   --source-root=...         Sets the "sourceRoot" field in generated source maps
   --sourcefile=...          Set the source file for the source map (for stdin)
   --sourcemap=external      Do not link to the source map with a comment
   --sourcemap=inline        Emit the source map with an inline data URL
   --sources-content=false   Omit "sourcesContent" in generated source maps
+  --sources-ignore-list=... Add sources matching this regular expression to
+                            "x_google_ignoreList" (default "node_modules")
+  --sources-rewrite=...     Template for paths in "sources" in generated source
+                            maps (e.g. "webpack://app/[input]", can also use
+                            "[path]" and "[namespace]")
+  --status-file=...         Write a JSON summary of the outcome of each build
+                            to this file (e.g. for build orchestrators)
   --supported:F=...         Consider syntax F to be supported (true | false)
+  --timing                  Print the time spent in each phase of the build
+                            and in each plugin (also added to the metafile)
   --tree-shaking=...        Force tree shaking on or off (false | true)
+  --tree-shaking-report=... Write a JSON file explaining why each top-level
+                            symbol was kept and listing the removed exports
   --tsconfig=...            Use this tsconfig.json file instead of other ones
+  --tsconfig-nested         Still use tsconfig.json files in subdirectories of
+                            the --tsconfig file's directory
+  --verify-determinism      Build twice and fail if the output files differ
+  --verify-lockfile         Fail if a bundled package's version isn't in
+                            package-lock.json, pnpm-lock.yaml, or yarn.lock
   --version                 Print the current version (` + esbuildVersion + `) and exit
+  --watch-ignore=...        Don't watch paths matching this regular expression
+  --watch-interval=...      Milliseconds between file system polls in watch
+                            mode (default 100)
+  --watch=stdin             Rebuild for each length-prefixed input on stdin
+                            and write each output to stdout the same way
 
 ` + colors.Bold + `Examples:` + colors.Reset + `
   ` + colors.Dim + `# Produces dist/entry_point.js and dist/entry_point.js.map` + colors.Reset + `
@@ -170,9 +320,10 @@ func main() {
 		case strings.HasPrefix(arg, "--trace="):
 			traceFile = arg[len("--trace="):]
 
-		case strings.HasPrefix(arg, "--timing"):
+		case arg == "--timing=internal":
 			// This is a hidden flag because it's only intended for debugging esbuild
-			// itself. The output is not documented and not stable.
+			// itself. The output is not documented and not stable. Use "--timing"
+			// for the documented per-phase timing information instead.
 			api_helpers.UseTimer = true
 
 		case strings.HasPrefix(arg, "--cpuprofile="):
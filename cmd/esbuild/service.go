@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"os"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -417,9 +418,19 @@ func (service *serviceType) handleBuildRequest(id uint32, request map[string]int
 		entry := entry.([]interface{})
 		key := entry[0].(string)
 		value := entry[1].(string)
+		var conditions []string
+		if text := entry[2].(string); text != "" {
+			conditions = strings.Split(text, ",")
+		}
+		var inject []string
+		if paths := entry[3].([]interface{}); len(paths) > 0 {
+			inject = decodeStringArray(paths)
+		}
 		options.EntryPointsAdvanced = append(options.EntryPointsAdvanced, api.EntryPoint{
 			OutputPath: key,
 			InputPath:  value,
+			Conditions: conditions,
+			Inject:     inject,
 		})
 	}
 
@@ -445,12 +456,48 @@ func (service *serviceType) handleBuildRequest(id uint32, request map[string]int
 		}
 	}
 
+	// Virtual entry points and modules are encoded as [name, contents, resolveDir, loader]
+	if virtualEntries, ok := request["virtualEntries"].([]interface{}); ok {
+		for _, entry := range virtualEntries {
+			entry := entry.([]interface{})
+			virtual := api.VirtualEntryPoint{
+				Name:       entry[0].(string),
+				Contents:   entry[1].(string),
+				ResolveDir: entry[2].(string),
+			}
+			if loader := entry[3].(string); loader != "" {
+				var err *cli_helpers.ErrorWithNote
+				if virtual.Loader, err = cli_helpers.ParseLoader(loader); err != nil {
+					return outgoingPacket{bytes: encodeErrorPacket(id, errors.New(err.Text))}
+				}
+			}
+			options.VirtualEntryPoints = append(options.VirtualEntryPoints, virtual)
+		}
+	}
+	if virtualModules, ok := request["virtualModules"].([]interface{}); ok {
+		for _, entry := range virtualModules {
+			entry := entry.([]interface{})
+			virtual := api.VirtualModule{
+				Name:       entry[0].(string),
+				Contents:   entry[1].(string),
+				ResolveDir: entry[2].(string),
+			}
+			if loader := entry[3].(string); loader != "" {
+				var err *cli_helpers.ErrorWithNote
+				if virtual.Loader, err = cli_helpers.ParseLoader(loader); err != nil {
+					return outgoingPacket{bytes: encodeErrorPacket(id, errors.New(err.Text))}
+				}
+			}
+			options.VirtualModules = append(options.VirtualModules, virtual)
+		}
+	}
+
 	activeBuild := &activeBuild{refCount: 1}
 	service.trackActiveBuild(key, activeBuild)
 	defer service.decRefCount(key, activeBuild)
 
 	if plugins, ok := request["plugins"]; ok {
-		if plugins, err := service.convertPlugins(key, plugins, activeBuild); err != nil {
+		if plugins, err := service.convertPlugins(key, plugins, activeBuild, options.Timing); err != nil {
 			return outgoingPacket{bytes: encodeErrorPacket(id, err)}
 		} else {
 			options.Plugins = plugins
@@ -478,6 +525,12 @@ func (service *serviceType) handleBuildRequest(id uint32, request map[string]int
 		if options.MangleCache != nil {
 			response["mangleCache"] = result.MangleCache
 		}
+		if options.CollectLegalComments {
+			response["legalComments"] = encodeLegalComments(result.LegalComments)
+		}
+		if options.GraphOnly {
+			response["graph"] = result.Graph
+		}
 		if writeToStdout && len(result.OutputFiles) == 1 {
 			response["writeToStdout"] = result.OutputFiles[0].Contents
 		}
@@ -608,6 +661,8 @@ func resolveKindToString(kind api.ResolveKind) string {
 		return "dynamic-import"
 	case api.ResolveJSRequireResolve:
 		return "require-resolve"
+	case api.ResolveJSNewURL:
+		return "new-url"
 
 	// CSS
 	case api.ResolveCSSImportRule:
@@ -634,6 +689,8 @@ func stringToResolveKind(kind string) (api.ResolveKind, bool) {
 		return api.ResolveJSDynamicImport, true
 	case "require-resolve":
 		return api.ResolveJSRequireResolve, true
+	case "new-url":
+		return api.ResolveJSNewURL, true
 
 	// CSS
 	case "import-rule":
@@ -645,7 +702,7 @@ func stringToResolveKind(kind string) (api.ResolveKind, bool) {
 	return api.ResolveEntryPoint, false
 }
 
-func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activeBuild *activeBuild) ([]api.Plugin, error) {
+func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activeBuild *activeBuild, timing bool) ([]api.Plugin, error) {
 	type filteredCallback struct {
 		filter     *regexp.Regexp
 		pluginName string
@@ -653,8 +710,12 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 		id         int
 	}
 
-	var onResolveCallbacks []filteredCallback
-	var onLoadCallbacks []filteredCallback
+	type jsPlugin struct {
+		name               string
+		hasOnStart         bool
+		onResolveCallbacks []filteredCallback
+		onLoadCallbacks    []filteredCallback
+	}
 
 	filteredCallbacks := func(pluginName string, kind string, items []interface{}) (result []filteredCallback, err error) {
 		for _, item := range items {
@@ -673,249 +734,322 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 		return
 	}
 
+	var plugins []jsPlugin
 	for _, p := range jsPlugins.([]interface{}) {
 		p := p.(map[string]interface{})
-		pluginName := p["name"].(string)
+		plugin := jsPlugin{
+			name:       p["name"].(string),
+			hasOnStart: p["onStart"].(bool),
+		}
 
-		if callbacks, err := filteredCallbacks(pluginName, "onResolve", p["onResolve"].([]interface{})); err != nil {
+		if callbacks, err := filteredCallbacks(plugin.name, "onResolve", p["onResolve"].([]interface{})); err != nil {
 			return nil, err
 		} else {
-			onResolveCallbacks = append(onResolveCallbacks, callbacks...)
+			plugin.onResolveCallbacks = callbacks
 		}
 
-		if callbacks, err := filteredCallbacks(pluginName, "onLoad", p["onLoad"].([]interface{})); err != nil {
+		if callbacks, err := filteredCallbacks(plugin.name, "onLoad", p["onLoad"].([]interface{})); err != nil {
 			return nil, err
 		} else {
-			onLoadCallbacks = append(onLoadCallbacks, callbacks...)
+			plugin.onLoadCallbacks = callbacks
 		}
-	}
 
-	// We want to minimize the amount of IPC traffic. Instead of adding one Go
-	// plugin for every JavaScript plugin, we just add a single Go plugin that
-	// proxies the plugin queries to the list of JavaScript plugins in the host.
-	return []api.Plugin{{
-		Name: "JavaScript plugins",
-		Setup: func(build api.PluginBuild) {
-			activeBuild.mutex.Lock()
-			activeBuild.pluginResolve = func(id uint32, request map[string]interface{}) []byte {
-				path := request["path"].(string)
-				var options api.ResolveOptions
-				if value, ok := request["pluginName"]; ok {
-					options.PluginName = value.(string)
-				}
-				if value, ok := request["importer"]; ok {
-					options.Importer = value.(string)
-				}
-				if value, ok := request["namespace"]; ok {
-					options.Namespace = value.(string)
-				}
-				if value, ok := request["resolveDir"]; ok {
-					options.ResolveDir = value.(string)
-				}
-				if value, ok := request["kind"]; ok {
-					str := value.(string)
-					kind, ok := stringToResolveKind(str)
-					if !ok {
-						return encodePacket(packet{
-							id: id,
-							value: map[string]interface{}{
-								"error": fmt.Sprintf("Invalid kind: %q", str),
-							},
-						})
-					}
-					options.Kind = kind
-				}
-				if value, ok := request["pluginData"]; ok {
-					options.PluginData = value.(int)
-				}
+		plugins = append(plugins, plugin)
+	}
 
-				result := build.Resolve(path, options)
-				return encodePacket(packet{
-					id: id,
-					value: map[string]interface{}{
-						"errors":      encodeMessages(result.Errors),
-						"warnings":    encodeMessages(result.Warnings),
-						"path":        result.Path,
-						"external":    result.External,
-						"sideEffects": result.SideEffects,
-						"namespace":   result.Namespace,
-						"suffix":      result.Suffix,
-						"pluginData":  result.PluginData,
-					},
-				})
+	// When each JavaScript plugin has its own Go plugin, its Go callbacks only
+	// run for paths that one of its JavaScript callbacks applies to. Otherwise
+	// the number of calls in the timing information would include every path.
+	proxyFilter := func(callbacks []filteredCallback, pluginIndex int) (filter string, namespace string, ok bool) {
+		if pluginIndex == -1 {
+			return ".*", "", true
+		}
+		if len(callbacks) == 0 {
+			return "", "", false
+		}
+		filters := make([]string, len(callbacks))
+		namespace = callbacks[0].namespace
+		for i, item := range callbacks {
+			filters[i] = "(?:" + item.filter.String() + ")"
+			if item.namespace != namespace {
+				namespace = ""
 			}
-			activeBuild.mutex.Unlock()
-
-			build.OnStart(func() (api.OnStartResult, error) {
-				result := api.OnStartResult{}
-
-				response := service.sendRequest(map[string]interface{}{
-					"command": "on-start",
-					"key":     key,
-				}).(map[string]interface{})
+		}
+		return strings.Join(filters, "|"), namespace, true
+	}
 
-				if value, ok := response["errors"]; ok {
-					result.Errors = decodeMessages(value.([]interface{}))
-				}
-				if value, ok := response["warnings"]; ok {
-					result.Warnings = decodeMessages(value.([]interface{}))
+	// The "on-start" request runs the "onStart" callbacks of every JavaScript
+	// plugin unless a plugin index is given. Only the first Go plugin handles
+	// "resolve" requests from the host since they don't depend on the plugin.
+	proxy := func(plugin jsPlugin, pluginIndex int, handleResolve bool) api.Plugin {
+		onResolveCallbacks := plugin.onResolveCallbacks
+		onLoadCallbacks := plugin.onLoadCallbacks
+
+		return api.Plugin{
+			Name: plugin.name,
+			Setup: func(build api.PluginBuild) {
+				if handleResolve {
+					service.handlePluginResolve(activeBuild, build)
 				}
 
-				return result, nil
-			})
+				if plugin.hasOnStart {
+					build.OnStart(func() (api.OnStartResult, error) {
+						result := api.OnStartResult{}
 
-			build.OnResolve(api.OnResolveOptions{Filter: ".*"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
-				var ids []interface{}
-				applyPath := logger.Path{Text: args.Path, Namespace: args.Namespace}
-				for _, item := range onResolveCallbacks {
-					if config.PluginAppliesToPath(applyPath, item.filter, item.namespace) {
-						ids = append(ids, item.id)
-					}
-				}
+						request := map[string]interface{}{
+							"command": "on-start",
+							"key":     key,
+						}
+						if pluginIndex != -1 {
+							request["plugin"] = pluginIndex
+						}
+						response := service.sendRequest(request).(map[string]interface{})
 
-				result := api.OnResolveResult{}
-				if len(ids) == 0 {
-					return result, nil
+						if value, ok := response["errors"]; ok {
+							result.Errors = decodeMessages(value.([]interface{}))
+						}
+						if value, ok := response["warnings"]; ok {
+							result.Warnings = decodeMessages(value.([]interface{}))
+						}
+
+						return result, nil
+					})
 				}
 
-				response := service.sendRequest(map[string]interface{}{
-					"command":    "on-resolve",
-					"key":        key,
-					"ids":        ids,
-					"path":       args.Path,
-					"importer":   args.Importer,
-					"namespace":  args.Namespace,
-					"resolveDir": args.ResolveDir,
-					"kind":       resolveKindToString(args.Kind),
-					"pluginData": args.PluginData,
-				}).(map[string]interface{})
-
-				if value, ok := response["id"]; ok {
-					id := value.(int)
+				onResolve := func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+					var ids []interface{}
+					applyPath := logger.Path{Text: args.Path, Namespace: args.Namespace}
 					for _, item := range onResolveCallbacks {
-						if item.id == id {
-							result.PluginName = item.pluginName
-							break
+						if config.PluginAppliesToPath(applyPath, item.filter, item.namespace) {
+							ids = append(ids, item.id)
 						}
 					}
-				}
-				if value, ok := response["error"]; ok {
-					return result, errors.New(value.(string))
-				}
-				if value, ok := response["pluginName"]; ok {
-					result.PluginName = value.(string)
-				}
-				if value, ok := response["path"]; ok {
-					result.Path = value.(string)
-				}
-				if value, ok := response["namespace"]; ok {
-					result.Namespace = value.(string)
-				}
-				if value, ok := response["suffix"]; ok {
-					result.Suffix = value.(string)
-				}
-				if value, ok := response["external"]; ok {
-					result.External = value.(bool)
-				}
-				if value, ok := response["sideEffects"]; ok {
-					if value.(bool) {
-						result.SideEffects = api.SideEffectsTrue
-					} else {
-						result.SideEffects = api.SideEffectsFalse
-					}
-				}
-				if value, ok := response["pluginData"]; ok {
-					result.PluginData = value.(int)
-				}
-				if value, ok := response["errors"]; ok {
-					result.Errors = decodeMessages(value.([]interface{}))
-				}
-				if value, ok := response["warnings"]; ok {
-					result.Warnings = decodeMessages(value.([]interface{}))
-				}
-				if value, ok := response["watchFiles"]; ok {
-					result.WatchFiles = decodeStringArray(value.([]interface{}))
-				}
-				if value, ok := response["watchDirs"]; ok {
-					result.WatchDirs = decodeStringArray(value.([]interface{}))
-				}
 
-				return result, nil
-			})
+					result := api.OnResolveResult{}
+					if len(ids) == 0 {
+						return result, nil
+					}
 
-			build.OnLoad(api.OnLoadOptions{Filter: ".*"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
-				var ids []interface{}
-				applyPath := logger.Path{Text: args.Path, Namespace: args.Namespace}
-				for _, item := range onLoadCallbacks {
-					if config.PluginAppliesToPath(applyPath, item.filter, item.namespace) {
-						ids = append(ids, item.id)
+					response := service.sendRequest(map[string]interface{}{
+						"command":    "on-resolve",
+						"key":        key,
+						"ids":        ids,
+						"path":       args.Path,
+						"importer":   args.Importer,
+						"namespace":  args.Namespace,
+						"resolveDir": args.ResolveDir,
+						"kind":       resolveKindToString(args.Kind),
+						"pluginData": args.PluginData,
+					}).(map[string]interface{})
+
+					if value, ok := response["id"]; ok {
+						id := value.(int)
+						for _, item := range onResolveCallbacks {
+							if item.id == id {
+								result.PluginName = item.pluginName
+								break
+							}
+						}
+					}
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["path"]; ok {
+						result.Path = value.(string)
+					}
+					if value, ok := response["namespace"]; ok {
+						result.Namespace = value.(string)
+					}
+					if value, ok := response["suffix"]; ok {
+						result.Suffix = value.(string)
+					}
+					if value, ok := response["external"]; ok {
+						result.External = value.(bool)
+					}
+					if value, ok := response["sideEffects"]; ok {
+						if value.(bool) {
+							result.SideEffects = api.SideEffectsTrue
+						} else {
+							result.SideEffects = api.SideEffectsFalse
+						}
+					}
+					if value, ok := response["pluginData"]; ok {
+						result.PluginData = value.(int)
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["watchFiles"]; ok {
+						result.WatchFiles = decodeStringArray(value.([]interface{}))
+					}
+					if value, ok := response["watchDirs"]; ok {
+						result.WatchDirs = decodeStringArray(value.([]interface{}))
 					}
-				}
 
-				result := api.OnLoadResult{}
-				if len(ids) == 0 {
 					return result, nil
 				}
+				if filter, namespace, ok := proxyFilter(onResolveCallbacks, pluginIndex); ok {
+					build.OnResolve(api.OnResolveOptions{Filter: filter, Namespace: namespace}, onResolve)
+				}
 
-				response := service.sendRequest(map[string]interface{}{
-					"command":    "on-load",
-					"key":        key,
-					"ids":        ids,
-					"path":       args.Path,
-					"namespace":  args.Namespace,
-					"suffix":     args.Suffix,
-					"pluginData": args.PluginData,
-				}).(map[string]interface{})
-
-				if value, ok := response["id"]; ok {
-					id := value.(int)
+				onLoad := func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					var ids []interface{}
+					applyPath := logger.Path{Text: args.Path, Namespace: args.Namespace}
 					for _, item := range onLoadCallbacks {
-						if item.id == id {
-							result.PluginName = item.pluginName
-							break
+						if config.PluginAppliesToPath(applyPath, item.filter, item.namespace) {
+							ids = append(ids, item.id)
 						}
 					}
-				}
-				if value, ok := response["error"]; ok {
-					return result, errors.New(value.(string))
-				}
-				if value, ok := response["pluginName"]; ok {
-					result.PluginName = value.(string)
-				}
-				if value, ok := response["loader"]; ok {
-					loader, err := cli_helpers.ParseLoader(value.(string))
-					if err != nil {
-						return result, errors.New(err.Text)
+
+					result := api.OnLoadResult{}
+					if len(ids) == 0 {
+						return result, nil
 					}
-					result.Loader = loader
-				}
-				if value, ok := response["contents"]; ok {
-					contents := string(value.([]byte))
-					result.Contents = &contents
-				}
-				if value, ok := response["resolveDir"]; ok {
-					result.ResolveDir = value.(string)
-				}
-				if value, ok := response["pluginData"]; ok {
-					result.PluginData = value.(int)
-				}
-				if value, ok := response["errors"]; ok {
-					result.Errors = decodeMessages(value.([]interface{}))
-				}
-				if value, ok := response["warnings"]; ok {
-					result.Warnings = decodeMessages(value.([]interface{}))
-				}
-				if value, ok := response["watchFiles"]; ok {
-					result.WatchFiles = decodeStringArray(value.([]interface{}))
+
+					response := service.sendRequest(map[string]interface{}{
+						"command":    "on-load",
+						"key":        key,
+						"ids":        ids,
+						"path":       args.Path,
+						"namespace":  args.Namespace,
+						"suffix":     args.Suffix,
+						"pluginData": args.PluginData,
+					}).(map[string]interface{})
+
+					if value, ok := response["id"]; ok {
+						id := value.(int)
+						for _, item := range onLoadCallbacks {
+							if item.id == id {
+								result.PluginName = item.pluginName
+								break
+							}
+						}
+					}
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["loader"]; ok {
+						loader, err := cli_helpers.ParseLoader(value.(string))
+						if err != nil {
+							return result, errors.New(err.Text)
+						}
+						result.Loader = loader
+					}
+					if value, ok := response["contents"]; ok {
+						contents := string(value.([]byte))
+						result.Contents = &contents
+					}
+					if value, ok := response["resolveDir"]; ok {
+						result.ResolveDir = value.(string)
+					}
+					if value, ok := response["pluginData"]; ok {
+						result.PluginData = value.(int)
+					}
+					if value, ok := response["sourceMap"]; ok {
+						sourceMap := value.(string)
+						result.SourceMap = &sourceMap
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["watchFiles"]; ok {
+						result.WatchFiles = decodeStringArray(value.([]interface{}))
+					}
+					if value, ok := response["watchDirs"]; ok {
+						result.WatchDirs = decodeStringArray(value.([]interface{}))
+					}
+
+					return result, nil
 				}
-				if value, ok := response["watchDirs"]; ok {
-					result.WatchDirs = decodeStringArray(value.([]interface{}))
+				if filter, namespace, ok := proxyFilter(onLoadCallbacks, pluginIndex); ok {
+					build.OnLoad(api.OnLoadOptions{Filter: filter, Namespace: namespace}, onLoad)
 				}
+			},
+		}
+	}
 
-				return result, nil
-			})
-		},
-	}}, nil
+	// We want to minimize the amount of IPC traffic. Instead of adding one Go
+	// plugin for every JavaScript plugin, we just add a single Go plugin that
+	// proxies the plugin queries to the list of JavaScript plugins in the host.
+	// The exception is when timing is enabled, since time is measured per Go
+	// plugin and each JavaScript plugin should be reported separately.
+	if timing {
+		result := make([]api.Plugin, len(plugins))
+		for i, plugin := range plugins {
+			result[i] = proxy(plugin, i, i == 0)
+		}
+		return result, nil
+	}
+	all := jsPlugin{name: "JavaScript plugins", hasOnStart: true}
+	for _, plugin := range plugins {
+		all.onResolveCallbacks = append(all.onResolveCallbacks, plugin.onResolveCallbacks...)
+		all.onLoadCallbacks = append(all.onLoadCallbacks, plugin.onLoadCallbacks...)
+	}
+	return []api.Plugin{proxy(all, -1, true)}, nil
+}
+
+func (service *serviceType) handlePluginResolve(activeBuild *activeBuild, build api.PluginBuild) {
+	activeBuild.mutex.Lock()
+	activeBuild.pluginResolve = func(id uint32, request map[string]interface{}) []byte {
+		path := request["path"].(string)
+		var options api.ResolveOptions
+		if value, ok := request["pluginName"]; ok {
+			options.PluginName = value.(string)
+		}
+		if value, ok := request["importer"]; ok {
+			options.Importer = value.(string)
+		}
+		if value, ok := request["namespace"]; ok {
+			options.Namespace = value.(string)
+		}
+		if value, ok := request["resolveDir"]; ok {
+			options.ResolveDir = value.(string)
+		}
+		if value, ok := request["kind"]; ok {
+			str := value.(string)
+			kind, ok := stringToResolveKind(str)
+			if !ok {
+				return encodePacket(packet{
+					id: id,
+					value: map[string]interface{}{
+						"error": fmt.Sprintf("Invalid kind: %q", str),
+					},
+				})
+			}
+			options.Kind = kind
+		}
+		if value, ok := request["pluginData"]; ok {
+			options.PluginData = value.(int)
+		}
+
+		result := build.Resolve(path, options)
+		return encodePacket(packet{
+			id: id,
+			value: map[string]interface{}{
+				"errors":      encodeMessages(result.Errors),
+				"warnings":    encodeMessages(result.Warnings),
+				"path":        result.Path,
+				"external":    result.External,
+				"sideEffects": result.SideEffects,
+				"namespace":   result.Namespace,
+				"suffix":      result.Suffix,
+				"pluginData":  result.PluginData,
+			},
+		})
+	}
+	activeBuild.mutex.Unlock()
 }
 
 func (service *serviceType) handleTransformRequest(id uint32, request map[string]interface{}) []byte {
@@ -1062,6 +1196,17 @@ func encodeOutputFiles(outputFiles []api.OutputFile) []interface{} {
 	return values
 }
 
+func encodeLegalComments(legalComments []api.LegalComment) []interface{} {
+	values := make([]interface{}, len(legalComments))
+	for i, comment := range legalComments {
+		values[i] = map[string]interface{}{
+			"text":   comment.Text,
+			"inputs": encodeStringArray(comment.Inputs),
+		}
+	}
+	return values
+}
+
 func encodeLocation(loc *api.Location) interface{} {
 	if loc == nil {
 		return nil
@@ -24,6 +24,9 @@ const (
 	// A call to "require.resolve()"
 	ImportRequireResolve
 
+	// A "new URL()" expression with a relative path and "import.meta.url"
+	ImportNewURL
+
 	// A CSS "@import" rule
 	ImportAt
 
@@ -44,6 +47,8 @@ func (kind ImportKind) StringForMetafile() string {
 		return "dynamic-import"
 	case ImportRequireResolve:
 		return "require-resolve"
+	case ImportNewURL:
+		return "new-url"
 	case ImportAt, ImportAtConditional:
 		return "import-rule"
 	case ImportURL:
@@ -110,6 +115,22 @@ const (
 
 	// If true, this import can be removed if it's unused
 	IsExternalWithoutSideEffects
+
+	// If true, this "new URL()" expression is passed to "new Worker()". The
+	// file is bundled as a separate entry point instead of being used as an
+	// asset.
+	IsWorkerURL
+
+	// If either of these is true, this worker is inlined into the output file
+	// that refers to it instead of being referenced by path. The worker's code
+	// is printed either as a JavaScript string or as a "data:" URL.
+	IsInlineWorkerText
+	IsInlineWorkerDataURL
+
+	// If true, this "require.resolve()" call refers to an asset in the bundle.
+	// It's printed as the path of the output file for that asset instead of as
+	// a call, since the original file won't exist next to the bundle.
+	IsResolvedAtBuildTime
 )
 
 func (flags ImportRecordFlags) Has(flag ImportRecordFlags) bool {
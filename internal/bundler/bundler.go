@@ -2,12 +2,16 @@ package bundler
 
 import (
 	"bytes"
+	"crypto/sha512"
 	"encoding/base32"
 	"encoding/base64"
 	"fmt"
+	"html"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -23,6 +27,7 @@ import (
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/html_parser"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
 	"github.com/evanw/esbuild/internal/js_parser"
@@ -31,6 +36,7 @@ import (
 	"github.com/evanw/esbuild/internal/resolver"
 	"github.com/evanw/esbuild/internal/runtime"
 	"github.com/evanw/esbuild/internal/sourcemap"
+	"github.com/evanw/esbuild/internal/wasm_parser"
 	"github.com/evanw/esbuild/internal/xxhash"
 )
 
@@ -40,6 +46,10 @@ type scannerFile struct {
 	// fully assembled later.
 	jsonMetadataChunk string
 
+	// If "TSDeclarations" is enabled, this is the generated ".d.ts" file for
+	// this TypeScript file. Its path is filled in after scanning is complete.
+	tsDeclaration *graph.OutputFile
+
 	pluginData interface{}
 	inputFile  graph.InputFile
 }
@@ -57,6 +67,26 @@ type dataForSourceMap struct {
 	// needs to be embedded in the "sourcesContent" array in the final source
 	// map. Quoting is precomputed because it's somewhat expensive.
 	quotedContents [][]byte
+
+	// In low-memory mode, the quoted contents are written to a spill file
+	// instead and these are their locations in that file
+	spilledContents []spillRange
+	spill           *spillFile
+}
+
+// If writing to the spill file fails, the contents are just kept in memory
+func (data *dataForSourceMap) spillQuotedContents(spill *spillFile) {
+	spilled := make([]spillRange, len(data.quotedContents))
+	for i, quotedContents := range data.quotedContents {
+		r, err := spill.write(quotedContents)
+		if err != nil {
+			return
+		}
+		spilled[i] = r
+	}
+	data.spilledContents = spilled
+	data.spill = spill
+	data.quotedContents = nil
 }
 
 type Bundle struct {
@@ -70,6 +100,13 @@ type Bundle struct {
 	res         resolver.Resolver
 	files       []scannerFile
 	entryPoints []graph.EntryPoint
+
+	// This is the "outbase" directory that the output paths of entry points
+	// are relative to, which may have been computed automatically
+	absOutputBase string
+
+	// These are files from "--copy" that go into the output directory as-is
+	copiedFiles []graph.OutputFile
 }
 
 type parseArgs struct {
@@ -89,13 +126,25 @@ type parseArgs struct {
 	importPathRange logger.Range
 	sourceIndex     uint32
 	skipResolve     bool
+	isEntryPoint    bool
 }
 
 type parseResult struct {
 	resolveResults []*resolver.ResolveResult
-	file           scannerFile
-	tlaCheck       tlaCheck
-	ok             bool
+
+	// These are the TypeScript files referenced by the generated ".d.ts" file.
+	// They are often type-only imports that don't end up in the bundle, but
+	// they still need their own ".d.ts" files for the output to type-check.
+	tsDeclarationImports []tsDeclarationImport
+
+	file     scannerFile
+	tlaCheck tlaCheck
+	ok       bool
+}
+
+type tsDeclarationImport struct {
+	resolveResult *resolver.ResolveResult
+	importRange   logger.Range
 }
 
 type tlaCheck struct {
@@ -112,10 +161,23 @@ func parseFile(args parseArgs) {
 		IdentifierName: js_ast.GenerateNonUniqueNameFromPath(args.keyPath.Text),
 	}
 
+	// Don't bother loading anything once the build has been cancelled
+	if args.options.CancelFlag.DidCancel() {
+		if args.inject != nil {
+			args.inject <- config.InjectedFile{
+				Source: source,
+			}
+		}
+		args.results <- parseResult{}
+		return
+	}
+
 	var loader config.Loader
 	var absResolveDir string
 	var pluginName string
 	var pluginData interface{}
+	var pluginSourceMap *string
+	var remoteURL string
 
 	if stdin := args.options.Stdin; stdin != nil {
 		// Special-case stdin
@@ -131,12 +193,13 @@ func parseFile(args parseArgs) {
 			args.res,
 			args.fs,
 			&args.caches.FSCache,
+			&args.caches.RemoteCache,
 			args.log,
 			&source,
 			args.importSource,
 			args.importPathRange,
 			args.pluginData,
-			args.options.WatchMode,
+			&args.options,
 		)
 		if !ok {
 			if args.inject != nil {
@@ -151,15 +214,42 @@ func parseFile(args parseArgs) {
 		absResolveDir = result.absResolveDir
 		pluginName = result.pluginName
 		pluginData = result.pluginData
+		pluginSourceMap = result.sourceMap
+		remoteURL = result.remoteURL
 	}
 
 	_, base, ext := logger.PlatformIndependentPathDirBaseExt(source.KeyPath.Text)
 
+	// Stylesheets in other languages are converted to CSS before being parsed
+	// unless a plugin has already loaded them
+	if loader == config.LoaderDefault {
+		if preprocessor := findStylePreprocessor(args.options.StylePreprocessors, source.KeyPath); preprocessor != nil {
+			sourceMap, ok := runStylePreprocessor(&args, preprocessor, &source, absResolveDir)
+			if !ok {
+				if args.inject != nil {
+					args.inject <- config.InjectedFile{
+						Source: source,
+					}
+				}
+				args.results <- parseResult{}
+				return
+			}
+			loader = config.LoaderCSS
+			pluginSourceMap = sourceMap
+		}
+	}
+
 	// The special "default" loader determines the loader from the file path
 	if loader == config.LoaderDefault {
 		loader = loaderFromFileExtension(args.options.ExtensionToLoader, base+ext)
 	}
 
+	// Files that are small enough are inlined as "data:" URLs instead of being
+	// copied to the output directory, which avoids an extra network request
+	if loader == config.LoaderFile && len(source.Contents) < args.options.AssetInlineLimit {
+		loader = config.LoaderDataURL
+	}
+
 	result := parseResult{
 		file: scannerFile{
 			inputFile: graph.InputFile{
@@ -171,9 +261,27 @@ func parseFile(args parseArgs) {
 		},
 	}
 
+	// Glob patterns in "import.meta.glob" are expanded relative to the resolve
+	// directory. The importing file is never included in its own results.
+	if args.options.Mode == config.ModeBundle && absResolveDir != "" {
+		args.options.ExpandGlob = func(pattern string) []string {
+			var results []string
+			for _, importPath := range args.res.Glob(absResolveDir, pattern) {
+				if args.fs.Join(absResolveDir, importPath) != source.KeyPath.Text {
+					results = append(results, importPath)
+				}
+			}
+			return results
+		}
+	}
+
+	isHoldingWorker := false
 	defer func() {
 		r := recover()
 		if r != nil {
+			if isHoldingWorker {
+				args.options.Workers.Release()
+			}
 			args.log.AddErrorWithNotes(nil, logger.Range{},
 				fmt.Sprintf("panic: %v (while parsing %q)", r, source.PrettyPath),
 				[]logger.MsgData{{Text: helpers.PrettyPrintedStack()}})
@@ -181,11 +289,31 @@ func parseFile(args parseArgs) {
 		}
 	}()
 
+	// Errors from most parsers are syntax errors. The JavaScript parser assigns
+	// categories itself since some of its errors are about the configured target.
+	parseLog := args.log.WithCategory(logger.MsgCategorySyntax)
+	args.options.Workers.Acquire()
+	isHoldingWorker = true
+	parseStart := time.Now()
+
 	switch loader {
 	case config.LoaderJS:
-		ast, ok := args.caches.JSCache.Parse(args.log, source, js_parser.OptionsFromConfig(&args.options))
+		var ast js_ast.AST
+		var ok bool
+		if source.KeyPath.Namespace == "shared" {
+			// The code for shared modules calls a runtime helper
+			ast, ok = js_parser.ParseGeneratedCode(args.log, source, js_parser.OptionsFromConfig(&args.options))
+		} else if source.KeyPath.Namespace == "inline" {
+			ast, ok = parseInlineModule(args.log, args.fs, source, &args.options), true
+		} else {
+			ast, ok = args.caches.JSCache.Parse(args.log, source, js_parser.OptionsFromConfig(&args.options))
+		}
 		if len(ast.Parts) <= 1 { // Ignore the implicitly-generated namespace export part
 			result.file.inputFile.SideEffects.Kind = graph.NoSideEffects_EmptyAST
+		} else if source.KeyPath.Namespace == "external-global" || source.KeyPath.Namespace == "shared" || source.KeyPath.Namespace == "inline" {
+			// These modules just read a global variable, look up a shared module, or
+			// evaluate to a string
+			result.file.inputFile.SideEffects.Kind = graph.NoSideEffects_PureData
 		}
 		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
 		result.ok = ok
@@ -208,6 +336,9 @@ func parseFile(args parseArgs) {
 		}
 		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
 		result.ok = ok
+		if ok && args.options.TSDeclarations {
+			generateTSDeclaration(args, &result, absResolveDir)
+		}
 
 	case config.LoaderTSX:
 		args.options.TS.Parse = true
@@ -218,9 +349,12 @@ func parseFile(args parseArgs) {
 		}
 		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
 		result.ok = ok
+		if ok && args.options.TSDeclarations {
+			generateTSDeclaration(args, &result, absResolveDir)
+		}
 
 	case config.LoaderCSS:
-		ast := args.caches.CSSCache.Parse(args.log, source, css_parser.Options{
+		ast := args.caches.CSSCache.Parse(parseLog, source, css_parser.Options{
 			MinifySyntax:           args.options.MinifySyntax,
 			MinifyWhitespace:       args.options.MinifyWhitespace,
 			UnsupportedCSSFeatures: args.options.UnsupportedCSSFeatures,
@@ -229,8 +363,25 @@ func parseFile(args parseArgs) {
 		result.file.inputFile.Repr = &graph.CSSRepr{AST: ast}
 		result.ok = true
 
+	case config.LoaderHTML:
+		if !args.isEntryPoint {
+			// HTML files that aren't entry points can only be used with "require()",
+			// which evaluates to the contents of the file like the "text" loader.
+			// Some older packages do this with their HTML templates. This is checked
+			// once all files have been scanned.
+			expr := js_ast.Expr{Data: &js_ast.EString{Value: helpers.StringToUTF16(source.Contents)}}
+			ast := js_parser.LazyExportAST(args.log, source, js_parser.OptionsFromConfig(&args.options), expr, "")
+			result.file.inputFile.SideEffects.Kind = graph.NoSideEffects_PureData
+			result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
+			result.ok = true
+			break
+		}
+		ast := html_parser.Parse(parseLog, source)
+		result.file.inputFile.Repr = &graph.HTMLRepr{AST: ast}
+		result.ok = true
+
 	case config.LoaderJSON:
-		expr, ok := args.caches.JSONCache.Parse(args.log, source, js_parser.JSONOptions{})
+		expr, ok := args.caches.JSONCache.Parse(parseLog, source, js_parser.JSONOptions{})
 		ast := js_parser.LazyExportAST(args.log, source, js_parser.OptionsFromConfig(&args.options), expr, "")
 		if pluginName != "" {
 			result.file.inputFile.SideEffects.Kind = graph.NoSideEffects_PureData_FromPlugin
@@ -327,6 +478,48 @@ func parseFile(args parseArgs) {
 		// Mark that this file is from the "copy" loader
 		result.file.inputFile.UniqueKeyForAdditionalFile = uniqueKey
 
+	case config.LoaderWasm:
+		module, ok := wasm_parser.Parse(parseLog, source)
+		if !ok {
+			break
+		}
+
+		// Error messages for the generated code (e.g. for imports that can't be
+		// resolved) are more useful if they refer to the generated code
+		source.Contents = generateCodeForWasm(source.Contents, module, args.options.Platform)
+		result.file.inputFile.Source = source
+		ast, ok := js_parser.ParseGeneratedCode(args.log, source, js_parser.OptionsFromConfig(&args.options))
+		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
+		result.ok = ok
+
+	case config.LoaderWasmFile:
+		uniqueKey := fmt.Sprintf("%sA%08d", args.uniqueKeyPrefix, args.sourceIndex)
+		uniqueKeyPath := uniqueKey + source.KeyPath.IgnoredSuffix
+		generated := source
+		generated.Contents = generateCodeForWasmFile(uniqueKeyPath)
+		ast, ok := js_parser.ParseGeneratedCode(args.log, generated, js_parser.OptionsFromConfig(&args.options))
+		ast.URLForCSS = uniqueKeyPath
+		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
+		result.ok = ok
+
+		// Mark that this file is from the "wasm-file" loader
+		result.file.inputFile.UniqueKeyForAdditionalFile = uniqueKey
+
+	case config.LoaderNAPI:
+		// Native addons can't be bundled, so the addon is copied to the output
+		// directory and loaded from there at run time using "require()"
+		uniqueKey := fmt.Sprintf("%sA%08d", args.uniqueKeyPrefix, args.sourceIndex)
+		uniqueKeyPath := uniqueKey + source.KeyPath.IgnoredSuffix
+		generated := source
+		generated.Contents = fmt.Sprintf("module.exports = require(%q);\n", uniqueKeyPath)
+		ast, ok := js_parser.ParseGeneratedCode(args.log, generated, js_parser.OptionsFromConfig(&args.options))
+		ast.URLForCSS = uniqueKeyPath
+		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
+		result.ok = ok
+
+		// Mark that this file is from the "napi" loader
+		result.file.inputFile.UniqueKeyForAdditionalFile = uniqueKey
+
 	default:
 		var message string
 		if source.KeyPath.Namespace == "file" && ext != "" {
@@ -338,6 +531,12 @@ func parseFile(args parseArgs) {
 		args.log.AddError(&tracker, args.importPathRange, message)
 	}
 
+	if args.options.Timing != nil {
+		args.options.Timing.AddParse(time.Since(parseStart))
+	}
+	args.options.Workers.Release()
+	isHoldingWorker = false
+
 	// This must come before we send on the "results" channel to avoid deadlock
 	if args.inject != nil {
 		var exports []config.InjectableExport
@@ -375,6 +574,13 @@ func parseFile(args parseArgs) {
 		*recordsPtr = records
 		result.resolveResults = make([]*resolver.ResolveResult, len(records))
 
+		// Relative imports in a remote module that was redirected are relative
+		// to the URL that it was redirected to, like they are in the browser
+		importer := source.KeyPath
+		if remoteURL != "" {
+			importer.Text = remoteURL
+		}
+
 		if len(records) > 0 {
 			resolverCache := make(map[ast.ImportKind]map[string]*resolver.ResolveResult)
 			tracker := logger.MakeLineColumnTracker(&source)
@@ -392,6 +598,16 @@ func parseFile(args parseArgs) {
 					continue
 				}
 
+				// The "napi" loader requires the copy of the addon in the output
+				// directory, which doesn't exist yet and must not be resolved
+				if loader == config.LoaderNAPI {
+					result.resolveResults[importRecordIndex] = &resolver.ResolveResult{
+						PathPair:   resolver.PathPair{Primary: record.Path},
+						IsExternal: true,
+					}
+					continue
+				}
+
 				// Cache the path in case it's imported multiple times in this file
 				cache, ok := resolverCache[record.Kind]
 				if !ok {
@@ -403,6 +619,16 @@ func parseFile(args parseArgs) {
 					continue
 				}
 
+				// Import paths that exactly match the name of a virtual module resolve
+				// to that module. This happens before plugins and the resolver run.
+				if isVirtualModuleName(args.options.VirtualModules, record.Path.Text) {
+					resolveResult := &resolver.ResolveResult{PathPair: resolver.PathPair{
+						Primary: logger.Path{Text: record.Path.Text, Namespace: "virtual"}}}
+					cache[record.Path.Text] = resolveResult
+					result.resolveResults[importRecordIndex] = resolveResult
+					continue
+				}
+
 				// Run the resolver and log an error if the path couldn't be resolved
 				resolveResult, didLogError, debug := RunOnResolvePlugins(
 					args.options.Plugins,
@@ -412,19 +638,47 @@ func parseFile(args parseArgs) {
 					&args.caches.FSCache,
 					&source,
 					record.Range,
-					source.KeyPath,
+					importer,
 					record.Path.Text,
 					record.Kind,
 					absResolveDir,
 					pluginData,
 				)
+
+				// Imports of shared modules are redirected to a generated module that
+				// checks the shared scope at run-time. The file that the import resolved
+				// to is only used as a fallback.
+				if resolveResult != nil && !resolveResult.IsExternal && resolveResult.PathPair.Primary.Namespace == "file" &&
+					(record.Kind == ast.ImportStmt || record.Kind == ast.ImportRequire || record.Kind == ast.ImportDynamic) &&
+					isSharedModule(args.options.SharedModules, record.Path.Text) {
+					resolveResult = &resolver.ResolveResult{
+						PathPair:   resolver.PathPair{Primary: logger.Path{Text: record.Path.Text, Namespace: "shared"}},
+						PluginData: resolveResult.PathPair.Primary.Text,
+					}
+				}
+
+				// Imports with an inline suffix are redirected to a generated module
+				// that evaluates to the code for the file, which is bundled separately
+				if resolveResult != nil && !resolveResult.IsExternal && resolveResult.PathPair.Primary.Namespace == "file" &&
+					(record.Kind == ast.ImportStmt || record.Kind == ast.ImportRequire || record.Kind == ast.ImportDynamic) &&
+					isInlineSuffix(resolveResult.PathPair.Primary.IgnoredSuffix) {
+					primary := resolveResult.PathPair.Primary
+					resolveResult = &resolver.ResolveResult{
+						PathPair: resolver.PathPair{Primary: logger.Path{
+							Text:          args.res.PrettyPath(logger.Path{Text: primary.Text, Namespace: "file"}),
+							Namespace:     "inline",
+							IgnoredSuffix: primary.IgnoredSuffix,
+						}},
+						PluginData: primary.Text,
+					}
+				}
 				cache[record.Path.Text] = resolveResult
 
-				// All "require.resolve()" imports should be external because we don't
-				// want to waste effort traversing into them
+				// External "require.resolve()" imports are left alone other than path
+				// substitution. Internal ones are traversed so that they can be checked
+				// later on, since only assets can be resolved at build time.
 				if record.Kind == ast.ImportRequireResolve {
-					if resolveResult != nil && resolveResult.IsExternal {
-						// Allow path substitution as long as the result is external
+					if resolveResult != nil {
 						result.resolveResults[importRecordIndex] = resolveResult
 					} else if !record.Flags.Has(ast.HandlesImportErrors) {
 						args.log.AddID(logger.MsgID_Bundler_RequireResolveNotExternal, logger.Warning, &tracker, record.Range,
@@ -441,7 +695,7 @@ func parseFile(args parseArgs) {
 					if !didLogError && !record.Flags.Has(ast.HandlesImportErrors) {
 						text, suggestion, notes := ResolveFailureErrorTextSuggestionNotes(args.res, record.Path.Text, record.Kind,
 							pluginName, args.fs, absResolveDir, args.options.Platform, source.PrettyPath)
-						debug.LogErrorMsg(args.log, &source, record.Range, text, suggestion, notes)
+						debug.LogErrorMsg(args.log.WithCategory(logger.MsgCategoryResolve), &source, record.Range, text, suggestion, notes)
 					} else if !didLogError && record.Flags.Has(ast.HandlesImportErrors) {
 						args.log.AddIDWithNotes(logger.MsgID_Bundler_IgnoredDynamicImport, logger.Debug, &tracker, record.Range,
 							fmt.Sprintf("Importing %q was allowed even though it could not be resolved because dynamic import failures appear to be handled here:",
@@ -453,33 +707,190 @@ func parseFile(args parseArgs) {
 
 				result.resolveResults[importRecordIndex] = resolveResult
 			}
+
+			// Everything imported by a file that was resolved using additional
+			// conditions must also be resolved using those conditions
+			if conditions := source.KeyPath.Conditions; conditions != "" {
+				for _, resolveResult := range result.resolveResults {
+					if resolveResult != nil && !resolveResult.IsExternal {
+						resolveResult.PathPair.SetConditions(conditions)
+					}
+				}
+			}
 		}
 	}
 
-	// Attempt to parse the source map if present
+	// Attempt to parse the source map if present. A source map from a plugin
+	// takes precedence over a source map comment in the generated code.
 	if loader.CanHaveSourceMap() && args.options.SourceMap != config.SourceMapNone {
-		var sourceMapComment logger.Span
-		switch repr := result.file.inputFile.Repr.(type) {
-		case *graph.JSRepr:
-			sourceMapComment = repr.AST.SourceMapComment
-		case *graph.CSSRepr:
-			sourceMapComment = repr.AST.SourceMapComment
-		}
-		if sourceMapComment.Text != "" {
-			if path, contents := extractSourceMapFromComment(args.log, args.fs, &args.caches.FSCache,
-				args.res, &source, sourceMapComment, absResolveDir); contents != nil {
-				result.file.inputFile.InputSourceMap = js_parser.ParseSourceMap(args.log, logger.Source{
-					KeyPath:    path,
-					PrettyPath: args.res.PrettyPath(path),
-					Contents:   *contents,
-				})
+		var sm *sourcemap.SourceMap
+		if pluginSourceMap != nil {
+			sm = parseInputSourceMap(args, logger.Path{Text: source.PrettyPath, IgnoredSuffix: "#sourceMap"},
+				*pluginSourceMap, source.KeyPath)
+		} else {
+			var sourceMapComment logger.Span
+			switch repr := result.file.inputFile.Repr.(type) {
+			case *graph.JSRepr:
+				sourceMapComment = repr.AST.SourceMapComment
+			case *graph.CSSRepr:
+				sourceMapComment = repr.AST.SourceMapComment
 			}
+			if sourceMapComment.Text != "" {
+				if path, contents := extractSourceMapFromComment(args.log, args.fs, &args.caches.FSCache,
+					args.res, &source, sourceMapComment, absResolveDir); contents != nil {
+					sm = parseInputSourceMap(args, path, *contents, path)
+				}
+			}
+		}
+
+		// The original sources may themselves have been generated by another tool
+		// with its own source map, so follow those too. That way the final source
+		// map points at the files that were actually authored.
+		if sm != nil {
+			sm = composeNestedSourceMaps(args, sm, map[string]bool{source.KeyPath.Text: true})
 		}
+		result.file.inputFile.InputSourceMap = sm
 	}
 
 	args.results <- result
 }
 
+// Relative paths in the source map are relative to the source map file, which
+// may be in a different directory than the file it's for. They are made
+// absolute so that they don't depend on where the source map was.
+func parseInputSourceMap(args parseArgs, path logger.Path, contents string, relativeTo logger.Path) *sourcemap.SourceMap {
+	sm := js_parser.ParseSourceMap(args.log, logger.Source{
+		KeyPath:    path,
+		PrettyPath: args.res.PrettyPath(path),
+		Contents:   contents,
+	})
+	if sm != nil && relativeTo.Namespace == "file" {
+		mapDir := args.fs.Dir(relativeTo.Text)
+		for i, sourcePath := range sm.Sources {
+			if sourcePath != "" && !args.fs.IsAbs(sourcePath) && !helpers.HasURLScheme(sourcePath) {
+				sm.Sources[i] = args.fs.Join(mapDir, sourcePath)
+			}
+		}
+	}
+	return sm
+}
+
+func composeNestedSourceMaps(args parseArgs, sm *sourcemap.SourceMap, visited map[string]bool) *sourcemap.SourceMap {
+	var nested []*sourcemap.SourceMap
+
+	for i, sourcePath := range sm.Sources {
+		if !args.fs.IsAbs(sourcePath) || visited[sourcePath] {
+			continue
+		}
+		contents, err, _ := args.caches.FSCache.ReadFile(args.fs, sourcePath)
+		if err != nil {
+			continue
+		}
+		comment, ok := findSourceMappingURLComment(contents)
+		if !ok {
+			continue
+		}
+		source := logger.Source{
+			KeyPath:    logger.Path{Text: sourcePath, Namespace: "file"},
+			PrettyPath: args.res.PrettyPath(logger.Path{Text: sourcePath, Namespace: "file"}),
+			Contents:   contents,
+		}
+		path, mapContents := extractSourceMapFromComment(args.log, args.fs, &args.caches.FSCache,
+			args.res, &source, comment, args.fs.Dir(sourcePath))
+		if mapContents == nil {
+			continue
+		}
+		relativeTo := path
+		if path.Namespace != "file" {
+			relativeTo = source.KeyPath
+		}
+		inner := parseInputSourceMap(args, path, *mapContents, relativeTo)
+		if inner == nil {
+			continue
+		}
+
+		// Avoid infinite loops if source maps refer to each other
+		visited[sourcePath] = true
+		inner = composeNestedSourceMaps(args, inner, visited)
+		delete(visited, sourcePath)
+
+		if nested == nil {
+			nested = make([]*sourcemap.SourceMap, len(sm.Sources))
+		}
+		nested[i] = inner
+	}
+
+	if nested == nil {
+		return sm
+	}
+	return sm.Compose(nested)
+}
+
+// This finds the last "//# sourceMappingURL=" or "/*# sourceMappingURL= */"
+// comment in a file that hasn't been parsed. It doesn't need to be precise
+// since it's only used to find source maps for files that aren't bundled.
+func findSourceMappingURLComment(contents string) (logger.Span, bool) {
+	for _, prefix := range []string{"//# sourceMappingURL=", "/*# sourceMappingURL=", "//@ sourceMappingURL="} {
+		if i := strings.LastIndex(contents, prefix); i != -1 {
+			start := i + len(prefix)
+			end := start
+			for end < len(contents) && !strings.ContainsRune(" \t\r\n*", rune(contents[end])) {
+				end++
+			}
+			if end > start {
+				return logger.Span{Text: contents[start:end], Range: logger.Range{
+					Loc: logger.Loc{Start: int32(start)}, Len: int32(end - start)}}, true
+			}
+		}
+	}
+	return logger.Span{}, false
+}
+
+func generateTSDeclaration(args parseArgs, result *parseResult, absResolveDir string) {
+	source := &result.file.inputFile.Source
+
+	// Only generate declarations for the user's own code. Declaration files
+	// don't need to be regenerated and packages ship with their own types.
+	if source.KeyPath.Namespace != "file" || helpers.IsInsideNodeModules(source.KeyPath.Text) {
+		return
+	}
+	if _, base, _ := logger.PlatformIndependentPathDirBaseExt(source.KeyPath.Text); strings.HasSuffix(base, ".d") {
+		return
+	}
+
+	contents, importPaths, ok := js_parser.EmitTSDeclarations(args.log, *source, js_parser.OptionsFromConfig(&args.options))
+	if !ok {
+		return
+	}
+	result.file.tsDeclaration = &graph.OutputFile{Contents: []byte(contents)}
+
+	// Follow relative imports to other TypeScript files when bundling so that
+	// the declaration files for those are generated too
+	if args.options.Mode != config.ModeBundle {
+		return
+	}
+	for _, importPath := range importPaths {
+		if !strings.HasPrefix(importPath.Text, "./") && !strings.HasPrefix(importPath.Text, "../") {
+			continue
+		}
+		resolveResult, _ := args.res.Resolve(absResolveDir, importPath.Text, ast.ImportStmt)
+		if resolveResult == nil || resolveResult.IsExternal || resolveResult.PathPair.Primary.Namespace != "file" {
+			continue
+		}
+		_, base, ext := logger.PlatformIndependentPathDirBaseExt(resolveResult.PathPair.Primary.Text)
+		if strings.HasSuffix(base, ".d") || helpers.IsInsideNodeModules(resolveResult.PathPair.Primary.Text) {
+			continue
+		}
+		switch loaderFromFileExtension(args.options.ExtensionToLoader, base+ext) {
+		case config.LoaderTS, config.LoaderTSNoAmbiguousLessThan, config.LoaderTSX:
+			result.tsDeclarationImports = append(result.tsDeclarationImports, tsDeclarationImport{
+				resolveResult: resolveResult,
+				importRange:   importPath.Range,
+			})
+		}
+	}
+}
+
 func ResolveFailureErrorTextSuggestionNotes(
 	res resolver.Resolver,
 	path string,
@@ -667,6 +1078,9 @@ func logPluginMessages(
 		if msg.Kind == logger.Error {
 			didLogError = true
 		}
+		if msg.Category == logger.MsgCategoryNone {
+			msg.Category = logger.MsgCategoryPlugin
+		}
 
 		// Sanitize the locations
 		for _, note := range msg.Notes {
@@ -695,6 +1109,7 @@ func logPluginMessages(
 		log.AddMsg(logger.Msg{
 			PluginName: name,
 			Kind:       logger.Error,
+			Category:   logger.MsgCategoryPlugin,
 			Data: logger.MsgData{
 				Text:       text,
 				Location:   tracker.MsgLocationOrNil(importPathRange),
@@ -807,6 +1222,13 @@ func RunOnResolvePlugins(
 		}
 	}
 
+	// Relative paths in remote modules are relative to the URL of the module
+	if importer.Namespace == "remote" {
+		if remoteURL, ok := resolver.ResolveRelativeRemoteURL(importer.Text, path); ok {
+			path = remoteURL
+		}
+	}
+
 	// Resolve relative to the resolve directory by default. All paths in the
 	// "file" namespace automatically have a resolve directory. Loader plugins
 	// can also configure a custom resolve directory for files in other namespaces.
@@ -827,8 +1249,10 @@ func RunOnResolvePlugins(
 
 type loaderPluginResult struct {
 	pluginData    interface{}
+	sourceMap     *string
 	absResolveDir string
 	pluginName    string
+	remoteURL     string // The URL of a remote module after following redirects
 	loader        config.Loader
 }
 
@@ -837,13 +1261,15 @@ func runOnLoadPlugins(
 	res resolver.Resolver,
 	fs fs.FS,
 	fsCache *cache.FSCache,
+	remoteCache *cache.RemoteCache,
 	log logger.Log,
 	source *logger.Source,
 	importSource *logger.Source,
 	importPathRange logger.Range,
 	pluginData interface{},
-	isWatchMode bool,
+	options *config.Options,
 ) (loaderPluginResult, bool) {
+	isWatchMode := options.WatchMode
 	loaderArgs := config.OnLoadArgs{
 		Path:       source.KeyPath,
 		PluginData: pluginData,
@@ -903,6 +1329,7 @@ func runOnLoadPlugins(
 				absResolveDir: result.AbsResolveDir,
 				pluginName:    pluginName,
 				pluginData:    result.PluginData,
+				sourceMap:     result.SourceMap,
 			}, true
 		}
 	}
@@ -960,10 +1387,86 @@ func runOnLoadPlugins(
 		}
 	}
 
+	// Modules provided by a global variable re-export that global
+	if source.KeyPath.Namespace == "external-global" {
+		if parts, ok := options.ExternalGlobals[source.KeyPath.Text]; ok {
+			sb := strings.Builder{}
+			sb.WriteString("module.exports = ")
+			sb.WriteString(parts[0])
+			for _, part := range parts[1:] {
+				sb.WriteByte('[')
+				sb.Write(js_printer.QuoteForJSON(part, false))
+				sb.WriteByte(']')
+			}
+			sb.WriteString(";\n")
+			source.Contents = sb.String()
+			return loaderPluginResult{loader: config.LoaderJS}, true
+		}
+	}
+
+	// Shared modules look for a compatible version in the shared scope first
+	if source.KeyPath.Namespace == "shared" {
+		if fallback, ok := pluginData.(string); ok {
+			source.Contents = generateCodeForSharedModule(fs, fsCache, options.SharedScope, source.KeyPath.Text, fallback)
+			return loaderPluginResult{loader: config.LoaderJS, absResolveDir: fs.Dir(fallback)}, true
+		}
+	}
+
+	// The code for inlined files is generated once they have been linked
+	if source.KeyPath.Namespace == "inline" {
+		if absPath, ok := pluginData.(string); ok {
+			return loaderPluginResult{loader: config.LoaderJS, absResolveDir: fs.Dir(absPath)}, true
+		}
+	}
+
+	// Download remote modules, or get them from the cache
+	if source.KeyPath.Namespace == "remote" {
+		contents, contentType, finalURL, err := remoteCache.Fetch(&options.RemoteImports, source.KeyPath.Text)
+		if err != nil {
+			log.AddError(&tracker, importPathRange,
+				fmt.Sprintf("Could not load %q: %s", source.KeyPath.Text, err.Error()))
+			return loaderPluginResult{}, false
+		}
+		source.Contents = contents
+		return loaderPluginResult{
+			loader:    loaderForRemoteModule(options.ExtensionToLoader, finalURL, contentType),
+			remoteURL: finalURL,
+		}, true
+	}
+
 	// Otherwise, fail to load the path
 	return loaderPluginResult{loader: config.LoaderNone}, true
 }
 
+// Remote modules are identified by the file extension in their URL like other
+// modules. If there isn't one (which is common with CDNs), the "Content-Type"
+// header from the server is used instead.
+func loaderForRemoteModule(extensionToLoader map[string]config.Loader, rawURL string, contentType string) config.Loader {
+	if parsed, err := url.Parse(rawURL); err == nil {
+		base := parsed.Path[strings.LastIndexByte(parsed.Path, '/')+1:]
+		if loader := loaderFromFileExtension(extensionToLoader, base); loader != config.LoaderNone {
+			return loader
+		}
+	}
+	if semicolon := strings.IndexByte(contentType, ';'); semicolon != -1 {
+		contentType = contentType[:semicolon]
+	}
+	switch strings.ToLower(strings.TrimSpace(contentType)) {
+	case "application/typescript", "text/typescript":
+		return config.LoaderTS
+	case "text/jsx":
+		return config.LoaderJSX
+	case "text/tsx":
+		return config.LoaderTSX
+	case "text/css":
+		return config.LoaderCSS
+	case "application/json":
+		return config.LoaderJSON
+	default:
+		return config.LoaderJS
+	}
+}
+
 func loaderFromFileExtension(extensionToLoader map[string]config.Loader, base string) config.Loader {
 	// Pick the loader with the longest matching extension. So if there's an
 	// extension for ".css" and for ".module.css", we want to match the one for
@@ -993,6 +1496,13 @@ func hashForFileName(hashBytes []byte) string {
 	return base32.StdEncoding.EncodeToString(hashBytes)[:8]
 }
 
+// This is the value of an "integrity" attribute for a file with these
+// contents. See https://www.w3.org/TR/SRI/ for the format.
+func integrityForContents(contents []byte) string {
+	hash := sha512.Sum384(contents)
+	return "sha384-" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
 type scanner struct {
 	log             logger.Log
 	fs              fs.FS
@@ -1021,7 +1531,12 @@ type visitedFile struct {
 type EntryPoint struct {
 	InputPath  string
 	OutputPath string
-	IsFile     bool
+
+	// Additional conditions for the "exports" and "imports" fields in
+	// package.json that only apply to this entry point and its imports
+	Conditions []string
+
+	IsFile bool
 }
 
 func generateUniqueKeyPrefix() (string, error) {
@@ -1137,23 +1652,90 @@ func ScanBundle(
 	s.preprocessInjectedFiles()
 	entryPointMeta := s.addEntryPoints(entryPoints)
 	s.scanAllDependencies()
+	entryPointMeta = s.addEntryPointsFromHTML(entryPointMeta)
+	entryPointMeta = s.addEntryPointsFromWorkers(entryPointMeta)
 	files := s.processScannedFiles(entryPointMeta)
+	if len(options.DisallowedLicenses) > 0 {
+		s.checkDisallowedLicenses(files, entryPointMeta)
+	}
+	if options.VerifyLockfile {
+		s.verifyLockfile(files, entryPointMeta)
+	}
+	s.checkDuplicatePackages(files, entryPointMeta)
+	s.reportImportCycles(files, entryPointMeta)
 
 	return Bundle{
 		fs:              fs,
 		res:             res,
 		files:           files,
 		entryPoints:     entryPointMeta,
+		absOutputBase:   s.options.AbsOutputBase,
+		copiedFiles:     s.scanCopiedFiles(),
 		uniqueKeyPrefix: uniqueKeyPrefix,
 	}
 }
 
+// This returns the absolute paths of all input files that were read from the
+// file system
+func (b *Bundle) InputFilePaths() []string {
+	var absPaths []string
+	for _, file := range b.files {
+		if keyPath := file.inputFile.Source.KeyPath; keyPath.Namespace == "file" {
+			absPaths = append(absPaths, keyPath.Text)
+		}
+	}
+	return absPaths
+}
+
+// Entry points with different options are scanned as separate bundles, so
+// each one computes its own "outbase" directory. The output paths of entry
+// points in different bundles must be relative to the same directory to
+// avoid collisions, so this moves them all to the lowest common ancestor of
+// the "outbase" directories of every bundle.
+func ShareOutputBase(fs fs.FS, bundles []*Bundle) {
+	absDirs := make([]string, 0, len(bundles))
+	for _, b := range bundles {
+		if b.absOutputBase != "" {
+			absDirs = append(absDirs, b.absOutputBase)
+		}
+	}
+	absOutputBase := lowestCommonAncestorOfDirectories(absDirs)
+	if absOutputBase == "" {
+		return
+	}
+
+	for _, b := range bundles {
+		if b.absOutputBase == absOutputBase || b.absOutputBase == "" {
+			continue
+		}
+		for i := range b.entryPoints {
+			entryPoint := &b.entryPoints[i]
+			if entryPoint.OutputPathIsRelativeToOutbase {
+				if relPath, ok := fs.Rel(absOutputBase, fs.Join(b.absOutputBase, entryPoint.OutputPath)); ok {
+					entryPoint.OutputPath = relPath
+				}
+			}
+		}
+		b.absOutputBase = absOutputBase
+	}
+}
+
+func isVirtualModuleName(virtualModules []config.VirtualModule, name string) bool {
+	for _, virtual := range virtualModules {
+		if virtual.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 type inputKind uint8
 
 const (
 	inputKindNormal inputKind = iota
 	inputKindEntryPoint
 	inputKindStdin
+	inputKindVirtualModule
 )
 
 // This returns the source index of the resulting file
@@ -1188,6 +1770,24 @@ func (s *scanner) maybeParseFile(
 		optionsClone.Stdin = nil
 	}
 
+	// Virtual modules are parsed the same way as stdin
+	if kind == inputKindVirtualModule {
+		for _, virtual := range s.options.VirtualModules {
+			if virtual.Name == path.Text {
+				loader := virtual.Loader
+				if loader == config.LoaderNone {
+					loader = loaderFromFileExtension(s.options.ExtensionToLoader, s.fs.Base(virtual.Name))
+				}
+				optionsClone.Stdin = &config.StdinInfo{
+					Contents:      virtual.Contents,
+					AbsResolveDir: virtual.AbsResolveDir,
+					Loader:        loader,
+				}
+				break
+			}
+		}
+	}
+
 	// Allow certain properties to be overridden
 	if len(resolveResult.JSXFactory) > 0 {
 		optionsClone.JSX.Factory = config.DefineExpr{Parts: resolveResult.JSXFactory}
@@ -1195,12 +1795,36 @@ func (s *scanner) maybeParseFile(
 	if len(resolveResult.JSXFragment) > 0 {
 		optionsClone.JSX.Fragment = config.DefineExpr{Parts: resolveResult.JSXFragment}
 	}
+	if !optionsClone.JSX.Preserve {
+		resolveResult.JSX.ApplyTo(&optionsClone.JSX)
+	}
+	if resolveResult.JSXImportSource != nil {
+		optionsClone.JSX.ImportSource = *resolveResult.JSXImportSource
+	}
+	if path := resolveResult.PathPair.Primary; path.Namespace == "file" {
+		for _, override := range s.options.JSXPathOverrides {
+			if override.Matches(path.Text) {
+				if len(override.Factory.Parts) > 0 {
+					optionsClone.JSX.Factory = override.Factory
+				}
+				if len(override.Fragment.Parts) > 0 || override.Fragment.Constant != nil {
+					optionsClone.JSX.Fragment = override.Fragment
+				}
+				if override.ImportSource != "" {
+					optionsClone.JSX.ImportSource = override.ImportSource
+				}
+			}
+		}
+	}
 	if resolveResult.UseDefineForClassFieldsTS != config.Unspecified {
 		optionsClone.UseDefineForClassFields = resolveResult.UseDefineForClassFieldsTS
 	}
 	if resolveResult.UnusedImportFlagsTS != 0 {
 		optionsClone.UnusedImportFlagsTS = resolveResult.UnusedImportFlagsTS
 	}
+	if resolveResult.EmitDecoratorMetadataTS {
+		optionsClone.EmitDecoratorMetadata = true
+	}
 	optionsClone.TSTarget = resolveResult.TSTarget
 	optionsClone.TSAlwaysStrict = resolveResult.TSAlwaysStrict
 
@@ -1247,12 +1871,14 @@ func (s *scanner) maybeParseFile(
 	if resolveResult.PrimarySideEffectsData != nil {
 		sideEffects.Kind = graph.NoSideEffects_PackageJSON
 		sideEffects.Data = resolveResult.PrimarySideEffectsData
+	} else if inject != nil && s.options.InjectIfUsed {
+		sideEffects.Kind = graph.NoSideEffects_InjectIfUsed
 	}
 
 	go parseFile(parseArgs{
 		fs:              s.fs,
 		log:             s.log,
-		res:             s.res,
+		res:             s.res.WithConditions(path.Conditions),
 		caches:          s.caches,
 		keyPath:         path,
 		prettyPath:      prettyPath,
@@ -1265,6 +1891,7 @@ func (s *scanner) maybeParseFile(
 		results:         s.resultChannel,
 		inject:          inject,
 		skipResolve:     skipResolve,
+		isEntryPoint:    kind == inputKindEntryPoint,
 		uniqueKeyPrefix: s.uniqueKeyPrefix,
 	})
 
@@ -1408,6 +2035,25 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 		})
 	}
 
+	// Virtual entry points have no file on disk, so their names are used as
+	// explicit output paths (minus the file extension)
+	for _, virtual := range s.options.VirtualModules {
+		if !virtual.IsEntryPoint {
+			continue
+		}
+		virtualPath := logger.Path{Text: virtual.Name, Namespace: "virtual"}
+		resolveResult := resolver.ResolveResult{PathPair: resolver.PathPair{Primary: virtualPath}}
+		sourceIndex := s.maybeParseFile(resolveResult, s.res.PrettyPath(virtualPath), nil, logger.Range{}, nil, inputKindVirtualModule, nil)
+		outputPath := sanitizeFilePathForVirtualModulePath(virtual.Name)
+		if last := strings.LastIndexAny(outputPath, "/.\\"); last != -1 && outputPath[last] == '.' {
+			outputPath = outputPath[:last]
+		}
+		entryMetas = append(entryMetas, graph.EntryPoint{
+			OutputPath:  outputPath,
+			SourceIndex: sourceIndex,
+		})
+	}
+
 	// Check each entry point ahead of time to see if it's a real file
 	entryPointAbsResolveDir := s.fs.Cwd()
 	for i := range entryPoints {
@@ -1455,11 +2101,13 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 			if entryPoint.IsFile {
 				importer.Namespace = "file"
 			}
+			conditions := resolver.ConditionsKey(entryPoint.Conditions)
+			res := s.res.WithConditions(conditions)
 
 			// Run the resolver and log an error if the path couldn't be resolved
 			resolveResult, didLogError, debug := RunOnResolvePlugins(
 				s.options.Plugins,
-				s.res,
+				res,
 				s.log,
 				s.fs,
 				&s.caches.FSCache,
@@ -1475,6 +2123,7 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 				if resolveResult.IsExternal {
 					s.log.AddError(nil, logger.Range{}, fmt.Sprintf("The entry point %q cannot be marked as external", entryPoint.InputPath))
 				} else {
+					resolveResult.PathPair.SetConditions(conditions)
 					entryPointResolveResults[i] = resolveResult
 				}
 			} else if !didLogError {
@@ -1494,7 +2143,7 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 						})
 					}
 				}
-				debug.LogErrorMsg(s.log, nil, logger.Range{}, fmt.Sprintf("Could not resolve %q", entryPoint.InputPath), "", notes)
+				debug.LogErrorMsg(s.log.WithCategory(logger.MsgCategoryResolve), nil, logger.Range{}, fmt.Sprintf("Could not resolve %q", entryPoint.InputPath), "", notes)
 			}
 			entryPointWaitGroup.Done()
 		}(i, entryPoint)
@@ -1582,6 +2231,7 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 				// path, use the path relative to the "outbase" directory
 				if relPath, ok := s.fs.Rel(s.options.AbsOutputBase, entryPoint.OutputPath); ok {
 					entryPoint.OutputPath = relPath
+					entryPoint.OutputPathIsRelativeToOutbase = true
 				}
 			}
 		}
@@ -1590,29 +2240,163 @@ func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 	return entryMetas
 }
 
-func lowestCommonAncestorDirectory(fs fs.FS, entryPoints []graph.EntryPoint) string {
-	// Ignore any explicitly-specified output paths
-	absPaths := make([]string, 0, len(entryPoints))
-	for _, entryPoint := range entryPoints {
-		if entryPoint.OutputPathWasAutoGenerated {
-			absPaths = append(absPaths, entryPoint.OutputPath)
-		}
+// Files referenced by "<script>" and "<link>" tags in HTML entry points are
+// bundled as entry points themselves so that the browser can load them. The
+// HTML file is then rewritten to reference the resulting output files.
+func (s *scanner) addEntryPointsFromHTML(entryMetas []graph.EntryPoint) []graph.EntryPoint {
+	isEntryPoint := make(map[uint32]bool, len(entryMetas))
+	for _, entryPoint := range entryMetas {
+		isEntryPoint[entryPoint.SourceIndex] = true
 	}
 
-	if len(absPaths) == 0 {
-		return ""
-	}
+	for _, entryPoint := range entryMetas {
+		result := &s.results[entryPoint.SourceIndex]
+		repr, ok := result.file.inputFile.Repr.(*graph.HTMLRepr)
+		if !ok {
+			continue
+		}
 
-	lowestAbsDir := fs.Dir(absPaths[0])
+		for _, record := range repr.AST.ImportRecords {
+			if !record.SourceIndex.IsValid() || isEntryPoint[record.SourceIndex.GetIndex()] {
+				continue
+			}
 
-	for _, absPath := range absPaths[1:] {
-		absDir := fs.Dir(absPath)
-		lastSlash := 0
-		a := 0
-		b := 0
+			// Other kinds of files are reported as errors later on
+			sourceIndex := record.SourceIndex.GetIndex()
+			switch s.results[sourceIndex].file.inputFile.Repr.(type) {
+			case *graph.JSRepr, *graph.CSSRepr:
+			default:
+				continue
+			}
 
-		for {
-			runeA, widthA := utf8.DecodeRuneInString(absDir[a:])
+			if s.options.WriteToStdout || s.options.AbsOutputFile != "" {
+				tracker := logger.MakeLineColumnTracker(&result.file.inputFile.Source)
+				s.log.AddError(&tracker, record.Range,
+					"Must use \"outdir\" when an HTML entry point references other files")
+				break
+			}
+
+			isEntryPoint[sourceIndex] = true
+			outputPath, isRelativeToOutbase := s.outputPathRelativeToOutbase(s.results[sourceIndex].file.inputFile.Source.KeyPath)
+			entryMetas = append(entryMetas, graph.EntryPoint{
+				OutputPath:                    outputPath,
+				SourceIndex:                   sourceIndex,
+				OutputPathWasAutoGenerated:    true,
+				OutputPathIsRelativeToOutbase: isRelativeToOutbase,
+			})
+		}
+	}
+
+	return entryMetas
+}
+
+// This derives the output path for an entry point that was discovered while
+// scanning from its input path relative to "outbase", minus the extension
+func (s *scanner) outputPathRelativeToOutbase(keyPath logger.Path) (outputPath string, isRelativeToOutbase bool) {
+	outputPath = sanitizeFilePathForVirtualModulePath(keyPath.Text)
+	if keyPath.Namespace == "file" {
+		outputPath = keyPath.Text
+		if relPath, ok := s.fs.Rel(s.options.AbsOutputBase, keyPath.Text); ok {
+			outputPath = relPath
+			isRelativeToOutbase = true
+		}
+	}
+	if last := strings.LastIndexAny(outputPath, "/.\\"); last != -1 && outputPath[last] == '.' {
+		outputPath = outputPath[:last]
+	}
+	return
+}
+
+// Workers constructed with "new Worker(new URL(path, import.meta.url))" run in
+// a separate context from the code that creates them, so each one is bundled
+// as a separate entry point. The URL is replaced with the path to the worker's
+// output file once that's known.
+func (s *scanner) addEntryPointsFromWorkers(entryMetas []graph.EntryPoint) []graph.EntryPoint {
+	isEntryPoint := make(map[uint32]bool, len(entryMetas))
+	for _, entryPoint := range entryMetas {
+		isEntryPoint[entryPoint.SourceIndex] = true
+	}
+	userEntryPointCount := len(entryMetas)
+	isReferencedByPath := make(map[uint32]bool)
+
+	for sourceIndex := range s.results {
+		result := &s.results[sourceIndex]
+		repr, ok := result.file.inputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+
+		for _, record := range repr.AST.ImportRecords {
+			if !record.Flags.Has(ast.IsWorkerURL) || !record.SourceIndex.IsValid() {
+				continue
+			}
+			otherIndex := record.SourceIndex.GetIndex()
+			other := &s.results[otherIndex].file.inputFile
+
+			if otherRepr, ok := other.Repr.(*graph.JSRepr); !ok || otherRepr.AST.HasLazyExport {
+				tracker := logger.MakeLineColumnTracker(&result.file.inputFile.Source)
+				s.log.AddError(&tracker, record.Range,
+					fmt.Sprintf("Cannot use %q as a worker because it's not a JavaScript file", other.Source.PrettyPath))
+				continue
+			}
+			isInline := record.Flags.Has(ast.IsInlineWorkerText | ast.IsInlineWorkerDataURL)
+			if !isInline {
+				isReferencedByPath[otherIndex] = true
+			}
+			if isEntryPoint[otherIndex] {
+				continue
+			}
+
+			if !isInline && (s.options.WriteToStdout || s.options.AbsOutputFile != "") {
+				tracker := logger.MakeLineColumnTracker(&result.file.inputFile.Source)
+				s.log.AddError(&tracker, record.Range,
+					"Must use \"outdir\" when bundling a worker")
+				continue
+			}
+
+			isEntryPoint[otherIndex] = true
+			outputPath, isRelativeToOutbase := s.outputPathRelativeToOutbase(other.Source.KeyPath)
+			entryMetas = append(entryMetas, graph.EntryPoint{
+				OutputPath:                    outputPath,
+				SourceIndex:                   otherIndex,
+				OutputPathWasAutoGenerated:    true,
+				OutputPathIsRelativeToOutbase: isRelativeToOutbase,
+			})
+		}
+	}
+
+	// Don't write output files for workers that are only used inline
+	for i := userEntryPointCount; i < len(entryMetas); i++ {
+		entryMetas[i].IsInlineOnlyWorker = !isReferencedByPath[entryMetas[i].SourceIndex]
+	}
+	return entryMetas
+}
+
+func lowestCommonAncestorDirectory(fs fs.FS, entryPoints []graph.EntryPoint) string {
+	// Ignore any explicitly-specified output paths
+	absDirs := make([]string, 0, len(entryPoints))
+	for _, entryPoint := range entryPoints {
+		if entryPoint.OutputPathWasAutoGenerated {
+			absDirs = append(absDirs, fs.Dir(entryPoint.OutputPath))
+		}
+	}
+	return lowestCommonAncestorOfDirectories(absDirs)
+}
+
+func lowestCommonAncestorOfDirectories(absDirs []string) string {
+	if len(absDirs) == 0 {
+		return ""
+	}
+
+	lowestAbsDir := absDirs[0]
+
+	for _, absDir := range absDirs[1:] {
+		lastSlash := 0
+		a := 0
+		b := 0
+
+		for {
+			runeA, widthA := utf8.DecodeRuneInString(absDir[a:])
 			runeB, widthB := utf8.DecodeRuneInString(lowestAbsDir[b:])
 			boundaryA := widthA == 0 || runeA == '/' || runeA == '\\'
 			boundaryB := widthB == 0 || runeB == '/' || runeB == '\\'
@@ -1669,8 +2453,12 @@ func (s *scanner) scanAllDependencies() {
 				path := resolveResult.PathPair.Primary
 				if !resolveResult.IsExternal {
 					// Handle a path within the bundle
+					kind := inputKindNormal
+					if path.Namespace == "virtual" && isVirtualModuleName(s.options.VirtualModules, path.Text) {
+						kind = inputKindVirtualModule
+					}
 					sourceIndex := s.maybeParseFile(*resolveResult, s.res.PrettyPath(path),
-						&result.file.inputFile.Source, record.Range, resolveResult.PluginData, inputKindNormal, nil)
+						&result.file.inputFile.Source, record.Range, resolveResult.PluginData, kind, nil)
 					record.SourceIndex = ast.MakeIndex32(sourceIndex)
 				} else {
 					// Allow this import statement to be removed if something marked it as "sideEffects: false"
@@ -1698,6 +2486,12 @@ func (s *scanner) scanAllDependencies() {
 			}
 		}
 
+		// Also scan the files that only the generated declaration file depends on
+		for _, imp := range result.tsDeclarationImports {
+			s.maybeParseFile(*imp.resolveResult, s.res.PrettyPath(imp.resolveResult.PathPair.Primary),
+				&result.file.inputFile.Source, imp.importRange, imp.resolveResult.PluginData, inputKindNormal, nil)
+		}
+
 		s.results[result.file.inputFile.Source.Index] = result
 	}
 }
@@ -1712,6 +2506,35 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 		entryPointSourceIndices[meta.SourceIndex] = true
 	}
 
+	// Remember which "browser" field mapping disabled each disabled module so
+	// that the reason can be included in the metafile
+	var disabledBy map[uint32]resolver.BrowserRemap
+	if s.options.NeedsMetafile && s.options.Mode == config.ModeBundle {
+		disabledBy = make(map[uint32]resolver.BrowserRemap)
+		for _, result := range s.results {
+			if !result.ok {
+				continue
+			}
+			if recordsPtr := result.file.inputFile.Repr.ImportRecords(); recordsPtr != nil {
+				for importRecordIndex, record := range *recordsPtr {
+					resolveResult := result.resolveResults[importRecordIndex]
+					if resolveResult == nil || !record.SourceIndex.IsValid() || !resolveResult.PathPair.Primary.IsDisabled() {
+						continue
+					}
+					if _, ok := disabledBy[record.SourceIndex.GetIndex()]; ok {
+						continue
+					}
+					for _, remap := range resolveResult.BrowserRemaps {
+						if remap.Remapped == nil {
+							disabledBy[record.SourceIndex.GetIndex()] = remap
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+
 	// Now that all files have been scanned, process the final file import records
 	for sourceIndex, result := range s.results {
 		if !result.ok {
@@ -1741,6 +2564,22 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 					continue
 				}
 
+				// Report substitutions from "browser" fields. These are debug messages
+				// by default but can be made more visible using a log override.
+				for _, remap := range resolveResult.BrowserRemaps {
+					var text string
+					if remap.Remapped == nil {
+						text = fmt.Sprintf("The \"browser\" field in %q replaced %q with an empty module",
+							remap.PackageJSON.PrettyPath, remap.Key)
+					} else {
+						text = fmt.Sprintf("The \"browser\" field in %q remapped %q to %q",
+							remap.PackageJSON.PrettyPath, remap.Key, *remap.Remapped)
+					}
+					pkgTracker := logger.MakeLineColumnTracker(remap.PackageJSON)
+					s.log.AddIDWithNotes(logger.MsgID_Bundler_BrowserFieldSubstitution, logger.Debug, &tracker, record.Range, text,
+						[]logger.MsgData{pkgTracker.MsgData(remap.PackageJSON.RangeOfString(remap.KeyLoc), "The mapping is here:")})
+				}
+
 				// Now that all files have been scanned, look for packages that are imported
 				// both with "import" and "require". Rewrite any imports that reference the
 				// "module" package.json field to the "main" package.json field instead.
@@ -1774,6 +2613,16 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 						js_printer.QuoteForJSON(record.Kind.StringForMetafile(), s.options.ASCIIOnly)))
 				}
 
+				// HTML files can only be entry points, except that "require()" of an HTML
+				// file evaluates to the contents of the file as a string
+				if otherFile.inputFile.Loader == config.LoaderHTML && record.Kind != ast.ImportEntryPoint {
+					if _, ok := otherFile.inputFile.Repr.(*graph.HTMLRepr); ok || record.Kind != ast.ImportRequire {
+						s.log.AddError(&tracker, record.Range,
+							fmt.Sprintf("Cannot import %q because HTML files can only be used as entry points", otherFile.inputFile.Source.PrettyPath))
+						continue
+					}
+				}
+
 				switch record.Kind {
 				case ast.ImportAt, ast.ImportAtConditional:
 					// Using a JavaScript file with CSS "@import" is not allowed
@@ -1785,6 +2634,56 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 							"Bundling with conditional \"@import\" rules is not currently supported")
 					}
 
+				case ast.ImportEntryPoint:
+					// HTML files can only reference JavaScript and CSS files
+					if _, ok := otherFile.inputFile.Repr.(*graph.CopyRepr); ok || otherFile.inputFile.Loader == config.LoaderHTML {
+						s.log.AddError(&tracker, record.Range,
+							fmt.Sprintf("Cannot reference %q from an HTML file", otherFile.inputFile.Source.PrettyPath))
+					}
+
+				case ast.ImportNewURL:
+					// Using a JavaScript or CSS file with "new URL()" is not allowed unless
+					// it's a worker, which is checked later
+					if !record.Flags.Has(ast.IsWorkerURL) {
+						switch otherRepr := otherFile.inputFile.Repr.(type) {
+						case *graph.CSSRepr:
+							s.log.AddError(&tracker, record.Range,
+								fmt.Sprintf("Cannot use %q as a URL", otherFile.inputFile.Source.PrettyPath))
+							continue
+
+						case *graph.JSRepr:
+							if otherRepr.AST.URLForCSS == "" {
+								s.log.AddError(&tracker, record.Range,
+									fmt.Sprintf("Cannot use %q as a URL", otherFile.inputFile.Source.PrettyPath))
+							}
+						}
+					}
+
+				case ast.ImportRequireResolve:
+					// Calls to "require.resolve()" that refer to an asset are replaced
+					// with the path of the asset in the output directory. Other files are
+					// bundled, so there's nothing for "require.resolve()" to find at run
+					// time. Failures inside a try/catch are passed through unmodified.
+					isAsset := false
+					switch otherRepr := otherFile.inputFile.Repr.(type) {
+					case *graph.JSRepr:
+						isAsset = otherRepr.AST.URLForCSS != ""
+					case *graph.CopyRepr:
+						isAsset = true
+					}
+					if isAsset {
+						record.Flags |= ast.IsResolvedAtBuildTime
+					} else {
+						if !record.Flags.Has(ast.HandlesImportErrors) {
+							s.log.AddErrorWithNotes(&tracker, record.Range,
+								fmt.Sprintf("Cannot use \"require.resolve\" with %q because it will be bundled", otherFile.inputFile.Source.PrettyPath),
+								[]logger.MsgData{{Text: "You can mark the path as external to resolve it at run time instead, " +
+									"or use a loader that copies the file to the output directory such as \"file\" to resolve it to the path of the copy."}})
+						}
+						record.SourceIndex = ast.Index32{}
+						continue
+					}
+
 				case ast.ImportURL:
 					// Using a JavaScript or CSS file with CSS "url()" is not allowed
 					switch otherRepr := otherFile.inputFile.Repr.(type) {
@@ -1871,9 +2770,13 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 
 						var notes []logger.MsgData
 						var by string
-						if data := otherModule.SideEffects.Data; data != nil {
+						if otherModule.SideEffects.Kind == graph.NoSideEffects_InjectIfUsed {
+							by = " by the \"inject if used\" setting"
+						} else if data := otherModule.SideEffects.Data; data != nil {
 							if data.PluginName != "" {
 								by = fmt.Sprintf(" by plugin %q", data.PluginName)
+							} else if data.OverridePackageName != "" {
+								by = fmt.Sprintf(" by the side effects override for %q", data.OverridePackageName)
 							} else {
 								var text string
 								if data.IsSideEffectsArrayInJSON {
@@ -1898,7 +2801,22 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 			if !isFirstImport {
 				sb.WriteString("\n      ")
 			}
-			sb.WriteString("]\n    }")
+			sb.WriteString("]")
+			if remap, ok := disabledBy[uint32(sourceIndex)]; ok {
+				sb.WriteString(fmt.Sprintf(",\n      \"disabled\": {\n        \"reason\": \"browser-field\",\n        \"packageJson\": %s,\n        \"key\": %s\n      }",
+					js_printer.QuoteForJSON(remap.PackageJSON.PrettyPath, s.options.ASCIIOnly),
+					js_printer.QuoteForJSON(remap.Key, s.options.ASCIIOnly)))
+			}
+			if s.options.RefreshMetadata {
+				switch result.file.inputFile.Loader {
+				case config.LoaderJS, config.LoaderJSX, config.LoaderTS, config.LoaderTSNoAmbiguousLessThan, config.LoaderTSX:
+					if repr, ok := result.file.inputFile.Repr.(*graph.JSRepr); ok {
+						sb.WriteString(",\n      \"refresh\": ")
+						sb.WriteString(computeRefreshMetadata(repr).json(s.options.ASCIIOnly))
+					}
+				}
+			}
+			sb.WriteString("\n    }")
 		}
 
 		result.file.jsonMetadataChunk = sb.String()
@@ -1908,12 +2826,26 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 			bytes := []byte(result.file.inputFile.Source.Contents)
 			template := s.options.AssetPathTemplate
 
+			pathOptions := &s.options
+			absOutputDir := s.options.AbsOutputDir
+
 			// Use the entry path template instead of the asset path template if this
 			// file is an entry point and uses the "copy" loader. With the "file" loader
 			// the JS stub is the entry point, but with the "copy" loader the file is
 			// the entry point itself.
 			if result.file.inputFile.Loader == config.LoaderCopy && entryPointSourceIndices[uint32(sourceIndex)] {
 				template = s.options.EntryPathTemplate
+			} else {
+				// Assets can have their own root directory for "[dir]" and their own
+				// output directory, which keeps them apart from the generated code
+				if s.options.AbsAssetRoot != "" {
+					clone := s.options
+					clone.AbsOutputBase = s.options.AbsAssetRoot
+					pathOptions = &clone
+				}
+				if s.options.AbsAssetOutputDir != "" {
+					absOutputDir = s.options.AbsAssetOutputDir
+				}
 			}
 
 			// Add a hash to the file name to prevent multiple files with the same name
@@ -1921,6 +2853,9 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 			var hash string
 			if config.HasPlaceholder(template, config.HashPlaceholder) {
 				h := xxhash.New()
+				if s.options.HashSalt != "" {
+					hashWriteLengthPrefixed(h, []byte(s.options.HashSalt))
+				}
 				h.Write(bytes)
 				hash = hashForFileName(h.Sum(nil))
 			}
@@ -1929,7 +2864,7 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 			_, _, originalExt := logger.PlatformIndependentPathDirBaseExt(result.file.inputFile.Source.KeyPath.Text)
 			dir, base := pathRelativeToOutbase(
 				&result.file.inputFile,
-				&s.options,
+				pathOptions,
 				s.fs,
 				/* avoidIndex */ false,
 				/* customFilePath */ "",
@@ -1937,11 +2872,13 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 
 			// Apply the path template
 			templateExt := strings.TrimPrefix(originalExt, ".")
+			pkg := packageNameForFile(s.res, &result.file.inputFile)
 			relPath := config.TemplateToString(config.SubstituteTemplate(template, config.PathPlaceholders{
 				Dir:  &dir,
 				Name: &base,
 				Hash: &hash,
 				Ext:  &templateExt,
+				Pkg:  &pkg,
 			})) + originalExt
 
 			// Optionally add metadata about the file
@@ -1960,12 +2897,40 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 
 			// Generate the additional file to copy into the output directory
 			result.file.inputFile.AdditionalFiles = []graph.OutputFile{{
-				AbsPath:           s.fs.Join(s.options.AbsOutputDir, relPath),
+				AbsPath:           s.fs.Join(absOutputDir, relPath),
 				Contents:          bytes,
 				JSONMetadataChunk: jsonMetadataChunk,
 			}}
 		}
 
+		// Place the generated declaration file in the output directory using the
+		// same relative path as the TypeScript file it came from
+		if decl := result.file.tsDeclaration; decl != nil {
+			_, _, originalExt := logger.PlatformIndependentPathDirBaseExt(result.file.inputFile.Source.KeyPath.Text)
+			dir, base := pathRelativeToOutbase(
+				&result.file.inputFile,
+				&s.options,
+				s.fs,
+				/* avoidIndex */ false,
+				/* customFilePath */ "",
+			)
+			declExt := ".d.ts"
+			switch originalExt {
+			case ".mts":
+				declExt = ".d.mts"
+			case ".cts":
+				declExt = ".d.cts"
+			}
+			decl.AbsPath = s.fs.Join(s.options.AbsOutputDir, dir, base+declExt)
+			if s.options.NeedsMetafile {
+				decl.JSONMetadataChunk = fmt.Sprintf(
+					"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {\n        %s: {\n          \"bytesInOutput\": 0\n        }\n      },\n      \"bytes\": %d\n    }",
+					js_printer.QuoteForJSON(result.file.inputFile.Source.PrettyPath, s.options.ASCIIOnly),
+					len(decl.Contents),
+				)
+			}
+		}
+
 		s.results[sourceIndex] = result
 	}
 
@@ -2080,6 +3045,7 @@ func DefaultExtensionToLoaderMap() map[string]config.Loader {
 		".mts":  config.LoaderTSNoAmbiguousLessThan,
 		".tsx":  config.LoaderTSX,
 		".css":  config.LoaderCSS,
+		".html": config.LoaderHTML,
 		".json": config.LoaderJSON,
 		".txt":  config.LoaderText,
 	}
@@ -2143,22 +3109,141 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 	// Get the base path from the options or choose the lowest common ancestor of all entry points
 	allReachableFiles := findReachableFiles(files, b.entryPoints)
 
+	// The printed code for each file and the original source code embedded in
+	// source maps are each the same size as all input files together, so avoid
+	// keeping them in memory in low-memory mode
+	var spill *spillFile
+	if options.LowMemory {
+		if s, err := newSpillFile(); err != nil {
+			log.AddID(logger.MsgID_None, logger.Debug, nil, logger.Range{}, fmt.Sprintf("Failed to create spill file: %s", err.Error()))
+		} else {
+			spill = s
+		}
+	}
+
 	// Compute source map data in parallel with linking
 	timer.Begin("Spawn source map tasks")
-	dataForSourceMaps := b.computeDataForSourceMapsInParallel(&options, allReachableFiles)
+	dataForSourceMaps := b.computeDataForSourceMapsInParallel(&options, allReachableFiles, spill)
 	timer.End("Spawn source map tasks")
+	if spill != nil {
+		defer func() {
+			dataForSourceMaps()
+			spill.close()
+		}()
+	}
+
+	// HTML entry points aren't linked. They are generated at the end from the
+	// output files for the files that they reference instead. Workers are
+	// linked separately before everything else.
+	linkEntryPoints := b.entryPoints
+	linkReachableFiles := allReachableFiles
+	var htmlEntryPoints []graph.EntryPoint
+	var workerEntryPoints []graph.EntryPoint
+	isWorker := findWorkers(files, allReachableFiles)
+	isInlinedWorker := findInlinedWorkers(files, allReachableFiles)
+	for _, entryPoint := range b.entryPoints {
+		if _, ok := files[entryPoint.SourceIndex].Repr.(*graph.HTMLRepr); ok {
+			htmlEntryPoints = append(htmlEntryPoints, entryPoint)
+		} else if isWorker[entryPoint.SourceIndex] {
+			workerEntryPoints = append(workerEntryPoints, entryPoint)
+		}
+	}
+	if len(htmlEntryPoints) > 0 || len(workerEntryPoints) > 0 {
+		linkEntryPoints = make([]graph.EntryPoint, 0, len(b.entryPoints)-len(htmlEntryPoints)-len(workerEntryPoints))
+		for _, entryPoint := range b.entryPoints {
+			if _, ok := files[entryPoint.SourceIndex].Repr.(*graph.HTMLRepr); !ok && !isWorker[entryPoint.SourceIndex] {
+				linkEntryPoints = append(linkEntryPoints, entryPoint)
+			}
+		}
+		linkReachableFiles = findReachableFiles(files, linkEntryPoints)
+	}
 
+	// Each call to "link" adds the results of its tree shaking to this
+	var treeShakingResults *treeShakingReport
+	if options.TreeShakingReportPath != "" {
+		treeShakingResults = newTreeShakingReport()
+	}
+
+	// The output path of each worker must be known before the files that
+	// construct it are linked, so workers are linked first (each one by itself)
 	var resultGroups [][]graph.OutputFile
-	if options.CodeSplitting || len(b.entryPoints) == 1 {
+	for len(workerEntryPoints) > 0 {
+		var ready []graph.EntryPoint
+		var pending []graph.EntryPoint
+		for _, entryPoint := range workerEntryPoints {
+			isReady := true
+			for workerSourceIndex := range findWorkers(files, findReachableFiles(files, []graph.EntryPoint{entryPoint})) {
+				if files[workerSourceIndex].AbsWorkerOutputPath == "" {
+					isReady = false
+					break
+				}
+			}
+			if isReady {
+				ready = append(ready, entryPoint)
+			} else {
+				pending = append(pending, entryPoint)
+			}
+		}
+
+		// Workers that construct each other in a cycle can never be linked
+		if len(ready) == 0 {
+			for _, entryPoint := range pending {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot bundle the worker %q because it constructs itself (possibly indirectly)",
+					files[entryPoint.SourceIndex].Source.PrettyPath))
+			}
+			break
+		}
+
+		for _, entryPoint := range ready {
+			entryPoints := []graph.EntryPoint{entryPoint}
+			workerOptions := &options
+			if isInlinedWorker[entryPoint.SourceIndex] {
+				// Inlined workers must be self-contained since they have no path that
+				// other files could be loaded relative to
+				optionsClone := options
+				optionsClone.CodeSplitting = false
+				switch optionsClone.SourceMap {
+				case config.SourceMapInline, config.SourceMapInlineAndExternal:
+					optionsClone.SourceMap = config.SourceMapInline
+				default:
+					optionsClone.SourceMap = config.SourceMapNone
+				}
+				workerOptions = &optionsClone
+			}
+			group := link(workerOptions, timer, log, b.fs, b.res, files, entryPoints,
+				b.uniqueKeyPrefix, findReachableFiles(files, entryPoints), dataForSourceMaps, spill, treeShakingResults)
+			for _, outputFile := range group {
+				if outputFile.EntryPointSourceIndex.IsValid() && outputFile.EntryPointSourceIndex.GetIndex() == entryPoint.SourceIndex {
+					files[entryPoint.SourceIndex].AbsWorkerOutputPath = outputFile.AbsPath
+					if isInlinedWorker[entryPoint.SourceIndex] {
+						files[entryPoint.SourceIndex].InlineWorkerContents = outputFile.Contents
+						warnAboutLargeInlineWorker(log, &files[entryPoint.SourceIndex])
+					}
+				}
+			}
+			if !entryPoint.IsInlineOnlyWorker {
+				resultGroups = append(resultGroups, group)
+			}
+		}
+		workerEntryPoints = pending
+	}
+
+	switch {
+	case len(linkEntryPoints) == 0:
+		// There is nothing to link if the only entry points are HTML files
+
+	case options.CodeSplitting || len(linkEntryPoints) == 1:
 		// If code splitting is enabled or if there's only one entry point, link all entry points together
-		resultGroups = [][]graph.OutputFile{link(&options, timer, log, b.fs, b.res,
-			files, b.entryPoints, b.uniqueKeyPrefix, allReachableFiles, dataForSourceMaps)}
-	} else {
+		resultGroups = append(resultGroups, link(&options, timer, log, b.fs, b.res,
+			files, linkEntryPoints, b.uniqueKeyPrefix, linkReachableFiles, dataForSourceMaps, spill, treeShakingResults))
+
+	default:
 		// Otherwise, link each entry point with the runtime file separately
 		waitGroup := sync.WaitGroup{}
-		resultGroups = make([][]graph.OutputFile, len(b.entryPoints))
-		serializer := helpers.MakeSerializer(len(b.entryPoints))
-		for i, entryPoint := range b.entryPoints {
+		workerGroupCount := len(resultGroups)
+		resultGroups = append(resultGroups, make([][]graph.OutputFile, len(linkEntryPoints))...)
+		serializer := helpers.MakeSerializer(len(linkEntryPoints))
+		for i, entryPoint := range linkEntryPoints {
 			waitGroup.Add(1)
 			go func(i int, entryPoint graph.EntryPoint) {
 				entryPoints := []graph.EntryPoint{entryPoint}
@@ -2178,8 +3263,8 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 					// Each goroutine can share an options object
 					optionsPtr = &options
 				}
-				resultGroups[i] = link(optionsPtr, forked, log, b.fs, b.res, files, entryPoints,
-					b.uniqueKeyPrefix, findReachableFiles(files, entryPoints), dataForSourceMaps)
+				resultGroups[workerGroupCount+i] = link(optionsPtr, forked, log, b.fs, b.res, files, entryPoints,
+					b.uniqueKeyPrefix, findReachableFiles(files, entryPoints), dataForSourceMaps, spill, treeShakingResults)
 				timer.Join(forked)
 				waitGroup.Done()
 			}(i, entryPoint)
@@ -2193,11 +3278,78 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 		outputFiles = append(outputFiles, group...)
 	}
 
+	// Generate the HTML entry points now that the output paths are known
+	for _, entryPoint := range htmlEntryPoints {
+		outputFiles = append(outputFiles, b.generateHTMLOutputFile(&options, entryPoint, outputFiles))
+	}
+
+	// Add the generated declaration files in source index order for determinism
+	if options.TSDeclarations {
+		for _, file := range b.files {
+			if file.tsDeclaration != nil {
+				outputFiles = append(outputFiles, *file.tsDeclaration)
+			}
+		}
+	}
+
+	// Copy static files and directories into the output directory as-is
+	outputFiles = append(outputFiles, b.copiedFiles...)
+
+	// Combine the legal comments from all output files into a single file
+	if options.LegalComments == config.LegalCommentsCombined {
+		if outputFile, ok := b.generateCombinedLegalComments(&options, outputFiles); ok {
+			outputFiles = append(outputFiles, outputFile)
+		}
+	}
+
+	// Generate a manifest that maps input files to their output files
+	if options.ManifestPath != "" {
+		timer.Begin("Generate manifest")
+		if outputFile, ok := b.generateManifest(log, &options, outputFiles, allReachableFiles); ok {
+			outputFiles = append(outputFiles, outputFile)
+		}
+		timer.End("Generate manifest")
+	}
+
+	// Explain why each top-level symbol was kept by tree shaking
+	if treeShakingResults != nil {
+		timer.Begin("Generate tree shaking report")
+		if outputFile, ok := b.generateTreeShakingReport(log, &options, treeShakingResults); ok {
+			outputFiles = append(outputFiles, outputFile)
+		}
+		timer.End("Generate tree shaking report")
+	}
+
+	// List the runtime APIs that need polyfills in the target environment
+	if options.PolyfillReportPath != "" {
+		timer.Begin("Generate polyfill report")
+		if outputFile, ok := b.generatePolyfillReport(log, &options, allReachableFiles); ok {
+			outputFiles = append(outputFiles, outputFile)
+		}
+		timer.End("Generate polyfill report")
+	}
+
+	// Generate a "package.json" file that can be published from the output directory
+	if options.PublishPackageJSON {
+		timer.Begin("Generate publish package.json")
+		if outputFile, ok := b.generatePublishPackageJSON(log, &options, outputFiles); ok {
+			outputFiles = append(outputFiles, outputFile)
+		}
+		timer.End("Generate publish package.json")
+	}
+
+	// Write the import map for the imports that were left external
+	if options.ImportMapExternal && options.AbsImportMapPath != "" {
+		if outputFile, ok := b.generateImportMap(log, &options); ok {
+			outputFiles = append(outputFiles, outputFile)
+		}
+	}
+
 	// Also generate the metadata file if necessary
 	var metafileJSON string
 	if options.NeedsMetafile {
 		timer.Begin("Generate metadata JSON")
-		metafileJSON = b.generateMetadataJSON(outputFiles, allReachableFiles, options.ASCIIOnly)
+		metafileJSON = b.generateMetadataJSON(outputFiles, allReachableFiles, options.ASCIIOnly, options.Integrity)
 		timer.End("Generate metadata JSON")
 	}
 
@@ -2269,6 +3421,22 @@ func (b *Bundle) Compile(log logger.Log, options config.Options, timer *helpers.
 	return outputFiles, metafileJSON
 }
 
+// This returns the set of files that are constructed as workers by any of the
+// given files
+func findWorkers(files []graph.InputFile, sourceIndices []uint32) map[uint32]bool {
+	workers := make(map[uint32]bool)
+	for _, sourceIndex := range sourceIndices {
+		if repr, ok := files[sourceIndex].Repr.(*graph.JSRepr); ok {
+			for _, record := range repr.AST.ImportRecords {
+				if record.Flags.Has(ast.IsWorkerURL) && record.SourceIndex.IsValid() {
+					workers[record.SourceIndex.GetIndex()] = true
+				}
+			}
+		}
+	}
+	return workers
+}
+
 // Find all files reachable from all entry points. This order should be
 // deterministic given that the entry point order is deterministic, since the
 // returned order is the postorder of the graph traversal and import record
@@ -2288,6 +3456,10 @@ func findReachableFiles(files []graph.InputFile, entryPoints []graph.EntryPoint)
 			}
 			if recordsPtr := file.Repr.ImportRecords(); recordsPtr != nil {
 				for _, record := range *recordsPtr {
+					if record.Flags.Has(ast.IsWorkerURL) {
+						// Workers are separate entry points instead of dependencies
+						continue
+					}
 					if record.SourceIndex.IsValid() {
 						visit(record.SourceIndex.GetIndex())
 					} else if record.CopySourceIndex.IsValid() {
@@ -2322,7 +3494,7 @@ func findReachableFiles(files []graph.InputFile, entryPoints []graph.EntryPoint)
 // it could be good to optionally have this be computed during the parsing
 // phase when incremental builds are active but otherwise still have it be
 // computed during linking for optimal speed during non-incremental builds.
-func (b *Bundle) computeDataForSourceMapsInParallel(options *config.Options, reachableFiles []uint32) func() []dataForSourceMap {
+func (b *Bundle) computeDataForSourceMapsInParallel(options *config.Options, reachableFiles []uint32, spill *spillFile) func() []dataForSourceMap {
 	if options.SourceMap == config.SourceMapNone {
 		return func() []dataForSourceMap {
 			return nil
@@ -2343,6 +3515,8 @@ func (b *Bundle) computeDataForSourceMapsInParallel(options *config.Options, rea
 			}
 			waitGroup.Add(1)
 			go func(sourceIndex uint32, f *scannerFile, approximateLineCount int32) {
+				options.Workers.Acquire()
+				defer options.Workers.Release()
 				result := &results[sourceIndex]
 				result.lineOffsetTables = sourcemap.GenerateLineOffsetTables(f.inputFile.Source.Contents, approximateLineCount)
 				sm := f.inputFile.InputSourceMap
@@ -2371,6 +3545,9 @@ func (b *Bundle) computeDataForSourceMapsInParallel(options *config.Options, rea
 							result.quotedContents[i] = quotedContents
 						}
 					}
+					if spill != nil {
+						result.spillQuotedContents(spill)
+					}
 				}
 				waitGroup.Done()
 			}(sourceIndex, f, approximateLineCount)
@@ -2383,7 +3560,7 @@ func (b *Bundle) computeDataForSourceMapsInParallel(options *config.Options, rea
 	}
 }
 
-func (b *Bundle) generateMetadataJSON(results []graph.OutputFile, allReachableFiles []uint32, asciiOnly bool) string {
+func (b *Bundle) generateMetadataJSON(results []graph.OutputFile, allReachableFiles []uint32, asciiOnly bool, integrity bool) string {
 	sb := strings.Builder{}
 	sb.WriteString("{\n  \"inputs\": {")
 
@@ -2424,7 +3601,14 @@ func (b *Bundle) generateMetadataJSON(results []graph.OutputFile, allReachableFi
 			}
 			paths[path] = true
 			sb.WriteString(fmt.Sprintf("%s: ", js_printer.QuoteForJSON(path, asciiOnly)))
-			sb.WriteString(result.JSONMetadataChunk)
+
+			// Every metadata chunk for an output file ends with the "bytes" field
+			chunk := result.JSONMetadataChunk
+			if integrity && strings.HasSuffix(chunk, "\n    }") {
+				chunk = fmt.Sprintf("%s,\n      \"integrity\": %q\n    }",
+					chunk[:len(chunk)-len("\n    }")], integrityForContents(result.Contents))
+			}
+			sb.WriteString(chunk)
 		}
 	}
 
@@ -2432,6 +3616,598 @@ func (b *Bundle) generateMetadataJSON(results []graph.OutputFile, allReachableFi
 	return sb.String()
 }
 
+// HTML entry points reference other entry points with "<script>" and "<link>"
+// tags. These references are rewritten to point to the corresponding output
+// files. A "<link>" tag is also inserted for the CSS file that is generated
+// when a JavaScript entry point imports CSS.
+func (b *Bundle) generateHTMLOutputFile(options *config.Options, entryPoint graph.EntryPoint, outputFiles []graph.OutputFile) graph.OutputFile {
+	file := &b.files[entryPoint.SourceIndex].inputFile
+	repr := file.Repr.(*graph.HTMLRepr)
+	contents := file.Source.Contents
+
+	outputPaths := make(map[uint32]string)
+	cssOutputPaths := make(map[uint32]string)
+	preloadPaths := make(map[uint32][]string)
+	integrities := make(map[string]string)
+	for _, outputFile := range outputFiles {
+		if options.Integrity {
+			integrities[outputFile.AbsPath] = integrityForContents(outputFile.Contents)
+		}
+		if outputFile.EntryPointSourceIndex.IsValid() {
+			outputPaths[outputFile.EntryPointSourceIndex.GetIndex()] = outputFile.AbsPath
+			preloadPaths[outputFile.EntryPointSourceIndex.GetIndex()] = outputFile.AbsPreloadPaths
+		}
+		if outputFile.CSSForEntryPointSourceIndex.IsValid() {
+			cssOutputPaths[outputFile.CSSForEntryPointSourceIndex.GetIndex()] = outputFile.AbsPath
+		}
+	}
+
+	// The hash doesn't depend on the final contents, since those contain paths
+	// relative to the output file. The paths to the referenced output files
+	// already contain the hashes of those files instead.
+	var hash string
+	if config.HasPlaceholder(options.EntryPathTemplate, config.HashPlaceholder) {
+		h := xxhash.New()
+		if options.HashSalt != "" {
+			hashWriteLengthPrefixed(h, []byte(options.HashSalt))
+		}
+		hashWriteLengthPrefixed(h, []byte(contents))
+		for _, record := range repr.AST.ImportRecords {
+			if record.SourceIndex.IsValid() {
+				hashWriteLengthPrefixed(h, []byte(outputPaths[record.SourceIndex.GetIndex()]))
+				hashWriteLengthPrefixed(h, []byte(cssOutputPaths[record.SourceIndex.GetIndex()]))
+			}
+		}
+		hash = hashForFileName(h.Sum(nil))
+	}
+
+	// Determine the output path in the same way as for other entry points
+	var dir, base, ext string
+	if options.AbsOutputFile != "" {
+		dir = "/"
+		base = b.fs.Base(options.AbsOutputFile)
+		ext = b.fs.Ext(base)
+		base = base[:len(base)-len(ext)]
+	} else {
+		dir, base = pathRelativeToOutbase(file, options, b.fs, false, entryPoint.OutputPath)
+		ext = ".html"
+	}
+	templateExt := strings.TrimPrefix(ext, ".")
+	pkg := packageNameForFile(b.res, file)
+	relPath := config.TemplateToString(config.SubstituteTemplate(options.EntryPathTemplate, config.PathPlaceholders{
+		Dir:  &dir,
+		Name: &base,
+		Hash: &hash,
+		Ext:  &templateExt,
+		Pkg:  &pkg,
+	})) + ext
+	absPath := b.fs.Join(options.AbsOutputDir, relPath)
+	absDir := b.fs.Dir(absPath)
+
+	urlForOutputFile := func(outputPath string) string {
+		if options.PublicPath != "" {
+			if relPath, ok := b.fs.Rel(options.AbsOutputDir, outputPath); ok {
+				return joinWithPublicPath(options.PublicPath, strings.ReplaceAll(relPath, "\\", "/"))
+			}
+		}
+		if relPath, ok := b.fs.Rel(absDir, outputPath); ok {
+			return strings.ReplaceAll(relPath, "\\", "/")
+		}
+		return outputPath
+	}
+
+	// This is empty unless subresource integrity is enabled
+	integrityAttribute := func(outputPath string) string {
+		if integrity, ok := integrities[outputPath]; ok {
+			return " integrity=\"" + integrity + "\""
+		}
+		return ""
+	}
+
+	// Rewrite the tags
+	sb := strings.Builder{}
+	var imports []string
+	end := 0
+	for _, tag := range repr.AST.Tags {
+		record := &repr.AST.ImportRecords[tag.ImportRecordIndex]
+		if !record.SourceIndex.IsValid() {
+			continue
+		}
+		outputPath, ok := outputPaths[record.SourceIndex.GetIndex()]
+		if !ok {
+			continue
+		}
+		imports = append(imports, outputPath)
+
+		// Insert another tag right before the script, on its own line if the
+		// script is on its own line
+		insertTagBeforeScript := func(rel string, path string) {
+			tagStart := int(tag.TagLoc.Start)
+			lineStart := strings.LastIndexByte(contents[:tagStart], '\n') + 1
+			indent := contents[lineStart:tagStart]
+			sb.WriteString(contents[end:tagStart])
+			sb.WriteString("<link rel=\"" + rel + "\" href=\"")
+			sb.WriteString(html.EscapeString(urlForOutputFile(path)))
+			sb.WriteString("\"")
+			sb.WriteString(integrityAttribute(path))
+			sb.WriteString(">")
+			if strings.TrimSpace(indent) == "" {
+				sb.WriteString("\n")
+				sb.WriteString(indent)
+			}
+			end = tagStart
+		}
+
+		if tag.Kind == html_parser.TagScript {
+			// Load the CSS for a JavaScript entry point right before the script
+			if cssPath, ok := cssOutputPaths[record.SourceIndex.GetIndex()]; ok {
+				insertTagBeforeScript("stylesheet", cssPath)
+				imports = append(imports, cssPath)
+			}
+
+			// Start loading the chunks that the script imports right away instead
+			// of waiting until the script has been downloaded and parsed
+			for _, preloadPath := range preloadPaths[record.SourceIndex.GetIndex()] {
+				insertTagBeforeScript("modulepreload", preloadPath)
+			}
+		}
+
+		sb.WriteString(contents[end:tag.ValueRange.Loc.Start])
+		sb.WriteString("\"")
+		sb.WriteString(html.EscapeString(urlForOutputFile(outputPath)))
+		sb.WriteString("\"")
+		sb.WriteString(integrityAttribute(outputPath))
+		end = int(tag.ValueRange.End())
+	}
+	sb.WriteString(contents[end:])
+	outputContents := []byte(sb.String())
+
+	// Optionally add metadata about the file
+	var jsonMetadataChunk string
+	if options.NeedsMetafile {
+		metaImports := make([]string, 0, len(imports))
+		for _, path := range imports {
+			metaImports = append(metaImports, fmt.Sprintf("\n        {\n          \"path\": %s,\n          \"kind\": \"entry-point\"\n        }",
+				js_printer.QuoteForJSON(b.res.PrettyPath(logger.Path{Text: path, Namespace: "file"}), options.ASCIIOnly)))
+		}
+		importsSuffix := ""
+		if len(metaImports) > 0 {
+			importsSuffix = "\n      "
+		}
+		prettyPath := js_printer.QuoteForJSON(file.Source.PrettyPath, options.ASCIIOnly)
+		jsonMetadataChunk = fmt.Sprintf(
+			"{\n      \"imports\": [%s%s],\n      \"entryPoint\": %s,\n      \"inputs\": {\n        %s: {\n          \"bytesInOutput\": %d\n        }\n      },\n      \"bytes\": %d\n    }",
+			strings.Join(metaImports, ","), importsSuffix, prettyPath, prettyPath, len(outputContents), len(outputContents))
+	}
+
+	return graph.OutputFile{
+		AbsPath:               absPath,
+		Contents:              outputContents,
+		JSONMetadataChunk:     jsonMetadataChunk,
+		EntryPointSourceIndex: ast.MakeIndex32(entryPoint.SourceIndex),
+	}
+}
+
+// Legal comments are deduplicated across all output files since many packages
+// use the same license text. They are sorted for determinism.
+func (b *Bundle) generateCombinedLegalComments(options *config.Options, outputFiles []graph.OutputFile) (graph.OutputFile, bool) {
+	seen := make(map[string]bool)
+	var texts []string
+	for _, outputFile := range outputFiles {
+		for _, comment := range outputFile.LegalComments {
+			if !seen[comment.Text] {
+				seen[comment.Text] = true
+				texts = append(texts, comment.Text)
+			}
+		}
+	}
+	if len(texts) == 0 {
+		return graph.OutputFile{}, false
+	}
+	sort.Strings(texts)
+
+	j := helpers.Joiner{}
+	for _, text := range texts {
+		j.AddString(text)
+		j.AddString("\n")
+	}
+	contents := j.Done()
+
+	return graph.OutputFile{
+		AbsPath:  b.fs.Join(options.AbsOutputDir, "LEGAL.txt"),
+		Contents: contents,
+		JSONMetadataChunk: fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(contents)),
+	}, true
+}
+
+// The manifest is a simpler alternative to the metafile for server-side code
+// that needs to look up the hashed output path for a given input file. Each
+// entry point and asset is keyed by its input path. Entry points also list the
+// CSS files generated for the CSS that they import and the chunks that they
+// import, which can be preloaded.
+func (b *Bundle) generateManifest(log logger.Log, options *config.Options, outputFiles []graph.OutputFile, allReachableFiles []uint32) (graph.OutputFile, bool) {
+	if options.WriteToStdout {
+		log.AddError(nil, logger.Range{}, "Cannot use \"manifest\" without an output path")
+		return graph.OutputFile{}, false
+	}
+
+	type manifestEntry struct {
+		file    string
+		css     []string
+		preload []string
+	}
+	entries := make(map[string]*manifestEntry)
+	entryFor := func(sourceIndex uint32) *manifestEntry {
+		key := b.files[sourceIndex].inputFile.Source.PrettyPath
+		entry, ok := entries[key]
+		if !ok {
+			entry = &manifestEntry{}
+			entries[key] = entry
+		}
+		return entry
+	}
+	relPathInOutdir := func(absPath string) string {
+		if relPath, ok := b.fs.Rel(options.AbsOutputDir, absPath); ok {
+			return strings.ReplaceAll(relPath, "\\", "/")
+		}
+		return absPath
+	}
+
+	outputPaths := make(map[string]bool, len(outputFiles))
+	integrities := make(map[string]string)
+	for _, outputFile := range outputFiles {
+		outputPaths[outputFile.AbsPath] = true
+		if options.Integrity {
+			integrities[relPathInOutdir(outputFile.AbsPath)] = integrityForContents(outputFile.Contents)
+		}
+		if outputFile.EntryPointSourceIndex.IsValid() {
+			entry := entryFor(outputFile.EntryPointSourceIndex.GetIndex())
+			entry.file = relPathInOutdir(outputFile.AbsPath)
+			for _, preloadPath := range outputFile.AbsPreloadPaths {
+				entry.preload = append(entry.preload, relPathInOutdir(preloadPath))
+			}
+		}
+		if outputFile.CSSForEntryPointSourceIndex.IsValid() {
+			entry := entryFor(outputFile.CSSForEntryPointSourceIndex.GetIndex())
+			entry.css = append(entry.css, relPathInOutdir(outputFile.AbsPath))
+		}
+	}
+
+	// Files from the "file" and "copy" loaders are written out as-is. Only
+	// include them if they were actually written, since unused imports of
+	// these files are removed by tree shaking.
+	for _, sourceIndex := range allReachableFiles {
+		file := &b.files[sourceIndex].inputFile
+		if file.UniqueKeyForAdditionalFile == "" || len(file.AdditionalFiles) != 1 || !outputPaths[file.AdditionalFiles[0].AbsPath] {
+			continue
+		}
+		if entry := entryFor(sourceIndex); entry.file == "" {
+			entry.file = relPathInOutdir(file.AdditionalFiles[0].AbsPath)
+		}
+	}
+
+	// Sort the keys so the manifest is stable across builds
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, key := range keys {
+		entry := entries[key]
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n  ")
+		sb.Write(js_printer.QuoteForJSON(key, options.ASCIIOnly))
+		sb.WriteString(": {\n    \"file\": ")
+		sb.Write(js_printer.QuoteForJSON(entry.file, options.ASCIIOnly))
+		for _, list := range []struct {
+			name  string
+			paths []string
+		}{{"css", entry.css}, {"preload", entry.preload}} {
+			if len(list.paths) > 0 {
+				sb.WriteString(",\n    \"" + list.name + "\": [")
+				for j, path := range list.paths {
+					if j > 0 {
+						sb.WriteString(",")
+					}
+					sb.WriteString("\n      ")
+					sb.Write(js_printer.QuoteForJSON(path, options.ASCIIOnly))
+				}
+				sb.WriteString("\n    ]")
+			}
+		}
+
+		// Servers that generate tags for these files also need their hashes
+		if options.Integrity {
+			sb.WriteString(",\n    \"integrity\": {")
+			isFirst := true
+			seen := make(map[string]bool)
+			for _, paths := range [][]string{{entry.file}, entry.css, entry.preload} {
+				for _, path := range paths {
+					if integrity, ok := integrities[path]; ok && !seen[path] {
+						seen[path] = true
+						if !isFirst {
+							sb.WriteString(",")
+						}
+						isFirst = false
+						sb.WriteString("\n      ")
+						sb.Write(js_printer.QuoteForJSON(path, options.ASCIIOnly))
+						sb.WriteString(": ")
+						sb.Write(js_printer.QuoteForJSON(integrity, options.ASCIIOnly))
+					}
+				}
+			}
+			if !isFirst {
+				sb.WriteString("\n    ")
+			}
+			sb.WriteString("}")
+		}
+		sb.WriteString("\n  }")
+	}
+	if len(keys) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}\n")
+	outputContents := []byte(sb.String())
+
+	absPath := options.ManifestPath
+	if !b.fs.IsAbs(absPath) {
+		absPath = b.fs.Join(options.AbsOutputDir, absPath)
+	}
+	return graph.OutputFile{
+		AbsPath:  absPath,
+		Contents: outputContents,
+		JSONMetadataChunk: fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(outputContents)),
+	}, true
+}
+
+// This generates a copy of the "package.json" file in the current working
+// directory that can be published from the output directory. Paths to entry
+// points are rewritten to the paths of the corresponding output files, and
+// fields that only make sense in the source tree are removed.
+func (b *Bundle) generatePublishPackageJSON(log logger.Log, options *config.Options, outputFiles []graph.OutputFile) (graph.OutputFile, bool) {
+	absPath := b.fs.Join(b.fs.Cwd(), "package.json")
+	path := logger.Path{Text: absPath, Namespace: "file"}
+	contents, err, _ := b.fs.ReadFile(absPath)
+	if err != nil {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot read file %q: %s", b.res.PrettyPath(path), err.Error()))
+		return graph.OutputFile{}, false
+	}
+	source := logger.Source{
+		KeyPath:    path,
+		PrettyPath: b.res.PrettyPath(path),
+		Contents:   contents,
+	}
+	tracker := logger.MakeLineColumnTracker(&source)
+	json, ok := js_parser.ParseJSON(log, source, js_parser.JSONOptions{})
+	if !ok {
+		return graph.OutputFile{}, false
+	}
+	obj, ok := json.Data.(*js_ast.EObject)
+	if !ok {
+		log.AddError(&tracker, logger.Range{Loc: json.Loc}, "Expected \"package.json\" to contain an object")
+		return graph.OutputFile{}, false
+	}
+
+	// Paths may refer to either an entry point or to an output file
+	outputPaths := make(map[string]string)
+	for _, outputFile := range outputFiles {
+		outputPaths[outputFile.AbsPath] = outputFile.AbsPath
+		if outputFile.EntryPointSourceIndex.IsValid() {
+			keyPath := b.files[outputFile.EntryPointSourceIndex.GetIndex()].inputFile.Source.KeyPath
+			if keyPath.Namespace == "file" {
+				outputPaths[keyPath.Text] = outputFile.AbsPath
+			}
+		}
+	}
+
+	var rewritePaths func(value js_ast.Expr)
+	rewritePaths = func(value js_ast.Expr) {
+		switch e := value.Data.(type) {
+		case *js_ast.EString:
+			text := helpers.UTF16ToString(e.Value)
+			if outputPath, ok := outputPaths[b.fs.Join(b.fs.Dir(absPath), text)]; ok {
+				if relPath, ok := b.fs.Rel(options.AbsOutputDir, outputPath); ok {
+					e.Value = helpers.StringToUTF16("./" + strings.ReplaceAll(relPath, "\\", "/"))
+					return
+				}
+			}
+			log.AddID(logger.MsgID_PackageJSON_UnmappedPublishPath, logger.Warning, &tracker, source.RangeOfString(value.Loc),
+				fmt.Sprintf("The path %q does not refer to an entry point or an output file, so it was left unchanged", text))
+
+		case *js_ast.EArray:
+			for _, item := range e.Items {
+				rewritePaths(item)
+			}
+
+		case *js_ast.EObject:
+			for _, property := range e.Properties {
+				rewritePaths(property.ValueOrNil)
+			}
+		}
+	}
+
+	end := 0
+	for _, property := range obj.Properties {
+		if key, ok := property.Key.Data.(*js_ast.EString); ok {
+			switch helpers.UTF16ToString(key.Value) {
+			case "devDependencies", "scripts":
+				continue
+
+			case "main", "module", "types", "typings", "bin", "exports":
+				rewritePaths(property.ValueOrNil)
+
+			case "browser":
+				// Only rewrite the string form since the object form maps paths to paths
+				if _, ok := property.ValueOrNil.Data.(*js_ast.EString); ok {
+					rewritePaths(property.ValueOrNil)
+				}
+			}
+		}
+		obj.Properties[end] = property
+		end++
+	}
+	obj.Properties = obj.Properties[:end]
+
+	sb := strings.Builder{}
+	printPackageJSONValue(&sb, json, "", options.ASCIIOnly)
+	sb.WriteString("\n")
+	outputContents := []byte(sb.String())
+	return graph.OutputFile{
+		AbsPath:  b.fs.Join(options.AbsOutputDir, "package.json"),
+		Contents: outputContents,
+		JSONMetadataChunk: fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(outputContents)),
+	}, true
+}
+
+// When imports matched by the import map are left external, the browser needs
+// the import map at run-time. This writes a copy of it to the output directory
+// with relative paths rewritten to be relative to the output directory.
+func (b *Bundle) generateImportMap(log logger.Log, options *config.Options) (graph.OutputFile, bool) {
+	absPath := options.AbsImportMapPath
+	path := logger.Path{Text: absPath, Namespace: "file"}
+	contents, err, _ := b.fs.ReadFile(absPath)
+	if err != nil {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot read file %q: %s", b.res.PrettyPath(path), err.Error()))
+		return graph.OutputFile{}, false
+	}
+	source := logger.Source{
+		KeyPath:    path,
+		PrettyPath: b.res.PrettyPath(path),
+		Contents:   contents,
+	}
+	json, ok := js_parser.ParseJSON(log, source, js_parser.JSONOptions{})
+	if !ok {
+		return graph.OutputFile{}, false
+	}
+
+	rewritePath := func(e *js_ast.EString) {
+		text := helpers.UTF16ToString(e.Value)
+		if !strings.HasPrefix(text, "./") && !strings.HasPrefix(text, "../") {
+			return
+		}
+		relPath, ok := b.fs.Rel(options.AbsOutputDir, b.fs.Join(b.fs.Dir(absPath), text))
+		if !ok {
+			return
+		}
+		relPath = strings.ReplaceAll(relPath, "\\", "/")
+		if relPath == "." {
+			relPath = "./"
+		} else if !strings.HasPrefix(relPath, "../") {
+			relPath = "./" + relPath
+		}
+		if strings.HasSuffix(text, "/") && !strings.HasSuffix(relPath, "/") {
+			relPath += "/"
+		}
+		e.Value = helpers.StringToUTF16(relPath)
+	}
+
+	rewriteSpecifierMap := func(value js_ast.Expr) {
+		if obj, ok := value.Data.(*js_ast.EObject); ok {
+			for _, property := range obj.Properties {
+				if key, ok := property.Key.Data.(*js_ast.EString); ok {
+					rewritePath(key)
+				}
+				if str, ok := property.ValueOrNil.Data.(*js_ast.EString); ok {
+					rewritePath(str)
+				}
+			}
+		}
+	}
+
+	if obj, ok := json.Data.(*js_ast.EObject); ok {
+		for _, property := range obj.Properties {
+			if key, ok := property.Key.Data.(*js_ast.EString); ok {
+				switch helpers.UTF16ToString(key.Value) {
+				case "imports":
+					rewriteSpecifierMap(property.ValueOrNil)
+
+				case "scopes":
+					if scopes, ok := property.ValueOrNil.Data.(*js_ast.EObject); ok {
+						for _, scope := range scopes.Properties {
+							if scopeKey, ok := scope.Key.Data.(*js_ast.EString); ok {
+								rewritePath(scopeKey)
+							}
+							rewriteSpecifierMap(scope.ValueOrNil)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	sb := strings.Builder{}
+	printPackageJSONValue(&sb, json, "", options.ASCIIOnly)
+	sb.WriteString("\n")
+	outputContents := []byte(sb.String())
+	return graph.OutputFile{
+		AbsPath:  b.fs.Join(options.AbsOutputDir, b.fs.Base(absPath)),
+		Contents: outputContents,
+		JSONMetadataChunk: fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(outputContents)),
+	}, true
+}
+
+// This uses two-space indentation to match what "npm" writes
+func printPackageJSONValue(sb *strings.Builder, value js_ast.Expr, indent string, asciiOnly bool) {
+	switch e := value.Data.(type) {
+	case *js_ast.ENull:
+		sb.WriteString("null")
+
+	case *js_ast.EBoolean:
+		if e.Value {
+			sb.WriteString("true")
+		} else {
+			sb.WriteString("false")
+		}
+
+	case *js_ast.ENumber:
+		sb.WriteString(strconv.FormatFloat(e.Value, 'g', -1, 64))
+
+	case *js_ast.EString:
+		sb.Write(js_printer.QuoteForJSON(helpers.UTF16ToString(e.Value), asciiOnly))
+
+	case *js_ast.EArray:
+		if len(e.Items) == 0 {
+			sb.WriteString("[]")
+			return
+		}
+		sb.WriteString("[")
+		for i, item := range e.Items {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n" + indent + "  ")
+			printPackageJSONValue(sb, item, indent+"  ", asciiOnly)
+		}
+		sb.WriteString("\n" + indent + "]")
+
+	case *js_ast.EObject:
+		if len(e.Properties) == 0 {
+			sb.WriteString("{}")
+			return
+		}
+		sb.WriteString("{")
+		for i, property := range e.Properties {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n" + indent + "  ")
+			printPackageJSONValue(sb, property.Key, indent+"  ", asciiOnly)
+			sb.WriteString(": ")
+			printPackageJSONValue(sb, property.ValueOrNil, indent+"  ", asciiOnly)
+		}
+		sb.WriteString("\n" + indent + "}")
+	}
+}
+
 type runtimeCacheKey struct {
 	MinifySyntax      bool
 	MinifyIdentifiers bool
@@ -1,6 +1,8 @@
 package bundler
 
 import (
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/evanw/esbuild/internal/compat"
@@ -124,6 +126,80 @@ func TestCSSAtImport(t *testing.T) {
 	})
 }
 
+func TestCSSDuplicateRulesAcrossFilesMinify(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				@import "./a.css";
+				@import "./b.css";
+				.entry { color: red }
+				.entry { top: 0 }
+			`,
+			"/a.css": `
+				.reset { margin: 0 }
+				.a { color: green }
+				.image { background: url(./image.png) }
+				@layer base;
+			`,
+			"/b.css": `
+				.reset { margin: 0 }
+				.b { color: blue }
+				.image { background: url(./image.png) }
+				@layer base;
+			`,
+			"/image.png": `x`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.css",
+			MinifySyntax:  true,
+			ExtensionToLoader: map[string]config.Loader{
+				".css": config.LoaderCSS,
+				".png": config.LoaderDataURL,
+			},
+		},
+	})
+}
+
+func TestCSSPurgeUnusedSelectors(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import "./style.css"
+				document.body.className = 'used md:flex'
+				document.body.innerHTML = '<div id="main"></div>'
+			`,
+			"/style.css": `
+				body { margin: 0 }
+				.used { color: red }
+				.unused { color: green }
+				.used, .unused { top: 0 }
+				.used .unused { left: 0 }
+				.used:not(.unused) { right: 0 }
+				#main { color: blue }
+				#missing { color: blue }
+				.md\:flex { display: flex }
+				.dynamic-primary { color: black }
+				@media (min-width: 100px) {
+					.unused { color: red }
+				}
+				@media (min-width: 200px) {
+					.unused { color: red }
+					.used { color: green }
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			AbsOutputDir:     "/out",
+			PurgeCSS:         true,
+			PurgeCSSSafelist: []*regexp.Regexp{regexp.MustCompile("^dynamic-")},
+		},
+	})
+}
+
 func TestCSSFromJSMissingImport(t *testing.T) {
 	css_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -452,6 +528,34 @@ func TestFileImportURLInCSS(t *testing.T) {
 	})
 }
 
+func TestFileURLInCSSAssetInlineLimit(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				a { background: url(./small.png) }
+				b { background: url(./large.png) }
+				@font-face { src: url(./font.woff2) }
+			`,
+			"/small.png":  "small",
+			"/large.png":  "This file is too large to be inlined.",
+			"/font.woff2": "This file is too large to be inlined.",
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			AbsOutputDir:     "/out",
+			PublicPath:       "/static/",
+			AssetPublicPaths: map[string]string{".woff2": "https://fonts.example.com/"},
+			AssetInlineLimit: 10,
+			ExtensionToLoader: map[string]config.Loader{
+				".css":   config.LoaderCSS,
+				".png":   config.LoaderFile,
+				".woff2": config.LoaderFile,
+			},
+		},
+	})
+}
+
 func TestIgnoreURLsInAtRulePrelude(t *testing.T) {
 	css_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -707,3 +811,99 @@ func TestCSSNestingOldBrowser(t *testing.T) {
 `,
 	})
 }
+
+// This is a tiny subset of Sass for testing. It inlines files loaded with
+// "@use" and substitutes variables.
+func testStylePreprocessor() []config.StylePreprocessor {
+	useRule := regexp.MustCompile(`@use "([^"]*)";`)
+	varDecl := regexp.MustCompile(`\$(\w+):\s*([^;]*);`)
+	varUse := regexp.MustCompile(`\$(\w+)`)
+	var inline func(args config.StylePreprocessorArgs, contents string, importer string) (string, error)
+	inline = func(args config.StylePreprocessorArgs, contents string, importer string) (string, error) {
+		var err error
+		contents = useRule.ReplaceAllStringFunc(contents, func(rule string) string {
+			imported, importErr := args.Import(useRule.FindStringSubmatch(rule)[1], importer)
+			if importErr != nil {
+				err = importErr
+				return ""
+			}
+			text, nestedErr := inline(args, imported.Contents, imported.Path)
+			if nestedErr != nil {
+				err = nestedErr
+			}
+			return text
+		})
+		return contents, err
+	}
+	return []config.StylePreprocessor{{
+		Name:   "sass",
+		Filter: regexp.MustCompile(`\.scss$`),
+		Transform: func(args config.StylePreprocessorArgs) (result config.StylePreprocessorResult) {
+			contents, err := inline(args, args.Contents, "")
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+			vars := make(map[string]string)
+			for _, match := range varDecl.FindAllStringSubmatch(contents, -1) {
+				vars[match[1]] = match[2]
+			}
+			contents = varDecl.ReplaceAllString(contents, "")
+			contents = varUse.ReplaceAllStringFunc(contents, func(name string) string { return vars[name[1:]] })
+			result.Contents = strings.TrimSpace(contents)
+			return
+		},
+	}}
+}
+
+func TestCSSStylePreprocessor(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './style.scss'
+			`,
+			"/style.scss": `
+				@use "partials/colors";
+				@use "theme";
+				a { color: $primary; background: $background }
+			`,
+			"/partials/_colors.scss": `
+				@use "../sizes.scss";
+				$primary: red;
+			`,
+			"/_sizes.scss": `
+				$size: 10px;
+			`,
+			"/node_modules/theme/_index.scss": `
+				$background: black;
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			AbsOutputDir:       "/out",
+			StylePreprocessors: testStylePreprocessor(),
+		},
+	})
+}
+
+func TestCSSStylePreprocessorImportError(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				@import "./style.scss";
+			`,
+			"/style.scss": `
+				@use "missing";
+			`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			AbsOutputDir:       "/out",
+			StylePreprocessors: testStylePreprocessor(),
+		},
+		expectedScanLog: `entry.css: ERROR: Could not resolve "missing"
+`,
+	})
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
 )
 
 var dce_suite = suite{
@@ -732,6 +734,95 @@ Users/user/project/node_modules/demo-pkg/package.json: NOTE: It was excluded fro
 	})
 }
 
+func TestPackageJsonSideEffectsArrayGlobBracesAndClasses(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import "demo-pkg/a/keep"
+				import "demo-pkg/b/keep"
+				import "demo-pkg/c/keep"
+				import "demo-pkg/polyfill.mjs"
+				import "demo-pkg/deep/nested/style.css"
+				import "demo-pkg/remove"
+			`,
+			"/Users/user/project/node_modules/demo-pkg/a/keep.js":             `console.log('a')`,
+			"/Users/user/project/node_modules/demo-pkg/b/keep.js":             `console.log('b')`,
+			"/Users/user/project/node_modules/demo-pkg/c/keep.js":             `console.log('TEST FAILED')`,
+			"/Users/user/project/node_modules/demo-pkg/polyfill.mjs":          `console.log('polyfill')`,
+			"/Users/user/project/node_modules/demo-pkg/deep/nested/style.css": `a { color: red }`,
+			"/Users/user/project/node_modules/demo-pkg/remove.js":             `console.log('TEST FAILED')`,
+			"/Users/user/project/node_modules/demo-pkg/package.json": `
+				{
+					"sideEffects": [
+						"./{a,b}/keep.js",
+						"polyfill.[cm]js",
+						"./**/*.{css,scss}",
+						"./[invalid",
+						"./[z-a].js"
+					]
+				}
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+		expectedScanLog: `Users/user/project/node_modules/demo-pkg/package.json: WARNING: Invalid pattern in "sideEffects": "./[z-a].js"
+Users/user/project/src/entry.js: WARNING: Ignoring this import because ` +
+			`"Users/user/project/node_modules/demo-pkg/c/keep.js" was marked as having no side effects
+Users/user/project/node_modules/demo-pkg/package.json: NOTE: It was excluded from the "sideEffects" ` +
+			`array in the enclosing "package.json" file
+Users/user/project/src/entry.js: WARNING: Ignoring this import because ` +
+			`"Users/user/project/node_modules/demo-pkg/remove.js" was marked as having no side effects
+Users/user/project/node_modules/demo-pkg/package.json: NOTE: It was excluded from the "sideEffects" ` +
+			`array in the enclosing "package.json" file
+`,
+	})
+}
+
+func TestPackageJsonSideEffectsOverride(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import "missing-field"
+				import "missing-field/dist/esm"
+				import "wrong-field"
+				import { used } from "@scope/pkg"
+				console.log(used)
+			`,
+			"/Users/user/project/node_modules/missing-field/package.json":          `{ "name": "missing-field" }`,
+			"/Users/user/project/node_modules/missing-field/index.js":              `console.log('TEST FAILED')`,
+			"/Users/user/project/node_modules/missing-field/dist/esm/package.json": `{ "type": "module" }`,
+			"/Users/user/project/node_modules/missing-field/dist/esm/index.js":     `console.log('TEST FAILED')`,
+			"/Users/user/project/node_modules/wrong-field/package.json":            `{ "sideEffects": false }`,
+			"/Users/user/project/node_modules/wrong-field/index.js":                `console.log('side effect')`,
+			"/Users/user/project/node_modules/@scope/pkg/package.json":             `{}`,
+			"/Users/user/project/node_modules/@scope/pkg/index.js": `
+				export * from './used'
+				export * from './unused'
+			`,
+			"/Users/user/project/node_modules/@scope/pkg/used.js":   `export let used = 'used'`,
+			"/Users/user/project/node_modules/@scope/pkg/unused.js": `export let unused = 'unused'; console.log('TEST FAILED')`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			SideEffectsOverrides: map[string]bool{
+				"missing-field": false,
+				"wrong-field":   true,
+				"@scope/pkg":    false,
+			},
+		},
+		expectedScanLog: `Users/user/project/src/entry.js: WARNING: Ignoring this import because ` +
+			`"Users/user/project/node_modules/missing-field/index.js" was marked as having no side effects by the side effects override for "missing-field"
+Users/user/project/src/entry.js: WARNING: Ignoring this import because ` +
+			`"Users/user/project/node_modules/missing-field/dist/esm/index.js" was marked as having no side effects by the side effects override for "missing-field"
+`,
+	})
+}
+
 func TestPackageJsonSideEffectsNestedDirectoryRemove(t *testing.T) {
 	dce_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1297,6 +1388,80 @@ func TestDisableTreeShaking(t *testing.T) {
 	})
 }
 
+func TestDropCalls(t *testing.T) {
+	defines := config.ProcessDefines(map[string]config.DefineData{
+		"invariant": {CallMustBeDropped: true},
+		"log.debug": {CallMustBeDropped: true},
+	})
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { message } from './message'
+				let unused = 1
+				invariant(unused, message)
+				invariant(check(), 'side effects are kept')
+				log.debug(...[1, 2], ...iterable)
+				log.info('kept')
+				let x = invariant(x)
+				export function shadowed(invariant) { invariant('kept') }
+			`,
+			"/message.js": `
+				export let message = 'removed'
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatESModule,
+			Defines:       &defines,
+		},
+	})
+}
+
+func TestTreeShakingReport(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { used, helper } from './lib'
+				import './side-effects'
+				import cjs from './cjs'
+				export function main() {
+					return used()
+				}
+				console.log(helper, cjs)
+			`,
+			"/lib.js": `
+				import { deep } from './deep'
+				export function used() {
+					return deep()
+				}
+				export const helper = 1
+				export function unused() {}
+				export default class {}
+			`,
+			"/deep.js": `
+				export function deep() { return 1 }
+				export let notUsed = 2
+			`,
+			"/side-effects.js": `
+				let counter = 0
+				window.count = () => counter++
+			`,
+			"/cjs.js": `
+				module.exports = { value: 1 }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			AbsOutputDir:          "/out",
+			OutputFormat:          config.FormatESModule,
+			TreeShakingReportPath: "tree-shaking.json",
+		},
+	})
+}
+
 func TestDeadCodeFollowingJump(t *testing.T) {
 	dce_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -3219,3 +3384,106 @@ func TestMultipleDeclarationTreeShakingMinifySyntax(t *testing.T) {
 		},
 	})
 }
+
+func TestDCEImportsOnlyUsedInDeadCode(t *testing.T) {
+	defines := config.ProcessDefines(map[string]config.DefineData{
+		"process.env.TARGET": {
+			DefineExpr: &config.DefineExpr{
+				Constant: &js_ast.EString{Value: helpers.StringToUTF16("browser")},
+			},
+		},
+	})
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { readFileSync } from 'fs'
+				import * as path from 'path'
+				import nodeOnly, { shared } from './shared'
+				import { browserOnly } from './browser-only'
+				import { neverUsed } from './never-used'
+				import './side-effects'
+				if (process.env.TARGET === 'node') {
+					console.log(readFileSync(path.join('a', 'b')), nodeOnly, shared)
+				} else {
+					console.log(browserOnly, shared)
+				}
+			`,
+			"/shared.js":       `export default 'nodeOnly'; export let shared = 'shared'`,
+			"/browser-only.js": `export let browserOnly = 'browserOnly'`,
+			"/never-used.js":   `console.log('side effects'); export let neverUsed = 'neverUsed'`,
+			"/side-effects.js": `console.log('side effects')`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			AbsOutputFile:   "/out.js",
+			Defines:         &defines,
+			MinifySyntax:    true,
+			DropDeadImports: true,
+		},
+	})
+}
+
+func TestDCEImportsOnlyUsedInDeadCodeTypeScript(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import { missing } from './does-not-exist'
+				import { DEBUG } from './constants'
+				if (false) missing()
+				if (DEBUG) console.log('debug')
+			`,
+			"/constants.ts": `export const DEBUG = false`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			AbsOutputFile:   "/out.js",
+			DropDeadImports: true,
+		},
+	})
+}
+
+// Imports only used in dead code must be kept by default since the imported
+// file may have side effects
+func TestDCEImportsOnlyUsedInDeadCodeSideEffects(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { polyfill } from './polyfill'
+				if (false) polyfill()
+			`,
+			"/polyfill.js": `
+				globalThis.installed = true
+				export function polyfill() {}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestDCEImportsOnlyUsedInDeadCodeSideEffectsDropped(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { polyfill } from './polyfill'
+				import * as ns from './polyfill'
+				if (false) polyfill(ns.polyfill)
+			`,
+			"/polyfill.js": `
+				globalThis.installed = true
+				export function polyfill() {}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			AbsOutputFile:   "/out.js",
+			DropDeadImports: true,
+		},
+	})
+}
@@ -0,0 +1,235 @@
+package bundler
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+var html_suite = suite{
+	name: "html",
+}
+
+func TestHTMLEntryPoint(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<!DOCTYPE html>
+<html>
+  <head>
+    <link rel="stylesheet" href="/global.css">
+    <link rel="icon" href="favicon.ico">
+    <script src="https://example.com/analytics.js"></script>
+  </head>
+  <body>
+    <!-- <script src="commented-out.js"></script> -->
+    <script type="text/template" src="template.js"></script>
+    <script>console.log("<script src='inline.js'></script>")</script>
+    <script type=module src=./app.js?v=1></script>
+  </body>
+</html>
+`,
+			"/src/global.css": `
+				@import "./reset.css";
+				body { color: red }
+			`,
+			"/src/reset.css": `
+				* { margin: 0 }
+			`,
+			"/src/app.js": `
+				import './app.css'
+				import { render } from './render'
+				render()
+			`,
+			"/src/app.css": `
+				.app { color: blue }
+			`,
+			"/src/render.js": `
+				export function render() { console.log('render') }
+			`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+			EntryPathTemplate: []config.PathTemplate{
+				{Data: "./", Placeholder: config.DirPlaceholder},
+				{Data: "/", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+		},
+	})
+}
+
+func TestHTMLEntryPointCodeSplitting(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/home.html": `<script type="module" src="home.js"></script>`,
+			"/src/about/index.html": `
+				<link rel=stylesheet href='../shared.css'>
+				<script type="module" src="../about.js"></script>
+			`,
+			"/src/home.js": `
+				import { shared } from './shared'
+				shared('home')
+			`,
+			"/src/about.js": `
+				import { shared } from './shared'
+				shared('about')
+			`,
+			"/src/shared.js": `
+				export function shared(name) { console.log(name) }
+			`,
+			"/src/shared.css": `
+				body { color: red }
+			`,
+		},
+		entryPaths: []string{"/src/home.html", "/src/about/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			PublicPath:    "https://example.com/",
+		},
+	})
+}
+
+func TestHTMLEntryPointIntegrity(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `
+				<link rel=stylesheet href="global.css">
+				<script type="module" src="app.js"></script>
+			`,
+			"/src/global.css": `
+				body { color: red }
+			`,
+			"/src/app.js": `
+				import './app.css'
+				import('./lazy').then(({ lazy }) => lazy())
+			`,
+			"/src/app.css": `
+				.app { color: blue }
+			`,
+			"/src/lazy.js": `
+				export function lazy() { console.log('lazy') }
+			`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			ManifestPath:  "manifest.json",
+			NeedsMetafile: true,
+			Integrity:     true,
+		},
+	})
+}
+
+func TestHTMLEntryPointWithoutReferences(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<p>Hello, world</p>
+<script src="https://example.com/app.js"></script>
+`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.html",
+		},
+	})
+}
+
+func TestHTMLEntryPointErrors(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `
+				<script src="app.js"></script>
+				<script src="other.html"></script>
+				<script src="missing.js"></script>
+			`,
+			"/app.js": `
+				import page from './other.html'
+				console.log(page)
+			`,
+			"/other.html": `<p>Other</p>`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+		expectedScanLog: `app.js: ERROR: Cannot import "other.html" because HTML files can only be used as entry points
+index.html: ERROR: Cannot reference "other.html" from an HTML file
+index.html: ERROR: Could not resolve "./missing.js"
+`,
+	})
+}
+
+func TestHTMLEntryPointOutfileError(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<script src="app.js"></script>`,
+			"/app.js":     `console.log('app')`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.html",
+		},
+		expectedScanLog: `index.html: ERROR: Must use "outdir" when an HTML entry point references other files
+`,
+	})
+}
+
+func TestHTMLRequireTemplate(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(require('legacy-widget'))
+			`,
+			"/node_modules/legacy-widget/index.js": `
+				require('./widget.css')
+				module.exports = {
+					template: require('./widget.html'),
+					config: require('./config.json'),
+					license: require('./LICENSE.txt'),
+				}
+			`,
+			"/node_modules/legacy-widget/widget.html": `<div class="widget"><script src="not-bundled.js"></script></div>`,
+			"/node_modules/legacy-widget/widget.css":  `.widget { color: red }`,
+			"/node_modules/legacy-widget/config.json": `{ "size": 10 }`,
+			"/node_modules/legacy-widget/LICENSE.txt": `MIT`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestHTMLRequireEntryPointError(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<script src="app.js"></script>`,
+			"/app.js": `
+				console.log(require('./index.html'), require('./other.html'))
+				import('./other.html')
+			`,
+			"/other.html": `<p>Other</p>`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+		expectedScanLog: `app.js: ERROR: Cannot import "index.html" because HTML files can only be used as entry points
+app.js: ERROR: Cannot import "other.html" because HTML files can only be used as entry points
+`,
+	})
+}
@@ -527,6 +527,68 @@ func TestLoaderFileRelativePathAssetNamesCSS(t *testing.T) {
 	})
 }
 
+func TestLoaderFileAssetRootAndOutdir(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/src/app/entry.js": `
+				import x from '../../assets/images/icons/x.svg'
+				import './entry.css'
+				console.log(x)
+			`,
+			"/project/src/app/entry.css": `
+				div {
+					background: url(../../assets/images/icons/x.svg);
+				}
+			`,
+			"/project/assets/images/icons/x.svg": "<svg></svg>",
+		},
+		entryPaths: []string{"/project/src/app/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			AbsOutputDir:      "/project/dist/js",
+			AbsAssetRoot:      "/project/assets",
+			AbsAssetOutputDir: "/project/dist/static",
+			AssetPathTemplate: []config.PathTemplate{
+				{Data: "./", Placeholder: config.DirPlaceholder},
+				{Data: "/", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderCSS,
+				".svg": config.LoaderFile,
+			},
+		},
+	})
+}
+
+func TestLoaderFileAssetOutdirPublicPath(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/src/entry.js": `
+				import x from './images/x.svg'
+				console.log(x)
+			`,
+			"/project/src/images/x.svg": "<svg></svg>",
+		},
+		entryPaths: []string{"/project/src/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			AbsOutputDir:      "/project/dist/js",
+			AbsAssetOutputDir: "/project/dist/static",
+			PublicPath:        "https://example.com/static/",
+			AssetPathTemplate: []config.PathTemplate{
+				{Data: "./", Placeholder: config.DirPlaceholder},
+				{Data: "/", Placeholder: config.NamePlaceholder},
+			},
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".svg": config.LoaderFile,
+			},
+		},
+	})
+}
+
 func TestLoaderFilePublicPathJS(t *testing.T) {
 	loader_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1081,3 +1143,113 @@ func TestLoaderCopyWithFormat(t *testing.T) {
 		},
 	})
 }
+
+func TestLoaderWasm(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { add, memory } from './math.wasm'
+				import * as ns from './math.wasm'
+				console.log(add(1, 2), memory, ns['not-an-identifier'])
+			`,
+			"/env.js": `
+				export let log = x => console.log(x)
+			`,
+
+			// This imports "log" from "./env.js" and exports "add", "memory", and
+			// "not-an-identifier"
+			"/math.wasm": "\x00asm\x01\x00\x00\x00" +
+				"\x02\x10\x01\x08./env.js\x03log\x00\x00" +
+				"\x07\x24\x03\x03add\x00\x01\x06memory\x02\x00\x11not-an-identifier\x00\x01",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".wasm": config.LoaderWasm,
+			},
+		},
+	})
+}
+
+func TestLoaderWasmFile(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import url, { instantiate } from './math.wasm'
+				console.log(url, instantiate({ env: {} }))
+			`,
+			"/math.wasm": "wasm",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".wasm": config.LoaderWasmFile,
+			},
+		},
+	})
+}
+
+func TestLoaderWasmErrors(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './invalid.wasm'
+				import './unresolved.wasm'
+			`,
+			"/invalid.wasm":    "not wasm",
+			"/unresolved.wasm": "\x00asm\x01\x00\x00\x00\x02\x0B\x01\x03env\x03log\x00\x00",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".wasm": config.LoaderWasm,
+			},
+		},
+		expectedScanLog: `ERROR: The file "invalid.wasm" is not a WebAssembly module
+unresolved.wasm: ERROR: Could not resolve "env"
+NOTE: You can mark the path "env" as external to exclude it from the bundle, which will remove this error.
+`,
+	})
+}
+
+func TestLoaderNAPI(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				const addon = require('../lib/addon.node')
+				import other from './other.node'
+				console.log(addon, other, require.resolve('../lib/addon.node'))
+			`,
+			"/lib/addon.node": "addon",
+			"/src/other.node": "other",
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			Platform:     config.PlatformNode,
+			OutputFormat: config.FormatCommonJS,
+			AbsOutputDir: "/out",
+			PublicPath:   "https://example.com/",
+			EntryPathTemplate: []config.PathTemplate{
+				{Data: "js/", Placeholder: config.NoPlaceholder},
+				{Data: "", Placeholder: config.NamePlaceholder},
+			},
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".node": config.LoaderNAPI,
+			},
+		},
+	})
+}
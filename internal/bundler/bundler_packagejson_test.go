@@ -2613,3 +2613,491 @@ NOTE: You can mark the path "xyz/src/foo.js" as external to exclude it from the
 `,
 	})
 }
+
+func TestPackageJsonPublish(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/package.json": `
+				{
+					"name": "@scope/demo-pkg",
+					"version": "1.0.0",
+					"main": "./src/index.js",
+					"types": "./src/index.d.ts",
+					"bin": { "demo": "./src/cli.js" },
+					"exports": {
+						".": { "import": "./src/index.js", "default": "./dist/index.js" }
+					},
+					"scripts": { "build": "esbuild" },
+					"dependencies": { "foo": "^1.0.0" },
+					"devDependencies": { "esbuild": "^0.14.0" }
+				}
+			`,
+			"/src/index.js": `
+				export let version = '1.0.0'
+			`,
+			"/src/cli.js": `
+				import { version } from './index'
+				console.log(version)
+			`,
+		},
+		entryPaths: []string{"/src/index.js", "/src/cli.js"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			AbsOutputDir:       "/dist",
+			PublishPackageJSON: true,
+		},
+		expectedCompileLog: `package.json: WARNING: The path "./src/index.d.ts" does not refer to an entry point or an output file, so it was left unchanged
+`,
+	})
+}
+
+func TestPackageJsonDisallowedLicenses(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import './lib'
+				import 'allowed'
+			`,
+			"/Users/user/project/src/lib.js": `
+				import 'gpl'
+				import 'dual'
+				import '@scope/from-license-file'
+			`,
+			"/Users/user/project/node_modules/gpl/package.json": `{ "license": "GPL-3.0-or-later" }`,
+			"/Users/user/project/node_modules/gpl/index.js":     `console.log('gpl')`,
+
+			"/Users/user/project/node_modules/dual/package.json": `{ "license": "(MIT OR GPL-3.0-only)" }`,
+			"/Users/user/project/node_modules/dual/index.js":     `console.log('dual')`,
+
+			"/Users/user/project/node_modules/allowed/package.json": `{ "licenses": [{ "type": "MIT" }] }`,
+			"/Users/user/project/node_modules/allowed/index.js":     `console.log('allowed')`,
+
+			"/Users/user/project/node_modules/@scope/from-license-file/package.json": `{}`,
+			"/Users/user/project/node_modules/@scope/from-license-file/LICENSE": `
+                    GNU AFFERO GENERAL PUBLIC LICENSE
+                       Version 3, 19 November 2007
+			`,
+			"/Users/user/project/node_modules/@scope/from-license-file/index.js": `console.log('agpl')`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			AbsOutputFile:      "/Users/user/project/out.js",
+			DisallowedLicenses: []string{"GPL-3.0", "AGPL-*"},
+		},
+		expectedScanLog: `WARNING: The package "gpl" has the disallowed license "GPL-3.0-or-later"
+NOTE: The license was read from "Users/user/project/node_modules/gpl/package.json".
+Users/user/project/src/lib.js: NOTE: The package "gpl" is imported here:
+NOTE: The import chain is: Users/user/project/src/entry.js -> Users/user/project/src/lib.js -> Users/user/project/node_modules/gpl/index.js
+WARNING: The package "@scope/from-license-file" has the disallowed license "AGPL-3.0"
+NOTE: The license was read from "Users/user/project/node_modules/@scope/from-license-file/LICENSE".
+Users/user/project/src/lib.js: NOTE: The package "@scope/from-license-file" is imported here:
+NOTE: The import chain is: Users/user/project/src/entry.js -> Users/user/project/src/lib.js -> Users/user/project/node_modules/@scope/from-license-file/index.js
+`,
+	})
+}
+
+func TestPackageJsonDuplicatePackages(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import React from 'react'
+				import 'ui'
+				import 'single'
+				console.log(React)
+			`,
+			"/Users/user/project/node_modules/react/package.json": `{ "name": "react", "version": "18.2.0" }`,
+			"/Users/user/project/node_modules/react/index.js":     `export default 'react 18'`,
+
+			"/Users/user/project/node_modules/ui/package.json": `{ "name": "ui" }`,
+			"/Users/user/project/node_modules/ui/index.js": `
+				import React from 'react'
+				import { helper } from './helper'
+				console.log(React, helper)
+			`,
+			"/Users/user/project/node_modules/ui/helper.js":                       `export let helper = 1`,
+			"/Users/user/project/node_modules/ui/node_modules/react/package.json": `{ "name": "react", "version": "17.0.2" }`,
+			"/Users/user/project/node_modules/ui/node_modules/react/index.js":     `export default 'react 17'`,
+
+			"/Users/user/project/node_modules/single/package.json": `{ "name": "single" }`,
+			"/Users/user/project/node_modules/single/index.js":     `console.log('single')`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+		expectedScanLog: `WARNING: The package "react" is included in the bundle 2 times from different directories
+Users/user/project/src/entry.js: NOTE: The copy in "Users/user/project/node_modules/react" (version 18.2.0) is imported here:
+NOTE: The import chain is: Users/user/project/src/entry.js -> Users/user/project/node_modules/react/index.js
+Users/user/project/node_modules/ui/index.js: NOTE: The copy in "Users/user/project/node_modules/ui/node_modules/react" (version 17.0.2) is imported here:
+NOTE: The import chain is: Users/user/project/src/entry.js -> Users/user/project/node_modules/ui/index.js -> Users/user/project/node_modules/ui/node_modules/react/index.js
+`,
+	})
+}
+
+func TestPackageJsonEntryPointConditions(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/server.js": `
+				import x from 'pkg'
+				import { shared } from './shared'
+				console.log(x, shared)
+			`,
+			"/Users/user/project/src/client.js": `
+				import x from 'pkg'
+				import { shared } from './shared'
+				console.log(x, shared)
+			`,
+			"/Users/user/project/src/shared.js": `
+				export let shared = 123
+			`,
+			"/Users/user/project/node_modules/pkg/package.json": `
+				{
+					"exports": {
+						"react-server": "./server.js",
+						"default": "./client.js"
+					},
+					"imports": {
+						"#impl": {
+							"development": "./dev.js",
+							"default": "./prod.js"
+						}
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg/server.js": `
+				import impl from '#impl'
+				export default 'server ' + impl
+			`,
+			"/Users/user/project/node_modules/pkg/client.js": `
+				import impl from '#impl'
+				export default 'client ' + impl
+			`,
+			"/Users/user/project/node_modules/pkg/dev.js": `
+				export default 'dev'
+			`,
+			"/Users/user/project/node_modules/pkg/prod.js": `
+				export default 'prod'
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/client.js"},
+		entryPathsAdvanced: []EntryPoint{
+			{InputPath: "/Users/user/project/src/server.js", Conditions: []string{"react-server", "development"}},
+		},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/Users/user/project/out",
+		},
+	})
+}
+
+func TestPackageJsonBrowserMapChainAndDisabledMetafile(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import 'pkg'
+				import 'pkg/unused'
+			`,
+			"/Users/user/project/node_modules/pkg/package.json": `
+				{
+					"browser": {
+						"./a.js": "./b.js",
+						"./b.js": "./c.js",
+						"./unused.js": "./loop.js",
+						"./loop.js": "./unused.js",
+						"fs": false
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg/index.js": `
+				import './a.js'
+				import * as fs from 'fs'
+				console.log('index')
+			`,
+			"/Users/user/project/node_modules/pkg/a.js":      `console.log('a')`,
+			"/Users/user/project/node_modules/pkg/b.js":      `console.log('b')`,
+			"/Users/user/project/node_modules/pkg/c.js":      `console.log('c')`,
+			"/Users/user/project/node_modules/pkg/unused.js": `console.log('unused')`,
+			"/Users/user/project/node_modules/pkg/loop.js":   `console.log('loop')`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			Platform:      config.PlatformBrowser,
+			AbsOutputFile: "/Users/user/project/out.js",
+			NeedsMetafile: true,
+		},
+		debugLogs: true,
+		expectedScanLog: `Users/user/project/node_modules/pkg/index.js: DEBUG: The "browser" field in "Users/user/project/node_modules/pkg/package.json" remapped "./a.js" to "./b.js"
+Users/user/project/node_modules/pkg/package.json: NOTE: The mapping is here:
+Users/user/project/node_modules/pkg/index.js: DEBUG: The "browser" field in "Users/user/project/node_modules/pkg/package.json" remapped "./b.js" to "./c.js"
+Users/user/project/node_modules/pkg/package.json: NOTE: The mapping is here:
+Users/user/project/node_modules/pkg/index.js: DEBUG: The "browser" field in "Users/user/project/node_modules/pkg/package.json" replaced "fs" with an empty module
+Users/user/project/node_modules/pkg/package.json: NOTE: The mapping is here:
+Users/user/project/src/entry.js: DEBUG: The "browser" field in "Users/user/project/node_modules/pkg/package.json" remapped "./loop.js" to "./unused.js"
+Users/user/project/node_modules/pkg/package.json: NOTE: The mapping is here:
+Users/user/project/src/entry.js: DEBUG: The "browser" field in "Users/user/project/node_modules/pkg/package.json" remapped "./unused.js" to "./loop.js"
+Users/user/project/node_modules/pkg/package.json: NOTE: The mapping is here:
+`,
+	})
+}
+
+func TestPackageJsonVerifyLockfileNpm(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import react from 'react'
+				import lodash from 'lodash'
+				import scheduler from 'scheduler'
+				import alias from 'alias'
+				console.log(react, lodash, scheduler, alias)
+			`,
+			"/package-lock.json": `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": { "name": "project" },
+					"node_modules/react": { "version": "18.2.0" },
+					"node_modules/lodash": { "version": "4.17.20" },
+					"node_modules/alias": { "name": "real", "version": "1.0.0" },
+					"node_modules/other/node_modules/lodash": { "version": "3.10.1" }
+				}
+			}`,
+			"/node_modules/react/package.json":     `{ "name": "react", "version": "18.2.0" }`,
+			"/node_modules/react/index.js":         `export default 'react'`,
+			"/node_modules/lodash/package.json":    `{ "name": "lodash", "version": "4.17.21" }`,
+			"/node_modules/lodash/index.js":        `export default 'lodash'`,
+			"/node_modules/scheduler/package.json": `{ "name": "scheduler", "version": "0.23.0" }`,
+			"/node_modules/scheduler/index.js":     `export default 'scheduler'`,
+			"/node_modules/alias/package.json":     `{ "name": "real", "version": "1.0.0" }`,
+			"/node_modules/alias/index.js":         `export default 'alias'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/out.js",
+			VerifyLockfile: true,
+		},
+		expectedScanLog: `ERROR: The package "lodash" in "node_modules/lodash" is at version 4.17.21, which is not in "package-lock.json"
+NOTE: The lock file contains lodash@3.10.1, lodash@4.17.20 instead.
+src/entry.js: NOTE: The package "lodash" is imported here:
+NOTE: The import chain is: src/entry.js -> node_modules/lodash/index.js
+ERROR: The package "scheduler" in "node_modules/scheduler" is not in "package-lock.json"
+NOTE: This usually means that the package is only present because another package depends on it. You should add it to your own dependencies if you import it directly.
+src/entry.js: NOTE: The package "scheduler" is imported here:
+NOTE: The import chain is: src/entry.js -> node_modules/scheduler/index.js
+`,
+	})
+}
+
+func TestPackageJsonVerifyLockfileNpmV1(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import ui from 'ui'
+				import alias from 'alias'
+				console.log(ui, alias)
+			`,
+			"/package-lock.json": `{
+				"lockfileVersion": 1,
+				"dependencies": {
+					"ui": {
+						"version": "2.0.0",
+						"dependencies": {
+							"react": { "version": "17.0.2" }
+						}
+					},
+					"alias": { "version": "npm:real@1.0.0" }
+				}
+			}`,
+			"/node_modules/ui/package.json":                    `{ "name": "ui", "version": "2.0.0" }`,
+			"/node_modules/ui/index.js":                        `import react from 'react'; export default react`,
+			"/node_modules/ui/node_modules/react/package.json": `{ "name": "react", "version": "17.0.2" }`,
+			"/node_modules/ui/node_modules/react/index.js":     `export default 'react'`,
+			"/node_modules/alias/package.json":                 `{ "name": "real", "version": "1.0.0" }`,
+			"/node_modules/alias/index.js":                     `export default 'alias'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/out.js",
+			VerifyLockfile: true,
+		},
+	})
+}
+
+func TestPackageJsonVerifyLockfilePnpm(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import ui from '@scope/ui'
+				import react from 'react'
+				console.log(ui, react)
+			`,
+			"/pnpm-lock.yaml": `lockfileVersion: '9.0'
+
+importers:
+  .:
+    dependencies:
+      react:
+        specifier: ^18.0.0
+        version: 18.2.0
+
+packages:
+
+  '@scope/ui@1.0.0(react@18.2.0)':
+    resolution: {integrity: sha512-abc}
+
+  react@18.3.1:
+    resolution: {integrity: sha512-def}
+
+snapshots:
+
+  react@18.2.0: {}
+`,
+			"/node_modules/@scope/ui/package.json": `{ "name": "@scope/ui", "version": "1.0.0" }`,
+			"/node_modules/@scope/ui/index.js":     `export default 'ui'`,
+			"/node_modules/react/package.json":     `{ "name": "react", "version": "18.2.0" }`,
+			"/node_modules/react/index.js":         `export default 'react'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/out.js",
+			VerifyLockfile: true,
+		},
+		expectedScanLog: `ERROR: The package "react" in "node_modules/react" is at version 18.2.0, which is not in "pnpm-lock.yaml"
+NOTE: The lock file contains react@18.3.1 instead.
+src/entry.js: NOTE: The package "react" is imported here:
+NOTE: The import chain is: src/entry.js -> node_modules/react/index.js
+`,
+	})
+}
+
+func TestPackageJsonVerifyLockfilePnpmV5(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import ui from '@scope/ui'
+				import react from 'react'
+				console.log(ui, react)
+			`,
+			"/pnpm-lock.yaml": `lockfileVersion: 5.4
+
+specifiers:
+  react: ^18.0.0
+
+packages:
+
+  /@scope/ui/1.0.0_react@18.2.0:
+    resolution: {integrity: sha512-abc}
+
+  /react/18.2.0:
+    resolution: {integrity: sha512-def}
+`,
+			"/node_modules/@scope/ui/package.json": `{ "name": "@scope/ui", "version": "1.0.0" }`,
+			"/node_modules/@scope/ui/index.js":     `export default 'ui'`,
+			"/node_modules/react/package.json":     `{ "name": "react", "version": "18.2.0" }`,
+			"/node_modules/react/index.js":         `export default 'react'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/out.js",
+			VerifyLockfile: true,
+		},
+	})
+}
+
+func TestPackageJsonVerifyLockfileYarn(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import ui from '@scope/ui'
+				import react from 'react'
+				console.log(ui, react)
+			`,
+			"/yarn.lock": `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@scope/ui@^1.0.0":
+  version "1.0.0"
+  resolved "https://registry.yarnpkg.com/@scope/ui/-/ui-1.0.0.tgz"
+
+react@^17.0.0, react@^17.0.1:
+  version "17.0.2"
+  resolved "https://registry.yarnpkg.com/react/-/react-17.0.2.tgz"
+`,
+			"/node_modules/@scope/ui/package.json": `{ "name": "@scope/ui", "version": "1.0.0" }`,
+			"/node_modules/@scope/ui/index.js":     `export default 'ui'`,
+			"/node_modules/react/package.json":     `{ "name": "react", "version": "18.2.0" }`,
+			"/node_modules/react/index.js":         `export default 'react'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/out.js",
+			VerifyLockfile: true,
+		},
+		expectedScanLog: `ERROR: The package "react" in "node_modules/react" is at version 18.2.0, which is not in "yarn.lock"
+NOTE: The lock file contains react@17.0.2 instead.
+src/entry.js: NOTE: The package "react" is imported here:
+NOTE: The import chain is: src/entry.js -> node_modules/react/index.js
+`,
+	})
+}
+
+func TestPackageJsonVerifyLockfileYarnBerry(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import ui from '@scope/ui'
+				import react from 'react'
+				console.log(ui, react)
+			`,
+			"/yarn.lock": `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 6
+  cacheKey: 8
+
+"@scope/ui@npm:^1.0.0":
+  version: 1.0.0
+  resolution: "@scope/ui@npm:1.0.0"
+
+"react@npm:^18.0.0, react@npm:^18.2.0":
+  version: 18.2.0
+  resolution: "react@npm:18.2.0"
+`,
+			"/node_modules/@scope/ui/package.json": `{ "name": "@scope/ui", "version": "1.0.0" }`,
+			"/node_modules/@scope/ui/index.js":     `export default 'ui'`,
+			"/node_modules/react/package.json":     `{ "name": "react", "version": "18.2.0" }`,
+			"/node_modules/react/index.js":         `export default 'react'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/out.js",
+			VerifyLockfile: true,
+		},
+	})
+}
+
+func TestPackageJsonVerifyLockfileMissing(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js":                    `import react from 'react'; console.log(react)`,
+			"/node_modules/react/package.json": `{ "name": "react", "version": "18.2.0" }`,
+			"/node_modules/react/index.js":     `export default 'react'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputFile:  "/out.js",
+			VerifyLockfile: true,
+		},
+		expectedScanLog: `ERROR: Cannot verify packages because no lock file was found (looked for "package-lock.json", "pnpm-lock.yaml", "yarn.lock")
+`,
+	})
+}
@@ -525,3 +525,107 @@ func TestSplittingPublicPathEntryName(t *testing.T) {
 		},
 	})
 }
+
+func TestSplittingWorkerNewURL(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { shared } from './shared'
+				shared(new Worker(new URL('./worker.js', import.meta.url), { type: 'module' }))
+			`,
+			"/b.js": `
+				import { shared } from './shared'
+				shared(new Worker(new URL('./worker.js', import.meta.url), { type: 'module' }))
+			`,
+			"/shared.js": `
+				export function shared(x) { console.log(x) }
+			`,
+			"/worker.js": `
+				import { shared } from './shared'
+				import('./lazy').then(shared)
+			`,
+			"/lazy.js": `
+				export default 'lazy'
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingChunkPriority(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { shared } from './shared'
+				shared()
+				import('./lazy').then(({ lazy }) => lazy())
+			`,
+			"/b.js": `
+				import { shared } from './shared'
+				import { common } from './common'
+				shared(common)
+			`,
+			"/lazy.js": `
+				import { common } from './common'
+				export let lazy = () => common
+			`,
+			"/shared.js": `
+				import { deep } from './deep'
+				export let shared = x => deep(x)
+			`,
+			"/deep.js":   `export let deep = x => x`,
+			"/common.js": `export let common = 1`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			NeedsMetafile: true,
+			ManifestPath:  "manifest.json",
+		},
+	})
+}
+
+func TestSplittingBoundaryPackages(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { render } from 'ui'
+				import { helper } from '@scope/util'
+				render(helper())
+			`,
+			"/b.js": `
+				import { hydrate } from 'ui'
+				import { shared } from './shared'
+				hydrate(shared)
+			`,
+			"/shared.js": `
+				import { helper } from '@scope/util'
+				export let shared = helper()
+			`,
+			"/node_modules/ui/index.js": `
+				export { render } from './render'
+				export { hydrate } from './hydrate'
+			`,
+			"/node_modules/ui/render.js":         `export let render = x => console.log('render', x)`,
+			"/node_modules/ui/hydrate.js":        `export let hydrate = x => console.log('hydrate', x)`,
+			"/node_modules/@scope/util/index.js": `export let helper = () => 123`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			CodeSplitting:    true,
+			OutputFormat:     config.FormatESModule,
+			AbsOutputDir:     "/out",
+			BoundaryPackages: []string{"ui", "@scope/util"},
+		},
+	})
+}
@@ -57,6 +57,7 @@ type bundled struct {
 	expectedCompileLog string
 	options            config.Options
 	debugLogs          bool
+	graphOnly          bool
 }
 
 type suite struct {
@@ -104,9 +105,15 @@ func (s *suite) expectBundled(t *testing.T, args bundled) {
 			return
 		}
 
+		// Only the import graph is generated when linking is skipped
+		if args.graphOnly {
+			s.compareSnapshot(t, testName, bundle.GenerateImportGraphJSON(false))
+			return
+		}
+
 		log = logger.NewDeferLog(logKind, nil)
 		args.options.OmitRuntimeForTests = true
-		results, _ := bundle.Compile(log, args.options, nil, nil)
+		results, metafileJSON := bundle.Compile(log, args.options, nil, nil)
 		msgs = log.Done()
 		assertLog(t, msgs, args.expectedCompileLog)
 
@@ -127,6 +134,9 @@ func (s *suite) expectBundled(t *testing.T, args bundled) {
 				generated += fmt.Sprintf("---------- %s ----------\n%s", result.AbsPath, string(result.Contents))
 			}
 		}
+		if args.options.NeedsMetafile {
+			generated += fmt.Sprintf("\n---------- metafile.json ----------\n%s", metafileJSON)
+		}
 		s.compareSnapshot(t, testName, generated)
 	})
 }
@@ -305,6 +305,40 @@ func TestTSExportMissingES6(t *testing.T) {
 	})
 }
 
+func TestTSIsolatedModulesCheckReExportType(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import * as ns from './foo'
+				console.log(ns)
+			`,
+			"/foo.ts": `
+				import { Local } from './types'
+				export { Local }
+				export { Remote, value } from './types'
+				export type { Explicit } from './types'
+			`,
+			"/types.ts": `
+				export type Local = number
+				export interface Remote {}
+				export type Explicit = string
+				export let value = 123
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			TS:            config.TSOptions{IsolatedModulesCheck: true},
+		},
+		expectedCompileLog: `foo.ts: WARNING: Re-exporting the type "Local" requires using "export type" when compiling each file in isolation
+NOTE: There is no value called "Local", so it's assumed to be a type. A compiler that only looks at this file can't know that, so it will generate code that tries to re-export a value called "Local".
+foo.ts: WARNING: Re-exporting the type "Remote" requires using "export type" when compiling each file in isolation
+NOTE: There is no value called "Remote", so it's assumed to be a type. A compiler that only looks at this file can't know that, so it will generate code that tries to re-export a value called "Remote".
+`,
+	})
+}
+
 // It's an error to import from a file that does not exist
 func TestTSImportMissingFile(t *testing.T) {
 	ts_suite.expectBundled(t, bundled{
@@ -1969,3 +2003,39 @@ warning3.ts: NOTE: This file is considered to be an ECMAScript module because of
 `,
 	})
 }
+
+func TestTSDeclarations(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.ts": `
+				import type { Options } from './types'
+				import { helper } from './util'
+				export function run(options: Options): number {
+					return helper(options.count)
+				}
+				export let lazy = helper(1)
+			`,
+			"/src/types.ts": `
+				export interface Options {
+					count: number
+				}
+			`,
+			"/src/util.ts": `
+				export function helper(x: number): number {
+					return x * 2
+				}
+			`,
+			"/src/ambient.d.ts": `
+				declare let ambient: number
+			`,
+		},
+		entryPaths: []string{"/src/entry.ts"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputDir:   "/out",
+			TSDeclarations: true,
+		},
+		expectedScanLog: `src/entry.ts: WARNING: Using "any" for the type of "lazy" in the generated declaration file because it has no type annotation
+`,
+	})
+}
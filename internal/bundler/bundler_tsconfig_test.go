@@ -552,6 +552,34 @@ func TestTsConfigJSX(t *testing.T) {
 	})
 }
 
+func TestTsConfigReactJSX(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/entry.tsx": `
+				console.log(<><div/><div/></>)
+			`,
+			"/Users/user/project/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"jsx": "react-jsx",
+						"jsxImportSource": "notreact"
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/notreact/jsx-runtime.js": `
+				export function jsx() {}
+				export function jsxs() {}
+				export const Fragment = Symbol()
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/entry.tsx"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
 func TestTsConfigNestedJSX(t *testing.T) {
 	tsconfig_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -989,6 +1017,197 @@ func TestTsconfigJsonOverrideNodeModules(t *testing.T) {
 	})
 }
 
+func TestTsconfigJsonOverrideNested(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/entry.ts": `
+				import a from './packages/a/index'
+				import b from './packages/b/index'
+				import c from './packages/c/index'
+				console.log(a, b, c)
+			`,
+			"/Users/user/project/packages/a/index.tsx": `
+				import foo from 'foo'
+				export default <div>{foo}</div>
+			`,
+			"/Users/user/project/packages/a/foo-a.ts": `
+				export default 'a'
+			`,
+			"/Users/user/project/packages/a/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"jsxFactory": "A.createElement",
+						"baseUrl": ".",
+						"paths": {
+							"foo": ["./foo-a.ts"]
+						}
+					}
+				}
+			`,
+			"/Users/user/project/packages/b/index.tsx": `
+				import foo from 'foo'
+				export default <div>{foo}</div>
+			`,
+			"/Users/user/project/packages/b/foo-b.ts": `
+				export default 'b'
+			`,
+			"/Users/user/project/packages/b/tsconfig.json": `
+				{
+					"extends": "../../tsconfig.build.json",
+					"compilerOptions": {
+						"jsxFactory": "B.createElement",
+						"baseUrl": ".",
+						"paths": {
+							"foo": ["./foo-b.ts"]
+						}
+					}
+				}
+			`,
+			"/Users/user/project/packages/c/index.tsx": `
+				import foo from 'foo'
+				export default <div>{foo}</div>
+			`,
+			"/Users/user/project/foo-root.ts": `
+				export default 'root'
+			`,
+			"/Users/user/project/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"jsxFactory": "Bad.createElement"
+					}
+				}
+			`,
+			"/Users/user/project/tsconfig.build.json": `
+				{
+					"compilerOptions": {
+						"jsxFactory": "Root.createElement",
+						"baseUrl": ".",
+						"paths": {
+							"foo": ["./foo-root.ts"]
+						}
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/entry.ts"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			AbsOutputFile:    "/Users/user/project/out.js",
+			TsConfigOverride: "/Users/user/project/tsconfig.build.json",
+			TsConfigNested:   true,
+		},
+	})
+}
+
+func TestTsconfigProjectReferences(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/app/src/entry.ts": `
+				import { lib } from '../../lib/dist/index.js'
+				import { util } from '@shared/util'
+				console.log(lib, util)
+			`,
+			"/Users/user/project/app/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"paths": {
+							"@shared/*": ["../missing/*", "../shared/out/*"]
+						}
+					},
+					"references": [
+						{ "path": "../lib" },
+						{ "path": "../shared/tsconfig.shared.json" }
+					]
+				}
+			`,
+			"/Users/user/project/lib/src/index.ts": `
+				export let lib: string = 'lib'
+			`,
+			"/Users/user/project/lib/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"composite": true,
+						"rootDir": "src",
+						"outDir": "dist"
+					}
+				}
+			`,
+			"/Users/user/project/shared/util.ts": `
+				export let util: string = 'util'
+			`,
+			"/Users/user/project/shared/tsconfig.shared.json": `
+				{
+					"compilerOptions": {
+						"composite": true,
+						"outDir": "out"
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/app/src/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestTsconfigProjectReferencesMissing(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.ts": `
+				console.log(123)
+			`,
+			"/Users/user/project/tsconfig.json": `
+				{
+					"references": [
+						{ "path": "../missing" },
+						"../invalid"
+					]
+				}
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+		expectedScanLog: `Users/user/project/tsconfig.json: WARNING: Cannot find referenced project "Users/user/missing/tsconfig.json"
+Users/user/project/tsconfig.json: WARNING: Each project reference must be an object with a "path" property
+`,
+	})
+}
+
+func TestTsconfigRootDirs(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/views/entry.ts": `
+				import template from './template'
+				import other from '../other'
+				console.log(template, other)
+			`,
+			"/Users/user/project/generated/views/template.ts": `
+				export default 'template'
+			`,
+			"/Users/user/project/generated/other.ts": `
+				export default 'other'
+			`,
+			"/Users/user/project/tsconfig.json": `
+				{
+					"compilerOptions": {
+						"rootDirs": ["src", "generated"]
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/views/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
 func TestTsconfigJsonOverrideInvalid(t *testing.T) {
 	tsconfig_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1863,3 +2082,38 @@ func TestTsConfigAlwaysStrictTrueEmitDirectiveBundle(t *testing.T) {
 		},
 	})
 }
+
+func TestTsConfigEmitDecoratorMetadata(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.ts": `
+				import { Service } from './service'
+				import { Inject } from './inject'
+				@Inject()
+				export class Foo {
+					constructor(public service: Service, count: number) {}
+					@Inject() name: string
+					@Inject() method(x: Service): boolean { return true }
+				}
+			`,
+			"/Users/user/project/src/service.ts": `
+				export class Service {}
+			`,
+			"/Users/user/project/src/inject.ts": `
+				export const Inject = () => () => {}
+			`,
+			"/Users/user/project/tsconfig.json": `{
+				"compilerOptions": {
+					"experimentalDecorators": true,
+					"emitDecoratorMetadata": true
+				}
+			}`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
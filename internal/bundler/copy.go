@@ -0,0 +1,89 @@
+package bundler
+
+import (
+	"fmt"
+
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This reads the files and directories that should be copied into the output
+// directory as-is. It's done during the scan phase using the same file system
+// and cache as the other input files so that watch mode notices when a copied
+// file is added, changed, or removed.
+func (s *scanner) scanCopiedFiles() (results []graph.OutputFile) {
+	for _, item := range s.options.Copy {
+		absDest := s.fs.Join(s.options.AbsOutputDir, item.RelDest)
+		dir, base := s.fs.Dir(item.AbsSource), s.fs.Base(item.AbsSource)
+		entries, err, originalError := s.fs.ReadDirectory(dir)
+		if err == nil {
+			if entry, _ := entries.Get(base); entry != nil {
+				switch entry.Kind(s.fs) {
+				case fs.DirEntry:
+					results = s.scanCopiedDirectory(results, item.AbsSource, absDest)
+					continue
+
+				case fs.FileEntry:
+					results = s.scanCopiedFile(results, item.AbsSource, absDest)
+					continue
+				}
+			}
+		}
+		if originalError == nil {
+			originalError = fmt.Errorf("The path does not exist")
+		}
+		s.log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot copy %q: %s",
+			s.res.PrettyPath(logger.Path{Text: item.AbsSource, Namespace: "file"}), originalError.Error()))
+	}
+	return
+}
+
+// Symbolic links to directories are not followed since they could form a cycle
+func (s *scanner) scanCopiedDirectory(results []graph.OutputFile, absSource string, absDest string) []graph.OutputFile {
+	entries, _, originalError := s.fs.ReadDirectory(absSource)
+	if originalError != nil {
+		s.log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot copy %q: %s",
+			s.res.PrettyPath(logger.Path{Text: absSource, Namespace: "file"}), originalError.Error()))
+		return results
+	}
+	for _, name := range entries.SortedKeys() {
+		entry, _ := entries.Get(name)
+		switch entry.Kind(s.fs) {
+		case fs.DirEntry:
+			if entry.Symlink(s.fs) == "" {
+				results = s.scanCopiedDirectory(results, s.fs.Join(absSource, name), s.fs.Join(absDest, name))
+			}
+
+		case fs.FileEntry:
+			results = s.scanCopiedFile(results, s.fs.Join(absSource, name), s.fs.Join(absDest, name))
+		}
+	}
+	return results
+}
+
+func (s *scanner) scanCopiedFile(results []graph.OutputFile, absSource string, absDest string) []graph.OutputFile {
+	contents, _, originalError := s.caches.FSCache.ReadFile(s.fs, absSource)
+	if originalError != nil {
+		s.log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot copy %q: %s",
+			s.res.PrettyPath(logger.Path{Text: absSource, Namespace: "file"}), originalError.Error()))
+		return results
+	}
+
+	// Copied files aren't inputs to the bundle, so they have no inputs in the metafile
+	var jsonMetadataChunk string
+	if s.options.NeedsMetafile {
+		jsonMetadataChunk = fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"copiedFrom\": %s,\n      \"bytes\": %d\n    }",
+			js_printer.QuoteForJSON(s.res.PrettyPath(logger.Path{Text: absSource, Namespace: "file"}), s.options.ASCIIOnly),
+			len(contents),
+		)
+	}
+
+	return append(results, graph.OutputFile{
+		AbsPath:           absDest,
+		Contents:          []byte(contents),
+		JSONMetadataChunk: jsonMetadataChunk,
+	})
+}
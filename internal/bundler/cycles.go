@@ -0,0 +1,170 @@
+package bundler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type importEdge struct {
+	sourceIndex uint32
+	recordIndex uint32
+}
+
+// Only imports that are evaluated when the importing file is evaluated can
+// form a cycle. Dynamic imports and references to assets are left out since
+// they don't affect the order in which module code runs.
+func importCycleEdges(file *scannerFile) (edges []importEdge) {
+	if repr := file.inputFile.Repr; repr != nil {
+		if records := repr.ImportRecords(); records != nil {
+			for recordIndex, record := range *records {
+				if !record.SourceIndex.IsValid() {
+					continue
+				}
+				switch record.Kind {
+				case ast.ImportStmt, ast.ImportRequire, ast.ImportAt, ast.ImportAtConditional:
+					edges = append(edges, importEdge{sourceIndex: record.SourceIndex.GetIndex(), recordIndex: uint32(recordIndex)})
+				}
+			}
+		}
+	}
+	return
+}
+
+// This finds the strongly-connected components of the module graph using
+// Tarjan's algorithm and reports one import cycle for each of them. Cycles are
+// reported as debug messages by default since they are often harmless, but
+// they can be turned into warnings or errors with "--log-override:import-cycle=".
+func (s *scanner) reportImportCycles(files []scannerFile, entryPoints []graph.EntryPoint) {
+	s.timer.Begin("Check import cycles")
+	defer s.timer.End("Check import cycles")
+
+	edges := make([][]importEdge, len(files))
+	visitOrder := make([]int, len(files))
+	lowLink := make([]int, len(files))
+	onStack := make([]bool, len(files))
+	var stack []uint32
+	var roots []uint32
+	var components [][]uint32
+	nextVisit := 1
+
+	var visit func(sourceIndex uint32)
+	visit = func(sourceIndex uint32) {
+		visitOrder[sourceIndex] = nextVisit
+		lowLink[sourceIndex] = nextVisit
+		nextVisit++
+		stack = append(stack, sourceIndex)
+		onStack[sourceIndex] = true
+		edges[sourceIndex] = importCycleEdges(&files[sourceIndex])
+
+		for _, edge := range edges[sourceIndex] {
+			if visitOrder[edge.sourceIndex] == 0 {
+				visit(edge.sourceIndex)
+				if lowLink[edge.sourceIndex] < lowLink[sourceIndex] {
+					lowLink[sourceIndex] = lowLink[edge.sourceIndex]
+				}
+			} else if onStack[edge.sourceIndex] && visitOrder[edge.sourceIndex] < lowLink[sourceIndex] {
+				lowLink[sourceIndex] = visitOrder[edge.sourceIndex]
+			}
+		}
+
+		if lowLink[sourceIndex] == visitOrder[sourceIndex] {
+			var component []uint32
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == sourceIndex {
+					break
+				}
+			}
+			roots = append(roots, sourceIndex)
+			components = append(components, component)
+		}
+	}
+
+	for _, entryPoint := range entryPoints {
+		if visitOrder[entryPoint.SourceIndex] == 0 {
+			visit(entryPoint.SourceIndex)
+		}
+	}
+
+	// Report cycles in the order that their first file was reached for determinism
+	order := make([]int, len(roots))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return visitOrder[roots[order[a]]] < visitOrder[roots[order[b]]]
+	})
+
+	for _, i := range order {
+		root := roots[i]
+		inComponent := make(map[uint32]bool, len(components[i]))
+		for _, sourceIndex := range components[i] {
+			inComponent[sourceIndex] = true
+		}
+
+		// Find the shortest path from the root back to itself. A component with
+		// only one file is only a cycle if that file imports itself.
+		parents := make(map[uint32]importEdge)
+		queue := []uint32{root}
+		var closing importEdge
+		found := false
+	search:
+		for len(queue) > 0 {
+			from := queue[0]
+			queue = queue[1:]
+			for _, edge := range edges[from] {
+				if edge.sourceIndex == root {
+					closing = importEdge{sourceIndex: from, recordIndex: edge.recordIndex}
+					found = true
+					break search
+				}
+				if _, ok := parents[edge.sourceIndex]; !ok && inComponent[edge.sourceIndex] {
+					parents[edge.sourceIndex] = importEdge{sourceIndex: from, recordIndex: edge.recordIndex}
+					queue = append(queue, edge.sourceIndex)
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+
+		// Walk backward from the closing import to recover the chain of imports
+		chain := []importEdge{closing}
+		for current := closing.sourceIndex; current != root; {
+			parent := parents[current]
+			chain = append(chain, parent)
+			current = parent.sourceIndex
+		}
+		for a, b := 0, len(chain)-1; a < b; a, b = a+1, b-1 {
+			chain[a], chain[b] = chain[b], chain[a]
+		}
+
+		names := make([]string, 0, len(chain)+1)
+		var notes []logger.MsgData
+		for _, edge := range chain {
+			source := &files[edge.sourceIndex].inputFile.Source
+			record := &(*files[edge.sourceIndex].inputFile.Repr.ImportRecords())[edge.recordIndex]
+			names = append(names, source.PrettyPath)
+			if edge != closing {
+				tracker := logger.MakeLineColumnTracker(source)
+				notes = append(notes, tracker.MsgData(record.Range, fmt.Sprintf("The file %q imports %q here:",
+					source.PrettyPath, files[record.SourceIndex.GetIndex()].inputFile.Source.PrettyPath)))
+			}
+		}
+		names = append(names, files[root].inputFile.Source.PrettyPath)
+
+		source := &files[closing.sourceIndex].inputFile.Source
+		record := &(*files[closing.sourceIndex].inputFile.Repr.ImportRecords())[closing.recordIndex]
+		tracker := logger.MakeLineColumnTracker(source)
+		s.log.AddIDWithNotes(logger.MsgID_Bundler_ImportCycle, logger.Debug, &tracker, record.Range,
+			fmt.Sprintf("Import cycle detected: %s", strings.Join(names, " -> ")), notes)
+	}
+}
@@ -0,0 +1,148 @@
+package bundler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type packageCopy struct {
+	dir         string
+	version     string
+	sourceIndex uint32
+}
+
+// This warns when the same package is included in the bundle more than once
+// from different directories, which usually means that the package manager
+// installed two versions of it. Packages such as "react" or "core-js" often
+// break in subtle ways when this happens, and it also increases the size of
+// the bundle. Files are visited in breadth-first order from the entry points
+// so that the import chain reported for each copy is as short as possible.
+func (s *scanner) checkDuplicatePackages(files []scannerFile, entryPoints []graph.EntryPoint) {
+	s.timer.Begin("Check duplicate packages")
+	defer s.timer.End("Check duplicate packages")
+
+	type importer struct {
+		sourceIndex uint32
+		recordIndex uint32
+	}
+	importers := make(map[uint32]importer)
+	visited := make([]bool, len(files))
+	queue := make([]uint32, 0, len(files))
+	seenDirs := make(map[string]bool)
+	copiesByName := make(map[string][]packageCopy)
+	var names []string
+
+	for _, entryPoint := range entryPoints {
+		if !visited[entryPoint.SourceIndex] {
+			visited[entryPoint.SourceIndex] = true
+			queue = append(queue, entryPoint.SourceIndex)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		sourceIndex := queue[i]
+		file := &files[sourceIndex]
+
+		if keyPath := file.inputFile.Source.KeyPath; keyPath.Namespace == "file" && helpers.IsInsideNodeModules(keyPath.Text) {
+			if pkgDir, ok := s.packageDirForPath(keyPath.Text); ok && !seenDirs[pkgDir] {
+				seenDirs[pkgDir] = true
+				name, version := s.readPackageNameAndVersion(pkgDir)
+				if _, ok := copiesByName[name]; !ok {
+					names = append(names, name)
+				}
+				copiesByName[name] = append(copiesByName[name], packageCopy{dir: pkgDir, version: version, sourceIndex: sourceIndex})
+			}
+		}
+
+		if repr := file.inputFile.Repr; repr != nil {
+			if records := repr.ImportRecords(); records != nil {
+				for recordIndex, record := range *records {
+					if record.SourceIndex.IsValid() {
+						if other := record.SourceIndex.GetIndex(); !visited[other] {
+							visited[other] = true
+							importers[other] = importer{sourceIndex: sourceIndex, recordIndex: uint32(recordIndex)}
+							queue = append(queue, other)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Report packages in the order they were first reached for determinism
+	for _, name := range names {
+		copies := copiesByName[name]
+		if len(copies) < 2 {
+			continue
+		}
+
+		var notes []logger.MsgData
+		for _, pkg := range copies {
+			what := fmt.Sprintf("The copy in %q", s.res.PrettyPath(logger.Path{Text: pkg.dir, Namespace: "file"}))
+			if pkg.version != "" {
+				what += fmt.Sprintf(" (version %s)", pkg.version)
+			}
+
+			// Show where this copy was imported and how that file was reached
+			if parent, ok := importers[pkg.sourceIndex]; ok {
+				parentSource := &files[parent.sourceIndex].inputFile.Source
+				record := &(*files[parent.sourceIndex].inputFile.Repr.ImportRecords())[parent.recordIndex]
+				tracker := logger.MakeLineColumnTracker(parentSource)
+				notes = append(notes, tracker.MsgData(record.Range, what+" is imported here:"))
+			} else {
+				notes = append(notes, logger.MsgData{Text: what + " is an entry point."})
+			}
+			chain := []string{files[pkg.sourceIndex].inputFile.Source.PrettyPath}
+			for current, ok := importers[pkg.sourceIndex]; ok; current, ok = importers[current.sourceIndex] {
+				chain = append(chain, files[current.sourceIndex].inputFile.Source.PrettyPath)
+			}
+			for a, b := 0, len(chain)-1; a < b; a, b = a+1, b-1 {
+				chain[a], chain[b] = chain[b], chain[a]
+			}
+			notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The import chain is: %s", strings.Join(chain, " -> "))})
+		}
+
+		s.log.AddIDWithNotes(logger.MsgID_Bundler_DuplicatePackage, logger.Warning, nil, logger.Range{},
+			fmt.Sprintf("The package %q is included in the bundle %d times from different directories", name, len(copies)), notes)
+	}
+}
+
+// Packages are identified by the "name" field in "package.json", falling back
+// to the name of their directory inside "node_modules" if it's missing
+func (s *scanner) readPackageNameAndVersion(pkgDir string) (name string, version string) {
+	name = s.fs.Base(pkgDir)
+	if parent := s.fs.Dir(pkgDir); strings.HasPrefix(s.fs.Base(parent), "@") {
+		name = s.fs.Base(parent) + "/" + name
+	}
+
+	packageJSON := s.fs.Join(pkgDir, "package.json")
+	if contents, err, _ := s.caches.FSCache.ReadFile(s.fs, packageJSON); err == nil {
+		path := logger.Path{Text: packageJSON, Namespace: "file"}
+		source := logger.Source{KeyPath: path, PrettyPath: s.res.PrettyPath(path), Contents: contents}
+		if json, ok := js_parser.ParseJSON(logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil), source, js_parser.JSONOptions{}); ok {
+			if obj, ok := json.Data.(*js_ast.EObject); ok {
+				for _, prop := range obj.Properties {
+					key, ok := prop.Key.Data.(*js_ast.EString)
+					if !ok {
+						continue
+					}
+					if str, ok := prop.ValueOrNil.Data.(*js_ast.EString); ok && len(str.Value) > 0 {
+						switch helpers.UTF16ToString(key.Value) {
+						case "name":
+							name = helpers.UTF16ToString(str.Value)
+						case "version":
+							version = helpers.UTF16ToString(str.Value)
+						}
+					}
+				}
+			}
+		}
+	}
+	return
+}
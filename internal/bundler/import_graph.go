@@ -0,0 +1,105 @@
+package bundler
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// This describes the import graph found by the scan phase as JSON. It's used
+// instead of the metafile when only the graph is needed, since it doesn't
+// require linking or printing anything. Unlike the metafile, imports of
+// external modules are included and are marked with "external": true.
+func (b *Bundle) GenerateImportGraphJSON(asciiOnly bool) string {
+	files := make([]graph.InputFile, len(b.files))
+	for i, file := range b.files {
+		files[i] = file.inputFile
+	}
+	isEntryPoint := make(map[uint32]bool, len(b.entryPoints))
+	for _, entryPoint := range b.entryPoints {
+		isEntryPoint[entryPoint.SourceIndex] = true
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("{\n  \"inputs\": {")
+	isFirst := true
+
+	for _, sourceIndex := range findReachableFiles(files, b.entryPoints) {
+		file := &files[sourceIndex]
+
+		// Skip the runtime and the JavaScript stubs that are generated for CSS
+		// files, since neither of them were imported by name
+		if sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		if repr, ok := file.Repr.(*graph.JSRepr); ok && repr.CSSSourceIndex.IsValid() {
+			continue
+		}
+
+		if isFirst {
+			isFirst = false
+			sb.WriteString("\n    ")
+		} else {
+			sb.WriteString(",\n    ")
+		}
+		sb.Write(js_printer.QuoteForJSON(file.Source.PrettyPath, asciiOnly))
+		sb.WriteString(": {\n      \"loader\": ")
+		sb.Write(js_printer.QuoteForJSON(config.LoaderToString[file.Loader], asciiOnly))
+		if isEntryPoint[sourceIndex] {
+			sb.WriteString(",\n      \"entryPoint\": true")
+		}
+		sb.WriteString(",\n      \"imports\": [")
+
+		isFirstImport := true
+		if recordsPtr := file.Repr.ImportRecords(); recordsPtr != nil {
+			for _, record := range *recordsPtr {
+				if record.Flags.Has(ast.IsUnused) {
+					continue
+				}
+
+				// Imports that weren't bundled are external. The path of an external
+				// import is the path that will be used at run time.
+				var otherPath string
+				isExternal := false
+				if record.SourceIndex.IsValid() {
+					otherPath = files[record.SourceIndex.GetIndex()].Source.PrettyPath
+				} else if record.CopySourceIndex.IsValid() {
+					otherPath = files[record.CopySourceIndex.GetIndex()].Source.PrettyPath
+				} else {
+					otherPath = record.Path.Text
+					isExternal = true
+				}
+
+				if isFirstImport {
+					isFirstImport = false
+					sb.WriteString("\n        ")
+				} else {
+					sb.WriteString(",\n        ")
+				}
+				sb.WriteString("{\n          \"path\": ")
+				sb.Write(js_printer.QuoteForJSON(otherPath, asciiOnly))
+				sb.WriteString(",\n          \"kind\": ")
+				sb.Write(js_printer.QuoteForJSON(record.Kind.StringForMetafile(), asciiOnly))
+				if isExternal {
+					sb.WriteString(",\n          \"external\": true")
+				} else {
+					sb.WriteString(",\n          \"original\": ")
+					sb.Write(js_printer.QuoteForJSON(record.Path.Text, asciiOnly))
+				}
+				sb.WriteString("\n        }")
+			}
+		}
+
+		if !isFirstImport {
+			sb.WriteString("\n      ")
+		}
+		sb.WriteString("]\n    }")
+	}
+
+	sb.WriteString("\n  }\n}\n")
+	return sb.String()
+}
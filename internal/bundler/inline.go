@@ -0,0 +1,85 @@
+package bundler
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Importing a file with one of these suffixes bundles that file separately
+// like a worker and evaluates to the output, either as a string of JavaScript
+// code or as a "data:" URL:
+//
+//   import workerCode from './worker.js?inline-text'
+//   import workerURL from './worker.js?inline-url'
+//
+const (
+	inlineTextSuffix = "?inline-text"
+	inlineURLSuffix  = "?inline-url"
+)
+
+// Inlined code is duplicated into every file that imports it, so warn when
+// it's bigger than this
+const inlineWorkerSizeWarningThreshold = 100 * 1024
+
+func isInlineSuffix(suffix string) bool {
+	return suffix == inlineTextSuffix || suffix == inlineURLSuffix
+}
+
+// The generated module exports a string that refers to the file as a worker.
+// The file is linked by itself before the files that import this module and
+// the string is replaced with the worker's code at the end.
+func parseInlineModule(log logger.Log, fs fs.FS, source logger.Source, options *config.Options) js_ast.AST {
+	flags := ast.IsWorkerURL | ast.IsInlineWorkerDataURL
+	if source.KeyPath.IgnoredSuffix == inlineTextSuffix {
+		flags = ast.IsWorkerURL | ast.IsInlineWorkerText
+	}
+	expr := js_ast.Expr{Data: &js_ast.ENewURLString{ImportRecordIndex: 0}}
+	tree := js_parser.LazyExportAST(log, source, js_parser.OptionsFromConfig(options), expr, "")
+	tree.ImportRecords = append(tree.ImportRecords, ast.ImportRecord{
+		Kind:  ast.ImportNewURL,
+		Path:  logger.Path{Text: "./" + fs.Base(source.KeyPath.Text)},
+		Flags: flags,
+	})
+	tree.Parts[1].ImportRecordIndices = append(tree.Parts[1].ImportRecordIndices, 0)
+	return tree
+}
+
+// This returns the set of workers that are inlined into any of the given files
+func findInlinedWorkers(files []graph.InputFile, sourceIndices []uint32) map[uint32]bool {
+	inlined := make(map[uint32]bool)
+	for _, sourceIndex := range sourceIndices {
+		if repr, ok := files[sourceIndex].Repr.(*graph.JSRepr); ok {
+			for _, record := range repr.AST.ImportRecords {
+				if record.Flags.Has(ast.IsInlineWorkerText|ast.IsInlineWorkerDataURL) && record.SourceIndex.IsValid() {
+					inlined[record.SourceIndex.GetIndex()] = true
+				}
+			}
+		}
+	}
+	return inlined
+}
+
+// The inlined code replaces the contents of a double-quoted string literal
+func inlineWorkerString(contents []byte, asDataURL bool, asciiOnly bool) string {
+	if asDataURL {
+		return "data:text/javascript;base64," + base64.StdEncoding.EncodeToString(contents)
+	}
+	quoted := js_printer.QuoteForJSON(string(contents), asciiOnly)
+	return string(quoted[1 : len(quoted)-1])
+}
+
+func warnAboutLargeInlineWorker(log logger.Log, file *graph.InputFile) {
+	if n := len(file.InlineWorkerContents); n > inlineWorkerSizeWarningThreshold {
+		log.AddID(logger.MsgID_Bundler_LargeInlineWorker, logger.Warning, nil, logger.Range{},
+			fmt.Sprintf("The inlined worker %q is %.1fkb, which is added to every file that imports it", file.Source.PrettyPath, float64(n)/1024))
+	}
+}
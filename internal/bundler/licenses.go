@@ -0,0 +1,334 @@
+package bundler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Packages that don't declare a license in "package.json" often still have a
+// license file. These are the phrases used to recognize common licenses from
+// the text of that file. More specific phrases must come first.
+var licenseFileSignatures = []struct {
+	phrase  string
+	license string
+}{
+	{"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE", "LGPL"},
+	{"GNU LIBRARY GENERAL PUBLIC LICENSE", "LGPL"},
+	{"GNU GENERAL PUBLIC LICENSE", "GPL"},
+	{"Mozilla Public License Version 2.0", "MPL-2.0"},
+	{"Eclipse Public License", "EPL"},
+	{"Server Side Public License", "SSPL-1.0"},
+	{"Apache License", "Apache-2.0"},
+	{"Permission is hereby granted, free of charge", "MIT"},
+	{"Permission to use, copy, modify, and/or distribute this software for any", "ISC"},
+	{"Redistribution and use in source and binary forms", "BSD"},
+}
+
+func licenseFromText(text string) string {
+	for _, signature := range licenseFileSignatures {
+		i := strings.Index(text, signature.phrase)
+		if i == -1 {
+			continue
+		}
+
+		// The GNU licenses state their version right after their title
+		license := signature.license
+		if strings.HasPrefix(signature.phrase, "GNU ") {
+			after := text[i+len(signature.phrase):]
+			if len(after) > 100 {
+				after = after[:100]
+			}
+			if strings.Contains(after, "Version 3") {
+				license += "-3.0"
+			} else if strings.Contains(after, "Version 2.1") {
+				license += "-2.1"
+			} else if strings.Contains(after, "Version 2") {
+				license += "-2.0"
+			}
+		}
+		return license
+	}
+	return ""
+}
+
+type packageLicense struct {
+	name       string
+	license    string
+	sourcePath logger.Path
+}
+
+// This checks the license of every package in the bundle against the list of
+// disallowed licenses. Packages are visited in breadth-first order from the
+// entry points so that the import chain reported for each package is as short
+// as possible.
+func (s *scanner) checkDisallowedLicenses(files []scannerFile, entryPoints []graph.EntryPoint) {
+	s.timer.Begin("Check licenses")
+	defer s.timer.End("Check licenses")
+
+	type importer struct {
+		sourceIndex uint32
+		recordIndex uint32
+	}
+	importers := make(map[uint32]importer)
+	visited := make([]bool, len(files))
+	queue := make([]uint32, 0, len(files))
+	checkedPackages := make(map[string]bool)
+
+	for _, entryPoint := range entryPoints {
+		if !visited[entryPoint.SourceIndex] {
+			visited[entryPoint.SourceIndex] = true
+			queue = append(queue, entryPoint.SourceIndex)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		sourceIndex := queue[i]
+		file := &files[sourceIndex]
+
+		if keyPath := file.inputFile.Source.KeyPath; keyPath.Namespace == "file" && helpers.IsInsideNodeModules(keyPath.Text) {
+			if pkgDir, ok := s.packageDirForPath(keyPath.Text); ok && !checkedPackages[pkgDir] {
+				checkedPackages[pkgDir] = true
+				if pkg, ok := s.readPackageLicense(pkgDir); ok && isLicenseDisallowed(pkg.license, s.options.DisallowedLicenses) {
+					var notes []logger.MsgData
+					notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The license was read from %q.", s.res.PrettyPath(pkg.sourcePath))})
+
+					// Show where the package was imported and how that file was reached
+					if parent, ok := importers[sourceIndex]; ok {
+						parentSource := &files[parent.sourceIndex].inputFile.Source
+						record := &(*files[parent.sourceIndex].inputFile.Repr.ImportRecords())[parent.recordIndex]
+						tracker := logger.MakeLineColumnTracker(parentSource)
+						notes = append(notes, tracker.MsgData(record.Range, fmt.Sprintf("The package %q is imported here:", pkg.name)))
+					}
+					chain := []string{file.inputFile.Source.PrettyPath}
+					for current, ok := importers[sourceIndex]; ok; current, ok = importers[current.sourceIndex] {
+						chain = append(chain, files[current.sourceIndex].inputFile.Source.PrettyPath)
+					}
+					for a, b := 0, len(chain)-1; a < b; a, b = a+1, b-1 {
+						chain[a], chain[b] = chain[b], chain[a]
+					}
+					notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The import chain is: %s", strings.Join(chain, " -> "))})
+
+					s.log.AddIDWithNotes(logger.MsgID_Bundler_DisallowedLicense, logger.Warning, nil, logger.Range{},
+						fmt.Sprintf("The package %q has the disallowed license %q", pkg.name, pkg.license), notes)
+				}
+			}
+		}
+
+		if repr := file.inputFile.Repr; repr != nil {
+			if records := repr.ImportRecords(); records != nil {
+				for recordIndex, record := range *records {
+					if record.SourceIndex.IsValid() {
+						if other := record.SourceIndex.GetIndex(); !visited[other] {
+							visited[other] = true
+							importers[other] = importer{sourceIndex: sourceIndex, recordIndex: uint32(recordIndex)}
+							queue = append(queue, other)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Returns the directory of the package inside "node_modules" that contains
+// this path (e.g. "node_modules/@scope/pkg" for "node_modules/@scope/pkg/x.js")
+func (s *scanner) packageDirForPath(path string) (string, bool) {
+	dir := s.fs.Dir(path)
+	for {
+		parent := s.fs.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		if s.fs.Base(parent) == "node_modules" {
+			return dir, true
+		}
+		if grandparent := s.fs.Dir(parent); strings.HasPrefix(s.fs.Base(parent), "@") && s.fs.Base(grandparent) == "node_modules" {
+			return dir, true
+		}
+		dir = parent
+	}
+}
+
+func (s *scanner) readPackageLicense(pkgDir string) (packageLicense, bool) {
+	pkg := packageLicense{name: s.fs.Base(pkgDir)}
+	if parent := s.fs.Dir(pkgDir); strings.HasPrefix(s.fs.Base(parent), "@") {
+		pkg.name = s.fs.Base(parent) + "/" + pkg.name
+	}
+
+	// Prefer the "license" field in "package.json"
+	packageJSON := s.fs.Join(pkgDir, "package.json")
+	if contents, err, _ := s.caches.FSCache.ReadFile(s.fs, packageJSON); err == nil {
+		path := logger.Path{Text: packageJSON, Namespace: "file"}
+		source := logger.Source{KeyPath: path, PrettyPath: s.res.PrettyPath(path), Contents: contents}
+		if json, ok := js_parser.ParseJSON(logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil), source, js_parser.JSONOptions{}); ok {
+			if obj, ok := json.Data.(*js_ast.EObject); ok {
+				for _, prop := range obj.Properties {
+					if key, ok := prop.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(key.Value, "name") {
+						if str, ok := prop.ValueOrNil.Data.(*js_ast.EString); ok && len(str.Value) > 0 {
+							pkg.name = helpers.UTF16ToString(str.Value)
+						}
+					}
+				}
+				if license := licenseFromPackageJSON(obj); license != "" {
+					pkg.license = license
+					pkg.sourcePath = path
+					return pkg, true
+				}
+			}
+		}
+	}
+
+	// Otherwise, try to recognize the text of a license file
+	if entries, err, _ := s.fs.ReadDirectory(pkgDir); err == nil {
+		for _, base := range entries.SortedKeys() {
+			lower := strings.ToLower(base)
+			if !strings.HasPrefix(lower, "license") && !strings.HasPrefix(lower, "licence") && !strings.HasPrefix(lower, "copying") {
+				continue
+			}
+			absPath := s.fs.Join(pkgDir, base)
+			if contents, err, _ := s.caches.FSCache.ReadFile(s.fs, absPath); err == nil {
+				if license := licenseFromText(contents); license != "" {
+					pkg.license = license
+					pkg.sourcePath = logger.Path{Text: absPath, Namespace: "file"}
+					return pkg, true
+				}
+			}
+		}
+	}
+
+	return packageLicense{}, false
+}
+
+// Handles "license": "MIT", the deprecated "license": { "type": "MIT" }, and
+// the deprecated "licenses": [{ "type": "MIT" }, { "type": "Apache-2.0" }]
+func licenseFromPackageJSON(obj *js_ast.EObject) string {
+	typeOf := func(value js_ast.Expr) string {
+		switch e := value.Data.(type) {
+		case *js_ast.EString:
+			return helpers.UTF16ToString(e.Value)
+		case *js_ast.EObject:
+			for _, prop := range e.Properties {
+				if key, ok := prop.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(key.Value, "type") {
+					if str, ok := prop.ValueOrNil.Data.(*js_ast.EString); ok {
+						return helpers.UTF16ToString(str.Value)
+					}
+				}
+			}
+		}
+		return ""
+	}
+
+	for _, prop := range obj.Properties {
+		key, ok := prop.Key.Data.(*js_ast.EString)
+		if !ok {
+			continue
+		}
+		switch helpers.UTF16ToString(key.Value) {
+		case "license":
+			if license := typeOf(prop.ValueOrNil); license != "" {
+				return license
+			}
+
+		case "licenses":
+			if array, ok := prop.ValueOrNil.Data.(*js_ast.EArray); ok {
+				var licenses []string
+				for _, item := range array.Items {
+					if license := typeOf(item); license != "" {
+						licenses = append(licenses, license)
+					}
+				}
+				if len(licenses) == 1 {
+					return licenses[0]
+				}
+				if len(licenses) > 1 {
+					return "(" + strings.Join(licenses, " OR ") + ")"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// This evaluates an SPDX license expression such as "(MIT OR GPL-3.0-only)".
+// An expression is disallowed if every alternative separated by "OR" contains
+// a disallowed license, since otherwise the package can be used under one of
+// the allowed alternatives.
+func isLicenseDisallowed(expression string, disallowed []string) bool {
+	expression = strings.ReplaceAll(expression, "(", " ( ")
+	expression = strings.ReplaceAll(expression, ")", " ) ")
+	tokens := strings.Fields(expression)
+	if len(tokens) == 0 {
+		return false
+	}
+
+	var parseOr func() bool
+	parseAtom := func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		token := tokens[0]
+		tokens = tokens[1:]
+		if token == "(" {
+			result := parseOr()
+			if len(tokens) > 0 && tokens[0] == ")" {
+				tokens = tokens[1:]
+			}
+			return result
+		}
+
+		// Ignore license exceptions (e.g. "GPL-2.0 WITH Classpath-exception-2.0")
+		if len(tokens) > 1 && strings.EqualFold(tokens[0], "WITH") {
+			tokens = tokens[2:]
+		}
+		return licenseMatchesAny(token, disallowed)
+	}
+	parseAnd := func() bool {
+		result := parseAtom()
+		for len(tokens) > 0 && strings.EqualFold(tokens[0], "AND") {
+			tokens = tokens[1:]
+			if parseAtom() {
+				result = true
+			}
+		}
+		return result
+	}
+	parseOr = func() bool {
+		result := parseAnd()
+		for len(tokens) > 0 && strings.EqualFold(tokens[0], "OR") {
+			tokens = tokens[1:]
+			if !parseAnd() {
+				result = false
+			}
+		}
+		return result
+	}
+	return parseOr()
+}
+
+// Patterns are case-insensitive and may contain "*" wildcards. A pattern
+// without a version suffix also matches the "-only", "-or-later", and "+"
+// variants of a license (e.g. "GPL-3.0" matches "GPL-3.0-or-later").
+func licenseMatchesAny(license string, patterns []string) bool {
+	license = strings.ToLower(license)
+	base := license
+	for _, suffix := range []string{"-only", "-or-later", "+"} {
+		if strings.HasSuffix(base, suffix) {
+			base = base[:len(base)-len(suffix)]
+			break
+		}
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if helpers.GlobMatchSegment(pattern, license) || helpers.GlobMatchSegment(pattern, base) {
+			return true
+		}
+	}
+	return false
+}
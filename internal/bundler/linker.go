@@ -2,14 +2,17 @@ package bundler
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"fmt"
 	"hash"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/compat"
@@ -21,6 +24,7 @@ import (
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_parser"
 	"github.com/evanw/esbuild/internal/js_printer"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/renamer"
@@ -46,6 +50,10 @@ type linkerContext struct {
 	// is shared between threads and must be treated as immutable.
 	dataForSourceMaps func() []dataForSourceMap
 
+	// In low-memory mode, the code printed for each file is written here until
+	// the output file that contains it is generated. This is nil otherwise.
+	spill *spillFile
+
 	// This is passed to us from the bundling phase
 	uniqueKeyPrefix      string
 	uniqueKeyPrefixBytes []byte // This is just "uniqueKeyPrefix" in byte form
@@ -56,6 +64,31 @@ type linkerContext struct {
 	// We may need to refer to the "__esm" and/or "__commonJS" runtime symbols
 	cjsRuntimeRef js_ast.Ref
 	esmRuntimeRef js_ast.Ref
+
+	// This maps each original property name to its mangled name. It's only
+	// populated when property mangling is active and is used for name maps.
+	mangledPropNames map[string]string
+
+	// This maps the source index of each worker constructed by this bundle to
+	// the path of the worker's output file, which was linked separately
+	absWorkerOutputPaths map[uint32]string
+
+	// This maps the source index of each worker inlined into this bundle to the
+	// contents of the worker's output file, which was linked separately
+	inlineWorkerContents map[uint32][]byte
+
+	// This is the set of words in the bundled JavaScript and HTML files. It's
+	// only populated when purging unused CSS rules is active.
+	purgeCSSNames map[string]bool
+
+	// These are only used when generating a tree shaking report. Each live part
+	// is either a root (kept for the reason given) or has a parent (the part
+	// that caused it to be kept). Files also have a parent unless they are an
+	// entry point, which explains why the roots in that file were included.
+	treeShakingReport      *treeShakingReport
+	treeShakingParents     map[js_ast.Dependency]js_ast.Dependency
+	treeShakingRoots       map[js_ast.Dependency]string
+	treeShakingFileParents map[uint32]js_ast.Dependency
 }
 
 type partRange struct {
@@ -75,6 +108,22 @@ type chunkInfo struct {
 	// For code splitting
 	crossChunkImports []chunkImport
 
+	// If non-empty, this chunk contains the code for this boundary package
+	boundaryPackage string
+
+	// This is the substitution for "[name]" in the output path template. It's
+	// also available to banners and footers.
+	name string
+
+	// This is only set if the final hash was needed, either for the output path
+	// or because the banner or footer contains "[hash]"
+	finalHash              string
+	isHashInBannerOrFooter bool
+
+	// This is only set if debug IDs are enabled. It's derived from the final
+	// hash and the final path so it's stable across identical builds.
+	debugID string
+
 	// This is the representation-specific information
 	chunkRepr chunkRepr
 
@@ -89,6 +138,16 @@ type chunkInfo struct {
 	// If non-empty, this chunk needs to generate an external legal comments file.
 	externalLegalComments []byte
 
+	// These are all legal comments extracted from this chunk, along with the
+	// input files they came from
+	legalComments []graph.LegalComment
+
+	// If non-empty, this chunk needs to generate a name map file.
+	nameMap []byte
+
+	// If non-empty, this chunk needs to generate a concatenation report file.
+	concatReport []byte
+
 	// This contains the hash for just this chunk without including information
 	// from the hashes of other chunks. Later on in the linking process, the
 	// final hash for this chunk will be constructed by merging the isolated
@@ -110,6 +169,12 @@ type chunkInfo struct {
 	sourceIndex   uint32 // An index into "c.sources"
 	isEntryPoint  bool
 
+	// These are only computed when code splitting is active. See the function
+	// "computeChunkPriorities" for details.
+	isCritical    bool
+	preloadRank   int
+	preloadChunks []uint32
+
 	isExecutable bool
 }
 
@@ -124,6 +189,10 @@ const (
 	outputPieceNone outputPieceIndexKind = iota
 	outputPieceAssetIndex
 	outputPieceChunkIndex
+	outputPieceWorkerIndex
+	outputPieceInlineWorkerTextIndex
+	outputPieceInlineWorkerDataURLIndex
+	outputPieceHashIndex
 )
 
 // This is a chunk of source code followed by a reference to another chunk. For
@@ -217,9 +286,16 @@ func link(
 	uniqueKeyPrefix string,
 	reachableFiles []uint32,
 	dataForSourceMaps func() []dataForSourceMap,
+	spill *spillFile,
+	treeShakingReport *treeShakingReport,
 ) []graph.OutputFile {
 	timer.Begin("Link")
 	defer timer.End("Link")
+	linkStart := time.Now()
+
+	if options.CancelFlag.DidCancel() {
+		return []graph.OutputFile{}
+	}
 
 	log = wrappedLog(log)
 
@@ -231,6 +307,8 @@ func link(
 		fs:                   fs,
 		res:                  res,
 		dataForSourceMaps:    dataForSourceMaps,
+		spill:                spill,
+		treeShakingReport:    treeShakingReport,
 		uniqueKeyPrefix:      uniqueKeyPrefix,
 		uniqueKeyPrefixBytes: []byte(uniqueKeyPrefix),
 		graph: graph.CloneLinkerGraph(
@@ -242,6 +320,12 @@ func link(
 	}
 	timer.End("Clone linker graph")
 
+	if c.options.PurgeCSS {
+		timer.Begin("Find purge CSS names")
+		c.purgeCSSNames = findPurgeCSSNames(inputFiles, reachableFiles)
+		timer.End("Find purge CSS names")
+	}
+
 	// Use a smaller version of these functions if we don't need profiler names
 	runtimeRepr := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr)
 	if c.options.ProfilerNames {
@@ -284,6 +368,38 @@ func link(
 		}
 	}
 
+	// Workers are linked before the files that construct them. Replace each
+	// reference to a worker with a unique key that is substituted with the
+	// path to the worker's output file at the end, and remove the dependency
+	// so that the worker's code isn't included in this output file.
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok {
+			for i := range repr.AST.ImportRecords {
+				if record := &repr.AST.ImportRecords[i]; record.Flags.Has(ast.IsWorkerURL) && record.SourceIndex.IsValid() {
+					workerSourceIndex := record.SourceIndex.GetIndex()
+					if record.Flags.Has(ast.IsInlineWorkerText | ast.IsInlineWorkerDataURL) {
+						if c.inlineWorkerContents == nil {
+							c.inlineWorkerContents = make(map[uint32][]byte)
+						}
+						c.inlineWorkerContents[workerSourceIndex] = inputFiles[workerSourceIndex].InlineWorkerContents
+						kind := 'U'
+						if record.Flags.Has(ast.IsInlineWorkerText) {
+							kind = 'T'
+						}
+						record.Path.Text = fmt.Sprintf("%s%c%08d", c.uniqueKeyPrefix, kind, workerSourceIndex)
+					} else {
+						if c.absWorkerOutputPaths == nil {
+							c.absWorkerOutputPaths = make(map[uint32]string)
+						}
+						c.absWorkerOutputPaths[workerSourceIndex] = inputFiles[workerSourceIndex].AbsWorkerOutputPath
+						record.Path.Text = fmt.Sprintf("%sW%08d", c.uniqueKeyPrefix, workerSourceIndex)
+					}
+					record.SourceIndex = ast.Index32{}
+				}
+			}
+		}
+	}
+
 	// Allocate a new unbound symbol called "module" in case we need it later
 	if c.options.OutputFormat == config.FormatCommonJS {
 		c.unboundModuleRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, js_ast.SymbolUnbound, "module")
@@ -293,13 +409,30 @@ func link(
 
 	c.scanImportsAndExports()
 
-	// Stop now if there were errors
-	if c.log.HasErrors() {
+	// Stop now if there were errors or if the build was cancelled
+	if c.log.HasErrors() || c.options.CancelFlag.DidCancel() {
 		return []graph.OutputFile{}
 	}
 
+	if c.treeShakingReport != nil {
+		c.treeShakingParents = make(map[js_ast.Dependency]js_ast.Dependency)
+		c.treeShakingRoots = make(map[js_ast.Dependency]string)
+		c.treeShakingFileParents = make(map[uint32]js_ast.Dependency)
+	}
+
 	c.treeShakingAndCodeSplitting()
 
+	// Each of the remaining phases can take a while for large builds
+	if c.options.CancelFlag.DidCancel() {
+		return []graph.OutputFile{}
+	}
+
+	if c.treeShakingReport != nil {
+		c.timer.Begin("Add to tree shaking report")
+		c.addToTreeShakingReport()
+		c.timer.End("Add to tree shaking report")
+	}
+
 	if c.options.Mode == config.ModePassThrough {
 		for _, entryPoint := range c.graph.EntryPoints() {
 			c.preventExportsFromBeingRenamed(entryPoint.SourceIndex)
@@ -308,6 +441,12 @@ func link(
 
 	chunks := c.computeChunks()
 	c.computeCrossChunkDependencies(chunks)
+	if c.options.CodeSplitting {
+		c.computeChunkPriorities(chunks)
+	}
+	if c.options.CancelFlag.DidCancel() {
+		return []graph.OutputFile{}
+	}
 
 	// Merge mangled properties before chunks are generated since the names must
 	// be consistent across all chunks, or the generated code will break
@@ -323,6 +462,15 @@ func link(
 	// won't hit concurrent map mutation hazards
 	js_ast.FollowAllSymbols(c.graph.Symbols)
 
+	// Everything after this point counts as printing instead of linking
+	if c.options.Timing != nil {
+		c.options.Timing.AddLink(time.Since(linkStart))
+		printStart := time.Now()
+		defer func() {
+			c.options.Timing.AddPrint(time.Since(printStart))
+		}()
+	}
+
 	return c.generateChunksInParallel(chunks, additionalFiles)
 }
 
@@ -392,12 +540,14 @@ func (c *linkerContext) mangleProps(mangleCache map[string]interface{}) {
 	// Assign names in order of use count
 	minifier := freq.Compile()
 	nextName := 0
+	c.mangledPropNames = make(map[string]string, len(sorted))
 	for _, symbolCount := range sorted {
 		symbol := c.graph.Symbols.Get(symbolCount.Ref)
 
 		// Don't change existing mappings
 		if existing, ok := mangleCache[symbol.OriginalName]; ok {
 			if existing != false {
+				c.mangledPropNames[symbol.OriginalName] = existing.(string)
 				symbol.OriginalName = existing.(string)
 			}
 			continue
@@ -417,6 +567,7 @@ func (c *linkerContext) mangleProps(mangleCache map[string]interface{}) {
 		if mangleCache != nil {
 			mangleCache[symbol.OriginalName] = name
 		}
+		c.mangledPropNames[symbol.OriginalName] = name
 		symbol.OriginalName = name
 	}
 }
@@ -462,20 +613,36 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo, additionalF
 	c.timer.Begin("Generate chunks")
 	defer c.timer.End("Generate chunks")
 
-	// Generate each chunk on a separate goroutine
+	// Generate each chunk on a separate goroutine. In low-memory mode, chunks
+	// are generated one at a time instead so that only one chunk's worth of
+	// intermediate data is in memory at once. Files within each chunk are still
+	// printed in parallel.
 	generateWaitGroup := sync.WaitGroup{}
 	generateWaitGroup.Add(len(chunks))
 	for chunkIndex := range chunks {
 		switch chunks[chunkIndex].chunkRepr.(type) {
 		case *chunkReprJS:
-			go c.generateChunkJS(chunks, chunkIndex, &generateWaitGroup)
+			if c.options.LowMemory {
+				c.generateChunkJS(chunks, chunkIndex, &generateWaitGroup)
+			} else {
+				go c.generateChunkJS(chunks, chunkIndex, &generateWaitGroup)
+			}
 		case *chunkReprCSS:
-			go c.generateChunkCSS(chunks, chunkIndex, &generateWaitGroup)
+			if c.options.LowMemory {
+				c.generateChunkCSS(chunks, chunkIndex, &generateWaitGroup)
+			} else {
+				go c.generateChunkCSS(chunks, chunkIndex, &generateWaitGroup)
+			}
 		}
 	}
 	c.enforceNoCyclicChunkImports(chunks)
 	generateWaitGroup.Wait()
 
+	// The output of a cancelled build is thrown away
+	if c.options.CancelFlag.DidCancel() {
+		return []graph.OutputFile{}
+	}
+
 	// Compute the final hashes of each chunk. This can technically be done in
 	// parallel but it probably doesn't matter so much because we're not hashing
 	// that much data.
@@ -486,19 +653,26 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo, additionalF
 		var hashSubstitution *string
 
 		// Only wait for the hash if necessary
-		if config.HasPlaceholder(chunk.finalTemplate, config.HashPlaceholder) {
+		needsHash := config.HasPlaceholder(chunk.finalTemplate, config.HashPlaceholder) || chunk.isHashInBannerOrFooter
+		if needsHash || c.options.DebugID {
 			// Compute the final hash using the isolated hashes of the dependencies
 			hash := xxhash.New()
 			c.appendIsolatedHashesForImportedChunks(hash, chunks, uint32(chunkIndex), visited, ^uint32(chunkIndex))
 			finalBytes = hash.Sum(finalBytes[:0])
-			finalString := hashForFileName(finalBytes)
-			hashSubstitution = &finalString
+			if needsHash {
+				chunk.finalHash = hashForFileName(finalBytes)
+				hashSubstitution = &chunk.finalHash
+			}
 		}
 
 		// Render the last remaining placeholder in the template
 		chunk.finalRelPath = config.TemplateToString(config.SubstituteTemplate(chunk.finalTemplate, config.PathPlaceholders{
 			Hash: hashSubstitution,
 		}))
+
+		if c.options.DebugID {
+			chunk.debugID = debugIDForChunk(finalBytes, chunk.finalRelPath)
+		}
 	}
 
 	// Generate the final output files by joining file pieces together
@@ -532,8 +706,27 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo, additionalF
 			// Path substitution for the chunk itself
 			finalRelDir := c.fs.Dir(chunk.finalRelPath)
 			outputContentsJoiner, outputSourceMapShifts := c.substituteFinalPaths(chunks, chunk.intermediateOutput,
-				func(finalRelPathForImport string) string {
-					return c.pathBetweenChunks(finalRelDir, finalRelPathForImport)
+				func(finalRelPathForImport string, assetLoader config.Loader) string {
+					switch assetLoader {
+					case config.LoaderNone:
+						return c.pathBetweenChunks(finalRelDir, finalRelPathForImport)
+
+					case config.LoaderNAPI:
+						// Native addons are loaded from the file system with "require()"
+						// instead of over the network, so the public path doesn't apply
+						return c.relativePathBetweenChunks(finalRelDir, finalRelPathForImport)
+
+					default:
+						// Assets may be served from somewhere else depending on their type
+						publicPath, ok := c.options.AssetPublicPaths[path.Ext(finalRelPathForImport)]
+						if !ok {
+							publicPath = c.options.PublicPath
+						}
+						if publicPath != "" {
+							return joinWithPublicPath(publicPath, c.assetPathForPublicPath(finalRelPathForImport))
+						}
+						return c.relativePathBetweenChunks(finalRelDir, finalRelPathForImport)
+					}
 				})
 
 			// Generate the optional legal comments file for this chunk
@@ -559,11 +752,50 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo, additionalF
 				})
 			}
 
+			// Generate the optional name map file for this chunk
+			if chunk.nameMap != nil {
+				outputFiles = append(outputFiles, graph.OutputFile{
+					AbsPath:  c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath+".names.json"),
+					Contents: chunk.nameMap,
+					JSONMetadataChunk: fmt.Sprintf(
+						"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(chunk.nameMap)),
+				})
+			}
+
+			// Generate the optional concatenation report for this chunk
+			if chunk.concatReport != nil {
+				outputFiles = append(outputFiles, graph.OutputFile{
+					AbsPath:  c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath+".concat.json"),
+					Contents: chunk.concatReport,
+					JSONMetadataChunk: fmt.Sprintf(
+						"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(chunk.concatReport)),
+				})
+			}
+
+			// Link the file to its source map by debug ID. This comment must come
+			// before the source map comment since some tools only look at the
+			// last line for that.
+			if chunk.debugID != "" {
+				outputContentsJoiner.EnsureNewlineAtEnd()
+				outputContentsJoiner.AddString(commentPrefix)
+				outputContentsJoiner.AddString("# debugId=")
+				outputContentsJoiner.AddString(chunk.debugID)
+				outputContentsJoiner.AddString(commentSuffix)
+				outputContentsJoiner.AddString("\n")
+			}
+
 			// Generate the optional source map for this chunk
 			if c.options.SourceMap != config.SourceMapNone && chunk.outputSourceMap.HasContent() {
 				outputSourceMap := chunk.outputSourceMap.Finalize(outputSourceMapShifts)
 				finalRelPathForSourceMap := chunk.finalRelPath + ".map"
 
+				// The source map always ends with "\n}\n", so the debug ID can just be
+				// added as the last property
+				if chunk.debugID != "" {
+					outputSourceMap = append(outputSourceMap[:len(outputSourceMap)-3],
+						fmt.Sprintf(",\n  \"debug_id\": %q\n}\n", chunk.debugID)...)
+				}
+
 				// Potentially write a trailing source map comment
 				switch c.options.SourceMap {
 				case config.SourceMapLinkedWithComment:
@@ -572,7 +804,7 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo, additionalF
 					outputContentsJoiner.EnsureNewlineAtEnd()
 					outputContentsJoiner.AddString(commentPrefix)
 					outputContentsJoiner.AddString("# sourceMappingURL=")
-					outputContentsJoiner.AddString(importPath)
+					outputContentsJoiner.AddString(helpers.EncodeURLPath(importPath))
 					outputContentsJoiner.AddString(commentSuffix)
 					outputContentsJoiner.AddString("\n")
 
@@ -599,23 +831,49 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo, additionalF
 
 			// Finalize the output contents
 			outputContents := outputContentsJoiner.Done()
+			if c.options.ScanSecrets {
+				c.scanChunkForSecrets(&chunk, outputContents)
+			}
 
 			// Path substitution for the JSON metadata
 			var jsonMetadataChunk string
 			if c.options.NeedsMetafile {
 				jsonMetadataChunkPieces := c.breakOutputIntoPieces(chunk.jsonMetadataChunkCallback(len(outputContents)), uint32(len(chunks)))
-				jsonMetadataChunkBytes, _ := c.substituteFinalPaths(chunks, jsonMetadataChunkPieces, func(finalRelPathForImport string) string {
+				jsonMetadataChunkBytes, _ := c.substituteFinalPaths(chunks, jsonMetadataChunkPieces, func(finalRelPathForImport string, _ config.Loader) string {
 					return c.res.PrettyPath(logger.Path{Text: c.fs.Join(c.options.AbsOutputDir, finalRelPathForImport), Namespace: "file"})
 				})
 				jsonMetadataChunk = string(jsonMetadataChunkBytes.Done())
 			}
 
+			// CSS chunks that are the result of importing CSS into JavaScript are
+			// not considered to be the output file for the JavaScript entry point
+			var entryPointSourceIndex ast.Index32
+			var cssForEntryPointSourceIndex ast.Index32
+			if chunk.isEntryPoint {
+				_, isCSSFile := c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.CSSRepr)
+				if _, isCSSChunk := chunk.chunkRepr.(*chunkReprCSS); isCSSChunk == isCSSFile {
+					entryPointSourceIndex = ast.MakeIndex32(chunk.sourceIndex)
+				} else {
+					cssForEntryPointSourceIndex = ast.MakeIndex32(chunk.sourceIndex)
+				}
+			}
+
+			// Entry points list the chunks that they import so they can be preloaded
+			var absPreloadPaths []string
+			for _, otherChunkIndex := range chunk.preloadChunks {
+				absPreloadPaths = append(absPreloadPaths, c.fs.Join(c.options.AbsOutputDir, chunks[otherChunkIndex].finalRelPath))
+			}
+
 			// Generate the output file for this chunk
 			outputFiles = append(outputFiles, graph.OutputFile{
-				AbsPath:           c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath),
-				Contents:          outputContents,
-				JSONMetadataChunk: jsonMetadataChunk,
-				IsExecutable:      chunk.isExecutable,
+				AbsPath:                     c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath),
+				Contents:                    outputContents,
+				JSONMetadataChunk:           jsonMetadataChunk,
+				IsExecutable:                chunk.isExecutable,
+				EntryPointSourceIndex:       entryPointSourceIndex,
+				CSSForEntryPointSourceIndex: cssForEntryPointSourceIndex,
+				AbsPreloadPaths:             absPreloadPaths,
+				LegalComments:               chunk.legalComments,
 			})
 
 			results[chunkIndex] = outputFiles
@@ -644,7 +902,7 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo, additionalF
 func (c *linkerContext) substituteFinalPaths(
 	chunks []chunkInfo,
 	intermediateOutput intermediateOutput,
-	modifyPath func(string) string,
+	modifyPath func(relPath string, assetLoader config.Loader) string,
 ) (j helpers.Joiner, shifts []sourcemap.SourceMapShift) {
 	// Optimization: If there can be no substitutions, just reuse the initial
 	// joiner that was used when generating the intermediate chunk output
@@ -675,7 +933,7 @@ func (c *linkerContext) substituteFinalPaths(
 			// Make sure to always use forward slashes, even on Windows
 			relPath = strings.ReplaceAll(relPath, "\\", "/")
 
-			importPath := modifyPath(relPath)
+			importPath := modifyPath(relPath, file.InputFile.Loader)
 			j.AddString(importPath)
 			shift.Before.AdvanceString(file.InputFile.UniqueKeyForAdditionalFile)
 			shift.After.AdvanceString(importPath)
@@ -683,17 +941,50 @@ func (c *linkerContext) substituteFinalPaths(
 
 		case outputPieceChunkIndex:
 			chunk := chunks[piece.index]
-			importPath := modifyPath(chunk.finalRelPath)
+			importPath := modifyPath(chunk.finalRelPath, config.LoaderNone)
 			j.AddString(importPath)
 			shift.Before.AdvanceString(chunk.uniqueKey)
 			shift.After.AdvanceString(importPath)
 			shifts = append(shifts, shift)
+
+		case outputPieceWorkerIndex:
+			relPath := c.relPathForWorker(piece.index)
+			importPath := modifyPath(relPath, config.LoaderNone)
+			j.AddString(importPath)
+			shift.Before.AdvanceString(fmt.Sprintf("%sW%08d", c.uniqueKeyPrefix, piece.index))
+			shift.After.AdvanceString(importPath)
+			shifts = append(shifts, shift)
+
+		case outputPieceInlineWorkerTextIndex, outputPieceInlineWorkerDataURLIndex:
+			kind := 'U'
+			if piece.kind == outputPieceInlineWorkerTextIndex {
+				kind = 'T'
+			}
+			text := inlineWorkerString(c.inlineWorkerContents[piece.index], kind == 'U', c.options.ASCIIOnly)
+			j.AddString(text)
+			shift.Before.AdvanceString(fmt.Sprintf("%s%c%08d", c.uniqueKeyPrefix, kind, piece.index))
+			shift.After.AdvanceString(text)
+			shifts = append(shifts, shift)
+
+		case outputPieceHashIndex:
+			finalHash := chunks[piece.index].finalHash
+			j.AddString(finalHash)
+			shift.Before.AdvanceString(fmt.Sprintf("%sH%08d", c.uniqueKeyPrefix, piece.index))
+			shift.After.AdvanceString(finalHash)
+			shifts = append(shifts, shift)
 		}
 	}
 
 	return
 }
 
+func (c *linkerContext) relPathForWorker(sourceIndex uint32) string {
+	relPath, _ := c.fs.Rel(c.options.AbsOutputDir, c.absWorkerOutputPaths[sourceIndex])
+
+	// Make sure to always use forward slashes, even on Windows
+	return strings.ReplaceAll(relPath, "\\", "/")
+}
+
 func (c *linkerContext) pathBetweenChunks(fromRelDir string, toRelPath string) string {
 	// Join with the public path if it has been configured
 	if c.options.PublicPath != "" {
@@ -701,6 +992,24 @@ func (c *linkerContext) pathBetweenChunks(fromRelDir string, toRelPath string) s
 	}
 
 	// Otherwise, return a relative path
+	return c.relativePathBetweenChunks(fromRelDir, toRelPath)
+}
+
+// Assets written to a separate asset directory are assumed to be served from
+// the public path as if that directory were the output directory
+func (c *linkerContext) assetPathForPublicPath(finalRelPath string) string {
+	if c.options.AbsAssetOutputDir == "" {
+		return finalRelPath
+	}
+	absPath := c.fs.Join(c.options.AbsOutputDir, finalRelPath)
+	relPath, ok := c.fs.Rel(c.options.AbsAssetOutputDir, absPath)
+	if !ok {
+		return finalRelPath
+	}
+	return strings.ReplaceAll(relPath, "\\", "/")
+}
+
+func (c *linkerContext) relativePathBetweenChunks(fromRelDir string, toRelPath string) string {
 	relPath, ok := c.fs.Rel(fromRelDir, toRelPath)
 	if !ok {
 		c.log.AddError(nil, logger.Range{},
@@ -720,6 +1029,15 @@ func (c *linkerContext) pathBetweenChunks(fromRelDir string, toRelPath string) s
 	return relPath
 }
 
+// Returns the substitution for the "[pkg]" placeholder for this file. Files
+// that aren't on the file system don't belong to a package.
+func packageNameForFile(res resolver.Resolver, inputFile *graph.InputFile) string {
+	if inputFile.Source.KeyPath.Namespace != "file" {
+		return ""
+	}
+	return res.PackageName(inputFile.Source.KeyPath.Text)
+}
+
 // Returns the path of this file relative to "outbase", which is then ready to
 // be joined with the absolute output directory path. The directory and name
 // components are returned separately for convenience.
@@ -812,6 +1130,124 @@ func pathRelativeToOutbase(
 	return
 }
 
+// Chunks are ranked by how soon they are needed. A chunk is "critical" if a
+// user-specified entry point imports it with import statements (possibly
+// indirectly), since it must be loaded before the entry point can run. Other
+// chunks are "lazy" since they are only needed after a dynamic import. The
+// preload rank is the number of imports between the chunk and the nearest
+// entry point, which is the order in which a browser would discover it.
+func (c *linkerContext) computeChunkPriorities(chunks []chunkInfo) {
+	// Walk the chunk import graph breadth-first starting from the given chunks
+	walk := func(roots []uint32, followDynamicImports bool) []int {
+		ranks := make([]int, len(chunks))
+		for i := range ranks {
+			ranks[i] = -1
+		}
+		queue := append([]uint32{}, roots...)
+		for _, chunkIndex := range roots {
+			ranks[chunkIndex] = 0
+		}
+		for len(queue) > 0 {
+			chunkIndex := queue[0]
+			queue = queue[1:]
+			for _, chunkImport := range chunks[chunkIndex].crossChunkImports {
+				if (followDynamicImports || chunkImport.importKind != ast.ImportDynamic) && ranks[chunkImport.chunkIndex] == -1 {
+					ranks[chunkImport.chunkIndex] = ranks[chunkIndex] + 1
+					queue = append(queue, chunkImport.chunkIndex)
+				}
+			}
+		}
+		return ranks
+	}
+
+	var roots []uint32
+	for chunkIndex, chunk := range chunks {
+		if _, ok := chunk.chunkRepr.(*chunkReprJS); ok && chunk.isEntryPoint && c.graph.Files[chunk.sourceIndex].IsUserSpecifiedEntryPoint() {
+			roots = append(roots, uint32(chunkIndex))
+		}
+	}
+	staticRanks := walk(roots, false)
+	allRanks := walk(roots, true)
+	jsChunkForEntryPoint := make(map[uint32]uint32)
+	for chunkIndex := range chunks {
+		chunk := &chunks[chunkIndex]
+		if _, ok := chunk.chunkRepr.(*chunkReprJS); ok {
+			if staticRanks[chunkIndex] != -1 {
+				chunk.isCritical = true
+				chunk.preloadRank = staticRanks[chunkIndex]
+			} else if allRanks[chunkIndex] != -1 {
+				chunk.preloadRank = allRanks[chunkIndex]
+			}
+			if chunk.isEntryPoint {
+				jsChunkForEntryPoint[chunk.sourceIndex] = uint32(chunkIndex)
+			}
+		}
+	}
+
+	for chunkIndex := range chunks {
+		chunk := &chunks[chunkIndex]
+		switch chunk.chunkRepr.(type) {
+		case *chunkReprJS:
+			// Each user-specified entry point has a list of the chunks to preload
+			if chunk.isEntryPoint && c.graph.Files[chunk.sourceIndex].IsUserSpecifiedEntryPoint() {
+				ranks := walk([]uint32{uint32(chunkIndex)}, false)
+				for otherChunkIndex, rank := range ranks {
+					if rank > 0 {
+						chunk.preloadChunks = append(chunk.preloadChunks, uint32(otherChunkIndex))
+					}
+				}
+				sort.SliceStable(chunk.preloadChunks, func(i, j int) bool {
+					return ranks[chunk.preloadChunks[i]] < ranks[chunk.preloadChunks[j]]
+				})
+			}
+
+		case *chunkReprCSS:
+			// CSS chunks for a JavaScript entry point are needed at the same time
+			// as that entry point. CSS entry points are always critical.
+			if !chunk.isEntryPoint {
+				continue
+			}
+			if jsChunkIndex, ok := jsChunkForEntryPoint[chunk.sourceIndex]; ok {
+				chunk.isCritical = chunks[jsChunkIndex].isCritical
+				chunk.preloadRank = chunks[jsChunkIndex].preloadRank
+			} else {
+				chunk.isCritical = c.graph.Files[chunk.sourceIndex].IsUserSpecifiedEntryPoint()
+			}
+		}
+	}
+}
+
+func (c *linkerContext) chunkPriorityMetadata(chunk *chunkInfo) string {
+	if !c.options.CodeSplitting {
+		return ""
+	}
+	priority := "lazy"
+	if chunk.isCritical {
+		priority = "critical"
+	}
+	return fmt.Sprintf(",\n      \"priority\": %q,\n      \"preloadRank\": %d", priority, chunk.preloadRank)
+}
+
+func chunkDebugIDMetadata(chunk *chunkInfo) string {
+	if chunk.debugID == "" {
+		return ""
+	}
+	return fmt.Sprintf(",\n      \"debugId\": %q", chunk.debugID)
+}
+
+// Debug IDs are formatted as UUIDs since that's what crash reporting tools
+// expect. The version and variant bits are set like a random (version 4) UUID
+// even though the ID is actually derived from the contents of the chunk.
+func debugIDForChunk(finalHash []byte, finalRelPath string) string {
+	hash := sha256.New()
+	hashWriteLengthPrefixed(hash, finalHash)
+	hashWriteLengthPrefixed(hash, []byte(finalRelPath))
+	id := hash.Sum(nil)[:16]
+	id[6] = (id[6] & 0x0F) | 0x40
+	id[8] = (id[8] & 0x3F) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
 func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 	c.timer.Begin("Compute cross-chunk dependencies")
 	defer c.timer.End("Compute cross-chunk dependencies")
@@ -1256,6 +1692,17 @@ func (c *linkerContext) scanImportsAndExports() {
 				otherFile := &c.graph.Files[record.SourceIndex.GetIndex()]
 				otherRepr := otherFile.InputFile.Repr.(*graph.JSRepr)
 
+				// Inline URLs for assets into "new URL()" and "require.resolve()" expressions
+				if record.Kind == ast.ImportNewURL || record.Kind == ast.ImportRequireResolve {
+					record.Path.Text = otherRepr.AST.URLForCSS
+					record.Path.Namespace = ""
+					record.SourceIndex = ast.Index32{}
+
+					// Copy the additional files to the output directory
+					additionalFiles = append(additionalFiles, otherFile.InputFile.AdditionalFiles...)
+					continue
+				}
+
 				switch record.Kind {
 				case ast.ImportStmt:
 					// Importing using ES6 syntax from a file without any ES6 syntax
@@ -1282,6 +1729,7 @@ func (c *linkerContext) scanImportsAndExports() {
 						otherRepr.AST.ExportsKind == js_ast.ExportsNone && !otherRepr.AST.HasLazyExport {
 						otherRepr.Meta.Wrap = graph.WrapCJS
 						otherRepr.AST.ExportsKind = js_ast.ExportsCommonJS
+						setWrapReason(otherRepr, graph.WrapReasonImportStar, sourceIndex)
 					}
 
 				case ast.ImportRequire:
@@ -1292,6 +1740,7 @@ func (c *linkerContext) scanImportsAndExports() {
 						otherRepr.Meta.Wrap = graph.WrapCJS
 						otherRepr.AST.ExportsKind = js_ast.ExportsCommonJS
 					}
+					setWrapReason(otherRepr, graph.WrapReasonRequire, sourceIndex)
 
 				case ast.ImportDynamic:
 					if !c.options.CodeSplitting {
@@ -1303,6 +1752,7 @@ func (c *linkerContext) scanImportsAndExports() {
 							otherRepr.Meta.Wrap = graph.WrapCJS
 							otherRepr.AST.ExportsKind = js_ast.ExportsCommonJS
 						}
+						setWrapReason(otherRepr, graph.WrapReasonDynamicImport, sourceIndex)
 					}
 				}
 			}
@@ -1314,6 +1764,7 @@ func (c *linkerContext) scanImportsAndExports() {
 			if repr.AST.ExportsKind == js_ast.ExportsCommonJS && (!file.IsEntryPoint() ||
 				c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatESModule) {
 				repr.Meta.Wrap = graph.WrapCJS
+				setWrapReason(repr, graph.WrapReasonCommonJS, sourceIndex)
 			}
 		}
 
@@ -1613,6 +2064,17 @@ func (c *linkerContext) scanImportsAndExports() {
 	// parts that declare the export to all parts that use the import. Also
 	// generate wrapper parts for wrapped files.
 	c.timer.Begin("Step 6")
+
+	// Give all top-level runtime symbols the runtime prefix, if there is one
+	if c.options.RuntimePrefix != "" {
+		runtimeRepr := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr)
+		for _, member := range runtimeRepr.AST.ModuleScope.Members {
+			if c.graph.Symbols.Get(member.Ref).Kind != js_ast.SymbolUnbound {
+				c.addRuntimePrefix(member.Ref)
+			}
+		}
+	}
+
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		file := &c.graph.Files[sourceIndex]
 		repr, ok := file.InputFile.Repr.(*graph.JSRepr)
@@ -1645,6 +2107,15 @@ func (c *linkerContext) scanImportsAndExports() {
 			name := file.InputFile.Source.IdentifierName
 			c.graph.Symbols.Get(repr.AST.ExportsRef).OriginalName = name + "_exports"
 			c.graph.Symbols.Get(repr.AST.ModuleRef).OriginalName = name + "_module"
+			if c.options.RuntimePrefix != "" {
+				c.addRuntimePrefix(repr.AST.ExportsRef)
+			}
+		}
+
+		// The generated "require_*" and "init_*" wrappers are also top-level
+		// symbols that need the runtime prefix, if there is one
+		if repr.Meta.Wrap != graph.WrapNone && c.options.RuntimePrefix != "" {
+			c.addRuntimePrefix(repr.AST.WrapperRef)
 		}
 
 		// Include the "__export" symbol from the runtime if it was used in the
@@ -1746,14 +2217,19 @@ func (c *linkerContext) scanImportsAndExports() {
 			for _, importRecordIndex := range part.ImportRecordIndices {
 				record := &repr.AST.ImportRecords[importRecordIndex]
 
+				// The paths in "new URL()" expressions are just strings
+				if record.Kind == ast.ImportNewURL || record.Flags.Has(ast.IsResolvedAtBuildTime) {
+					continue
+				}
+
 				// Don't follow external imports (this includes import() expressions)
 				if !record.SourceIndex.IsValid() || c.isExternalDynamicImport(record, sourceIndex) {
 					// This is an external import. Check if it will be a "require()" call.
-					if record.Kind == ast.ImportRequire || !c.options.OutputFormat.KeepES6ImportExportSyntax() ||
+					if record.Kind == ast.ImportRequire || record.Kind == ast.ImportRequireResolve || !c.options.OutputFormat.KeepES6ImportExportSyntax() ||
 						(record.Kind == ast.ImportDynamic && c.options.UnsupportedJSFeatures.Has(compat.DynamicImport)) {
 						// We should use "__require" instead of "require" if we're not
 						// generating a CommonJS output file, since it won't exist otherwise
-						if config.ShouldCallRuntimeRequire(c.options.Mode, c.options.OutputFormat) {
+						if config.ShouldCallRuntimeRequire(c.options.Mode, c.options.Platform, c.options.OutputFormat) {
 							record.Flags |= ast.CallRuntimeRequire
 							runtimeRequireUses++
 						}
@@ -1770,7 +2246,7 @@ func (c *linkerContext) scanImportsAndExports() {
 						// - The ES module namespace object must not be captured
 						// - The "default" and "__esModule" exports must not be accessed
 						//
-						if record.Kind != ast.ImportRequire &&
+						if record.Kind != ast.ImportRequire && record.Kind != ast.ImportRequireResolve &&
 							(record.Kind != ast.ImportStmt ||
 								record.Flags.Has(ast.ContainsImportStar) ||
 								record.Flags.Has(ast.ContainsDefaultAlias) ||
@@ -2252,6 +2728,22 @@ func (c *linkerContext) matchImportsWithExportsForFile(sourceIndex uint32) {
 		case matchImportProbablyTypeScriptType:
 			repr.Meta.IsProbablyTypeScriptType[importRef] = true
 
+			// Re-exporting a type requires "export type" when each file is compiled
+			// in isolation, since otherwise there's no way to know it's a type
+			if namedImport := repr.AST.NamedImports[importRef]; c.options.TS.IsolatedModulesCheck &&
+				namedImport.IsExported && file.InputFile.Loader.IsTypeScript() {
+				kind := logger.Warning
+				if helpers.IsInsideNodeModules(file.InputFile.Source.KeyPath.Text) {
+					kind = logger.Debug
+				}
+				c.log.AddIDWithNotes(logger.MsgID_JS_IsolatedModules, kind, file.LineColumnTracker(),
+					js_lexer.RangeOfIdentifier(file.InputFile.Source, namedImport.AliasLoc),
+					fmt.Sprintf("Re-exporting the type %q requires using \"export type\" when compiling each file in isolation", namedImport.Alias),
+					[]logger.MsgData{{Text: fmt.Sprintf("There is no value called %q, so it's assumed to be a type. "+
+						"A compiler that only looks at this file can't know that, so it will generate code that tries to re-export a value called %q.",
+						namedImport.Alias, namedImport.Alias)}})
+			}
+
 		case matchImportAmbiguous:
 			namedImport := repr.AST.NamedImports[importRef]
 			r := js_lexer.RangeOfIdentifier(file.InputFile.Source, namedImport.AliasLoc)
@@ -2534,6 +3026,7 @@ func (c *linkerContext) recursivelyWrapDependencies(sourceIndex uint32) {
 	if repr.Meta.Wrap == graph.WrapNone {
 		if repr.AST.ExportsKind == js_ast.ExportsCommonJS {
 			repr.Meta.Wrap = graph.WrapCJS
+			setWrapReason(repr, graph.WrapReasonCommonJS, sourceIndex)
 		} else {
 			repr.Meta.Wrap = graph.WrapESM
 		}
@@ -2542,7 +3035,21 @@ func (c *linkerContext) recursivelyWrapDependencies(sourceIndex uint32) {
 	// All dependencies must also be wrapped
 	for _, record := range repr.AST.ImportRecords {
 		if record.SourceIndex.IsValid() {
-			c.recursivelyWrapDependencies(record.SourceIndex.GetIndex())
+			otherSourceIndex := record.SourceIndex.GetIndex()
+			if otherRepr := c.graph.Files[otherSourceIndex].InputFile.Repr.(*graph.JSRepr); otherRepr.Meta.Wrap == graph.WrapNone {
+				setWrapReason(otherRepr, graph.WrapReasonDependency, sourceIndex)
+			}
+			c.recursivelyWrapDependencies(otherSourceIndex)
+		}
+	}
+}
+
+// Only the first reason is kept since that's the one that caused the wrapper
+func setWrapReason(repr *graph.JSRepr, reason graph.WrapReason, importer uint32) {
+	if repr.Meta.WrapReason == graph.WrapReasonNone {
+		repr.Meta.WrapReason = reason
+		if reason != graph.WrapReasonCommonJS {
+			repr.Meta.WrapImporter = ast.MakeIndex32(importer)
 		}
 	}
 }
@@ -2868,6 +3375,9 @@ func (c *linkerContext) markFileLiveForTreeShaking(sourceIndex uint32) {
 					}
 
 					// Otherwise, include this module for its side effects
+					if c.treeShakingReport != nil {
+						c.recordTreeShakingFileParent(otherSourceIndex, js_ast.Dependency{SourceIndex: sourceIndex, PartIndex: uint32(partIndex)})
+					}
 					c.markFileLiveForTreeShaking(otherSourceIndex)
 				} else if record.Flags.Has(ast.IsExternalWithoutSideEffects) {
 					// This can be removed if it's unused
@@ -2883,6 +3393,15 @@ func (c *linkerContext) markFileLiveForTreeShaking(sourceIndex uint32) {
 			// everything if tree-shaking is disabled. Note that we still want to
 			// perform tree-shaking on the runtime even if tree-shaking is disabled.
 			if !canBeRemovedIfUnused || (!part.ForceTreeShaking && !c.options.TreeShaking && file.IsEntryPoint()) {
+				if c.treeShakingReport != nil {
+					reason := "tree shaking disabled"
+					if repr.Meta.EntryPointPartIndex.IsValid() && repr.Meta.EntryPointPartIndex.GetIndex() == uint32(partIndex) {
+						reason = "entry point"
+					} else if !canBeRemovedIfUnused {
+						reason = "side effects"
+					}
+					c.recordTreeShakingRoot(sourceIndex, uint32(partIndex), reason)
+				}
 				c.markPartLiveForTreeShaking(sourceIndex, uint32(partIndex))
 			}
 		}
@@ -2913,10 +3432,16 @@ func (c *linkerContext) markPartLiveForTreeShaking(sourceIndex uint32, partIndex
 	part.IsLive = true
 
 	// Include the file containing this part
+	if c.treeShakingReport != nil {
+		c.recordTreeShakingFileParent(sourceIndex, js_ast.Dependency{SourceIndex: sourceIndex, PartIndex: partIndex})
+	}
 	c.markFileLiveForTreeShaking(sourceIndex)
 
 	// Also include any dependencies
 	for _, dep := range part.Dependencies {
+		if c.treeShakingReport != nil {
+			c.recordTreeShakingParent(dep, js_ast.Dependency{SourceIndex: sourceIndex, PartIndex: partIndex})
+		}
 		c.markPartLiveForTreeShaking(dep.SourceIndex, dep.PartIndex)
 	}
 }
@@ -3195,18 +3720,42 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 		}
 	}
 
+	// Files in boundary packages all go in one chunk per package. That chunk
+	// belongs to every entry point that uses any file in the package.
+	var boundaryPackages map[string]bool
+	if c.options.CodeSplitting && len(c.options.BoundaryPackages) > 0 {
+		boundaryPackages = make(map[string]bool)
+		for _, name := range c.options.BoundaryPackages {
+			boundaryPackages[name] = true
+		}
+	}
+
 	// Figure out which JS files are in which chunk
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		if file := &c.graph.Files[sourceIndex]; file.IsLive {
 			if _, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
 				key := file.EntryBits.String()
+				var boundaryPackage string
+				if boundaryPackages != nil && !file.IsEntryPoint() {
+					if name, ok := helpers.PackageNameFromPath(file.InputFile.Source.KeyPath.Text); ok && boundaryPackages[name] {
+						key = "boundary:" + name
+						boundaryPackage = name
+					}
+				}
 				chunk, ok := jsChunks[key]
 				if !ok {
 					chunk.entryBits = file.EntryBits
+					if boundaryPackage != "" {
+						chunk.entryBits = helpers.NewBitSet(uint(len(c.graph.EntryPoints())))
+						chunk.boundaryPackage = boundaryPackage
+					}
 					chunk.filesWithPartsInChunk = make(map[uint32]bool)
 					chunk.chunkRepr = &chunkReprJS{}
 					jsChunks[key] = chunk
 				}
+				if boundaryPackage != "" {
+					chunk.entryBits.Union(file.EntryBits)
+				}
 				chunk.filesWithPartsInChunk[uint32(sourceIndex)] = true
 			}
 		}
@@ -3278,7 +3827,7 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 		}
 
 		// Compute the template substitutions
-		var dir, base, ext string
+		var dir, base, ext, pkg string
 		var template []config.PathTemplate
 		if chunk.isEntryPoint {
 			// Only use the entry path template for user-specified entry points
@@ -3288,6 +3837,7 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 			} else {
 				template = c.options.ChunkPathTemplate
 			}
+			pkg = packageNameForFile(c.res, &file.InputFile)
 
 			if c.options.AbsOutputFile != "" {
 				// If the output path was configured explicitly, use it verbatim
@@ -3318,23 +3868,49 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 		} else {
 			dir = "/"
 			base = "chunk"
+			if chunk.boundaryPackage != "" {
+				base = strings.ReplaceAll(strings.TrimPrefix(chunk.boundaryPackage, "@"), "/", "-")
+				pkg = chunk.boundaryPackage
+			} else if config.HasPlaceholder(c.options.ChunkPathTemplate, config.PkgPlaceholder) {
+				pkg = c.commonPackageNameForChunk(chunk)
+			}
 			ext = stdExt
 			template = c.options.ChunkPathTemplate
 		}
 
 		// Determine the output path template
+		chunk.name = base
 		templateExt := strings.TrimPrefix(ext, ".")
 		template = append(append(make([]config.PathTemplate, 0, len(template)+1), template...), config.PathTemplate{Data: ext})
 		chunk.finalTemplate = config.SubstituteTemplate(template, config.PathPlaceholders{
 			Dir:  &dir,
 			Name: &base,
 			Ext:  &templateExt,
+			Pkg:  &pkg,
 		})
 	}
 
 	return sortedChunks
 }
 
+// Shared chunks only belong to a package if all of the code in them comes
+// from that package. Otherwise "[pkg]" is substituted with the empty string.
+func (c *linkerContext) commonPackageNameForChunk(chunk *chunkInfo) string {
+	pkg, isFirst := "", true
+	for sourceIndex := range chunk.filesWithPartsInChunk {
+		if sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		name := packageNameForFile(c.res, &c.graph.Files[sourceIndex].InputFile)
+		if isFirst {
+			pkg, isFirst = name, false
+		} else if name != pkg {
+			return ""
+		}
+	}
+	return pkg
+}
+
 type chunkOrder struct {
 	sourceIndex uint32
 	distance    uint32
@@ -3416,7 +3992,7 @@ func (c *linkerContext) findImportedPartsInJSOrder(chunk *chunkInfo) (js []uint3
 		file := &c.graph.Files[sourceIndex]
 
 		if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
-			isFileInThisChunk := chunk.entryBits.Equals(file.EntryBits)
+			isFileInThisChunk := chunk.filesWithPartsInChunk[sourceIndex]
 
 			// Wrapped files can't be split because they are all inside the wrapper
 			canFileBeSplit := repr.Meta.Wrap == graph.WrapNone
@@ -3856,9 +4432,16 @@ type compileResultJS struct {
 
 	sourceIndex uint32
 
+	// This is the number of bytes added by wrapping this file in a closure. It's
+	// only computed when a concatenation report is requested.
+	wrapperOverhead int
+
 	// This is the line and column offset since the previous JavaScript string
 	// or the start of the file if this is the first JavaScript string.
 	generatedOffset sourcemap.LineColumnOffset
+
+	// In low-memory mode, "JS" is written to the spill file and cleared
+	spilledJS *spilledCode
 }
 
 func (c *linkerContext) requireOrImportMetaForSource(sourceIndex uint32) (meta js_printer.RequireOrImportMeta) {
@@ -3887,6 +4470,16 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 ) {
 	defer c.recoverInternalError(waitGroup, partRange.sourceIndex)
 
+	// The output of a cancelled build is thrown away, so skip printing it
+	if c.options.CancelFlag.DidCancel() {
+		result.sourceIndex = partRange.sourceIndex
+		waitGroup.Done()
+		return
+	}
+
+	c.options.Workers.Acquire()
+	defer c.options.Workers.Release()
+
 	file := &c.graph.Files[partRange.sourceIndex]
 	repr := file.InputFile.Repr.(*graph.JSRepr)
 	nsExportPartIndex := js_ast.NSExportPartIndex
@@ -4023,6 +4616,13 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		stmts = mergeAdjacentLocalStmts(stmts)
 	}
 
+	// Remember what the code looks like without the wrapper so that the size of
+	// the wrapper can be reported
+	var unwrappedStmts []js_ast.Stmt
+	if needsWrapper && c.options.ConcatReport {
+		unwrappedStmts = append(append([]js_ast.Stmt{}, stmtList.outsideWrapperPrefix...), stmts...)
+	}
+
 	// Optionally wrap all statements in a closure
 	if needsWrapper {
 		switch repr.Meta.Wrap {
@@ -4166,6 +4766,12 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		indent++
 	}
 
+	// The printer needs the original code to copy its formatting
+	var originalSource *logger.Source
+	if c.options.PreserveFormatting {
+		originalSource = &file.InputFile.Source
+	}
+
 	// Convert the AST to JavaScript code
 	printOptions := js_printer.Options{
 		Indent:                       indent,
@@ -4174,6 +4780,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		MinifyWhitespace:             c.options.MinifyWhitespace,
 		MinifySyntax:                 c.options.MinifySyntax,
 		ASCIIOnly:                    c.options.ASCIIOnly,
+		BMPOnly:                      c.options.BMPOnly,
 		ToCommonJSRef:                toCommonJSRef,
 		ToESMRef:                     toESMRef,
 		RuntimeRequireRef:            runtimeRequireRef,
@@ -4185,6 +4792,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		InputSourceMap:               inputSourceMap,
 		LineOffsetTables:             lineOffsetTables,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
+		OriginalSource:               originalSource,
 	}
 	tree := repr.AST
 	tree.Directive = "" // This is handled elsewhere
@@ -4193,6 +4801,22 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		PrintResult: js_printer.Print(tree, c.graph.Symbols, r, printOptions),
 		sourceIndex: partRange.sourceIndex,
 	}
+	if unwrappedStmts != nil {
+		tree.Parts = []js_ast.Part{{Stmts: unwrappedStmts}}
+		printOptions.AddSourceMappings = false
+		unwrapped := js_printer.Print(tree, c.graph.Symbols, r, printOptions)
+		result.wrapperOverhead = len(result.JS) - len(unwrapped.JS)
+	}
+
+	// The printed code for all files in a chunk would otherwise stay in memory
+	// until every file is printed and then be copied into the output file. If
+	// writing to the spill file fails, the code is just kept in memory.
+	if c.spill != nil && partRange.sourceIndex != runtime.SourceIndex {
+		if spilled, err := c.spill.writeCode(result.JS); err == nil {
+			result.spilledJS = spilled
+			result.JS = nil
+		}
+	}
 
 	waitGroup.Done()
 }
@@ -4495,6 +5119,7 @@ func (c *linkerContext) generateEntryPointTailJS(
 		MinifyWhitespace:             c.options.MinifyWhitespace,
 		MinifySyntax:                 c.options.MinifySyntax,
 		ASCIIOnly:                    c.options.ASCIIOnly,
+		BMPOnly:                      c.options.BMPOnly,
 		ToCommonJSRef:                toCommonJSRef,
 		ToESMRef:                     toESMRef,
 		LegalComments:                c.options.LegalComments,
@@ -4505,6 +5130,15 @@ func (c *linkerContext) generateEntryPointTailJS(
 	return
 }
 
+// Generated symbols are renamed with the runtime prefix so that they can't
+// collide with the symbols of another independently-built bundle that is
+// loaded into the same global scope. This must be done before renaming.
+func (c *linkerContext) addRuntimePrefix(ref js_ast.Ref) {
+	symbol := c.graph.Symbols.Get(ref)
+	symbol.OriginalName = c.options.RuntimePrefix + symbol.OriginalName
+	symbol.Flags |= js_ast.MustStartWithRuntimePrefix
+}
+
 func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []uint32, timer *helpers.Timer) renamer.Renamer {
 	if c.options.MinifyIdentifiers {
 		timer.Begin("Minify symbols")
@@ -4527,6 +5161,12 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		reservedNames["require"] = 1
 		reservedNames["Promise"] = 1
 	}
+	if config.ShouldShimNodeESMGlobals(c.options.Mode, c.options.Platform, c.options.OutputFormat) {
+		// Note: "__dirname" and "__filename" are already reserved if they're used
+		reservedNames["__createRequire"] = 1
+		reservedNames["__fileURLToPath"] = 1
+		reservedNames["__pathDirname"] = 1
+	}
 	timer.End("Compute reserved names")
 
 	// Make sure imports get a chance to be renamed too
@@ -4548,7 +5188,7 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		for _, sourceIndex := range filesInOrder {
 			firstTopLevelSlots.UnionMax(c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr).AST.NestedScopeSlotCounts)
 		}
-		r := renamer.NewMinifyRenamer(c.graph.Symbols, firstTopLevelSlots, reservedNames)
+		r := renamer.NewMinifyRenamer(c.graph.Symbols, firstTopLevelSlots, reservedNames, c.options.RuntimePrefix)
 
 		// Accumulate nested symbol usage counts
 		timer.Begin("Accumulate symbol counts")
@@ -4619,7 +5259,16 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		// over assigning minified names in order (i.e. "a b c ..."). Even though
 		// it's a very small win, we still do it because it's simple to do and very
 		// cheap to compute.
-		minifier := freq.Compile()
+		//
+		// If there's a name seed, the character sequence comes from the seed
+		// instead. This gives up the compression win in exchange for names that
+		// don't all change when an unrelated part of the code changes.
+		var minifier js_ast.NameMinifier
+		if c.options.NameSeed != "" {
+			minifier = js_ast.SeededNameMinifier(c.options.NameSeed)
+		} else {
+			minifier = freq.Compile()
+		}
 		timer.Begin("Assign names by frequency")
 		r.AssignNamesByFrequency(&minifier)
 		timer.End("Assign names by frequency")
@@ -4740,6 +5389,112 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 	return r
 }
 
+// Node's ES module loader doesn't provide "require", "__dirname", or
+// "__filename". This generates code for the top of the chunk that recreates
+// whichever ones are used by the files in the chunk (including calls to
+// "require()" for external modules, which are left as-is in this case).
+//
+// People commonly work around the lack of these globals with a banner such as
+// "const require = createRequire(import.meta.url)". Declaring the same name
+// again would be a syntax error, so names the banner or footer declares are
+// left alone.
+func (c *linkerContext) generateNodeESMShimsForChunk(chunkRepr *chunkReprJS, banner string, footer string, space string, newline string) string {
+	usesRequire := false
+	usesDirname := false
+	usesFilename := false
+
+	for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+		if sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+		isUnboundAndUsed := func(name string) bool {
+			if member, ok := repr.AST.ModuleScope.Members[name]; ok {
+				symbol := c.graph.Symbols.Get(member.Ref)
+				return symbol.Kind == js_ast.SymbolUnbound && symbol.UseCountEstimate > 0
+			}
+			return false
+		}
+		if isUnboundAndUsed("require") {
+			usesRequire = true
+		}
+		if isUnboundAndUsed("__dirname") {
+			usesDirname = true
+		}
+		if isUnboundAndUsed("__filename") {
+			usesFilename = true
+		}
+		if !usesRequire {
+			for _, record := range repr.AST.ImportRecords {
+				if !record.SourceIndex.IsValid() && !record.Flags.Has(ast.IsUnused) &&
+					(record.Kind == ast.ImportRequire || record.Kind == ast.ImportRequireResolve ||
+						(record.Kind == ast.ImportDynamic && c.options.UnsupportedJSFeatures.Has(compat.DynamicImport))) {
+					usesRequire = true
+					break
+				}
+			}
+		}
+	}
+
+	if (usesRequire || usesDirname || usesFilename) && (banner != "" || footer != "") {
+		declared := topLevelNamesDeclaredBy(banner + "\n" + footer)
+		usesRequire = usesRequire && !declared["require"]
+		usesDirname = usesDirname && !declared["__dirname"]
+		usesFilename = usesFilename && !declared["__filename"]
+	}
+
+	if !usesRequire && !usesDirname && !usesFilename {
+		return ""
+	}
+
+	kind := "const"
+	if c.options.UnsupportedJSFeatures.Has(compat.ConstAndLet) {
+		kind = "var"
+	}
+	sb := strings.Builder{}
+	if usesRequire {
+		sb.WriteString("import" + space + "{" + space + "createRequire as __createRequire" + space + "}" + space + "from" + space + "\"module\";" + newline)
+	}
+	if usesDirname || usesFilename {
+		sb.WriteString("import" + space + "{" + space + "fileURLToPath as __fileURLToPath" + space + "}" + space + "from" + space + "\"url\";" + newline)
+	}
+	if usesDirname {
+		sb.WriteString("import" + space + "{" + space + "dirname as __pathDirname" + space + "}" + space + "from" + space + "\"path\";" + newline)
+	}
+	if usesRequire {
+		sb.WriteString(kind + " require" + space + "=" + space + "__createRequire(import.meta.url);" + newline)
+	}
+	if usesFilename {
+		sb.WriteString(kind + " __filename" + space + "=" + space + "__fileURLToPath(import.meta.url);" + newline)
+	}
+	if usesDirname {
+		sb.WriteString(kind + " __dirname" + space + "=" + space + "__pathDirname(__fileURLToPath(import.meta.url));" + newline)
+	}
+	return sb.String()
+}
+
+// The banner and footer are arbitrary code, so they are parsed together (they
+// may open and close a wrapper) to find what they declare. Nothing is reported
+// as declared if they don't parse.
+func topLevelNamesDeclaredBy(code string) map[string]bool {
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	source := logger.Source{KeyPath: logger.Path{Text: "<banner>"}, PrettyPath: "<banner>", Contents: code}
+	tree, ok := js_parser.Parse(log, source, js_parser.OptionsFromConfig(&config.Options{}))
+	if !ok {
+		return nil
+	}
+	declared := make(map[string]bool)
+	for name, member := range tree.ModuleScope.Members {
+		if tree.Symbols[member.Ref.InnerIndex].Kind != js_ast.SymbolUnbound {
+			declared[name] = true
+		}
+	}
+	return declared
+}
+
 func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chunkWaitGroup *sync.WaitGroup) {
 	defer c.recoverInternalError(chunkWaitGroup, runtime.SourceIndex)
 
@@ -4880,10 +5635,11 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 		}
 	}
 
-	if len(c.options.JSBanner) > 0 {
-		prevOffset.AdvanceString(c.options.JSBanner)
+	banner, footer := c.bannerAndFooterForChunk(chunk, chunkIndex, c.options.JSBanner, c.options.JSFooter, "js")
+	if len(banner) > 0 {
+		prevOffset.AdvanceString(banner)
 		prevOffset.AdvanceString("\n")
-		j.AddString(c.options.JSBanner)
+		j.AddString(banner)
 		j.AddString("\n")
 	}
 
@@ -4911,6 +5667,15 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 		j.AddBytes(crossChunkPrefix)
 	}
 
+	// Define any CommonJS globals that this chunk needs but that don't exist
+	if config.ShouldShimNodeESMGlobals(c.options.Mode, c.options.Platform, c.options.OutputFormat) {
+		if text := c.generateNodeESMShimsForChunk(chunkRepr, banner, footer, space, newline); text != "" {
+			newlineBeforeComment = true
+			prevOffset.AdvanceString(text)
+			j.AddString(text)
+		}
+	}
+
 	// Start the metadata
 	jMeta := helpers.Joiner{}
 	if c.options.NeedsMetafile {
@@ -4927,6 +5692,21 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 				js_printer.QuoteForJSON(c.res.PrettyPath(logger.Path{Text: chunks[chunkImport.chunkIndex].uniqueKey, Namespace: "file"}), c.options.ASCIIOnly),
 				js_printer.QuoteForJSON(chunkImport.importKind.StringForMetafile(), c.options.ASCIIOnly)))
 		}
+		for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+			for _, record := range c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr).AST.ImportRecords {
+				if record.Flags.Has(ast.IsWorkerURL) && !record.Flags.Has(ast.IsInlineWorkerText|ast.IsInlineWorkerDataURL) &&
+					strings.HasPrefix(record.Path.Text, c.uniqueKeyPrefix) {
+					if isFirstMeta {
+						isFirstMeta = false
+					} else {
+						jMeta.AddString(",")
+					}
+					jMeta.AddString(fmt.Sprintf("\n        {\n          \"path\": %s,\n          \"kind\": %s\n        }",
+						js_printer.QuoteForJSON(record.Path.Text, c.options.ASCIIOnly),
+						js_printer.QuoteForJSON(record.Kind.StringForMetafile(), c.options.ASCIIOnly)))
+				}
+			}
+		}
 		if !isFirstMeta {
 			jMeta.AddString("\n      ")
 		}
@@ -4980,6 +5760,7 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 	var metaOrder []uint32
 	var metaByteCount map[string]int
 	legalCommentSet := make(map[string]bool)
+	legalCommentInputs := make(map[string][]uint32)
 	prevFileNameComment := uint32(0)
 	if c.options.NeedsMetafile {
 		metaOrder = make([]uint32, 0, len(compileResults))
@@ -4987,16 +5768,21 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 	}
 	for _, compileResult := range compileResults {
 		isRuntime := compileResult.sourceIndex == runtime.SourceIndex
+		jsLength := len(compileResult.JS)
+		if compileResult.spilledJS != nil {
+			jsLength = compileResult.spilledJS.length
+		}
 		for text := range compileResult.ExtractedLegalComments {
 			if !legalCommentSet[text] {
 				legalCommentSet[text] = true
 				legalCommentList = append(legalCommentList, text)
 			}
+			legalCommentInputs[text] = append(legalCommentInputs[text], compileResult.sourceIndex)
 		}
 
 		// Add a comment with the file path before the file contents
 		if c.options.Mode == config.ModeBundle && !c.options.MinifyWhitespace &&
-			prevFileNameComment != compileResult.sourceIndex && len(compileResult.JS) > 0 {
+			prevFileNameComment != compileResult.sourceIndex && jsLength > 0 {
 			if newlineBeforeComment {
 				prevOffset.AdvanceString("\n")
 				j.AddString("\n")
@@ -5025,11 +5811,19 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 		} else {
 			// Save the offset to the start of the stored JavaScript
 			compileResult.generatedOffset = prevOffset
-			j.AddBytes(compileResult.JS)
+			if spilled := compileResult.spilledJS; spilled != nil {
+				j.AddReader(uint32(spilled.length), spilled.lastByte, c.spilledCodeReader(spilled))
+			} else {
+				j.AddBytes(compileResult.JS)
+			}
 
 			// Ignore empty source map chunks
 			if compileResult.SourceMapChunk.ShouldIgnore {
-				prevOffset.AdvanceBytes(compileResult.JS)
+				if compileResult.spilledJS != nil {
+					prevOffset.Add(compileResult.spilledJS.endOffset)
+				} else {
+					prevOffset.AdvanceBytes(compileResult.JS)
+				}
 			} else {
 				prevOffset = sourcemap.LineColumnOffset{}
 
@@ -5048,16 +5842,16 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 				// Accumulate file sizes since a given file may be split into multiple parts
 				path := c.graph.Files[compileResult.sourceIndex].InputFile.Source.PrettyPath
 				if count, ok := metaByteCount[path]; ok {
-					metaByteCount[path] = count + len(compileResult.JS)
+					metaByteCount[path] = count + jsLength
 				} else {
 					metaOrder = append(metaOrder, compileResult.sourceIndex)
-					metaByteCount[path] = len(compileResult.JS)
+					metaByteCount[path] = jsLength
 				}
 			}
 		}
 
 		// Put a newline before the next file path comment
-		if len(compileResult.JS) > 0 {
+		if jsLength > 0 {
 			newlineBeforeComment = true
 		}
 	}
@@ -5083,9 +5877,17 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 	// Make sure the file ends with a newline
 	j.EnsureNewlineAtEnd()
 	maybeAppendLegalComments(c.options.LegalComments, legalCommentList, chunk, &j, "/script")
+	chunk.legalComments = c.legalCommentsWithInputs(legalCommentList, legalCommentInputs)
+
+	if c.options.NameMap {
+		chunk.nameMap = c.generateNameMapJS(chunkRepr.partsInChunkInOrder, r)
+	}
+	if c.options.ConcatReport {
+		chunk.concatReport = c.generateConcatReportJS(chunkRepr.filesInChunkInOrder, compileResults)
+	}
 
-	if len(c.options.JSFooter) > 0 {
-		j.AddString(c.options.JSFooter)
+	if len(footer) > 0 {
+		j.AddString(footer)
 		j.AddString("\n")
 	}
 
@@ -5119,7 +5921,7 @@ func (c *linkerContext) generateChunkJS(chunks []chunkInfo, chunkIndex int, chun
 			if !isFirstMeta {
 				jMeta.AddString("\n      ")
 			}
-			jMeta.AddString(fmt.Sprintf("},\n      \"bytes\": %d\n    }", finalOutputSize))
+			jMeta.AddString(fmt.Sprintf("}%s%s,\n      \"bytes\": %d\n    }", c.chunkPriorityMetadata(chunk), chunkDebugIDMetadata(chunk), finalOutputSize))
 			return jMeta
 		}
 	}
@@ -5133,6 +5935,10 @@ func (c *linkerContext) generateGlobalNamePrefix() string {
 	var text string
 	prefix := c.options.GlobalName[0]
 	space := " "
+
+	// The "bmp" charset escapes all non-ASCII characters in the global name
+	// since it's not worth handling this rare case specially
+	asciiOnly := c.options.ASCIIOnly || c.options.BMPOnly
 	join := ";\n"
 
 	if c.options.MinifyWhitespace {
@@ -5140,25 +5946,25 @@ func (c *linkerContext) generateGlobalNamePrefix() string {
 		join = ";"
 	}
 
-	if js_printer.CanEscapeIdentifier(prefix, c.options.UnsupportedJSFeatures, c.options.ASCIIOnly) {
-		if c.options.ASCIIOnly {
+	if js_printer.CanEscapeIdentifier(prefix, c.options.UnsupportedJSFeatures, asciiOnly) {
+		if asciiOnly {
 			prefix = string(js_printer.QuoteIdentifier(nil, prefix, c.options.UnsupportedJSFeatures))
 		}
 		text = fmt.Sprintf("var %s%s=%s", prefix, space, space)
 	} else {
-		prefix = fmt.Sprintf("this[%s]", js_printer.QuoteForJSON(prefix, c.options.ASCIIOnly))
+		prefix = fmt.Sprintf("this[%s]", js_printer.QuoteForJSON(prefix, asciiOnly))
 		text = fmt.Sprintf("%s%s=%s", prefix, space, space)
 	}
 
 	for _, name := range c.options.GlobalName[1:] {
 		oldPrefix := prefix
-		if js_printer.CanEscapeIdentifier(name, c.options.UnsupportedJSFeatures, c.options.ASCIIOnly) {
-			if c.options.ASCIIOnly {
+		if js_printer.CanEscapeIdentifier(name, c.options.UnsupportedJSFeatures, asciiOnly) {
+			if asciiOnly {
 				name = string(js_printer.QuoteIdentifier(nil, name, c.options.UnsupportedJSFeatures))
 			}
 			prefix = fmt.Sprintf("%s.%s", prefix, name)
 		} else {
-			prefix = fmt.Sprintf("%s[%s]", prefix, js_printer.QuoteForJSON(name, c.options.ASCIIOnly))
+			prefix = fmt.Sprintf("%s[%s]", prefix, js_printer.QuoteForJSON(name, asciiOnly))
 		}
 		text += fmt.Sprintf("%s%s||%s{}%s%s%s=%s", oldPrefix, space, space, join, prefix, space, space)
 	}
@@ -5166,6 +5972,176 @@ func (c *linkerContext) generateGlobalNamePrefix() string {
 	return text
 }
 
+// This generates a JSON file that maps original names to the names that were
+// used in the output for this chunk. It's intended for de-obfuscating stack
+// traces in environments that can't use source maps. Top-level symbols are
+// grouped by input file since the same name can be declared in many files.
+func (c *linkerContext) generateNameMapJS(partsInChunkInOrder []partRange, r renamer.Renamer) []byte {
+	type fileNames struct {
+		path  string
+		names map[string]string
+	}
+	var files []fileNames
+	fileIndex := make(map[uint32]int)
+
+	for _, partRange := range partsInChunkInOrder {
+		if partRange.sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		repr := c.graph.Files[partRange.sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		for partIndex := partRange.partIndexBegin; partIndex < partRange.partIndexEnd; partIndex++ {
+			for _, declared := range repr.AST.Parts[partIndex].DeclaredSymbols {
+				if !declared.IsTopLevel {
+					continue
+				}
+				ref := js_ast.FollowSymbols(c.graph.Symbols, declared.Ref)
+				original := c.graph.Symbols.Get(ref).OriginalName
+				name := r.NameForSymbol(ref)
+				if name == original {
+					continue
+				}
+				index, ok := fileIndex[partRange.sourceIndex]
+				if !ok {
+					index = len(files)
+					fileIndex[partRange.sourceIndex] = index
+					files = append(files, fileNames{
+						path:  c.graph.Files[partRange.sourceIndex].InputFile.Source.PrettyPath,
+						names: make(map[string]string),
+					})
+				}
+				files[index].names[original] = name
+			}
+		}
+	}
+
+	// Sort everything for determinism
+	sort.Slice(files, func(i int, j int) bool { return files[i].path < files[j].path })
+	writeNames := func(j *helpers.Joiner, names map[string]string, indent string) {
+		keys := make([]string, 0, len(names))
+		for key := range names {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			if i > 0 {
+				j.AddString(",")
+			}
+			j.AddString(fmt.Sprintf("\n%s%s: %s", indent,
+				js_printer.QuoteForJSON(key, c.options.ASCIIOnly),
+				js_printer.QuoteForJSON(names[key], c.options.ASCIIOnly)))
+		}
+		if len(keys) > 0 {
+			j.AddString("\n" + indent[:len(indent)-2])
+		}
+	}
+
+	j := helpers.Joiner{}
+	j.AddString("{\n  \"symbols\": {")
+	for i, file := range files {
+		if i > 0 {
+			j.AddString(",")
+		}
+		j.AddString(fmt.Sprintf("\n    %s: {", js_printer.QuoteForJSON(file.path, c.options.ASCIIOnly)))
+		writeNames(&j, file.names, "      ")
+		j.AddString("}")
+	}
+	if len(files) > 0 {
+		j.AddString("\n  ")
+	}
+	j.AddString("},\n  \"props\": {")
+	writeNames(&j, c.mangledPropNames, "    ")
+	j.AddString("}\n}\n")
+	return j.Done()
+}
+
+// This generates a JSON file that describes which input files were hoisted
+// into the top-level scope of this chunk and which ones had to be wrapped in
+// a closure instead. Wrapped files are slower and bigger, so this is intended
+// to help people restructure their code to avoid wrappers.
+func (c *linkerContext) generateConcatReportJS(filesInChunkInOrder []uint32, compileResults []compileResultJS) []byte {
+	wrapperOverhead := make(map[uint32]int)
+	for _, compileResult := range compileResults {
+		wrapperOverhead[compileResult.sourceIndex] += compileResult.wrapperOverhead
+	}
+
+	var hoisted []uint32
+	var wrapped []uint32
+	for _, sourceIndex := range filesInChunkInOrder {
+		if sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok {
+			if repr.Meta.Wrap == graph.WrapNone {
+				hoisted = append(hoisted, sourceIndex)
+			} else {
+				wrapped = append(wrapped, sourceIndex)
+			}
+		}
+	}
+
+	quote := func(sourceIndex uint32) string {
+		return string(js_printer.QuoteForJSON(c.graph.Files[sourceIndex].InputFile.Source.PrettyPath, c.options.ASCIIOnly))
+	}
+
+	j := helpers.Joiner{}
+	j.AddString("{\n  \"hoisted\": [")
+	for i, sourceIndex := range hoisted {
+		if i > 0 {
+			j.AddString(",")
+		}
+		j.AddString("\n    " + quote(sourceIndex))
+	}
+	if len(hoisted) > 0 {
+		j.AddString("\n  ")
+	}
+	j.AddString("],\n  \"wrapped\": [")
+	for i, sourceIndex := range wrapped {
+		repr := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if i > 0 {
+			j.AddString(",")
+		}
+		kind := "esm"
+		if repr.Meta.Wrap == graph.WrapCJS {
+			kind = "commonjs"
+		}
+		j.AddString(fmt.Sprintf("\n    {\n      \"path\": %s,\n      \"wrapper\": %q,\n      \"reason\": %q,",
+			quote(sourceIndex), kind, repr.Meta.WrapReason.String()))
+		isCycle := false
+		if repr.Meta.WrapImporter.IsValid() {
+			importer := repr.Meta.WrapImporter.GetIndex()
+			isCycle = c.isReachableFrom(sourceIndex, importer, make(map[uint32]bool))
+			j.AddString(fmt.Sprintf("\n      \"importer\": %s,", quote(importer)))
+		}
+		j.AddString(fmt.Sprintf("\n      \"async\": %v,\n      \"cycle\": %v,\n      \"overheadBytes\": %d\n    }",
+			repr.Meta.IsAsyncOrHasAsyncDependency, isCycle, wrapperOverhead[sourceIndex]))
+	}
+	if len(wrapped) > 0 {
+		j.AddString("\n  ")
+	}
+	j.AddString("]\n}\n")
+	return j.Done()
+}
+
+// This returns true if "target" is a direct or indirect import of "sourceIndex"
+func (c *linkerContext) isReachableFrom(sourceIndex uint32, target uint32, visited map[uint32]bool) bool {
+	if visited[sourceIndex] {
+		return false
+	}
+	visited[sourceIndex] = true
+	repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+	if !ok {
+		return false
+	}
+	for _, record := range repr.AST.ImportRecords {
+		if record.SourceIndex.IsValid() {
+			if otherSourceIndex := record.SourceIndex.GetIndex(); otherSourceIndex == target || c.isReachableFrom(otherSourceIndex, target, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type compileResultCSS struct {
 	css_printer.PrintResult
 
@@ -5177,6 +6153,57 @@ type compileResultCSS struct {
 	hasCharset  bool
 }
 
+// This finds top-level rules that are identical to a top-level rule in a later
+// file in the same chunk. Only the last copy needs to be kept since it always
+// takes precedence over the earlier copies. The files have already removed
+// their own duplicate rules when they were parsed.
+func (c *linkerContext) findDuplicateCSSRulesAcrossFiles(filesInChunkInOrder []uint32) map[css_ast.R]bool {
+	type hashEntry struct {
+		rules []css_ast.R
+	}
+
+	duplicateRules := make(map[css_ast.R]bool)
+	entries := make(map[uint32]hashEntry)
+
+	for i := len(filesInChunkInOrder) - 1; i >= 0; i-- {
+		rules := c.graph.Files[filesInChunkInOrder[i]].InputFile.Repr.(*graph.CSSRepr).AST.Rules
+	nextRule:
+		for j := len(rules) - 1; j >= 0; j-- {
+			rule := rules[j]
+
+			switch rule.Data.(type) {
+			case *css_ast.RAtCharset, *css_ast.RAtImport, *css_ast.RComment:
+				continue
+
+			case *css_ast.RAtLayer:
+				// Removing a layer could change the order in which layers are first
+				// declared, which determines their precedence
+				continue
+			}
+
+			// URL tokens can't be compared across files because they refer to the
+			// import records of each file
+			if css_ast.RulesUseImportRecords(rules[j : j+1]) {
+				continue
+			}
+
+			if hash, ok := rule.Data.Hash(); ok {
+				entry := entries[hash]
+				for _, other := range entry.rules {
+					if rule.Data.Equal(other) {
+						duplicateRules[rule.Data] = true
+						continue nextRule
+					}
+				}
+				entry.rules = append(entry.rules, rule.Data)
+				entries[hash] = entry
+			}
+		}
+	}
+
+	return duplicateRules
+}
+
 func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chunkWaitGroup *sync.WaitGroup) {
 	defer c.recoverInternalError(chunkWaitGroup, runtime.SourceIndex)
 
@@ -5203,6 +6230,12 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 	// never change the "../" count.
 	chunkAbsDir := c.fs.Dir(c.fs.Join(c.options.AbsOutputDir, config.TemplateToString(chunk.finalTemplate)))
 
+	// Rules that are repeated in a later file are redundant when minifying
+	var duplicateRules map[css_ast.R]bool
+	if c.options.MinifySyntax {
+		duplicateRules = c.findDuplicateCSSRulesAcrossFiles(chunkRepr.filesInChunkInOrder)
+	}
+
 	// Generate CSS for each file in parallel
 	timer.Begin("Print CSS files")
 	waitGroup := sync.WaitGroup{}
@@ -5212,6 +6245,16 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 		go func(sourceIndex uint32, compileResult *compileResultCSS) {
 			defer c.recoverInternalError(&waitGroup, sourceIndex)
 
+			// The output of a cancelled build is thrown away, so skip printing it
+			if c.options.CancelFlag.DidCancel() {
+				compileResult.sourceIndex = sourceIndex
+				waitGroup.Done()
+				return
+			}
+
+			c.options.Workers.Acquire()
+			defer c.options.Workers.Release()
+
 			file := &c.graph.Files[sourceIndex]
 			ast := file.InputFile.Repr.(*graph.CSSRepr).AST
 
@@ -5226,8 +6269,14 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 				case *css_ast.RAtImport:
 					continue
 				}
+				if duplicateRules[rule.Data] {
+					continue
+				}
 				rules = append(rules, rule)
 			}
+			if c.purgeCSSNames != nil {
+				rules = c.purgeUnusedCSSRules(rules)
+			}
 			ast.Rules = rules
 
 			// Only generate a source map if needed
@@ -5243,6 +6292,7 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 			cssOptions := css_printer.Options{
 				MinifyWhitespace:  c.options.MinifyWhitespace,
 				ASCIIOnly:         c.options.ASCIIOnly,
+				BMPOnly:           c.options.BMPOnly,
 				LegalComments:     c.options.LegalComments,
 				AddSourceMappings: addSourceMappings,
 				InputSourceMap:    inputSourceMap,
@@ -5264,9 +6314,10 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 	prevOffset := sourcemap.LineColumnOffset{}
 	newlineBeforeComment := false
 
-	if len(c.options.CSSBanner) > 0 {
-		prevOffset.AdvanceString(c.options.CSSBanner)
-		j.AddString(c.options.CSSBanner)
+	banner, footer := c.bannerAndFooterForChunk(chunk, chunkIndex, c.options.CSSBanner, c.options.CSSFooter, "css")
+	if len(banner) > 0 {
+		prevOffset.AdvanceString(banner)
+		j.AddString(banner)
 		prevOffset.AdvanceString("\n")
 		j.AddString("\n")
 	}
@@ -5303,6 +6354,7 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 			result := css_printer.Print(tree, css_printer.Options{
 				MinifyWhitespace: c.options.MinifyWhitespace,
 				ASCIIOnly:        c.options.ASCIIOnly,
+				BMPOnly:          c.options.BMPOnly,
 			})
 			if len(result.CSS) > 0 {
 				prevOffset.AdvanceBytes(result.CSS)
@@ -5352,12 +6404,14 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 	var compileResultsForSourceMap []compileResultForSourceMap
 	var legalCommentList []string
 	legalCommentSet := make(map[string]bool)
+	legalCommentInputs := make(map[string][]uint32)
 	for _, compileResult := range compileResults {
 		for text := range compileResult.ExtractedLegalComments {
 			if !legalCommentSet[text] {
 				legalCommentSet[text] = true
 				legalCommentList = append(legalCommentList, text)
 			}
+			legalCommentInputs[text] = append(legalCommentInputs[text], compileResult.sourceIndex)
 		}
 
 		if c.options.Mode == config.ModeBundle && !c.options.MinifyWhitespace {
@@ -5409,9 +6463,10 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 	// Make sure the file ends with a newline
 	j.EnsureNewlineAtEnd()
 	maybeAppendLegalComments(c.options.LegalComments, legalCommentList, chunk, &j, "/style")
+	chunk.legalComments = c.legalCommentsWithInputs(legalCommentList, legalCommentInputs)
 
-	if len(c.options.CSSFooter) > 0 {
-		j.AddString(c.options.CSSFooter)
+	if len(footer) > 0 {
+		j.AddString(footer)
 		j.AddString("\n")
 	}
 
@@ -5433,7 +6488,7 @@ func (c *linkerContext) generateChunkCSS(chunks []chunkInfo, chunkIndex int, chu
 			if !isFirstMeta {
 				jMeta.AddString("\n      ")
 			}
-			jMeta.AddString(fmt.Sprintf("},\n      \"bytes\": %d\n    }", finalOutputSize))
+			jMeta.AddString(fmt.Sprintf("}%s%s,\n      \"bytes\": %d\n    }", c.chunkPriorityMetadata(chunk), chunkDebugIDMetadata(chunk), finalOutputSize))
 			return jMeta
 		}
 	}
@@ -5475,6 +6530,22 @@ func maybeAppendLegalComments(
 	}
 }
 
+// The list of legal comments must already be sorted
+func (c *linkerContext) legalCommentsWithInputs(legalCommentList []string, legalCommentInputs map[string][]uint32) []graph.LegalComment {
+	if len(legalCommentList) == 0 {
+		return nil
+	}
+	legalComments := make([]graph.LegalComment, len(legalCommentList))
+	for i, text := range legalCommentList {
+		inputPaths := make([]string, 0, len(legalCommentInputs[text]))
+		for _, sourceIndex := range legalCommentInputs[text] {
+			inputPaths = append(inputPaths, c.graph.Files[sourceIndex].InputFile.Source.PrettyPath)
+		}
+		legalComments[i] = graph.LegalComment{Text: text, InputPaths: inputPaths}
+	}
+	return legalComments
+}
+
 func (c *linkerContext) appendIsolatedHashesForImportedChunks(
 	hash hash.Hash,
 	chunks []chunkInfo,
@@ -5511,6 +6582,12 @@ func (c *linkerContext) appendIsolatedHashesForImportedChunks(
 
 			// Mix in the hash for the relative path, which ends up as a JS string
 			hashWriteLengthPrefixed(hash, []byte(relPath))
+		} else if piece.kind == outputPieceWorkerIndex {
+			// The path to the worker already contains the worker's hash
+			hashWriteLengthPrefixed(hash, []byte(c.relPathForWorker(piece.index)))
+		} else if piece.kind == outputPieceInlineWorkerTextIndex || piece.kind == outputPieceInlineWorkerDataURLIndex {
+			// Inlined workers are part of the contents of this chunk
+			hashWriteLengthPrefixed(hash, c.inlineWorkerContents[piece.index])
 		}
 	}
 
@@ -5518,6 +6595,32 @@ func (c *linkerContext) appendIsolatedHashesForImportedChunks(
 	hash.Write(chunk.waitForIsolatedHash())
 }
 
+// The banner and footer can be customized for each output file by a callback,
+// and can contain the "[name]" and "[hash]" placeholders. The final hash isn't
+// known yet, so "[hash]" is replaced by a unique key that's substituted later.
+func (c *linkerContext) bannerAndFooterForChunk(chunk *chunkInfo, chunkIndex int, banner string, footer string, kind string) (string, string) {
+	if c.options.BannerCallback != nil {
+		var entryPoint string
+		if chunk.isEntryPoint {
+			entryPoint = c.graph.Files[chunk.sourceIndex].InputFile.Source.PrettyPath
+		}
+		banner, footer = c.options.BannerCallback(config.BannerArgs{
+			EntryPoint: entryPoint,
+			Kind:       kind,
+			Name:       chunk.name,
+		})
+	}
+	hashKey := fmt.Sprintf("%sH%08d", c.uniqueKeyPrefix, chunkIndex)
+	substitute := func(text string) string {
+		if strings.Contains(text, "[hash]") {
+			chunk.isHashInBannerOrFooter = true
+			text = strings.ReplaceAll(text, "[hash]", hashKey)
+		}
+		return strings.ReplaceAll(text, "[name]", chunk.name)
+	}
+	return substitute(banner), substitute(footer)
+}
+
 func (c *linkerContext) breakOutputIntoPieces(j helpers.Joiner, chunkCount uint32) intermediateOutput {
 	// Optimization: If there can be no substitutions, just reuse the initial
 	// joiner that was used when generating the intermediate chunk output
@@ -5529,6 +6632,15 @@ func (c *linkerContext) breakOutputIntoPieces(j helpers.Joiner, chunkCount uint3
 	var pieces []outputPiece
 	output := j.Done()
 	prefix := c.uniqueKeyPrefixBytes
+
+	// The joiner can't tell for sure if it contains code that hasn't been read
+	// from the spill file yet, so check again now that it has been read
+	if !bytes.Contains(output, prefix) {
+		j = helpers.Joiner{}
+		j.AddBytes(output)
+		return intermediateOutput{joiner: j}
+	}
+
 	for {
 		// Scan for the next piece boundary
 		boundary := bytes.Index(output, prefix)
@@ -5545,6 +6657,14 @@ func (c *linkerContext) breakOutputIntoPieces(j helpers.Joiner, chunkCount uint3
 					kind = outputPieceAssetIndex
 				case 'C':
 					kind = outputPieceChunkIndex
+				case 'W':
+					kind = outputPieceWorkerIndex
+				case 'T':
+					kind = outputPieceInlineWorkerTextIndex
+				case 'U':
+					kind = outputPieceInlineWorkerDataURLIndex
+				case 'H':
+					kind = outputPieceHashIndex
 				}
 				for j := 1; j < 9; j++ {
 					c := output[start+j]
@@ -5564,11 +6684,21 @@ func (c *linkerContext) breakOutputIntoPieces(j helpers.Joiner, chunkCount uint3
 				boundary = -1
 			}
 
-		case outputPieceChunkIndex:
+		case outputPieceChunkIndex, outputPieceHashIndex:
 			if index >= chunkCount {
 				boundary = -1
 			}
 
+		case outputPieceWorkerIndex:
+			if c.absWorkerOutputPaths[index] == "" {
+				boundary = -1
+			}
+
+		case outputPieceInlineWorkerTextIndex, outputPieceInlineWorkerDataURLIndex:
+			if _, ok := c.inlineWorkerContents[index]; !ok {
+				boundary = -1
+			}
+
 		default:
 			boundary = -1
 		}
@@ -5607,6 +6737,12 @@ func (c *linkerContext) generateIsolatedHashInParallel(chunk *chunkInfo) {
 func (c *linkerContext) generateIsolatedHash(chunk *chunkInfo, channel chan []byte) {
 	hash := xxhash.New()
 
+	// Mix in the user-specified salt, if any. This allows for separating the
+	// hashes of otherwise identical builds (e.g. different deployments).
+	if c.options.HashSalt != "" {
+		hashWriteLengthPrefixed(hash, []byte(c.options.HashSalt))
+	}
+
 	// Mix the file names and part ranges of all of the files in this chunk into
 	// the hash. Objects that appear identical but that live in separate files or
 	// that live in separate parts in the same file must not be merged. This only
@@ -5794,6 +6930,28 @@ type compileResultForSourceMap struct {
 	sourceIndex     uint32
 }
 
+// This reads the spilled code directly into the output file when the output
+// file is joined together
+func (c *linkerContext) spilledCodeReader(spilled *spilledCode) func(buffer []byte) {
+	return func(buffer []byte) {
+		if err := c.spill.readInto(spilled.spillRange, buffer); err != nil {
+			c.log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to read from spill file: %s", err.Error()))
+		}
+	}
+}
+
+func (c *linkerContext) quotedContentsForSourceMap(data *dataForSourceMap, index int) []byte {
+	if data.spill == nil {
+		return data.quotedContents[index]
+	}
+	contents, err := data.spill.read(data.spilledContents[index])
+	if err != nil {
+		c.log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to read from spill file: %s", err.Error()))
+		return []byte("null")
+	}
+	return contents
+}
+
 func (c *linkerContext) generateSourceMapForChunk(
 	results []compileResultForSourceMap,
 	chunkAbsDir string,
@@ -5811,6 +6969,7 @@ func (c *linkerContext) generateSourceMapForChunk(
 		path           logger.Path
 		prettyPath     string
 		quotedContents []byte
+		isIgnored      bool
 	}
 	items := make([]item, 0, len(results))
 	nextSourcesIndex := 0
@@ -5825,7 +6984,7 @@ func (c *linkerContext) generateSourceMapForChunk(
 		if file.InputFile.InputSourceMap == nil {
 			var quotedContents []byte
 			if !c.options.ExcludeSourcesContent {
-				quotedContents = dataForSourceMaps[result.sourceIndex].quotedContents[0]
+				quotedContents = c.quotedContentsForSourceMap(&dataForSourceMaps[result.sourceIndex], 0)
 			}
 			items = append(items, item{
 				path:           file.InputFile.Source.KeyPath,
@@ -5845,19 +7004,25 @@ func (c *linkerContext) generateSourceMapForChunk(
 			}
 
 			// If this file is in the "file" namespace, change the relative path in
-			// the source map into an absolute path using the directory of this file
-			if path.Namespace == "file" {
-				path.Text = c.fs.Join(c.fs.Dir(file.InputFile.Source.KeyPath.Text), source)
+			// the source map into an absolute path using the directory of this file.
+			// URLs such as "webpack://" paths are passed through unmodified.
+			if path.Namespace == "file" && !c.fs.IsAbs(source) {
+				if helpers.HasURLScheme(source) {
+					path.Namespace = ""
+				} else {
+					path.Text = c.fs.Join(c.fs.Dir(file.InputFile.Source.KeyPath.Text), source)
+				}
 			}
 
 			var quotedContents []byte
 			if !c.options.ExcludeSourcesContent {
-				quotedContents = dataForSourceMaps[result.sourceIndex].quotedContents[i]
+				quotedContents = c.quotedContentsForSourceMap(&dataForSourceMaps[result.sourceIndex], i)
 			}
 			items = append(items, item{
 				path:           path,
 				prettyPath:     source,
 				quotedContents: quotedContents,
+				isIgnored:      sm.IgnoreList != nil && sm.IgnoreList[i],
 			})
 		}
 		nextSourcesIndex += len(sm.Sources)
@@ -5879,6 +7044,29 @@ func (c *linkerContext) generateSourceMapForChunk(
 			}
 		}
 
+		// Third-party code is hidden by debuggers if it's in the ignore list
+		if c.options.SourcesIgnoreList != nil {
+			items[i].isIgnored = item.isIgnored || c.options.SourcesIgnoreList.MatchString(item.prettyPath)
+		} else {
+			items[i].isIgnored = item.isIgnored || helpers.IsInsideNodeModules(item.path.Text)
+		}
+
+		// Let the user customize the path (e.g. to strip a monorepo prefix)
+		if c.options.SourcesRewrite != nil {
+			args := config.SourcesRewriteArgs{
+				Path:      item.prettyPath,
+				InputPath: item.prettyPath,
+				Namespace: item.path.Namespace,
+			}
+			if item.path.Namespace == "file" {
+				args.AbsPath = item.path.Text
+				args.InputPath = c.res.PrettyPath(item.path)
+			}
+			if path := c.options.SourcesRewrite(args); path != "" {
+				item.prettyPath = path
+			}
+		}
+
 		j.AddBytes(js_printer.QuoteForJSON(item.prettyPath, c.options.ASCIIOnly))
 	}
 	j.AddString("]")
@@ -5888,6 +7076,24 @@ func (c *linkerContext) generateSourceMapForChunk(
 		j.AddBytes(js_printer.QuoteForJSON(c.options.SourceRoot, c.options.ASCIIOnly))
 	}
 
+	// Write the sources that debuggers should hide. This includes sources that
+	// were in the ignore lists of input source maps.
+	hasIgnoreList := false
+	for i, item := range items {
+		if item.isIgnored {
+			if !hasIgnoreList {
+				j.AddString(",\n  \"x_google_ignoreList\": [")
+				hasIgnoreList = true
+			} else {
+				j.AddString(", ")
+			}
+			j.AddString(strconv.Itoa(i))
+		}
+	}
+	if hasIgnoreList {
+		j.AddString("]")
+	}
+
 	// Write the sourcesContent
 	if !c.options.ExcludeSourcesContent {
 		j.AddString(",\n  \"sourcesContent\": [")
@@ -0,0 +1,342 @@
+package bundler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// These are searched for in this order in the working directory and then in
+// each parent directory (so that the lock file at the root of a monorepo is
+// found when building one of its packages)
+var lockfileNames = []string{"package-lock.json", "pnpm-lock.yaml", "yarn.lock"}
+
+// This maps each package name to the set of versions in the lock file
+type lockfilePackages map[string]map[string]bool
+
+func (p lockfilePackages) add(name string, version string) {
+	if name == "" || version == "" {
+		return
+	}
+	versions := p[name]
+	if versions == nil {
+		versions = make(map[string]bool)
+		p[name] = versions
+	}
+	versions[version] = true
+}
+
+// This fails the build if a package in the bundle is at a version that isn't
+// in the lock file. That happens when a package was installed or updated
+// without updating the lock file, when code imports a package that is only in
+// "node_modules" because it was hoisted from another package's dependencies
+// (a "phantom dependency"), or when hoisting picks a different copy than
+// expected. Packages are visited in breadth-first order from the entry points
+// so that the import chain reported for each package is as short as possible.
+func (s *scanner) verifyLockfile(files []scannerFile, entryPoints []graph.EntryPoint) {
+	s.timer.Begin("Verify lock file")
+	defer s.timer.End("Verify lock file")
+
+	lockfilePath, packages, ok := s.readLockfile()
+	if !ok {
+		return
+	}
+	prettyLockfilePath := s.res.PrettyPath(logger.Path{Text: lockfilePath, Namespace: "file"})
+
+	type importer struct {
+		sourceIndex uint32
+		recordIndex uint32
+	}
+	importers := make(map[uint32]importer)
+	visited := make([]bool, len(files))
+	queue := make([]uint32, 0, len(files))
+	checkedPackages := make(map[string]bool)
+
+	for _, entryPoint := range entryPoints {
+		if !visited[entryPoint.SourceIndex] {
+			visited[entryPoint.SourceIndex] = true
+			queue = append(queue, entryPoint.SourceIndex)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		sourceIndex := queue[i]
+		file := &files[sourceIndex]
+
+		if keyPath := file.inputFile.Source.KeyPath; keyPath.Namespace == "file" && helpers.IsInsideNodeModules(keyPath.Text) {
+			if pkgDir, ok := s.packageDirForPath(keyPath.Text); ok && !checkedPackages[pkgDir] {
+				checkedPackages[pkgDir] = true
+
+				// Packages without a version can't be checked
+				if name, version := s.readPackageNameAndVersion(pkgDir); version != "" && !packages[name][version] {
+					var text string
+					var notes []logger.MsgData
+					prettyDir := s.res.PrettyPath(logger.Path{Text: pkgDir, Namespace: "file"})
+					if versions := packages[name]; len(versions) > 0 {
+						text = fmt.Sprintf("The package %q in %q is at version %s, which is not in %q",
+							name, prettyDir, version, prettyLockfilePath)
+						sorted := make([]string, 0, len(versions))
+						for v := range versions {
+							sorted = append(sorted, name+"@"+v)
+						}
+						sort.Strings(sorted)
+						notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The lock file contains %s instead.", strings.Join(sorted, ", "))})
+					} else {
+						text = fmt.Sprintf("The package %q in %q is not in %q", name, prettyDir, prettyLockfilePath)
+						notes = append(notes, logger.MsgData{Text: "This usually means that the package is only present because another " +
+							"package depends on it. You should add it to your own dependencies if you import it directly."})
+					}
+
+					// Show where the package was imported and how that file was reached
+					if parent, ok := importers[sourceIndex]; ok {
+						parentSource := &files[parent.sourceIndex].inputFile.Source
+						record := &(*files[parent.sourceIndex].inputFile.Repr.ImportRecords())[parent.recordIndex]
+						tracker := logger.MakeLineColumnTracker(parentSource)
+						notes = append(notes, tracker.MsgData(record.Range, fmt.Sprintf("The package %q is imported here:", name)))
+					}
+					chain := []string{file.inputFile.Source.PrettyPath}
+					for current, ok := importers[sourceIndex]; ok; current, ok = importers[current.sourceIndex] {
+						chain = append(chain, files[current.sourceIndex].inputFile.Source.PrettyPath)
+					}
+					for a, b := 0, len(chain)-1; a < b; a, b = a+1, b-1 {
+						chain[a], chain[b] = chain[b], chain[a]
+					}
+					notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The import chain is: %s", strings.Join(chain, " -> "))})
+
+					s.log.AddErrorWithNotes(nil, logger.Range{}, text, notes)
+				}
+			}
+		}
+
+		if repr := file.inputFile.Repr; repr != nil {
+			if records := repr.ImportRecords(); records != nil {
+				for recordIndex, record := range *records {
+					if record.SourceIndex.IsValid() {
+						if other := record.SourceIndex.GetIndex(); !visited[other] {
+							visited[other] = true
+							importers[other] = importer{sourceIndex: sourceIndex, recordIndex: uint32(recordIndex)}
+							queue = append(queue, other)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *scanner) readLockfile() (string, lockfilePackages, bool) {
+	for dir := s.fs.Cwd(); ; {
+		for _, name := range lockfileNames {
+			absPath := s.fs.Join(dir, name)
+			contents, err, _ := s.caches.FSCache.ReadFile(s.fs, absPath)
+			if err != nil {
+				continue
+			}
+			path := logger.Path{Text: absPath, Namespace: "file"}
+			source := logger.Source{KeyPath: path, PrettyPath: s.res.PrettyPath(path), Contents: contents}
+			packages := make(lockfilePackages)
+			switch name {
+			case "package-lock.json":
+				json, ok := js_parser.ParseJSON(s.log, source, js_parser.JSONOptions{})
+				if !ok {
+					return "", nil, false
+				}
+				parsePackageLockJSON(json, packages)
+			case "pnpm-lock.yaml":
+				parsePnpmLock(contents, packages)
+			case "yarn.lock":
+				parseYarnLock(contents, packages)
+			}
+			return absPath, packages, true
+		}
+
+		parent := s.fs.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	s.log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot verify packages because no lock file was found (looked for %s)",
+		helpers.StringArrayToQuotedCommaSeparatedString(lockfileNames)))
+	return "", nil, false
+}
+
+// This handles both the "packages" map in lock file versions 2 and 3 and the
+// nested "dependencies" map in lock file version 1
+func parsePackageLockJSON(json js_ast.Expr, packages lockfilePackages) {
+	obj, ok := json.Data.(*js_ast.EObject)
+	if !ok {
+		return
+	}
+
+	var visitDependencies func(value js_ast.Expr)
+	visitDependencies = func(value js_ast.Expr) {
+		if deps, ok := value.Data.(*js_ast.EObject); ok {
+			for _, prop := range deps.Properties {
+				if key, ok := prop.Key.Data.(*js_ast.EString); ok {
+					name := helpers.UTF16ToString(key.Value)
+					version := jsonStringProperty(prop.ValueOrNil, "version")
+
+					// Aliased packages look like "npm:real-name@1.2.3"
+					if strings.HasPrefix(version, "npm:") {
+						if at := strings.LastIndexByte(version, '@'); at > len("npm:") {
+							name, version = version[len("npm:"):at], version[at+1:]
+						}
+					}
+					packages.add(name, version)
+					if nested, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
+						for _, nestedProp := range nested.Properties {
+							if nestedKey, ok := nestedProp.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(nestedKey.Value, "dependencies") {
+								visitDependencies(nestedProp.ValueOrNil)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, prop := range obj.Properties {
+		key, ok := prop.Key.Data.(*js_ast.EString)
+		if !ok {
+			continue
+		}
+		switch helpers.UTF16ToString(key.Value) {
+		case "packages":
+			if entries, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
+				for _, entry := range entries.Properties {
+					if entryKey, ok := entry.Key.Data.(*js_ast.EString); ok {
+						// Keys look like "node_modules/a/node_modules/@scope/b"
+						path := helpers.UTF16ToString(entryKey.Value)
+						i := strings.LastIndex(path, "node_modules/")
+						if i == -1 {
+							continue
+						}
+
+						// Aliased packages have a "name" field with the real name
+						name := jsonStringProperty(entry.ValueOrNil, "name")
+						if name == "" {
+							name = path[i+len("node_modules/"):]
+						}
+						packages.add(name, jsonStringProperty(entry.ValueOrNil, "version"))
+					}
+				}
+			}
+
+		case "dependencies":
+			visitDependencies(prop.ValueOrNil)
+		}
+	}
+}
+
+func jsonStringProperty(value js_ast.Expr, name string) string {
+	if obj, ok := value.Data.(*js_ast.EObject); ok {
+		for _, prop := range obj.Properties {
+			if key, ok := prop.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(key.Value, name) {
+				if str, ok := prop.ValueOrNil.Data.(*js_ast.EString); ok {
+					return helpers.UTF16ToString(str.Value)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// This only understands the keys of the "packages" section, which identify
+// each installed package. The key format depends on the lock file version:
+//
+//	v5: "/@scope/name/1.2.3_peer@4.5.6"
+//	v6: "/@scope/name@1.2.3(peer@4.5.6)"
+//	v9: "'@scope/name@1.2.3'"
+func parsePnpmLock(contents string, packages lockfilePackages) {
+	isV5 := false
+	inPackages := false
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		// Top-level keys start a new section
+		if line[0] != ' ' {
+			inPackages = line == "packages:"
+			if strings.HasPrefix(line, "lockfileVersion:") {
+				version := strings.Trim(strings.TrimSpace(line[len("lockfileVersion:"):]), "'\"")
+				isV5 = strings.HasPrefix(version, "5.") || version == "5"
+			}
+			continue
+		}
+
+		// Package keys are indented by exactly two spaces
+		if !inPackages || !strings.HasPrefix(line, "  ") || line[2] == ' ' || !strings.HasSuffix(line, ":") {
+			continue
+		}
+		key := strings.Trim(line[2:len(line)-1], "'\"")
+		key = strings.TrimPrefix(key, "/")
+		if paren := strings.IndexByte(key, '('); paren != -1 {
+			key = key[:paren]
+		}
+
+		if isV5 {
+			if slash := strings.LastIndexByte(key, '/'); slash > 0 {
+				version := key[slash+1:]
+				if underscore := strings.IndexByte(version, '_'); underscore != -1 {
+					version = version[:underscore]
+				}
+				packages.add(key[:slash], version)
+			}
+		} else if at := strings.LastIndexByte(key, '@'); at > 0 {
+			packages.add(key[:at], key[at+1:])
+		}
+	}
+}
+
+// This handles both the format used by Yarn 1:
+//
+//	"@scope/name@^1.0.0", "@scope/name@^1.1.0":
+//	  version "1.2.3"
+//
+// and the format used by later versions of Yarn:
+//
+//	"@scope/name@npm:^1.0.0, @scope/name@npm:^1.1.0":
+//	  version: 1.2.3
+func parseYarnLock(contents string, packages lockfilePackages) {
+	var names []string
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Unindented lines list the specifiers that resolve to the entry below
+		if line[0] != ' ' {
+			names = names[:0]
+			for _, spec := range strings.Split(strings.TrimSuffix(line, ":"), ",") {
+				spec = strings.Trim(strings.TrimSpace(spec), "\"")
+				if len(spec) > 1 {
+					if at := strings.IndexByte(spec[1:], '@'); at != -1 {
+						names = append(names, spec[:at+1])
+					}
+				}
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if len(names) > 0 && strings.HasPrefix(trimmed, "version") {
+			version := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(trimmed, "version"), ":"))
+			version = strings.Trim(version, "\"")
+			for _, name := range names {
+				packages.add(name, version)
+			}
+			names = names[:0]
+		}
+	}
+}
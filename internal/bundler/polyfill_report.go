@@ -0,0 +1,77 @@
+package bundler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This lists the runtime APIs that are used by the bundle but that aren't
+// available in the target environment, along with the input files that use
+// them. Unlike syntax, these can't be lowered so they need polyfills instead.
+// The keys of the top-level object can be given directly to a polyfill tool.
+func (b *Bundle) generatePolyfillReport(log logger.Log, options *config.Options, allReachableFiles []uint32) (graph.OutputFile, bool) {
+	if options.WriteToStdout {
+		log.AddError(nil, logger.Range{}, "Cannot use \"polyfill report\" without an output path")
+		return graph.OutputFile{}, false
+	}
+
+	inputsForFeature := make(map[string][]string)
+	for _, sourceIndex := range allReachableFiles {
+		file := &b.files[sourceIndex].inputFile
+		if repr, ok := file.Repr.(*graph.JSRepr); ok {
+			for _, feature := range repr.AST.UsedRuntimeFeatures.List() {
+				name := compat.RuntimeFeatureToString[feature]
+				inputsForFeature[name] = append(inputsForFeature[name], file.Source.PrettyPath)
+			}
+		}
+	}
+	names := make([]string, 0, len(inputsForFeature))
+	for name := range inputsForFeature {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n  ")
+		sb.Write(js_printer.QuoteForJSON(name, options.ASCIIOnly))
+		sb.WriteString(": [")
+		inputs := inputsForFeature[name]
+		sort.Strings(inputs)
+		for j, input := range inputs {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n    ")
+			sb.Write(js_printer.QuoteForJSON(input, options.ASCIIOnly))
+		}
+		sb.WriteString("\n  ]")
+	}
+	if len(names) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}\n")
+	outputContents := []byte(sb.String())
+
+	absPath := options.PolyfillReportPath
+	if !b.fs.IsAbs(absPath) {
+		absPath = b.fs.Join(options.AbsOutputDir, absPath)
+	}
+	return graph.OutputFile{
+		AbsPath:  absPath,
+		Contents: outputContents,
+		JSONMetadataChunk: fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(outputContents)),
+	}, true
+}
@@ -0,0 +1,151 @@
+package bundler
+
+import (
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// This collects every word that could be a class name or an id from the
+// JavaScript and HTML files in the bundle. Like other tools that do this, it
+// doesn't try to understand the code. It just splits the text into words both
+// at characters that can't appear in an identifier and at whitespace and
+// quotes, which handles class names such as "md:flex" and "w-1/2" too.
+func findPurgeCSSNames(files []graph.InputFile, reachableFiles []uint32) map[string]bool {
+	names := make(map[string]bool)
+
+	for _, sourceIndex := range reachableFiles {
+		if sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		file := &files[sourceIndex]
+		switch file.Repr.(type) {
+		case *graph.JSRepr, *graph.HTMLRepr:
+		default:
+			continue
+		}
+		text := file.Source.Contents
+
+		// Split at non-identifier characters
+		start := -1
+		for i := 0; i <= len(text); i++ {
+			if i < len(text) && isPurgeCSSNameChar(text[i]) {
+				if start == -1 {
+					start = i
+				}
+			} else if start != -1 {
+				names[text[start:i]] = true
+				start = -1
+			}
+		}
+
+		// Split at whitespace and quotes
+		start = -1
+		for i := 0; i <= len(text); i++ {
+			if i < len(text) && !isPurgeCSSSeparator(text[i]) {
+				if start == -1 {
+					start = i
+				}
+			} else if start != -1 {
+				names[text[start:i]] = true
+				start = -1
+			}
+		}
+	}
+
+	return names
+}
+
+func isPurgeCSSNameChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c >= 0x80
+}
+
+func isPurgeCSSSeparator(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\f', '"', '\'', '`', '<', '>', '=':
+		return true
+	}
+	return false
+}
+
+func (c *linkerContext) isPurgeCSSNameUsed(name string) bool {
+	if c.purgeCSSNames[name] {
+		return true
+	}
+	for _, regex := range c.options.PurgeCSSSafelist {
+		if regex.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// A complex selector can never match if it requires a class or an id that
+// doesn't appear anywhere. Selectors inside pseudo-classes such as ":not()"
+// are ignored since they don't need to match for the selector to match.
+func (c *linkerContext) canSelectorMatch(complex css_ast.ComplexSelector) bool {
+	for _, compound := range complex.Selectors {
+		for _, ss := range compound.SubclassSelectors {
+			switch s := ss.(type) {
+			case *css_ast.SSClass:
+				if !c.isPurgeCSSNameUsed(s.Name) {
+					return false
+				}
+
+			case *css_ast.SSHash:
+				if !c.isPurgeCSSNameUsed(s.Name) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// This returns a copy of the rules without the selectors that can never
+// match. The original rules are left alone since they may be shared with
+// other chunks.
+func (c *linkerContext) purgeUnusedCSSRules(rules []css_ast.Rule) []css_ast.Rule {
+	result := make([]css_ast.Rule, 0, len(rules))
+
+	for _, rule := range rules {
+		switch r := rule.Data.(type) {
+		case *css_ast.RSelector:
+			var selectors []css_ast.ComplexSelector
+			for _, complex := range r.Selectors {
+				if c.canSelectorMatch(complex) {
+					selectors = append(selectors, complex)
+				}
+			}
+			if len(selectors) == 0 {
+				continue
+			}
+			clone := *r
+			clone.Selectors = selectors
+			clone.Rules = c.purgeUnusedCSSRules(r.Rules)
+			rule.Data = &clone
+
+		case *css_ast.RKnownAt:
+			if r.Rules != nil {
+				clone := *r
+				clone.Rules = c.purgeUnusedCSSRules(r.Rules)
+				if len(clone.Rules) == 0 && len(r.Rules) > 0 {
+					// Remove conditional rules that became empty
+					continue
+				}
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtLayer:
+			if len(r.Rules) > 0 {
+				clone := *r
+				clone.Rules = c.purgeUnusedCSSRules(r.Rules)
+				rule.Data = &clone
+			}
+		}
+
+		result = append(result, rule)
+	}
+
+	return result
+}
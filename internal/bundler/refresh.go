@@ -0,0 +1,116 @@
+package bundler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_printer"
+)
+
+// Hot module replacement systems with component-level state preservation
+// (e.g. React Fast Refresh and Solid HMR) need to know which exports of a
+// module are components. A module can be updated in place without reloading
+// its importers when every export is a component. This is called a "refresh
+// boundary". This information is added to the metafile so that plugins for
+// these frameworks don't need to parse the module again to find it.
+type refreshMetadata struct {
+	exports    []string
+	components []string
+	isBoundary bool
+}
+
+func computeRefreshMetadata(repr *graph.JSRepr) refreshMetadata {
+	var result refreshMetadata
+	if repr.AST.ExportsKind == js_ast.ExportsCommonJS {
+		return result
+	}
+
+	// Find the names of all top-level functions and classes, including ones
+	// stored in variables. Calls that wrap a function such as "memo()" and
+	// "forwardRef()" are considered to be functions too.
+	functionNames := make(map[js_ast.Ref]string)
+	for _, part := range repr.AST.Parts {
+		for _, stmt := range part.Stmts {
+			switch s := stmt.Data.(type) {
+			case *js_ast.SFunction:
+				if s.Fn.Name != nil {
+					functionNames[s.Fn.Name.Ref] = repr.AST.Symbols[s.Fn.Name.Ref.InnerIndex].OriginalName
+				}
+
+			case *js_ast.SClass:
+				if s.Class.Name != nil {
+					functionNames[s.Class.Name.Ref] = repr.AST.Symbols[s.Class.Name.Ref.InnerIndex].OriginalName
+				}
+
+			case *js_ast.SLocal:
+				for _, decl := range s.Decls {
+					if id, ok := decl.Binding.Data.(*js_ast.BIdentifier); ok && isFunctionLikeExpr(decl.ValueOrNil) {
+						functionNames[id.Ref] = repr.AST.Symbols[id.Ref.InnerIndex].OriginalName
+					}
+				}
+
+			case *js_ast.SExportDefault:
+				// Anonymous default exports don't have a name that can be checked
+				var name *js_ast.LocRef
+				switch v := s.Value.Data.(type) {
+				case *js_ast.SFunction:
+					name = v.Fn.Name
+				case *js_ast.SClass:
+					name = v.Class.Name
+				}
+				if name != nil {
+					functionNames[s.DefaultName.Ref] = repr.AST.Symbols[name.Ref.InnerIndex].OriginalName
+				}
+			}
+		}
+	}
+
+	result.exports = make([]string, 0, len(repr.AST.NamedExports))
+	for alias := range repr.AST.NamedExports {
+		result.exports = append(result.exports, alias)
+	}
+	sort.Strings(result.exports)
+
+	// Components are functions with a capitalized name, which is the same
+	// heuristic that the React Fast Refresh runtime uses
+	for _, alias := range result.exports {
+		if name, ok := functionNames[repr.AST.NamedExports[alias].Ref]; ok && name != "" && name[0] >= 'A' && name[0] <= 'Z' {
+			result.components = append(result.components, alias)
+		}
+	}
+
+	// Re-exports with "export * from" could export anything
+	result.isBoundary = len(result.exports) > 0 && len(result.components) == len(result.exports) &&
+		len(repr.AST.ExportStarImportRecords) == 0
+	return result
+}
+
+func isFunctionLikeExpr(expr js_ast.Expr) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EArrow, *js_ast.EFunction, *js_ast.EClass:
+		return true
+	case *js_ast.ECall:
+		return len(e.Args) > 0 && isFunctionLikeExpr(e.Args[0])
+	}
+	return false
+}
+
+func (meta refreshMetadata) json(asciiOnly bool) string {
+	quoted := func(names []string) string {
+		parts := make([]string, len(names))
+		for i, name := range names {
+			parts[i] = string(js_printer.QuoteForJSON(name, asciiOnly))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	isBoundary := "false"
+	if meta.isBoundary {
+		isBoundary = "true"
+	}
+	return "{\n        \"boundary\": " + isBoundary +
+		",\n        \"components\": " + quoted(meta.components) +
+		",\n        \"exports\": " + quoted(meta.exports) +
+		"\n      }"
+}
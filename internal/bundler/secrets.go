@@ -0,0 +1,188 @@
+package bundler
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// These are formats for credentials that are recognizable from their text
+// alone. They are deliberately strict so that false positives are rare.
+var knownSecretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,}`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}`)},
+}
+
+// Anything else that looks like a random token is also considered to be a
+// secret. The length limits avoid flagging both short hashes and large blobs
+// of encoded data such as inline source maps.
+var highEntropyTokenPattern = regexp.MustCompile(`[0-9A-Za-z+/=_-]{32,128}`)
+
+const highEntropyThreshold = 4.5
+
+type secretInOutput struct {
+	kind string
+	text string
+}
+
+func findSecretsInOutput(contents string) (secrets []secretInOutput) {
+	seen := make(map[string]bool)
+	add := func(kind string, text string) {
+		if !seen[text] {
+			seen[text] = true
+			secrets = append(secrets, secretInOutput{kind: kind, text: text})
+		}
+	}
+
+	for _, known := range knownSecretPatterns {
+		for _, text := range known.pattern.FindAllString(contents, -1) {
+			add(known.kind, text)
+		}
+	}
+
+	for _, loc := range highEntropyTokenPattern.FindAllStringIndex(contents, -1) {
+		start, end := loc[0], loc[1]
+
+		// Ignore tokens that are part of a longer token (i.e. the match was cut
+		// off by the length limit) and data that is explicitly encoded, such as
+		// data URLs and subresource integrity hashes
+		if (start > 0 && isSecretTokenChar(contents[start-1])) || (end < len(contents) && isSecretTokenChar(contents[end])) {
+			continue
+		}
+		text := contents[start:end]
+		if strings.HasSuffix(contents[:start], "base64,") || strings.HasPrefix(text, "sha256-") ||
+			strings.HasPrefix(text, "sha384-") || strings.HasPrefix(text, "sha512-") {
+			continue
+		}
+
+		if looksLikeRandomToken(text) {
+			add("high-entropy string", text)
+		}
+	}
+	return
+}
+
+func isSecretTokenChar(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') ||
+		c == '+' || c == '/' || c == '=' || c == '_' || c == '-'
+}
+
+// Random tokens have a mix of lowercase letters, uppercase letters, and digits,
+// which rules out long identifiers and paths. They also need to have a high
+// Shannon entropy per character.
+func looksLikeRandomToken(text string) bool {
+	var hasLower, hasUpper, hasDigit bool
+	var counts [256]int
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		counts[c]++
+		switch {
+		case c >= 'a' && c <= 'z':
+			hasLower = true
+		case c >= 'A' && c <= 'Z':
+			hasUpper = true
+		case c >= '0' && c <= '9':
+			hasDigit = true
+		}
+	}
+	if !hasLower || !hasUpper || !hasDigit {
+		return false
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(text))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy >= highEntropyThreshold
+}
+
+// This scans the final contents of an output file for things that look like
+// secrets and fails the build if any are found. Each secret is traced back to
+// the input file or the define that it came from so that it can be removed.
+func (c *linkerContext) scanChunkForSecrets(chunk *chunkInfo, contents []byte) {
+	secrets := findSecretsInOutput(string(contents))
+	if len(secrets) == 0 {
+		return
+	}
+
+	// Search the input files in this chunk in a deterministic order
+	sourceIndices := make([]uint32, 0, len(chunk.filesWithPartsInChunk))
+	for sourceIndex := range chunk.filesWithPartsInChunk {
+		sourceIndices = append(sourceIndices, sourceIndex)
+	}
+	sort.Slice(sourceIndices, func(i, j int) bool { return sourceIndices[i] < sourceIndices[j] })
+
+	outputPath := c.res.PrettyPath(logger.Path{Text: c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath), Namespace: "file"})
+
+	for _, secret := range secrets {
+		text := fmt.Sprintf("The output file %q contains what looks like a secret (%s)", outputPath, secret.kind)
+		found := false
+
+		for _, sourceIndex := range sourceIndices {
+			file := &c.graph.Files[sourceIndex]
+			if i := strings.Index(file.InputFile.Source.Contents, secret.text); i != -1 {
+				r := logger.Range{Loc: logger.Loc{Start: int32(i)}, Len: int32(len(secret.text))}
+				c.log.AddError(file.LineColumnTracker(), r, text)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			var notes []logger.MsgData
+			if name, ok := findDefineWithString(c.options.Defines, secret.text); ok {
+				notes = append(notes, logger.MsgData{Text: fmt.Sprintf("The secret comes from the define for %q.", name)})
+			}
+			c.log.AddErrorWithNotes(nil, logger.Range{}, text, notes)
+		}
+	}
+}
+
+func findDefineWithString(defines *config.ProcessedDefines, text string) (string, bool) {
+	if defines == nil {
+		return "", false
+	}
+	containsText := func(data config.DefineData) bool {
+		if data.DefineExpr != nil {
+			if str, ok := data.DefineExpr.Constant.(*js_ast.EString); ok {
+				return strings.Contains(helpers.UTF16ToString(str.Value), text)
+			}
+		}
+		return false
+	}
+
+	var names []string
+	for name, data := range defines.IdentifierDefines {
+		if containsText(data) {
+			names = append(names, name)
+		}
+	}
+	for _, dots := range defines.DotDefines {
+		for _, dot := range dots {
+			if containsText(dot.Data) {
+				names = append(names, strings.Join(dot.Parts, "."))
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
@@ -0,0 +1,71 @@
+package bundler
+
+import (
+	"fmt"
+
+	"github.com/evanw/esbuild/internal/cache"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Separately-built bundles on the same page find each other's shared modules
+// using this object unless the "SharedScope" option says otherwise
+const defaultSharedScope = "globalThis.__esbuild_shared__ || (globalThis.__esbuild_shared__ = {})"
+
+func isSharedModule(sharedModules []string, path string) bool {
+	for _, shared := range sharedModules {
+		if shared == path {
+			return true
+		}
+	}
+	return false
+}
+
+// A shared module registers the bundled copy of the module in the shared scope
+// under its version and then evaluates to the highest compatible version in
+// the scope, which may come from another bundle. The bundled copy is wrapped
+// in a lazily-evaluated closure so that it only runs if it's actually used.
+func generateCodeForSharedModule(fs fs.FS, fsCache *cache.FSCache, scope string, name string, fallback string) string {
+	if scope == "" {
+		scope = defaultSharedScope
+	}
+	version := readVersionForPath(fs, fsCache, fallback)
+	if version == "" {
+		version = "0.0.0"
+	}
+	return fmt.Sprintf("module.exports = __shared(%s, %s, %s, () => require(%s));\n", scope,
+		js_printer.QuoteForJSON(name, false), js_printer.QuoteForJSON(version, false),
+		js_printer.QuoteForJSON("./"+fs.Base(fallback), false))
+}
+
+// This returns the "version" field from the closest "package.json" file in a
+// parent directory of the given path
+func readVersionForPath(fs fs.FS, fsCache *cache.FSCache, path string) string {
+	for dir := fs.Dir(path); ; {
+		packageJSON := fs.Join(dir, "package.json")
+		if contents, err, _ := fsCache.ReadFile(fs, packageJSON); err == nil {
+			source := logger.Source{KeyPath: logger.Path{Text: packageJSON, Namespace: "file"}, Contents: contents}
+			if json, ok := js_parser.ParseJSON(logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil), source, js_parser.JSONOptions{}); ok {
+				if obj, ok := json.Data.(*js_ast.EObject); ok {
+					for _, prop := range obj.Properties {
+						if key, ok := prop.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(key.Value, "version") {
+							if str, ok := prop.ValueOrNil.Data.(*js_ast.EString); ok {
+								return helpers.UTF16ToString(str.Value)
+							}
+						}
+					}
+				}
+			}
+			return ""
+		}
+		parent := fs.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
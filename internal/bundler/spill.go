@@ -0,0 +1,86 @@
+package bundler
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/evanw/esbuild/internal/sourcemap"
+)
+
+// In low-memory mode, large per-module data that's only needed briefly during
+// linking is written to a temporary file instead of being kept in memory. It's
+// read back on demand when it's needed. The file is deleted when the compile
+// phase ends.
+type spillFile struct {
+	file  *os.File
+	mutex sync.Mutex
+	size  int64
+}
+
+type spillRange struct {
+	offset int64
+	length int
+}
+
+// This is printed code that was written to the spill file. Everything needed
+// to join it with the surrounding code is remembered so that it only has to
+// be read back once, directly into the final output file.
+type spilledCode struct {
+	spillRange
+	lastByte byte
+
+	// This is the line and column offset from the start to the end of the code
+	endOffset sourcemap.LineColumnOffset
+}
+
+func newSpillFile() (*spillFile, error) {
+	file, err := ioutil.TempFile("", "esbuild-spill-")
+	if err != nil {
+		return nil, err
+	}
+	return &spillFile{file: file}, nil
+}
+
+func (s *spillFile) write(data []byte) (spillRange, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	offset := s.size
+	if _, err := s.file.WriteAt(data, offset); err != nil {
+		return spillRange{}, err
+	}
+	s.size += int64(len(data))
+	return spillRange{offset: offset, length: len(data)}, nil
+}
+
+func (s *spillFile) writeCode(code []byte) (*spilledCode, error) {
+	r, err := s.write(code)
+	if err != nil {
+		return nil, err
+	}
+	result := &spilledCode{spillRange: r}
+	if len(code) > 0 {
+		result.lastByte = code[len(code)-1]
+	}
+	result.endOffset.AdvanceBytes(code)
+	return result, nil
+}
+
+func (s *spillFile) read(r spillRange) ([]byte, error) {
+	data := make([]byte, r.length)
+	if err := s.readInto(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// This is safe to call concurrently since "ReadAt" doesn't use the file offset
+func (s *spillFile) readInto(r spillRange, buffer []byte) error {
+	_, err := s.file.ReadAt(buffer[:r.length], r.offset)
+	return err
+}
+
+func (s *spillFile) close() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
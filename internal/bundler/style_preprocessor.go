@@ -0,0 +1,154 @@
+package bundler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Imports from stylesheets that are preprocessed follow the conventions of
+// Sass, which allows the extension to be omitted and the file name to start
+// with an underscore (a "partial"), and also looks for index files
+var stylePreprocessorExtensions = []string{".scss", ".sass", ".css"}
+
+func findStylePreprocessor(preprocessors []config.StylePreprocessor, path logger.Path) *config.StylePreprocessor {
+	for i := range preprocessors {
+		if config.PluginAppliesToPath(path, preprocessors[i].Filter, "") {
+			return &preprocessors[i]
+		}
+	}
+	return nil
+}
+
+// This replaces the contents of the source with the CSS generated by the
+// preprocessor. It returns the source map for the generated CSS if there is
+// one, and false if the preprocessor failed.
+func runStylePreprocessor(args *parseArgs, preprocessor *config.StylePreprocessor, source *logger.Source, absResolveDir string) (*string, bool) {
+	result := preprocessor.Transform(config.StylePreprocessorArgs{
+		Path:     source.KeyPath,
+		Contents: source.Contents,
+		Import: func(path string, importer string) (config.StyleImport, error) {
+			return importForStylePreprocessor(args, source, absResolveDir, path, importer)
+		},
+	})
+
+	// Preprocessors can also provide additional file system paths to watch
+	for _, file := range result.AbsWatchFiles {
+		args.caches.FSCache.ReadFile(args.fs, file)
+	}
+
+	if logPluginMessages(args.res, args.log, preprocessor.Name, result.Msgs, result.ThrownError, args.importSource, args.importPathRange) {
+		return nil, false
+	}
+	source.Contents = result.Contents
+	return result.SourceMap, true
+}
+
+func importForStylePreprocessor(
+	args *parseArgs,
+	source *logger.Source,
+	absResolveDir string,
+	importPath string,
+	importer string,
+) (config.StyleImport, error) {
+	importerPath := source.KeyPath
+	if importer != "" {
+		importerPath = logger.Path{Text: importer, Namespace: "file"}
+		absResolveDir = args.fs.Dir(importer)
+	}
+	candidates := stylePreprocessorCandidates(importPath)
+
+	// Look for the file next to the importer first like Sass does
+	if absResolveDir != "" {
+		for _, candidate := range candidates {
+			absPath := candidate
+			if !args.fs.IsAbs(candidate) {
+				absPath = args.fs.Join(absResolveDir, candidate)
+			}
+			if contents, err, _ := args.caches.FSCache.ReadFile(args.fs, absPath); err == nil {
+				return config.StyleImport{Path: absPath, Contents: contents}, nil
+			}
+		}
+	}
+
+	// Otherwise, resolve the path like any other import so that plugins and
+	// packages in "node_modules" can provide the file
+	for _, candidate := range candidates {
+		resolveResult, didLogError, _ := RunOnResolvePlugins(
+			args.options.Plugins,
+			args.res,
+			args.log,
+			args.fs,
+			&args.caches.FSCache,
+			source,
+			logger.Range{},
+			importerPath,
+			candidate,
+			ast.ImportAt,
+			absResolveDir,
+			nil,
+		)
+		if didLogError {
+			break
+		}
+		if resolveResult == nil || resolveResult.IsExternal {
+			continue
+		}
+		loadSource := logger.Source{
+			KeyPath:    resolveResult.PathPair.Primary,
+			PrettyPath: args.res.PrettyPath(resolveResult.PathPair.Primary),
+		}
+		if _, ok := runOnLoadPlugins(
+			args.options.Plugins,
+			args.res,
+			args.fs,
+			&args.caches.FSCache,
+			&args.caches.RemoteCache,
+			args.log,
+			&loadSource,
+			source,
+			logger.Range{},
+			resolveResult.PluginData,
+			&args.options,
+		); !ok {
+			break
+		}
+		return config.StyleImport{Path: loadSource.KeyPath.Text, Contents: loadSource.Contents}, nil
+	}
+
+	return config.StyleImport{}, fmt.Errorf("Could not resolve %q", importPath)
+}
+
+// This returns the paths to try for an import from a preprocessed stylesheet
+// in order. For example, "dir/name" tries "dir/name.scss", "dir/_name.scss",
+// and so on before trying "dir/name/_index.scss" and "dir/name/index.scss".
+func stylePreprocessorCandidates(importPath string) []string {
+	dir, base := "", importPath
+	if slash := strings.LastIndexByte(importPath, '/'); slash != -1 {
+		dir, base = importPath[:slash+1], importPath[slash+1:]
+	}
+	candidates := []string{importPath}
+
+	for _, ext := range stylePreprocessorExtensions {
+		if strings.HasSuffix(base, ext) {
+			if !strings.HasPrefix(base, "_") {
+				candidates = append(candidates, dir+"_"+base)
+			}
+			return candidates
+		}
+	}
+
+	for _, ext := range stylePreprocessorExtensions {
+		candidates = append(candidates, dir+base+ext)
+		if !strings.HasPrefix(base, "_") {
+			candidates = append(candidates, dir+"_"+base+ext)
+		}
+	}
+	for _, ext := range stylePreprocessorExtensions {
+		candidates = append(candidates, importPath+"/_index"+ext, importPath+"/index"+ext)
+	}
+	return candidates
+}
@@ -0,0 +1,324 @@
+package bundler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/graph"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// This collects the results of tree shaking from every call to "link". Files
+// may be linked more than once (e.g. for each entry point when code splitting
+// is disabled) so the results are merged: a symbol is kept if it was kept by
+// any link, and an export is only dropped if it was dropped by every link.
+type treeShakingReport struct {
+	mutex sync.Mutex
+	files map[uint32]*treeShakingReportFile
+}
+
+type treeShakingReportFile struct {
+	prettyPath  string
+	kept        map[string][]treeShakingReportStep
+	exports     []string
+	keptExports map[string]bool
+}
+
+// Each step is a part of a file. The first step of a chain is a part that was
+// kept for its own sake and each following step was kept because the previous
+// step referenced it.
+type treeShakingReportStep struct {
+	path   string
+	symbol string
+	reason string
+	line   int
+}
+
+func newTreeShakingReport() *treeShakingReport {
+	return &treeShakingReport{files: make(map[uint32]*treeShakingReportFile)}
+}
+
+// Only the first reason a part was kept is recorded, which happens right
+// before the part is marked as live
+func (c *linkerContext) recordTreeShakingRoot(sourceIndex uint32, partIndex uint32, reason string) {
+	part := js_ast.Dependency{SourceIndex: sourceIndex, PartIndex: partIndex}
+	if !c.isPartLiveOrRecorded(part) {
+		c.treeShakingRoots[part] = reason
+	}
+}
+
+func (c *linkerContext) recordTreeShakingParent(part js_ast.Dependency, parent js_ast.Dependency) {
+	if !c.isPartLiveOrRecorded(part) {
+		c.treeShakingParents[part] = parent
+	}
+}
+
+func (c *linkerContext) recordTreeShakingFileParent(sourceIndex uint32, parent js_ast.Dependency) {
+	if !c.graph.Files[sourceIndex].IsLive {
+		if _, ok := c.treeShakingFileParents[sourceIndex]; !ok {
+			c.treeShakingFileParents[sourceIndex] = parent
+		}
+	}
+}
+
+func (c *linkerContext) isPartLiveOrRecorded(part js_ast.Dependency) bool {
+	if c.graph.Files[part.SourceIndex].InputFile.Repr.(*graph.JSRepr).AST.Parts[part.PartIndex].IsLive {
+		return true
+	}
+	if _, ok := c.treeShakingParents[part]; ok {
+		return true
+	}
+	_, ok := c.treeShakingRoots[part]
+	return ok
+}
+
+func (c *linkerContext) addToTreeShakingReport() {
+	lineTables := make(map[uint32][]int32)
+	stepForPart := func(part js_ast.Dependency) treeShakingReportStep {
+		file := &c.graph.Files[part.SourceIndex].InputFile
+		repr := file.Repr.(*graph.JSRepr)
+		step := treeShakingReportStep{path: file.Source.PrettyPath}
+		if names := c.reportedSymbolsForPart(repr, &repr.AST.Parts[part.PartIndex]); len(names) > 0 {
+			step.symbol = names[0]
+		}
+		if stmts := repr.AST.Parts[part.PartIndex].Stmts; len(stmts) > 0 {
+			lineTable, ok := lineTables[part.SourceIndex]
+			if !ok {
+				lineTable = computeNewlineOffsets(file.Source.Contents)
+				lineTables[part.SourceIndex] = lineTable
+			}
+			start := stmts[0].Loc.Start
+			step.line = sort.Search(len(lineTable), func(i int) bool { return lineTable[i] >= start }) + 1
+		}
+		return step
+	}
+
+	c.treeShakingReport.mutex.Lock()
+	defer c.treeShakingReport.mutex.Unlock()
+
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		if sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		file := &c.graph.Files[sourceIndex].InputFile
+		repr, ok := file.Repr.(*graph.JSRepr)
+		if !ok || repr.CSSSourceIndex.IsValid() {
+			continue
+		}
+
+		reportFile := c.treeShakingReport.files[sourceIndex]
+		if reportFile == nil {
+			reportFile = &treeShakingReportFile{
+				prettyPath:  file.Source.PrettyPath,
+				kept:        make(map[string][]treeShakingReportStep),
+				keptExports: make(map[string]bool),
+			}
+			for alias, export := range repr.AST.NamedExports {
+				// Re-exports are reported by the module that declares them
+				if _, ok := repr.AST.NamedImports[export.Ref]; !ok {
+					reportFile.exports = append(reportFile.exports, alias)
+				}
+			}
+			sort.Strings(reportFile.exports)
+			c.treeShakingReport.files[sourceIndex] = reportFile
+		}
+
+		// Find the chain of references that kept each live top-level symbol
+		for partIndex := range repr.AST.Parts {
+			part := &repr.AST.Parts[partIndex]
+			if !part.IsLive {
+				continue
+			}
+			names := c.reportedSymbolsForPart(repr, part)
+			if len(names) == 0 {
+				continue
+			}
+			var chain []treeShakingReportStep
+			for dep := (js_ast.Dependency{SourceIndex: sourceIndex, PartIndex: uint32(partIndex)}); ; {
+				chain = append(chain, stepForPart(dep))
+				parent, ok := c.treeShakingParents[dep]
+				if !ok {
+					// Roots explain why they were kept, and the file containing them
+					// explains why that file was included (unless it's an entry point)
+					chain[len(chain)-1].reason = c.treeShakingRoots[dep]
+					if parent, ok = c.treeShakingFileParents[dep.SourceIndex]; !ok {
+						break
+					}
+				}
+				dep = parent
+			}
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+			for _, name := range names {
+				// Prefer shorter chains so that the result is deterministic
+				if existing, ok := reportFile.kept[name]; !ok || isBetterTreeShakingChain(chain, existing) {
+					reportFile.kept[name] = chain
+				}
+			}
+		}
+
+		for _, alias := range reportFile.exports {
+			for _, partIndex := range repr.TopLevelSymbolToParts(repr.AST.NamedExports[alias].Ref) {
+				if repr.AST.Parts[partIndex].IsLive {
+					reportFile.keptExports[alias] = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// Imports and unbound names aren't declarations in this file, so they are
+// omitted. They are reported by the file that declares them instead. This
+// includes the generated namespace symbol for each import statement and the
+// "exports" and "module" symbols of CommonJS files.
+func (c *linkerContext) reportedSymbolsForPart(repr *graph.JSRepr, part *js_ast.Part) (names []string) {
+	if len(part.Stmts) == 1 {
+		if _, ok := part.Stmts[0].Data.(*js_ast.SImport); ok {
+			return nil
+		}
+	}
+	for _, declared := range part.DeclaredSymbols {
+		if !declared.IsTopLevel || declared.Ref == repr.AST.ExportsRef || declared.Ref == repr.AST.ModuleRef {
+			continue
+		}
+		symbol := c.graph.Symbols.Get(declared.Ref)
+		if symbol.Kind == js_ast.SymbolImport || symbol.Kind == js_ast.SymbolUnbound {
+			continue
+		}
+		names = append(names, symbol.OriginalName)
+	}
+	return
+}
+
+func isBetterTreeShakingChain(a []treeShakingReportStep, b []treeShakingReportStep) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	for i := range a {
+		if a[i].path != b[i].path {
+			return a[i].path < b[i].path
+		}
+		if a[i].line != b[i].line {
+			return a[i].line < b[i].line
+		}
+	}
+	return false
+}
+
+func computeNewlineOffsets(contents string) (offsets []int32) {
+	for i := 0; i < len(contents); i++ {
+		if contents[i] == '\n' {
+			offsets = append(offsets, int32(i))
+		}
+	}
+	return
+}
+
+// This lists each top-level symbol that was kept by tree shaking along with
+// the chain of references that caused it to be kept, and each export that was
+// removed by tree shaking. It's meant to help find out why code is in a bundle.
+func (b *Bundle) generateTreeShakingReport(log logger.Log, options *config.Options, report *treeShakingReport) (graph.OutputFile, bool) {
+	if options.WriteToStdout {
+		log.AddError(nil, logger.Range{}, "Cannot use \"tree shaking report\" without an output path")
+		return graph.OutputFile{}, false
+	}
+
+	sourceIndices := make([]uint32, 0, len(report.files))
+	for sourceIndex := range report.files {
+		sourceIndices = append(sourceIndices, sourceIndex)
+	}
+	sort.Slice(sourceIndices, func(i, j int) bool {
+		return report.files[sourceIndices[i]].prettyPath < report.files[sourceIndices[j]].prettyPath
+	})
+
+	quote := func(text string) []byte {
+		return js_printer.QuoteForJSON(text, options.ASCIIOnly)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, sourceIndex := range sourceIndices {
+		file := report.files[sourceIndex]
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n  ")
+		sb.Write(quote(file.prettyPath))
+		sb.WriteString(": {\n    \"kept\": {")
+
+		names := make([]string, 0, len(file.kept))
+		for name := range file.kept {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for j, name := range names {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n      ")
+			sb.Write(quote(name))
+			sb.WriteString(": [")
+			for k, step := range file.kept[name] {
+				if k > 0 {
+					sb.WriteString(",")
+				}
+				sb.WriteString("\n        {\"path\": ")
+				sb.Write(quote(step.path))
+				if step.line != 0 {
+					sb.WriteString(fmt.Sprintf(", \"line\": %d", step.line))
+				}
+				if step.symbol != "" {
+					sb.WriteString(", \"symbol\": ")
+					sb.Write(quote(step.symbol))
+				}
+				if step.reason != "" {
+					sb.WriteString(", \"reason\": ")
+					sb.Write(quote(step.reason))
+				}
+				sb.WriteString("}")
+			}
+			sb.WriteString("\n      ]")
+		}
+		if len(names) > 0 {
+			sb.WriteString("\n    ")
+		}
+		sb.WriteString("},\n    \"droppedExports\": [")
+
+		isFirst := true
+		for _, alias := range file.exports {
+			if file.keptExports[alias] {
+				continue
+			}
+			if !isFirst {
+				sb.WriteString(", ")
+			}
+			isFirst = false
+			sb.Write(quote(alias))
+		}
+		sb.WriteString("]\n  }")
+	}
+	if len(sourceIndices) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}\n")
+	outputContents := []byte(sb.String())
+
+	absPath := options.TreeShakingReportPath
+	if !b.fs.IsAbs(absPath) {
+		absPath = b.fs.Join(options.AbsOutputDir, absPath)
+	}
+	return graph.OutputFile{
+		AbsPath:  absPath,
+		Contents: outputContents,
+		JSONMetadataChunk: fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(outputContents)),
+	}, true
+}
@@ -0,0 +1,80 @@
+package bundler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/wasm_parser"
+)
+
+// The "wasm" loader follows the proposed ES module integration for WebAssembly.
+// Each import of the WebAssembly module is resolved through the module graph
+// using its module name as the import path, and each export of the WebAssembly
+// module becomes an export of the JavaScript module. The binary is embedded in
+// the output and instantiated using top-level await.
+func generateCodeForWasm(contents string, module wasm_parser.Module, platform config.Platform) string {
+	sb := strings.Builder{}
+
+	// Import each module once in the order that they are first used
+	var modules []string
+	seen := make(map[string]bool)
+	for _, imp := range module.Imports {
+		if !seen[imp.Module] {
+			seen[imp.Module] = true
+			modules = append(modules, imp.Module)
+		}
+	}
+	for i, path := range modules {
+		sb.WriteString(fmt.Sprintf("import * as __wasm_import_%d from %s;\n", i, js_printer.QuoteForJSON(path, false)))
+	}
+
+	helper := "__toBinary"
+	if platform == config.PlatformNode {
+		helper = "__toBinaryNode"
+	}
+	sb.WriteString(fmt.Sprintf("var { instance: { exports: __wasm_exports } } = await WebAssembly.instantiate(%s(%q), {",
+		helper, base64.StdEncoding.EncodeToString([]byte(contents))))
+	for i, path := range modules {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(fmt.Sprintf("\n  %s: __wasm_import_%d", js_printer.QuoteForJSON(path, false), i))
+	}
+	if len(modules) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("});\n")
+
+	// Export names don't have to be valid identifiers
+	for i, exp := range module.Exports {
+		if canUseAsWasmExportVariable(exp.Name) {
+			sb.WriteString(fmt.Sprintf("export var %s = __wasm_exports.%s;\n", exp.Name, exp.Name))
+		} else {
+			quoted := js_printer.QuoteForJSON(exp.Name, false)
+			sb.WriteString(fmt.Sprintf("var __wasm_export_%d = __wasm_exports[%s];\nexport { __wasm_export_%d as %s };\n", i, quoted, i, quoted))
+		}
+	}
+	return sb.String()
+}
+
+func canUseAsWasmExportVariable(name string) bool {
+	switch name {
+	case "arguments", "await", "eval":
+		return false
+	}
+	return js_lexer.IsIdentifier(name) && js_lexer.Keywords[name] == 0 &&
+		!js_lexer.StrictModeReservedWords[name] && !strings.HasPrefix(name, "__wasm_")
+}
+
+// The "wasm-file" loader copies the binary to the output directory like the
+// "file" loader. The default export is the URL of the file and the named export
+// "instantiate" compiles it using streaming compilation.
+func generateCodeForWasmFile(uniqueKeyPath string) string {
+	return fmt.Sprintf("var url = %q;\n", uniqueKeyPath) +
+		"export var instantiate = (imports) => __instantiateWasmStreaming(url, imports);\n" +
+		"export default url;\n"
+}
@@ -36,6 +36,7 @@ type CacheSet struct {
 	CSSCache         CSSCache
 	JSONCache        JSONCache
 	JSCache          JSCache
+	RemoteCache      RemoteCache
 	SourceIndexCache SourceIndexCache
 }
 
@@ -60,6 +61,27 @@ func MakeCacheSet() *CacheSet {
 	}
 }
 
+// This discards all cached file contents and parsed ASTs so that the memory
+// can be reclaimed. Subsequent builds will read and parse everything again.
+// Source indices are not discarded because they must be stable across builds.
+func (c *CacheSet) Flush() {
+	c.FSCache.mutex.Lock()
+	c.FSCache.entries = make(map[string]*fsEntry)
+	c.FSCache.mutex.Unlock()
+
+	c.CSSCache.mutex.Lock()
+	c.CSSCache.entries = make(map[logger.Path]*cssCacheEntry)
+	c.CSSCache.mutex.Unlock()
+
+	c.JSONCache.mutex.Lock()
+	c.JSONCache.entries = make(map[logger.Path]*jsonCacheEntry)
+	c.JSONCache.mutex.Unlock()
+
+	c.JSCache.mutex.Lock()
+	c.JSCache.entries = make(map[logger.Path]*jsCacheEntry)
+	c.JSCache.mutex.Unlock()
+}
+
 type SourceIndexCache struct {
 	entries         map[sourceIndexKey]uint32
 	mutex           sync.Mutex
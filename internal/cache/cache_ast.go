@@ -158,7 +158,7 @@ func (c *JSCache) Parse(log logger.Log, source logger.Source, options js_parser.
 	}()
 
 	// Cache hit
-	if entry != nil && entry.source == source && entry.options.Equal(&options) {
+	if entry != nil && entry.source == source && entry.options.Equal(&options) && !entry.ast.UsesImportMetaGlob {
 		for _, msg := range entry.msgs {
 			log.AddMsg(msg)
 		}
@@ -173,6 +173,11 @@ func (c *JSCache) Parse(log logger.Log, source logger.Source, options js_parser.
 		log.AddMsg(msg)
 	}
 
+	// Parsing may have been stopped partway through
+	if !ok && options.DidCancel() {
+		return ast, false
+	}
+
 	// Create the cache entry
 	entry = &jsCacheEntry{
 		source:  source,
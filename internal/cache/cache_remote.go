@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+// This cache stores modules downloaded for "http://" and "https://" imports.
+// Remote modules are assumed to be immutable (the URL typically contains a
+// version number), so a URL is never downloaded more than once. It's kept in
+// memory for the lifetime of this cache (i.e. across incremental builds) and
+// optionally on disk so that future processes can build without the network.
+
+type RemoteCache struct {
+	entries map[string]*remoteEntry
+
+	// These are the integrity hashes of modules that weren't in the lock file
+	newIntegrity map[string]string
+
+	mutex sync.Mutex
+}
+
+type remoteEntry struct {
+	contents    string
+	contentType string
+	integrity   string
+
+	// This is the URL after following any redirects. Relative imports in the
+	// module are relative to this URL instead of to the requested URL.
+	finalURL string
+}
+
+func RemoteIntegrity(contents string) string {
+	hash := sha256.Sum256([]byte(contents))
+	return "sha256-" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func (c *RemoteCache) Fetch(options *config.RemoteImports, url string) (contents string, contentType string, finalURL string, err error) {
+	c.mutex.Lock()
+	entry := c.entries[url]
+	c.mutex.Unlock()
+
+	if entry == nil {
+		if entry, err = loadRemoteEntry(options, url); err != nil {
+			return "", "", "", err
+		}
+		c.mutex.Lock()
+		if c.entries == nil {
+			c.entries = make(map[string]*remoteEntry)
+		}
+		c.entries[url] = entry
+
+		// Importing the final URL directly shouldn't download it again
+		if _, ok := c.entries[entry.finalURL]; !ok {
+			c.entries[entry.finalURL] = entry
+		}
+		c.mutex.Unlock()
+	}
+
+	// The lock file is checked every time because it may change between builds
+	if expected, ok := options.Integrity[url]; ok {
+		if expected != entry.integrity {
+			return "", "", "", fmt.Errorf("integrity check failed (expected %q but got %q)", expected, entry.integrity)
+		}
+	} else {
+		c.mutex.Lock()
+		if c.newIntegrity == nil {
+			c.newIntegrity = make(map[string]string)
+		}
+		c.newIntegrity[url] = entry.integrity
+		c.mutex.Unlock()
+	}
+
+	return entry.contents, entry.contentType, entry.finalURL, nil
+}
+
+// This returns the integrity hashes of the modules that were fetched but that
+// weren't in the lock file, so that they can be added to it. Calling this
+// resets the list.
+func (c *RemoteCache) TakeNewIntegrity() map[string]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	result := c.newIntegrity
+	c.newIntegrity = nil
+	return result
+}
+
+func loadRemoteEntry(options *config.RemoteImports, url string) (*remoteEntry, error) {
+	// Check the on-disk cache first
+	var cachePath string
+	if options.AbsCacheDir != "" {
+		key := sha256.Sum256([]byte(url))
+		cachePath = filepath.Join(options.AbsCacheDir, hex.EncodeToString(key[:]))
+		if contents, err := ioutil.ReadFile(cachePath); err == nil {
+			contentType, _ := ioutil.ReadFile(cachePath + ".content-type")
+			finalURL := url
+			if redirect, err := ioutil.ReadFile(cachePath + ".url"); err == nil {
+				finalURL = string(redirect)
+			}
+			return &remoteEntry{
+				contents:    string(contents),
+				contentType: string(contentType),
+				integrity:   RemoteIntegrity(string(contents)),
+				finalURL:    finalURL,
+			}, nil
+		}
+	}
+
+	if options.Offline {
+		return nil, errors.New("not in the cache and downloading is disabled in offline mode")
+	}
+
+	fetch := options.Fetch
+	if fetch == nil {
+		fetch = httpFetch
+	}
+	contents, contentType, finalURL, err := fetch(url)
+	if err != nil {
+		return nil, err
+	}
+	if finalURL == "" {
+		finalURL = url
+	}
+	entry := &remoteEntry{
+		contents:    string(contents),
+		contentType: contentType,
+		integrity:   RemoteIntegrity(string(contents)),
+		finalURL:    finalURL,
+	}
+
+	// Failing to write to the cache isn't fatal since the build can still
+	// continue. Files are renamed into place so that a concurrent process
+	// never observes a partially-written module.
+	if cachePath != "" && !options.DoNotWriteCache {
+		if err := os.MkdirAll(options.AbsCacheDir, 0755); err == nil {
+			writeAtomically(cachePath+".content-type", []byte(contentType))
+			if finalURL != url {
+				writeAtomically(cachePath+".url", []byte(finalURL))
+			}
+			writeAtomically(cachePath, contents)
+		}
+	}
+
+	return entry, nil
+}
+
+func writeAtomically(path string, contents []byte) {
+	temp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := ioutil.WriteFile(temp, contents, 0644); err == nil {
+		if os.Rename(temp, path) != nil {
+			os.Remove(temp)
+		}
+	}
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// The HTTP client follows redirects, and the request on the response is the
+// last request that was made
+func httpFetch(url string) ([]byte, string, string, error) {
+	response, err := httpClient.Get(url)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, "", "", fmt.Errorf("the server responded with %q", response.Status)
+	}
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return contents, response.Header.Get("Content-Type"), response.Request.URL.String(), nil
+}
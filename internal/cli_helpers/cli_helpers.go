@@ -49,10 +49,18 @@ func ParseLoader(text string) (api.Loader, *ErrorWithNote) {
 		return api.LoaderDefault, nil
 	case "copy":
 		return api.LoaderCopy, nil
+	case "html":
+		return api.LoaderHTML, nil
+	case "wasm":
+		return api.LoaderWasm, nil
+	case "wasm-file":
+		return api.LoaderWasmFile, nil
+	case "napi":
+		return api.LoaderNAPI, nil
 	default:
 		return api.LoaderNone, MakeErrorWithNote(
 			fmt.Sprintf("Invalid loader value: %q", text),
-			"Valid values are \"js\", \"jsx\", \"ts\", \"tsx\", \"css\", \"json\", \"text\", \"base64\", \"dataurl\", \"file\", \"binary\", or \"copy\".",
+			"Valid values are \"js\", \"jsx\", \"ts\", \"tsx\", \"css\", \"json\", \"text\", \"base64\", \"dataurl\", \"file\", \"binary\", \"copy\", \"html\", \"wasm\", \"wasm-file\", or \"napi\".",
 		)
 	}
 }
@@ -0,0 +1,258 @@
+package compat
+
+// These are runtime APIs instead of syntax features. They can't be lowered by
+// rewriting the code, so uses of them are reported instead. The code needs a
+// polyfill for these to work in the target environment.
+type RuntimeFeature uint32
+
+const (
+	AggregateError RuntimeFeature = 1 << iota
+	ArrayPrototypeAt
+	ArrayPrototypeFindLast
+	ArrayPrototypeFlat
+	GlobalThis
+	ObjectFromEntries
+	ObjectHasOwn
+	PromiseAllSettled
+	PromiseAny
+	QueueMicrotask
+	StringPrototypeReplaceAll
+	StructuredClone
+	WeakRef
+)
+
+var RuntimeFeatureToString = map[RuntimeFeature]string{
+	AggregateError:            "AggregateError",
+	ArrayPrototypeAt:          "Array.prototype.at",
+	ArrayPrototypeFindLast:    "Array.prototype.findLast",
+	ArrayPrototypeFlat:        "Array.prototype.flat",
+	GlobalThis:                "globalThis",
+	ObjectFromEntries:         "Object.fromEntries",
+	ObjectHasOwn:              "Object.hasOwn",
+	PromiseAllSettled:         "Promise.allSettled",
+	PromiseAny:                "Promise.any",
+	QueueMicrotask:            "queueMicrotask",
+	StringPrototypeReplaceAll: "String.prototype.replaceAll",
+	StructuredClone:           "structuredClone",
+	WeakRef:                   "WeakRef",
+}
+
+// These are detected as references to unbound global variables
+var RuntimeGlobals = map[string]RuntimeFeature{
+	"AggregateError":  AggregateError,
+	"globalThis":      GlobalThis,
+	"queueMicrotask":  QueueMicrotask,
+	"structuredClone": StructuredClone,
+	"WeakRef":         WeakRef,
+}
+
+// These are detected as property accesses on unbound global variables
+var RuntimeStaticProperties = map[string]map[string]RuntimeFeature{
+	"Object": {
+		"fromEntries": ObjectFromEntries,
+		"hasOwn":      ObjectHasOwn,
+	},
+	"Promise": {
+		"allSettled": PromiseAllSettled,
+		"any":        PromiseAny,
+	},
+}
+
+// These are detected using only the name of the method in a method call since
+// the type of the object isn't known. Some of these may be false positives.
+var RuntimePrototypeMethods = map[string]RuntimeFeature{
+	"at":            ArrayPrototypeAt,
+	"findLast":      ArrayPrototypeFindLast,
+	"findLastIndex": ArrayPrototypeFindLast,
+	"flat":          ArrayPrototypeFlat,
+	"flatMap":       ArrayPrototypeFlat,
+	"replaceAll":    StringPrototypeReplaceAll,
+}
+
+func (features RuntimeFeature) Has(feature RuntimeFeature) bool {
+	return (features & feature) != 0
+}
+
+// Returns the features in a deterministic order
+func (features RuntimeFeature) List() (list []RuntimeFeature) {
+	for feature := RuntimeFeature(1); feature != 0 && feature <= features; feature <<= 1 {
+		if features.Has(feature) {
+			list = append(list, feature)
+		}
+	}
+	return
+}
+
+var runtimeTable = map[RuntimeFeature]map[Engine][]versionRange{
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/AggregateError
+	AggregateError: {
+		Chrome:  {{start: v{85, 0, 0}}},
+		Edge:    {{start: v{85, 0, 0}}},
+		ES:      {{start: v{2021, 0, 0}}},
+		Firefox: {{start: v{79, 0, 0}}},
+		IOS:     {{start: v{14, 0, 0}}},
+		Node:    {{start: v{15, 0, 0}}},
+		Opera:   {{start: v{71, 0, 0}}},
+		Safari:  {{start: v{14, 0, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Array/at
+	ArrayPrototypeAt: {
+		Chrome:  {{start: v{92, 0, 0}}},
+		Edge:    {{start: v{92, 0, 0}}},
+		ES:      {{start: v{2022, 0, 0}}},
+		Firefox: {{start: v{90, 0, 0}}},
+		IOS:     {{start: v{15, 4, 0}}},
+		Node:    {{start: v{16, 6, 0}}},
+		Opera:   {{start: v{78, 0, 0}}},
+		Safari:  {{start: v{15, 4, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Array/findLast
+	ArrayPrototypeFindLast: {
+		Chrome:  {{start: v{97, 0, 0}}},
+		Edge:    {{start: v{97, 0, 0}}},
+		ES:      {{start: v{2023, 0, 0}}},
+		Firefox: {{start: v{104, 0, 0}}},
+		IOS:     {{start: v{15, 4, 0}}},
+		Node:    {{start: v{18, 0, 0}}},
+		Opera:   {{start: v{83, 0, 0}}},
+		Safari:  {{start: v{15, 4, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Array/flat
+	ArrayPrototypeFlat: {
+		Chrome:  {{start: v{69, 0, 0}}},
+		Edge:    {{start: v{79, 0, 0}}},
+		ES:      {{start: v{2019, 0, 0}}},
+		Firefox: {{start: v{62, 0, 0}}},
+		IOS:     {{start: v{12, 0, 0}}},
+		Node:    {{start: v{11, 0, 0}}},
+		Opera:   {{start: v{56, 0, 0}}},
+		Safari:  {{start: v{12, 0, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/globalThis
+	GlobalThis: {
+		Chrome:  {{start: v{71, 0, 0}}},
+		Edge:    {{start: v{79, 0, 0}}},
+		ES:      {{start: v{2020, 0, 0}}},
+		Firefox: {{start: v{65, 0, 0}}},
+		IOS:     {{start: v{12, 2, 0}}},
+		Node:    {{start: v{12, 0, 0}}},
+		Opera:   {{start: v{58, 0, 0}}},
+		Safari:  {{start: v{12, 1, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Object/fromEntries
+	ObjectFromEntries: {
+		Chrome:  {{start: v{73, 0, 0}}},
+		Edge:    {{start: v{79, 0, 0}}},
+		ES:      {{start: v{2019, 0, 0}}},
+		Firefox: {{start: v{63, 0, 0}}},
+		IOS:     {{start: v{12, 2, 0}}},
+		Node:    {{start: v{12, 0, 0}}},
+		Opera:   {{start: v{60, 0, 0}}},
+		Safari:  {{start: v{12, 1, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Object/hasOwn
+	ObjectHasOwn: {
+		Chrome:  {{start: v{93, 0, 0}}},
+		Edge:    {{start: v{93, 0, 0}}},
+		ES:      {{start: v{2022, 0, 0}}},
+		Firefox: {{start: v{92, 0, 0}}},
+		IOS:     {{start: v{15, 4, 0}}},
+		Node:    {{start: v{16, 9, 0}}},
+		Opera:   {{start: v{79, 0, 0}}},
+		Safari:  {{start: v{15, 4, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Promise/allSettled
+	PromiseAllSettled: {
+		Chrome:  {{start: v{76, 0, 0}}},
+		Edge:    {{start: v{79, 0, 0}}},
+		ES:      {{start: v{2020, 0, 0}}},
+		Firefox: {{start: v{71, 0, 0}}},
+		IOS:     {{start: v{13, 0, 0}}},
+		Node:    {{start: v{12, 9, 0}}},
+		Opera:   {{start: v{63, 0, 0}}},
+		Safari:  {{start: v{13, 0, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/Promise/any
+	PromiseAny: {
+		Chrome:  {{start: v{85, 0, 0}}},
+		Edge:    {{start: v{85, 0, 0}}},
+		ES:      {{start: v{2021, 0, 0}}},
+		Firefox: {{start: v{79, 0, 0}}},
+		IOS:     {{start: v{14, 0, 0}}},
+		Node:    {{start: v{15, 0, 0}}},
+		Opera:   {{start: v{71, 0, 0}}},
+		Safari:  {{start: v{14, 0, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/API/queueMicrotask
+	QueueMicrotask: {
+		Chrome:  {{start: v{71, 0, 0}}},
+		Edge:    {{start: v{79, 0, 0}}},
+		Firefox: {{start: v{69, 0, 0}}},
+		IOS:     {{start: v{12, 2, 0}}},
+		Node:    {{start: v{11, 0, 0}}},
+		Opera:   {{start: v{58, 0, 0}}},
+		Safari:  {{start: v{12, 1, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/String/replaceAll
+	StringPrototypeReplaceAll: {
+		Chrome:  {{start: v{85, 0, 0}}},
+		Edge:    {{start: v{85, 0, 0}}},
+		ES:      {{start: v{2021, 0, 0}}},
+		Firefox: {{start: v{77, 0, 0}}},
+		IOS:     {{start: v{13, 4, 0}}},
+		Node:    {{start: v{15, 0, 0}}},
+		Opera:   {{start: v{71, 0, 0}}},
+		Safari:  {{start: v{13, 1, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/API/structuredClone
+	StructuredClone: {
+		Chrome:  {{start: v{98, 0, 0}}},
+		Edge:    {{start: v{98, 0, 0}}},
+		Firefox: {{start: v{94, 0, 0}}},
+		IOS:     {{start: v{15, 4, 0}}},
+		Node:    {{start: v{17, 0, 0}}},
+		Opera:   {{start: v{84, 0, 0}}},
+		Safari:  {{start: v{15, 4, 0}}},
+	},
+
+	// Data from: https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Global_Objects/WeakRef
+	WeakRef: {
+		Chrome:  {{start: v{84, 0, 0}}},
+		Edge:    {{start: v{84, 0, 0}}},
+		ES:      {{start: v{2021, 0, 0}}},
+		Firefox: {{start: v{79, 0, 0}}},
+		IOS:     {{start: v{14, 5, 0}}},
+		Node:    {{start: v{14, 6, 0}}},
+		Opera:   {{start: v{70, 0, 0}}},
+		Safari:  {{start: v{14, 1, 0}}},
+	},
+}
+
+// Return all features that are not available in at least one environment
+func UnsupportedRuntimeFeatures(constraints map[Engine][]int) (unsupported RuntimeFeature) {
+	for feature, engines := range runtimeTable {
+		for engine, version := range constraints {
+			versionRanges, ok := engines[engine]
+			if !ok && engine == ES {
+				// Web APIs such as "structuredClone" aren't part of the language
+				// specification, so "--target=es2020" shouldn't affect them
+				continue
+			}
+			if !ok || !isVersionSupported(versionRanges, version) {
+				unsupported |= feature
+			}
+		}
+	}
+	return
+}
@@ -5,9 +5,11 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/logger"
 )
@@ -17,11 +19,67 @@ type JSXOptions struct {
 	Fragment DefineExpr
 	Parse    bool
 	Preserve bool
+
+	// If true, JSX elements are converted to calls to "jsx" and "jsxs" that
+	// are automatically imported from "ImportSource" + "/jsx-runtime" instead
+	// of to calls to the factory. This is React's "automatic" JSX runtime.
+	AutomaticRuntime bool
+	ImportSource     string // Default if empty: "react"
+	Development      bool   // Use "jsxDEV" from "/jsx-dev-runtime" instead
+}
+
+// These are the values of the "jsx" setting in "tsconfig.json" that affect
+// how esbuild transforms JSX
+type TSConfigJSX uint8
+
+const (
+	TSConfigJSXNone TSConfigJSX = iota
+	TSConfigJSXReact
+	TSConfigJSXReactJSX
+	TSConfigJSXReactJSXDev
+)
+
+func (value TSConfigJSX) ApplyTo(jsx *JSXOptions) {
+	switch value {
+	case TSConfigJSXReact:
+		jsx.AutomaticRuntime = false
+		jsx.Development = false
+	case TSConfigJSXReactJSX:
+		jsx.AutomaticRuntime = true
+		jsx.Development = false
+	case TSConfigJSXReactJSXDev:
+		jsx.AutomaticRuntime = true
+		jsx.Development = true
+	}
+}
+
+// This overrides the JSX settings for all files with an absolute path that
+// matches either "Pattern" or "Exact". A path in "Exact" matches that file or
+// any file inside that directory.
+type JSXPathOverride struct {
+	Pattern      WildcardPattern
+	Exact        string
+	Factory      DefineExpr
+	Fragment     DefineExpr
+	ImportSource string
+}
+
+func (override JSXPathOverride) Matches(path string) bool {
+	if exact := override.Exact; exact != "" {
+		return strings.HasPrefix(path, exact) && (len(path) == len(exact) || path[len(exact)] == '/' || path[len(exact)] == '\\')
+	}
+	prefix, suffix := override.Pattern.Prefix, override.Pattern.Suffix
+	return len(path) >= len(prefix)+len(suffix) && strings.HasPrefix(path, prefix) && strings.HasSuffix(path, suffix)
 }
 
 type TSOptions struct {
 	Parse               bool
 	NoAmbiguousLessThan bool
+
+	// If true, warn about TypeScript code that can't be compiled correctly
+	// when each file is compiled in isolation (i.e. without type information
+	// from other files). This is similar to TypeScript's "isolatedModules".
+	IsolatedModulesCheck bool
 }
 
 type Platform uint8
@@ -30,6 +88,7 @@ const (
 	PlatformBrowser Platform = iota
 	PlatformNode
 	PlatformNeutral
+	PlatformDeno
 )
 
 type SourceMap uint8
@@ -50,12 +109,32 @@ const (
 	LegalCommentsEndOfFile
 	LegalCommentsLinkedWithComment
 	LegalCommentsExternalWithoutComment
+
+	// All legal comments from all output files are written to a single
+	// "LEGAL.txt" file in the output directory
+	LegalCommentsCombined
 )
 
 func (lc LegalComments) HasExternalFile() bool {
-	return lc == LegalCommentsLinkedWithComment || lc == LegalCommentsExternalWithoutComment
+	return lc == LegalCommentsLinkedWithComment || lc == LegalCommentsExternalWithoutComment || lc == LegalCommentsCombined
 }
 
+// This is what happens to "require()" calls with a non-constant argument
+// when bundling
+type DynamicRequire uint8
+
+const (
+	// Leave the call as-is so that it's evaluated at run-time
+	DynamicRequireKeep DynamicRequire = iota
+
+	// Report an error (except inside a try/catch statement)
+	DynamicRequireError
+
+	// Bundle all files that the argument could refer to, and generate a map
+	// from each import path to the bundled module
+	DynamicRequireContext
+)
+
 type Loader uint8
 
 const (
@@ -67,15 +146,41 @@ const (
 	LoaderDataURL
 	LoaderDefault
 	LoaderFile
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderJSX
+	LoaderNAPI
 	LoaderText
 	LoaderTS
 	LoaderTSNoAmbiguousLessThan // Used with ".mts" and ".cts"
 	LoaderTSX
+	LoaderWasm
+	LoaderWasmFile
 )
 
+var LoaderToString = []string{
+	"none",
+	"base64",
+	"binary",
+	"copy",
+	"css",
+	"dataurl",
+	"default",
+	"file",
+	"html",
+	"js",
+	"json",
+	"jsx",
+	"napi",
+	"text",
+	"ts",
+	"ts",
+	"tsx",
+	"wasm",
+	"wasm-file",
+}
+
 func (loader Loader) IsTypeScript() bool {
 	switch loader {
 	case LoaderTS, LoaderTSNoAmbiguousLessThan, LoaderTSX:
@@ -156,6 +261,20 @@ type StdinInfo struct {
 	Loader        Loader
 }
 
+type BannerArgs struct {
+	EntryPoint string // The pretty path of the entry point, or empty for shared chunks
+	Kind       string // Either "js" or "css"
+	Name       string // The substitution for "[name]" in the output path
+}
+
+type VirtualModule struct {
+	Name          string
+	Contents      string
+	AbsResolveDir string
+	Loader        Loader
+	IsEntryPoint  bool
+}
+
 type WildcardPattern struct {
 	Prefix string
 	Suffix string
@@ -191,6 +310,31 @@ const (
 	False
 )
 
+type RemoteImports struct {
+	// Downloaded modules are stored in this directory so that later builds can
+	// reuse them. Remote modules are assumed to be immutable, so entries in
+	// this directory are never invalidated. Nothing is stored if this is empty.
+	AbsCacheDir string
+
+	// These are the integrity hashes from the lock file indexed by URL. They
+	// use the same "sha256-" format as subresource integrity.
+	Integrity map[string]string
+
+	// This downloads the module at the given URL. It defaults to an HTTP GET
+	// request if absent, but tests substitute a fake network here. The final
+	// URL is the URL of the module after following any redirects.
+	Fetch func(url string) (contents []byte, contentType string, finalURL string, err error)
+
+	Enabled bool
+
+	// Only use modules from the cache and fail if anything needs downloading
+	Offline bool
+
+	// Don't add downloaded modules to the cache directory. This is used when
+	// the build isn't writing to the file system (e.g. for "--dry-run").
+	DoNotWriteCache bool
+}
+
 type Options struct {
 	ModuleTypeData js_ast.ModuleTypeData
 	Defines        *ProcessedDefines
@@ -199,6 +343,9 @@ type Options struct {
 	MangleProps    *regexp.Regexp
 	ReserveProps   *regexp.Regexp
 
+	// If present, "KeepNames" only applies to names that match this
+	KeepNamesFilter *regexp.Regexp
+
 	// When mangling property names, call this function with a callback and do
 	// the property name mangling inside the callback. The callback takes an
 	// argument which is the mangle cache map to mutate. These callbacks are
@@ -214,6 +361,26 @@ type Options struct {
 	// has finished.
 	ExclusiveMangleCacheUpdate func(cb func(mangleCache map[string]interface{}))
 
+	// This is set by the bundler for each file that's parsed. It returns the
+	// import paths for all files matching an "import.meta.glob" pattern,
+	// relative to the directory containing the file being parsed.
+	ExpandGlob func(pattern string) []string
+
+	// What to do with "require()" calls that have a non-constant argument
+	DynamicRequire DynamicRequire
+
+	// These are used to re-print code with as little change as possible. All
+	// comments before statements are kept instead of only legal comments, and
+	// the printer reuses the original quote style of string literals and the
+	// blank lines between statements.
+	PreserveComments   bool
+	PreserveFormatting bool
+
+	// If true, the parser skips over statements containing syntax errors
+	// instead of stopping at the first one. This is used to report all syntax
+	// errors and to get a best-effort AST for code that's still being written.
+	ErrorRecovery bool
+
 	// This is the original information that was used to generate the
 	// unsupported feature sets above. It's used for error messages.
 	OriginalTargetEnv string
@@ -224,6 +391,34 @@ type Options struct {
 	AbsNodePaths     []string // The "NODE_PATH" variable from Node.js
 	ExternalSettings ExternalSettings
 
+	// This maps a package name to a value that replaces the "sideEffects" field
+	// in that package's "package.json" file
+	SideEffectsOverrides map[string]bool
+
+	// Controls whether "http://" and "https://" imports are downloaded and
+	// bundled instead of being left external
+	RemoteImports RemoteImports
+
+	// This maps import paths to the global variable (split into property
+	// names) that provides that module at run-time. These imports are bundled
+	// as a module that re-exports the global instead of being left external.
+	ExternalGlobals map[string][]string
+
+	// Imports of these paths are looked up at run-time in a shared scope, which
+	// is an object that separately-built bundles on the same page can share.
+	// The bundled copy of the module is only used if the scope doesn't already
+	// have a compatible version. "SharedScope" is a JavaScript expression that
+	// evaluates to the scope object.
+	SharedModules []string
+	SharedScope   string
+
+	// This is an import map (https://github.com/WICG/import-maps) that applies
+	// to all imports. If "ImportMapExternal" is true, bare specifiers matched
+	// by the map are left external and a copy of the map is written to the
+	// output directory so that the browser can apply it at run-time instead.
+	AbsImportMapPath  string
+	ImportMapExternal bool
+
 	AbsOutputFile      string
 	AbsOutputDir       string
 	AbsOutputBase      string
@@ -233,28 +428,72 @@ type Options struct {
 	TsConfigOverride   string
 	ExtensionToLoader  map[string]Loader
 
+	// If true, "tsconfig.json" files in subdirectories of the directory
+	// containing "TsConfigOverride" are still used for the files in those
+	// subdirectories instead of being replaced by the override
+	TsConfigNested bool
+
 	PublicPath      string
 	InjectAbsPaths  []string
 	InjectedDefines []InjectedDefine
 	InjectedFiles   []InjectedFile
 
+	// If true, injected files are considered to have no side effects. They are
+	// then only included in the bundle when one of their exports is used.
+	InjectIfUsed bool
+
+	// This overrides "PublicPath" for assets with certain file extensions
+	// (e.g. fonts on a different server than images)
+	AssetPublicPaths map[string]string
+
+	// Files that would use the "file" loader are inlined using the "dataurl"
+	// loader instead if they are smaller than this many bytes
+	AssetInlineLimit int
+
 	JSBanner  string
 	JSFooter  string
 	CSSBanner string
 	CSSFooter string
 
+	// If present, this returns the banner and footer for each output file
+	// instead of using the ones above. It may be called concurrently.
+	BannerCallback func(BannerArgs) (banner string, footer string)
+
 	EntryPathTemplate []PathTemplate
 	ChunkPathTemplate []PathTemplate
 	AssetPathTemplate []PathTemplate
 
+	// If present, the "[dir]" placeholder for assets is relative to this
+	// directory instead of to "outbase", and assets are written relative to
+	// "AbsAssetOutputDir" instead of to "AbsOutputDir"
+	AbsAssetRoot      string
+	AbsAssetOutputDir string
+
+	// These files and directories are copied into the output directory as-is
+	Copy []CopyPath
+
 	Plugins    []Plugin
 	SourceRoot string
 	Stdin      *StdinInfo
 	JSX        JSXOptions
 
+	// These convert stylesheets in other languages (e.g. Sass) into CSS before
+	// they are parsed
+	StylePreprocessors []StylePreprocessor
+
+	// These are parsed like stdin but there can be more than one. Import paths
+	// that are exactly equal to the name of one of these resolve to it.
+	VirtualModules []VirtualModule
+
+	// These are checked in order and later matches take precedence
+	JSXPathOverrides []JSXPathOverride
+
 	UnsupportedJSFeatures  compat.JSFeature
 	UnsupportedCSSFeatures compat.CSSFeature
 
+	// Runtime APIs can't be lowered, so uses of these are reported instead
+	UnsupportedRuntimeFeatures compat.RuntimeFeature
+
 	UnsupportedJSFeatureOverrides      compat.JSFeature
 	UnsupportedJSFeatureOverridesMask  compat.JSFeature
 	UnsupportedCSSFeatureOverrides     compat.CSSFeature
@@ -275,21 +514,102 @@ type Options struct {
 	// If true, make sure to generate a single file that can be written to stdout
 	WriteToStdout bool
 
+	// Files in these packages are put in a separate chunk per package when code
+	// splitting, regardless of which entry points use them
+	BoundaryPackages []string
+
+	// Packages in node_modules with these licenses cause a warning (SPDX
+	// identifiers, optionally with "*" wildcards)
+	DisallowedLicenses []string
+
+	// If true, every package in node_modules that ends up in the bundle must be
+	// at a version that's listed in the project's lock file
+	VerifyLockfile bool
+
 	OmitRuntimeForTests     bool
 	UnusedImportFlagsTS     UnusedImportFlagsTS
 	UseDefineForClassFields MaybeBool
+	EmitDecoratorMetadata   bool
 	ASCIIOnly               bool
+	BMPOnly                 bool
 	KeepNames               bool
+	KeepNamesOnly           KeepNamesOnly
 	IgnoreDCEAnnotations    bool
 	TreeShaking             bool
 	DropDebugger            bool
+	DropDeadImports         bool
 	MangleQuoted            bool
 	Platform                Platform
 	TargetFromAPI           TargetFromAPI
 	OutputFormat            Format
 	NeedsMetafile           bool
+	NameMap                 bool
+	PublishPackageJSON      bool
+	TSDeclarations          bool
+	ConcatReport            bool
+	ScanSecrets             bool
+	RefreshMetadata         bool
+	NameSeed                string
+	RuntimePrefix           string
+	HashSalt                string
+	ManifestPath            string
+	PolyfillReportPath      string
+	TreeShakingReportPath   string
+	Integrity               bool
+	DebugID                 bool
 	SourceMap               SourceMap
 	ExcludeSourcesContent   bool
+
+	// Sources with paths matching this are added to "x_google_ignoreList" in
+	// generated source maps. If this is nil, sources in "node_modules" are.
+	SourcesIgnoreList *regexp.Regexp
+
+	// If present, this is called for each path in the "sources" array of
+	// generated source maps and returns the path to write instead. It may be
+	// called from multiple goroutines at once.
+	SourcesRewrite func(SourcesRewriteArgs) string
+
+	// If true, CSS rules with class or id selectors that don't appear anywhere
+	// in the bundled JavaScript or HTML files are removed. Names matching any of
+	// the safelist patterns are always considered to be used.
+	PurgeCSS         bool
+	PurgeCSSSafelist []*regexp.Regexp
+
+	// If true, trade speed for lower peak memory usage when linking
+	LowMemory bool
+
+	// If true, avoid putting anything in the output that depends on the machine
+	// doing the build, such as absolute paths from plugins
+	Deterministic bool
+
+	// If present, the time spent in each phase of the build is recorded here
+	Timing *BuildTiming
+
+	// If present, this is checked before each file is parsed and printed so
+	// that builds that are no longer needed can stop early
+	CancelFlag *CancelFlag
+
+	// If present, this limits how many files are parsed and printed at once
+	Workers *helpers.WorkerLimiter
+}
+
+// This is set from another goroutine when the caller no longer needs the
+// result of a build. It's safe to call methods on a nil flag.
+type CancelFlag struct {
+	flag int32
+}
+
+func (f *CancelFlag) Cancel() {
+	atomic.StoreInt32(&f.flag, 1)
+}
+
+func (f *CancelFlag) DidCancel() bool {
+	return f != nil && atomic.LoadInt32(&f.flag) != 0
+}
+
+type CopyPath struct {
+	AbsSource string
+	RelDest   string // Relative to the output directory
 }
 
 type TargetFromAPI uint8
@@ -340,6 +660,20 @@ const (
 	UnusedImportKeepValues                                 // "preserveValueImports" == true
 )
 
+// These restrict "KeepNames" to a subset of names. Setting both classes and
+// functions is the same as setting neither of them.
+type KeepNamesOnly uint8
+
+const (
+	KeepNamesOnlyClasses KeepNamesOnly = 1 << iota
+	KeepNamesOnlyFunctions
+	KeepNamesOnlyExports
+)
+
+func (flags KeepNamesOnly) Has(flag KeepNamesOnly) bool {
+	return (flags & flag) != 0
+}
+
 func UnusedImportFlagsFromTsconfigValues(preserveImportsNotUsedAsValues bool, preserveValueImports bool) (flags UnusedImportFlagsTS) {
 	if preserveValueImports {
 		flags |= UnusedImportKeepValues
@@ -391,6 +725,10 @@ const (
 	// The original extension of the file, or the name of the output file
 	// (e.g. "css", "svg", "png")
 	ExtPlaceholder
+
+	// The "name" field from the nearest enclosing "package.json" file, or the
+	// empty string if there isn't one
+	PkgPlaceholder
 )
 
 type PathTemplate struct {
@@ -403,6 +741,7 @@ type PathPlaceholders struct {
 	Name *string
 	Hash *string
 	Ext  *string
+	Pkg  *string
 }
 
 func (placeholders PathPlaceholders) Get(placeholder PathPlaceholder) *string {
@@ -415,6 +754,8 @@ func (placeholders PathPlaceholders) Get(placeholder PathPlaceholder) *string {
 		return placeholders.Hash
 	case ExtPlaceholder:
 		return placeholders.Ext
+	case PkgPlaceholder:
+		return placeholders.Pkg
 	}
 	return nil
 }
@@ -436,6 +777,8 @@ func TemplateToString(template []PathTemplate) string {
 			sb.WriteString("[hash]")
 		case ExtPlaceholder:
 			sb.WriteString("[ext]")
+		case PkgPlaceholder:
+			sb.WriteString("[pkg]")
 		}
 	}
 	return sb.String()
@@ -481,8 +824,15 @@ func SubstituteTemplate(template []PathTemplate, placeholders PathPlaceholders)
 	return result
 }
 
-func ShouldCallRuntimeRequire(mode Mode, outputFormat Format) bool {
-	return mode == ModeBundle && outputFormat != FormatCommonJS
+func ShouldCallRuntimeRequire(mode Mode, platform Platform, outputFormat Format) bool {
+	return mode == ModeBundle && outputFormat != FormatCommonJS && !ShouldShimNodeESMGlobals(mode, platform, outputFormat)
+}
+
+// When bundling for node with the "esm" output format, the CommonJS globals
+// "require", "__dirname", and "__filename" don't exist. Instead of replacing
+// "require" with a stub that throws, each chunk that uses them defines them.
+func ShouldShimNodeESMGlobals(mode Mode, platform Platform, outputFormat Format) bool {
+	return mode == ModeBundle && platform == PlatformNode && outputFormat == FormatESModule
 }
 
 type InjectedDefine struct {
@@ -619,6 +969,13 @@ type OnLoadArgs struct {
 	Path       logger.Path
 }
 
+type SourcesRewriteArgs struct {
+	Path      string // The default path, which is relative to the source map
+	AbsPath   string // The absolute path (only for the "file" namespace)
+	InputPath string // The path relative to the working directory
+	Namespace string
+}
+
 type OnLoadResult struct {
 	PluginName string
 
@@ -626,6 +983,10 @@ type OnLoadResult struct {
 	AbsResolveDir string
 	PluginData    interface{}
 
+	// This is an optional source map for "Contents" in JSON format. It's used
+	// to map generated code back to the original file the plugin loaded.
+	SourceMap *string
+
 	Msgs        []logger.Msg
 	ThrownError error
 
@@ -634,3 +995,37 @@ type OnLoadResult struct {
 
 	Loader Loader
 }
+
+type StylePreprocessor struct {
+	Filter    *regexp.Regexp
+	Transform func(StylePreprocessorArgs) StylePreprocessorResult
+	Name      string
+}
+
+type StylePreprocessorArgs struct {
+	Path     logger.Path
+	Contents string
+
+	// This resolves and loads a file imported by the stylesheet (or by one of
+	// the files it imports, if "importer" is present) in the same way as other
+	// imports, so plugins and the file system are taken into account
+	Import func(path string, importer string) (StyleImport, error)
+}
+
+type StyleImport struct {
+	Path     string
+	Contents string
+}
+
+type StylePreprocessorResult struct {
+	Contents string
+
+	// This is an optional source map for "Contents" in JSON format. It's
+	// composed with the source map for the final CSS.
+	SourceMap *string
+
+	Msgs        []logger.Msg
+	ThrownError error
+
+	AbsWatchFiles []string
+}
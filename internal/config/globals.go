@@ -878,6 +878,12 @@ type DefineData struct {
 	// output, even when the arguments have side effects. This is used to
 	// implement the "--drop:console" flag.
 	MethodCallsMustBeReplacedWithUndefined bool
+
+	// If true, the user has indicated that every direct call to this value is
+	// to be removed from the output. Unlike "MethodCallsMustBeReplacedWithUndefined",
+	// any side effects in the call's arguments are kept. This is used to
+	// implement the "--drop-calls" flag.
+	CallMustBeDropped bool
 }
 
 func mergeDefineData(old DefineData, new DefineData) DefineData {
@@ -887,6 +893,9 @@ func mergeDefineData(old DefineData, new DefineData) DefineData {
 	if old.CallCanBeUnwrappedIfUnused {
 		new.CallCanBeUnwrappedIfUnused = true
 	}
+	if old.CallMustBeDropped {
+		new.CallMustBeDropped = true
+	}
 	return new
 }
 
@@ -898,6 +907,12 @@ type DotDefine struct {
 type ProcessedDefines struct {
 	IdentifierDefines map[string]DefineData
 	DotDefines        map[string][]DotDefine
+
+	// This identifies the options that these defines were generated from. The
+	// defines are generated again for every build, so this is used to tell if
+	// a file parsed with different defines (e.g. for an entry point that
+	// overrides them) can be reused.
+	Key string
 }
 
 // This transformation is expensive, so we only want to do it once. Make sure
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// This collects how long each phase of a build took so that users can tell
+// whether a slow build is caused by one of their plugins or by esbuild. The
+// parse, link, and print times are summed over all goroutines, so they can
+// add up to more than the wall-clock time of the build.
+type BuildTiming struct {
+	// These are accessed atomically and must come first for 64-bit alignment
+	parseNanos int64
+	linkNanos  int64
+	printNanos int64
+
+	Scan  time.Duration
+	Write time.Duration
+
+	mutex   sync.Mutex
+	plugins []*PluginTiming
+}
+
+type PluginTiming struct {
+	Name      string
+	OnStart   HookTiming
+	OnResolve HookTiming
+	OnLoad    HookTiming
+}
+
+type HookTiming struct {
+	Calls    int
+	Duration time.Duration
+}
+
+func (t *BuildTiming) AddParse(duration time.Duration) {
+	atomic.AddInt64(&t.parseNanos, int64(duration))
+}
+
+func (t *BuildTiming) AddLink(duration time.Duration) {
+	atomic.AddInt64(&t.linkNanos, int64(duration))
+}
+
+func (t *BuildTiming) AddPrint(duration time.Duration) {
+	atomic.AddInt64(&t.printNanos, int64(duration))
+}
+
+func (t *BuildTiming) Parse() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.parseNanos))
+}
+
+func (t *BuildTiming) Link() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.linkNanos))
+}
+
+func (t *BuildTiming) Print() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.printNanos))
+}
+
+// This returns a copy of the per-plugin timing in plugin order
+func (t *BuildTiming) Plugins() []PluginTiming {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	plugins := make([]PluginTiming, len(t.plugins))
+	for i, plugin := range t.plugins {
+		plugins[i] = *plugin
+	}
+	return plugins
+}
+
+func (t *BuildTiming) record(hook *HookTiming, start time.Time) {
+	duration := time.Since(start)
+	t.mutex.Lock()
+	hook.Calls++
+	hook.Duration += duration
+	t.mutex.Unlock()
+}
+
+// This returns copies of the plugins whose callbacks record how long they
+// take. The original plugins are left alone since they are reused when
+// rebuilding.
+func (t *BuildTiming) WrapPlugins(plugins []Plugin) []Plugin {
+	result := make([]Plugin, len(plugins))
+
+	for i, plugin := range plugins {
+		timing := &PluginTiming{Name: plugin.Name}
+		t.plugins = append(t.plugins, timing)
+		clone := Plugin{Name: plugin.Name}
+
+		for _, onStart := range plugin.OnStart {
+			callback := onStart.Callback
+			onStart.Callback = func() OnStartResult {
+				defer t.record(&timing.OnStart, time.Now())
+				return callback()
+			}
+			clone.OnStart = append(clone.OnStart, onStart)
+		}
+
+		for _, onResolve := range plugin.OnResolve {
+			callback := onResolve.Callback
+			onResolve.Callback = func(args OnResolveArgs) OnResolveResult {
+				defer t.record(&timing.OnResolve, time.Now())
+				return callback(args)
+			}
+			clone.OnResolve = append(clone.OnResolve, onResolve)
+		}
+
+		for _, onLoad := range plugin.OnLoad {
+			callback := onLoad.Callback
+			onLoad.Callback = func(args OnLoadArgs) OnLoadResult {
+				defer t.record(&timing.OnLoad, time.Now())
+				return callback(args)
+			}
+			clone.OnLoad = append(clone.OnLoad, onLoad)
+		}
+
+		result[i] = clone
+	}
+
+	return result
+}
@@ -261,6 +261,78 @@ type Rule struct {
 	Loc  logger.Loc
 }
 
+// Tokens refer to import records by index, so rules that contain URL tokens
+// can only be compared with other rules from the same file
+func TokensUseImportRecords(tokens []Token) bool {
+	for _, t := range tokens {
+		if t.Kind == css_lexer.TURL || (t.Children != nil && TokensUseImportRecords(*t.Children)) {
+			return true
+		}
+	}
+	return false
+}
+
+func RulesUseImportRecords(rules []Rule) bool {
+	for _, rule := range rules {
+		switch r := rule.Data.(type) {
+		case *RAtImport:
+			return true
+
+		case *RAtKeyframes:
+			for _, block := range r.Blocks {
+				if RulesUseImportRecords(block.Rules) {
+					return true
+				}
+			}
+
+		case *RKnownAt:
+			if TokensUseImportRecords(r.Prelude) || RulesUseImportRecords(r.Rules) {
+				return true
+			}
+
+		case *RUnknownAt:
+			if TokensUseImportRecords(r.Prelude) || TokensUseImportRecords(r.Block) {
+				return true
+			}
+
+		case *RSelector:
+			for _, complex := range r.Selectors {
+				for _, compound := range complex.Selectors {
+					for _, ss := range compound.SubclassSelectors {
+						if pseudo, ok := ss.(*SSPseudoClass); ok && TokensUseImportRecords(pseudo.Args) {
+							return true
+						}
+					}
+				}
+			}
+			if RulesUseImportRecords(r.Rules) {
+				return true
+			}
+
+		case *RQualified:
+			if TokensUseImportRecords(r.Prelude) || RulesUseImportRecords(r.Rules) {
+				return true
+			}
+
+		case *RDeclaration:
+			if TokensUseImportRecords(r.Value) {
+				return true
+			}
+
+		case *RBadDeclaration:
+			if TokensUseImportRecords(r.Tokens) {
+				return true
+			}
+
+		case *RAtLayer:
+			if RulesUseImportRecords(r.Rules) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type R interface {
 	Equal(rule R) bool
 	Hash() (uint32, bool)
@@ -373,7 +445,7 @@ type RKnownAt struct {
 
 func (a *RKnownAt) Equal(rule R) bool {
 	b, ok := rule.(*RKnownAt)
-	return ok && a.AtToken == b.AtToken && TokensEqual(a.Prelude, b.Prelude) && RulesEqual(a.Rules, a.Rules)
+	return ok && a.AtToken == b.AtToken && TokensEqual(a.Prelude, b.Prelude) && RulesEqual(a.Rules, b.Rules)
 }
 
 func (r *RKnownAt) Hash() (uint32, bool) {
@@ -392,7 +464,7 @@ type RUnknownAt struct {
 
 func (a *RUnknownAt) Equal(rule R) bool {
 	b, ok := rule.(*RUnknownAt)
-	return ok && a.AtToken == b.AtToken && TokensEqual(a.Prelude, b.Prelude) && TokensEqual(a.Block, a.Block)
+	return ok && a.AtToken == b.AtToken && TokensEqual(a.Prelude, b.Prelude) && TokensEqual(a.Block, b.Block)
 }
 
 func (r *RUnknownAt) Hash() (uint32, bool) {
@@ -78,9 +78,10 @@ func compactTokenQuad(a css_ast.Token, b css_ast.Token, c css_ast.Token, d css_a
 }
 
 func (p *parser) processDeclarations(rules []css_ast.Rule) []css_ast.Rule {
-	margin := boxTracker{key: css_ast.DMargin, keyText: "margin", allowAuto: true}
-	padding := boxTracker{key: css_ast.DPadding, keyText: "padding", allowAuto: false}
-	inset := boxTracker{key: css_ast.DInset, keyText: "inset", allowAuto: true}
+	margin := boxTracker{key: css_ast.DMargin, keyText: "margin", allowAuto: true, allowPercent: true}
+	padding := boxTracker{key: css_ast.DPadding, keyText: "padding", allowAuto: false, allowPercent: true}
+	inset := boxTracker{key: css_ast.DInset, keyText: "inset", allowAuto: true, allowPercent: true}
+	borderWidth := boxTracker{key: css_ast.DBorderWidth, keyText: "border-width", allowAuto: false, allowPercent: false}
 	borderRadius := borderRadiusTracker{}
 
 	for i, rule := range rules {
@@ -216,6 +217,44 @@ func (p *parser) processDeclarations(rules []css_ast.Rule) []css_ast.Rule {
 				inset.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxLeft)
 			}
 
+		// Border width
+		case css_ast.DBorderWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSides(rules, decl, i, p.options.MinifyWhitespace)
+			}
+		case css_ast.DBorderTopWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxTop)
+			}
+		case css_ast.DBorderRightWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxRight)
+			}
+		case css_ast.DBorderBottomWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxBottom)
+			}
+		case css_ast.DBorderLeftWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxLeft)
+			}
+		case css_ast.DBorder,
+			css_ast.DBorderTop,
+			css_ast.DBorderRight,
+			css_ast.DBorderBottom,
+			css_ast.DBorderLeft,
+			css_ast.DBorderBlockEnd,
+			css_ast.DBorderBlockEndWidth,
+			css_ast.DBorderBlockStart,
+			css_ast.DBorderBlockStartWidth,
+			css_ast.DBorderInlineEnd,
+			css_ast.DBorderInlineEndWidth,
+			css_ast.DBorderInlineStart,
+			css_ast.DBorderInlineStartWidth:
+			// These also set the width of one or more sides, so the border width
+			// properties before them must not be moved after them
+			borderWidth.sides = [4]boxSide{}
+
 		// Border radius
 		case css_ast.DBorderRadius:
 			if p.options.MinifySyntax {
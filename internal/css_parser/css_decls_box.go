@@ -21,11 +21,12 @@ type boxSide struct {
 }
 
 type boxTracker struct {
-	keyText   string
-	sides     [4]boxSide
-	allowAuto bool // If true, allow the "auto" keyword
-	important bool // True if all active rules were flagged as "!important"
-	key       css_ast.D
+	keyText      string
+	sides        [4]boxSide
+	allowAuto    bool // If true, allow the "auto" keyword
+	allowPercent bool // If true, allow percentages
+	important    bool // True if all active rules were flagged as "!important"
+	key          css_ast.D
 }
 
 type unitSafetyStatus uint8
@@ -103,7 +104,7 @@ func (box *boxTracker) mangleSides(rules []css_ast.Rule, decl *css_ast.RDeclarat
 	if box.allowAuto {
 		allowedIdent = "auto"
 	}
-	if quad, ok := expandTokenQuad(decl.Value, allowedIdent); ok {
+	if quad, ok := expandTokenQuad(decl.Value, allowedIdent); ok && (box.allowPercent || !hasPercent(quad)) {
 		// Use a single tracker for the whole rule
 		unitSafety := unitSafetyTracker{}
 		for _, t := range quad {
@@ -135,7 +136,8 @@ func (box *boxTracker) mangleSide(rules []css_ast.Rule, decl *css_ast.RDeclarati
 	}
 
 	if tokens := decl.Value; len(tokens) == 1 {
-		if t := tokens[0]; t.Kind.IsNumeric() || (t.Kind == css_lexer.TIdent && box.allowAuto && t.Text == "auto") {
+		if t := tokens[0]; (t.Kind.IsNumeric() && (box.allowPercent || t.Kind != css_lexer.TPercentage)) ||
+			(t.Kind == css_lexer.TIdent && box.allowAuto && t.Text == "auto") {
 			unitSafety := unitSafetyTracker{}
 			if !box.allowAuto || t.Kind.IsNumeric() {
 				unitSafety.includeUnitOf(t)
@@ -157,6 +159,15 @@ func (box *boxTracker) mangleSide(rules []css_ast.Rule, decl *css_ast.RDeclarati
 	box.sides = [4]boxSide{}
 }
 
+func hasPercent(quad [4]css_ast.Token) bool {
+	for _, t := range quad {
+		if t.Kind == css_lexer.TPercentage {
+			return true
+		}
+	}
+	return false
+}
+
 func (box *boxTracker) compactRules(rules []css_ast.Rule, keyRange logger.Range, minifyWhitespace bool) {
 	// All tokens must be present
 	if eof := css_lexer.TEndOfFile; box.sides[0].token.Kind == eof || box.sides[1].token.Kind == eof ||
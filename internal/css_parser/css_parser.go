@@ -289,7 +289,7 @@ loop:
 	}
 
 	if p.options.MinifySyntax {
-		rules = mangleRules(rules)
+		rules = p.mangleRules(rules)
 	}
 	return rules
 }
@@ -304,7 +304,7 @@ func (p *parser) parseListOfDeclarations() (list []css_ast.Rule) {
 		case css_lexer.TEndOfFile, css_lexer.TCloseBrace:
 			list = p.processDeclarations(list)
 			if p.options.MinifySyntax {
-				list = mangleRules(list)
+				list = p.mangleRules(list)
 			}
 			return
 
@@ -324,7 +324,7 @@ func (p *parser) parseListOfDeclarations() (list []css_ast.Rule) {
 	}
 }
 
-func mangleRules(rules []css_ast.Rule) []css_ast.Rule {
+func (p *parser) mangleRules(rules []css_ast.Rule) []css_ast.Rule {
 	type hashEntry struct {
 		indices []uint32
 	}
@@ -392,6 +392,23 @@ skipRule:
 			next--
 		}
 
+		// Merge adjacent rules with the same selectors. The declarations are
+		// processed again afterward so that longhand properties from different
+		// rules can be collapsed into shorthand properties.
+		// "a { color: red; } a { top: 0; }" => "a { color: red; top: 0; }"
+		if next >= 0 {
+			if r, ok := rule.Data.(*css_ast.RSelector); ok && !r.HasAtNest && hasOnlyDeclarations(r.Rules) {
+				if prev, ok := rules[next].Data.(*css_ast.RSelector); ok && !prev.HasAtNest && hasOnlyDeclarations(prev.Rules) &&
+					complexSelectorsEqual(r.Selectors, prev.Selectors) {
+					merged := make([]css_ast.Rule, 0, len(prev.Rules)+len(r.Rules))
+					merged = append(merged, prev.Rules...)
+					merged = append(merged, r.Rules...)
+					prev.Rules = p.mangleRules(p.processDeclarations(merged))
+					continue skipRule
+				}
+			}
+		}
+
 		// Merge adjacent selectors with the same content
 		// "a { color: red; } b { color: red; }" => "a, b { color: red; }"
 		if next >= 0 {
@@ -432,6 +449,27 @@ skipRule:
 	return rules[start:]
 }
 
+func hasOnlyDeclarations(rules []css_ast.Rule) bool {
+	for _, rule := range rules {
+		if _, ok := rule.Data.(*css_ast.RDeclaration); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func complexSelectorsEqual(a []css_ast.ComplexSelector, b []css_ast.ComplexSelector) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, sel := range a {
+		if !sel.Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // Reference: https://developer.mozilla.org/en-US/docs/Web/HTML/Element
 var nonDeprecatedElementsSupportedByIE7 = map[string]bool{
 	"a":          true,
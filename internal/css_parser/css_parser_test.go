@@ -1283,6 +1283,30 @@ func TestMarginAndPaddingAndInset(t *testing.T) {
 	expectPrintedLowerMangle(t, "a { top: 0; right: 0; bottom: 0; left: 0; }", "a {\n  top: 0;\n  right: 0;\n  bottom: 0;\n  left: 0;\n}\n")
 }
 
+func TestBorderWidth(t *testing.T) {
+	expectPrinted(t, "a { border-top-width: 1px; border-right-width: 2px; border-bottom-width: 3px; border-left-width: 4px }",
+		"a {\n  border-top-width: 1px;\n  border-right-width: 2px;\n  border-bottom-width: 3px;\n  border-left-width: 4px;\n}\n")
+	expectPrintedMangle(t, "a { border-top-width: 1px; border-right-width: 2px; border-bottom-width: 3px; border-left-width: 4px }",
+		"a {\n  border-width: 1px 2px 3px 4px;\n}\n")
+	expectPrintedMangle(t, "a { border-top-width: 0px; border-right-width: 1px; border-bottom-width: 0px; border-left-width: 1px }",
+		"a {\n  border-width: 0 1px;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 0 0 0 0 }", "a {\n  border-width: 0;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 1px 2px 3px 4px; border-top-width: 5px }", "a {\n  border-width: 5px 2px 3px 4px;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 1px; border-left-width: 2px !important }",
+		"a {\n  border-width: 1px;\n  border-left-width: 2px !important;\n}\n")
+
+	// Keywords and percentages are not collapsed
+	expectPrintedMangle(t, "a { border-width: thin; border-left-width: 1px }", "a {\n  border-width: thin;\n  border-left-width: 1px;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 1px; border-left-width: 10% }", "a {\n  border-width: 1px;\n  border-left-width: 10%;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 1px 10%; border-left-width: 1px }", "a {\n  border-width: 1px 10%;\n  border-left-width: 1px;\n}\n")
+
+	// Other properties that set the border width must not be reordered
+	expectPrintedMangle(t, "a { border-width: 1px; border-top: 2px solid; border-left-width: 3px }",
+		"a {\n  border-width: 1px;\n  border-top: 2px solid;\n  border-left-width: 3px;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 1px; border-block-start-width: 2px; border-left-width: 3px }",
+		"a {\n  border-width: 1px;\n  border-block-start-width: 2px;\n  border-left-width: 3px;\n}\n")
+}
+
 func TestBorderRadius(t *testing.T) {
 	expectPrinted(t, "a { border-top-left-radius: 0 0 }", "a {\n  border-top-left-radius: 0 0;\n}\n")
 	expectPrintedMangle(t, "a { border-top-left-radius: 0 0 }", "a {\n  border-top-left-radius: 0;\n}\n")
@@ -1385,7 +1409,7 @@ func TestDeduplicateRules(t *testing.T) {
 	expectPrinted(t, "a { color: red } a { color: green } a { color: red }",
 		"a {\n  color: red;\n}\na {\n  color: green;\n}\na {\n  color: red;\n}\n")
 	expectPrintedMangle(t, "a { color: red } a { color: green } a { color: red }",
-		"a {\n  color: green;\n}\na {\n  color: red;\n}\n")
+		"a {\n  color: green;\n  color: red;\n}\n")
 
 	expectPrintedMangle(t, "@media screen { a { color: red } } @media screen { a { color: red } }",
 		"@media screen {\n  a {\n    color: red;\n  }\n}\n")
@@ -1793,6 +1817,29 @@ func TestMangleDuplicateSelectorRules(t *testing.T) {
 	expectPrintedMangle(t, "c { color: green } a { color: red } /*!x*/ /*!y*/ a { color: red }", "c {\n  color: green;\n}\na {\n  color: red;\n}\n/*!x*/\n/*!y*/\n")
 }
 
+func TestMangleAdjacentRulesWithSameSelectors(t *testing.T) {
+	expectPrinted(t, "a { color: red } a { top: 0 }", "a {\n  color: red;\n}\na {\n  top: 0;\n}\n")
+	expectPrintedMangle(t, "a { color: red } a { top: 0 }", "a {\n  color: red;\n  top: 0;\n}\n")
+	expectPrintedMangle(t, "a, b { color: red } a, b { top: 0 }", "a,\nb {\n  color: red;\n  top: 0;\n}\n")
+	expectPrintedMangle(t, "a { color: red } a { color: red; top: 0 }", "a {\n  color: red;\n  top: 0;\n}\n")
+	expectPrintedMangle(t, "a { color: red } a { color: blue }", "a {\n  color: red;\n  color: #00f;\n}\n")
+	expectPrintedMangle(t, "a { color: red } /*!x*/ a { top: 0 }", "a {\n  color: red;\n  top: 0;\n}\n/*!x*/\n")
+	expectPrintedMangle(t, "@media screen { a { color: red } a { top: 0 } }", "@media screen {\n  a {\n    color: red;\n    top: 0;\n  }\n}\n")
+
+	// Longhand properties from different rules can be collapsed together
+	expectPrintedMangle(t, "a { margin-top: 1px; margin-right: 2px } a { margin-bottom: 3px; margin-left: 4px }",
+		"a {\n  margin: 1px 2px 3px 4px;\n}\n")
+	expectPrintedMangle(t, "a { margin: 0 } a { margin-top: 1px }", "a {\n  margin: 1px 0 0;\n}\n")
+
+	// Do not merge rules that aren't adjacent
+	expectPrintedMangle(t, "a { color: red } b { top: 1px } a { top: 0 }", "a {\n  color: red;\n}\nb {\n  top: 1px;\n}\na {\n  top: 0;\n}\n")
+	expectPrintedMangle(t, "a { color: red } a:hover { top: 0 }", "a {\n  color: red;\n}\na:hover {\n  top: 0;\n}\n")
+	expectPrintedMangle(t, "a, b { color: red } b, a { top: 0 }", "a,\nb {\n  color: red;\n}\nb,\na {\n  top: 0;\n}\n")
+
+	// Do not merge rules with nested rules
+	expectPrintedMangle(t, "a { color: red } a { & b { top: 0 } }", "a {\n  color: red;\n}\na {\n  & b {\n    top: 0;\n  }\n}\n")
+}
+
 func TestFontWeight(t *testing.T) {
 	expectPrintedMangle(t, "a { font-weight: normal }", "a {\n  font-weight: 400;\n}\n")
 	expectPrintedMangle(t, "a { font-weight: bold }", "a {\n  font-weight: 700;\n}\n")
@@ -34,6 +34,7 @@ type Options struct {
 
 	MinifyWhitespace  bool
 	ASCIIOnly         bool
+	BMPOnly           bool
 	AddSourceMappings bool
 	LegalComments     config.LegalComments
 }
@@ -68,7 +69,8 @@ func (p *printer) printRule(rule css_ast.Rule, indent int32, omitTrailingSemicol
 
 		case config.LegalCommentsEndOfFile,
 			config.LegalCommentsLinkedWithComment,
-			config.LegalCommentsExternalWithoutComment:
+			config.LegalCommentsExternalWithoutComment,
+			config.LegalCommentsCombined:
 			if p.extractedLegalComments == nil {
 				p.extractedLegalComments = make(map[string]bool)
 			}
@@ -576,7 +578,7 @@ func (p *printer) printQuotedWithQuote(text string, quote byte) {
 			}
 
 		default:
-			if (p.options.ASCIIOnly && c >= 0x80) || c == '\uFEFF' {
+			if (p.options.ASCIIOnly && c >= 0x80) || (p.options.BMPOnly && helpers.IsEscapedInBMPCharset(c)) || c == '\uFEFF' {
 				escape = escapeHex
 			}
 		}
@@ -608,7 +610,7 @@ func (p *printer) printIdent(text string, mode identMode, whitespace trailingWhi
 	for i, c := range text {
 		escape := escapeNone
 
-		if p.options.ASCIIOnly && c >= 0x80 {
+		if (p.options.ASCIIOnly && c >= 0x80) || (p.options.BMPOnly && helpers.IsEscapedInBMPCharset(c)) {
 			escape = escapeHex
 		} else if c == '\r' || c == '\n' || c == '\f' || c == '\uFEFF' {
 			// Use a hexadecimal escape for characters that would be invalid escapes
@@ -55,6 +55,13 @@ func expectPrintedASCII(t *testing.T, contents string, expected string) {
 	})
 }
 
+func expectPrintedBMP(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents+" [bmp]", contents, expected, Options{
+		BMPOnly: true,
+	})
+}
+
 func expectPrintedString(t *testing.T, stringValue string, expected string) {
 	t.Helper()
 	t.Run(stringValue, func(t *testing.T) {
@@ -412,3 +419,10 @@ func TestASCII(t *testing.T) {
 	// This character should always be escaped
 	expectPrinted(t, ".\\FEFF:after { content: '\uFEFF' }", ".\\feff:after {\n  content: \"\\feff\";\n}\n")
 }
+
+func TestBMP(t *testing.T) {
+	expectPrintedBMP(t, ".貓 { content: '貓' }", ".貓 {\n  content: \"貓\";\n}\n")
+	expectPrintedBMP(t, ".🐈 { content: '🐈' }", ".\\1f408  {\n  content: \"\\1f408\";\n}\n")
+	expectPrintedBMP(t, "* { background: url(🐈.png) }", "* {\n  background: url(\\1f408.png);\n}\n")
+	expectPrintedBMP(t, "* { content: 'a\u200Bb' }", "* {\n  content: \"a\\200b b\";\n}\n")
+}
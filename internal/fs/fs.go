@@ -221,16 +221,60 @@ type ModKey struct {
 const modKeySafetyGap = 3 // In seconds
 var modKeyUnusable = errors.New("The modification key is unusable")
 
-// Limit the number of files open simultaneously to avoid ulimit issues
-var fileOpenLimit = make(chan bool, 32)
+// Limit the number of files open simultaneously to avoid ulimit issues. The
+// default limit is shared by everything in the process. A file system that
+// was created with its own limit uses that limit instead, so changing the
+// limit for one build doesn't affect any other builds.
+const DefaultMaxOpenFiles = 32
+
+type OpenFileLimit struct {
+	cond  *sync.Cond
+	mutex sync.Mutex
+	count int
+	limit int
+}
+
+func NewOpenFileLimit(limit int) *OpenFileLimit {
+	l := &OpenFileLimit{limit: limit}
+	l.cond = sync.NewCond(&l.mutex)
+	return l
+}
+
+var sharedOpenFileLimit = NewOpenFileLimit(DefaultMaxOpenFiles)
+
+func (l *OpenFileLimit) BeforeFileOpen() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 
-func BeforeFileOpen() {
 	// This will block if the number of open files is already at the limit
-	fileOpenLimit <- false
+	for l.count >= l.limit {
+		l.cond.Wait()
+	}
+	l.count++
+}
+
+func (l *OpenFileLimit) AfterFileClose() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.count--
+	l.cond.Signal()
+}
+
+func BeforeFileOpen() {
+	sharedOpenFileLimit.BeforeFileOpen()
 }
 
 func AfterFileClose() {
-	<-fileOpenLimit
+	sharedOpenFileLimit.AfterFileClose()
+}
+
+// Files that a build writes should count towards the same limit as the files
+// that the build reads
+func OpenFileLimitOf(fs FS) *OpenFileLimit {
+	if real, ok := fs.(*realFS); ok {
+		return real.openFiles
+	}
+	return sharedOpenFileLimit
 }
 
 // This is a fork of "os.MkdirAll" to work around bugs with the WebAssembly
@@ -27,6 +27,9 @@ type realFS struct {
 	entriesMutex sync.Mutex
 	watchMutex   sync.Mutex
 
+	// This limits the number of files that are open at the same time
+	openFiles *OpenFileLimit
+
 	// If true, do not use the "entries" cache
 	doNotCacheEntries bool
 }
@@ -60,6 +63,11 @@ type RealFSOptions struct {
 	AbsWorkingDir string
 	WantWatchData bool
 	DoNotCache    bool
+
+	// If this is positive, files opened by this file system count towards a
+	// limit of this many open files that belongs to this file system. The
+	// default limit is shared by the whole process.
+	MaxOpenFiles int
 }
 
 func RealFS(options RealFSOptions) (FS, error) {
@@ -110,10 +118,16 @@ func RealFS(options RealFSOptions) (FS, error) {
 		watchData = make(map[string]privateWatchData)
 	}
 
+	openFiles := sharedOpenFileLimit
+	if options.MaxOpenFiles > 0 {
+		openFiles = NewOpenFileLimit(options.MaxOpenFiles)
+	}
+
 	return &realFS{
 		entries:           make(map[string]entriesOrErr),
 		fp:                fp,
 		watchData:         watchData,
+		openFiles:         openFiles,
 		doNotCacheEntries: options.DoNotCache,
 	}, nil
 }
@@ -188,8 +202,8 @@ func (fs *realFS) ReadDirectory(dir string) (entries DirEntries, canonicalError
 }
 
 func (fs *realFS) ReadFile(path string) (contents string, canonicalError error, originalError error) {
-	BeforeFileOpen()
-	defer AfterFileClose()
+	fs.openFiles.BeforeFileOpen()
+	defer fs.openFiles.AfterFileClose()
 	buffer, originalError := ioutil.ReadFile(path)
 	canonicalError = fs.canonicalizeError(originalError)
 
@@ -246,9 +260,13 @@ func (f *realOpenedFile) Close() error {
 	return f.handle.Close()
 }
 
+// The file only counts towards the open file limit while it's being opened.
+// Opened files may be kept open for a long time (e.g. while the development
+// server is streaming one to a slow client) and holding on to a slot for that
+// long could stall builds that are running at the same time.
 func (fs *realFS) OpenFile(path string) (OpenedFile, error, error) {
-	BeforeFileOpen()
-	defer AfterFileClose()
+	fs.openFiles.BeforeFileOpen()
+	defer fs.openFiles.AfterFileClose()
 
 	f, err := os.Open(path)
 	if err != nil {
@@ -265,8 +283,8 @@ func (fs *realFS) OpenFile(path string) (OpenedFile, error, error) {
 }
 
 func (fs *realFS) ModKey(path string) (ModKey, error) {
-	BeforeFileOpen()
-	defer AfterFileClose()
+	fs.openFiles.BeforeFileOpen()
+	defer fs.openFiles.AfterFileClose()
 	key, err := modKey(path)
 
 	// Store data for watch mode
@@ -329,8 +347,8 @@ func (fs *realFS) Rel(base string, target string) (string, bool) {
 }
 
 func (fs *realFS) readdir(dirname string) (entries []string, canonicalError error, originalError error) {
-	BeforeFileOpen()
-	defer AfterFileClose()
+	fs.openFiles.BeforeFileOpen()
+	defer fs.openFiles.AfterFileClose()
 	f, originalError := os.Open(dirname)
 	canonicalError = fs.canonicalizeError(originalError)
 
@@ -385,8 +403,8 @@ func (fs *realFS) kind(dir string, base string) (symlink string, kind EntryKind)
 	entryPath := fs.fp.join([]string{dir, base})
 
 	// Use "lstat" since we want information about symbolic links
-	BeforeFileOpen()
-	defer AfterFileClose()
+	fs.openFiles.BeforeFileOpen()
+	defer fs.openFiles.AfterFileClose()
 	stat, err := os.Lstat(entryPath)
 	if err != nil {
 		return
@@ -0,0 +1,101 @@
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func expectDoneBeforeTimeout(t *testing.T, done chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for %s", what)
+	}
+}
+
+func TestRealFSOpenFileReleasesLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-fs-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs, err := RealFS(RealFSOptions{AbsWorkingDir: dir, MaxOpenFiles: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files that are kept open must not block other files from being opened
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, name := range []string{"a.txt", "b.txt"} {
+			file, err, _ := fs.OpenFile(fs.Join(dir, name))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer file.Close()
+		}
+	}()
+	expectDoneBeforeTimeout(t, done, "the second file to be opened")
+}
+
+func TestMaxOpenFilesIsPerFileSystem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-fs-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	limited, err := RealFS(RealFSOptions{AbsWorkingDir: dir, MaxOpenFiles: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := RealFS(RealFSOptions{AbsWorkingDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openFiles := OpenFileLimitOf(limited)
+	openFiles.BeforeFileOpen()
+
+	// Another file system must not be affected by this limit
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err, _ := other.ReadFile(other.Join(dir, "a.txt")); err != nil {
+			t.Error(err)
+		}
+	}()
+	expectDoneBeforeTimeout(t, done, "the other file system to read a file")
+
+	// This blocks until the open file is closed
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err, _ := limited.ReadFile(limited.Join(dir, "a.txt")); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+		t.Fatal("Expected the open file limit to be enforced")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	openFiles.AfterFileClose()
+	expectDoneBeforeTimeout(t, done, "the open file to be closed")
+}
@@ -95,6 +95,15 @@ type EntryPoint struct {
 	// "outbase" directory, which is computed as the lowest common ancestor of
 	// all automatically generated output paths.
 	OutputPathWasAutoGenerated bool
+
+	// This is true if "OutputPath" is relative to the automatically-computed
+	// "outbase" directory, in which case it must be recomputed if that
+	// directory changes
+	OutputPathIsRelativeToOutbase bool
+
+	// Workers that are only ever inlined into the files that construct them
+	// don't need their own output files
+	IsInlineOnlyWorker bool
 }
 
 type LinkerGraph struct {
@@ -11,6 +11,7 @@ import (
 	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/config"
 	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/html_parser"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/resolver"
@@ -27,6 +28,15 @@ type InputFile struct {
 	AdditionalFiles            []OutputFile
 	UniqueKeyForAdditionalFile string
 
+	// If this file is bundled as a worker, this is the path of the worker's
+	// output file. It's filled in once the worker has been linked so that the
+	// path can be substituted into the files that construct the worker.
+	AbsWorkerOutputPath string
+
+	// If this file is bundled as a worker that is inlined into other files, this
+	// is the contents of the worker's output file
+	InlineWorkerContents []byte
+
 	SideEffects SideEffects
 	Source      logger.Source
 	Loader      config.Loader
@@ -41,6 +51,34 @@ type OutputFile struct {
 	AbsPath      string
 	Contents     []byte
 	IsExecutable bool
+
+	// If this is the primary output file for an entry point, this is the
+	// source index of that entry point. It's used to map the paths in a
+	// "package.json" file from input files to output files.
+	EntryPointSourceIndex ast.Index32
+
+	// If this is the CSS file generated for the CSS imported by a JavaScript
+	// entry point, this is the source index of that entry point. It's used to
+	// add the CSS file to HTML files that reference the JavaScript file.
+	CSSForEntryPointSourceIndex ast.Index32
+
+	// If this is the primary output file for an entry point and code splitting
+	// is enabled, these are the chunks that it imports with import statements
+	// (possibly indirectly), sorted by how soon they are needed. They can be
+	// preloaded to avoid a waterfall of requests.
+	AbsPreloadPaths []string
+
+	// These are the legal comments that were extracted from the code in this
+	// output file, sorted by text. They are combined into a single file when
+	// legal comments are set to "combined".
+	LegalComments []LegalComment
+}
+
+type LegalComment struct {
+	Text string
+
+	// The pretty paths of the input files that contain this comment
+	InputPaths []string
 }
 
 type SideEffects struct {
@@ -72,6 +110,10 @@ const (
 	// unused imports to these files since running the plugin is a side effect.
 	// Removing the import would not call the plugin which is observable.
 	NoSideEffects_PureData_FromPlugin
+
+	// This file was injected while "InjectIfUsed" was enabled, which means it
+	// should only be included if one of its exports is used.
+	NoSideEffects_InjectIfUsed
 )
 
 type InputFileRepr interface {
@@ -115,6 +157,14 @@ func (repr *CSSRepr) ImportRecords() *[]ast.ImportRecord {
 	return &repr.AST.ImportRecords
 }
 
+type HTMLRepr struct {
+	AST html_parser.AST
+}
+
+func (repr *HTMLRepr) ImportRecords() *[]ast.ImportRecord {
+	return &repr.AST.ImportRecords
+}
+
 type CopyRepr struct {
 	// The URL that replaces the contents of any import record paths for this file
 	URLForCode string
@@ -43,6 +43,46 @@ const (
 	WrapESM
 )
 
+// This records why a module had to be wrapped instead of being hoisted into
+// the top-level scope of the chunk. It's only used for reporting.
+type WrapReason uint8
+
+const (
+	WrapReasonNone WrapReason = iota
+
+	// The module uses CommonJS features such as "module" or "exports"
+	WrapReasonCommonJS
+
+	// The module is imported using "require()"
+	WrapReasonRequire
+
+	// The module is imported using "import()" and code splitting is disabled
+	WrapReasonDynamicImport
+
+	// The module has no exports but is imported using "import * as" or a
+	// default import, so it's treated as a CommonJS module
+	WrapReasonImportStar
+
+	// The module is imported by another module that is wrapped
+	WrapReasonDependency
+)
+
+func (reason WrapReason) String() string {
+	switch reason {
+	case WrapReasonCommonJS:
+		return "commonjs"
+	case WrapReasonRequire:
+		return "require"
+	case WrapReasonDynamicImport:
+		return "dynamic-import"
+	case WrapReasonImportStar:
+		return "import-star"
+	case WrapReasonDependency:
+		return "dependency"
+	}
+	return ""
+}
+
 // This contains linker-specific metadata corresponding to a "file" struct
 // from the initial scan phase of the bundler. It's separated out because it's
 // conceptually only used for a single linking operation and because multiple
@@ -133,6 +173,10 @@ type JSReprMeta struct {
 
 	Wrap WrapKind
 
+	// This is the reason "Wrap" was set and the file that caused it, if any
+	WrapImporter ast.Index32
+	WrapReason   WrapReason
+
 	// If true, we need to insert "var exports = {};". This is the case for ESM
 	// files when the import namespace is captured via "import * as" and also
 	// when they are the target of a "require()" call.
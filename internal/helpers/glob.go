@@ -0,0 +1,108 @@
+package helpers
+
+import "strings"
+
+// These implement the subset of glob syntax used by "import.meta.glob". Paths
+// always use "/" as the separator. A "**" segment matches zero or more path
+// segments, "*" matches zero or more characters within a segment, "?" matches
+// a single character within a segment, and "{a,b}" matches either alternative.
+
+func IsGlobPattern(text string) bool {
+	return strings.ContainsAny(text, "*?{")
+}
+
+// Brace alternatives are expanded up front so that the other functions don't
+// need to handle them. Nested braces are supported.
+func ExpandGlobBraces(pattern string) []string {
+	open := strings.IndexByte(pattern, '{')
+	if open == -1 {
+		return []string{pattern}
+	}
+
+	// Find the matching close brace and split the alternatives at the top level
+	depth := 0
+	start := open + 1
+	var alternatives []string
+	for i := open; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[start:i])
+				start = i + 1
+			}
+
+		case '}':
+			depth--
+			if depth == 0 {
+				alternatives = append(alternatives, pattern[start:i])
+				prefix, suffix := pattern[:open], pattern[i+1:]
+				var results []string
+				for _, alternative := range alternatives {
+					results = append(results, ExpandGlobBraces(prefix+alternative+suffix)...)
+				}
+				return results
+			}
+		}
+	}
+
+	// Treat an unterminated brace as a literal character
+	return []string{pattern}
+}
+
+// The pattern must not contain braces (use "ExpandGlobBraces" first)
+func GlobMatch(pattern string, path string) bool {
+	return globMatchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func globMatchSegments(pattern []string, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if globMatchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 || !GlobMatchSegment(pattern[0], path[0]) {
+			return false
+		}
+		pattern = pattern[1:]
+		path = path[1:]
+	}
+	return len(path) == 0
+}
+
+// The pattern must not contain braces (use "ExpandGlobBraces" first)
+func GlobMatchSegment(pattern string, name string) bool {
+	p := []rune(pattern)
+	n := []rune(name)
+	pi, ni := 0, 0
+	starP, starN := -1, 0
+
+	for ni < len(n) {
+		if pi < len(p) && (p[pi] == '?' || p[pi] == n[ni]) {
+			pi++
+			ni++
+		} else if pi < len(p) && p[pi] == '*' {
+			starP = pi
+			starN = ni
+			pi++
+		} else if starP != -1 {
+			// Backtrack and let the last "*" consume one more character
+			pi = starP + 1
+			starN++
+			ni = starN
+		} else {
+			return false
+		}
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
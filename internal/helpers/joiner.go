@@ -12,6 +12,7 @@ import (
 type Joiner struct {
 	strings  []joinerString
 	bytes    []joinerBytes
+	readers  []joinerReader
 	length   uint32
 	lastByte byte
 }
@@ -26,6 +27,12 @@ type joinerBytes struct {
 	offset uint32
 }
 
+type joinerReader struct {
+	read   func(buffer []byte)
+	offset uint32
+	length uint32
+}
+
 func (j *Joiner) AddString(data string) {
 	if len(data) > 0 {
 		j.lastByte = data[len(data)-1]
@@ -42,6 +49,17 @@ func (j *Joiner) AddBytes(data []byte) {
 	j.length += uint32(len(data))
 }
 
+// The data is only read when "Done" is called, so it doesn't need to be kept
+// in memory until then. The last byte must be passed in ahead of time since
+// it's needed by "LastByte" before the data has been read.
+func (j *Joiner) AddReader(length uint32, lastByte byte, read func(buffer []byte)) {
+	if length > 0 {
+		j.lastByte = lastByte
+	}
+	j.readers = append(j.readers, joinerReader{read, j.length, length})
+	j.length += length
+}
+
 func (j *Joiner) LastByte() byte {
 	return j.lastByte
 }
@@ -57,7 +75,7 @@ func (j *Joiner) EnsureNewlineAtEnd() {
 }
 
 func (j *Joiner) Done() []byte {
-	if len(j.strings) == 0 && len(j.bytes) == 1 && j.bytes[0].offset == 0 {
+	if len(j.strings) == 0 && len(j.readers) == 0 && len(j.bytes) == 1 && j.bytes[0].offset == 0 {
 		// No need to allocate if there was only a single byte array written
 		return j.bytes[0].data
 	}
@@ -68,10 +86,18 @@ func (j *Joiner) Done() []byte {
 	for _, item := range j.bytes {
 		copy(buffer[item.offset:], item.data)
 	}
+	for _, item := range j.readers {
+		item.read(buffer[item.offset : item.offset+item.length])
+	}
 	return buffer
 }
 
+// Data from readers can't be searched without reading it, so this assumes
+// that it might contain the text
 func (j *Joiner) Contains(s string, b []byte) bool {
+	if len(j.readers) > 0 {
+		return true
+	}
 	for _, item := range j.strings {
 		if strings.Contains(item.data, s) {
 			return true
@@ -20,3 +20,64 @@ func IsInsideNodeModules(path string) bool {
 		path = dir
 	}
 }
+
+// This returns the name of the package that contains the path, which is the
+// directory (or two directories for scoped packages) after the last
+// "node_modules" directory. For example, "/a/node_modules/@b/c/d.js" returns
+// "@b/c".
+func PackageNameFromPath(path string) (string, bool) {
+	path = strings.ReplaceAll(path, "\\", "/")
+	index := strings.LastIndex(path, "/node_modules/")
+	if index == -1 {
+		return "", false
+	}
+	parts := strings.SplitN(path[index+len("/node_modules/"):], "/", 3)
+	if strings.HasPrefix(parts[0], "@") {
+		if len(parts) < 3 {
+			return "", false
+		}
+		return parts[0] + "/" + parts[1], true
+	}
+	if len(parts) < 2 {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// Note that a Windows drive letter such as "C:" also looks like a URL scheme,
+// so check for absolute paths first if that matters.
+func HasURLScheme(url string) bool {
+	for i := 0; i < len(url); i++ {
+		c := url[i]
+		switch {
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case i > 0 && ((c >= '0' && c <= '9') || c == '+' || c == '-' || c == '.'):
+		case i > 0 && c == ':':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// This percent-encodes a relative path so that it can be used as a URL. Path
+// separators are kept but everything else that isn't an unreserved URL
+// character is escaped. That includes non-ASCII characters, which are escaped
+// as UTF-8 bytes, and "*", which could otherwise end a CSS comment.
+func EncodeURLPath(path string) string {
+	const hex = "0123456789ABCDEF"
+	sb := strings.Builder{}
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&15])
+		}
+	}
+	return sb.String()
+}
@@ -30,6 +30,29 @@ func ContainsNonBMPCodePointUTF16(text []uint16) bool {
 	return false
 }
 
+// This is used by the "bmp" charset, which keeps most non-ASCII characters
+// as-is but escapes the ones that legacy tools and servers tend to mangle:
+// code points outside the Basic Multilingual Plane (which need surrogate
+// pairs in UTF-16) and invisible formatting characters that are easy to
+// misread (C1 controls, soft hyphens, zero-width characters, bidirectional
+// overrides, and non-characters).
+func IsEscapedInBMPCharset(c rune) bool {
+	switch {
+	case c > 0xFFFF,
+		c >= 0x80 && c <= 0x9F,
+		c == 0xAD,
+		c >= 0x200B && c <= 0x200F,
+		c >= 0x2028 && c <= 0x202E,
+		c >= 0x2060 && c <= 0x2069,
+		c >= 0xD800 && c <= 0xDFFF,
+		c == 0xFEFF,
+		c >= 0xFFF9 && c <= 0xFFFB,
+		c == 0xFFFE, c == 0xFFFF:
+		return true
+	}
+	return false
+}
+
 func StringToUTF16(text string) []uint16 {
 	decoded := make([]uint16, 0, len(text))
 	for _, c := range text {
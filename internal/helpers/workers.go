@@ -0,0 +1,28 @@
+package helpers
+
+// This limits how many goroutines can do CPU-heavy work such as parsing and
+// printing at once. A nil limiter doesn't limit anything. Work done while
+// holding a slot must never wait for work that needs another slot, or the
+// build could deadlock.
+type WorkerLimiter struct {
+	slots chan struct{}
+}
+
+func MakeWorkerLimiter(maxWorkers int) *WorkerLimiter {
+	if maxWorkers <= 0 {
+		return nil
+	}
+	return &WorkerLimiter{slots: make(chan struct{}, maxWorkers)}
+}
+
+func (w *WorkerLimiter) Acquire() {
+	if w != nil {
+		w.slots <- struct{}{}
+	}
+}
+
+func (w *WorkerLimiter) Release() {
+	if w != nil {
+		<-w.slots
+	}
+}
@@ -0,0 +1,279 @@
+package html_parser
+
+import (
+	"html"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This is not a general-purpose HTML parser. It doesn't build a DOM and it
+// doesn't do any error recovery beyond what's needed to find tags. It only
+// scans for the tags that reference files that esbuild knows how to bundle
+// so that those references can be rewritten to point to the output files.
+// Everything else in the file is passed through unchanged.
+
+type AST struct {
+	ImportRecords []ast.ImportRecord
+	Tags          []Tag
+}
+
+type TagKind uint8
+
+const (
+	TagScript TagKind = iota
+	TagStylesheet
+)
+
+type Tag struct {
+	// This is the range of the attribute value including any quotes. It's
+	// replaced with the path to the output file.
+	ValueRange logger.Range
+
+	// This is the start of the tag. Additional tags (e.g. a "<link>" tag for the
+	// CSS generated by a JavaScript entry point) are inserted here.
+	TagLoc logger.Loc
+
+	ImportRecordIndex uint32
+	Kind              TagKind
+}
+
+type attribute struct {
+	name       string
+	value      string
+	valueRange logger.Range
+}
+
+type parser struct {
+	log     logger.Log
+	source  logger.Source
+	tracker logger.LineColumnTracker
+	ast     AST
+}
+
+func Parse(log logger.Log, source logger.Source) AST {
+	p := parser{
+		log:     log,
+		source:  source,
+		tracker: logger.MakeLineColumnTracker(&source),
+	}
+	p.parse()
+	return p.ast
+}
+
+func (p *parser) parse() {
+	contents := p.source.Contents
+	i := 0
+
+	for i < len(contents) {
+		lt := strings.IndexByte(contents[i:], '<')
+		if lt == -1 {
+			break
+		}
+		i += lt
+		rest := contents[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end == -1 {
+				p.log.AddID(logger.MsgID_HTML_UnterminatedComment, logger.Warning, &p.tracker,
+					logger.Range{Loc: logger.Loc{Start: int32(i)}, Len: 4}, "Expected \"-->\" to terminate this HTML comment")
+				return
+			}
+			i += 4 + end + 3
+
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"), strings.HasPrefix(rest, "</"):
+			// Skip over doctypes, processing instructions, and end tags
+			end := strings.IndexByte(rest, '>')
+			if end == -1 {
+				return
+			}
+			i += end + 1
+
+		case len(rest) > 1 && isTagNameStart(rest[1]):
+			i = p.parseStartTag(i)
+
+		default:
+			i++
+		}
+	}
+}
+
+// This returns the index after the end of the tag. If the tag's contents are
+// raw text (e.g. "<script>" and "<style>"), the contents are skipped too.
+func (p *parser) parseStartTag(start int) int {
+	contents := p.source.Contents
+	i := start + 1
+	nameStart := i
+	for i < len(contents) && isTagNameChar(contents[i]) {
+		i++
+	}
+	name := strings.ToLower(contents[nameStart:i])
+	var attrs []attribute
+
+	// Parse the attributes
+	for {
+		for i < len(contents) && (isWhitespace(contents[i]) || contents[i] == '/') {
+			i++
+		}
+		if i >= len(contents) {
+			return i
+		}
+		if contents[i] == '>' {
+			i++
+			break
+		}
+
+		// Parse the attribute name
+		attrStart := i
+		for i < len(contents) && !isWhitespace(contents[i]) && contents[i] != '=' && contents[i] != '>' && contents[i] != '/' {
+			i++
+		}
+		attr := attribute{name: strings.ToLower(contents[attrStart:i])}
+		for i < len(contents) && isWhitespace(contents[i]) {
+			i++
+		}
+
+		// Parse the attribute value, which is optional
+		if i < len(contents) && contents[i] == '=' {
+			i++
+			for i < len(contents) && isWhitespace(contents[i]) {
+				i++
+			}
+			valueStart := i
+			if i < len(contents) && (contents[i] == '"' || contents[i] == '\'') {
+				quote := contents[i]
+				end := strings.IndexByte(contents[i+1:], quote)
+				if end == -1 {
+					return len(contents)
+				}
+				attr.value = contents[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				for i < len(contents) && !isWhitespace(contents[i]) && contents[i] != '>' {
+					i++
+				}
+				attr.value = contents[valueStart:i]
+			}
+			attr.value = html.UnescapeString(attr.value)
+			attr.valueRange = logger.Range{Loc: logger.Loc{Start: int32(valueStart)}, Len: int32(i - valueStart)}
+		}
+		attrs = append(attrs, attr)
+	}
+
+	switch name {
+	case "script":
+		if src, ok := findAttribute(attrs, "src"); ok && isJavaScriptType(attrs) {
+			p.addTag(TagScript, start, src)
+		}
+
+	case "link":
+		if href, ok := findAttribute(attrs, "href"); ok {
+			if rel, ok := findAttribute(attrs, "rel"); ok && hasToken(rel.value, "stylesheet") {
+				p.addTag(TagStylesheet, start, href)
+			}
+		}
+	}
+
+	// Skip over the contents of raw text elements so they aren't scanned for tags
+	switch name {
+	case "script", "style", "textarea", "title":
+		closing := "</" + name
+		for j := i; j < len(contents); j++ {
+			if contents[j] == '<' && len(contents)-j >= len(closing) && strings.EqualFold(contents[j:j+len(closing)], closing) {
+				return j
+			}
+		}
+		return len(contents)
+	}
+
+	return i
+}
+
+func (p *parser) addTag(kind TagKind, tagStart int, attr attribute) {
+	path, ok := importPathFromURL(attr.value)
+	if !ok {
+		return
+	}
+	p.ast.Tags = append(p.ast.Tags, Tag{
+		Kind:              kind,
+		TagLoc:            logger.Loc{Start: int32(tagStart)},
+		ValueRange:        attr.valueRange,
+		ImportRecordIndex: uint32(len(p.ast.ImportRecords)),
+	})
+	p.ast.ImportRecords = append(p.ast.ImportRecords, ast.ImportRecord{
+		Kind:  ast.ImportEntryPoint,
+		Path:  logger.Path{Text: path},
+		Range: attr.valueRange,
+	})
+}
+
+// URLs in HTML are always relative to the HTML file (or to the root of the
+// site if they start with "/", which is assumed to be the directory containing
+// the HTML file). Absolute URLs are left alone since they aren't bundled.
+func importPathFromURL(url string) (string, bool) {
+	url = strings.TrimSpace(url)
+	if url == "" || strings.HasPrefix(url, "//") || strings.HasPrefix(url, "#") || helpers.HasURLScheme(url) {
+		return "", false
+	}
+
+	// Drop the query and hash since they don't make sense for a bundled file
+	if i := strings.IndexAny(url, "?#"); i != -1 {
+		url = url[:i]
+	}
+
+	if strings.HasPrefix(url, "/") {
+		return "." + url, true
+	}
+	if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+		return "./" + url, true
+	}
+	return url, true
+}
+
+// Only scripts that the browser would run as JavaScript are bundled. Other
+// types such as "text/template" or "importmap" are used for data.
+func isJavaScriptType(attrs []attribute) bool {
+	attr, ok := findAttribute(attrs, "type")
+	if !ok {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(attr.value)) {
+	case "", "module", "text/javascript", "application/javascript":
+		return true
+	}
+	return false
+}
+
+func findAttribute(attrs []attribute, name string) (attribute, bool) {
+	for _, attr := range attrs {
+		if attr.name == name {
+			return attr, true
+		}
+	}
+	return attribute{}, false
+}
+
+func hasToken(value string, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTagNameStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isTagNameChar(c byte) bool {
+	return isTagNameStart(c) || (c >= '0' && c <= '9') || c == '-' || c == ':'
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
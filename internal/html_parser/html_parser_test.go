@@ -0,0 +1,95 @@
+package html_parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func expectTags(t *testing.T, contents string, expected string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		tree := Parse(log, test.SourceForTest(contents))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		for _, tag := range tree.Tags {
+			kind := "script"
+			if tag.Kind == TagStylesheet {
+				kind = "stylesheet"
+			}
+			record := tree.ImportRecords[tag.ImportRecordIndex]
+			value := contents[tag.ValueRange.Loc.Start:tag.ValueRange.End()]
+			text += fmt.Sprintf("%s %s %s\n", kind, record.Path.Text, value)
+		}
+		test.AssertEqualWithDiff(t, text, expected)
+	})
+}
+
+func TestScript(t *testing.T) {
+	expectTags(t, `<script src="a.js"></script>`, "script ./a.js \"a.js\"\n")
+	expectTags(t, `<script src='a.js'></script>`, "script ./a.js 'a.js'\n")
+	expectTags(t, `<script src=a.js></script>`, "script ./a.js a.js\n")
+	expectTags(t, `<SCRIPT SRC = "a.js" defer></SCRIPT>`, "script ./a.js \"a.js\"\n")
+	expectTags(t, `<script defer src="a.js"/>`, "script ./a.js \"a.js\"\n")
+	expectTags(t, `<script src="./a.js"></script>`, "script ./a.js \"./a.js\"\n")
+	expectTags(t, `<script src="../a.js"></script>`, "script ../a.js \"../a.js\"\n")
+	expectTags(t, `<script src="/a.js"></script>`, "script ./a.js \"/a.js\"\n")
+	expectTags(t, `<script src="a.js?v=1#x"></script>`, "script ./a.js \"a.js?v=1#x\"\n")
+	expectTags(t, `<script src="a&amp;b.js"></script>`, "script ./a&b.js \"a&amp;b.js\"\n")
+	expectTags(t, `<script type="module" src="a.js"></script>`, "script ./a.js \"a.js\"\n")
+	expectTags(t, `<script type="text/javascript" src="a.js"></script>`, "script ./a.js \"a.js\"\n")
+
+	// These are not bundled
+	expectTags(t, `<script></script>`, "")
+	expectTags(t, `<script src></script>`, "")
+	expectTags(t, `<script src=""></script>`, "")
+	expectTags(t, `<script src="https://example.com/a.js"></script>`, "")
+	expectTags(t, `<script src="//example.com/a.js"></script>`, "")
+	expectTags(t, `<script src="data:text/javascript,"></script>`, "")
+	expectTags(t, `<script type="text/template" src="a.js"></script>`, "")
+	expectTags(t, `<script type="importmap" src="a.json"></script>`, "")
+}
+
+func TestStylesheet(t *testing.T) {
+	expectTags(t, `<link rel="stylesheet" href="a.css">`, "stylesheet ./a.css \"a.css\"\n")
+	expectTags(t, `<link href="a.css" rel="stylesheet">`, "stylesheet ./a.css \"a.css\"\n")
+	expectTags(t, `<link rel="preload stylesheet" href="a.css">`, "stylesheet ./a.css \"a.css\"\n")
+	expectTags(t, `<LINK REL=STYLESHEET HREF=a.css>`, "stylesheet ./a.css a.css\n")
+
+	// These are not bundled
+	expectTags(t, `<link rel="icon" href="a.ico">`, "")
+	expectTags(t, `<link rel="stylesheet">`, "")
+	expectTags(t, `<link rel="stylesheet" href="https://example.com/a.css">`, "")
+}
+
+func TestRawText(t *testing.T) {
+	expectTags(t, `<!-- <script src="a.js"></script> --><script src="b.js"></script>`, "script ./b.js \"b.js\"\n")
+	expectTags(t, `<script>"<script src='a.js'></script>"</script><script src="b.js"></script>`,
+		"script ./b.js \"b.js\"\n")
+	expectTags(t, `<style><link rel="stylesheet" href="a.css"></STYLE><link rel="stylesheet" href="b.css">`,
+		"stylesheet ./b.css \"b.css\"\n")
+	expectTags(t, `<textarea><script src="a.js"></script></textarea>`, "")
+	expectTags(t, `<!DOCTYPE html><script src="a.js"></script>`, "script ./a.js \"a.js\"\n")
+	expectTags(t, `<div title="<script src='a.js'>"></div>`, "")
+
+	expectTags(t, `<!-- <script src="a.js"></script>`,
+		"<stdin>: WARNING: Expected \"-->\" to terminate this HTML comment\n")
+}
+
+func TestTagLoc(t *testing.T) {
+	contents := "<body>\n  <script src=\"a.js\"></script>\n</body>"
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	tree := Parse(log, test.SourceForTest(contents))
+	if len(tree.Tags) != 1 {
+		t.Fatalf("Expected one tag, got %d", len(tree.Tags))
+	}
+	test.AssertEqual(t, int(tree.Tags[0].TagLoc.Start), strings.Index(contents, "<script"))
+}
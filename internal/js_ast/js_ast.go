@@ -249,8 +249,9 @@ type LocRef struct {
 }
 
 type Comment struct {
-	Text string
-	Loc  logger.Loc
+	Text           string
+	Loc            logger.Loc
+	IsLegalComment bool
 }
 
 type PropertyKind uint8
@@ -304,11 +305,22 @@ type Property struct {
 
 	TSDecorators []Expr
 
+	// This is only used for TypeScript's "emitDecoratorMetadata" setting
+	TSDecoratorMetadata []TSDecoratorMetadata
+
 	Loc   logger.Loc
 	Kind  PropertyKind
 	Flags PropertyFlags
 }
 
+// Each of these becomes an additional call to "Reflect.metadata()" after the
+// decorators for a class member. The metadata for a class constructor is
+// added to the decorators for the class instead.
+type TSDecoratorMetadata struct {
+	Key   string // "design:type", "design:paramtypes", or "design:returntype"
+	Value Expr
+}
+
 type PropertyBinding struct {
 	Key               Expr
 	Value             Binding
@@ -437,6 +449,7 @@ func (*EYield) isExpr()                {}
 func (*EIf) isExpr()                   {}
 func (*ERequireString) isExpr()        {}
 func (*ERequireResolveString) isExpr() {}
+func (*ENewURLString) isExpr()         {}
 func (*EImportString) isExpr()         {}
 func (*EImportCall) isExpr()           {}
 
@@ -728,6 +741,13 @@ type ERequireResolveString struct {
 	ImportRecordIndex uint32
 }
 
+// This is the string argument of "new URL(path, import.meta.url)" when the
+// path refers to a file in the bundle. It's printed as the path of the output
+// file for that file, relative to the output file containing this expression.
+type ENewURLString struct {
+	ImportRecordIndex uint32
+}
+
 type EImportString struct {
 	// Comments inside "import()" expressions have special meaning for Webpack.
 	// Preserving comments inside these expressions makes it possible to use
@@ -966,6 +986,7 @@ type STry struct {
 type Case struct {
 	ValueOrNil Expr // If this is nil, this is "default" instead of "case"
 	Body       []Stmt
+	Loc        logger.Loc
 }
 
 type SSwitch struct {
@@ -1317,6 +1338,12 @@ const (
 	// This means the symbol is a normal function that takes a single argument
 	// and returns that argument.
 	IsIdentityFunction
+
+	// Generated runtime symbols (e.g. "__export" and "require_foo") are given
+	// a user-specified prefix so that independently-built bundles loaded in
+	// the same global scope can't collide with each other. The minifier must
+	// also preserve the prefix when it renames these symbols.
+	MustStartWithRuntimePrefix
 )
 
 func (flags SymbolFlags) Has(flag SymbolFlags) bool {
@@ -1817,6 +1844,14 @@ type AST struct {
 	NestedScopeSlotCounts SlotCounts
 	HasLazyExport         bool
 
+	// The set of files matching an "import.meta.glob" pattern can change even
+	// if the contents of this file don't, so this file can't be cached
+	UsesImportMetaGlob bool
+
+	// These runtime APIs are used by this file but aren't available in the
+	// target environment. They can't be lowered so they need a polyfill.
+	UsedRuntimeFeatures compat.RuntimeFeature
+
 	// This is a list of CommonJS features. When a file uses CommonJS features,
 	// it's not a candidate for "flat bundling" and must be wrapped in its own
 	// closure. Note that this also includes top-level "return" but these aren't
@@ -1986,6 +2021,40 @@ func (freq *CharFreq) Compile() NameMinifier {
 	return minifier
 }
 
+// This returns a name minifier with a character order that only depends on
+// the seed. Unlike "Compile()", the resulting names don't change when the
+// character frequencies of the input code change, so two builds of slightly
+// different code are more likely to use the same minified names.
+func SeededNameMinifier(seed string) NameMinifier {
+	// Hash the seed with FNV-1a
+	state := uint64(14695981039346656037)
+	for i := 0; i < len(seed); i++ {
+		state ^= uint64(seed[i])
+		state *= 1099511628211
+	}
+
+	// Shuffle the characters with the "splitmix64" generator
+	tail := []byte(DefaultNameMinifier.tail)
+	for i := len(tail) - 1; i > 0; i-- {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		z ^= z >> 31
+		j := int(z % uint64(i+1))
+		tail[i], tail[j] = tail[j], tail[i]
+	}
+
+	// Compute the identifier start and identifier continue sequences
+	minifier := NameMinifier{tail: string(tail)}
+	for _, c := range tail {
+		if c < '0' || c > '9' {
+			minifier.head += string(c)
+		}
+	}
+	return minifier
+}
+
 func (minifier *NameMinifier) NumberToMinifiedName(i int) string {
 	j := i % 54
 	name := minifier.head[j : j+1]
@@ -0,0 +1,1873 @@
+package js_estree
+
+// This converts a JavaScript syntax tree into JSON that follows the ESTree
+// specification (https://github.com/estree/estree) as closely as possible.
+// It's meant for tools written in Go that want to reuse esbuild's parser.
+//
+// The syntax tree comes from the parser's first pass, so it's what was
+// written in the source code. Nothing has been folded or lowered. There are
+// some differences from ESTree since esbuild's AST doesn't store everything
+// that ESTree describes:
+//
+//   - TypeScript types have already been removed by the parser
+//   - Optional chains use "optional" without a "ChainExpression" wrapper
+//   - Template literals only have "cooked" values unless they are tagged
+//   - Assignment targets are expressions instead of patterns
+//
+// Nodes have "start" and "end" byte offsets. Identifiers that refer to a
+// symbol have a "symbol" property, which is an index into the "symbols" array
+// on the "Program" node. The "scope" property on the "Program" node is the
+// tree of scopes and the symbols they declare.
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type printer struct {
+	tree    *js_parser.SyntaxTree
+	source  *logger.Source
+	symbols js_ast.SymbolMap
+	globals map[string]js_ast.Ref
+	labels  []label
+	imports []importPath
+	js      []byte
+}
+
+type label struct {
+	name string
+	ref  js_ast.Ref
+}
+
+type importPath struct {
+	path string
+	kind ast.ImportKind
+	loc  logger.Loc
+}
+
+func Print(tree *js_parser.SyntaxTree, source *logger.Source) []byte {
+	p := &printer{
+		tree:    tree,
+		source:  source,
+		symbols: js_ast.NewSymbolMap(int(source.Index) + 1),
+		globals: make(map[string]js_ast.Ref),
+	}
+
+	// Symbols for globals and labels are added while printing, so copy the
+	// symbols to avoid modifying the syntax tree
+	p.symbols.SymbolsForSource[source.Index] = append([]js_ast.Symbol{}, tree.Symbols...)
+	for _, record := range tree.ImportRecords {
+		p.imports = append(p.imports, importPath{path: record.Path.Text, kind: record.Kind, loc: record.Range.Loc})
+	}
+
+	p.open("Program", logger.Loc{}, int32(len(source.Contents)))
+	p.field("sourceType")
+	if tree.IsESM {
+		p.string("module")
+	} else {
+		p.string("script")
+	}
+	if tree.Hashbang != "" {
+		p.field("hashbang")
+		p.string(tree.Hashbang)
+	}
+	p.field("body")
+	p.printStmts(tree.Stmts)
+	p.field("imports")
+	p.printImports()
+	p.field("symbols")
+	p.printSymbols()
+	p.field("scope")
+	p.printScope(tree.ModuleScope)
+	p.close()
+	return p.js
+}
+
+// TypeScript declarations and comments don't have an ESTree equivalent
+func isPrintable(stmt js_ast.Stmt) bool {
+	switch stmt.Data.(type) {
+	case *js_ast.STypeScript, *js_ast.SComment:
+		return false
+	}
+	return true
+}
+
+func (p *printer) print(text string) {
+	p.js = append(p.js, text...)
+}
+
+func (p *printer) string(text string) {
+	p.js = append(p.js, js_printer.QuoteForJSON(text, false)...)
+}
+
+func (p *printer) int(value int) {
+	p.js = strconv.AppendInt(p.js, int64(value), 10)
+}
+
+func (p *printer) bool(value bool) {
+	p.js = strconv.AppendBool(p.js, value)
+}
+
+func (p *printer) null() {
+	p.print("null")
+}
+
+func (p *printer) open(kind string, loc logger.Loc, end int32) {
+	p.print("{\"type\":")
+	p.string(kind)
+	p.print(",\"start\":")
+	p.int(int(loc.Start))
+	p.print(",\"end\":")
+	p.int(int(end))
+}
+
+func (p *printer) field(name string) {
+	p.print(",\"")
+	p.print(name)
+	p.print("\":")
+}
+
+func (p *printer) close() {
+	p.print("}")
+}
+
+// The parser only records where nodes end if they can be more than one token
+func (p *printer) tokenEnd(loc logger.Loc) int32 {
+	return js_lexer.RangeOfTokenAt(*p.source, loc).End()
+}
+
+func (p *printer) exprEnd(expr js_ast.Expr) int32 {
+	if end, ok := p.tree.NodeEnds[expr.Data]; ok {
+		return end
+	}
+	switch e := expr.Data.(type) {
+	case *js_ast.EArray:
+		return e.CloseBracketLoc.Start + 1
+	case *js_ast.EObject:
+		return e.CloseBraceLoc.Start + 1
+	case *js_ast.ESpread:
+		return p.exprEnd(e.Value)
+	case *js_ast.EFunction:
+		return blockEnd(e.Fn.Body.Block)
+	case *js_ast.EClass:
+		return e.Class.CloseBraceLoc.Start + 1
+	case *js_ast.EJSXElement:
+		return p.jsxElementEnd(e)
+	case *js_ast.ERegExp:
+		return expr.Loc.Start + int32(len(e.Value))
+	case *js_ast.ENewTarget:
+		return e.Range.End()
+	case *js_ast.EImportMeta:
+		return expr.Loc.Start + e.RangeLen
+	}
+	return p.tokenEnd(expr.Loc)
+}
+
+func (p *printer) stmtEnd(stmt js_ast.Stmt) int32 {
+	if end, ok := p.tree.NodeEnds[stmt.Data]; ok {
+		return end
+	}
+	switch s := stmt.Data.(type) {
+	case *js_ast.SExpr:
+		return p.exprEnd(s.Value)
+	case *js_ast.SFunction:
+		return blockEnd(s.Fn.Body.Block)
+	case *js_ast.SClass:
+		return s.Class.CloseBraceLoc.Start + 1
+	case *js_ast.SLocal:
+		if len(s.Decls) > 0 {
+			return p.declEnd(s.Decls[len(s.Decls)-1])
+		}
+	case *js_ast.SDebugger:
+		// Include the ";" after "debugger" if there is one
+		end := p.tokenEnd(stmt.Loc)
+		if r := js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: end}); r.Len == 1 && p.source.Contents[r.Loc.Start] == ';' {
+			return r.End()
+		}
+		return end
+	}
+	return p.tokenEnd(stmt.Loc)
+}
+
+func (p *printer) bindingEnd(binding js_ast.Binding) int32 {
+	if end, ok := p.tree.NodeEnds[binding.Data]; ok {
+		return end
+	}
+	return p.tokenEnd(binding.Loc)
+}
+
+func (p *printer) declEnd(decl js_ast.Decl) int32 {
+	if decl.ValueOrNil.Data != nil {
+		return p.exprEnd(decl.ValueOrNil)
+	}
+	return p.bindingEnd(decl.Binding)
+}
+
+func blockEnd(block js_ast.SBlock) int32 {
+	return block.CloseBraceLoc.Start + 1
+}
+
+// Rest elements start at the "..." before the binding
+func (p *printer) restLoc(loc logger.Loc) logger.Loc {
+	return p.source.RangeOfOperatorBefore(loc, "...").Loc
+}
+
+// The visit pass is what normally binds identifiers to symbols, so that's
+// done here instead. Each name is looked up starting from the scope that it
+// was parsed in, and names that aren't declared anywhere are globals.
+func (p *printer) bind(ref js_ast.Ref) js_ast.Ref {
+	name, ok := p.tree.NamesInScope[ref]
+	if !ok {
+		return ref
+	}
+	for scope := name.Scope; scope != nil; scope = scope.Parent {
+		if member, ok := scope.Members[name.Name]; ok {
+			return member.Ref
+		}
+	}
+	ref, ok = p.globals[name.Name]
+	if !ok {
+		ref = p.newSymbol(js_ast.SymbolUnbound, name.Name)
+		p.globals[name.Name] = ref
+	}
+	return ref
+}
+
+func (p *printer) newSymbol(kind js_ast.SymbolKind, name string) js_ast.Ref {
+	symbols := &p.symbols.SymbolsForSource[p.source.Index]
+	ref := js_ast.Ref{SourceIndex: p.source.Index, InnerIndex: uint32(len(*symbols))}
+	*symbols = append(*symbols, js_ast.Symbol{Kind: kind, OriginalName: name, Link: js_ast.InvalidRef})
+	return ref
+}
+
+func (p *printer) symbolIndex(ref js_ast.Ref) int {
+	return int(js_ast.FollowSymbols(p.symbols, ref).InnerIndex)
+}
+
+func (p *printer) printIdentifier(loc logger.Loc, ref js_ast.Ref) {
+	ref = p.bind(ref)
+	symbol := p.symbols.Get(js_ast.FollowSymbols(p.symbols, ref))
+	p.open("Identifier", loc, p.tokenEnd(loc))
+	p.field("name")
+	p.string(symbol.OriginalName)
+	p.field("symbol")
+	p.int(p.symbolIndex(ref))
+	p.close()
+}
+
+func (p *printer) printName(loc logger.Loc, end int32, name string) {
+	p.open("Identifier", loc, end)
+	p.field("name")
+	p.string(name)
+	p.close()
+}
+
+func (p *printer) printLocRefOrNull(name *js_ast.LocRef) {
+	if name == nil {
+		p.null()
+	} else {
+		p.printIdentifier(name.Loc, name.Ref)
+	}
+}
+
+// Labels have their own namespace, and a label is only visible inside of the
+// statement that it labels
+func (p *printer) printLabelOrNull(name *js_ast.LocRef) {
+	if name == nil {
+		p.null()
+		return
+	}
+	text := p.tree.NamesInScope[name.Ref].Name
+	for i := len(p.labels) - 1; i >= 0; i-- {
+		if p.labels[i].name == text {
+			p.open("Identifier", name.Loc, p.tokenEnd(name.Loc))
+			p.field("name")
+			p.string(text)
+			p.field("symbol")
+			p.int(p.symbolIndex(p.labels[i].ref))
+			p.close()
+			return
+		}
+	}
+	p.printName(name.Loc, p.tokenEnd(name.Loc), text)
+}
+
+func (p *printer) printStringLiteral(loc logger.Loc, end int32, value string) {
+	p.open("Literal", loc, end)
+	p.field("value")
+	p.string(value)
+	p.close()
+}
+
+func (p *printer) printImportPath(importRecordIndex uint32) {
+	record := &p.tree.ImportRecords[importRecordIndex]
+	p.printStringLiteral(record.Range.Loc, record.Range.End(), record.Path.Text)
+}
+
+func (p *printer) printImports() {
+	sort.SliceStable(p.imports, func(i int, j int) bool {
+		return p.imports[i].loc.Start < p.imports[j].loc.Start
+	})
+	p.print("[")
+	for i, record := range p.imports {
+		if i > 0 {
+			p.print(",")
+		}
+		p.print("{\"path\":")
+		p.string(record.path)
+		p.print(",\"kind\":")
+		p.string(record.kind.StringForMetafile())
+		p.print(",\"start\":")
+		p.int(int(record.loc.Start))
+		p.print("}")
+	}
+	p.print("]")
+}
+
+var symbolKindNames = map[js_ast.SymbolKind]string{
+	js_ast.SymbolUnbound:                  "unbound",
+	js_ast.SymbolHoisted:                  "hoisted",
+	js_ast.SymbolHoistedFunction:          "hoisted-function",
+	js_ast.SymbolCatchIdentifier:          "catch-identifier",
+	js_ast.SymbolGeneratorOrAsyncFunction: "generator-or-async-function",
+	js_ast.SymbolArguments:                "arguments",
+	js_ast.SymbolClass:                    "class",
+	js_ast.SymbolPrivateField:             "private-field",
+	js_ast.SymbolPrivateMethod:            "private-method",
+	js_ast.SymbolPrivateGet:               "private-get",
+	js_ast.SymbolPrivateSet:               "private-set",
+	js_ast.SymbolPrivateGetSetPair:        "private-get-set-pair",
+	js_ast.SymbolPrivateStaticField:       "private-static-field",
+	js_ast.SymbolPrivateStaticMethod:      "private-static-method",
+	js_ast.SymbolPrivateStaticGet:         "private-static-get",
+	js_ast.SymbolPrivateStaticSet:         "private-static-set",
+	js_ast.SymbolPrivateStaticGetSetPair:  "private-static-get-set-pair",
+	js_ast.SymbolLabel:                    "label",
+	js_ast.SymbolTSEnum:                   "ts-enum",
+	js_ast.SymbolTSNamespace:              "ts-namespace",
+	js_ast.SymbolImport:                   "import",
+	js_ast.SymbolConst:                    "const",
+	js_ast.SymbolInjected:                 "injected",
+	js_ast.SymbolMangledProp:              "mangled-prop",
+	js_ast.SymbolOther:                    "other",
+}
+
+func (p *printer) printSymbols() {
+	p.print("[")
+	for i, symbol := range p.symbols.SymbolsForSource[p.source.Index] {
+		if i > 0 {
+			p.print(",")
+		}
+		p.print("{\"name\":")
+		p.string(symbol.OriginalName)
+		p.print(",\"kind\":")
+		p.string(symbolKindNames[symbol.Kind])
+		if symbol.Link != js_ast.InvalidRef {
+			// Merged symbols point to the symbol that they were merged into
+			p.print(",\"mergedInto\":")
+			p.int(p.symbolIndex(symbol.Link))
+		}
+		p.print("}")
+	}
+	p.print("]")
+}
+
+var scopeKindNames = map[js_ast.ScopeKind]string{
+	js_ast.ScopeBlock:           "block",
+	js_ast.ScopeWith:            "with",
+	js_ast.ScopeLabel:           "label",
+	js_ast.ScopeClassName:       "class-name",
+	js_ast.ScopeClassBody:       "class-body",
+	js_ast.ScopeCatchBinding:    "catch-binding",
+	js_ast.ScopeEntry:           "module",
+	js_ast.ScopeFunctionArgs:    "function-args",
+	js_ast.ScopeFunctionBody:    "function-body",
+	js_ast.ScopeClassStaticInit: "class-static-init",
+}
+
+func (p *printer) printScope(scope *js_ast.Scope) {
+	p.print("{\"kind\":")
+	if scope.TSNamespace != nil {
+		p.string("ts-namespace")
+	} else {
+		p.string(scopeKindNames[scope.Kind])
+	}
+
+	// Globals are members of the module scope
+	members := make(map[string]js_ast.Ref, len(scope.Members))
+	for name, member := range scope.Members {
+		members[name] = member.Ref
+	}
+	if scope == p.tree.ModuleScope {
+		for name, ref := range p.globals {
+			members[name] = ref
+		}
+	}
+
+	// Sort the members by name so the output is deterministic
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	p.print(",\"members\":{")
+	for i, name := range names {
+		if i > 0 {
+			p.print(",")
+		}
+		p.string(name)
+		p.print(":")
+		p.int(p.symbolIndex(members[name]))
+	}
+	p.print("}")
+
+	p.print(",\"children\":[")
+	for i, child := range scope.Children {
+		if i > 0 {
+			p.print(",")
+		}
+		p.printScope(child)
+	}
+	p.print("]}")
+}
+
+func (p *printer) printStmts(stmts []js_ast.Stmt) {
+	p.print("[")
+	isFirst := true
+	for _, stmt := range stmts {
+		if !isPrintable(stmt) {
+			continue
+		}
+		if !isFirst {
+			p.print(",")
+		}
+		p.printStmt(stmt)
+		isFirst = false
+	}
+	p.print("]")
+}
+
+func (p *printer) printBlock(loc logger.Loc, block js_ast.SBlock) {
+	p.open("BlockStatement", loc, blockEnd(block))
+	p.field("body")
+	p.printStmts(block.Stmts)
+	p.close()
+}
+
+func (p *printer) printStmtOrNull(stmt js_ast.Stmt) {
+	if stmt.Data == nil {
+		p.null()
+	} else {
+		p.printStmt(stmt)
+	}
+}
+
+// Declarations with "export" in front of them are wrapped in an export node.
+// The declaration itself starts either at the "export" keyword or after it,
+// so this returns where the declaration starts after the "export" keyword.
+func (p *printer) openExport(loc logger.Loc, end int32, isExport bool) logger.Loc {
+	if !isExport {
+		return loc
+	}
+	exportLoc := loc
+	if strings.HasPrefix(p.source.Contents[loc.Start:], "export") {
+		loc = js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: loc.Start + int32(len("export"))}).Loc
+	} else {
+		exportLoc = p.source.RangeOfOperatorBefore(loc, "export").Loc
+	}
+	p.open("ExportNamedDeclaration", exportLoc, end)
+	p.field("declaration")
+	return loc
+}
+
+func (p *printer) closeExport(isExport bool) {
+	if isExport {
+		p.print(",\"specifiers\":[],\"source\":null}")
+	}
+}
+
+func (p *printer) printStmt(stmt js_ast.Stmt) {
+	end := p.stmtEnd(stmt)
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SBlock:
+		p.printBlock(stmt.Loc, *s)
+
+	case *js_ast.SEmpty, *js_ast.STypeScript, *js_ast.SComment:
+		p.open("EmptyStatement", stmt.Loc, end)
+		p.close()
+
+	case *js_ast.SDebugger:
+		p.open("DebuggerStatement", stmt.Loc, end)
+		p.close()
+
+	case *js_ast.SDirective:
+		value := helpers.UTF16ToString(s.Value)
+		p.open("ExpressionStatement", stmt.Loc, end)
+		p.field("expression")
+		p.printStringLiteral(stmt.Loc, p.tokenEnd(stmt.Loc), value)
+		p.field("directive")
+		p.string(value)
+		p.close()
+
+	case *js_ast.SExportClause:
+		p.open("ExportNamedDeclaration", stmt.Loc, end)
+		p.field("declaration")
+		p.null()
+		p.field("specifiers")
+		p.print("[")
+		for i, item := range s.Items {
+			if i > 0 {
+				p.print(",")
+			}
+			aliasEnd := p.tokenEnd(item.AliasLoc)
+			p.open("ExportSpecifier", item.Name.Loc, aliasEnd)
+			p.field("local")
+			p.printIdentifier(item.Name.Loc, item.Name.Ref)
+			p.field("exported")
+			p.printName(item.AliasLoc, aliasEnd, item.Alias)
+			p.close()
+		}
+		p.print("]")
+		p.field("source")
+		p.null()
+		p.close()
+
+	case *js_ast.SExportFrom:
+		p.open("ExportNamedDeclaration", stmt.Loc, end)
+		p.field("declaration")
+		p.null()
+		p.field("specifiers")
+		p.print("[")
+		for i, item := range s.Items {
+			if i > 0 {
+				p.print(",")
+			}
+			aliasEnd := p.tokenEnd(item.AliasLoc)
+			p.open("ExportSpecifier", item.Name.Loc, aliasEnd)
+			p.field("local")
+			p.printName(item.Name.Loc, p.tokenEnd(item.Name.Loc), item.OriginalName)
+			p.field("exported")
+			p.printName(item.AliasLoc, aliasEnd, item.Alias)
+			p.close()
+		}
+		p.print("]")
+		p.field("source")
+		p.printImportPath(s.ImportRecordIndex)
+		p.close()
+
+	case *js_ast.SExportDefault:
+		p.open("ExportDefaultDeclaration", stmt.Loc, end)
+		p.field("declaration")
+		switch s2 := s.Value.Data.(type) {
+		case *js_ast.SExpr:
+			p.printExpr(s2.Value)
+		case *js_ast.SFunction:
+			p.printFn(s.Value.Loc, p.stmtEnd(s.Value), "FunctionDeclaration", &s2.Fn)
+		case *js_ast.SClass:
+			p.printClass(s.Value.Loc, p.stmtEnd(s.Value), "ClassDeclaration", &s2.Class)
+		default:
+			p.null()
+		}
+		p.close()
+
+	case *js_ast.SExportStar:
+		p.open("ExportAllDeclaration", stmt.Loc, end)
+		p.field("exported")
+		if s.Alias != nil {
+			p.printName(s.Alias.Loc, p.tokenEnd(s.Alias.Loc), s.Alias.OriginalName)
+		} else {
+			p.null()
+		}
+		p.field("source")
+		p.printImportPath(s.ImportRecordIndex)
+		p.close()
+
+	case *js_ast.SExportEquals:
+		p.open("TSExportAssignment", stmt.Loc, end)
+		p.field("expression")
+		p.printExpr(s.Value)
+		p.close()
+
+	case *js_ast.SExpr:
+		p.open("ExpressionStatement", stmt.Loc, end)
+		p.field("expression")
+		p.printExpr(s.Value)
+		p.close()
+
+	case *js_ast.SEnum:
+		loc := p.openExport(stmt.Loc, end, s.IsExport)
+		p.open("TSEnumDeclaration", loc, end)
+		p.field("id")
+		p.printIdentifier(s.Name.Loc, s.Name.Ref)
+		p.field("members")
+		p.print("[")
+		for i, value := range s.Values {
+			if i > 0 {
+				p.print(",")
+			}
+			nameEnd := p.tokenEnd(value.Loc)
+			if value.ValueOrNil.Data != nil {
+				p.open("TSEnumMember", value.Loc, p.exprEnd(value.ValueOrNil))
+			} else {
+				p.open("TSEnumMember", value.Loc, nameEnd)
+			}
+			p.field("id")
+			if _, ok := js_lexer.Keywords[p.source.Contents[value.Loc.Start:nameEnd]]; ok || js_lexer.IsIdentifierUTF16(value.Name) {
+				p.printName(value.Loc, nameEnd, helpers.UTF16ToString(value.Name))
+			} else {
+				p.printStringLiteral(value.Loc, nameEnd, helpers.UTF16ToString(value.Name))
+			}
+			p.field("initializer")
+			p.printExprOrNull(value.ValueOrNil)
+			p.close()
+		}
+		p.print("]")
+		p.close()
+		p.closeExport(s.IsExport)
+
+	case *js_ast.SNamespace:
+		loc := p.openExport(stmt.Loc, end, s.IsExport)
+		p.printNamespace(loc, end, s)
+		p.closeExport(s.IsExport)
+
+	case *js_ast.SFunction:
+		loc := p.openExport(stmt.Loc, end, s.IsExport)
+		p.printFn(loc, end, "FunctionDeclaration", &s.Fn)
+		p.closeExport(s.IsExport)
+
+	case *js_ast.SClass:
+		loc := p.openExport(stmt.Loc, end, s.IsExport)
+		p.printClass(loc, end, "ClassDeclaration", &s.Class)
+		p.closeExport(s.IsExport)
+
+	case *js_ast.SLabel:
+		name := p.tree.NamesInScope[s.Name.Ref].Name
+		ref := p.newSymbol(js_ast.SymbolLabel, name)
+		p.open("LabeledStatement", stmt.Loc, end)
+		p.field("label")
+		p.open("Identifier", s.Name.Loc, p.tokenEnd(s.Name.Loc))
+		p.field("name")
+		p.string(name)
+		p.field("symbol")
+		p.int(p.symbolIndex(ref))
+		p.close()
+		p.field("body")
+		p.labels = append(p.labels, label{name: name, ref: ref})
+		p.printStmt(s.Stmt)
+		p.labels = p.labels[:len(p.labels)-1]
+		p.close()
+
+	case *js_ast.SIf:
+		p.open("IfStatement", stmt.Loc, end)
+		p.field("test")
+		p.printExpr(s.Test)
+		p.field("consequent")
+		p.printStmt(s.Yes)
+		p.field("alternate")
+		p.printStmtOrNull(s.NoOrNil)
+		p.close()
+
+	case *js_ast.SFor:
+		p.open("ForStatement", stmt.Loc, end)
+		p.field("init")
+		p.printForInit(s.InitOrNil)
+		p.field("test")
+		p.printExprOrNull(s.TestOrNil)
+		p.field("update")
+		p.printExprOrNull(s.UpdateOrNil)
+		p.field("body")
+		p.printStmt(s.Body)
+		p.close()
+
+	case *js_ast.SForIn:
+		p.open("ForInStatement", stmt.Loc, end)
+		p.field("left")
+		p.printForInit(s.Init)
+		p.field("right")
+		p.printExpr(s.Value)
+		p.field("body")
+		p.printStmt(s.Body)
+		p.close()
+
+	case *js_ast.SForOf:
+		p.open("ForOfStatement", stmt.Loc, end)
+		p.field("await")
+		p.bool(s.IsAwait)
+		p.field("left")
+		p.printForInit(s.Init)
+		p.field("right")
+		p.printExpr(s.Value)
+		p.field("body")
+		p.printStmt(s.Body)
+		p.close()
+
+	case *js_ast.SDoWhile:
+		p.open("DoWhileStatement", stmt.Loc, end)
+		p.field("body")
+		p.printStmt(s.Body)
+		p.field("test")
+		p.printExpr(s.Test)
+		p.close()
+
+	case *js_ast.SWhile:
+		p.open("WhileStatement", stmt.Loc, end)
+		p.field("test")
+		p.printExpr(s.Test)
+		p.field("body")
+		p.printStmt(s.Body)
+		p.close()
+
+	case *js_ast.SWith:
+		p.open("WithStatement", stmt.Loc, end)
+		p.field("object")
+		p.printExpr(s.Value)
+		p.field("body")
+		p.printStmt(s.Body)
+		p.close()
+
+	case *js_ast.STry:
+		p.open("TryStatement", stmt.Loc, end)
+		p.field("block")
+		p.printBlock(s.BlockLoc, s.Block)
+		p.field("handler")
+		if s.Catch != nil {
+			p.open("CatchClause", s.Catch.Loc, blockEnd(s.Catch.Block))
+			p.field("param")
+			p.printBindingOrNull(s.Catch.BindingOrNil)
+			p.field("body")
+			p.printBlock(s.Catch.BlockLoc, s.Catch.Block)
+			p.close()
+		} else {
+			p.null()
+		}
+		p.field("finalizer")
+		if s.Finally != nil {
+			// The location of "finally" is the keyword, not the block after it
+			blockLoc := js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: p.tokenEnd(s.Finally.Loc)}).Loc
+			p.printBlock(blockLoc, s.Finally.Block)
+		} else {
+			p.null()
+		}
+		p.close()
+
+	case *js_ast.SSwitch:
+		p.open("SwitchStatement", stmt.Loc, end)
+		p.field("discriminant")
+		p.printExpr(s.Test)
+		p.field("cases")
+		p.print("[")
+		for i, c := range s.Cases {
+			if i > 0 {
+				p.print(",")
+			}
+
+			// A case without a body ends at the ":" after "case x" or "default"
+			var caseEnd int32
+			if len(c.Body) > 0 {
+				caseEnd = p.stmtEnd(c.Body[len(c.Body)-1])
+			} else if c.ValueOrNil.Data != nil {
+				caseEnd = p.tokenEnd(logger.Loc{Start: p.exprEnd(c.ValueOrNil)})
+			} else {
+				caseEnd = p.tokenEnd(logger.Loc{Start: p.tokenEnd(c.Loc)})
+			}
+
+			p.open("SwitchCase", c.Loc, caseEnd)
+			p.field("test")
+			p.printExprOrNull(c.ValueOrNil)
+			p.field("consequent")
+			p.printStmts(c.Body)
+			p.close()
+		}
+		p.print("]")
+		p.close()
+
+	case *js_ast.SImport:
+		p.open("ImportDeclaration", stmt.Loc, end)
+		p.field("specifiers")
+		p.print("[")
+		isFirst := true
+		if s.DefaultName != nil {
+			p.open("ImportDefaultSpecifier", s.DefaultName.Loc, p.tokenEnd(s.DefaultName.Loc))
+			p.field("local")
+			p.printIdentifier(s.DefaultName.Loc, s.DefaultName.Ref)
+			p.close()
+			isFirst = false
+		}
+		if s.StarNameLoc != nil {
+			if !isFirst {
+				p.print(",")
+			}
+			p.open("ImportNamespaceSpecifier", *s.StarNameLoc, p.tokenEnd(*s.StarNameLoc))
+			p.field("local")
+			p.printIdentifier(*s.StarNameLoc, s.NamespaceRef)
+			p.close()
+			isFirst = false
+		}
+		if s.Items != nil {
+			for _, item := range *s.Items {
+				if !isFirst {
+					p.print(",")
+				}
+				p.open("ImportSpecifier", item.AliasLoc, p.tokenEnd(item.Name.Loc))
+				p.field("imported")
+				p.printName(item.AliasLoc, p.tokenEnd(item.AliasLoc), item.Alias)
+				p.field("local")
+				p.printIdentifier(item.Name.Loc, item.Name.Ref)
+				p.close()
+				isFirst = false
+			}
+		}
+		p.print("]")
+		p.field("source")
+		p.printImportPath(s.ImportRecordIndex)
+		p.close()
+
+	case *js_ast.SReturn:
+		p.open("ReturnStatement", stmt.Loc, end)
+		p.field("argument")
+		p.printExprOrNull(s.ValueOrNil)
+		p.close()
+
+	case *js_ast.SThrow:
+		p.open("ThrowStatement", stmt.Loc, end)
+		p.field("argument")
+		p.printExpr(s.Value)
+		p.close()
+
+	case *js_ast.SLocal:
+		loc := p.openExport(stmt.Loc, end, s.IsExport)
+		p.printLocal(loc, end, s)
+		p.closeExport(s.IsExport)
+
+	case *js_ast.SBreak:
+		p.open("BreakStatement", stmt.Loc, end)
+		p.field("label")
+		p.printLabelOrNull(s.Label)
+		p.close()
+
+	case *js_ast.SContinue:
+		p.open("ContinueStatement", stmt.Loc, end)
+		p.field("label")
+		p.printLabelOrNull(s.Label)
+		p.close()
+
+	default:
+		panic("Internal error")
+	}
+}
+
+// The parser turns "namespace A.B {}" into a namespace "B" nested inside of
+// the namespace "A" that starts at the ".", so the nested namespace is used
+// as the body of the outer namespace instead
+func (p *printer) printNamespace(loc logger.Loc, end int32, s *js_ast.SNamespace) {
+	p.open("TSModuleDeclaration", loc, end)
+	p.field("id")
+	p.printIdentifier(s.Name.Loc, s.Name.Ref)
+	p.field("body")
+	if len(s.Stmts) == 1 && p.source.Contents[s.Stmts[0].Loc.Start] == '.' {
+		if inner, ok := s.Stmts[0].Data.(*js_ast.SNamespace); ok {
+			p.printNamespace(inner.Name.Loc, end, inner)
+			p.close()
+			return
+		}
+	}
+	p.open("TSModuleBlock", js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: p.tokenEnd(s.Name.Loc)}).Loc, end)
+	p.field("body")
+	p.printStmts(s.Stmts)
+	p.close()
+	p.close()
+}
+
+func (p *printer) printLocal(loc logger.Loc, end int32, s *js_ast.SLocal) {
+	p.open("VariableDeclaration", loc, end)
+	p.field("kind")
+	switch s.Kind {
+	case js_ast.LocalVar:
+		p.string("var")
+	case js_ast.LocalLet:
+		p.string("let")
+	case js_ast.LocalConst:
+		p.string("const")
+	}
+	p.field("declarations")
+	p.print("[")
+	for i, decl := range s.Decls {
+		if i > 0 {
+			p.print(",")
+		}
+		p.open("VariableDeclarator", decl.Binding.Loc, p.declEnd(decl))
+		p.field("id")
+		p.printBinding(decl.Binding)
+		p.field("init")
+		p.printExprOrNull(decl.ValueOrNil)
+		p.close()
+	}
+	p.print("]")
+	p.close()
+}
+
+// The initializer of a "for" loop is either a variable declaration or an expression
+func (p *printer) printForInit(stmt js_ast.Stmt) {
+	switch s := stmt.Data.(type) {
+	case nil:
+		p.null()
+	case *js_ast.SLocal:
+		p.printLocal(stmt.Loc, p.stmtEnd(stmt), s)
+	case *js_ast.SExpr:
+		p.printExpr(s.Value)
+	default:
+		p.printStmt(stmt)
+	}
+}
+
+func (p *printer) printBindingOrNull(binding js_ast.Binding) {
+	if binding.Data == nil {
+		p.null()
+	} else {
+		p.printBinding(binding)
+	}
+}
+
+func (p *printer) printBinding(binding js_ast.Binding) {
+	switch b := binding.Data.(type) {
+	case *js_ast.BMissing:
+		p.null()
+
+	case *js_ast.BIdentifier:
+		p.printIdentifier(binding.Loc, b.Ref)
+
+	case *js_ast.BArray:
+		p.open("ArrayPattern", binding.Loc, p.bindingEnd(binding))
+		p.field("elements")
+		p.print("[")
+		for i, item := range b.Items {
+			if i > 0 {
+				p.print(",")
+			}
+			if b.HasSpread && i+1 == len(b.Items) {
+				p.open("RestElement", p.restLoc(item.Binding.Loc), p.bindingEnd(item.Binding))
+				p.field("argument")
+				p.printBinding(item.Binding)
+				p.close()
+			} else {
+				p.printBindingWithDefault(item.Binding, item.DefaultValueOrNil)
+			}
+		}
+		p.print("]")
+		p.close()
+
+	case *js_ast.BObject:
+		p.open("ObjectPattern", binding.Loc, p.bindingEnd(binding))
+		p.field("properties")
+		p.print("[")
+		for i, property := range b.Properties {
+			if i > 0 {
+				p.print(",")
+			}
+			if property.IsSpread {
+				p.open("RestElement", p.restLoc(property.Value.Loc), p.bindingEnd(property.Value))
+				p.field("argument")
+				p.printBinding(property.Value)
+				p.close()
+				continue
+			}
+			end := p.bindingEnd(property.Value)
+			if property.DefaultValueOrNil.Data != nil {
+				end = p.exprEnd(property.DefaultValueOrNil)
+			}
+			p.open("Property", property.Key.Loc, end)
+			p.field("key")
+			p.printPropertyKey(property.Key, property.IsComputed)
+			p.field("value")
+			p.printBindingWithDefault(property.Value, property.DefaultValueOrNil)
+			p.field("kind")
+			p.string("init")
+			p.field("method")
+			p.bool(false)
+			p.field("shorthand")
+			p.bool(p.isShorthandBinding(property))
+			p.field("computed")
+			p.bool(property.IsComputed)
+			p.close()
+		}
+		p.print("]")
+		p.close()
+
+	default:
+		panic("Internal error")
+	}
+}
+
+func (p *printer) nameOf(ref js_ast.Ref) string {
+	if name, ok := p.tree.NamesInScope[ref]; ok {
+		return name.Name
+	}
+	return p.symbols.Get(ref).OriginalName
+}
+
+// Object patterns don't remember whether they were written using shorthand
+// syntax, but shorthand properties use the same location for the key and value
+func (p *printer) isShorthandBinding(property js_ast.PropertyBinding) bool {
+	if id, ok := property.Value.Data.(*js_ast.BIdentifier); ok && !property.IsComputed && property.Value.Loc == property.Key.Loc {
+		return isStringKey(property.Key, p.nameOf(id.Ref))
+	}
+	return false
+}
+
+func (p *printer) printBindingWithDefault(binding js_ast.Binding, defaultValueOrNil js_ast.Expr) {
+	if defaultValueOrNil.Data == nil {
+		p.printBinding(binding)
+		return
+	}
+	p.open("AssignmentPattern", binding.Loc, p.exprEnd(defaultValueOrNil))
+	p.field("left")
+	p.printBinding(binding)
+	p.field("right")
+	p.printExpr(defaultValueOrNil)
+	p.close()
+}
+
+func (p *printer) printArgs(args []js_ast.Arg, hasRestArg bool) {
+	p.print("[")
+	for i, arg := range args {
+		if i > 0 {
+			p.print(",")
+		}
+		if hasRestArg && i+1 == len(args) {
+			p.open("RestElement", p.restLoc(arg.Binding.Loc), p.bindingEnd(arg.Binding))
+			p.field("argument")
+			p.printBinding(arg.Binding)
+			p.close()
+		} else {
+			p.printBindingWithDefault(arg.Binding, arg.DefaultOrNil)
+		}
+	}
+	p.print("]")
+}
+
+func (p *printer) printFn(loc logger.Loc, end int32, kind string, fn *js_ast.Fn) {
+	p.open(kind, loc, end)
+	p.field("id")
+	p.printLocRefOrNull(fn.Name)
+	p.field("params")
+	p.printArgs(fn.Args, fn.HasRestArg)
+	p.field("body")
+	p.printBlock(fn.Body.Loc, fn.Body.Block)
+	p.field("async")
+	p.bool(fn.IsAsync)
+	p.field("generator")
+	p.bool(fn.IsGenerator)
+	p.close()
+}
+
+// TypeScript decorators are stored with the location of the "@" before them
+func (p *printer) printDecorators(decorators []js_ast.Expr) {
+	p.field("decorators")
+	p.print("[")
+	for i, decorator := range decorators {
+		if i > 0 {
+			p.print(",")
+		}
+		p.open("Decorator", decorator.Loc, p.exprEnd(decorator))
+		p.field("expression")
+		p.printExpr(js_ast.Expr{
+			Loc:  js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: decorator.Loc.Start + 1}).Loc,
+			Data: decorator.Data,
+		})
+		p.close()
+	}
+	p.print("]")
+}
+
+// Decorated classes and class members start at their first decorator
+func decoratedLoc(loc logger.Loc, decorators []js_ast.Expr) logger.Loc {
+	if len(decorators) > 0 && decorators[0].Loc.Start < loc.Start {
+		return decorators[0].Loc
+	}
+	return loc
+}
+
+func (p *printer) printClass(loc logger.Loc, end int32, kind string, class *js_ast.Class) {
+	p.open(kind, decoratedLoc(loc, class.TSDecorators), end)
+	p.field("id")
+	p.printLocRefOrNull(class.Name)
+	p.field("superClass")
+	p.printExprOrNull(class.ExtendsOrNil)
+	p.printDecorators(class.TSDecorators)
+	p.field("body")
+	p.open("ClassBody", class.BodyLoc, class.CloseBraceLoc.Start+1)
+	p.field("body")
+	p.print("[")
+	isFirst := true
+	for _, property := range class.Properties {
+		// TypeScript "declare" fields don't exist at run-time
+		if property.Kind == js_ast.PropertyDeclare {
+			continue
+		}
+		if !isFirst {
+			p.print(",")
+		}
+		isFirst = false
+
+		if property.Kind == js_ast.PropertyClassStaticBlock {
+			p.open("StaticBlock", property.ClassStaticBlock.Loc, blockEnd(property.ClassStaticBlock.Block))
+			p.field("body")
+			p.printStmts(property.ClassStaticBlock.Block.Stmts)
+			p.close()
+			continue
+		}
+
+		isComputed := property.Flags.Has(js_ast.PropertyIsComputed)
+		isStatic := property.Flags.Has(js_ast.PropertyIsStatic)
+		if property.Flags.Has(js_ast.PropertyIsMethod) || property.Kind == js_ast.PropertyGet || property.Kind == js_ast.PropertySet {
+			p.open("MethodDefinition", decoratedLoc(property.Loc, property.TSDecorators), p.exprEnd(property.ValueOrNil))
+			p.field("key")
+			p.printPropertyKey(property.Key, isComputed)
+			p.field("value")
+			p.printExpr(property.ValueOrNil)
+			p.field("kind")
+			switch {
+			case property.Kind == js_ast.PropertyGet:
+				p.string("get")
+			case property.Kind == js_ast.PropertySet:
+				p.string("set")
+			case !isStatic && !isComputed && isStringKey(property.Key, "constructor"):
+				p.string("constructor")
+			default:
+				p.string("method")
+			}
+		} else {
+			// Fields end after their key or initializer and include the ";" after them
+			var fieldEnd int32
+			if property.InitializerOrNil.Data != nil {
+				fieldEnd = p.exprEnd(property.InitializerOrNil)
+			} else if isComputed {
+				fieldEnd = p.tokenEnd(logger.Loc{Start: p.exprEnd(property.Key)})
+			} else {
+				fieldEnd = p.tokenEnd(property.Key.Loc)
+			}
+			if r := js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: fieldEnd}); r.Len == 1 && p.source.Contents[r.Loc.Start] == ';' {
+				fieldEnd = r.End()
+			}
+			p.open("PropertyDefinition", decoratedLoc(property.Loc, property.TSDecorators), fieldEnd)
+			p.field("key")
+			p.printPropertyKey(property.Key, isComputed)
+			p.field("value")
+			p.printExprOrNull(property.InitializerOrNil)
+		}
+		p.field("static")
+		p.bool(isStatic)
+		p.field("computed")
+		p.bool(isComputed)
+		p.printDecorators(property.TSDecorators)
+		p.close()
+	}
+	p.print("]")
+	p.close()
+	p.close()
+}
+
+func isStringKey(key js_ast.Expr, name string) bool {
+	str, ok := key.Data.(*js_ast.EString)
+	return ok && helpers.UTF16EqualsString(str.Value, name)
+}
+
+// Non-computed property keys are printed as identifiers unless they were quoted
+func (p *printer) printPropertyKey(key js_ast.Expr, isComputed bool) {
+	if !isComputed {
+		switch k := key.Data.(type) {
+		case *js_ast.EString:
+			if c := p.source.Contents[key.Loc.Start]; c != '"' && c != '\'' && js_lexer.IsIdentifierUTF16(k.Value) {
+				p.printName(key.Loc, p.tokenEnd(key.Loc), helpers.UTF16ToString(k.Value))
+				return
+			}
+
+		case *js_ast.EMangledProp:
+			p.printName(key.Loc, p.tokenEnd(key.Loc), p.nameOf(k.Ref))
+			return
+		}
+	}
+	p.printExpr(key)
+}
+
+func (p *printer) printExprOrNull(expr js_ast.Expr) {
+	if expr.Data == nil {
+		p.null()
+	} else {
+		p.printExpr(expr)
+	}
+}
+
+func (p *printer) printExprs(exprs []js_ast.Expr) {
+	p.print("[")
+	for i, expr := range exprs {
+		if i > 0 {
+			p.print(",")
+		}
+		p.printExpr(expr)
+	}
+	p.print("]")
+}
+
+func (p *printer) printOptional(optionalChain js_ast.OptionalChain) {
+	p.field("optional")
+	p.bool(optionalChain == js_ast.OptionalChainStart)
+}
+
+func (p *printer) printNumber(loc logger.Loc, end int32, value float64) {
+	p.open("Literal", loc, end)
+	p.field("value")
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		// JSON can't represent these values
+		p.null()
+		p.field("raw")
+		switch {
+		case math.IsNaN(value):
+			p.string("NaN")
+		case value > 0:
+			p.string("Infinity")
+		default:
+			p.string("-Infinity")
+		}
+	} else {
+		p.js = strconv.AppendFloat(p.js, value, 'g', -1, 64)
+	}
+	p.close()
+}
+
+// Calls to "require" and "import" with a string are included in the list of
+// import paths, like the import paths that the parser found
+func (p *printer) addImportPath(kind ast.ImportKind, target js_ast.Expr, args []js_ast.Expr) {
+	if len(args) != 1 {
+		return
+	}
+	str, ok := args[0].Data.(*js_ast.EString)
+	if !ok {
+		return
+	}
+	if kind != ast.ImportDynamic {
+		if dot, ok := target.Data.(*js_ast.EDot); ok && dot.Name == "resolve" {
+			target = dot.Target
+			kind = ast.ImportRequireResolve
+		}
+		id, ok := target.Data.(*js_ast.EIdentifier)
+		if !ok {
+			return
+		}
+		if symbol := p.symbols.Get(p.bind(id.Ref)); symbol.Kind != js_ast.SymbolUnbound || symbol.OriginalName != "require" {
+			return
+		}
+	}
+	p.imports = append(p.imports, importPath{path: helpers.UTF16ToString(str.Value), kind: kind, loc: args[0].Loc})
+}
+
+func (p *printer) printExpr(expr js_ast.Expr) {
+	end := p.exprEnd(expr)
+
+	switch e := expr.Data.(type) {
+	case *js_ast.EArray:
+		p.open("ArrayExpression", expr.Loc, end)
+		p.field("elements")
+		p.print("[")
+		for i, item := range e.Items {
+			if i > 0 {
+				p.print(",")
+			}
+			if _, ok := item.Data.(*js_ast.EMissing); ok {
+				p.null()
+			} else {
+				p.printExpr(item)
+			}
+		}
+		p.print("]")
+		p.close()
+
+	case *js_ast.EUnary:
+		if e.Op >= js_ast.UnOpPreDec && e.Op <= js_ast.UnOpPostInc {
+			p.open("UpdateExpression", expr.Loc, end)
+		} else {
+			p.open("UnaryExpression", expr.Loc, end)
+		}
+		p.field("operator")
+		p.string(js_ast.OpTable[e.Op].Text)
+		p.field("prefix")
+		p.bool(e.Op.IsPrefix())
+		p.field("argument")
+		p.printExpr(e.Value)
+		p.close()
+
+	case *js_ast.EBinary:
+		if e.Op == js_ast.BinOpComma {
+			// Flatten nested comma operators into a single sequence
+			p.open("SequenceExpression", expr.Loc, end)
+			p.field("expressions")
+			p.printExprs(appendCommaOperands(nil, expr))
+			p.close()
+			return
+		}
+		switch {
+		case e.Op.BinaryAssignTarget() != js_ast.AssignTargetNone:
+			p.open("AssignmentExpression", expr.Loc, end)
+		case e.Op == js_ast.BinOpLogicalOr || e.Op == js_ast.BinOpLogicalAnd || e.Op == js_ast.BinOpNullishCoalescing:
+			p.open("LogicalExpression", expr.Loc, end)
+		default:
+			p.open("BinaryExpression", expr.Loc, end)
+		}
+		p.field("operator")
+		p.string(js_ast.OpTable[e.Op].Text)
+		p.field("left")
+		p.printExpr(e.Left)
+		p.field("right")
+		p.printExpr(e.Right)
+		p.close()
+
+	case *js_ast.EBoolean:
+		p.open("Literal", expr.Loc, end)
+		p.field("value")
+		p.bool(e.Value)
+		p.close()
+
+	case *js_ast.ENull:
+		p.open("Literal", expr.Loc, end)
+		p.field("value")
+		p.null()
+		p.close()
+
+	case *js_ast.EUndefined:
+		p.printName(expr.Loc, end, "undefined")
+
+	case *js_ast.ENumber:
+		p.printNumber(expr.Loc, end, e.Value)
+
+	case *js_ast.EBigInt:
+		p.open("Literal", expr.Loc, end)
+		p.field("value")
+		p.null()
+		p.field("bigint")
+		p.string(e.Value)
+		p.close()
+
+	case *js_ast.EString:
+		if e.PreferTemplate {
+			// The parser turns template literals without substitutions into strings
+			p.printTemplate(expr.Loc, end, &js_ast.ETemplate{HeadLoc: expr.Loc, HeadCooked: e.Value})
+		} else {
+			p.printStringLiteral(expr.Loc, end, helpers.UTF16ToString(e.Value))
+		}
+
+	case *js_ast.ERegExp:
+		slash := strings.LastIndexByte(e.Value, '/')
+		p.open("Literal", expr.Loc, end)
+		p.field("value")
+		p.null()
+		p.field("regex")
+		p.print("{\"pattern\":")
+		p.string(e.Value[1:slash])
+		p.print(",\"flags\":")
+		p.string(e.Value[slash+1:])
+		p.print("}")
+		p.close()
+
+	case *js_ast.ETemplate:
+		if e.TagOrNil.Data != nil {
+			p.open("TaggedTemplateExpression", expr.Loc, end)
+			p.field("tag")
+			p.printExpr(e.TagOrNil)
+			p.field("quasi")
+			p.printTemplate(e.HeadLoc, end, e)
+			p.close()
+		} else {
+			p.printTemplate(expr.Loc, end, e)
+		}
+
+	case *js_ast.ESuper:
+		p.open("Super", expr.Loc, end)
+		p.close()
+
+	case *js_ast.EThis:
+		p.open("ThisExpression", expr.Loc, end)
+		p.close()
+
+	case *js_ast.ENewTarget:
+		p.printMetaProperty(expr.Loc, end, "new", "target")
+
+	case *js_ast.EImportMeta:
+		p.printMetaProperty(expr.Loc, end, "import", "meta")
+
+	case *js_ast.ENew:
+		p.open("NewExpression", expr.Loc, end)
+		p.field("callee")
+		p.printExpr(e.Target)
+		p.field("arguments")
+		p.printExprs(e.Args)
+		p.close()
+
+	case *js_ast.ECall:
+		p.addImportPath(ast.ImportRequire, e.Target, e.Args)
+		p.open("CallExpression", expr.Loc, end)
+		p.field("callee")
+		p.printExpr(e.Target)
+		p.field("arguments")
+		p.printExprs(e.Args)
+		p.printOptional(e.OptionalChain)
+		p.close()
+
+	case *js_ast.EDot:
+		p.open("MemberExpression", expr.Loc, end)
+		p.field("object")
+		p.printExpr(e.Target)
+		p.field("property")
+		p.printName(e.NameLoc, p.tokenEnd(e.NameLoc), e.Name)
+		p.field("computed")
+		p.bool(false)
+		p.printOptional(e.OptionalChain)
+		p.close()
+
+	case *js_ast.EIndex:
+		_, isPrivate := e.Index.Data.(*js_ast.EPrivateIdentifier)
+		p.open("MemberExpression", expr.Loc, end)
+		p.field("object")
+		p.printExpr(e.Target)
+		p.field("property")
+		p.printExpr(e.Index)
+		p.field("computed")
+		p.bool(!isPrivate)
+		p.printOptional(e.OptionalChain)
+		p.close()
+
+	case *js_ast.EArrow:
+		p.open("ArrowFunctionExpression", expr.Loc, end)
+		p.field("id")
+		p.null()
+		p.field("params")
+		p.printArgs(e.Args, e.HasRestArg)
+		p.field("body")
+		isExpression := false
+		if e.PreferExpr && len(e.Body.Block.Stmts) == 1 {
+			if s, ok := e.Body.Block.Stmts[0].Data.(*js_ast.SReturn); ok && s.ValueOrNil.Data != nil {
+				p.printExpr(s.ValueOrNil)
+				isExpression = true
+			}
+		}
+		if !isExpression {
+			p.printBlock(e.Body.Loc, e.Body.Block)
+		}
+		p.field("async")
+		p.bool(e.IsAsync)
+		p.field("generator")
+		p.bool(false)
+		p.field("expression")
+		p.bool(isExpression)
+		p.close()
+
+	case *js_ast.EFunction:
+		p.printFn(expr.Loc, end, "FunctionExpression", &e.Fn)
+
+	case *js_ast.EClass:
+		p.printClass(expr.Loc, end, "ClassExpression", &e.Class)
+
+	case *js_ast.EIdentifier:
+		p.printIdentifier(expr.Loc, e.Ref)
+
+	case *js_ast.EPrivateIdentifier:
+		ref := p.bind(e.Ref)
+		p.open("PrivateIdentifier", expr.Loc, end)
+		p.field("name")
+		p.string(strings.TrimPrefix(p.symbols.Get(ref).OriginalName, "#"))
+		p.field("symbol")
+		p.int(p.symbolIndex(ref))
+		p.close()
+
+	case *js_ast.EMangledProp:
+		p.printStringLiteral(expr.Loc, end, p.nameOf(e.Ref))
+
+	case *js_ast.EJSXElement:
+		p.printJSXElement(expr.Loc, e)
+
+	case *js_ast.EMissing:
+		p.null()
+
+	case *js_ast.EObject:
+		p.open("ObjectExpression", expr.Loc, end)
+		p.field("properties")
+		p.print("[")
+		for i, property := range e.Properties {
+			if i > 0 {
+				p.print(",")
+			}
+			p.printObjectProperty(property)
+		}
+		p.print("]")
+		p.close()
+
+	case *js_ast.ESpread:
+		p.open("SpreadElement", expr.Loc, end)
+		p.field("argument")
+		p.printExpr(e.Value)
+		p.close()
+
+	case *js_ast.EAwait:
+		p.open("AwaitExpression", expr.Loc, end)
+		p.field("argument")
+		p.printExpr(e.Value)
+		p.close()
+
+	case *js_ast.EYield:
+		p.open("YieldExpression", expr.Loc, end)
+		p.field("argument")
+		p.printExprOrNull(e.ValueOrNil)
+		p.field("delegate")
+		p.bool(e.IsStar)
+		p.close()
+
+	case *js_ast.EIf:
+		p.open("ConditionalExpression", expr.Loc, end)
+		p.field("test")
+		p.printExpr(e.Test)
+		p.field("consequent")
+		p.printExpr(e.Yes)
+		p.field("alternate")
+		p.printExpr(e.No)
+		p.close()
+
+	case *js_ast.EImportCall:
+		p.addImportPath(ast.ImportDynamic, js_ast.Expr{}, []js_ast.Expr{e.Expr})
+		p.open("ImportExpression", expr.Loc, end)
+		p.field("source")
+		p.printExpr(e.Expr)
+		p.field("options")
+		p.printExprOrNull(e.OptionsOrNil)
+		p.close()
+
+	default:
+		panic("Internal error")
+	}
+}
+
+func appendCommaOperands(exprs []js_ast.Expr, expr js_ast.Expr) []js_ast.Expr {
+	if e, ok := expr.Data.(*js_ast.EBinary); ok && e.Op == js_ast.BinOpComma {
+		return appendCommaOperands(appendCommaOperands(exprs, e.Left), e.Right)
+	}
+	return append(exprs, expr)
+}
+
+func (p *printer) printMetaProperty(loc logger.Loc, end int32, meta string, property string) {
+	p.open("MetaProperty", loc, end)
+	p.field("meta")
+	p.printName(loc, loc.Start+int32(len(meta)), meta)
+	p.field("property")
+	p.printName(logger.Loc{Start: end - int32(len(property))}, end, property)
+	p.close()
+}
+
+// Template elements don't include the "`", "${", or "}" around them
+func (p *printer) templateElementEnd(start int32) int32 {
+	text := p.source.Contents
+	i := int(start)
+	for i < len(text) {
+		switch text[i] {
+		case '\\':
+			i++
+		case '`':
+			return int32(i)
+		case '$':
+			if i+1 < len(text) && text[i+1] == '{' {
+				return int32(i)
+			}
+		}
+		i++
+	}
+	return int32(len(text))
+}
+
+func (p *printer) printTemplate(loc logger.Loc, end int32, e *js_ast.ETemplate) {
+	isTagged := e.TagOrNil.Data != nil
+	p.open("TemplateLiteral", loc, end)
+	p.field("quasis")
+	p.print("[")
+	p.printTemplateElement(e.HeadLoc, isTagged, e.HeadRaw, e.HeadCooked, len(e.Parts) == 0)
+	for i, part := range e.Parts {
+		p.print(",")
+		p.printTemplateElement(part.TailLoc, isTagged, part.TailRaw, part.TailCooked, i+1 == len(e.Parts))
+	}
+	p.print("]")
+	p.field("expressions")
+	p.print("[")
+	for i, part := range e.Parts {
+		if i > 0 {
+			p.print(",")
+		}
+		p.printExpr(part.Value)
+	}
+	p.print("]")
+	p.close()
+}
+
+func (p *printer) printTemplateElement(loc logger.Loc, isTagged bool, raw string, cooked []uint16, isTail bool) {
+	start := logger.Loc{Start: loc.Start + 1}
+	p.open("TemplateElement", start, p.templateElementEnd(start.Start))
+	p.field("value")
+	p.print("{\"raw\":")
+	if isTagged {
+		p.string(raw)
+	} else {
+		p.null()
+	}
+	p.print(",\"cooked\":")
+	if isTagged {
+		p.null()
+	} else {
+		p.string(helpers.UTF16ToString(cooked))
+	}
+	p.print("}")
+	p.field("tail")
+	p.bool(isTail)
+	p.close()
+}
+
+func (p *printer) printObjectProperty(property js_ast.Property) {
+	if property.Kind == js_ast.PropertySpread {
+		p.open("SpreadElement", property.Loc, p.exprEnd(property.ValueOrNil))
+		p.field("argument")
+		p.printExpr(property.ValueOrNil)
+		p.close()
+		return
+	}
+
+	isComputed := property.Flags.Has(js_ast.PropertyIsComputed)
+	if property.InitializerOrNil.Data != nil {
+		p.open("Property", property.Loc, p.exprEnd(property.InitializerOrNil))
+	} else {
+		p.open("Property", property.Loc, p.exprEnd(property.ValueOrNil))
+	}
+	p.field("key")
+	p.printPropertyKey(property.Key, isComputed)
+	p.field("value")
+	if property.InitializerOrNil.Data != nil {
+		// This is a default value in an object literal used as a pattern
+		p.open("AssignmentPattern", property.ValueOrNil.Loc, p.exprEnd(property.InitializerOrNil))
+		p.field("left")
+		p.printExpr(property.ValueOrNil)
+		p.field("right")
+		p.printExpr(property.InitializerOrNil)
+		p.close()
+	} else {
+		p.printExpr(property.ValueOrNil)
+	}
+	p.field("kind")
+	switch property.Kind {
+	case js_ast.PropertyGet:
+		p.string("get")
+	case js_ast.PropertySet:
+		p.string("set")
+	default:
+		p.string("init")
+	}
+	p.field("method")
+	p.bool(property.Flags.Has(js_ast.PropertyIsMethod))
+	p.field("shorthand")
+	p.bool(property.Flags.Has(js_ast.PropertyWasShorthand))
+	p.field("computed")
+	p.bool(isComputed)
+	p.close()
+}
+
+// JSX elements end at the ">" of the closing tag, or of the opening tag if
+// the element is self-closing
+func (p *printer) jsxElementEnd(e *js_ast.EJSXElement) int32 {
+	if i := strings.IndexByte(p.source.Contents[e.CloseLoc.Start:], '>'); i >= 0 {
+		return e.CloseLoc.Start + int32(i) + 1
+	}
+	return int32(len(p.source.Contents))
+}
+
+// The opening tag ends at the first ">" after the tag name and attributes
+func (p *printer) jsxOpeningElementEnd(after int32) int32 {
+	for {
+		r := js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: after})
+		if r.Len == 0 || p.source.Contents[r.Loc.Start] == '>' {
+			return r.Loc.Start + 1
+		}
+		after = r.End()
+	}
+}
+
+// JSX text ends at the next element or expression
+func (p *printer) jsxTextEnd(loc logger.Loc) int32 {
+	if i := strings.IndexAny(p.source.Contents[loc.Start:], "<{"); i >= 0 {
+		return loc.Start + int32(i)
+	}
+	return int32(len(p.source.Contents))
+}
+
+// JSX expressions and spreads are stored without the "{" and "}" around them
+func (p *printer) jsxBraces(loc logger.Loc, end int32) (logger.Loc, int32) {
+	start := p.source.RangeOfOperatorBefore(loc, "{").Loc
+	if r := js_lexer.RangeOfTokenAt(*p.source, logger.Loc{Start: end}); r.Len == 1 && p.source.Contents[r.Loc.Start] == '}' {
+		end = r.End()
+	}
+	return start, end
+}
+
+// JSX attribute strings end at the next quote since they don't have escapes
+func (p *printer) jsxStringEnd(loc logger.Loc) int32 {
+	text := p.source.Contents[loc.Start:]
+	if i := strings.IndexByte(text[1:], text[0]); i >= 0 {
+		return loc.Start + int32(i) + 2
+	}
+	return int32(len(p.source.Contents))
+}
+
+func (p *printer) printJSXElement(loc logger.Loc, e *js_ast.EJSXElement) {
+	end := p.jsxElementEnd(e)
+	if e.TagOrNil.Data == nil {
+		p.open("JSXFragment", loc, end)
+	} else {
+		p.open("JSXElement", loc, end)
+		p.field("openingElement")
+		openingEnd := end
+		if len(e.Children) > 0 || p.source.Contents[e.CloseLoc.Start] != '/' {
+			after := p.jsxNameEnd(e.TagOrNil)
+			if len(e.Properties) > 0 {
+				after = p.jsxAttributeEnd(e.Properties[len(e.Properties)-1])
+			}
+			openingEnd = p.jsxOpeningElementEnd(after)
+		}
+		p.open("JSXOpeningElement", loc, openingEnd)
+		p.field("name")
+		p.printJSXName(e.TagOrNil)
+		p.field("attributes")
+		p.print("[")
+		for i, property := range e.Properties {
+			if i > 0 {
+				p.print(",")
+			}
+			if property.Kind == js_ast.PropertySpread {
+				start, end := p.jsxBraces(property.Loc, p.exprEnd(property.ValueOrNil))
+				p.open("JSXSpreadAttribute", start, end)
+				p.field("argument")
+				p.printExpr(property.ValueOrNil)
+				p.close()
+				continue
+			}
+			p.open("JSXAttribute", property.Loc, p.jsxAttributeEnd(property))
+			p.field("name")
+			if str, ok := property.Key.Data.(*js_ast.EString); ok {
+				name := helpers.UTF16ToString(str.Value)
+				p.open("JSXIdentifier", property.Key.Loc, property.Key.Loc.Start+int32(len(name)))
+				p.field("name")
+				p.string(name)
+			} else {
+				p.open("JSXIdentifier", property.Key.Loc, p.tokenEnd(property.Key.Loc))
+				p.field("name")
+				p.null()
+			}
+			p.close()
+			p.field("value")
+			if property.Flags.Has(js_ast.PropertyWasShorthand) {
+				p.null()
+			} else if str, ok := property.ValueOrNil.Data.(*js_ast.EString); ok {
+				value := property.ValueOrNil
+				p.printStringLiteral(value.Loc, p.jsxStringEnd(value.Loc), helpers.UTF16ToString(str.Value))
+			} else {
+				start, end := p.jsxBraces(property.ValueOrNil.Loc, p.exprEnd(property.ValueOrNil))
+				p.open("JSXExpressionContainer", start, end)
+				p.field("expression")
+				p.printExpr(property.ValueOrNil)
+				p.close()
+			}
+			p.close()
+		}
+		p.print("]")
+		p.close()
+	}
+	p.field("children")
+	p.print("[")
+	for i, child := range e.Children {
+		if i > 0 {
+			p.print(",")
+		}
+		switch c := child.Data.(type) {
+		case *js_ast.EString:
+			p.open("JSXText", child.Loc, p.jsxTextEnd(child.Loc))
+			p.field("value")
+			p.string(helpers.UTF16ToString(c.Value))
+			p.close()
+		case *js_ast.EJSXElement:
+			p.printJSXElement(child.Loc, c)
+		default:
+			start, end := p.jsxBraces(child.Loc, p.exprEnd(child))
+			p.open("JSXExpressionContainer", start, end)
+			p.field("expression")
+			p.printExpr(child)
+			p.close()
+		}
+	}
+	p.print("]")
+	p.close()
+}
+
+func (p *printer) jsxAttributeEnd(property js_ast.Property) int32 {
+	if property.Flags.Has(js_ast.PropertyWasShorthand) {
+		return property.ValueOrNil.Loc.Start
+	}
+	if _, ok := property.ValueOrNil.Data.(*js_ast.EString); ok && property.Kind != js_ast.PropertySpread {
+		return p.jsxStringEnd(property.ValueOrNil.Loc)
+	}
+	_, end := p.jsxBraces(property.ValueOrNil.Loc, p.exprEnd(property.ValueOrNil))
+	return end
+}
+
+func (p *printer) jsxNameEnd(tag js_ast.Expr) int32 {
+	switch t := tag.Data.(type) {
+	case *js_ast.EString:
+		return tag.Loc.Start + int32(len(helpers.UTF16ToString(t.Value)))
+	case *js_ast.EDot:
+		return t.NameLoc.Start + int32(len(t.Name))
+	}
+	return p.tokenEnd(tag.Loc)
+}
+
+func (p *printer) printJSXName(tag js_ast.Expr) {
+	switch t := tag.Data.(type) {
+	case *js_ast.EString:
+		p.open("JSXIdentifier", tag.Loc, p.jsxNameEnd(tag))
+		p.field("name")
+		p.string(helpers.UTF16ToString(t.Value))
+		p.close()
+
+	case *js_ast.EIdentifier:
+		ref := p.bind(t.Ref)
+		p.open("JSXIdentifier", tag.Loc, p.jsxNameEnd(tag))
+		p.field("name")
+		p.string(p.symbols.Get(js_ast.FollowSymbols(p.symbols, ref)).OriginalName)
+		p.field("symbol")
+		p.int(p.symbolIndex(ref))
+		p.close()
+
+	case *js_ast.EDot:
+		p.open("JSXMemberExpression", tag.Loc, p.jsxNameEnd(tag))
+		p.field("object")
+		p.printJSXName(t.Target)
+		p.field("property")
+		p.open("JSXIdentifier", t.NameLoc, p.jsxNameEnd(tag))
+		p.field("name")
+		p.string(t.Name)
+		p.close()
+		p.close()
+
+	default:
+		p.printExpr(tag)
+	}
+}
@@ -0,0 +1,360 @@
+package js_estree
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+// The JSON is summarized as one line per node with the nested nodes indented
+// below it, which is much easier to read than the JSON itself
+func summarize(sb *strings.Builder, value interface{}, indent string) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			summarize(sb, item, indent)
+		}
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if kind, ok := v["type"]; ok {
+			sb.WriteString(fmt.Sprintf("%s%v", indent, kind))
+			for _, key := range keys {
+				switch item := v[key].(type) {
+				case map[string]interface{}, []interface{}:
+				case string:
+					if key != "type" {
+						sb.WriteString(fmt.Sprintf(" %s=%q", key, item))
+					}
+				default:
+					sb.WriteString(fmt.Sprintf(" %s=%v", key, item))
+				}
+			}
+			sb.WriteString("\n")
+			indent += "  "
+		}
+		for _, key := range keys {
+			switch v[key].(type) {
+			case map[string]interface{}, []interface{}:
+				summarize(sb, v[key], indent)
+			}
+		}
+	}
+}
+
+func expectPrintedCommon(t *testing.T, contents string, expectedBody string, expectedExtra string, options config.Options) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		source := test.SourceForTest(contents)
+		tree, ok := js_parser.ParseSyntaxTree(log, source, js_parser.OptionsFromConfig(&options))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		test.AssertEqualWithDiff(t, text, "")
+		if !ok {
+			t.Fatal("Parse error")
+		}
+		var program map[string]interface{}
+		if err := json.Unmarshal(Print(&tree, &source), &program); err != nil {
+			t.Fatal(err)
+		}
+		sb := strings.Builder{}
+		summarize(&sb, program["body"], "")
+		test.AssertEqualWithDiff(t, sb.String(), expectedBody)
+		if expectedExtra != "" {
+			extra, _ := json.Marshal(map[string]interface{}{
+				"imports": program["imports"],
+				"scope":   program["scope"],
+			})
+			test.AssertEqualWithDiff(t, string(extra), expectedExtra)
+		}
+	})
+}
+
+func expectPrinted(t *testing.T, contents string, expectedBody string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expectedBody, "", config.Options{})
+}
+
+func expectPrintedJSX(t *testing.T, contents string, expectedBody string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expectedBody, "", config.Options{
+		JSX: config.JSXOptions{Parse: true, Preserve: true},
+	})
+}
+
+func expectPrintedTS(t *testing.T, contents string, expectedBody string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expectedBody, "", config.Options{
+		TS:                  config.TSOptions{Parse: true},
+		UnusedImportFlagsTS: config.UnusedImportKeepValues,
+	})
+}
+
+func TestStatements(t *testing.T) {
+	expectPrinted(t, "let x = 1; x++",
+		`VariableDeclaration end=10 kind="let" start=0
+  VariableDeclarator end=9 start=4
+    Identifier end=5 name="x" start=4 symbol=0
+    Literal end=9 start=8 value=1
+ExpressionStatement end=14 start=11
+  UpdateExpression end=14 operator="++" prefix=false start=11
+    Identifier end=12 name="x" start=11 symbol=0
+`)
+
+	expectPrinted(t, "a: for (const [b, ...c] of d) if (b) continue a; else break",
+		`LabeledStatement end=59 start=0
+  ForOfStatement await=false end=59 start=3
+    IfStatement end=59 start=30
+      BreakStatement end=59 label=<nil> start=54
+      ContinueStatement end=48 start=37
+        Identifier end=47 name="a" start=46 symbol=2
+      Identifier end=35 name="b" start=34 symbol=0
+    VariableDeclaration end=23 kind="const" start=8
+      VariableDeclarator end=23 init=<nil> start=14
+        ArrayPattern end=23 start=14
+          Identifier end=16 name="b" start=15 symbol=0
+          RestElement end=22 start=18
+            Identifier end=22 name="c" start=21 symbol=1
+    Identifier end=28 name="d" start=27 symbol=3
+  Identifier end=1 name="a" start=0 symbol=2
+`)
+
+	expectPrinted(t, "try { throw a } catch ({ b = 1 }) {} finally {}",
+		`TryStatement end=47 start=0
+  BlockStatement end=15 start=4
+    ThrowStatement end=13 start=6
+      Identifier end=13 name="a" start=12 symbol=1
+  BlockStatement end=47 start=45
+  CatchClause end=36 start=16
+    BlockStatement end=36 start=34
+    ObjectPattern end=32 start=23
+      Property computed=false end=30 kind="init" method=false shorthand=true start=25
+        Identifier end=26 name="b" start=25
+        AssignmentPattern end=30 start=25
+          Identifier end=26 name="b" start=25 symbol=0
+          Literal end=30 start=29 value=1
+`)
+}
+
+func TestExpressions(t *testing.T) {
+	expectPrinted(t, "a = b ?? c?.d[e](...f), `x${y}`, g`z`",
+		`ExpressionStatement end=37 start=0
+  SequenceExpression end=37 start=0
+    AssignmentExpression end=22 operator="=" start=0
+      Identifier end=1 name="a" start=0 symbol=0
+      LogicalExpression end=22 operator="??" start=4
+        Identifier end=5 name="b" start=4 symbol=1
+        CallExpression end=22 optional=false start=9
+          SpreadElement end=21 start=17
+            Identifier end=21 name="f" start=20 symbol=4
+          MemberExpression computed=true end=16 optional=false start=9
+            MemberExpression computed=false end=13 optional=true start=9
+              Identifier end=10 name="c" start=9 symbol=2
+              Identifier end=13 name="d" start=12
+            Identifier end=15 name="e" start=14 symbol=3
+    TemplateLiteral end=31 start=24
+      Identifier end=29 name="y" start=28 symbol=5
+      TemplateElement end=26 start=25 tail=false
+      TemplateElement end=30 start=30 tail=true
+    TaggedTemplateExpression end=37 start=33
+      TemplateLiteral end=37 start=34
+        TemplateElement end=36 start=35 tail=true
+      Identifier end=34 name="g" start=33 symbol=6
+`)
+
+	expectPrinted(t, "({ a, [b]: 1, get c() {}, ...d }); /x/g; 1n",
+		`ExpressionStatement end=34 start=0
+  ObjectExpression end=32 start=1
+    Property computed=false end=4 kind="init" method=false shorthand=true start=3
+      Identifier end=4 name="a" start=3
+      Identifier end=4 name="a" start=3 symbol=1
+    Property computed=true end=12 kind="init" method=false shorthand=false start=6
+      Identifier end=8 name="b" start=7 symbol=2
+      Literal end=12 start=11 value=1
+    Property computed=false end=24 kind="get" method=true shorthand=false start=14
+      Identifier end=19 name="c" start=18
+      FunctionExpression async=false end=24 generator=false id=<nil> start=19
+        BlockStatement end=24 start=22
+    SpreadElement end=30 start=26
+      Identifier end=30 name="d" start=29 symbol=3
+ExpressionStatement end=40 start=35
+  Literal end=39 start=35 value=<nil>
+ExpressionStatement end=43 start=41
+  Literal bigint="1" end=43 start=41 value=<nil>
+`)
+
+	expectPrinted(t, "class A extends B { #x = 1; static y; constructor() { super() } m() { return this.#x } }",
+		`ClassDeclaration end=88 start=0
+  ClassBody end=88 start=18
+    PropertyDefinition computed=false end=27 start=20 static=false
+      PrivateIdentifier end=22 name="x" start=20 symbol=1
+      Literal end=26 start=25 value=1
+    PropertyDefinition computed=false end=37 start=28 static=true value=<nil>
+      Identifier end=36 name="y" start=35
+    MethodDefinition computed=false end=63 kind="constructor" start=38 static=false
+      Identifier end=49 name="constructor" start=38
+      FunctionExpression async=false end=63 generator=false id=<nil> start=49
+        BlockStatement end=63 start=52
+          ExpressionStatement end=61 start=54
+            CallExpression end=61 optional=false start=54
+              Super end=59 start=54
+    MethodDefinition computed=false end=86 kind="method" start=64 static=false
+      Identifier end=65 name="m" start=64
+      FunctionExpression async=false end=86 generator=false id=<nil> start=65
+        BlockStatement end=86 start=68
+          ReturnStatement end=84 start=70
+            MemberExpression computed=false end=84 optional=false start=77
+              ThisExpression end=81 start=77
+              PrivateIdentifier end=84 name="x" start=82 symbol=1
+  Identifier end=7 name="A" start=6 symbol=0
+  Identifier end=17 name="B" start=16 symbol=4
+`)
+}
+
+func TestImportsAndExports(t *testing.T) {
+	expectPrintedCommon(t, "import a, { b as c } from 'x'; export * as d from 'y'; export { a }; import('z')",
+		`ImportDeclaration end=30 start=0
+  Literal end=29 start=26 value="x"
+  ImportDefaultSpecifier end=8 start=7
+    Identifier end=8 name="a" start=7 symbol=1
+  ImportSpecifier end=18 start=12
+    Identifier end=13 name="b" start=12
+    Identifier end=18 name="c" start=17 symbol=2
+ExportAllDeclaration end=54 start=31
+  Identifier end=44 name="d" start=43
+  Literal end=53 start=50 value="y"
+ExportNamedDeclaration declaration=<nil> end=68 source=<nil> start=55
+  ExportSpecifier end=65 start=64
+    Identifier end=65 name="a" start=64
+    Identifier end=65 name="a" start=64 symbol=1
+ExpressionStatement end=80 start=69
+  ImportExpression end=80 options=<nil> start=69
+    Literal end=79 start=76 value="z"
+`,
+		`{"imports":[{"kind":"import-statement","path":"x","start":26},{"kind":"import-statement","path":"y","start":50},{"kind":"dynamic-import","path":"z","start":76}],`+
+			`"scope":{"children":[],"kind":"module","members":{"a":1,"c":2}}}`,
+		config.Options{})
+}
+
+func TestScopes(t *testing.T) {
+	expectPrintedCommon(t, "function f(a) { let b = a; { let a = b } }",
+		`FunctionDeclaration async=false end=42 generator=false start=0
+  BlockStatement end=42 start=14
+    VariableDeclaration end=26 kind="let" start=16
+      VariableDeclarator end=25 start=20
+        Identifier end=21 name="b" start=20 symbol=2
+        Identifier end=25 name="a" start=24 symbol=0
+    BlockStatement end=40 start=27
+      VariableDeclaration end=38 kind="let" start=29
+        VariableDeclarator end=38 start=33
+          Identifier end=34 name="a" start=33 symbol=3
+          Identifier end=38 name="b" start=37 symbol=2
+  Identifier end=10 name="f" start=9 symbol=4
+  Identifier end=12 name="a" start=11 symbol=0
+`,
+		`{"imports":[],"scope":{"children":[{"children":[{"children":[{"children":[],"kind":"block","members":{"a":3}}],`+
+			`"kind":"function-body","members":{"a":0,"arguments":1,"b":2}}],"kind":"function-args","members":{"a":0,"arguments":1}}],`+
+			`"kind":"module","members":{"f":4}}}`,
+		config.Options{})
+}
+
+func TestJSX(t *testing.T) {
+	expectPrintedJSX(t, "<a.b c='d' {...e} f={g}>h{i}<></></a.b>",
+		`ExpressionStatement end=39 start=0
+  JSXElement end=39 start=0
+    JSXText end=25 start=24 value="h"
+    JSXExpressionContainer end=28 start=25
+      Identifier end=27 name="i" start=26 symbol=3
+    JSXFragment end=33 start=28
+    JSXOpeningElement end=24 start=0
+      JSXAttribute end=10 start=5
+        JSXIdentifier end=6 name="c" start=5
+        Literal end=10 start=7 value="d"
+      JSXSpreadAttribute end=17 start=11
+        Identifier end=16 name="e" start=15 symbol=1
+      JSXAttribute end=23 start=18
+        JSXIdentifier end=19 name="f" start=18
+        JSXExpressionContainer end=23 start=20
+          Identifier end=22 name="g" start=21 symbol=2
+      JSXMemberExpression end=4 start=1
+        JSXIdentifier end=2 name="a" start=1 symbol=0
+        JSXIdentifier end=4 name="b" start=3
+`)
+}
+
+func TestTypeScript(t *testing.T) {
+	expectPrintedTS(t, "import { T } from 'x'; type U = T; let v: U = 1 as any",
+		`ImportDeclaration end=22 start=0
+  Literal end=21 start=18 value="x"
+  ImportSpecifier end=10 start=9
+    Identifier end=10 name="T" start=9
+    Identifier end=10 name="T" start=9 symbol=1
+VariableDeclaration end=54 kind="let" start=35
+  VariableDeclarator end=47 start=39
+    Identifier end=40 name="v" start=39 symbol=2
+    Literal end=47 start=46 value=1
+`)
+}
+
+// The tree comes from the parser without any constant folding or lowering
+func TestNotFolded(t *testing.T) {
+	expectPrinted(t, "'a' + 'b'",
+		`ExpressionStatement end=9 start=0
+  BinaryExpression end=9 operator="+" start=0
+    Literal end=3 start=0 value="a"
+    Literal end=9 start=6 value="b"
+`)
+}
+
+func TestTypeScriptEnumsAndDecorators(t *testing.T) {
+	expectPrintedTS(t, "export enum E { A, B = 'x' }",
+		`ExportNamedDeclaration end=28 source=<nil> start=0
+  TSEnumDeclaration end=28 start=7
+    Identifier end=13 name="E" start=12 symbol=0
+    TSEnumMember end=17 initializer=<nil> start=16
+      Identifier end=17 name="A" start=16
+    TSEnumMember end=26 start=19
+      Identifier end=20 name="B" start=19
+      Literal end=26 start=23 value="x"
+`)
+
+	expectPrintedTS(t, "@a @b.c() class Z { @d x = 1; @e m() {} }",
+		`ClassDeclaration end=41 start=0 superClass=<nil>
+  ClassBody end=41 start=18
+    PropertyDefinition computed=false end=29 start=20 static=false
+      Decorator end=22 start=20
+        Identifier end=22 name="d" start=21 symbol=4
+      Identifier end=24 name="x" start=23
+      Literal end=28 start=27 value=1
+    MethodDefinition computed=false end=39 kind="method" start=30 static=false
+      Decorator end=32 start=30
+        Identifier end=32 name="e" start=31 symbol=5
+      Identifier end=34 name="m" start=33
+      FunctionExpression async=false end=39 generator=false id=<nil> start=34
+        BlockStatement end=39 start=37
+  Decorator end=2 start=0
+    Identifier end=2 name="a" start=1 symbol=2
+  Decorator end=9 start=3
+    CallExpression end=9 optional=false start=4
+      MemberExpression computed=false end=7 optional=false start=4
+        Identifier end=5 name="b" start=4 symbol=3
+        Identifier end=7 name="c" start=6
+  Identifier end=17 name="Z" start=16 symbol=0
+`)
+}
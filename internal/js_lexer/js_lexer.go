@@ -254,6 +254,10 @@ type Lexer struct {
 	JSXFragmentPragmaComment logger.Span
 	SourceMappingURL         logger.Span
 
+	// These are from the "@jsxRuntime" and "@jsxImportSource" pragmas
+	JSXRuntimePragmaComment      logger.Span
+	JSXImportSourcePragmaComment logger.Span
+
 	// Escape sequences in string literals are decoded lazily because they are
 	// not interpreted inside tagged templates, and tagged templates can contain
 	// invalid escape sequences. If the decoded array is nil, the encoded value
@@ -269,6 +273,7 @@ type Lexer struct {
 	current                         int
 	start                           int
 	end                             int
+	prevTokenEnd                    int
 	ApproximateNewlineCount         int
 	LegacyOctalLoc                  logger.Loc
 	AwaitKeywordLoc                 logger.Loc
@@ -283,6 +288,7 @@ type Lexer struct {
 	HasNewlineBefore                bool
 	HasPureCommentBefore            bool
 	PreserveAllCommentsBefore       bool
+	preserveAllComments             bool
 	IsLegacyOctalLiteral            bool
 	PrevTokenWasAwaitKeyword        bool
 	rescanCloseBraceAsTemplateToken bool
@@ -308,6 +314,23 @@ func NewLexer(log logger.Log, source logger.Source, ts config.TSOptions) Lexer {
 	return lexer
 }
 
+// This is used when comments should be kept in the output. Every comment that
+// comes before a token is collected, not just legal comments.
+func NewLexerPreservingComments(log logger.Log, source logger.Source, ts config.TSOptions) Lexer {
+	lexer := Lexer{
+		log:                 log,
+		source:              source,
+		tracker:             logger.MakeLineColumnTracker(&source),
+		prevErrorLoc:        logger.Loc{Start: -1},
+		FnOrArrowStartLoc:   logger.Loc{Start: -1},
+		ts:                  ts,
+		preserveAllComments: true,
+	}
+	lexer.step()
+	lexer.Next()
+	return lexer
+}
+
 func NewLexerGlobalName(log logger.Log, source logger.Source) Lexer {
 	lexer := Lexer{
 		log:               log,
@@ -347,6 +370,12 @@ func (lexer *Lexer) Range() logger.Range {
 	return logger.Range{Loc: logger.Loc{Start: int32(lexer.start)}, Len: int32(lexer.end - lexer.start)}
 }
 
+// This is where the token before the current one ends, which is also where
+// the syntax that the parser has most recently finished parsing ends
+func (lexer *Lexer) PrevTokenEnd() int32 {
+	return int32(lexer.prevTokenEnd)
+}
+
 func (lexer *Lexer) Raw() string {
 	return lexer.source.Contents[lexer.start:lexer.end]
 }
@@ -502,6 +531,37 @@ func (lexer *Lexer) Unexpected() {
 	panic(LexerPanic{})
 }
 
+// This is used to continue lexing after a syntax error. Unlike "Next", it
+// doesn't panic. Characters that can't start a token are reported and then
+// skipped over, so the resulting token is always valid (or the end of file).
+func (lexer *Lexer) NextAfterSyntaxError() {
+	for {
+		start := lexer.end
+		if lexer.tryToNext() {
+			return
+		}
+
+		// Make sure we always make forward progress
+		if lexer.end == start && lexer.codePoint != -1 {
+			lexer.step()
+		}
+	}
+}
+
+func (lexer *Lexer) tryToNext() (ok bool) {
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(LexerPanic); isLexerPanic {
+			ok = false
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	lexer.Next()
+	return true
+}
+
 func (lexer *Lexer) Expect(token T) {
 	if lexer.Token != token {
 		lexer.Expected(token)
@@ -531,15 +591,18 @@ func (lexer *Lexer) ExpectLessThan(isInsideJSXElement bool) {
 	case TLessThanEquals:
 		lexer.Token = TEquals
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 		lexer.maybeExpandEquals()
 
 	case TLessThanLessThan:
 		lexer.Token = TLessThan
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 
 	case TLessThanLessThanEquals:
 		lexer.Token = TLessThanEquals
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 
 	default:
 		lexer.Expected(TLessThan)
@@ -561,23 +624,28 @@ func (lexer *Lexer) ExpectGreaterThan(isInsideJSXElement bool) {
 	case TGreaterThanEquals:
 		lexer.Token = TEquals
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 		lexer.maybeExpandEquals()
 
 	case TGreaterThanGreaterThan:
 		lexer.Token = TGreaterThan
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 
 	case TGreaterThanGreaterThanEquals:
 		lexer.Token = TGreaterThanEquals
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 
 	case TGreaterThanGreaterThanGreaterThan:
 		lexer.Token = TGreaterThanGreaterThan
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 
 	case TGreaterThanGreaterThanGreaterThanEquals:
 		lexer.Token = TGreaterThanGreaterThanEquals
 		lexer.start++
+		lexer.prevTokenEnd = lexer.start
 
 	default:
 		lexer.Expected(TGreaterThan)
@@ -887,6 +955,33 @@ func RangeOfIdentifier(source logger.Source, loc logger.Loc) logger.Range {
 	return source.RangeOfString(loc)
 }
 
+// This returns the range of the first token at or after the given location.
+// It's used to find where a node ends when that node is a single token. The
+// range is empty if there is a syntax error.
+func RangeOfTokenAt(source logger.Source, loc logger.Loc) (r logger.Range) {
+	defer func() {
+		r2 := recover()
+		if _, isLexerPanic := r2.(LexerPanic); isLexerPanic {
+			r = logger.Range{Loc: loc}
+		} else if r2 != nil {
+			panic(r2)
+		}
+	}()
+
+	lexer := Lexer{
+		log:               logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil),
+		source:            source,
+		tracker:           logger.MakeLineColumnTracker(&source),
+		prevErrorLoc:      logger.Loc{Start: -1},
+		FnOrArrowStartLoc: logger.Loc{Start: -1},
+		current:           int(loc.Start),
+		IsLogDisabled:     true,
+	}
+	lexer.step()
+	lexer.Next()
+	return lexer.Range()
+}
+
 func (lexer *Lexer) ExpectJSXElementChild(token T) {
 	if lexer.Token != token {
 		lexer.Expected(token)
@@ -896,6 +991,7 @@ func (lexer *Lexer) ExpectJSXElementChild(token T) {
 
 func (lexer *Lexer) NextJSXElementChild() {
 	lexer.HasNewlineBefore = false
+	lexer.prevTokenEnd = lexer.end
 	originalStart := lexer.end
 
 	for {
@@ -1010,6 +1106,7 @@ func (lexer *Lexer) ExpectInsideJSXElement(token T) {
 
 func (lexer *Lexer) NextInsideJSXElement() {
 	lexer.HasNewlineBefore = false
+	lexer.prevTokenEnd = lexer.end
 
 	for {
 		lexer.start = lexer.end
@@ -1190,6 +1287,7 @@ func (lexer *Lexer) NextInsideJSXElement() {
 
 func (lexer *Lexer) Next() {
 	lexer.HasNewlineBefore = lexer.end == 0
+	lexer.prevTokenEnd = lexer.end
 	lexer.HasPureCommentBefore = false
 	lexer.PrevTokenWasAwaitKeyword = false
 	lexer.CommentsToPreserveBefore = nil
@@ -2589,12 +2687,15 @@ func (lexer *Lexer) RescanCloseBraceAsTemplateToken() {
 		lexer.Expected(TCloseBrace)
 	}
 
+	// The "}" is part of the template token, so the previous token doesn't change
+	prevTokenEnd := lexer.prevTokenEnd
 	lexer.rescanCloseBraceAsTemplateToken = true
 	lexer.codePoint = '`'
 	lexer.current = lexer.end
 	lexer.end -= 1
 	lexer.Next()
 	lexer.rescanCloseBraceAsTemplateToken = false
+	lexer.prevTokenEnd = prevTokenEnd
 }
 
 func (lexer *Lexer) step() {
@@ -2730,6 +2831,7 @@ func (lexer *Lexer) scanCommentText() {
 	text := lexer.source.Contents[lexer.start:lexer.end]
 	hasLegalAnnotation := len(text) > 2 && text[2] == '!'
 	isMultiLineComment := text[1] == '*'
+	isAnnotation := false
 
 	// Save the original comment text so we can subtract comments from the
 	// character frequency analysis used by symbol minification
@@ -2750,16 +2852,19 @@ func (lexer *Lexer) scanCommentText() {
 			rest := text[i+1 : endOfCommentText]
 			if hasPrefixWithWordBoundary(rest, "__PURE__") {
 				lexer.HasPureCommentBefore = true
+				isAnnotation = true
 			} else if i == 2 && strings.HasPrefix(rest, " sourceMappingURL=") {
 				if arg, ok := scanForPragmaArg(pragmaNoSpaceFirst, lexer.start+i+1, " sourceMappingURL=", rest); ok {
 					lexer.SourceMappingURL = arg
 				}
+				isAnnotation = true
 			}
 
 		case '@':
 			rest := text[i+1 : endOfCommentText]
 			if hasPrefixWithWordBoundary(rest, "__PURE__") {
 				lexer.HasPureCommentBefore = true
+				isAnnotation = true
 			} else if hasPrefixWithWordBoundary(rest, "preserve") || hasPrefixWithWordBoundary(rest, "license") {
 				hasLegalAnnotation = true
 			} else if hasPrefixWithWordBoundary(rest, "jsx") {
@@ -2770,22 +2875,34 @@ func (lexer *Lexer) scanCommentText() {
 				if arg, ok := scanForPragmaArg(pragmaSkipSpaceFirst, lexer.start+i+1, "jsxFrag", rest); ok {
 					lexer.JSXFragmentPragmaComment = arg
 				}
+			} else if hasPrefixWithWordBoundary(rest, "jsxRuntime") {
+				if arg, ok := scanForPragmaArg(pragmaSkipSpaceFirst, lexer.start+i+1, "jsxRuntime", rest); ok {
+					lexer.JSXRuntimePragmaComment = arg
+				}
+			} else if hasPrefixWithWordBoundary(rest, "jsxImportSource") {
+				if arg, ok := scanForPragmaArg(pragmaSkipSpaceFirst, lexer.start+i+1, "jsxImportSource", rest); ok {
+					lexer.JSXImportSourcePragmaComment = arg
+				}
 			} else if i == 2 && strings.HasPrefix(rest, " sourceMappingURL=") {
 				if arg, ok := scanForPragmaArg(pragmaNoSpaceFirst, lexer.start+i+1, " sourceMappingURL=", rest); ok {
 					lexer.SourceMappingURL = arg
 				}
+				isAnnotation = true
 			}
 		}
 	}
 
-	if hasLegalAnnotation || lexer.PreserveAllCommentsBefore {
+	// Annotations are regenerated by the printer when they still apply, so they
+	// aren't kept along with the other comments
+	if hasLegalAnnotation || lexer.PreserveAllCommentsBefore || (lexer.preserveAllComments && !isAnnotation) {
 		if isMultiLineComment {
 			text = helpers.RemoveMultiLineCommentIndent(lexer.source.Contents[:lexer.start], text)
 		}
 
 		lexer.CommentsToPreserveBefore = append(lexer.CommentsToPreserveBefore, js_ast.Comment{
-			Loc:  logger.Loc{Start: int32(lexer.start)},
-			Text: text,
+			Loc:            logger.Loc{Start: int32(lexer.start)},
+			Text:           text,
+			IsLegalComment: hasLegalAnnotation,
 		})
 	}
 }
@@ -55,6 +55,11 @@ type parser struct {
 	symbolCallUses             map[js_ast.Ref]js_ast.SymbolCallUse
 	declaredSymbols            []js_ast.DeclaredSymbol
 	runtimeImports             map[string]js_ast.Ref
+	jsxRuntimeImports          map[string]js_ast.Ref
+	jsxLegacyImports           map[string]js_ast.Ref
+	importMetaGlobParts        []js_ast.Part
+	usesImportMetaGlob         bool
+	usedRuntimeFeatures        compat.RuntimeFeature
 	duplicateCaseChecker       duplicateCaseChecker
 	unrepresentableIdentifiers map[string]bool
 	legacyOctalLiterals        map[js_ast.E]logger.Range
@@ -88,6 +93,8 @@ type parser struct {
 	isExportedInsideNamespace  map[js_ast.Ref]js_ast.Ref
 	localTypeNames             map[string]bool
 	tsEnums                    map[js_ast.Ref]map[string]js_ast.TSEnumValue
+	tsAmbientMerges            map[tsAmbientMergeKey]tsAmbientMergeFlags
+	tsEnumMetadata             map[js_ast.Ref]tsTypeMetadata
 	constValues                map[js_ast.Ref]js_ast.ConstValue
 	propMethodValue            js_ast.E
 	propMethodTSDecoratorScope *js_ast.Scope
@@ -121,6 +128,27 @@ type parser struct {
 	topLevelSymbolToParts   map[js_ast.Ref][]uint32
 	importNamespaceCCMap    map[importNamespaceCall]bool
 
+	// Imports that are referenced inside dead code regions (e.g. a branch that
+	// a define made statically false). Import statements whose imports are only
+	// referenced in dead code are removed when bundling with "--drop:dead-imports".
+	importRefsUsedInDeadCode map[js_ast.Ref]bool
+
+	// Top-level symbols that are exported from this file. This is only used
+	// when "keepNames" is restricted to exports, which must be known before the
+	// visit pass since an export clause may come after the declaration.
+	keepNamesExports map[js_ast.Ref]bool
+
+	// Errors logged to "log" during the parse pass are syntax errors. Errors
+	// about valid syntax that the configured target doesn't support are logged
+	// here instead, since they aren't syntax errors.
+	targetLog logger.Log
+
+	// These are only used by "ParseSyntaxTree", which doesn't do the visit pass.
+	// The parser doesn't otherwise remember where nodes end or which scope an
+	// identifier was found in, since the visit pass doesn't need either one.
+	nodeEnds     map[interface{}]int32
+	namesInScope map[js_ast.Ref]NameInScope
+
 	// The parser does two passes and we need to pass the scope tree information
 	// from the first pass to the second pass. That's done by tracking the calls
 	// to pushScopeForParsePass() and popScope() during the first pass in
@@ -146,6 +174,7 @@ type parser struct {
 	dotOrIndexTarget js_ast.E
 	templateTag      js_ast.E
 	deleteTarget     js_ast.E
+	typeofTarget     js_ast.E
 	loopBody         js_ast.S
 	moduleScope      *js_ast.Scope
 
@@ -367,11 +396,25 @@ type Options struct {
 	mangleProps    *regexp.Regexp
 	reserveProps   *regexp.Regexp
 
+	keepNamesFilter *regexp.Regexp
+
 	// This pointer will always be different for each build but the contents
 	// shouldn't ever behave different semantically. We ignore this field for the
 	// equality comparison.
 	defines *config.ProcessedDefines
 
+	// This is different for each file and is also ignored for the equality
+	// comparison. Files that use it are never reused from the cache instead.
+	expandGlob func(pattern string) []string
+
+	// These are used to find the files for "require()" calls with a non-constant
+	// argument. Files that do this are also never reused from the cache.
+	resolveExtensions []string
+
+	// This is set when the build no longer needs the result of parsing. It's
+	// different for each build and is also ignored for the equality comparison.
+	cancelFlag *config.CancelFlag
+
 	// This is an embedded struct. Always access these directly instead of off
 	// the name "optionsThatSupportStructuralEquality". This is only grouped like
 	// this to make the equality comparison easier and safer (and hopefully faster).
@@ -384,6 +427,7 @@ type optionsThatSupportStructuralEquality struct {
 	unsupportedJSFeatures             compat.JSFeature
 	unsupportedJSFeatureOverrides     compat.JSFeature
 	unsupportedJSFeatureOverridesMask compat.JSFeature
+	unsupportedRuntimeFeatures        compat.RuntimeFeature
 
 	// Byte-sized values go here (gathered together here to keep this object compact)
 	ts                      config.TSOptions
@@ -393,31 +437,43 @@ type optionsThatSupportStructuralEquality struct {
 	targetFromAPI           config.TargetFromAPI
 	asciiOnly               bool
 	keepNames               bool
+	keepNamesOnly           config.KeepNamesOnly
 	minifySyntax            bool
 	minifyIdentifiers       bool
 	omitRuntimeForTests     bool
 	ignoreDCEAnnotations    bool
 	treeShaking             bool
 	dropDebugger            bool
+	dropDeadImports         bool
 	mangleQuoted            bool
 	unusedImportFlagsTS     config.UnusedImportFlagsTS
 	useDefineForClassFields config.MaybeBool
+	emitDecoratorMetadata   bool
+	allowRuntimeHelpers     bool
+	dynamicRequire          config.DynamicRequire
+	preserveComments        bool
+	errorRecovery           bool
 }
 
 func OptionsFromConfig(options *config.Options) Options {
 	return Options{
-		injectedFiles:  options.InjectedFiles,
-		jsx:            options.JSX,
-		defines:        options.Defines,
-		tsTarget:       options.TSTarget,
-		tsAlwaysStrict: options.TSAlwaysStrict,
-		mangleProps:    options.MangleProps,
-		reserveProps:   options.ReserveProps,
+		injectedFiles:     options.InjectedFiles,
+		jsx:               options.JSX,
+		defines:           options.Defines,
+		expandGlob:        options.ExpandGlob,
+		resolveExtensions: options.ExtensionOrder,
+		tsTarget:          options.TSTarget,
+		tsAlwaysStrict:    options.TSAlwaysStrict,
+		mangleProps:       options.MangleProps,
+		reserveProps:      options.ReserveProps,
+		keepNamesFilter:   options.KeepNamesFilter,
+		cancelFlag:        options.CancelFlag,
 
 		optionsThatSupportStructuralEquality: optionsThatSupportStructuralEquality{
 			unsupportedJSFeatures:             options.UnsupportedJSFeatures,
 			unsupportedJSFeatureOverrides:     options.UnsupportedJSFeatureOverrides,
 			unsupportedJSFeatureOverridesMask: options.UnsupportedJSFeatureOverridesMask,
+			unsupportedRuntimeFeatures:        options.UnsupportedRuntimeFeatures,
 			originalTargetEnv:                 options.OriginalTargetEnv,
 			ts:                                options.TS,
 			mode:                              options.Mode,
@@ -425,21 +481,32 @@ func OptionsFromConfig(options *config.Options) Options {
 			outputFormat:                      options.OutputFormat,
 			moduleTypeData:                    options.ModuleTypeData,
 			targetFromAPI:                     options.TargetFromAPI,
-			asciiOnly:                         options.ASCIIOnly,
+			asciiOnly:                         options.ASCIIOnly || options.BMPOnly,
 			keepNames:                         options.KeepNames,
+			keepNamesOnly:                     options.KeepNamesOnly,
 			minifySyntax:                      options.MinifySyntax,
 			minifyIdentifiers:                 options.MinifyIdentifiers,
 			omitRuntimeForTests:               options.OmitRuntimeForTests,
 			ignoreDCEAnnotations:              options.IgnoreDCEAnnotations,
 			treeShaking:                       options.TreeShaking,
 			dropDebugger:                      options.DropDebugger,
+			dropDeadImports:                   options.DropDeadImports,
 			mangleQuoted:                      options.MangleQuoted,
 			unusedImportFlagsTS:               options.UnusedImportFlagsTS,
 			useDefineForClassFields:           options.UseDefineForClassFields,
+			emitDecoratorMetadata:             options.EmitDecoratorMetadata,
+			dynamicRequire:                    options.DynamicRequire,
+			preserveComments:                  options.PreserveComments,
+			errorRecovery:                     options.ErrorRecovery,
 		},
 	}
 }
 
+// Results from a cancelled build are incomplete and shouldn't be cached
+func (options *Options) DidCancel() bool {
+	return options.cancelFlag.DidCancel()
+}
+
 func (a *Options) Equal(b *Options) bool {
 	// Compare "optionsThatSupportStructuralEquality"
 	if a.optionsThatSupportStructuralEquality != b.optionsThatSupportStructuralEquality {
@@ -463,6 +530,11 @@ func (a *Options) Equal(b *Options) bool {
 		return false
 	}
 
+	// Compare "KeepNamesFilter"
+	if !isSameRegexp(a.keepNamesFilter, b.keepNamesFilter) {
+		return false
+	}
+
 	// Compare "InjectedFiles"
 	if len(a.injectedFiles) != len(b.injectedFiles) {
 		return false
@@ -480,7 +552,13 @@ func (a *Options) Equal(b *Options) bool {
 	}
 
 	// Compare "JSX"
-	if a.jsx.Parse != b.jsx.Parse || !jsxExprsEqual(a.jsx.Factory, b.jsx.Factory) || !jsxExprsEqual(a.jsx.Fragment, b.jsx.Fragment) {
+	if a.jsx.Parse != b.jsx.Parse || !jsxExprsEqual(a.jsx.Factory, b.jsx.Factory) || !jsxExprsEqual(a.jsx.Fragment, b.jsx.Fragment) ||
+		a.jsx.AutomaticRuntime != b.jsx.AutomaticRuntime || a.jsx.ImportSource != b.jsx.ImportSource || a.jsx.Development != b.jsx.Development {
+		return false
+	}
+
+	// Compare "Defines", which may be different for entry points with overrides
+	if (a.defines == nil) != (b.defines == nil) || (a.defines != nil && a.defines.Key != b.defines.Key) {
 		return false
 	}
 
@@ -565,6 +643,16 @@ type fnOrArrowDataParse struct {
 
 	// In TypeScript, forward declarations of functions have no bodies
 	allowMissingBodyForTypeScript bool
+
+	// If present, the types of the arguments and return value are stored here
+	tsFnMetadata *tsFnMetadata
+}
+
+// This is used for TypeScript's "emitDecoratorMetadata" setting
+type tsFnMetadata struct {
+	args          []tsTypeMetadata
+	returnType    tsTypeMetadata
+	hasReturnType bool
 }
 
 // This is function-specific information used during visiting. It is saved and
@@ -1446,6 +1534,11 @@ func (p *parser) recordUsage(ref js_ast.Ref) {
 		use := p.symbolUses[ref]
 		use.CountEstimate++
 		p.symbolUses[ref] = use
+	} else if p.options.dropDeadImports && p.options.mode == config.ModeBundle && p.symbols[ref.InnerIndex].Kind == js_ast.SymbolImport {
+		if p.importRefsUsedInDeadCode == nil {
+			p.importRefsUsedInDeadCode = make(map[js_ast.Ref]bool)
+		}
+		p.importRefsUsedInDeadCode[ref] = true
 	}
 
 	// The correctness of TypeScript-to-JavaScript conversion relies on accurate
@@ -1456,6 +1549,65 @@ func (p *parser) recordUsage(ref js_ast.Ref) {
 	}
 }
 
+// This returns true if none of the names imported by this import statement are
+// used outside of dead code, but at least one of them is used in dead code.
+// Imports without any uses at all are kept since they may be imported for
+// their side effects.
+func (p *parser) isImportOnlyUsedInDeadCode(s *js_ast.SImport) bool {
+	if p.importRefsUsedInDeadCode == nil || p.moduleScope.ContainsDirectEval {
+		return false
+	}
+
+	isUsedInDeadCode := false
+	check := func(ref js_ast.Ref) bool {
+		if p.symbols[ref.InnerIndex].UseCountEstimate != 0 {
+			return false
+		}
+		if p.importRefsUsedInDeadCode[ref] {
+			isUsedInDeadCode = true
+		}
+		return true
+	}
+
+	if s.DefaultName != nil && !check(s.DefaultName.Ref) {
+		return false
+	}
+	if s.StarNameLoc != nil {
+		if !check(s.NamespaceRef) {
+			return false
+		}
+		for _, item := range p.importItemsForNamespace[s.NamespaceRef] {
+			if !check(item.Ref) {
+				return false
+			}
+		}
+	}
+	if s.Items != nil {
+		for _, item := range *s.Items {
+			if !check(item.Name.Ref) {
+				return false
+			}
+		}
+	}
+	return isUsedInDeadCode
+}
+
+func (p *parser) markDroppedImportAsMissing(s *js_ast.SImport) {
+	if s.DefaultName != nil {
+		p.symbols[s.DefaultName.Ref.InnerIndex].ImportItemStatus = js_ast.ImportItemMissing
+	}
+	if s.StarNameLoc != nil {
+		for _, item := range p.importItemsForNamespace[s.NamespaceRef] {
+			p.symbols[item.Ref.InnerIndex].ImportItemStatus = js_ast.ImportItemMissing
+		}
+	}
+	if s.Items != nil {
+		for _, item := range *s.Items {
+			p.symbols[item.Name.Ref.InnerIndex].ImportItemStatus = js_ast.ImportItemMissing
+		}
+	}
+}
+
 func (p *parser) ignoreUsage(ref js_ast.Ref) {
 	// Roll back the use count increment in recordUsage()
 	if !p.isControlFlowDead {
@@ -1494,6 +1646,15 @@ func (p *parser) ignoreUsageOfIdentifierInDotChain(expr js_ast.Expr) {
 	}
 }
 
+func (p *parser) isNameDeclared(name string) bool {
+	for s := p.currentScope; s != nil; s = s.Parent {
+		if _, ok := s.Members[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *parser) importFromRuntime(loc logger.Loc, name string) js_ast.Expr {
 	ref, ok := p.runtimeImports[name]
 	if !ok {
@@ -1508,6 +1669,29 @@ func (p *parser) importFromRuntime(loc logger.Loc, name string) js_ast.Expr {
 	return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
 }
 
+// This returns a reference to an export of the automatic JSX runtime. The
+// import statements for these are generated after the whole file is visited.
+// Everything comes from "<importSource>/jsx-runtime" except for the fallback
+// to "createElement", which comes from "<importSource>" instead.
+func (p *parser) importJSXSymbol(loc logger.Loc, name string) js_ast.Expr {
+	imports := p.jsxRuntimeImports
+	if name == "createElement" {
+		imports = p.jsxLegacyImports
+	}
+	ref, ok := imports[name]
+	if !ok {
+		ref = p.newSymbol(js_ast.SymbolOther, name)
+		p.moduleScope.Generated = append(p.moduleScope.Generated, ref)
+		p.isImportItem[ref] = true
+		imports[name] = ref
+	}
+	p.recordUsage(ref)
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EImportIdentifier{
+		Ref:                     ref,
+		WasOriginallyIdentifier: true,
+	}}
+}
+
 func (p *parser) callRuntime(loc logger.Loc, name string, args []js_ast.Expr) js_ast.Expr {
 	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
 		Target: p.importFromRuntime(loc, name),
@@ -1517,7 +1701,7 @@ func (p *parser) callRuntime(loc logger.Loc, name string, args []js_ast.Expr) js
 
 func (p *parser) valueToSubstituteForRequire(loc logger.Loc) js_ast.Expr {
 	if p.source.Index != runtime.SourceIndex &&
-		config.ShouldCallRuntimeRequire(p.options.mode, p.options.outputFormat) {
+		config.ShouldCallRuntimeRequire(p.options.mode, p.options.platform, p.options.outputFormat) {
 		return p.importFromRuntime(loc, "__require")
 	}
 
@@ -1525,6 +1709,11 @@ func (p *parser) valueToSubstituteForRequire(loc logger.Loc) js_ast.Expr {
 	return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.requireRef}}
 }
 
+func (p *parser) isRuntimeRequireRef(ref js_ast.Ref) bool {
+	runtimeRef, ok := p.runtimeImports["__require"]
+	return ok && ref == runtimeRef
+}
+
 func (p *parser) makePromiseRef() js_ast.Ref {
 	if p.promiseRef == js_ast.InvalidRef {
 		p.promiseRef = p.newSymbol(js_ast.SymbolUnbound, "Promise")
@@ -1549,7 +1738,7 @@ func (p *parser) makeRegExpRef() js_ast.Ref {
 // can just store the slice and not need to allocate any extra memory. In the
 // rare case, the name is an externally-allocated string. In that case we store
 // an index to the string and use that index during the scope traversal pass.
-func (p *parser) storeNameInRef(name js_lexer.MaybeSubstring) js_ast.Ref {
+func (p *parser) storeNameInRef(name js_lexer.MaybeSubstring) (ref js_ast.Ref) {
 	// Is the data in "name" a subset of the data in "p.source.Contents"?
 	if name.Start.IsValid() {
 		// The name is a slice of the file contents, so we can just reference it by
@@ -1558,16 +1747,21 @@ func (p *parser) storeNameInRef(name js_lexer.MaybeSubstring) js_ast.Ref {
 		// It's stored as a negative value so we'll crash if we try to use it. That
 		// way we'll catch cases where we've forgotten to call loadNameFromRef().
 		// The length is the negative part because we know it's non-zero.
-		return js_ast.Ref{SourceIndex: -uint32(len(name.String)), InnerIndex: uint32(name.Start.GetIndex())}
+		ref = js_ast.Ref{SourceIndex: -uint32(len(name.String)), InnerIndex: uint32(name.Start.GetIndex())}
 	} else {
 		// The name is some memory allocated elsewhere. This is either an inline
 		// string constant in the parser or an identifier with escape sequences
 		// in the source code, which is very unusual. Stash it away for later.
 		// This uses allocations but it should hopefully be very uncommon.
-		ref := js_ast.Ref{SourceIndex: 0x80000000, InnerIndex: uint32(len(p.allocatedNames))}
+		ref = js_ast.Ref{SourceIndex: 0x80000000, InnerIndex: uint32(len(p.allocatedNames))}
 		p.allocatedNames = append(p.allocatedNames, name.String)
-		return ref
 	}
+
+	// Remember where this name was found so that it can be bound later
+	if p.namesInScope != nil {
+		p.namesInScope[ref] = NameInScope{Name: name.String, Scope: p.currentScope}
+	}
+	return
 }
 
 // This is the inverse of storeNameInRef() above
@@ -1721,10 +1915,11 @@ type propertyOpts struct {
 	isGenerator    bool
 
 	// Class-related options
-	isStatic        bool
-	isTSAbstract    bool
-	isClass         bool
-	classHasExtends bool
+	isStatic             bool
+	isTSAbstract         bool
+	isClass              bool
+	classHasExtends      bool
+	classHasTSDecorators bool
 }
 
 func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, opts propertyOpts, errors *deferredErrors) (js_ast.Property, bool) {
@@ -1978,9 +2173,16 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 		}
 
 		// Skip over types
+		var fieldType tsTypeMetadata
 		if p.options.ts.Parse && p.lexer.Token == js_lexer.TColon {
 			p.lexer.Next()
-			p.skipTypeScriptType(js_ast.LLowest)
+			fieldType = p.skipTypeScriptType(js_ast.LLowest)
+		}
+		var tsDecoratorMetadata []js_ast.TSDecoratorMetadata
+		if p.options.emitDecoratorMetadata && len(opts.tsDecorators) > 0 {
+			tsDecoratorMetadata = []js_ast.TSDecoratorMetadata{
+				{Key: "design:type", Value: p.tsTypeMetadataToExpr(key.Loc, fieldType)},
+			}
 		}
 
 		if p.lexer.Token == js_lexer.TEquals {
@@ -2022,12 +2224,13 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			flags |= js_ast.PropertyIsStatic
 		}
 		return js_ast.Property{
-			TSDecorators:     opts.tsDecorators,
-			Loc:              startLoc,
-			Kind:             kind,
-			Flags:            flags,
-			Key:              key,
-			InitializerOrNil: initializerOrNil,
+			TSDecorators:        opts.tsDecorators,
+			TSDecoratorMetadata: tsDecoratorMetadata,
+			Loc:                 startLoc,
+			Kind:                kind,
+			Flags:               flags,
+			Key:                 key,
+			InitializerOrNil:    initializerOrNil,
 		}, true
 	}
 
@@ -2082,6 +2285,11 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			yield = allowExpr
 		}
 
+		var fnMetadata *tsFnMetadata
+		if p.options.emitDecoratorMetadata && opts.isClass {
+			fnMetadata = &tsFnMetadata{}
+		}
+
 		fn, hadBody := p.parseFn(nil, opts.classKeyword, fnOrArrowDataParse{
 			needsAsyncLoc:      key.Loc,
 			asyncRange:         opts.asyncRange,
@@ -2091,6 +2299,7 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			allowSuperProperty: true,
 			tsDecoratorScope:   opts.tsDecoratorScope,
 			isConstructor:      isConstructor,
+			tsFnMetadata:       fnMetadata,
 
 			// Only allow omitting the body if we're parsing TypeScript class
 			allowMissingBodyForTypeScript: p.options.ts.Parse && opts.isClass,
@@ -2165,16 +2374,22 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			}
 		}
 
+		var tsDecoratorMetadata []js_ast.TSDecoratorMetadata
+		if fnMetadata != nil {
+			tsDecoratorMetadata = p.tsDecoratorMetadataForMethod(key.Loc, kind, fn, *fnMetadata, opts, isConstructor)
+		}
+
 		if opts.isStatic {
 			flags |= js_ast.PropertyIsStatic
 		}
 		return js_ast.Property{
-			TSDecorators: opts.tsDecorators,
-			Loc:          startLoc,
-			Kind:         kind,
-			Flags:        flags | js_ast.PropertyIsMethod,
-			Key:          key,
-			ValueOrNil:   value,
+			TSDecorators:        opts.tsDecorators,
+			TSDecoratorMetadata: tsDecoratorMetadata,
+			Loc:                 startLoc,
+			Kind:                kind,
+			Flags:               flags | js_ast.PropertyIsMethod,
+			Key:                 key,
+			ValueOrNil:          value,
 		}, true
 	}
 
@@ -2810,11 +3025,15 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 			invalidLog = log
 			items = append(items, js_ast.ArrayBinding{Binding: binding, DefaultValueOrNil: initializerOrNil})
 		}
-		return js_ast.Binding{Loc: expr.Loc, Data: &js_ast.BArray{
+		binding := js_ast.Binding{Loc: expr.Loc, Data: &js_ast.BArray{
 			Items:        items,
 			HasSpread:    isSpread,
 			IsSingleLine: e.IsSingleLine,
-		}}, invalidLog
+		}}
+		if p.nodeEnds != nil {
+			p.nodeEnds[binding.Data] = e.CloseBracketLoc.Start + 1
+		}
+		return binding, invalidLog
 
 	case *js_ast.EObject:
 		if e.CommaAfterSpread.Start != 0 {
@@ -2840,10 +3059,14 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 				DefaultValueOrNil: initializerOrNil,
 			})
 		}
-		return js_ast.Binding{Loc: expr.Loc, Data: &js_ast.BObject{
+		binding := js_ast.Binding{Loc: expr.Loc, Data: &js_ast.BObject{
 			Properties:   properties,
 			IsSingleLine: e.IsSingleLine,
-		}}, invalidLog
+		}}
+		if p.nodeEnds != nil {
+			p.nodeEnds[binding.Data] = e.CloseBraceLoc.Start + 1
+		}
+		return binding, invalidLog
 
 	default:
 		invalidLog.invalidTokens = append(invalidLog.invalidTokens, logger.Range{Loc: expr.Loc})
@@ -3591,6 +3814,11 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 	optionalChain := js_ast.OptionalChainNone
 
 	for {
+		// Each time around the loop, "left" is a complete expression
+		if p.nodeEnds != nil {
+			p.recordNodeEnd(left.Data)
+		}
+
 		if p.lexer.Loc() == p.afterArrowBodyLoc {
 			for {
 				switch p.lexer.Token {
@@ -3600,6 +3828,9 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 					}
 					p.lexer.Next()
 					left = js_ast.Expr{Loc: left.Loc, Data: &js_ast.EBinary{Op: js_ast.BinOpComma, Left: left, Right: p.parseExpr(js_ast.LComma)}}
+					if p.nodeEnds != nil {
+						p.recordNodeEnd(left.Data)
+					}
 
 				default:
 					return left
@@ -5121,11 +5352,15 @@ func (p *parser) parseBinding() js_ast.Binding {
 			isSingleLine = false
 		}
 		p.lexer.Expect(js_lexer.TCloseBracket)
-		return js_ast.Binding{Loc: loc, Data: &js_ast.BArray{
+		binding := js_ast.Binding{Loc: loc, Data: &js_ast.BArray{
 			Items:        items,
 			HasSpread:    hasSpread,
 			IsSingleLine: isSingleLine,
 		}}
+		if p.nodeEnds != nil {
+			p.recordNodeEnd(binding.Data)
+		}
+		return binding
 
 	case js_lexer.TOpenBrace:
 		p.markSyntaxFeature(compat.Destructuring, p.lexer.Range())
@@ -5165,10 +5400,14 @@ func (p *parser) parseBinding() js_ast.Binding {
 			isSingleLine = false
 		}
 		p.lexer.Expect(js_lexer.TCloseBrace)
-		return js_ast.Binding{Loc: loc, Data: &js_ast.BObject{
+		binding := js_ast.Binding{Loc: loc, Data: &js_ast.BObject{
 			Properties:   properties,
 			IsSingleLine: isSingleLine,
 		}}
+		if p.nodeEnds != nil {
+			p.recordNodeEnd(binding.Data)
+		}
+		return binding
 	}
 
 	p.lexer.Expect(js_lexer.TIdentifier)
@@ -5322,9 +5561,22 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 			}
 
 			// "function foo(a: any) {}"
+			var argType tsTypeMetadata
 			if p.lexer.Token == js_lexer.TColon {
 				p.lexer.Next()
-				p.skipTypeScriptType(js_ast.LLowest)
+				argType = p.skipTypeScriptType(js_ast.LLowest)
+			}
+
+			// The metadata for a rest argument is the type of its elements
+			if data.tsFnMetadata != nil {
+				if fn.HasRestArg {
+					if argType.elementOrNil != nil {
+						argType = *argType.elementOrNil
+					} else {
+						argType = tsTypeMetadata{}
+					}
+				}
+				data.tsFnMetadata.args = append(data.tsFnMetadata.args, argType)
 			}
 		}
 
@@ -5377,7 +5629,11 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 	// "function foo(): any {}"
 	if p.options.ts.Parse && p.lexer.Token == js_lexer.TColon {
 		p.lexer.Next()
-		p.skipTypeScriptReturnType()
+		returnType := p.skipTypeScriptReturnType()
+		if data.tsFnMetadata != nil {
+			data.tsFnMetadata.returnType = returnType
+			data.tsFnMetadata.hasReturnType = true
+		}
 	}
 
 	// "function foo(): any;"
@@ -5527,6 +5783,8 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 		tsDecoratorScope: classOpts.tsDecoratorScope,
 		classHasExtends:  extendsOrNil.Data != nil,
 		classKeyword:     classKeyword,
+
+		classHasTSDecorators: len(classOpts.tsDecorators) > 0,
 	}
 	hasConstructor := false
 
@@ -5808,7 +6066,16 @@ type parseStmtOpts struct {
 	allowDirectivePrologue bool
 }
 
-func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
+func (p *parser) parseStmt(opts parseStmtOpts) (result js_ast.Stmt) {
+	// Remember where each statement ends when building a syntax tree
+	if p.nodeEnds != nil {
+		defer func() {
+			if result.Data != nil {
+				p.recordNodeEnd(result.Data)
+			}
+		}()
+	}
+
 	loc := p.lexer.Loc()
 
 	switch p.lexer.Token {
@@ -6201,6 +6468,9 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		p.lexer.Next()
 
 		if p.options.ts.Parse && p.lexer.Token == js_lexer.TEnum {
+			if opts.isTypeScriptDeclare && p.options.ts.IsolatedModulesCheck {
+				p.warnAboutAmbientConstEnum()
+			}
 			return p.parseTypeScriptEnumStmt(loc, opts)
 		}
 
@@ -6284,6 +6554,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		for p.lexer.Token != js_lexer.TCloseBrace {
 			var value js_ast.Expr
 			body := []js_ast.Stmt{}
+			caseLoc := p.lexer.Loc()
 
 			if p.lexer.Token == js_lexer.TDefault {
 				if foundDefault {
@@ -6310,7 +6581,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 				}
 			}
 
-			cases = append(cases, js_ast.Case{ValueOrNil: value, Body: body})
+			cases = append(cases, js_ast.Case{ValueOrNil: value, Body: body, Loc: caseLoc})
 		}
 
 		p.lexer.Expect(js_lexer.TCloseBrace)
@@ -6980,6 +7251,435 @@ func extractDeclsForBinding(binding js_ast.Binding, decls []js_ast.Decl) []js_as
 	return decls
 }
 
+func (p *parser) isUnboundIdentifier(expr js_ast.Expr, name string) bool {
+	if id, ok := expr.Data.(*js_ast.EIdentifier); ok {
+		symbol := &p.symbols[id.Ref.InnerIndex]
+		return symbol.Kind == js_ast.SymbolUnbound && symbol.OriginalName == name
+	}
+	return false
+}
+
+// This returns the import path for "new URL(path, import.meta.url)" if the
+// path is a relative URL. This is checked before the arguments are visited
+// because visiting may replace "import.meta" with something else.
+func importPathForNewURL(args []js_ast.Expr) (string, bool) {
+	if len(args) != 2 {
+		return "", false
+	}
+	if dot, ok := args[1].Data.(*js_ast.EDot); !ok || dot.Name != "url" {
+		return "", false
+	} else if _, ok := dot.Target.Data.(*js_ast.EImportMeta); !ok {
+		return "", false
+	}
+	str, ok := args[0].Data.(*js_ast.EString)
+	if !ok {
+		return "", false
+	}
+
+	// URLs are relative to the current module, so a bare name such as
+	// "worker.js" is a relative path instead of a package path. Absolute URLs
+	// and URLs with a scheme aren't bundled.
+	path := helpers.UTF16ToString(str.Value)
+	if path == "" || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "#") || strings.HasPrefix(path, "?") {
+		return "", false
+	}
+	if colon := strings.IndexByte(path, ':'); colon != -1 && !strings.ContainsRune(path[:colon], '/') {
+		return "", false
+	}
+	if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+		path = "./" + path
+	}
+	return path, true
+}
+
+// Workers are bundled as separate entry points instead of being used as
+// assets, so mark the import record for the URL passed to "new Worker()"
+func (p *parser) maybeMarkNewURLAsWorker(expr js_ast.Expr) {
+	if newURL, ok := expr.Data.(*js_ast.ENew); ok && len(newURL.Args) == 2 {
+		if str, ok := newURL.Args[0].Data.(*js_ast.ENewURLString); ok {
+			p.importRecords[str.ImportRecordIndex].Flags |= ast.IsWorkerURL
+		}
+	}
+}
+
+// This expands "import.meta.glob()" into an object literal with one property
+// for each matching file. The keys are the import paths and the values are
+// functions that import the file (or the imported modules themselves if the
+// "eager" option is set):
+//
+//   // Before
+//   const modules = import.meta.glob('./pages/*.js')
+//
+//   // After
+//   const modules = {
+//     './pages/a.js': () => import('./pages/a.js'),
+//     './pages/b.js': () => import('./pages/b.js'),
+//   }
+//
+// The arguments must be literals because the files are found at compile time.
+func (p *parser) visitImportMetaGlob(loc logger.Loc, call *js_ast.ECall) js_ast.Expr {
+	var patterns []string
+	var negations []string
+	var importName string
+	isEager := false
+	ok := true
+
+	addPattern := func(expr js_ast.Expr) {
+		str, isString := expr.Data.(*js_ast.EString)
+		if !isString {
+			p.log.AddError(&p.tracker, logger.Range{Loc: expr.Loc},
+				"Glob patterns passed to \"import.meta.glob\" must be string literals")
+			ok = false
+			return
+		}
+		pattern := helpers.UTF16ToString(str.Value)
+		isNegation := strings.HasPrefix(pattern, "!")
+		if isNegation {
+			pattern = pattern[1:]
+		}
+		if !strings.HasPrefix(pattern, "./") && !strings.HasPrefix(pattern, "../") {
+			p.log.AddErrorWithNotes(&p.tracker, p.source.RangeOfString(expr.Loc),
+				fmt.Sprintf("The glob pattern %q must be a relative path", pattern),
+				[]logger.MsgData{{Text: "Glob patterns must start with \"./\" or \"../\"."}})
+			ok = false
+			return
+		}
+		if isNegation {
+			negations = append(negations, helpers.ExpandGlobBraces(pattern)...)
+		} else {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	// Parse the patterns
+	if len(call.Args) < 1 || len(call.Args) > 2 {
+		p.log.AddError(&p.tracker, js_lexer.RangeOfIdentifier(p.source, loc),
+			"\"import.meta.glob\" must be called with one or two arguments")
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EObject{}}
+	}
+	patternLoc := call.Args[0].Loc
+	if array, isArray := call.Args[0].Data.(*js_ast.EArray); isArray {
+		for _, item := range array.Items {
+			addPattern(item)
+		}
+	} else {
+		addPattern(call.Args[0])
+	}
+
+	// Parse the options
+	if len(call.Args) == 2 {
+		if object, isObject := call.Args[1].Data.(*js_ast.EObject); !isObject {
+			p.log.AddError(&p.tracker, logger.Range{Loc: call.Args[1].Loc},
+				"The options passed to \"import.meta.glob\" must be an object literal")
+			ok = false
+		} else {
+			for _, property := range object.Properties {
+				key, isString := property.Key.Data.(*js_ast.EString)
+				if property.Kind != js_ast.PropertyNormal || property.Flags.Has(js_ast.PropertyIsComputed) ||
+					property.Flags.Has(js_ast.PropertyIsMethod) || !isString {
+					p.log.AddError(&p.tracker, logger.Range{Loc: property.Key.Loc},
+						"Invalid \"import.meta.glob\" option")
+					ok = false
+					continue
+				}
+				switch name := helpers.UTF16ToString(key.Value); name {
+				case "eager":
+					if value, isBool := property.ValueOrNil.Data.(*js_ast.EBoolean); isBool {
+						isEager = value.Value
+						continue
+					}
+					p.log.AddError(&p.tracker, logger.Range{Loc: property.ValueOrNil.Loc},
+						"The \"eager\" option for \"import.meta.glob\" must be a boolean literal")
+					ok = false
+
+				case "import":
+					if value, isString := property.ValueOrNil.Data.(*js_ast.EString); isString {
+						importName = helpers.UTF16ToString(value.Value)
+						continue
+					}
+					p.log.AddError(&p.tracker, logger.Range{Loc: property.ValueOrNil.Loc},
+						"The \"import\" option for \"import.meta.glob\" must be a string literal")
+					ok = false
+
+				default:
+					p.log.AddError(&p.tracker, p.source.RangeOfString(property.Key.Loc),
+						fmt.Sprintf("Unsupported \"import.meta.glob\" option %q", name))
+					ok = false
+				}
+			}
+		}
+	}
+
+	// Don't scan the file system if the control flow is provably dead here
+	if !ok || p.isControlFlowDead {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EObject{}}
+	}
+
+	// Expand the patterns, then remove anything matched by a negated pattern
+	p.usesImportMetaGlob = true
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range patterns {
+	nextPath:
+		for _, path := range p.options.expandGlob(pattern) {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			for _, negation := range negations {
+				if helpers.GlobMatch(negation, path) {
+					continue nextPath
+				}
+			}
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	properties := make([]js_ast.Property, 0, len(paths))
+	for _, path := range paths {
+		var value js_ast.Expr
+
+		if isEager {
+			// Eager imports become import statements at the top of the file
+			importRecordIndex := p.addImportRecord(ast.ImportStmt, patternLoc, path, nil)
+			namespaceRef := p.newSymbol(js_ast.SymbolOther, "import_"+js_ast.GenerateNonUniqueNameFromPath(path))
+			p.moduleScope.Generated = append(p.moduleScope.Generated, namespaceRef)
+			declaredSymbols := []js_ast.DeclaredSymbol{{Ref: namespaceRef, IsTopLevel: true}}
+			itemRefs := make(map[string]js_ast.LocRef)
+			s := &js_ast.SImport{NamespaceRef: namespaceRef, ImportRecordIndex: importRecordIndex}
+
+			if importName == "" {
+				s.StarNameLoc = &logger.Loc{Start: patternLoc.Start}
+				p.recordUsage(namespaceRef)
+				value = js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: namespaceRef}}
+			} else {
+				itemRef := p.newSymbol(js_ast.SymbolOther, js_ast.GenerateNonUniqueNameFromPath(path))
+				p.moduleScope.Generated = append(p.moduleScope.Generated, itemRef)
+				p.isImportItem[itemRef] = true
+				itemRefs[importName] = js_ast.LocRef{Loc: patternLoc, Ref: itemRef}
+				declaredSymbols = append(declaredSymbols, js_ast.DeclaredSymbol{Ref: itemRef, IsTopLevel: true})
+				s.Items = &[]js_ast.ClauseItem{{Alias: importName, AliasLoc: patternLoc, Name: js_ast.LocRef{Loc: patternLoc, Ref: itemRef}}}
+				p.recordUsage(itemRef)
+				value = js_ast.Expr{Loc: loc, Data: &js_ast.EImportIdentifier{Ref: itemRef}}
+			}
+
+			p.importItemsForNamespace[namespaceRef] = itemRefs
+			p.importMetaGlobParts = append(p.importMetaGlobParts, js_ast.Part{
+				DeclaredSymbols: declaredSymbols,
+				Stmts:           []js_ast.Stmt{{Loc: patternLoc, Data: s}},
+			})
+		} else {
+			// Lazy imports become functions that call "import()"
+			importRecordIndex := p.addImportRecord(ast.ImportDynamic, patternLoc, path, nil)
+			p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
+			value = js_ast.Expr{Loc: loc, Data: &js_ast.EImportString{ImportRecordIndex: importRecordIndex}}
+			if importName != "" {
+				value = p.callRuntime(loc, "__importGlobName", []js_ast.Expr{value,
+					{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(importName)}}})
+			}
+			body := js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: value}}}}}
+			if p.options.unsupportedJSFeatures.Has(compat.Arrow) {
+				value = js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: body}}}
+			} else {
+				value = js_ast.Expr{Loc: loc, Data: &js_ast.EArrow{Body: body, PreferExpr: true}}
+			}
+		}
+
+		properties = append(properties, js_ast.Property{
+			Key:        js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(path)}},
+			ValueOrNil: value,
+		})
+	}
+
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EObject{Properties: properties, IsSingleLine: len(properties) < 2}}
+}
+
+// This handles a "require()" call with a non-constant argument when the
+// "context" policy for dynamic requires is enabled. The argument is turned
+// into a glob pattern and every matching file is bundled. The call is then
+// replaced with a lookup in a map from import path to module:
+//
+//   // Before
+//   const plugin = require('./plugins/' + name)
+//
+//   // After
+//   const plugin = __requireContext({
+//     './plugins/a.js': () => require('./plugins/a.js'),
+//     './plugins/a': () => require('./plugins/a.js'),
+//   })('./plugins/' + name)
+//
+// Import paths that aren't in the map fall back to "require()" at run-time.
+func (p *parser) maybeRequireContext(loc logger.Loc, arg js_ast.Expr) (js_ast.Expr, bool) {
+	if p.options.expandGlob == nil {
+		return js_ast.Expr{}, false
+	}
+	pattern, ok := p.requireContextPattern(arg)
+	if !ok {
+		return js_ast.Expr{}, false
+	}
+
+	// Don't scan the file system if the control flow is provably dead here
+	if p.isControlFlowDead {
+		return js_ast.Expr{Loc: loc, Data: js_ast.ENullShared}, true
+	}
+
+	// If the pattern doesn't end with a file extension, only match the files
+	// that "require()" would be able to resolve. These can also be required
+	// without the file extension.
+	var extensions []string
+	if strings.HasSuffix(pattern, "*") && len(p.options.resolveExtensions) > 0 {
+		extensions = p.options.resolveExtensions
+		pattern += "{" + strings.Join(extensions, ",") + "}"
+	}
+
+	// The file system is scanned, so this must not be reused from the cache
+	p.usesImportMetaGlob = true
+	paths := p.options.expandGlob(pattern)
+	properties := make([]js_ast.Property, 0, len(paths))
+	recordForPath := make(map[string]uint32)
+	addProperty := func(key string, importRecordIndex uint32) {
+		body := js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SReturn{
+			ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.ERequireString{ImportRecordIndex: importRecordIndex}}}}}}}
+		var value js_ast.Expr
+		if p.options.unsupportedJSFeatures.Has(compat.Arrow) {
+			value = js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: body}}}
+		} else {
+			value = js_ast.Expr{Loc: loc, Data: &js_ast.EArrow{Body: body, PreferExpr: true}}
+		}
+		properties = append(properties, js_ast.Property{
+			Key:        js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(key)}},
+			ValueOrNil: value,
+		})
+	}
+	for _, path := range paths {
+		importRecordIndex := p.addImportRecord(ast.ImportRequire, arg.Loc, path, nil)
+		p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
+		recordForPath[path] = importRecordIndex
+		addProperty(path, importRecordIndex)
+	}
+
+	// Extensions are tried in order, so earlier extensions take precedence
+	for _, ext := range extensions {
+		for _, path := range paths {
+			if strings.HasSuffix(path, ext) {
+				alias := path[:len(path)-len(ext)]
+				if _, ok := recordForPath[alias]; !ok {
+					recordForPath[alias] = recordForPath[path]
+					addProperty(alias, recordForPath[path])
+				}
+			}
+		}
+	}
+
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: p.callRuntime(loc, "__requireContext", []js_ast.Expr{{Loc: loc, Data: &js_ast.EObject{Properties: properties}}}),
+		Args:   []js_ast.Expr{arg},
+	}}, true
+}
+
+// This converts the argument of a "require()" call into a glob pattern. The
+// contents of strings and template literals are kept and everything else is
+// replaced with a wildcard that matches within a single path segment:
+//
+//   require('./locale/' + lang + '.json')          => "./locale/*.json"
+//   require(`./commands/${name}`)                  => "./commands/*"
+//   require(path.join(__dirname, 'plugins', name)) => "./plugins/*"
+//   require(__dirname + '/plugins/' + name)        => "./plugins/*"
+//
+// A leading "__dirname" refers to the directory of the current file. The
+// pattern must be a relative path that starts with "./" or "../".
+func (p *parser) requireContextPattern(arg js_ast.Expr) (string, bool) {
+	sb := strings.Builder{}
+	endsWithWildcard := false
+	ok := true
+
+	addText := func(text string) {
+		if strings.ContainsAny(text, "*?{}[]") {
+			ok = false // Glob syntax can't be escaped
+		}
+		if text != "" {
+			sb.WriteString(text)
+			endsWithWildcard = false
+		}
+	}
+	addWildcard := func() {
+		if !endsWithWildcard {
+			sb.WriteByte('*')
+			endsWithWildcard = true
+		}
+	}
+	isDirname := func(expr js_ast.Expr) bool {
+		if id, ok := expr.Data.(*js_ast.EIdentifier); ok {
+			symbol := p.symbols[id.Ref.InnerIndex]
+			return symbol.Kind == js_ast.SymbolUnbound && symbol.OriginalName == "__dirname"
+		}
+		return false
+	}
+
+	var visit func(expr js_ast.Expr)
+	visit = func(expr js_ast.Expr) {
+		switch e := expr.Data.(type) {
+		case *js_ast.EString:
+			addText(helpers.UTF16ToString(e.Value))
+
+		case *js_ast.ETemplate:
+			if e.TagOrNil.Data != nil {
+				addWildcard()
+				return
+			}
+			addText(helpers.UTF16ToString(e.HeadCooked))
+			for _, part := range e.Parts {
+				visit(part.Value)
+				addText(helpers.UTF16ToString(part.TailCooked))
+			}
+
+		case *js_ast.EBinary:
+			if e.Op != js_ast.BinOpAdd {
+				addWildcard()
+				return
+			}
+			visit(e.Left)
+			visit(e.Right)
+
+		case *js_ast.ECall:
+			// Handle "path.join(__dirname, ...)" and "path.resolve(__dirname, ...)"
+			if dot, ok := e.Target.Data.(*js_ast.EDot); ok && (dot.Name == "join" || dot.Name == "resolve") &&
+				sb.Len() == 0 && len(e.Args) > 0 && isDirname(e.Args[0]) {
+				addText(".")
+				for _, arg := range e.Args[1:] {
+					addText("/")
+					visit(arg)
+				}
+				return
+			}
+			addWildcard()
+
+		default:
+			if sb.Len() == 0 && isDirname(expr) {
+				addText(".")
+				return
+			}
+			addWildcard()
+		}
+	}
+	visit(arg)
+
+	// Clean up the path separators
+	pattern := sb.String()
+	for strings.Contains(pattern, "//") {
+		pattern = strings.ReplaceAll(pattern, "//", "/")
+	}
+	for strings.Contains(pattern, "/./") {
+		pattern = strings.ReplaceAll(pattern, "/./", "/")
+	}
+
+	if !ok || (!strings.HasPrefix(pattern, "./") && !strings.HasPrefix(pattern, "../")) {
+		return "", false
+	}
+	return pattern, true
+}
+
 func (p *parser) addImportRecord(kind ast.ImportKind, loc logger.Loc, text string, assertions *[]ast.AssertEntry) uint32 {
 	index := uint32(len(p.importRecords))
 	p.importRecords = append(p.importRecords, ast.ImportRecord{
@@ -6997,25 +7697,154 @@ func (p *parser) parseFnBody(data fnOrArrowDataParse) js_ast.FnBody {
 	p.fnOrArrowDataParse = data
 	p.allowIn = true
 
-	loc := p.lexer.Loc()
-	p.pushScopeForParsePass(js_ast.ScopeFunctionBody, loc)
-	defer p.popScope()
+	loc := p.lexer.Loc()
+	p.pushScopeForParsePass(js_ast.ScopeFunctionBody, loc)
+	defer p.popScope()
+
+	p.lexer.Expect(js_lexer.TOpenBrace)
+	stmts := p.parseStmtsUpTo(js_lexer.TCloseBrace, parseStmtOpts{
+		allowDirectivePrologue: true,
+	})
+	closeBraceLoc := p.lexer.Loc()
+	p.lexer.Next()
+
+	p.allowIn = oldAllowIn
+	p.fnOrArrowDataParse = oldFnOrArrowData
+	return js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: stmts, CloseBraceLoc: closeBraceLoc}}
+}
+
+func (p *parser) forbidLexicalDecl(loc logger.Loc) {
+	r := js_lexer.RangeOfIdentifier(p.source, loc)
+	p.log.AddError(&p.tracker, r, "Cannot use a declaration in a single-statement context")
+}
+
+// This parses a statement but recovers from syntax errors instead of aborting
+// the whole parse. If there was a syntax error, the statement is dropped, the
+// parser state is restored to what it was before the statement, and the lexer
+// is moved to what looks like the start of the next statement.
+func (p *parser) parseStmtWithErrorRecovery(opts parseStmtOpts, end js_lexer.T) (stmt js_ast.Stmt, ok bool) {
+	scopeIndex := len(p.scopesInOrder)
+	symbolCount := len(p.symbols)
+	importRecordCount := len(p.importRecords)
+	importRecordsForCurrentPartCount := len(p.importRecordsForCurrentPart)
+	exportStarImportRecordCount := len(p.exportStarImportRecords)
+	currentScope := p.currentScope
+	fnOrArrowDataParse := p.fnOrArrowDataParse
+	allowIn := p.allowIn
+	isLogDisabled := p.lexer.IsLogDisabled
+
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); !isLexerPanic {
+			if r != nil {
+				panic(r)
+			}
+			return
+		}
+
+		// Don't recover from cancellation
+		if p.options.cancelFlag.DidCancel() {
+			panic(r)
+		}
+
+		// Unwind any scopes that were pushed while parsing this statement so
+		// that the scope order still matches the AST during the visit pass
+		for i := len(p.scopesInOrder) - 1; i >= scopeIndex; i-- {
+			scope := p.scopesInOrder[i].scope
+			if parent := scope.Parent; parent != nil {
+				if last := len(parent.Children) - 1; last >= 0 && parent.Children[last] == scope {
+					parent.Children = parent.Children[:last]
+				}
+			}
+		}
+		p.scopesInOrder = p.scopesInOrder[:scopeIndex]
+		p.currentScope = currentScope
+
+		// Forget about any symbols and import records that were created for the
+		// dropped statement. Otherwise a later statement that declares the same
+		// name would be reported as a duplicate declaration, and the linker would
+		// try to resolve imports that aren't in the AST.
+		for name, member := range currentScope.Members {
+			if member.Ref.InnerIndex >= uint32(symbolCount) {
+				delete(currentScope.Members, name)
+			}
+		}
+		for i, ref := range currentScope.Generated {
+			if ref.InnerIndex >= uint32(symbolCount) {
+				currentScope.Generated = currentScope.Generated[:i]
+				break
+			}
+		}
+		for ref := range p.isImportItem {
+			if ref.InnerIndex >= uint32(symbolCount) {
+				delete(p.isImportItem, ref)
+			}
+		}
+		for ref := range p.importItemsForNamespace {
+			if ref.InnerIndex >= uint32(symbolCount) {
+				delete(p.importItemsForNamespace, ref)
+			}
+		}
+		p.symbols = p.symbols[:symbolCount]
+		if p.options.ts.Parse {
+			p.tsUseCounts = p.tsUseCounts[:symbolCount]
+		}
+		p.importRecords = p.importRecords[:importRecordCount]
+		p.importRecordsForCurrentPart = p.importRecordsForCurrentPart[:importRecordsForCurrentPartCount]
+		p.exportStarImportRecords = p.exportStarImportRecords[:exportStarImportRecordCount]
+
+		p.fnOrArrowDataParse = fnOrArrowDataParse
+		p.allowIn = allowIn
+		p.lexer.IsLogDisabled = isLogDisabled
+
+		p.skipToNextStmtAfterSyntaxError(end)
+		ok = false
+	}()
+
+	return p.parseStmt(opts), true
+}
+
+// Skip tokens until a ";" at the same nesting level, the token that ends the
+// enclosing statement list, or a token on a new line. Brackets are tracked so
+// that a "}" that closes the enclosing block isn't skipped over. The token
+// where the error happened is always skipped to ensure forward progress.
+func (p *parser) skipToNextStmtAfterSyntaxError(end js_lexer.T) {
+	depth := 0
+	isFirstToken := true
+
+	for {
+		switch p.lexer.Token {
+		case js_lexer.TEndOfFile:
+			// The lexer may have stopped in the middle of something it couldn't lex
+			if int(p.lexer.Loc().Start) >= len(p.source.Contents) {
+				return
+			}
+
+		case js_lexer.TSemicolon:
+			if depth == 0 {
+				p.lexer.NextAfterSyntaxError()
+				return
+			}
 
-	p.lexer.Expect(js_lexer.TOpenBrace)
-	stmts := p.parseStmtsUpTo(js_lexer.TCloseBrace, parseStmtOpts{
-		allowDirectivePrologue: true,
-	})
-	closeBraceLoc := p.lexer.Loc()
-	p.lexer.Next()
+		case js_lexer.TOpenBrace, js_lexer.TOpenParen, js_lexer.TOpenBracket, js_lexer.TTemplateHead:
+			depth++
 
-	p.allowIn = oldAllowIn
-	p.fnOrArrowDataParse = oldFnOrArrowData
-	return js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: stmts, CloseBraceLoc: closeBraceLoc}}
-}
+		case js_lexer.TCloseBrace, js_lexer.TCloseParen, js_lexer.TCloseBracket:
+			if depth > 0 {
+				depth--
+			} else if p.lexer.Token == end {
+				return
+			}
 
-func (p *parser) forbidLexicalDecl(loc logger.Loc) {
-	r := js_lexer.RangeOfIdentifier(p.source, loc)
-	p.log.AddError(&p.tracker, r, "Cannot use a declaration in a single-statement context")
+		default:
+			if depth == 0 && !isFirstToken && p.lexer.HasNewlineBefore {
+				return
+			}
+		}
+
+		isFirstToken = false
+		p.lexer.NextAfterSyntaxError()
+	}
 }
 
 func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stmt {
@@ -7025,6 +7854,8 @@ func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stm
 	isDirectivePrologue := opts.allowDirectivePrologue
 
 	for {
+		p.checkForCancellation()
+
 		// Preserve some statement-level comments
 		comments := p.lexer.CommentsToPreserveBefore
 		if len(comments) > 0 {
@@ -7033,7 +7864,7 @@ func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stm
 					Loc: comment.Loc,
 					Data: &js_ast.SComment{
 						Text:           comment.Text,
-						IsLegalComment: true,
+						IsLegalComment: comment.IsLegalComment,
 					},
 				})
 			}
@@ -7043,7 +7874,20 @@ func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stm
 			break
 		}
 
-		stmt := p.parseStmt(opts)
+		var stmt js_ast.Stmt
+		if p.options.errorRecovery {
+			var ok bool
+			if stmt, ok = p.parseStmtWithErrorRecovery(opts, end); !ok {
+				// The missing end token was already reported as an unexpected end of
+				// file, so end this statement list early and keep what was parsed
+				if p.lexer.Token == js_lexer.TEndOfFile {
+					break
+				}
+				continue
+			}
+		} else {
+			stmt = p.parseStmt(opts)
+		}
 
 		// Skip TypeScript types entirely
 		if p.options.ts.Parse {
@@ -8462,7 +9306,7 @@ func (p *parser) visitBinding(binding js_ast.Binding, opts bindingOpts) {
 				// Optionally preserve the name
 				if id, ok := item.Binding.Data.(*js_ast.BIdentifier); ok {
 					item.DefaultValueOrNil = p.maybeKeepExprSymbolName(
-						item.DefaultValueOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, wasAnonymousNamedExpr)
+						item.DefaultValueOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, id.Ref, wasAnonymousNamedExpr)
 				}
 			}
 		}
@@ -8486,7 +9330,7 @@ func (p *parser) visitBinding(binding js_ast.Binding, opts bindingOpts) {
 				// Optionally preserve the name
 				if id, ok := property.Value.Data.(*js_ast.BIdentifier); ok {
 					property.DefaultValueOrNil = p.maybeKeepExprSymbolName(
-						property.DefaultValueOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, wasAnonymousNamedExpr)
+						property.DefaultValueOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, id.Ref, wasAnonymousNamedExpr)
 				}
 			}
 			b.Properties[i] = property
@@ -8926,13 +9770,90 @@ func (p *parser) isAnonymousNamedExpr(expr js_ast.Expr) bool {
 	return false
 }
 
-func (p *parser) maybeKeepExprSymbolName(value js_ast.Expr, name string, wasAnonymousNamedExpr bool) js_ast.Expr {
-	if p.options.keepNames && wasAnonymousNamedExpr {
-		return p.keepExprSymbolName(value, name)
+// The symbol is only used to check for exports and may be invalid
+func (p *parser) maybeKeepExprSymbolName(value js_ast.Expr, name string, ref js_ast.Ref, wasAnonymousNamedExpr bool) js_ast.Expr {
+	if wasAnonymousNamedExpr {
+		// The value has already been visited, so a class may have been lowered
+		// into something else. Arrow functions and functions stay as they are.
+		isClass := true
+		switch value.Data.(type) {
+		case *js_ast.EArrow, *js_ast.EFunction:
+			isClass = false
+		}
+		if p.shouldKeepName(name, ref, isClass) {
+			return p.keepExprSymbolName(value, name)
+		}
 	}
 	return value
 }
 
+// This checks "keepNames" along with any restrictions on which names to keep
+func (p *parser) shouldKeepName(name string, ref js_ast.Ref, isClass bool) bool {
+	if !p.options.keepNames {
+		return false
+	}
+	only := p.options.keepNamesOnly
+	if only.Has(config.KeepNamesOnlyClasses) != only.Has(config.KeepNamesOnlyFunctions) && only.Has(config.KeepNamesOnlyClasses) != isClass {
+		return false
+	}
+	if only.Has(config.KeepNamesOnlyExports) && (ref == js_ast.InvalidRef || !p.keepNamesExports[ref]) {
+		return false
+	}
+	if p.options.keepNamesFilter != nil && !p.options.keepNamesFilter.MatchString(name) {
+		return false
+	}
+	return true
+}
+
+func (p *parser) findKeepNamesExports(stmts []js_ast.Stmt) map[js_ast.Ref]bool {
+	exports := make(map[js_ast.Ref]bool)
+	for _, stmt := range stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SFunction:
+			if s.IsExport {
+				exports[s.Fn.Name.Ref] = true
+			}
+
+		case *js_ast.SClass:
+			if s.IsExport {
+				exports[s.Class.Name.Ref] = true
+			}
+
+		case *js_ast.SLocal:
+			// Names are only kept for values directly assigned to an identifier
+			if s.IsExport {
+				for _, decl := range s.Decls {
+					if b, ok := decl.Binding.Data.(*js_ast.BIdentifier); ok {
+						exports[b.Ref] = true
+					}
+				}
+			}
+
+		case *js_ast.SExportDefault:
+			exports[s.DefaultName.Ref] = true
+			switch s2 := s.Value.Data.(type) {
+			case *js_ast.SFunction:
+				if s2.Fn.Name != nil {
+					exports[s2.Fn.Name.Ref] = true
+				}
+			case *js_ast.SClass:
+				if s2.Class.Name != nil {
+					exports[s2.Class.Name.Ref] = true
+				}
+			}
+
+		case *js_ast.SExportClause:
+			// The names in export clauses haven't been bound yet
+			for _, item := range s.Items {
+				if member, ok := p.moduleScope.Members[item.OriginalName]; ok {
+					exports[member.Ref] = true
+				}
+			}
+		}
+	}
+	return exports
+}
+
 func (p *parser) keepExprSymbolName(value js_ast.Expr, name string) js_ast.Expr {
 	value = p.callRuntime(value.Loc, "__name", []js_ast.Expr{value,
 		{Loc: value.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(name)}},
@@ -8957,7 +9878,17 @@ func (p *parser) keepStmtSymbolName(loc logger.Loc, ref js_ast.Ref, name string)
 	}}
 }
 
+// Parsing a large file can take a while, so give up partway through if the
+// result is no longer needed. This fails the parse without logging an error.
+func (p *parser) checkForCancellation() {
+	if p.options.cancelFlag.DidCancel() {
+		panic(js_lexer.LexerPanic{})
+	}
+}
+
 func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_ast.Stmt {
+	p.checkForCancellation()
+
 	// By default any statement ends the const local prefix
 	wasAfterAfterConstLocalPrefix := p.currentScope.IsAfterConstLocalPrefix
 	p.currentScope.IsAfterConstLocalPrefix = true
@@ -9098,7 +10029,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			s2.Value = p.visitExpr(s2.Value)
 
 			// Optionally preserve the name
-			s2.Value = p.maybeKeepExprSymbolName(s2.Value, "default", wasAnonymousNamedExpr)
+			s2.Value = p.maybeKeepExprSymbolName(s2.Value, "default", s.DefaultName.Ref, wasAnonymousNamedExpr)
 
 			// Discard type-only export default statements
 			if p.options.ts.Parse {
@@ -9115,21 +10046,21 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		case *js_ast.SFunction:
 			// If we need to preserve the name but there is no name, generate a name
 			var name string
-			if p.options.keepNames {
-				if s2.Fn.Name == nil {
+			if s2.Fn.Name == nil {
+				if p.shouldKeepName("default", s.DefaultName.Ref, false) {
 					clone := s.DefaultName
 					s2.Fn.Name = &clone
 					name = "default"
-				} else {
-					name = p.symbols[s2.Fn.Name.Ref.InnerIndex].OriginalName
 				}
+			} else if original := p.symbols[s2.Fn.Name.Ref.InnerIndex].OriginalName; p.shouldKeepName(original, s2.Fn.Name.Ref, false) {
+				name = original
 			}
 
 			p.visitFn(&s2.Fn, s2.Fn.OpenParenLoc, visitFnOpts{})
 			stmts = append(stmts, stmt)
 
 			// Optionally preserve the name
-			if p.options.keepNames && s2.Fn.Name != nil {
+			if name != "" {
 				stmts = append(stmts, p.keepStmtSymbolName(s2.Fn.Name.Loc, s2.Fn.Name.Ref, name))
 			}
 
@@ -9252,7 +10183,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 				// Optionally preserve the name
 				if id, ok := d.Binding.Data.(*js_ast.BIdentifier); ok {
 					d.ValueOrNil = p.maybeKeepExprSymbolName(
-						d.ValueOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, wasAnonymousNamedExpr)
+						d.ValueOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, id.Ref, wasAnonymousNamedExpr)
 				}
 
 				// Initializing to undefined is implicit, but be careful to not
@@ -9713,8 +10644,8 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 		// Optionally preserve the name
-		if p.options.keepNames {
-			stmts = append(stmts, p.keepStmtSymbolName(s.Fn.Name.Loc, s.Fn.Name.Ref, p.symbols[s.Fn.Name.Ref.InnerIndex].OriginalName))
+		if name := p.symbols[s.Fn.Name.Ref.InnerIndex].OriginalName; p.shouldKeepName(name, s.Fn.Name.Ref, false) {
+			stmts = append(stmts, p.keepStmtSymbolName(s.Fn.Name.Loc, s.Fn.Name.Ref, name))
 		}
 		return stmts
 
@@ -10239,6 +11170,21 @@ func (p *parser) visitTSDecorators(tsDecorators []js_ast.Expr, tsDecoratorScope
 	return tsDecorators
 }
 
+func (p *parser) visitTSDecoratorMetadata(metadata []js_ast.TSDecoratorMetadata, tsDecoratorScope *js_ast.Scope) {
+	if metadata != nil {
+		// This metadata is evaluated alongside the decorators
+		oldScope := p.currentScope
+		p.currentScope = tsDecoratorScope
+
+		for i, item := range metadata {
+			metadata[i].Value = p.visitExpr(p.substituteTSEnumMetadata(item.Value))
+		}
+
+		// Avoid "popScope" because this decorator scope is not hierarchical
+		p.currentScope = oldScope
+	}
+}
+
 type visitClassResult struct {
 	shadowRef    js_ast.Ref
 	superCtorRef js_ast.Ref
@@ -10404,6 +11350,7 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 		}
 
 		property.TSDecorators = p.visitTSDecorators(property.TSDecorators, tsDecoratorScope)
+		p.visitTSDecoratorMetadata(property.TSDecoratorMetadata, tsDecoratorScope)
 
 		// Special-case certain expressions to allow them here
 		switch k := property.Key.Data.(type) {
@@ -10489,7 +11436,7 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 			p.propMethodTSDecoratorScope = tsDecoratorScope
 			if nameToKeep != "" {
 				wasAnonymousNamedExpr := p.isAnonymousNamedExpr(property.ValueOrNil)
-				property.ValueOrNil = p.maybeKeepExprSymbolName(p.visitExpr(property.ValueOrNil), nameToKeep, wasAnonymousNamedExpr)
+				property.ValueOrNil = p.maybeKeepExprSymbolName(p.visitExpr(property.ValueOrNil), nameToKeep, js_ast.InvalidRef, wasAnonymousNamedExpr)
 			} else {
 				property.ValueOrNil = p.visitExpr(property.ValueOrNil)
 			}
@@ -10503,7 +11450,7 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 			}
 			if nameToKeep != "" {
 				wasAnonymousNamedExpr := p.isAnonymousNamedExpr(property.InitializerOrNil)
-				property.InitializerOrNil = p.maybeKeepExprSymbolName(p.visitExpr(property.InitializerOrNil), nameToKeep, wasAnonymousNamedExpr)
+				property.InitializerOrNil = p.maybeKeepExprSymbolName(p.visitExpr(property.InitializerOrNil), nameToKeep, js_ast.InvalidRef, wasAnonymousNamedExpr)
 			} else {
 				property.InitializerOrNil = p.visitExpr(property.InitializerOrNil)
 			}
@@ -10792,7 +11739,7 @@ func (p *parser) checkForUnrepresentableIdentifier(loc logger.Loc, name string)
 			p.unrepresentableIdentifiers[name] = true
 			where, notes := p.prettyPrintTargetEnvironment(compat.UnicodeEscapes)
 			r := js_lexer.RangeOfIdentifier(p.source, loc)
-			p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf("%q cannot be escaped in %s but you "+
+			p.targetLog.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf("%q cannot be escaped in %s but you "+
 				"can set the charset to \"utf8\" to allow unescaped Unicode characters", name, where), notes)
 		}
 	}
@@ -11339,6 +12286,10 @@ type exprOut struct {
 	// If true and this is used as a call target, the whole call expression
 	// must be replaced with undefined.
 	methodCallMustBeReplacedWithUndefined bool
+
+	// If true and this is used as a call target, the call must be replaced
+	// with any side effects in its arguments
+	callMustBeDropped bool
 }
 
 func (p *parser) visitExpr(expr js_ast.Expr) js_ast.Expr {
@@ -11405,9 +12356,13 @@ func (p *parser) valueForThis(
 	return js_ast.Expr{}, false
 }
 
+func (p *parser) importMetaIsUnavailable() bool {
+	return p.options.unsupportedJSFeatures.Has(compat.ImportMeta) ||
+		(p.options.mode != config.ModePassThrough && !p.options.outputFormat.KeepES6ImportExportSyntax())
+}
+
 func (p *parser) valueForImportMeta(loc logger.Loc) (js_ast.Expr, bool) {
-	if p.options.unsupportedJSFeatures.Has(compat.ImportMeta) ||
-		(p.options.mode != config.ModePassThrough && !p.options.outputFormat.KeepES6ImportExportSyntax()) {
+	if p.importMetaIsUnavailable() {
 		// Generate the variable if it doesn't exist yet
 		if p.importMetaRef == js_ast.InvalidRef {
 			p.importMetaRef = p.newSymbol(js_ast.SymbolOther, "import_meta")
@@ -11845,10 +12800,24 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		if p.isStrictMode() && js_lexer.StrictModeReservedWords[name] {
 			p.markStrictModeFeature(reservedWord, js_lexer.RangeOfIdentifier(p.source, expr.Loc), name)
 		}
+
+		// Code generated by esbuild can reference runtime helpers by name
+		if p.options.allowRuntimeHelpers && strings.HasPrefix(name, "__") && !p.isNameDeclared(name) {
+			return p.importFromRuntime(expr.Loc, name), exprOut{}
+		}
+
 		result := p.findSymbol(expr.Loc, name)
 		e.MustKeepDueToWithStmt = result.isInsideWithScope
 		e.Ref = result.ref
 
+		// Report runtime APIs that aren't available in the target environment.
+		// Something like "typeof structuredClone" is likely a feature check.
+		if p.options.unsupportedRuntimeFeatures != 0 && e != p.typeofTarget && p.symbols[e.Ref.InnerIndex].Kind == js_ast.SymbolUnbound {
+			if feature, ok := compat.RuntimeGlobals[name]; ok {
+				p.markRuntimeFeature(feature, js_lexer.RangeOfIdentifier(p.source, expr.Loc))
+			}
+		}
+
 		// Handle assigning to a constant
 		if in.assignTarget != js_ast.AssignTargetNone {
 			switch p.symbols[result.ref.InnerIndex].Kind {
@@ -11883,6 +12852,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 
 		// Substitute user-specified defines for unbound or injected symbols
 		methodCallMustBeReplacedWithUndefined := false
+		callMustBeDropped := false
 		if p.symbols[e.Ref.InnerIndex].Kind.IsUnboundOrInjected() && !result.isInsideWithScope && e != p.deleteTarget {
 			if data, ok := p.options.defines.IdentifierDefines[name]; ok {
 				if data.DefineExpr != nil {
@@ -11910,6 +12880,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				if data.MethodCallsMustBeReplacedWithUndefined {
 					methodCallMustBeReplacedWithUndefined = true
 				}
+				if data.CallMustBeDropped && isCallTarget {
+					callMustBeDropped = true
+				}
 			}
 		}
 
@@ -11920,6 +12893,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				wasOriginallyIdentifier: true,
 			}), exprOut{
 				methodCallMustBeReplacedWithUndefined: methodCallMustBeReplacedWithUndefined,
+				callMustBeDropped:                     callMustBeDropped,
 			}
 
 	case *js_ast.EJSXElement:
@@ -11955,6 +12929,24 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Find the "key" prop for the automatic runtime. It's passed separately
+		// from the other props, which can't be done without changing the order of
+		// evaluation if it comes after a spread. React's "createElement()" is used
+		// instead in that case.
+		keyIndex := -1
+		isKeyAfterSpread := false
+		if !p.options.jsx.Preserve && p.options.jsx.AutomaticRuntime {
+			hasSpread := false
+			for i, property := range e.Properties {
+				if property.Kind == js_ast.PropertySpread {
+					hasSpread = true
+				} else if str, ok := property.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(str.Value, "key") {
+					keyIndex = i
+					isKeyAfterSpread = hasSpread
+				}
+			}
+		}
+
 		if p.options.jsx.Preserve {
 			// If the tag is an identifier, mark it as needing to be upper-case
 			switch tag := e.TagOrNil.Data.(type) {
@@ -11964,6 +12956,96 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			case *js_ast.EImportIdentifier:
 				p.symbols[tag.Ref.InnerIndex].Flags |= js_ast.MustStartWithCapitalLetterForJSX
 			}
+		} else if p.options.jsx.AutomaticRuntime && !isKeyAfterSpread {
+			// A missing tag is a fragment
+			if e.TagOrNil.Data == nil {
+				e.TagOrNil = p.importJSXSymbol(expr.Loc, "Fragment")
+			}
+
+			// The key is passed as a separate argument
+			var key js_ast.Expr
+			properties := make([]js_ast.Property, 0, len(e.Properties)+1)
+			for i, property := range e.Properties {
+				if i == keyIndex {
+					key = property.ValueOrNil
+				} else {
+					properties = append(properties, property)
+				}
+			}
+
+			// Children are passed as the "children" prop. Multiple children are
+			// passed as an array and are considered to be "static". TypeScript also
+			// considers spread children to be static, so we do the same.
+			isStaticChildren := len(e.Children) > 1
+			if len(e.Children) > 0 {
+				children := e.Children[0]
+				if _, ok := children.Data.(*js_ast.ESpread); ok || len(e.Children) > 1 {
+					children = js_ast.Expr{Loc: children.Loc, Data: &js_ast.EArray{Items: e.Children, IsSingleLine: true}}
+					isStaticChildren = true
+				}
+				properties = append(properties, js_ast.Property{
+					Key:        js_ast.Expr{Loc: children.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("children")}},
+					ValueOrNil: children,
+				})
+			}
+
+			// Arguments to jsx()
+			args := []js_ast.Expr{e.TagOrNil, p.lowerObjectSpread(propsLoc, &js_ast.EObject{
+				Properties: properties,
+			})}
+			if key.Data != nil {
+				args = append(args, key)
+			}
+
+			// The development runtime also takes the source location of the element
+			name := "jsx"
+			if p.options.jsx.Development {
+				name = "jsxDEV"
+				if key.Data == nil {
+					args = append(args, js_ast.Expr{Loc: expr.Loc, Data: js_ast.EUndefinedShared})
+				}
+
+				// "isStaticChildren"
+				args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EBoolean{Value: isStaticChildren}})
+
+				// "__source"
+				var line, column int
+				if loc := p.tracker.MsgLocationOrNil(logger.Range{Loc: expr.Loc}); loc != nil {
+					line, column = loc.Line, loc.Column+1
+				}
+				args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EObject{Properties: []js_ast.Property{
+					{
+						Key:        js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("fileName")}},
+						ValueOrNil: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(p.source.PrettyPath)}},
+					},
+					{
+						Key:        js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("lineNumber")}},
+						ValueOrNil: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: float64(line)}},
+					},
+					{
+						Key:        js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("columnNumber")}},
+						ValueOrNil: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: float64(column)}},
+					},
+				}}})
+
+				// "__self" (the top-level value of "this" isn't useful here)
+				if p.fnOnlyDataVisit.isThisNested {
+					args = append(args, p.visitExpr(js_ast.Expr{Loc: expr.Loc, Data: js_ast.EThisShared}))
+				} else {
+					args = append(args, js_ast.Expr{Loc: expr.Loc, Data: js_ast.EUndefinedShared})
+				}
+			} else if isStaticChildren {
+				name = "jsxs"
+			}
+
+			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ECall{
+				Target:        p.importJSXSymbol(expr.Loc, name),
+				Args:          args,
+				CloseParenLoc: e.CloseLoc,
+
+				// Enable tree shaking
+				CanBeUnwrappedIfUnused: !p.options.ignoreDCEAnnotations,
+			}}, exprOut{}
 		} else {
 			// A missing tag is a fragment
 			if e.TagOrNil.Data == nil {
@@ -11986,10 +13068,15 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 
 			// Call createElement()
-			target := p.instantiateDefineExpr(expr.Loc, p.options.jsx.Factory, identifierOpts{
-				wasOriginallyIdentifier: true,
-			})
-			p.warnAboutImportNamespaceCall(target, exprKindCall)
+			var target js_ast.Expr
+			if p.options.jsx.AutomaticRuntime {
+				target = p.importJSXSymbol(expr.Loc, "createElement")
+			} else {
+				target = p.instantiateDefineExpr(expr.Loc, p.options.jsx.Factory, identifierOpts{
+					wasOriginallyIdentifier: true,
+				})
+				p.warnAboutImportNamespaceCall(target, exprKindCall)
+			}
 			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ECall{
 				Target:        target,
 				Args:          args,
@@ -12454,7 +13541,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		case js_ast.BinOpAssign:
 			// Optionally preserve the name
 			if id, ok := e.Left.Data.(*js_ast.EIdentifier); ok {
-				e.Right = p.maybeKeepExprSymbolName(e.Right, p.symbols[id.Ref.InnerIndex].OriginalName, wasAnonymousNamedExpr)
+				e.Right = p.maybeKeepExprSymbolName(e.Right, p.symbols[id.Ref.InnerIndex].OriginalName, id.Ref, wasAnonymousNamedExpr)
 			}
 
 			if target, loc, private := p.extractPrivateIndex(e.Left); private != nil {
@@ -12633,6 +13720,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		isCallTarget := e == p.callTarget
 
 		// Check both user-specified defines and known globals
+		callMustBeDropped := false
 		if defines, ok := p.options.defines.DotDefines[e.Name]; ok {
 			for _, define := range defines {
 				if p.isDotOrIndexDefineMatch(expr, define.Parts) {
@@ -12652,6 +13740,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 					if define.Data.CallCanBeUnwrappedIfUnused && !p.options.ignoreDCEAnnotations {
 						e.CallCanBeUnwrappedIfUnused = true
 					}
+					if define.Data.CallMustBeDropped && isCallTarget {
+						callMustBeDropped = true
+					}
 					break
 				}
 			}
@@ -12680,6 +13771,11 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		})
 		e.Target = target
 
+		// Report runtime APIs that aren't available in the target environment
+		if p.options.unsupportedRuntimeFeatures != 0 && e != p.typeofTarget {
+			p.markRuntimeFeatureInDot(e, isCallTarget)
+		}
+
 		// Lower "super.prop" if necessary
 		if e.OptionalChain == js_ast.OptionalChainNone && in.assignTarget == js_ast.AssignTargetNone &&
 			!isCallTarget && p.shouldLowerSuperPropertyAccess(e.Target) {
@@ -12698,6 +13794,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		out = exprOut{
 			childContainsOptionalChain:            containsOptionalChain,
 			methodCallMustBeReplacedWithUndefined: out.methodCallMustBeReplacedWithUndefined,
+			callMustBeDropped:                     callMustBeDropped,
 			thisArgFunc:                           out.thisArgFunc,
 			thisArgWrapFunc:                       out.thisArgWrapFunc,
 		}
@@ -12880,6 +13977,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		switch e.Op {
 		case js_ast.UnOpTypeof:
 			_, idBefore := e.Value.Data.(*js_ast.EIdentifier)
+			p.typeofTarget = e.Value.Data
 			e.Value, _ = p.visitExprInOut(e.Value, exprIn{assignTarget: e.Op.UnaryAssignTarget()})
 			id, idAfter := e.Value.Data.(*js_ast.EIdentifier)
 
@@ -13128,7 +14226,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 					// Optionally preserve the name
 					if id, ok := e2.Left.Data.(*js_ast.EIdentifier); ok {
 						e2.Right = p.maybeKeepExprSymbolName(
-							e2.Right, p.symbols[id.Ref.InnerIndex].OriginalName, wasAnonymousNamedExpr)
+							e2.Right, p.symbols[id.Ref.InnerIndex].OriginalName, id.Ref, wasAnonymousNamedExpr)
 					}
 				} else {
 					item, _ = p.visitExprInOut(item, exprIn{assignTarget: in.assignTarget})
@@ -13236,7 +14334,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				if property.ValueOrNil.Data != nil {
 					if id, ok := property.ValueOrNil.Data.(*js_ast.EIdentifier); ok {
 						property.InitializerOrNil = p.maybeKeepExprSymbolName(
-							property.InitializerOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, wasAnonymousNamedExpr)
+							property.InitializerOrNil, p.symbols[id.Ref.InnerIndex].OriginalName, id.Ref, wasAnonymousNamedExpr)
 					}
 				}
 			}
@@ -13543,6 +14641,15 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}), exprOut{}
 
 	case *js_ast.ECall:
+		// Recognize "import.meta.glob()" when bundling
+		if p.options.expandGlob != nil {
+			if dot, ok := e.Target.Data.(*js_ast.EDot); ok && dot.Name == "glob" && dot.OptionalChain == js_ast.OptionalChainNone {
+				if _, ok := dot.Target.Data.(*js_ast.EImportMeta); ok {
+					return p.visitImportMetaGlob(expr.Loc, e), exprOut{}
+				}
+			}
+		}
+
 		p.callTarget = e.Target.Data
 
 		// Track ".then().catch()" chains
@@ -13636,6 +14743,21 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			return js_ast.Expr{Loc: expr.Loc, Data: js_ast.EUndefinedShared}, exprOut{}
 		}
 
+		// "invariant(a(), 'b')" => "a()"
+		if out.callMustBeDropped {
+			// Wrapping the arguments in an array makes sure spread arguments are
+			// still iterated over, since iteration may have side effects
+			args := js_ast.SimplifyUnusedExpr(js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EArray{Items: e.Args}},
+				p.options.unsupportedJSFeatures, p.isUnbound)
+			if e == p.stmtExprValue {
+				if args.Data == nil {
+					return js_ast.Expr{Loc: expr.Loc, Data: js_ast.EUndefinedShared}, exprOut{}
+				}
+				return args, exprOut{}
+			}
+			return js_ast.JoinWithComma(args, js_ast.Expr{Loc: expr.Loc, Data: js_ast.EUndefinedShared}), exprOut{}
+		}
+
 		// "foo(1, ...[2, 3], 4)" => "foo(1, 2, 3, 4)"
 		if p.options.minifySyntax && hasSpread && in.assignTarget == js_ast.AssignTargetNone {
 			e.Args = inlineSpreadsOfArrayLiterals(e.Args)
@@ -13698,8 +14820,10 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		case *js_ast.EDot:
 			// Recognize "require.resolve()" calls
 			if couldBeRequireResolve && t.Name == "resolve" {
-				if id, ok := t.Target.Data.(*js_ast.EIdentifier); ok && id.Ref == p.requireRef {
-					p.ignoreUsage(p.requireRef)
+				// The "require" identifier may have been replaced by the "__require"
+				// stub already, so recognize that too
+				if id, ok := t.Target.Data.(*js_ast.EIdentifier); ok && (id.Ref == p.requireRef || p.isRuntimeRequireRef(id.Ref)) {
+					p.ignoreUsage(id.Ref)
 					return p.maybeTransposeIfExprChain(e.Args[0], func(arg js_ast.Expr) js_ast.Expr {
 						if str, ok := e.Args[0].Data.(*js_ast.EString); ok {
 							// Ignore calls to require.resolve() if the control flow is provably
@@ -13835,10 +14959,32 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 								}}
 							}
 
-							// Use a debug log so people can see this if they want to
 							r := js_lexer.RangeOfIdentifier(p.source, e.Target.Loc)
-							p.log.AddID(logger.MsgID_JS_UnsupportedRequireCall, logger.Debug, &p.tracker, r,
-								"This call to \"require\" will not be bundled because the argument is not a string literal")
+							switch p.options.dynamicRequire {
+							case config.DynamicRequireError:
+								if !omitWarnings {
+									p.log.AddErrorWithNotes(&p.tracker, r,
+										"This call to \"require\" cannot be bundled because the argument is not a string literal",
+										[]logger.MsgData{{Text: "Use \"--dynamic-require=context\" to bundle all files that the argument could refer to, " +
+											"or \"--dynamic-require=keep\" to leave this call as-is and evaluate it at run-time."}})
+								}
+
+							case config.DynamicRequireContext:
+								if value, ok := p.maybeRequireContext(expr.Loc, arg); ok {
+									return value
+								}
+								if !omitWarnings {
+									p.log.AddIDWithNotes(logger.MsgID_JS_UnsupportedRequireCall, logger.Warning, &p.tracker, r,
+										"This call to \"require\" will not be bundled because the files it refers to can't be determined",
+										[]logger.MsgData{{Text: "The argument must start with a relative path such as \"./plugins/\" " +
+											"(either as a string, a template literal, or a call to \"path.join(__dirname, ...)\")."}})
+								}
+
+							default:
+								// Use a debug log so people can see this if they want to
+								p.log.AddID(logger.MsgID_JS_UnsupportedRequireCall, logger.Debug, &p.tracker, r,
+									"This call to \"require\" will not be bundled because the argument is not a string literal")
+							}
 
 							// Otherwise just return a clone of the "require()" call
 							return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ECall{
@@ -13882,7 +15028,27 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		e.Target = p.visitExpr(e.Target)
 		p.warnAboutImportNamespaceCall(e.Target, exprKindNew)
 
+		// Recognize "new URL('./file.png', import.meta.url)" so that the file can
+		// be bundled. The path is replaced with the path to the output file later.
+		var newURLPath string
+		isNewURL := false
+		if p.options.mode == config.ModeBundle && !p.isControlFlowDead && p.isUnboundIdentifier(e.Target, "URL") {
+			newURLPath, isNewURL = importPathForNewURL(e.Args)
+		}
+
 		for i, arg := range e.Args {
+			if isNewURL {
+				if i == 0 {
+					importRecordIndex := p.addImportRecord(ast.ImportNewURL, arg.Loc, newURLPath, nil)
+					p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
+					e.Args[i].Data = &js_ast.ENewURLString{ImportRecordIndex: importRecordIndex}
+				} else if p.importMetaIsUnavailable() {
+					// Use the URL of the output file instead of "import.meta.url" if
+					// "import.meta" isn't available. Otherwise it would be empty.
+					e.Args[i] = p.importFromRuntime(arg.Loc, "__importMetaURL")
+				}
+				continue
+			}
 			arg = p.visitExpr(arg)
 			if _, ok := arg.Data.(*js_ast.ESpread); ok {
 				hasSpread = true
@@ -13895,6 +15061,13 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			e.Args = inlineSpreadsOfArrayLiterals(e.Args)
 		}
 
+		// Recognize "new Worker(new URL('./worker.js', import.meta.url))" so that
+		// the worker can be bundled as a separate entry point
+		if p.options.mode == config.ModeBundle && !p.isControlFlowDead && len(e.Args) > 0 &&
+			(p.isUnboundIdentifier(e.Target, "Worker") || p.isUnboundIdentifier(e.Target, "SharedWorker")) {
+			p.maybeMarkNewURLAsWorker(e.Args[0])
+		}
+
 		p.maybeMarkKnownGlobalConstructorAsPure(e)
 
 	case *js_ast.EArrow:
@@ -13964,8 +15137,10 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}
 
 		// Optionally preserve the name
-		if p.options.keepNames && name != nil {
-			expr = p.keepExprSymbolName(expr, p.symbols[name.Ref.InnerIndex].OriginalName)
+		if name != nil {
+			if original := p.symbols[name.Ref.InnerIndex].OriginalName; p.shouldKeepName(original, name.Ref, false) {
+				expr = p.keepExprSymbolName(expr, original)
+			}
 		}
 
 	case *js_ast.EClass:
@@ -14512,6 +15687,24 @@ func (p *parser) scanForImportsAndExports(stmts []js_ast.Stmt) (result importsEx
 			keepUnusedImports := p.options.ts.Parse && (p.options.unusedImportFlagsTS&config.UnusedImportKeepValues) != 0 &&
 				p.options.mode != config.ModeBundle && !p.options.minifyIdentifiers
 
+			// Remove imports that are only used in dead code when bundling so that
+			// the imported file isn't pulled into the bundle. This lets defines
+			// exclude platform-specific dependencies:
+			//
+			//   import { readFileSync } from 'fs'
+			//   if (process.env.TARGET === 'node') readFileSync(file)
+			//
+			// Otherwise "fs" would still be resolved (and bundled if it's not
+			// external) for a browser build even though it's never used. This is
+			// opt-in because it also removes any side effects of the imported file.
+			// The references in dead code are replaced with "undefined" so that
+			// they don't end up referring to an unrelated global variable.
+			if p.isImportOnlyUsedInDeadCode(s) && !record.SourceIndex.IsValid() {
+				record.Flags |= ast.IsUnused
+				p.markDroppedImportAsMissing(s)
+				continue
+			}
+
 			// TypeScript always trims unused imports. This is important for
 			// correctness since some imports might be fake (only in the type
 			// system and used for type-only imports).
@@ -15200,12 +16393,15 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 
 	p := &parser{
 		log:                      log,
+		targetLog:                log,
 		source:                   source,
 		tracker:                  logger.MakeLineColumnTracker(&source),
 		lexer:                    lexer,
 		allowIn:                  true,
 		options:                  *options,
 		runtimeImports:           make(map[string]js_ast.Ref),
+		jsxRuntimeImports:        make(map[string]js_ast.Ref),
+		jsxLegacyImports:         make(map[string]js_ast.Ref),
 		promiseRef:               js_ast.InvalidRef,
 		regExpRef:                js_ast.InvalidRef,
 		afterArrowBodyLoc:        logger.Loc{Start: -1},
@@ -15246,6 +16442,7 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 
 var defaultJSXFactory = []string{"React", "createElement"}
 var defaultJSXFragment = []string{"React", "Fragment"}
+var defaultJSXImportSource = "react"
 
 func Parse(log logger.Log, source logger.Source, options Options) (result js_ast.AST, ok bool) {
 	ok = true
@@ -15265,6 +16462,9 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 	if len(options.jsx.Fragment.Parts) == 0 && options.jsx.Fragment.Constant == nil {
 		options.jsx.Fragment = config.DefineExpr{Parts: defaultJSXFragment}
 	}
+	if options.jsx.ImportSource == "" {
+		options.jsx.ImportSource = defaultJSXImportSource
+	}
 
 	if !options.ts.Parse {
 		// Non-TypeScript files always get the real JavaScript class field behavior
@@ -15300,7 +16500,16 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 			options.unsupportedJSFeatureOverridesMask)
 	}
 
-	p := newParser(log, source, js_lexer.NewLexer(log, source, options.ts), &options)
+	// Only errors from the parse pass are syntax errors
+	syntaxLog := log.WithCategory(logger.MsgCategorySyntax)
+	var lexer js_lexer.Lexer
+	if options.preserveComments {
+		lexer = js_lexer.NewLexerPreservingComments(syntaxLog, source, options.ts)
+	} else {
+		lexer = js_lexer.NewLexer(syntaxLog, source, options.ts)
+	}
+	p := newParser(syntaxLog, source, lexer, &options)
+	p.targetLog = log
 
 	// Consume a leading hashbang comment
 	hashbang := ""
@@ -15318,7 +16527,11 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 		isModuleScope:          true,
 		allowDirectivePrologue: true,
 	})
+	p.log = log
 	p.prepareForVisitPass()
+	if p.options.keepNames && p.options.keepNamesOnly.Has(config.KeepNamesOnlyExports) {
+		p.keepNamesExports = p.findKeepNamesExports(stmts)
+	}
 
 	// Insert a "use strict" directive if "alwaysStrict" is active
 	directive := ""
@@ -15380,7 +16593,7 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 				}
 			}
 		}
-		before = p.generateImportStmt(file.Source.KeyPath.Text, exportsNoConflict, file.Source.Index, before, symbols)
+		before = p.generateImportStmt(file.Source.KeyPath.Text, exportsNoConflict, ast.MakeIndex32(file.Source.Index), before, symbols)
 	}
 
 	// Bind symbols in a second pass over the AST. I started off doing this in a
@@ -15432,6 +16645,22 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 		}
 	}
 
+	// Insert import statements for the automatic JSX runtime now that all JSX
+	// elements have been visited. These go at the top of the file so that the
+	// output looks like what other tools generate.
+	if len(p.jsxRuntimeImports) > 0 {
+		path := p.options.jsx.ImportSource + "/jsx-runtime"
+		if p.options.jsx.Development {
+			path = p.options.jsx.ImportSource + "/jsx-dev-runtime"
+		}
+		keys := sortedKeysOfMapStringRef(p.jsxRuntimeImports)
+		before = p.generateImportStmt(path, keys, ast.Index32{}, before, p.jsxRuntimeImports)
+	}
+	if len(p.jsxLegacyImports) > 0 {
+		keys := sortedKeysOfMapStringRef(p.jsxLegacyImports)
+		before = p.generateImportStmt(p.options.jsx.ImportSource, keys, ast.Index32{}, before, p.jsxLegacyImports)
+	}
+
 	// Insert a variable for "import.meta" at the top of the file if it was used.
 	// We don't need to worry about "use strict" directives because this only
 	// happens when bundling, in which case we are flatting the module scopes of
@@ -15461,6 +16690,112 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 	return
 }
 
+// This parses JavaScript code that esbuild generates for other kinds of files
+// (e.g. WebAssembly modules). Unlike user code, it can call helper functions
+// from the runtime by name.
+func ParseGeneratedCode(log logger.Log, source logger.Source, options Options) (js_ast.AST, bool) {
+	options.allowRuntimeHelpers = true
+	return Parse(log, source, options)
+}
+
+// This is the syntax tree for a file exactly as it was written. Unlike the
+// AST returned by "Parse", nothing has been bound, folded, or lowered.
+type SyntaxTree struct {
+	Stmts         []js_ast.Stmt
+	Symbols       []js_ast.Symbol
+	ModuleScope   *js_ast.Scope
+	ImportRecords []ast.ImportRecord
+	Hashbang      string
+	IsESM         bool
+
+	// This maps the data of each expression, statement, and binding to where
+	// it ends in the source code. Nodes that are a single token such as
+	// identifiers may be missing, since their end can be found by lexing them.
+	NodeEnds map[interface{}]int32
+
+	// Declarations have symbols, but other identifiers are only bound to their
+	// symbols by the visit pass. Those identifiers instead have a placeholder
+	// reference that's a key in this map.
+	NamesInScope map[js_ast.Ref]NameInScope
+}
+
+type NameInScope struct {
+	Name  string
+	Scope *js_ast.Scope
+}
+
+// This parses the file without doing the visit pass. It's meant for tools
+// that need the syntax tree itself instead of code that can be printed.
+func ParseSyntaxTree(log logger.Log, source logger.Source, options Options) (result SyntaxTree, ok bool) {
+	ok = true
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); isLexerPanic {
+			ok = false
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	syntaxLog := log.WithCategory(logger.MsgCategorySyntax)
+	p := newParser(syntaxLog, source, js_lexer.NewLexer(syntaxLog, source, options.ts), &options)
+	p.targetLog = log
+	p.nodeEnds = make(map[interface{}]int32)
+	p.namesInScope = make(map[js_ast.Ref]NameInScope)
+
+	// Consume a leading hashbang comment
+	if p.lexer.Token == js_lexer.THashbang {
+		result.Hashbang = p.lexer.Identifier.String
+		p.lexer.Next()
+	}
+
+	// Allow top-level await
+	p.fnOrArrowDataParse.await = allowExpr
+	p.fnOrArrowDataParse.isTopLevel = true
+
+	result.Stmts = p.parseStmtsUpTo(js_lexer.TEndOfFile, parseStmtOpts{
+		isModuleScope:          true,
+		allowDirectivePrologue: true,
+	})
+
+	// Hoist declarations into the scopes they belong to like the visit pass
+	// does. ECMAScript modules must be marked as strict mode before this since
+	// strict mode can alter hoisting.
+	p.moduleScope = p.currentScope
+	result.IsESM = p.esmExportKeyword.Len > 0 ||
+		p.esmImportMeta.Len > 0 ||
+		p.topLevelAwaitKeyword.Len > 0 ||
+		p.esmImportStatementKeyword.Len > 0 ||
+		p.options.moduleTypeData.Type.IsESM()
+	if result.IsESM {
+		p.moduleScope.RecursiveSetStrictMode(js_ast.ImplicitStrictModeESM)
+	}
+	p.hoistSymbols(p.moduleScope)
+
+	result.Symbols = p.symbols
+	result.ModuleScope = p.moduleScope
+	result.ImportRecords = p.importRecords
+	result.NodeEnds = p.nodeEnds
+	result.NamesInScope = p.namesInScope
+	return
+}
+
+func (p *parser) recordNodeEnd(data interface{}) {
+	switch data.(type) {
+	case *js_ast.EMissing, *js_ast.ESuper, *js_ast.ENull, *js_ast.EUndefined, *js_ast.EThis, *js_ast.BMissing,
+		*js_ast.SEmpty, *js_ast.STypeScript, *js_ast.SDebugger:
+		// These are empty structs that may be shared between nodes, so they
+		// can't be used as a key
+		return
+	}
+
+	// Keep the first end that was recorded, since a node may be recorded again
+	// after parsing the closing parenthesis of a parenthesized expression
+	if _, ok := p.nodeEnds[data]; !ok {
+		p.nodeEnds[data] = p.lexer.PrevTokenEnd()
+	}
+}
+
 func LazyExportAST(log logger.Log, source logger.Source, options Options, expr js_ast.Expr, apiCall string) js_ast.AST {
 	// Don't create a new lexer using js_lexer.NewLexer() here since that will
 	// actually attempt to parse the first token, which might cause a syntax
@@ -15632,8 +16967,32 @@ func (p *parser) prepareForVisitPass() {
 		p.moduleRef = p.newSymbol(js_ast.SymbolHoisted, "module")
 	}
 
-	// Handle "@jsx" and "@jsxFrag" pragmas now that lexing is done
+	// Handle "@jsx", "@jsxFrag", "@jsxRuntime", and "@jsxImportSource" pragmas
+	// now that lexing is done
 	if p.options.jsx.Parse {
+		if jsxRuntime := p.lexer.JSXRuntimePragmaComment; jsxRuntime.Text != "" {
+			switch jsxRuntime.Text {
+			case "automatic":
+				p.options.jsx.AutomaticRuntime = true
+			case "classic":
+				p.options.jsx.AutomaticRuntime = false
+			default:
+				p.log.AddIDWithNotes(logger.MsgID_JS_UnsupportedJSXComment, logger.Warning, &p.tracker, jsxRuntime.Range,
+					fmt.Sprintf("Invalid JSX runtime: %s", jsxRuntime.Text),
+					[]logger.MsgData{{Text: "The JSX runtime can only be set to either \"classic\" or \"automatic\"."}})
+			}
+		}
+		if jsxImportSource := p.lexer.JSXImportSourcePragmaComment; jsxImportSource.Text != "" {
+			p.options.jsx.ImportSource = jsxImportSource.Text
+		}
+		if p.options.jsx.AutomaticRuntime {
+			for _, pragma := range []logger.Span{p.lexer.JSXFactoryPragmaComment, p.lexer.JSXFragmentPragmaComment} {
+				if pragma.Text != "" {
+					p.log.AddID(logger.MsgID_JS_UnsupportedJSXComment, logger.Warning, &p.tracker, pragma.Range,
+						"This JSX pragma comment is ignored because the automatic JSX runtime is enabled")
+				}
+			}
+		}
 		if jsxFactory := p.lexer.JSXFactoryPragmaComment; jsxFactory.Text != "" {
 			if expr, _ := ParseDefineExprOrJSON(jsxFactory.Text); len(expr.Parts) > 0 {
 				p.options.jsx.Factory = expr
@@ -15756,7 +17115,7 @@ func (p *parser) computeCharacterFrequency() *js_ast.CharFreq {
 func (p *parser) generateImportStmt(
 	path string,
 	imports []string,
-	sourceIndex uint32,
+	sourceIndex ast.Index32,
 	parts []js_ast.Part,
 	symbols map[string]js_ast.Ref,
 ) []js_ast.Part {
@@ -15765,7 +17124,7 @@ func (p *parser) generateImportStmt(
 	declaredSymbols := make([]js_ast.DeclaredSymbol, len(imports))
 	clauseItems := make([]js_ast.ClauseItem, len(imports))
 	importRecordIndex := p.addImportRecord(ast.ImportStmt, logger.Loc{}, path, nil)
-	p.importRecords[importRecordIndex].SourceIndex = ast.MakeIndex32(sourceIndex)
+	p.importRecords[importRecordIndex].SourceIndex = sourceIndex
 
 	// Create per-import information
 	for i, alias := range imports {
@@ -15793,16 +17152,24 @@ func (p *parser) generateImportStmt(
 	})
 }
 
+// Sort the imports for determinism
+func sortedKeysOfMapStringRef(in map[string]js_ast.Ref) []string {
+	keys := make([]string, 0, len(in))
+	for key := range in {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (p *parser) toAST(parts []js_ast.Part, hashbang string, directive string) js_ast.AST {
+	// Insert the import statements generated for eager "import.meta.glob" calls
+	parts = append(parts, p.importMetaGlobParts...)
+
 	// Insert an import statement for any runtime imports we generated
 	if len(p.runtimeImports) > 0 && !p.options.omitRuntimeForTests {
-		// Sort the imports for determinism
-		keys := make([]string, 0, len(p.runtimeImports))
-		for key := range p.runtimeImports {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-		parts = p.generateImportStmt("<runtime>", keys, runtime.SourceIndex, parts, p.runtimeImports)
+		keys := sortedKeysOfMapStringRef(p.runtimeImports)
+		parts = p.generateImportStmt("<runtime>", keys, ast.MakeIndex32(runtime.SourceIndex), parts, p.runtimeImports)
 	}
 
 	// Handle import paths after the whole file has been visited because we need
@@ -15984,5 +17351,8 @@ func (p *parser) toAST(parts []js_ast.Part, hashbang string, directive string) j
 		// ES6 features
 		ExportKeyword:        p.esmExportKeyword,
 		TopLevelAwaitKeyword: p.topLevelAwaitKeyword,
+
+		UsesImportMetaGlob:  p.usesImportMetaGlob,
+		UsedRuntimeFeatures: p.usedRuntimeFeatures,
 	}
 }
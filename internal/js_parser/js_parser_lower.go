@@ -45,12 +45,54 @@ func (p *parser) prettyPrintTargetEnvironment(feature compat.JSFeature) (where s
 	return
 }
 
+// Runtime APIs can't be lowered like syntax can. Instead, each file records
+// the unsupported runtime APIs that it uses so they can be reported, and there
+// is a warning for the first use of each one in each file.
+func (p *parser) markRuntimeFeature(feature compat.RuntimeFeature, r logger.Range) {
+	if !p.options.unsupportedRuntimeFeatures.Has(feature) || p.usedRuntimeFeatures.Has(feature) {
+		return
+	}
+	p.usedRuntimeFeatures |= feature
+
+	where := "the configured target environment"
+	if p.options.originalTargetEnv != "" {
+		where = fmt.Sprintf("%s (%s)", where, p.options.originalTargetEnv)
+	}
+	kind := logger.Warning
+	if p.suppressWarningsAboutWeirdCode {
+		kind = logger.Debug
+	}
+	p.log.AddIDWithNotes(logger.MsgID_JS_UnsupportedRuntimeFeature, kind, &p.tracker, r,
+		fmt.Sprintf("%q is not available in %s", compat.RuntimeFeatureToString[feature], where),
+		[]logger.MsgData{{Text: "This is a runtime API instead of syntax, so esbuild can't convert it to older code. " +
+			"You may need to include a polyfill for it."}})
+}
+
+func (p *parser) markRuntimeFeatureInDot(dot *js_ast.EDot, isCallTarget bool) {
+	// Check for something like "Object.hasOwn"
+	if id, ok := dot.Target.Data.(*js_ast.EIdentifier); ok && p.symbols[id.Ref.InnerIndex].Kind == js_ast.SymbolUnbound {
+		if props, ok := compat.RuntimeStaticProperties[p.symbols[id.Ref.InnerIndex].OriginalName]; ok {
+			if feature, ok := props[dot.Name]; ok {
+				p.markRuntimeFeature(feature, logger.Range{Loc: dot.Target.Loc, Len: dot.NameLoc.Start + int32(len(dot.Name)) - dot.Target.Loc.Start})
+			}
+			return
+		}
+	}
+
+	// Check for something like "array.at(-1)"
+	if isCallTarget {
+		if feature, ok := compat.RuntimePrototypeMethods[dot.Name]; ok {
+			p.markRuntimeFeature(feature, logger.Range{Loc: dot.NameLoc, Len: int32(len(dot.Name))})
+		}
+	}
+}
+
 func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (didGenerateError bool) {
 	didGenerateError = true
 
 	if !p.options.unsupportedJSFeatures.Has(feature) {
 		if feature == compat.TopLevelAwait && !p.options.outputFormat.KeepES6ImportExportSyntax() {
-			p.log.AddError(&p.tracker, r, fmt.Sprintf(
+			p.targetLog.AddError(&p.tracker, r, fmt.Sprintf(
 				"Top-level await is currently not supported with the %q output format", p.options.outputFormat.String()))
 			return
 		}
@@ -109,39 +151,39 @@ func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (di
 		name = "non-identifier array rest patterns"
 
 	case compat.ImportAssertions:
-		p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
+		p.targetLog.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
 			"Using an arbitrary value as the second argument to \"import()\" is not possible in %s", where), notes)
 		return
 
 	case compat.TopLevelAwait:
-		p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
+		p.targetLog.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
 			"Top-level await is not available in %s", where), notes)
 		return
 
 	case compat.ArbitraryModuleNamespaceNames:
-		p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
+		p.targetLog.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
 			"Using a string as a module namespace identifier name is not supported in %s", where), notes)
 		return
 
 	case compat.Bigint:
 		// Transforming these will never be supported
-		p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
+		p.targetLog.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
 			"Big integer literals are not available in %s", where), notes)
 		return
 
 	case compat.ImportMeta:
 		// This can't be polyfilled
-		p.log.AddIDWithNotes(logger.MsgID_JS_EmptyImportMeta, logger.Warning, &p.tracker, r, fmt.Sprintf(
+		p.targetLog.AddIDWithNotes(logger.MsgID_JS_EmptyImportMeta, logger.Warning, &p.tracker, r, fmt.Sprintf(
 			"\"import.meta\" is not available in %s and will be empty", where), notes)
 		return
 
 	default:
-		p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
+		p.targetLog.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
 			"This feature is not available in %s", where), notes)
 		return
 	}
 
-	p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
+	p.targetLog.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf(
 		"Transforming %s to %s is not supported yet", name, where), notes)
 	return
 }
@@ -1968,6 +2010,18 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 	return
 }
 
+// This is used for TypeScript's "emitDecoratorMetadata" setting. The metadata
+// goes after all other decorators, including parameter decorators.
+func (p *parser) appendTSDecoratorMetadata(loc logger.Loc, decorators []js_ast.Expr, metadata []js_ast.TSDecoratorMetadata) []js_ast.Expr {
+	for _, item := range metadata {
+		decorators = append(decorators, p.callRuntime(loc, "__metadata", []js_ast.Expr{
+			{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(item.Key)}},
+			item.Value,
+		}))
+	}
+	return decorators
+}
+
 // Lower class fields for environments that don't support them. This either
 // takes a statement or an expression.
 func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClassResult) ([]js_ast.Stmt, js_ast.Expr) {
@@ -1985,6 +2039,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 	var classLoc logger.Loc
 	var defaultName js_ast.LocRef
 	var nameToKeep string
+	refToKeep := js_ast.InvalidRef
 	if stmt.Data == nil {
 		e, _ := expr.Data.(*js_ast.EClass)
 		class = &e.Class
@@ -1992,6 +2047,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		if class.Name != nil {
 			symbol := &p.symbols[class.Name.Ref.InnerIndex]
 			nameToKeep = symbol.OriginalName
+			refToKeep = class.Name.Ref
 
 			// The shadowing name inside the class expression should be the same as
 			// the class expression name itself
@@ -2013,6 +2069,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 			kind = classKindStmt
 		}
 		nameToKeep = p.symbols[class.Name.Ref.InnerIndex].OriginalName
+		refToKeep = class.Name.Ref
 	} else {
 		s, _ := stmt.Data.(*js_ast.SExportDefault)
 		s2, _ := s.Value.Data.(*js_ast.SClass)
@@ -2021,8 +2078,10 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		kind = classKindExportDefaultStmt
 		if class.Name != nil {
 			nameToKeep = p.symbols[class.Name.Ref.InnerIndex].OriginalName
+			refToKeep = class.Name.Ref
 		} else {
 			nameToKeep = "default"
+			refToKeep = defaultName.Ref
 		}
 	}
 	if stmt.Data == nil {
@@ -2044,6 +2103,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 	var staticPrivateMethods []js_ast.Expr
 	var instanceDecorators []js_ast.Expr
 	var staticDecorators []js_ast.Expr
+	var ctorDecoratorMetadata []js_ast.TSDecoratorMetadata
 
 	// These are only for class expressions that need to be captured
 	var nameFunc func() js_ast.Expr
@@ -2134,6 +2194,9 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 				if key, ok := prop.Key.Data.(*js_ast.EString); ok {
 					isConstructor = helpers.UTF16EqualsString(key.Value, "constructor")
 				}
+				if isConstructor {
+					ctorDecoratorMetadata = prop.TSDecoratorMetadata
+				}
 				for i, arg := range fn.Fn.Args {
 					for _, decorator := range arg.TSDecorators {
 						// Generate a call to "__decorateParam()" for this parameter decorator
@@ -2217,6 +2280,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 			// Generate a single call to "__decorateClass()" for this property
 			if len(prop.TSDecorators) > 0 {
 				loc := prop.Key.Loc
+				prop.TSDecorators = p.appendTSDecoratorMetadata(loc, prop.TSDecorators, prop.TSDecoratorMetadata)
 
 				// Clone the key for the property descriptor
 				var descriptorKey js_ast.Expr
@@ -2516,7 +2580,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		}
 
 		// Optionally preserve the name
-		if p.options.keepNames && nameToKeep != "" {
+		if nameToKeep != "" && p.shouldKeepName(nameToKeep, refToKeep, true) {
 			expr = p.keepExprSymbolName(expr, nameToKeep)
 		}
 
@@ -2559,7 +2623,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 
 	// Optionally preserve the name
 	var keepNameStmt js_ast.Stmt
-	if p.options.keepNames && nameToKeep != "" {
+	if nameToKeep != "" && p.shouldKeepName(nameToKeep, refToKeep, true) {
 		name := nameFunc()
 		keepNameStmt = p.keepStmtSymbolName(name.Loc, name.Data.(*js_ast.EIdentifier).Ref, nameToKeep)
 	}
@@ -2689,6 +2753,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
 	if len(class.TSDecorators) > 0 {
+		class.TSDecorators = p.appendTSDecoratorMetadata(classLoc, class.TSDecorators, ctorDecoratorMetadata)
 		stmts = append(stmts, js_ast.AssignStmt(
 			js_ast.Expr{Loc: nameForClassDecorators.Loc, Data: &js_ast.EIdentifier{Ref: nameForClassDecorators.Ref}},
 			p.callRuntime(classLoc, "__decorateClass", []js_ast.Expr{
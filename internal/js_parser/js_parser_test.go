@@ -44,6 +44,15 @@ func expectParseErrorTarget(t *testing.T, esVersion int, contents string, expect
 	})
 }
 
+func expectParseErrorRuntimeTarget(t *testing.T, chromeVersion int, contents string, expected string) {
+	t.Helper()
+	expectParseErrorCommon(t, contents, expected, config.Options{
+		UnsupportedRuntimeFeatures: compat.UnsupportedRuntimeFeatures(map[compat.Engine][]int{
+			compat.Chrome: {chromeVersion},
+		}),
+	})
+}
+
 func expectPrintedCommon(t *testing.T, contents string, expected string, options config.Options) {
 	t.Helper()
 	t.Run(contents, func(t *testing.T) {
@@ -149,6 +158,35 @@ func expectPrintedJSX(t *testing.T, contents string, expected string) {
 	})
 }
 
+func expectPrintedJSXAutomatic(t *testing.T, options JSXAutomaticTestOptions, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expected, config.Options{
+		JSX: config.JSXOptions{
+			Parse:            true,
+			AutomaticRuntime: true,
+			Development:      options.Development,
+			ImportSource:     options.ImportSource,
+		},
+	})
+}
+
+func expectParseErrorJSXAutomatic(t *testing.T, options JSXAutomaticTestOptions, contents string, expected string) {
+	t.Helper()
+	expectParseErrorCommon(t, contents, expected, config.Options{
+		JSX: config.JSXOptions{
+			Parse:            true,
+			AutomaticRuntime: true,
+			Development:      options.Development,
+			ImportSource:     options.ImportSource,
+		},
+	})
+}
+
+type JSXAutomaticTestOptions struct {
+	Development  bool
+	ImportSource string
+}
+
 func expectParseErrorTargetJSX(t *testing.T, esVersion int, contents string, expected string) {
 	t.Helper()
 	expectParseErrorCommon(t, contents, expected, config.Options{
@@ -4704,6 +4742,33 @@ func TestJSXPragmas(t *testing.T) {
 	expectPrintedJSX(t, "/* @jsxFrag a.b.c */\n<></>", "/* @__PURE__ */ React.createElement(a.b.c, null);\n")
 }
 
+func TestJSXAutomatic(t *testing.T) {
+	// Prod, without explicit imports
+	p := JSXAutomaticTestOptions{}
+	expectPrintedJSXAutomatic(t, p, "<div>></div>", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {\n  children: \">\"\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<div>{1}{2}</div>", "import {\n  jsxs\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsxs(\"div\", {\n  children: [1, 2]\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<div key={1} a={2}>x</div>", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {\n  a: 2,\n  children: \"x\"\n}, 1);\n")
+	expectPrintedJSXAutomatic(t, p, "<div a={1} {...b} key={2} />", "import {\n  createElement\n} from \"react\";\n/* @__PURE__ */ createElement(\"div\", {\n  a: 1,\n  ...b,\n  key: 2\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<div {...b} />", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"div\", {\n  ...b\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<div>{...children}</div>", "import {\n  jsxs\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsxs(\"div\", {\n  children: [...children]\n});\n")
+	expectPrintedJSXAutomatic(t, p, "<><a/><b/></>", "import {\n  Fragment,\n  jsx,\n  jsxs\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsxs(Fragment, {\n  children: [/* @__PURE__ */ jsx(\"a\", {}), /* @__PURE__ */ jsx(\"b\", {})]\n});\n")
+	expectPrintedJSXAutomatic(t, JSXAutomaticTestOptions{ImportSource: "preact"}, "<a/>", "import {\n  jsx\n} from \"preact/jsx-runtime\";\n/* @__PURE__ */ jsx(\"a\", {});\n")
+
+	// Dev
+	d := JSXAutomaticTestOptions{Development: true}
+	expectPrintedJSXAutomatic(t, d, "<div>></div>", "import {\n  jsxDEV\n} from \"react/jsx-dev-runtime\";\n/* @__PURE__ */ jsxDEV(\"div\", {\n  children: \">\"\n}, void 0, false, {\n  fileName: \"<stdin>\",\n  lineNumber: 1,\n  columnNumber: 1\n}, void 0);\n")
+	expectPrintedJSXAutomatic(t, d, "<div key={1}>\n  {a}{b}\n</div>", "import {\n  jsxDEV\n} from \"react/jsx-dev-runtime\";\n/* @__PURE__ */ jsxDEV(\"div\", {\n  children: [a, b]\n}, 1, true, {\n  fileName: \"<stdin>\",\n  lineNumber: 1,\n  columnNumber: 1\n}, void 0);\n")
+	expectPrintedJSXAutomatic(t, d, "function Foo() { return <a/> }", "import {\n  jsxDEV\n} from \"react/jsx-dev-runtime\";\nfunction Foo() {\n  return /* @__PURE__ */ jsxDEV(\"a\", {}, void 0, false, {\n    fileName: \"<stdin>\",\n    lineNumber: 1,\n    columnNumber: 25\n  }, this);\n}\n")
+	expectPrintedJSXAutomatic(t, d, "<a {...b} key={c} />", "import {\n  createElement\n} from \"react\";\n/* @__PURE__ */ createElement(\"a\", {\n  ...b,\n  key: c\n});\n")
+
+	// Pragmas
+	expectPrintedJSXAutomatic(t, p, "// @jsxImportSource preact\n<a/>", "import {\n  jsx\n} from \"preact/jsx-runtime\";\n/* @__PURE__ */ jsx(\"a\", {});\n")
+	expectPrintedJSXAutomatic(t, p, "// @jsxRuntime classic\n<a/>", "/* @__PURE__ */ React.createElement(\"a\", null);\n")
+	expectPrintedJSX(t, "// @jsxRuntime automatic\n<a/>", "import {\n  jsx\n} from \"react/jsx-runtime\";\n/* @__PURE__ */ jsx(\"a\", {});\n")
+	expectParseErrorJSXAutomatic(t, p, "// @jsxRuntime foo\n<a/>", "<stdin>: WARNING: Invalid JSX runtime: foo\nNOTE: The JSX runtime can only be set to either \"classic\" or \"automatic\".\n")
+	expectParseErrorJSXAutomatic(t, p, "// @jsx h\n<a/>", "<stdin>: WARNING: This JSX pragma comment is ignored because the automatic JSX runtime is enabled\n")
+}
+
 func TestPreserveOptionalChainParentheses(t *testing.T) {
 	expectPrinted(t, "a?.b.c", "a?.b.c;\n")
 	expectPrinted(t, "(a?.b).c", "(a?.b).c;\n")
@@ -5271,3 +5336,106 @@ func TestAutoPureForWeakMap(t *testing.T) {
 	expectPrinted(t, "new WeakMap([x, []])", "new WeakMap([x, []]);\n")
 	expectPrinted(t, "new WeakMap([[], x])", "new WeakMap([[], x]);\n")
 }
+
+func TestRuntimeFeatures(t *testing.T) {
+	expectParseErrorRuntimeTarget(t, 100, "structuredClone(x); [].at(-1); Object.hasOwn(x, y)", "")
+	expectParseErrorRuntimeTarget(t, 90, "structuredClone(x); [].at(-1); Object.hasOwn(x, y)",
+		"<stdin>: WARNING: \"structuredClone\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n<stdin>: WARNING: \"Array.prototype.at\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n<stdin>: WARNING: \"Object.hasOwn\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n")
+	expectParseErrorRuntimeTarget(t, 90, "structuredClone(a); structuredClone(b)", "<stdin>: WARNING: \"structuredClone\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n")
+	expectParseErrorRuntimeTarget(t, 90, "x.flatMap(y); x.findLastIndex(y)", "<stdin>: WARNING: \"Array.prototype.findLast\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n")
+	expectParseErrorRuntimeTarget(t, 60, "x.flat(); x.flatMap(y)", "<stdin>: WARNING: \"Array.prototype.flat\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n")
+	expectParseErrorRuntimeTarget(t, 60, "new WeakRef(x); new AggregateError([]); queueMicrotask(f)",
+		"<stdin>: WARNING: \"WeakRef\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n<stdin>: WARNING: \"AggregateError\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n<stdin>: WARNING: \"queueMicrotask\" is not available in the configured target environment\nNOTE: This is a runtime API instead of syntax, so esbuild can't convert it to older code. You may need to include a polyfill for it.\n")
+
+	// Feature checks, bound symbols, and non-calls aren't reported
+	expectParseErrorRuntimeTarget(t, 90, "typeof structuredClone; typeof Object.hasOwn", "")
+	expectParseErrorRuntimeTarget(t, 90, "let structuredClone, Object; structuredClone(x); Object.hasOwn(x, y)", "")
+	expectParseErrorRuntimeTarget(t, 90, "x.at; Object.keys(x); Promise.resolve()", "")
+}
+
+func expectPrintedErrorRecovery(t *testing.T, contents string, expected string, expectedErrors string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		options := config.Options{
+			OmitRuntimeForTests: true,
+			ErrorRecovery:       true,
+		}
+		tree, ok := Parse(log, test.SourceForTest(contents), OptionsFromConfig(&options))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		test.AssertEqualWithDiff(t, text, expectedErrors)
+		if !ok {
+			t.Fatal("Parse error")
+		}
+		symbols := js_ast.NewSymbolMap(1)
+		symbols.SymbolsForSource[0] = tree.Symbols
+		r := renamer.NewNoOpRenamer(symbols)
+		js := js_printer.Print(tree, symbols, r, js_printer.Options{}).JS
+		test.AssertEqualWithDiff(t, string(js), expected)
+	})
+}
+
+func TestCancellation(t *testing.T) {
+	cancelFlag := &config.CancelFlag{}
+	cancelFlag.Cancel()
+
+	// Cancellation stops parsing without an error, even with error recovery
+	for _, errorRecovery := range []bool{false, true} {
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		options := OptionsFromConfig(&config.Options{CancelFlag: cancelFlag, ErrorRecovery: errorRecovery})
+		_, ok := Parse(log, test.SourceForTest("a()\nb()\nc()"), options)
+		if ok {
+			t.Fatal("Expected parsing to be cancelled")
+		}
+		if msgs := log.Done(); len(msgs) > 0 {
+			t.Fatalf("Unexpected message: %s", msgs[0].Data.Text)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	expectPrintedErrorRecovery(t, "let a = 1\nlet b = ;\nfoo(1 2)\nconst c = 3\n", "let a = 1;\nconst c = 3;\n",
+		"<stdin>: ERROR: Unexpected \";\"\n<stdin>: ERROR: Expected \")\" but found \"2\"\n")
+	expectPrintedErrorRecovery(t, "a(); b c; d()", "a();\nd();\n",
+		"<stdin>: ERROR: Expected \";\" but found \"c\"\n")
+	expectPrintedErrorRecovery(t, "a()\n}\nb()", "a();\nb();\n",
+		"<stdin>: ERROR: Unexpected \"}\"\n")
+
+	// Errors inside nested blocks only drop the nested statement
+	expectPrintedErrorRecovery(t, "function f() {\n  x y\n  return 2\n}\nf()", "function f() {\n  return 2;\n}\nf();\n",
+		"<stdin>: ERROR: Expected \";\" but found \"y\"\n")
+	expectPrintedErrorRecovery(t, "if (x) { let y = }\nconst z = () => { a b }\nz()", "if (x) {\n}\nconst z = () => {\n};\nz();\n",
+		"<stdin>: ERROR: Unexpected \"}\"\n<stdin>: ERROR: Expected \";\" but found \"b\"\n")
+	expectPrintedErrorRecovery(t, "a(function () { for (;;) { let x = ( } })\nb()", "a(function() {\n  for (; ; ) {\n  }\n});\nb();\n",
+		"<stdin>: ERROR: Unexpected \"}\"\n")
+
+	expectPrintedErrorRecovery(t, "let x = function () { { let y } + }", "let x = function() {\n  {\n    let y;\n  }\n};\n",
+		"<stdin>: ERROR: Unexpected \"}\"\n")
+
+	// Scopes from dropped statements must not confuse the visit pass
+	expectPrintedErrorRecovery(t, "x = [function () { let y }, () => {}]]\nlet z = () => { let w; return w }", "let z = () => {\n  let w;\n  return w;\n};\n",
+		"<stdin>: ERROR: Expected \";\" but found \"]\"\n")
+	expectPrintedErrorRecovery(t, "class A { m() { let q } n( }\nfunction f(a) { return a }", "function f(a) {\n  return a;\n}\n",
+		"<stdin>: ERROR: Expected identifier but found \"}\"\n")
+
+	// Names declared by dropped statements can be declared again
+	expectPrintedErrorRecovery(t, "let b = ;\nlet b = 2", "let b = 2;\n",
+		"<stdin>: ERROR: Unexpected \";\"\n")
+	expectPrintedErrorRecovery(t, "import { a } from 'x' y\nlet a = 1", "let a = 1;\n",
+		"<stdin>: ERROR: Expected \";\" but found \"y\"\n")
+
+	// Lexer errors are recovered from too
+	expectPrintedErrorRecovery(t, "a = 1 # 2\nb()", "b();\n",
+		"<stdin>: ERROR: Syntax error \" \"\n")
+	expectPrintedErrorRecovery(t, "a = 'unterminated\nb()", "b();\n",
+		"<stdin>: ERROR: Unterminated string literal\n")
+
+	// Unterminated blocks are ended at the end of the file
+	expectPrintedErrorRecovery(t, "a()\nfunction f() {\n  let x = 1", "a();\nfunction f() {\n  let x = 1;\n}\n",
+		"<stdin>: ERROR: Unexpected end of file\n")
+}
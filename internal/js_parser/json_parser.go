@@ -146,10 +146,12 @@ func (p *jsonParser) parseExpr() js_ast.Expr {
 		if p.lexer.HasNewlineBefore {
 			isSingleLine = false
 		}
+		closeBraceLoc := p.lexer.Loc()
 		p.lexer.Expect(js_lexer.TCloseBrace)
 		return js_ast.Expr{Loc: loc, Data: &js_ast.EObject{
-			Properties:   properties,
-			IsSingleLine: isSingleLine,
+			Properties:    properties,
+			CloseBraceLoc: closeBraceLoc,
+			IsSingleLine:  isSingleLine,
 		}}
 
 	default:
@@ -3,6 +3,7 @@ package js_parser
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
@@ -26,6 +27,8 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 
 	var sources []string
 	var sourcesContent []sourcemap.SourceContent
+	var sourceRoot string
+	var ignoreList []float64
 	var mappingsRaw []uint16
 	var mappingsStart int32
 	hasVersion := false
@@ -61,6 +64,21 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 				}
 			}
 
+		case "sourceRoot":
+			if value, ok := prop.ValueOrNil.Data.(*js_ast.EString); ok {
+				sourceRoot = helpers.UTF16ToString(value.Value)
+			}
+
+		case "x_google_ignoreList", "ignoreList":
+			if value, ok := prop.ValueOrNil.Data.(*js_ast.EArray); ok {
+				ignoreList = nil
+				for _, item := range value.Items {
+					if element, ok := item.Data.(*js_ast.ENumber); ok {
+						ignoreList = append(ignoreList, element.Value)
+					}
+				}
+			}
+
 		case "sourcesContent":
 			if value, ok := prop.ValueOrNil.Data.(*js_ast.EArray); ok {
 				sourcesContent = nil
@@ -88,6 +106,31 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 		return nil
 	}
 
+	// The source root is prepended to each relative source path. Doing this now
+	// means the paths are correct when this source map is composed with the
+	// source map for the output file, which has its own unrelated source root.
+	if sourceRoot != "" {
+		if !strings.HasSuffix(sourceRoot, "/") {
+			sourceRoot += "/"
+		}
+		for i, source := range sources {
+			if source != "" && !strings.HasPrefix(source, "/") && !helpers.HasURLScheme(source) {
+				sources[i] = sourceRoot + source
+			}
+		}
+	}
+
+	// Only keep indices that refer to a source
+	var ignored []bool
+	for _, value := range ignoreList {
+		if index := int(value); float64(index) == value && index >= 0 && index < len(sources) {
+			if ignored == nil {
+				ignored = make([]bool, len(sources))
+			}
+			ignored[index] = true
+		}
+	}
+
 	var mappings mappingArray
 	mappingsLen := len(mappingsRaw)
 	sourcesLen := len(sources)
@@ -235,6 +278,7 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 		Sources:        sources,
 		SourcesContent: sourcesContent,
 		Mappings:       mappings,
+		IgnoreList:     ignored,
 	}
 }
 
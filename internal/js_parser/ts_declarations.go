@@ -0,0 +1,1795 @@
+package js_parser
+
+// This file generates TypeScript declaration files (i.e. ".d.ts" files) from
+// TypeScript source files. It doesn't do any type checking or type inference,
+// so it only handles the simple cases where the types of all exported things
+// are written down explicitly. Function bodies and initializers are removed
+// and replaced by their type annotations. When a type annotation is missing
+// and can't be trivially determined (e.g. from a literal), "any" is used
+// instead and a warning is logged.
+//
+// This works by running the first pass of the parser over the file again
+// using a custom statement loop. Types are normally skipped over by the
+// parser, so the text for each type is copied verbatim from the source code.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type tsDeclEmitter struct {
+	p            *parser
+	log          logger.Log
+	overloadName string
+	isModule     bool
+}
+
+// Each top-level statement that may end up in the declaration file is turned
+// into a unit. Only units that are exported or that are referenced by other
+// included units end up in the output.
+type tsDeclUnit struct {
+	text       string
+	names      []string
+	warnings   []tsDeclWarning
+	imp        *tsDeclImport
+	exportFrom *logger.Span
+	isRoot     bool
+
+	// This is an "export {}" clause without a "from" path
+	isExportClause bool
+}
+
+type tsDeclWarning struct {
+	text string
+	r    logger.Range
+}
+
+type tsDeclImport struct {
+	path          logger.Span
+	pathText      string
+	assertText    string
+	defaultName   string
+	namespaceName string
+	items         []tsDeclImportItem
+	isTypeOnly    bool
+}
+
+type tsDeclImportItem struct {
+	text  string
+	local string
+}
+
+type tsDeclStmtInfo struct {
+	comments    string
+	indent      string
+	isExport    bool
+	isDefault   bool
+	isNamespace bool
+}
+
+type tsDeclParam struct {
+	defaultValue js_ast.Expr
+	name         string
+	typeText     string
+	modifiers    []string
+	nameRange    logger.Range
+	isOptional   bool
+	isRest       bool
+	hasDefault   bool
+}
+
+type tsDeclFn struct {
+	body       *js_ast.FnBody
+	typeParams string
+	returnType string
+	params     []tsDeclParam
+}
+
+type tsDeclInit struct {
+	text      string
+	typeText  string
+	isLiteral bool
+}
+
+func EmitTSDeclarations(log logger.Log, source logger.Source, options Options) (contents string, importPaths []logger.Span, ok bool) {
+	ok = true
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); isLexerPanic {
+			ok = false
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	// Any syntax errors were already reported when this file was parsed the
+	// first time, so don't report them again
+	parseLog := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	options.ts.Parse = true
+	p := newParser(parseLog, source, js_lexer.NewLexer(parseLog, source, options.ts), &options)
+	if p.lexer.Token == js_lexer.THashbang {
+		p.lexer.Next()
+	}
+	p.fnOrArrowDataParse.await = allowExpr
+	p.fnOrArrowDataParse.isTopLevel = true
+
+	e := tsDeclEmitter{p: p, log: log}
+	units := e.parseStmts(js_lexer.TEndOfFile, "", false)
+	if parseLog.HasErrors() {
+		ok = false
+		return
+	}
+
+	// Files without any imports or exports are scripts, so everything in them
+	// is global and must be kept
+	included, words := tsDeclIncludedUnits(units, !e.isModule)
+	sb := strings.Builder{}
+
+	// Only keep imported names that are actually used by the declarations
+	for _, unit := range units {
+		if unit.imp != nil {
+			if text, ok := unit.imp.print(words); ok {
+				sb.WriteString(text)
+				sb.WriteByte('\n')
+				importPaths = append(importPaths, unit.imp.path)
+			}
+		}
+	}
+
+	needsExportClause := false
+	hasExportClause := false
+	for i, unit := range units {
+		if !included[i] {
+			continue
+		}
+		sb.WriteString(unit.text)
+		sb.WriteByte('\n')
+		if unit.exportFrom != nil {
+			importPaths = append(importPaths, *unit.exportFrom)
+		}
+		if !unit.isRoot {
+			needsExportClause = true
+		}
+		if unit.isExportClause {
+			hasExportClause = true
+		}
+		for _, warning := range unit.warnings {
+			log.AddID(logger.MsgID_JS_DeclarationInference, logger.Warning, &p.tracker, warning.r, warning.text)
+		}
+	}
+
+	// Declarations in a module declaration file are implicitly exported unless
+	// there's an explicit export clause, so add an empty one to avoid exporting
+	// things that weren't originally exported
+	if e.isModule && !hasExportClause && (needsExportClause || sb.Len() == 0) {
+		sb.WriteString("export {};\n")
+	}
+
+	contents = sb.String()
+	return
+}
+
+func tsDeclIncludedUnits(units []tsDeclUnit, includeAll bool) ([]bool, map[string]bool) {
+	included := make([]bool, len(units))
+	words := make(map[string]bool)
+	unitsForName := make(map[string][]int)
+	var stack []int
+
+	for i, unit := range units {
+		if unit.imp != nil {
+			continue
+		}
+		for _, name := range unit.names {
+			unitsForName[name] = append(unitsForName[name], i)
+		}
+		if includeAll || unit.isRoot {
+			included[i] = true
+			stack = append(stack, i)
+		}
+	}
+
+	// Include everything that the included units reference by name. This is
+	// approximate since it doesn't know about scopes, but including too much
+	// is harmless.
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, word := range tsDeclWords(units[i].text) {
+			if words[word] {
+				continue
+			}
+			words[word] = true
+			for _, j := range unitsForName[word] {
+				if !included[j] {
+					included[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+	}
+
+	return included, words
+}
+
+func tsDeclWords(text string) (words []string) {
+	for i := 0; i < len(text); {
+		c, width := utf8.DecodeRuneInString(text[i:])
+		if !js_lexer.IsIdentifierStart(c) {
+			i += width
+			continue
+		}
+		start := i
+		i += width
+		for i < len(text) {
+			c, width = utf8.DecodeRuneInString(text[i:])
+			if !js_lexer.IsIdentifierContinue(c) {
+				break
+			}
+			i += width
+		}
+		words = append(words, text[start:i])
+	}
+	return
+}
+
+func (imp *tsDeclImport) print(words map[string]bool) (string, bool) {
+	var clauses []string
+	if imp.defaultName != "" && words[imp.defaultName] {
+		clauses = append(clauses, imp.defaultName)
+	}
+	if imp.namespaceName != "" && words[imp.namespaceName] {
+		clauses = append(clauses, "* as "+imp.namespaceName)
+	}
+	var items []string
+	for _, item := range imp.items {
+		if words[item.local] {
+			items = append(items, item.text)
+		}
+	}
+	if len(items) > 0 {
+		clauses = append(clauses, "{ "+strings.Join(items, ", ")+" }")
+	}
+	if len(clauses) == 0 {
+		return "", false
+	}
+	typeKeyword := ""
+	if imp.isTypeOnly {
+		typeKeyword = "type "
+	}
+	return fmt.Sprintf("import %s%s from %s%s;", typeKeyword, strings.Join(clauses, ", "), imp.pathText, imp.assertText), true
+}
+
+func (d tsDeclStmtInfo) prefix() string {
+	if d.isDefault {
+		return "export default "
+	}
+	if d.isExport {
+		return "export "
+	}
+	return ""
+}
+
+// Declarations nested inside a namespace are already in an ambient context
+func (d tsDeclStmtInfo) declareKeyword() string {
+	if d.isNamespace || d.isDefault {
+		return ""
+	}
+	return "declare "
+}
+
+// This returns the end of the previous token, which excludes any whitespace
+// and comments between the previous token and the current token
+func (e *tsDeclEmitter) endOfPreviousToken() int32 {
+	contents := e.p.source.Contents
+	comments := e.p.lexer.AllOriginalComments
+	end := int(e.p.lexer.Loc().Start)
+	i := len(comments) - 1
+
+	for {
+		for end > 0 {
+			if c := contents[end-1]; c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\v' {
+				break
+			}
+			end--
+		}
+		for i >= 0 && int(comments[i].Loc.Start)+len(comments[i].Text) > end {
+			i--
+		}
+		if i < 0 || int(comments[i].Loc.Start)+len(comments[i].Text) != end {
+			return int32(end)
+		}
+		end = int(comments[i].Loc.Start)
+		i--
+	}
+}
+
+func (e *tsDeclEmitter) textFrom(start int32) string {
+	return e.p.source.Contents[start:e.endOfPreviousToken()]
+}
+
+// Documentation comments before the current token are kept in the output
+func (e *tsDeclEmitter) leadingComments(indent string) string {
+	start := e.p.lexer.Loc().Start
+	prevEnd := e.endOfPreviousToken()
+	comments := e.p.lexer.AllOriginalComments
+	sb := strings.Builder{}
+
+	for i := sort.Search(len(comments), func(i int) bool {
+		return comments[i].Loc.Start >= prevEnd
+	}); i < len(comments) && comments[i].Loc.Start < start; i++ {
+		text := comments[i].Text
+		if !strings.HasPrefix(text, "/**") || text == "/**/" {
+			continue
+		}
+		for j, line := range strings.Split(text, "\n") {
+			line = strings.TrimRight(line, "\r")
+			if j > 0 {
+				line = strings.TrimLeft(line, " \t")
+				if strings.HasPrefix(line, "*") {
+					line = " " + line
+				}
+			}
+			sb.WriteString(indent)
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String()
+}
+
+// This returns a copy of the lexer after it has advanced to the next token
+func (e *tsDeclEmitter) peek() js_lexer.Lexer {
+	oldLexer := e.p.lexer
+	e.p.lexer.Next()
+	next := e.p.lexer
+	e.p.lexer = oldLexer
+	return next
+}
+
+func (e *tsDeclEmitter) warnAboutAny(warnings *[]tsDeclWarning, r logger.Range, what string) {
+	*warnings = append(*warnings, tsDeclWarning{
+		r:    r,
+		text: fmt.Sprintf("Using \"any\" for %s in the generated declaration file because it has no type annotation", what),
+	})
+}
+
+func (e *tsDeclEmitter) parseStmts(end js_lexer.T, indent string, isNamespace bool) (units []tsDeclUnit) {
+	for e.p.lexer.Token != end {
+		if unit, ok := e.parseStmt(indent, isNamespace); ok {
+			units = append(units, unit)
+		}
+	}
+	return
+}
+
+func (e *tsDeclEmitter) parseStmt(indent string, isNamespace bool) (tsDeclUnit, bool) {
+	p := e.p
+	prevOverloadName := e.overloadName
+	e.overloadName = ""
+	d := tsDeclStmtInfo{
+		comments:    e.leadingComments(indent),
+		indent:      indent,
+		isNamespace: isNamespace,
+	}
+	opts := parseStmtOpts{
+		isModuleScope:    !isNamespace,
+		isNamespaceScope: isNamespace,
+		lexicalDecl:      lexicalDeclAllowAll,
+	}
+
+	// Decorators are not allowed in declaration files
+	if p.lexer.Token == js_lexer.TAt {
+		p.parseTypeScriptDecorators(p.currentScope)
+	}
+
+	start := p.lexer.Loc()
+	oldLexer := p.lexer
+
+	if p.lexer.Token == js_lexer.TExport {
+		d.isExport = true
+		if !isNamespace {
+			e.isModule = true
+		}
+		p.lexer.Next()
+
+		switch p.lexer.Token {
+		case js_lexer.TDefault:
+			d.isDefault = true
+			p.lexer.Next()
+
+		case js_lexer.TAsterisk, js_lexer.TOpenBrace:
+			return e.parseExportFrom(start, d), true
+
+		case js_lexer.TEquals, js_lexer.TImport:
+			// "export = foo;"
+			// "export import foo = bar;"
+			return e.verbatim(oldLexer, start, opts, d, nil), true
+
+		case js_lexer.TIdentifier:
+			if p.lexer.IsContextualKeyword("as") {
+				// "export as namespace foo;"
+				return e.verbatim(oldLexer, start, opts, d, nil), true
+			}
+			if p.lexer.IsContextualKeyword("type") {
+				// "export type { foo } from 'bar';"
+				if next := e.peek(); next.Token == js_lexer.TOpenBrace || next.Token == js_lexer.TAsterisk {
+					return e.parseExportFrom(start, d), true
+				}
+			}
+		}
+	}
+
+	switch p.lexer.Token {
+	case js_lexer.TFunction:
+		return e.parseFunction(d, prevOverloadName, false)
+
+	case js_lexer.TClass:
+		return e.parseClass(d, false), true
+
+	case js_lexer.TEnum:
+		return e.parseEnum(oldLexer, opts, d), true
+
+	case js_lexer.TConst:
+		if e.peek().Token == js_lexer.TEnum {
+			return e.parseEnum(oldLexer, opts, d), true
+		}
+		if !d.isDefault {
+			return e.parseVars(d), true
+		}
+
+	case js_lexer.TVar:
+		if !d.isDefault {
+			return e.parseVars(d), true
+		}
+
+	case js_lexer.TImport:
+		if !d.isExport {
+			return e.parseImport(oldLexer, start, opts, d)
+		}
+
+	case js_lexer.TIdentifier:
+		next := e.peek()
+		switch p.lexer.Raw() {
+		case "async":
+			if next.Token == js_lexer.TFunction && !next.HasNewlineBefore {
+				p.lexer.Next()
+				return e.parseFunction(d, prevOverloadName, true)
+			}
+
+		case "abstract":
+			if next.Token == js_lexer.TClass && !next.HasNewlineBefore {
+				p.lexer.Next()
+				return e.parseClass(d, true), true
+			}
+
+		case "let":
+			if !d.isDefault && (next.Token == js_lexer.TIdentifier || next.Token == js_lexer.TOpenBrace || next.Token == js_lexer.TOpenBracket) {
+				return e.parseVars(d), true
+			}
+
+		case "interface", "type":
+			if next.Token == js_lexer.TIdentifier && !next.HasNewlineBefore {
+				return e.verbatim(oldLexer, start, opts, d, []string{next.Identifier.String}), true
+			}
+
+		case "namespace", "module":
+			if next.Token == js_lexer.TIdentifier && !next.HasNewlineBefore && !d.isDefault {
+				return e.parseNamespace(d), true
+			}
+
+		case "declare":
+			if next.IsIdentifierOrKeyword() && !next.HasNewlineBefore {
+				unit := e.verbatim(oldLexer, start, opts, d, nil)
+
+				// Global augmentations and ambient modules are always kept
+				afterNext := next
+				afterNext.Next()
+				if next.IsContextualKeyword("global") || (next.IsContextualKeyword("module") && afterNext.Token == js_lexer.TStringLiteral) {
+					unit.isRoot = true
+				} else if name := tsDeclNameAfterDeclare(next); name != "" {
+					unit.names = []string{name}
+				}
+				return unit, true
+			}
+		}
+	}
+
+	if d.isDefault {
+		return e.parseExportDefaultExpr(oldLexer, start, opts, d), true
+	}
+
+	// Everything else only has run-time behavior, so just skip over it
+	p.lexer = oldLexer
+	p.parseStmt(opts)
+	return tsDeclUnit{}, false
+}
+
+// "declare const foo: number" => "foo"
+func tsDeclNameAfterDeclare(next js_lexer.Lexer) string {
+	for {
+		switch next.Token {
+		case js_lexer.TIdentifier:
+			switch next.Raw() {
+			case "abstract", "let", "namespace", "module", "interface", "type":
+			default:
+				return next.Identifier.String
+			}
+
+		case js_lexer.TClass, js_lexer.TConst, js_lexer.TEnum, js_lexer.TFunction, js_lexer.TVar:
+
+		default:
+			return ""
+		}
+		next.Next()
+	}
+}
+
+// Some statements are already valid in declaration files and can just be
+// copied over as-is
+func (e *tsDeclEmitter) verbatim(oldLexer js_lexer.Lexer, start logger.Loc, opts parseStmtOpts, d tsDeclStmtInfo, names []string) tsDeclUnit {
+	e.p.lexer = oldLexer
+	e.p.parseStmt(opts)
+	return tsDeclUnit{
+		text:   d.comments + d.indent + e.textFrom(start.Start),
+		names:  names,
+		isRoot: d.isExport,
+	}
+}
+
+func (e *tsDeclEmitter) parseEnum(oldLexer js_lexer.Lexer, opts parseStmtOpts, d tsDeclStmtInfo) tsDeclUnit {
+	p := e.p
+	start := p.lexer.Loc()
+	name := ""
+	if next := e.peek(); next.Token == js_lexer.TIdentifier {
+		name = next.Identifier.String
+	} else if next.Token == js_lexer.TEnum {
+		next.Next()
+		name = next.Identifier.String
+	}
+
+	// Parse the whole statement again starting from the "export" keyword
+	p.lexer = oldLexer
+	p.parseStmt(opts)
+
+	return tsDeclUnit{
+		text:   d.comments + d.indent + d.prefix() + d.declareKeyword() + e.textFrom(start.Start),
+		names:  []string{name},
+		isRoot: d.isExport,
+	}
+}
+
+func (e *tsDeclEmitter) parseExportFrom(start logger.Loc, d tsDeclStmtInfo) tsDeclUnit {
+	p := e.p
+	var path *logger.Span
+
+	if p.lexer.IsContextualKeyword("type") {
+		p.lexer.Next()
+	}
+
+	if p.lexer.Token == js_lexer.TAsterisk {
+		p.lexer.Next()
+		if p.lexer.IsContextualKeyword("as") {
+			p.lexer.Next()
+			p.lexer.Next()
+		}
+		p.lexer.ExpectContextualKeyword("from")
+		path = e.parsePath()
+	} else {
+		p.lexer.Expect(js_lexer.TOpenBrace)
+		for p.lexer.Token != js_lexer.TCloseBrace {
+			p.lexer.Next()
+		}
+		p.lexer.Next()
+		if p.lexer.IsContextualKeyword("from") {
+			p.lexer.Next()
+			path = e.parsePath()
+		}
+	}
+
+	e.skipImportAssertions()
+	p.lexer.ExpectOrInsertSemicolon()
+	text := e.textFrom(start.Start)
+	if !strings.HasSuffix(text, ";") {
+		text += ";"
+	}
+
+	return tsDeclUnit{
+		text:           d.comments + d.indent + text,
+		exportFrom:     path,
+		isRoot:         true,
+		isExportClause: path == nil,
+	}
+}
+
+func (e *tsDeclEmitter) parsePath() *logger.Span {
+	p := e.p
+	path := &logger.Span{Text: helpers.UTF16ToString(p.lexer.StringLiteral()), Range: p.lexer.Range()}
+	p.lexer.Expect(js_lexer.TStringLiteral)
+	return path
+}
+
+func (e *tsDeclEmitter) skipImportAssertions() string {
+	p := e.p
+	if !p.lexer.IsContextualKeyword("assert") || p.lexer.HasNewlineBefore {
+		return ""
+	}
+	start := p.lexer.Loc().Start
+	p.lexer.Next()
+	p.lexer.Expect(js_lexer.TOpenBrace)
+	for p.lexer.Token != js_lexer.TCloseBrace {
+		p.lexer.Next()
+	}
+	p.lexer.Next()
+	return " " + e.textFrom(start)
+}
+
+func (e *tsDeclEmitter) parseImport(oldLexer js_lexer.Lexer, start logger.Loc, opts parseStmtOpts, d tsDeclStmtInfo) (tsDeclUnit, bool) {
+	p := e.p
+	next := e.peek()
+
+	// Skip over "import()" and "import.meta" expressions
+	if next.Token == js_lexer.TOpenParen || next.Token == js_lexer.TDot {
+		p.parseStmt(opts)
+		return tsDeclUnit{}, false
+	}
+	if !d.isNamespace {
+		e.isModule = true
+	}
+
+	// Side-effect imports don't matter for types
+	if next.Token == js_lexer.TStringLiteral {
+		p.parseStmt(opts)
+		return tsDeclUnit{}, false
+	}
+
+	// "import foo = bar"
+	// "import foo = require('bar')"
+	if next.Token == js_lexer.TIdentifier {
+		name := next.Identifier.String
+		if next.Next(); next.Token == js_lexer.TEquals {
+			return e.verbatim(oldLexer, start, opts, d, []string{name}), true
+		}
+	}
+
+	p.lexer.Next()
+	imp := &tsDeclImport{}
+
+	if p.lexer.IsContextualKeyword("type") {
+		if next := e.peek(); (next.Token == js_lexer.TIdentifier && !next.IsContextualKeyword("from")) ||
+			next.Token == js_lexer.TOpenBrace || next.Token == js_lexer.TAsterisk {
+			imp.isTypeOnly = true
+			p.lexer.Next()
+		}
+	}
+
+	if p.lexer.Token == js_lexer.TIdentifier && !p.lexer.IsContextualKeyword("from") {
+		imp.defaultName = p.lexer.Identifier.String
+		p.lexer.Next()
+		if p.lexer.Token == js_lexer.TComma {
+			p.lexer.Next()
+		}
+	}
+
+	switch p.lexer.Token {
+	case js_lexer.TAsterisk:
+		p.lexer.Next()
+		p.lexer.ExpectContextualKeyword("as")
+		imp.namespaceName = p.lexer.Identifier.String
+		p.lexer.Expect(js_lexer.TIdentifier)
+
+	case js_lexer.TOpenBrace:
+		p.lexer.Next()
+		for p.lexer.Token != js_lexer.TCloseBrace {
+			itemStart := p.lexer.Loc().Start
+			if p.lexer.IsContextualKeyword("type") {
+				if next := e.peek(); next.IsIdentifierOrKeyword() || next.Token == js_lexer.TStringLiteral {
+					p.lexer.Next()
+				}
+			}
+			local := p.lexer.Identifier.String
+			p.lexer.Next()
+			if p.lexer.IsContextualKeyword("as") {
+				p.lexer.Next()
+				local = p.lexer.Identifier.String
+				p.lexer.Expect(js_lexer.TIdentifier)
+			}
+			imp.items = append(imp.items, tsDeclImportItem{text: e.textFrom(itemStart), local: local})
+			if p.lexer.Token != js_lexer.TComma {
+				break
+			}
+			p.lexer.Next()
+		}
+		p.lexer.Expect(js_lexer.TCloseBrace)
+	}
+
+	p.lexer.ExpectContextualKeyword("from")
+	imp.pathText = p.lexer.Raw()
+	imp.path = *e.parsePath()
+	imp.assertText = e.skipImportAssertions()
+	p.lexer.ExpectOrInsertSemicolon()
+	return tsDeclUnit{imp: imp}, true
+}
+
+func (e *tsDeclEmitter) parseFunction(d tsDeclStmtInfo, prevOverloadName string, isAsync bool) (tsDeclUnit, bool) {
+	p := e.p
+	nameRange := p.lexer.Range()
+	p.lexer.Expect(js_lexer.TFunction)
+	isGenerator := false
+	if p.lexer.Token == js_lexer.TAsterisk {
+		isGenerator = true
+		p.lexer.Next()
+	}
+	name := ""
+	if p.lexer.Token == js_lexer.TIdentifier {
+		name = p.lexer.Identifier.String
+		nameRange = p.lexer.Range()
+		p.lexer.Next()
+	}
+
+	fn := e.parseFn(fnOrArrowDataParse{
+		await: awaitOrYieldFor(isAsync),
+		yield: awaitOrYieldFor(isGenerator),
+	})
+
+	// Only the overload signatures are part of the type when there are
+	// overloads. The implementation signature is omitted.
+	if fn.body == nil {
+		e.overloadName = name
+	} else if name != "" && name == prevOverloadName {
+		return tsDeclUnit{}, false
+	}
+
+	unit := tsDeclUnit{isRoot: d.isExport}
+	if name != "" {
+		unit.names = []string{name}
+	}
+	returnType := e.returnType(&unit.warnings, fn, nameRange, name, isAsync, isGenerator)
+	unit.text = fmt.Sprintf("%s%s%s%sfunction %s%s(%s): %s;", d.comments, d.indent, d.prefix(), d.declareKeyword(),
+		name, fn.typeParams, e.printParams(&unit.warnings, fn.params), returnType)
+	return unit, true
+}
+
+func awaitOrYieldFor(isAllowed bool) awaitOrYield {
+	if isAllowed {
+		return allowExpr
+	}
+	return allowIdent
+}
+
+func (e *tsDeclEmitter) parseFn(data fnOrArrowDataParse) (fn tsDeclFn) {
+	p := e.p
+	oldFnOrArrowData := p.fnOrArrowDataParse
+	p.fnOrArrowDataParse = data
+
+	if p.lexer.Token == js_lexer.TLessThan {
+		start := p.lexer.Loc().Start
+		p.skipTypeScriptTypeParameters(typeParametersNormal)
+		fn.typeParams = e.textFrom(start)
+	}
+
+	p.pushScopeForParsePass(js_ast.ScopeFunctionArgs, p.lexer.Loc())
+	fn.params = e.parseParams()
+
+	if p.lexer.Token == js_lexer.TColon {
+		p.lexer.Next()
+		start := p.lexer.Loc().Start
+		p.skipTypeScriptReturnType()
+		fn.returnType = e.textFrom(start)
+	}
+
+	// TypeScript allows function signatures without bodies for overloads
+	if p.lexer.Token == js_lexer.TOpenBrace {
+		body := p.parseFnBody(data)
+		fn.body = &body
+	} else {
+		p.lexer.ExpectOrInsertSemicolon()
+	}
+
+	p.popScope()
+	p.fnOrArrowDataParse = oldFnOrArrowData
+	return
+}
+
+func (e *tsDeclEmitter) parseParams() (params []tsDeclParam) {
+	p := e.p
+	p.lexer.Expect(js_lexer.TOpenParen)
+
+	for p.lexer.Token != js_lexer.TCloseParen {
+		var param tsDeclParam
+
+		if p.lexer.Token == js_lexer.TAt {
+			p.parseTypeScriptDecorators(p.currentScope)
+		}
+
+		// Parameter properties in constructors
+		for p.lexer.Token == js_lexer.TIdentifier {
+			raw := p.lexer.Raw()
+			if raw != "public" && raw != "private" && raw != "protected" && raw != "readonly" && raw != "override" {
+				break
+			}
+			if next := e.peek(); next.Token != js_lexer.TIdentifier && next.Token != js_lexer.TOpenBrace && next.Token != js_lexer.TOpenBracket {
+				break
+			}
+			param.modifiers = append(param.modifiers, raw)
+			p.lexer.Next()
+		}
+
+		if p.lexer.Token == js_lexer.TDotDotDot {
+			param.isRest = true
+			p.lexer.Next()
+		}
+
+		param.nameRange = p.lexer.Range()
+		if p.lexer.Token == js_lexer.TThis {
+			param.name = "this"
+			p.lexer.Next()
+		} else if text, ok := e.bindingText(p.parseBinding()); ok {
+			param.name = text
+		} else {
+			param.name = fmt.Sprintf("_%d", len(params))
+		}
+
+		if p.lexer.Token == js_lexer.TQuestion {
+			param.isOptional = true
+			p.lexer.Next()
+		}
+
+		if p.lexer.Token == js_lexer.TColon {
+			p.lexer.Next()
+			start := p.lexer.Loc().Start
+			p.skipTypeScriptType(js_ast.LLowest)
+			param.typeText = e.textFrom(start)
+		}
+
+		if p.lexer.Token == js_lexer.TEquals {
+			p.lexer.Next()
+			param.defaultValue = p.parseExpr(js_ast.LComma)
+			param.hasDefault = true
+		}
+
+		params = append(params, param)
+		if p.lexer.Token != js_lexer.TComma {
+			break
+		}
+		p.lexer.Next()
+	}
+
+	p.lexer.Expect(js_lexer.TCloseParen)
+	return
+}
+
+// Default values are not allowed in declaration files, so this prints binding
+// patterns without them
+func (e *tsDeclEmitter) bindingText(binding js_ast.Binding) (string, bool) {
+	switch b := binding.Data.(type) {
+	case *js_ast.BMissing:
+		return "", true
+
+	case *js_ast.BIdentifier:
+		return e.p.loadNameFromRef(b.Ref), true
+
+	case *js_ast.BArray:
+		items := make([]string, 0, len(b.Items))
+		for i, item := range b.Items {
+			text, ok := e.bindingText(item.Binding)
+			if !ok {
+				return "", false
+			}
+			if b.HasSpread && i+1 == len(b.Items) {
+				text = "..." + text
+			}
+			items = append(items, text)
+		}
+		return "[" + strings.Join(items, ", ") + "]", true
+
+	case *js_ast.BObject:
+		if len(b.Properties) == 0 {
+			return "{}", true
+		}
+		properties := make([]string, 0, len(b.Properties))
+		for _, property := range b.Properties {
+			value, ok := e.bindingText(property.Value)
+			if !ok {
+				return "", false
+			}
+			if property.IsSpread {
+				properties = append(properties, "..."+value)
+				continue
+			}
+			key, ok := property.Key.Data.(*js_ast.EString)
+			if !ok || property.IsComputed {
+				return "", false
+			}
+			keyText := helpers.UTF16ToString(key.Value)
+			if keyText == value {
+				properties = append(properties, value)
+				continue
+			}
+			if !js_lexer.IsIdentifier(keyText) {
+				keyText = fmt.Sprintf("%q", keyText)
+			}
+			properties = append(properties, keyText+": "+value)
+		}
+		return "{ " + strings.Join(properties, ", ") + " }", true
+	}
+
+	return "", false
+}
+
+func (e *tsDeclEmitter) bindingNames(binding js_ast.Binding, names []string) []string {
+	switch b := binding.Data.(type) {
+	case *js_ast.BIdentifier:
+		names = append(names, e.p.loadNameFromRef(b.Ref))
+
+	case *js_ast.BArray:
+		for _, item := range b.Items {
+			names = e.bindingNames(item.Binding, names)
+		}
+
+	case *js_ast.BObject:
+		for _, property := range b.Properties {
+			names = e.bindingNames(property.Value, names)
+		}
+	}
+	return names
+}
+
+func (e *tsDeclEmitter) printParams(warnings *[]tsDeclWarning, params []tsDeclParam) string {
+	sb := strings.Builder{}
+
+	for i, param := range params {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if param.isRest {
+			sb.WriteString("...")
+		}
+		sb.WriteString(param.name)
+
+		// A parameter with a default value is optional if all parameters after
+		// it are also optional. Otherwise it can only be omitted by explicitly
+		// passing "undefined".
+		isOptional := param.isOptional
+		orUndefined := false
+		if param.hasDefault {
+			isOptional = true
+			for _, after := range params[i+1:] {
+				if !after.isOptional && !after.hasDefault && !after.isRest {
+					isOptional = false
+					orUndefined = true
+					break
+				}
+			}
+		}
+
+		typeText := param.typeText
+		if typeText == "" && param.hasDefault {
+			typeText, _ = tsDeclLiteralType(param.defaultValue)
+		}
+		if typeText == "" {
+			if param.isRest {
+				typeText = "any[]"
+			} else {
+				typeText = "any"
+			}
+			e.warnAboutAny(warnings, param.nameRange, fmt.Sprintf("the type of parameter %q", param.name))
+		} else if orUndefined {
+			if strings.Contains(typeText, "=>") {
+				typeText = "(" + typeText + ")"
+			}
+			typeText += " | undefined"
+		}
+
+		if isOptional {
+			sb.WriteByte('?')
+		}
+		sb.WriteString(": ")
+		sb.WriteString(typeText)
+	}
+
+	return sb.String()
+}
+
+func (e *tsDeclEmitter) returnType(warnings *[]tsDeclWarning, fn tsDeclFn, r logger.Range, name string, isAsync bool, isGenerator bool) string {
+	if fn.returnType != "" {
+		return fn.returnType
+	}
+
+	// Functions that never return a value are easy to handle
+	if fn.body != nil && !isGenerator && !tsDeclReturnsValue(fn.body.Block.Stmts) {
+		if isAsync {
+			return "Promise<void>"
+		}
+		return "void"
+	}
+
+	if name == "" {
+		name = "default"
+	}
+	e.warnAboutAny(warnings, r, fmt.Sprintf("the return type of %q", name))
+	return "any"
+}
+
+func tsDeclReturnsValue(stmts []js_ast.Stmt) bool {
+	for _, stmt := range stmts {
+		if tsDeclStmtReturnsValue(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func tsDeclStmtReturnsValue(stmt js_ast.Stmt) bool {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SReturn:
+		return s.ValueOrNil.Data != nil
+
+	case *js_ast.SBlock:
+		return tsDeclReturnsValue(s.Stmts)
+
+	case *js_ast.SIf:
+		return tsDeclStmtReturnsValue(s.Yes) || (s.NoOrNil.Data != nil && tsDeclStmtReturnsValue(s.NoOrNil))
+
+	case *js_ast.SFor:
+		return tsDeclStmtReturnsValue(s.Body)
+
+	case *js_ast.SForIn:
+		return tsDeclStmtReturnsValue(s.Body)
+
+	case *js_ast.SForOf:
+		return tsDeclStmtReturnsValue(s.Body)
+
+	case *js_ast.SWhile:
+		return tsDeclStmtReturnsValue(s.Body)
+
+	case *js_ast.SDoWhile:
+		return tsDeclStmtReturnsValue(s.Body)
+
+	case *js_ast.SWith:
+		return tsDeclStmtReturnsValue(s.Body)
+
+	case *js_ast.SLabel:
+		return tsDeclStmtReturnsValue(s.Stmt)
+
+	case *js_ast.STry:
+		return tsDeclReturnsValue(s.Block.Stmts) ||
+			(s.Catch != nil && tsDeclReturnsValue(s.Catch.Block.Stmts)) ||
+			(s.Finally != nil && tsDeclReturnsValue(s.Finally.Block.Stmts))
+
+	case *js_ast.SSwitch:
+		for _, c := range s.Cases {
+			if tsDeclReturnsValue(c.Body) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// This returns the widened type of a literal, and whether the literal itself
+// can be used as the initializer of a constant in a declaration file
+func tsDeclLiteralType(expr js_ast.Expr) (string, bool) {
+	switch e := expr.Data.(type) {
+	case *js_ast.ENumber:
+		return "number", true
+
+	case *js_ast.EString:
+		return "string", true
+
+	case *js_ast.EBoolean:
+		return "boolean", true
+
+	case *js_ast.EBigInt:
+		return "bigint", true
+
+	case *js_ast.EUnary:
+		if e.Op == js_ast.UnOpNeg {
+			switch e.Value.Data.(type) {
+			case *js_ast.ENumber:
+				return "number", true
+			case *js_ast.EBigInt:
+				return "bigint", true
+			}
+		}
+
+	case *js_ast.ETemplate:
+		if e.TagOrNil.Data == nil {
+			return "string", false
+		}
+	}
+
+	return "", false
+}
+
+// This tries to parse the signature of an arrow function or a function
+// expression with type annotations without consuming any tokens
+func (e *tsDeclEmitter) tryParseFnSignature() (fn tsDeclFn, isAsync bool, isGenerator bool, ok bool) {
+	p := e.p
+	oldLexer := p.lexer
+	oldScope := p.currentScope
+	oldChildCount := len(oldScope.Children)
+	oldScopeCount := len(p.scopesInOrder)
+	oldFnOrArrowData := p.fnOrArrowDataParse
+
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); !isLexerPanic && r != nil {
+			panic(r)
+		}
+		p.lexer = oldLexer
+		p.currentScope = oldScope
+		oldScope.Children = oldScope.Children[:oldChildCount]
+		p.scopesInOrder = p.scopesInOrder[:oldScopeCount]
+		p.fnOrArrowDataParse = oldFnOrArrowData
+	}()
+
+	// This is only a lookahead, so something like "<const>1" shouldn't be
+	// reported as a syntax error
+	p.lexer.IsLogDisabled = true
+
+	isArrow := true
+	if p.lexer.IsContextualKeyword("async") {
+		isAsync = true
+		p.lexer.Next()
+		if p.lexer.HasNewlineBefore {
+			return
+		}
+	}
+	if p.lexer.Token == js_lexer.TFunction {
+		isArrow = false
+		p.lexer.Next()
+		if p.lexer.Token == js_lexer.TAsterisk {
+			isGenerator = true
+			p.lexer.Next()
+		}
+		if p.lexer.Token == js_lexer.TIdentifier {
+			p.lexer.Next()
+		}
+	}
+
+	if p.lexer.Token == js_lexer.TLessThan {
+		start := p.lexer.Loc().Start
+		p.skipTypeScriptTypeParameters(typeParametersNormal)
+		fn.typeParams = e.textFrom(start)
+	}
+	if p.lexer.Token != js_lexer.TOpenParen {
+		return
+	}
+	p.pushScopeForParsePass(js_ast.ScopeFunctionArgs, p.lexer.Loc())
+	fn.params = e.parseParams()
+	if p.lexer.Token == js_lexer.TColon {
+		p.lexer.Next()
+		start := p.lexer.Loc().Start
+		p.skipTypeScriptReturnType()
+		fn.returnType = e.textFrom(start)
+	}
+
+	if isArrow {
+		ok = p.lexer.Token == js_lexer.TEqualsGreaterThan
+	} else {
+		ok = p.lexer.Token == js_lexer.TOpenBrace
+	}
+	return
+}
+
+func (e *tsDeclEmitter) parseInitializer(warnings *[]tsDeclWarning, r logger.Range, name string) (init tsDeclInit) {
+	p := e.p
+	fn, isAsync, isGenerator, isFn := e.tryParseFnSignature()
+	start := p.lexer.Loc().Start
+	expr := p.parseExpr(js_ast.LComma)
+	init.text = e.textFrom(start)
+
+	if typeText, isLiteral := tsDeclLiteralType(expr); typeText != "" {
+		init.typeText = typeText
+		init.isLiteral = isLiteral
+		if isLiteral {
+			e.applyTypeAssertion(&init, expr, start)
+		}
+		return
+	}
+
+	if isFn {
+		switch fnExpr := expr.Data.(type) {
+		case *js_ast.EArrow:
+			if !fnExpr.PreferExpr {
+				fn.body = &fnExpr.Body
+			}
+		case *js_ast.EFunction:
+			fn.body = &fnExpr.Fn.Body
+		default:
+			isFn = false
+		}
+	}
+
+	if isFn {
+		if fn.returnType == "" && fn.body == nil {
+			e.warnAboutAny(warnings, r, fmt.Sprintf("the return type of %q", name))
+			fn.returnType = "any"
+		}
+		returnType := e.returnType(warnings, fn, r, name, isAsync, isGenerator)
+		init.typeText = fmt.Sprintf("%s(%s) => %s", fn.typeParams, e.printParams(warnings, fn.params), returnType)
+	}
+	return
+}
+
+// The parser skips over type assertions, so "1 as const" is parsed as "1".
+// Declaration files can't contain type assertions, so "as const" turns into
+// the type of the literal and other assertions replace the type of the
+// literal with the asserted type.
+func (e *tsDeclEmitter) applyTypeAssertion(init *tsDeclInit, expr js_ast.Expr, start int32) {
+	contents := e.p.source.Contents
+	valueLoc := expr.Loc
+	if unary, ok := expr.Data.(*js_ast.EUnary); ok {
+		valueLoc = unary.Value.Loc
+	}
+	literalEnd := js_lexer.RangeOfTokenAt(e.p.source, valueLoc).End()
+	literal := contents[expr.Loc.Start:literalEnd]
+	before := strings.Join(strings.Fields(contents[start:expr.Loc.Start]), " ")
+	after := strings.Join(strings.Fields(contents[literalEnd:e.endOfPreviousToken()]), " ")
+	init.text = literal
+
+	switch {
+	case before == "" && after == "":
+
+	case (before == "" && after == "as const") || (before == "<const>" && after == ""):
+		init.typeText = literal
+
+	case before == "" && strings.HasPrefix(after, "as "):
+		init.typeText = strings.TrimPrefix(after, "as ")
+		init.isLiteral = false
+
+	case strings.HasPrefix(before, "<") && strings.HasSuffix(before, ">") && after == "":
+		init.typeText = before[1 : len(before)-1]
+		init.isLiteral = false
+
+	default:
+		init.isLiteral = false
+	}
+}
+
+func (e *tsDeclEmitter) parseVars(d tsDeclStmtInfo) tsDeclUnit {
+	p := e.p
+	kind := p.lexer.Raw()
+	p.lexer.Next()
+	unit := tsDeclUnit{isRoot: d.isExport}
+	var decls []string
+
+	for {
+		nameRange := p.lexer.Range()
+
+		if p.lexer.Token != js_lexer.TIdentifier {
+			// Destructuring patterns are expanded into separate variables
+			binding := p.parseBinding()
+			if p.lexer.Token == js_lexer.TColon {
+				p.lexer.Next()
+				p.skipTypeScriptType(js_ast.LLowest)
+			}
+			if p.lexer.Token == js_lexer.TEquals {
+				p.lexer.Next()
+				p.parseExpr(js_ast.LComma)
+			}
+			for _, name := range e.bindingNames(binding, nil) {
+				decls = append(decls, name+": any")
+				unit.names = append(unit.names, name)
+				e.warnAboutAny(&unit.warnings, nameRange, fmt.Sprintf("the type of %q", name))
+			}
+		} else {
+			name := p.lexer.Identifier.String
+			p.lexer.Next()
+			if p.lexer.Token == js_lexer.TExclamation {
+				p.lexer.Next()
+			}
+
+			typeText := ""
+			if p.lexer.Token == js_lexer.TColon {
+				p.lexer.Next()
+				start := p.lexer.Loc().Start
+				p.skipTypeScriptType(js_ast.LLowest)
+				typeText = e.textFrom(start)
+			}
+
+			decl := name
+			if p.lexer.Token == js_lexer.TEquals {
+				p.lexer.Next()
+				var initWarnings []tsDeclWarning
+				init := e.parseInitializer(&initWarnings, nameRange, name)
+				switch {
+				case typeText != "":
+					decl += ": " + typeText
+				case init.isLiteral && kind == "const":
+					decl += " = " + init.text
+				case init.typeText != "":
+					decl += ": " + init.typeText
+					unit.warnings = append(unit.warnings, initWarnings...)
+				default:
+					decl += ": any"
+					e.warnAboutAny(&unit.warnings, nameRange, fmt.Sprintf("the type of %q", name))
+				}
+			} else if typeText != "" {
+				decl += ": " + typeText
+			} else {
+				decl += ": any"
+			}
+
+			decls = append(decls, decl)
+			unit.names = append(unit.names, name)
+		}
+
+		if p.lexer.Token != js_lexer.TComma {
+			break
+		}
+		p.lexer.Next()
+	}
+
+	p.lexer.ExpectOrInsertSemicolon()
+	unit.text = fmt.Sprintf("%s%s%s%s%s %s;", d.comments, d.indent, d.prefix(), d.declareKeyword(), kind, strings.Join(decls, ", "))
+	return unit
+}
+
+func (e *tsDeclEmitter) parseExportDefaultExpr(oldLexer js_lexer.Lexer, start logger.Loc, opts parseStmtOpts, d tsDeclStmtInfo) tsDeclUnit {
+	p := e.p
+
+	// "export default foo;"
+	if p.lexer.Token == js_lexer.TIdentifier {
+		if next := e.peek(); next.Token == js_lexer.TSemicolon || next.Token == js_lexer.TEndOfFile || next.HasNewlineBefore {
+			return e.verbatim(oldLexer, start, opts, d, nil)
+		}
+	}
+
+	unit := tsDeclUnit{isRoot: true}
+	r := p.lexer.Range()
+	init := e.parseInitializer(&unit.warnings, r, "default")
+	p.lexer.ExpectOrInsertSemicolon()
+	typeText := init.typeText
+	if typeText == "" {
+		typeText = "any"
+		e.warnAboutAny(&unit.warnings, r, "the default export")
+	}
+	unit.text = fmt.Sprintf("%s%sdeclare const _default: %s;\n%sexport default _default;", d.comments, d.indent, typeText, d.indent)
+	return unit
+}
+
+func (e *tsDeclEmitter) parseNamespace(d tsDeclStmtInfo) tsDeclUnit {
+	p := e.p
+	p.lexer.Next()
+	nameStart := p.lexer.Loc().Start
+	name := p.lexer.Identifier.String
+	p.lexer.Expect(js_lexer.TIdentifier)
+	for p.lexer.Token == js_lexer.TDot {
+		p.lexer.Next()
+		p.lexer.Expect(js_lexer.TIdentifier)
+	}
+	nameText := e.textFrom(nameStart)
+
+	oldFnOrArrowData := p.fnOrArrowDataParse
+	p.fnOrArrowDataParse = fnOrArrowDataParse{
+		isThisDisallowed:   true,
+		isReturnDisallowed: true,
+	}
+	p.pushScopeForParsePass(js_ast.ScopeEntry, p.lexer.Loc())
+	p.lexer.Expect(js_lexer.TOpenBrace)
+	units := e.parseStmts(js_lexer.TCloseBrace, d.indent+"    ", true)
+	p.lexer.Next()
+	p.popScope()
+	p.fnOrArrowDataParse = oldFnOrArrowData
+
+	// Only exported members of a namespace are visible from outside of it
+	unit := tsDeclUnit{names: []string{name}, isRoot: d.isExport}
+	included, _ := tsDeclIncludedUnits(units, false)
+	sb := strings.Builder{}
+	for i, inner := range units {
+		if included[i] {
+			sb.WriteString(inner.text)
+			sb.WriteByte('\n')
+			unit.warnings = append(unit.warnings, inner.warnings...)
+		}
+	}
+
+	unit.text = fmt.Sprintf("%s%s%s%snamespace %s {\n%s%s}", d.comments, d.indent, d.prefix(), d.declareKeyword(), nameText, sb.String(), d.indent)
+	return unit
+}
+
+func (e *tsDeclEmitter) parseClass(d tsDeclStmtInfo, isAbstract bool) tsDeclUnit {
+	p := e.p
+	nameRange := p.lexer.Range()
+	p.lexer.Expect(js_lexer.TClass)
+	unit := tsDeclUnit{isRoot: d.isExport}
+
+	name := ""
+	if p.lexer.Token == js_lexer.TIdentifier && !p.lexer.IsContextualKeyword("implements") {
+		name = p.lexer.Identifier.String
+		nameRange = p.lexer.Range()
+		unit.names = []string{name}
+		p.lexer.Next()
+	}
+
+	typeParams := ""
+	if p.lexer.Token == js_lexer.TLessThan {
+		start := p.lexer.Loc().Start
+		p.skipTypeScriptTypeParameters(typeParametersWithInOutVarianceAnnotations)
+		typeParams = e.textFrom(start)
+	}
+
+	heritage := ""
+	if p.lexer.Token == js_lexer.TExtends || p.lexer.IsContextualKeyword("implements") {
+		start := p.lexer.Loc().Start
+		if p.lexer.Token == js_lexer.TExtends {
+			p.lexer.Next()
+			extendsStart := p.lexer.Loc().Start
+			p.parseExpr(js_ast.LNew)
+			if !tsDeclIsEntityName(e.textFrom(extendsStart)) {
+				if name == "" {
+					name = "default"
+				}
+				unit.warnings = append(unit.warnings, tsDeclWarning{
+					r: nameRange,
+					text: fmt.Sprintf("The base class of %q was copied into the generated declaration file as-is "+
+						"because it's not a simple reference to another class", name),
+				})
+			}
+			p.skipTypeScriptTypeArguments(false /* isInsideJSXElement */)
+		}
+		if p.lexer.IsContextualKeyword("implements") {
+			p.lexer.Next()
+			for {
+				p.skipTypeScriptType(js_ast.LLowest)
+				if p.lexer.Token != js_lexer.TComma {
+					break
+				}
+				p.lexer.Next()
+			}
+		}
+		heritage = " " + e.textFrom(start)
+	}
+
+	oldAllowIn := p.allowIn
+	oldAllowPrivateIdentifiers := p.allowPrivateIdentifiers
+	oldFnOrArrowData := p.fnOrArrowDataParse
+	p.allowIn = true
+	p.allowPrivateIdentifiers = true
+	p.fnOrArrowDataParse = fnOrArrowDataParse{allowSuperProperty: true}
+	p.pushScopeForParsePass(js_ast.ScopeClassBody, p.lexer.Loc())
+	p.lexer.Expect(js_lexer.TOpenBrace)
+	members := e.parseClassMembers(&unit.warnings, d.indent+"    ")
+	p.lexer.Expect(js_lexer.TCloseBrace)
+	p.popScope()
+	p.allowIn = oldAllowIn
+	p.allowPrivateIdentifiers = oldAllowPrivateIdentifiers
+	p.fnOrArrowDataParse = oldFnOrArrowData
+
+	abstractKeyword := ""
+	if isAbstract {
+		abstractKeyword = "abstract "
+	}
+	className := ""
+	if name := unit.names; len(name) > 0 {
+		className = " " + name[0]
+	}
+	unit.text = fmt.Sprintf("%s%s%s%s%sclass%s%s%s {\n%s%s}", d.comments, d.indent, d.prefix(), d.declareKeyword(),
+		abstractKeyword, className, typeParams, heritage, members, d.indent)
+	return unit
+}
+
+func tsDeclIsEntityName(text string) bool {
+	for _, part := range strings.Split(text, ".") {
+		if !js_lexer.IsIdentifier(strings.TrimSpace(part)) {
+			return false
+		}
+	}
+	return true
+}
+
+var tsDeclClassModifiers = map[string]bool{
+	"abstract":  true,
+	"accessor":  true,
+	"async":     true,
+	"declare":   true,
+	"override":  true,
+	"private":   true,
+	"protected": true,
+	"public":    true,
+	"readonly":  true,
+	"static":    true,
+}
+
+func tsDeclStartsClassMemberKey(next *js_lexer.Lexer) bool {
+	switch next.Token {
+	case js_lexer.TPrivateIdentifier, js_lexer.TStringLiteral, js_lexer.TNumericLiteral,
+		js_lexer.TBigIntegerLiteral, js_lexer.TOpenBracket, js_lexer.TAsterisk:
+		return true
+	}
+	return next.IsIdentifierOrKeyword()
+}
+
+func (e *tsDeclEmitter) parseClassMembers(warnings *[]tsDeclWarning, indent string) string {
+	p := e.p
+	sb := strings.Builder{}
+	hasPrivateNames := false
+	privateMembers := make(map[string]bool)
+	overloadKey := ""
+
+	for p.lexer.Token != js_lexer.TCloseBrace {
+		if p.lexer.Token == js_lexer.TSemicolon {
+			p.lexer.Next()
+			continue
+		}
+
+		comments := e.leadingComments(indent)
+		if p.lexer.Token == js_lexer.TAt {
+			p.parseTypeScriptDecorators(p.currentScope)
+		}
+
+		// Parse the modifiers. Some of them don't make sense in a declaration
+		// file and are omitted.
+		var modifiers []string
+		isStatic := false
+		isPrivate := false
+		isReadonly := false
+		isAsync := false
+		isStaticBlock := false
+		for p.lexer.Token == js_lexer.TIdentifier && tsDeclClassModifiers[p.lexer.Raw()] {
+			raw := p.lexer.Raw()
+			next := e.peek()
+			if raw == "static" && next.Token == js_lexer.TOpenBrace {
+				isStaticBlock = true
+				p.lexer.Next()
+				break
+			}
+			if !tsDeclStartsClassMemberKey(&next) {
+				break
+			}
+			switch raw {
+			case "static":
+				isStatic = true
+			case "private":
+				isPrivate = true
+			case "readonly":
+				isReadonly = true
+			case "async":
+				isAsync = true
+			}
+			if raw != "async" && raw != "declare" && raw != "override" {
+				modifiers = append(modifiers, raw)
+			}
+			p.lexer.Next()
+		}
+
+		// Class static blocks only have run-time behavior
+		if isStaticBlock {
+			p.parseStmt(parseStmtOpts{})
+			continue
+		}
+
+		modifierText := ""
+		if len(modifiers) > 0 {
+			modifierText = strings.Join(modifiers, " ") + " "
+		}
+
+		accessorKind := ""
+		if p.lexer.IsContextualKeyword("get") || p.lexer.IsContextualKeyword("set") {
+			if next := e.peek(); next.Token != js_lexer.TAsterisk && tsDeclStartsClassMemberKey(&next) {
+				accessorKind = p.lexer.Raw()
+				p.lexer.Next()
+			}
+		}
+
+		isGenerator := false
+		if p.lexer.Token == js_lexer.TAsterisk {
+			isGenerator = true
+			p.lexer.Next()
+		}
+
+		keyRange := p.lexer.Range()
+		key := ""
+		isPrivateName := false
+		switch p.lexer.Token {
+		case js_lexer.TPrivateIdentifier:
+			isPrivateName = true
+			key = p.lexer.Raw()
+			p.lexer.Next()
+
+		case js_lexer.TStringLiteral, js_lexer.TNumericLiteral, js_lexer.TBigIntegerLiteral:
+			key = p.lexer.Raw()
+			p.lexer.Next()
+
+		case js_lexer.TOpenBracket:
+			// "[key: string]: any"
+			if next := e.peek(); next.Token == js_lexer.TIdentifier {
+				if next.Next(); next.Token == js_lexer.TColon {
+					start := p.lexer.Loc().Start
+					p.lexer.Next()
+					p.lexer.Next()
+					p.lexer.Expect(js_lexer.TColon)
+					p.skipTypeScriptType(js_ast.LLowest)
+					p.lexer.Expect(js_lexer.TCloseBracket)
+					p.lexer.Expect(js_lexer.TColon)
+					p.skipTypeScriptType(js_ast.LLowest)
+					text := e.textFrom(start)
+					p.lexer.ExpectOrInsertSemicolon()
+					sb.WriteString(fmt.Sprintf("%s%s%s%s;\n", comments, indent, modifierText, text))
+					continue
+				}
+			}
+
+			start := p.lexer.Loc().Start
+			p.lexer.Next()
+			p.parseExpr(js_ast.LComma)
+			p.lexer.Expect(js_lexer.TCloseBracket)
+			key = e.textFrom(start)
+
+		default:
+			if !p.lexer.IsIdentifierOrKeyword() {
+				p.lexer.Unexpected()
+			}
+			key = p.lexer.Raw()
+			p.lexer.Next()
+		}
+
+		optional := ""
+		if p.lexer.Token == js_lexer.TQuestion {
+			optional = "?"
+			p.lexer.Next()
+		} else if p.lexer.Token == js_lexer.TExclamation {
+			p.lexer.Next()
+		}
+
+		// Private members are emitted without any type information
+		printPrivate := func() {
+			if !privateMembers[key] {
+				privateMembers[key] = true
+				sb.WriteString(fmt.Sprintf("%s%s%s;\n", indent, modifierText, key))
+			}
+		}
+
+		if p.lexer.Token == js_lexer.TOpenParen || p.lexer.Token == js_lexer.TLessThan {
+			isConstructor := key == "constructor" && !isStatic && accessorKind == ""
+			fn := e.parseFn(fnOrArrowDataParse{
+				await:              awaitOrYieldFor(isAsync),
+				yield:              awaitOrYieldFor(isGenerator),
+				allowSuperCall:     isConstructor,
+				allowSuperProperty: true,
+				isConstructor:      isConstructor,
+			})
+
+			// Omit the implementation signature when there are overloads
+			if fn.body == nil {
+				overloadKey = accessorKind + key
+			} else {
+				isOverloadImplementation := overloadKey == accessorKind+key
+				overloadKey = ""
+				if isOverloadImplementation {
+					continue
+				}
+			}
+
+			if isPrivateName {
+				hasPrivateNames = true
+				continue
+			}
+
+			if isConstructor {
+				// Parameter properties turn into class fields
+				for i, param := range fn.params {
+					if len(param.modifiers) == 0 {
+						continue
+					}
+					var paramModifiers []string
+					isPrivateParam := false
+					for _, modifier := range param.modifiers {
+						switch modifier {
+						case "private":
+							isPrivateParam = true
+							paramModifiers = append(paramModifiers, modifier)
+						case "protected", "readonly":
+							paramModifiers = append(paramModifiers, modifier)
+						}
+					}
+					fieldModifiers := ""
+					if len(paramModifiers) > 0 {
+						fieldModifiers = strings.Join(paramModifiers, " ") + " "
+					}
+					if isPrivateParam {
+						sb.WriteString(fmt.Sprintf("%s%s%s;\n", indent, fieldModifiers, param.name))
+					} else {
+						field := param
+						field.hasDefault = field.hasDefault && i+1 == len(fn.params)
+						sb.WriteString(fmt.Sprintf("%s%s%s;\n", indent, fieldModifiers, e.printParams(warnings, []tsDeclParam{field})))
+					}
+					fn.params[i].modifiers = nil
+				}
+				if isPrivate {
+					sb.WriteString(fmt.Sprintf("%s%s%sconstructor();\n", comments, indent, modifierText))
+				} else {
+					sb.WriteString(fmt.Sprintf("%s%s%sconstructor(%s);\n", comments, indent, modifierText, e.printParams(warnings, fn.params)))
+				}
+				continue
+			}
+
+			if isPrivate {
+				printPrivate()
+				continue
+			}
+
+			switch accessorKind {
+			case "get":
+				returnType := fn.returnType
+				if returnType == "" {
+					returnType = "any"
+					e.warnAboutAny(warnings, keyRange, fmt.Sprintf("the type of %q", key))
+				}
+				sb.WriteString(fmt.Sprintf("%s%s%sget %s(): %s;\n", comments, indent, modifierText, key, returnType))
+
+			case "set":
+				sb.WriteString(fmt.Sprintf("%s%s%sset %s(%s);\n", comments, indent, modifierText, key, e.printParams(warnings, fn.params)))
+
+			default:
+				returnType := e.returnType(warnings, fn, keyRange, key, isAsync, isGenerator)
+				sb.WriteString(fmt.Sprintf("%s%s%s%s%s%s(%s): %s;\n", comments, indent, modifierText, key, optional,
+					fn.typeParams, e.printParams(warnings, fn.params), returnType))
+			}
+			continue
+		}
+
+		// Otherwise, this is a class field
+		typeText := ""
+		if p.lexer.Token == js_lexer.TColon {
+			p.lexer.Next()
+			start := p.lexer.Loc().Start
+			p.skipTypeScriptType(js_ast.LLowest)
+			typeText = e.textFrom(start)
+		}
+		var init tsDeclInit
+		var initWarnings []tsDeclWarning
+		hasInit := false
+		if p.lexer.Token == js_lexer.TEquals {
+			p.lexer.Next()
+			oldFnOrArrowData := p.fnOrArrowDataParse
+			p.fnOrArrowDataParse = fnOrArrowDataParse{allowSuperProperty: true}
+			init = e.parseInitializer(&initWarnings, keyRange, key)
+			p.fnOrArrowDataParse = oldFnOrArrowData
+			hasInit = true
+		}
+		p.lexer.ExpectOrInsertSemicolon()
+
+		if isPrivateName {
+			hasPrivateNames = true
+			continue
+		}
+		if isPrivate {
+			printPrivate()
+			continue
+		}
+
+		switch {
+		case typeText != "":
+			typeText = ": " + typeText
+		case init.isLiteral && isReadonly && optional == "":
+			typeText = " = " + init.text
+		case init.typeText != "":
+			typeText = ": " + init.typeText
+			*warnings = append(*warnings, initWarnings...)
+		default:
+			typeText = ": any"
+			if hasInit {
+				e.warnAboutAny(warnings, keyRange, fmt.Sprintf("the type of %q", key))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%s%s%s%s%s%s;\n", comments, indent, modifierText, key, optional, typeText))
+	}
+
+	// Classes with private names are marked so that they aren't considered to
+	// be structurally compatible with other classes
+	if hasPrivateNames {
+		return indent + "#private;\n" + sb.String()
+	}
+	return sb.String()
+}
@@ -142,22 +142,83 @@ func (p *parser) skipTypeScriptFnArgs() {
 //     let x = (y: any): (y) => {return 0};
 //     let x = (y: any): asserts y is (y) => {};
 //
-func (p *parser) skipTypeScriptParenOrFnType() {
+func (p *parser) skipTypeScriptParenOrFnType() (metadata tsTypeMetadata) {
 	if p.trySkipTypeScriptArrowArgsWithBacktracking() {
 		p.skipTypeScriptReturnType()
+		metadata.kind = tsTypeMetadataFunction
 	} else {
 		p.lexer.Expect(js_lexer.TOpenParen)
-		p.skipTypeScriptType(js_ast.LLowest)
+		metadata = p.skipTypeScriptType(js_ast.LLowest)
 		p.lexer.Expect(js_lexer.TCloseParen)
 	}
+	return
+}
+
+func (p *parser) skipTypeScriptReturnType() tsTypeMetadata {
+	return p.skipTypeScriptTypeWithOpts(js_ast.LLowest, skipTypeOpts{isReturnType: true})
 }
 
-func (p *parser) skipTypeScriptReturnType() {
-	p.skipTypeScriptTypeWithOpts(js_ast.LLowest, skipTypeOpts{isReturnType: true})
+func (p *parser) skipTypeScriptType(level js_ast.L) tsTypeMetadata {
+	return p.skipTypeScriptTypeWithOpts(level, skipTypeOpts{})
+}
+
+// TypeScript's "emitDecoratorMetadata" setting turns type annotations into
+// runtime values. We don't have any type information, so this is just the
+// part of the syntax of the type that's needed to pick the runtime value.
+// This is what the TypeScript compiler does in "isolatedModules" mode.
+type tsTypeMetadataKind uint8
+
+const (
+	tsTypeMetadataObject    tsTypeMetadataKind = iota
+	tsTypeMetadataUndefined                    // "null", "undefined", and "never" are ignored in unions
+	tsTypeMetadataVoid
+	tsTypeMetadataNumber
+	tsTypeMetadataString
+	tsTypeMetadataBoolean
+	tsTypeMetadataBigInt
+	tsTypeMetadataSymbol
+	tsTypeMetadataFunction
+	tsTypeMetadataArray
+	tsTypeMetadataReference
+)
+
+type tsTypeMetadata struct {
+	// This is only used for "tsTypeMetadataArray"
+	elementOrNil *tsTypeMetadata
+
+	// These are only used for "tsTypeMetadataReference". Type references such
+	// as "a.b.C" are stored as "a" followed by the members "b" and "C".
+	name    js_lexer.MaybeSubstring
+	members []string
+	loc     logger.Loc
+
+	kind tsTypeMetadataKind
 }
 
-func (p *parser) skipTypeScriptType(level js_ast.L) {
-	p.skipTypeScriptTypeWithOpts(level, skipTypeOpts{})
+// This follows the rules that the TypeScript compiler uses for union and
+// intersection types: if all non-nullable constituents serialize to the
+// same value then that value is used, otherwise "Object" is used.
+func (a tsTypeMetadata) mergeWith(b tsTypeMetadata) tsTypeMetadata {
+	if a.kind == tsTypeMetadataUndefined {
+		return b
+	}
+	if b.kind == tsTypeMetadataUndefined {
+		return a
+	}
+	if a.kind != b.kind {
+		return tsTypeMetadata{}
+	}
+	if a.kind == tsTypeMetadataReference {
+		if a.name.String != b.name.String || len(a.members) != len(b.members) {
+			return tsTypeMetadata{}
+		}
+		for i, member := range a.members {
+			if member != b.members[i] {
+				return tsTypeMetadata{}
+			}
+		}
+	}
+	return a
 }
 
 type skipTypeOpts struct {
@@ -199,13 +260,45 @@ var tsTypeIdentifierMap = map[string]tsTypeIdentifierKind{
 	"symbol":    tsTypeIdentifierPrimitive,
 }
 
-func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
+var tsTypeMetadataPrimitives = map[string]tsTypeMetadataKind{
+	"any":       tsTypeMetadataObject,
+	"unknown":   tsTypeMetadataObject,
+	"object":    tsTypeMetadataObject,
+	"never":     tsTypeMetadataUndefined,
+	"undefined": tsTypeMetadataUndefined,
+	"number":    tsTypeMetadataNumber,
+	"string":    tsTypeMetadataString,
+	"boolean":   tsTypeMetadataBoolean,
+	"bigint":    tsTypeMetadataBigInt,
+	"symbol":    tsTypeMetadataSymbol,
+}
+
+func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) (metadata tsTypeMetadata) {
 	for {
 		switch p.lexer.Token {
-		case js_lexer.TNumericLiteral, js_lexer.TBigIntegerLiteral, js_lexer.TStringLiteral,
-			js_lexer.TNoSubstitutionTemplateLiteral, js_lexer.TTrue, js_lexer.TFalse,
-			js_lexer.TNull, js_lexer.TVoid:
+		case js_lexer.TNumericLiteral:
 			p.lexer.Next()
+			metadata.kind = tsTypeMetadataNumber
+
+		case js_lexer.TBigIntegerLiteral:
+			p.lexer.Next()
+			metadata.kind = tsTypeMetadataBigInt
+
+		case js_lexer.TStringLiteral, js_lexer.TNoSubstitutionTemplateLiteral:
+			p.lexer.Next()
+			metadata.kind = tsTypeMetadataString
+
+		case js_lexer.TTrue, js_lexer.TFalse:
+			p.lexer.Next()
+			metadata.kind = tsTypeMetadataBoolean
+
+		case js_lexer.TNull:
+			p.lexer.Next()
+			metadata.kind = tsTypeMetadataUndefined
+
+		case js_lexer.TVoid:
+			p.lexer.Next()
+			metadata.kind = tsTypeMetadataVoid
 
 		case js_lexer.TConst:
 			r := p.lexer.Range()
@@ -223,6 +316,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			if p.lexer.IsContextualKeyword("is") && !p.lexer.HasNewlineBefore {
 				p.lexer.Next()
 				p.skipTypeScriptType(js_ast.LLowest)
+				metadata.kind = tsTypeMetadataBoolean
 				return
 			}
 
@@ -232,8 +326,10 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			p.lexer.Next()
 			if p.lexer.Token == js_lexer.TBigIntegerLiteral {
 				p.lexer.Next()
+				metadata.kind = tsTypeMetadataBigInt
 			} else {
 				p.lexer.Expect(js_lexer.TNumericLiteral)
+				metadata.kind = tsTypeMetadataNumber
 			}
 
 		case js_lexer.TAmpersand:
@@ -279,20 +375,25 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 
 			p.skipTypeScriptTypeParameters(typeParametersNormal)
 			p.skipTypeScriptParenOrFnType()
+			metadata.kind = tsTypeMetadataFunction
 
 		case js_lexer.TLessThan:
 			// "<T>() => Foo<T>"
 			p.skipTypeScriptTypeParameters(typeParametersNormal)
 			p.skipTypeScriptParenOrFnType()
+			metadata.kind = tsTypeMetadataFunction
 
 		case js_lexer.TOpenParen:
 			// "(number | string)"
-			p.skipTypeScriptParenOrFnType()
+			metadata = p.skipTypeScriptParenOrFnType()
 
 		case js_lexer.TIdentifier:
 			kind := tsTypeIdentifierMap[p.lexer.Identifier.String]
+			name := p.lexer.Identifier
+			nameLoc := p.lexer.Loc()
 
 			if kind == tsTypeIdentifierPrefix {
+				isReadonly := name.String == "readonly"
 				p.lexer.Next()
 
 				// Valid:
@@ -303,7 +404,10 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				//   "A extends B ? keyof : string"
 				//
 				if p.lexer.Token != js_lexer.TColon || (!opts.isIndexSignature && !opts.allowTupleLabels) {
-					p.skipTypeScriptType(js_ast.LPrefix)
+					// "readonly string[]" is serialized as "string[]"
+					if inner := p.skipTypeScriptType(js_ast.LPrefix); isReadonly {
+						metadata = inner
+					}
 				}
 				break
 			}
@@ -316,6 +420,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				// "let foo: unique symbol"
 				if p.lexer.IsContextualKeyword("symbol") {
 					p.lexer.Next()
+					metadata.kind = tsTypeMetadataSymbol
 					break
 				}
 			} else if kind == tsTypeIdentifierAbstract {
@@ -332,11 +437,16 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				// "function assert(x: boolean): asserts x is boolean"
 				if opts.isReturnType && !p.lexer.HasNewlineBefore && (p.lexer.Token == js_lexer.TIdentifier || p.lexer.Token == js_lexer.TThis) {
 					p.lexer.Next()
+					metadata.kind = tsTypeMetadataBoolean
+				} else {
+					metadata = tsTypeMetadata{kind: tsTypeMetadataReference, name: name, loc: nameLoc}
 				}
 			} else if kind == tsTypeIdentifierPrimitive {
+				metadata.kind = tsTypeMetadataPrimitives[name.String]
 				p.lexer.Next()
 				checkTypeParameters = false
 			} else {
+				metadata = tsTypeMetadata{kind: tsTypeMetadataReference, name: name, loc: nameLoc}
 				p.lexer.Next()
 			}
 
@@ -344,6 +454,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			if p.lexer.IsContextualKeyword("is") && !p.lexer.HasNewlineBefore {
 				p.lexer.Next()
 				p.skipTypeScriptType(js_ast.LLowest)
+				metadata = tsTypeMetadata{kind: tsTypeMetadataBoolean}
 				return
 			}
 
@@ -407,6 +518,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				p.lexer.Next()
 			}
 			p.lexer.Expect(js_lexer.TCloseBracket)
+			metadata.kind = tsTypeMetadataArray
 
 		case js_lexer.TOpenBrace:
 			p.skipTypeScriptObjectType()
@@ -422,6 +534,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 					break
 				}
 			}
+			metadata.kind = tsTypeMetadataString
 
 		default:
 			// "[function: number]"
@@ -448,14 +561,14 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 				return
 			}
 			p.lexer.Next()
-			p.skipTypeScriptType(js_ast.LBitwiseOr)
+			metadata = metadata.mergeWith(p.skipTypeScriptType(js_ast.LBitwiseOr))
 
 		case js_lexer.TAmpersand:
 			if level >= js_ast.LBitwiseAnd {
 				return
 			}
 			p.lexer.Next()
-			p.skipTypeScriptType(js_ast.LBitwiseAnd)
+			metadata = metadata.mergeWith(p.skipTypeScriptType(js_ast.LBitwiseAnd))
 
 		case js_lexer.TExclamation:
 			// A postfix "!" is allowed in JSDoc types in TypeScript, which are only
@@ -473,6 +586,11 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			if !p.lexer.IsIdentifierOrKeyword() {
 				p.lexer.Expect(js_lexer.TIdentifier)
 			}
+			if metadata.kind == tsTypeMetadataReference {
+				metadata.members = append(metadata.members, p.lexer.Identifier.String)
+			} else {
+				metadata = tsTypeMetadata{}
+			}
 			p.lexer.Next()
 
 			// "{ <A extends B>(): c.d \n <E extends F>(): g.h }" must not become a single type
@@ -487,7 +605,13 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			}
 			p.lexer.Next()
 			if p.lexer.Token != js_lexer.TCloseBracket {
+				// "T[K]"
 				p.skipTypeScriptType(js_ast.LLowest)
+				metadata = tsTypeMetadata{}
+			} else {
+				// "T[]"
+				element := metadata
+				metadata = tsTypeMetadata{kind: tsTypeMetadataArray, elementOrNil: &element}
 			}
 			p.lexer.Expect(js_lexer.TCloseBracket)
 
@@ -504,6 +628,7 @@ func (p *parser) skipTypeScriptTypeWithOpts(level js_ast.L, opts skipTypeOpts) {
 			p.skipTypeScriptType(js_ast.LLowest)
 			p.lexer.Expect(js_lexer.TColon)
 			p.skipTypeScriptType(js_ast.LLowest)
+			metadata = tsTypeMetadata{}
 
 		default:
 			return
@@ -1041,6 +1166,175 @@ func (p *parser) skipTypeScriptTypeStmt(opts parseStmtOpts) {
 	p.lexer.ExpectOrInsertSemicolon()
 }
 
+// This generates the value that the TypeScript compiler uses for a type
+// annotation when "emitDecoratorMetadata" is enabled. Type references are
+// guarded with a "typeof" check because the name may only exist as a type.
+func (p *parser) tsTypeMetadataToExpr(loc logger.Loc, metadata tsTypeMetadata) js_ast.Expr {
+	global := func(name string) js_ast.Expr {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef(js_lexer.MaybeSubstring{String: name})}}
+	}
+
+	switch metadata.kind {
+	case tsTypeMetadataUndefined, tsTypeMetadataVoid:
+		return js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+	case tsTypeMetadataNumber:
+		return global("Number")
+	case tsTypeMetadataString:
+		return global("String")
+	case tsTypeMetadataBoolean:
+		return global("Boolean")
+	case tsTypeMetadataBigInt:
+		return global("BigInt")
+	case tsTypeMetadataSymbol:
+		return global("Symbol")
+	case tsTypeMetadataFunction:
+		return global("Function")
+	case tsTypeMetadataArray:
+		return global("Array")
+
+	case tsTypeMetadataReference:
+		// "a.b.C" => "typeof a === 'undefined' || typeof a.b === 'undefined' || typeof a.b.C === 'undefined' ? Object : a.b.C"
+		loc := metadata.loc
+		reference := func(count int) js_ast.Expr {
+			value := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef(metadata.name)}}
+			for _, member := range metadata.members[:count] {
+				value = js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: value, Name: member, NameLoc: loc}}
+			}
+			return value
+		}
+		var test js_ast.Expr
+		for i := 0; i <= len(metadata.members); i++ {
+			check := js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+				Op:    js_ast.BinOpStrictEq,
+				Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{Op: js_ast.UnOpTypeof, Value: reference(i)}},
+				Right: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("undefined")}},
+			}}
+			if test.Data == nil {
+				test = check
+			} else {
+				test = js_ast.JoinWithLeftAssociativeOp(js_ast.BinOpLogicalOr, test, check)
+			}
+		}
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIf{Test: test, Yes: global("Object"), No: reference(len(metadata.members))}}
+	}
+
+	return global("Object")
+}
+
+// The TypeScript compiler serializes a reference to an enum as "Number" or
+// "String" depending on the values of the enum, or as "Object" if it has both.
+// Enums can be declared after the class that uses them, so the values are
+// recorded while parsing and references are replaced while visiting.
+func (p *parser) recordTSEnumMetadata(ref js_ast.Ref, values []js_ast.EnumValue) {
+	if p.tsEnumMetadata == nil {
+		p.tsEnumMetadata = make(map[js_ast.Ref]tsTypeMetadata)
+	}
+	metadata, ok := p.tsEnumMetadata[ref]
+	if !ok {
+		metadata.kind = tsTypeMetadataUndefined
+	}
+	for _, value := range values {
+		kind := tsTypeMetadataNumber
+		if value.ValueOrNil.Data != nil && js_ast.KnownPrimitiveType(value.ValueOrNil) == js_ast.PrimitiveString {
+			kind = tsTypeMetadataString
+		}
+		metadata = metadata.mergeWith(tsTypeMetadata{kind: kind})
+	}
+	p.tsEnumMetadata[ref] = metadata
+}
+
+func (p *parser) substituteTSEnumMetadata(value js_ast.Expr) js_ast.Expr {
+	switch e := value.Data.(type) {
+	case *js_ast.EArray:
+		for i, item := range e.Items {
+			e.Items[i] = p.substituteTSEnumMetadata(item)
+		}
+
+	case *js_ast.EIf:
+		// This is the "typeof E === 'undefined' ? Object : E" check for a reference
+		if id, ok := e.No.Data.(*js_ast.EIdentifier); ok {
+			name := p.loadNameFromRef(id.Ref)
+			for s := p.currentScope; s != nil; s = s.Parent {
+				if member, ok := s.Members[name]; ok {
+					if metadata, ok := p.tsEnumMetadata[member.Ref]; ok {
+						if metadata.kind == tsTypeMetadataUndefined {
+							metadata.kind = tsTypeMetadataNumber // "enum E {}"
+						}
+						return p.tsTypeMetadataToExpr(value.Loc, metadata)
+					}
+					break
+				}
+			}
+		}
+	}
+	return value
+}
+
+func (p *parser) tsDecoratorMetadataForMethod(
+	loc logger.Loc,
+	kind js_ast.PropertyKind,
+	fn js_ast.Fn,
+	fnMetadata tsFnMetadata,
+	opts propertyOpts,
+	isConstructor bool,
+) []js_ast.TSDecoratorMetadata {
+	// Metadata is only generated if something in this method is decorated.
+	// The metadata for the constructor is used by decorators on the class.
+	hasDecorators := len(opts.tsDecorators) > 0 || (isConstructor && opts.classHasTSDecorators)
+	for _, arg := range fn.Args {
+		if len(arg.TSDecorators) > 0 {
+			hasDecorators = true
+		}
+	}
+	if !hasDecorators {
+		return nil
+	}
+
+	paramTypes := func() js_ast.TSDecoratorMetadata {
+		items := make([]js_ast.Expr, len(fnMetadata.args))
+		for i, arg := range fnMetadata.args {
+			items[i] = p.tsTypeMetadataToExpr(loc, arg)
+		}
+		return js_ast.TSDecoratorMetadata{Key: "design:paramtypes", Value: js_ast.Expr{Loc: loc, Data: &js_ast.EArray{Items: items}}}
+	}
+
+	switch {
+	case isConstructor:
+		return []js_ast.TSDecoratorMetadata{paramTypes()}
+
+	case kind == js_ast.PropertyGet:
+		return []js_ast.TSDecoratorMetadata{
+			{Key: "design:type", Value: p.tsTypeMetadataToExpr(loc, fnMetadata.returnType)},
+		}
+
+	case kind == js_ast.PropertySet:
+		var valueType tsTypeMetadata
+		if len(fnMetadata.args) > 0 {
+			valueType = fnMetadata.args[0]
+		}
+		return []js_ast.TSDecoratorMetadata{
+			{Key: "design:type", Value: p.tsTypeMetadataToExpr(loc, valueType)},
+			paramTypes(),
+		}
+
+	default:
+		// Methods without a return type are either "void" or a promise
+		var returnType js_ast.Expr
+		if fnMetadata.hasReturnType {
+			returnType = p.tsTypeMetadataToExpr(loc, fnMetadata.returnType)
+		} else if opts.isAsync {
+			returnType = js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef(js_lexer.MaybeSubstring{String: "Promise"})}}
+		} else {
+			returnType = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+		}
+		return []js_ast.TSDecoratorMetadata{
+			{Key: "design:type", Value: p.tsTypeMetadataToExpr(loc, tsTypeMetadata{kind: tsTypeMetadataFunction})},
+			paramTypes(),
+			{Key: "design:returntype", Value: returnType},
+		}
+	}
+}
+
 func (p *parser) parseTypeScriptDecorators(tsDecoratorScope *js_ast.Scope) []js_ast.Expr {
 	var tsDecorators []js_ast.Expr
 
@@ -1102,10 +1396,74 @@ func (p *parser) logMisplacedDecoratorError(tsDecorators *deferredTSDecorators)
 	p.discardScopesUpTo(tsDecorators.scopeIndex)
 }
 
+// The lexer is expected to be at the "enum" keyword after "declare const"
+func (p *parser) warnAboutAmbientConstEnum() {
+	lexer := p.lexer
+	lexer.Next()
+	if lexer.Token != js_lexer.TIdentifier {
+		return
+	}
+	name := lexer.Identifier.String
+	kind := logger.Warning
+	if p.suppressWarningsAboutWeirdCode {
+		kind = logger.Debug
+	}
+	p.log.AddIDWithNotes(logger.MsgID_JS_IsolatedModules, kind, &p.tracker, lexer.Range(),
+		fmt.Sprintf("Cannot use the ambient const enum %q when compiling each file in isolation", name),
+		[]logger.MsgData{{Text: fmt.Sprintf("Ambient declarations are removed without being compiled, so the members of %q cannot be inlined. "+
+			"References to %q will fail at run-time unless a variable with that name exists.", name, name)}})
+}
+
+type tsAmbientMergeKey struct {
+	scope *js_ast.Scope
+	name  string
+}
+
+type tsAmbientMergeFlags uint8
+
+const (
+	tsAmbientMergeHasAmbient tsAmbientMergeFlags = 1 << iota
+	tsAmbientMergeHasNonAmbient
+	tsAmbientMergeDidWarn
+)
+
+// TypeScript lets namespaces and enums merge with ambient declarations of the
+// same name. We remove ambient declarations without compiling them, which
+// means members that are only declared in the ambient declaration won't be
+// handled correctly.
+func (p *parser) checkForAmbientMerge(scope *js_ast.Scope, nameRange logger.Range, name string, isAmbient bool) {
+	if !p.options.ts.IsolatedModulesCheck {
+		return
+	}
+	if p.tsAmbientMerges == nil {
+		p.tsAmbientMerges = make(map[tsAmbientMergeKey]tsAmbientMergeFlags)
+	}
+	key := tsAmbientMergeKey{scope: scope, name: name}
+	flags := p.tsAmbientMerges[key]
+	if isAmbient {
+		flags |= tsAmbientMergeHasAmbient
+	} else {
+		flags |= tsAmbientMergeHasNonAmbient
+	}
+	if (flags&tsAmbientMergeHasAmbient) != 0 && (flags&tsAmbientMergeHasNonAmbient) != 0 && (flags&tsAmbientMergeDidWarn) == 0 {
+		flags |= tsAmbientMergeDidWarn
+		kind := logger.Warning
+		if p.suppressWarningsAboutWeirdCode {
+			kind = logger.Debug
+		}
+		p.log.AddIDWithNotes(logger.MsgID_JS_IsolatedModules, kind, &p.tracker, nameRange,
+			fmt.Sprintf("Cannot merge %q with an ambient declaration when compiling each file in isolation", name),
+			[]logger.MsgData{{Text: fmt.Sprintf("Ambient declarations are removed without being compiled, so members that are only declared "+
+				"in the ambient declaration of %q may not be compiled correctly.", name)}})
+	}
+	p.tsAmbientMerges[key] = flags
+}
+
 func (p *parser) parseTypeScriptEnumStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt {
 	p.lexer.Expect(js_lexer.TEnum)
 	nameLoc := p.lexer.Loc()
 	nameText := p.lexer.Identifier.String
+	p.checkForAmbientMerge(p.currentScope, p.lexer.Range(), nameText, opts.isTypeScriptDeclare)
 	p.lexer.Expect(js_lexer.TIdentifier)
 	name := js_ast.LocRef{Loc: nameLoc, Ref: js_ast.InvalidRef}
 
@@ -1210,6 +1568,10 @@ func (p *parser) parseTypeScriptEnumStmt(loc logger.Loc, opts parseStmtOpts) js_
 	p.fnOrArrowDataParse = oldFnOrArrowData
 
 	if !opts.isTypeScriptDeclare {
+		if p.options.emitDecoratorMetadata {
+			p.recordTSEnumMetadata(name.Ref, values)
+		}
+
 		// Avoid a collision with the enum closure argument variable if the
 		// enum exports a symbol with the same name as the enum itself:
 		//
@@ -1303,6 +1665,9 @@ func (p *parser) parseTypeScriptImportEqualsStmt(loc logger.Loc, opts parseStmtO
 			p.lexer.Expect(js_lexer.TIdentifier)
 		}
 	}
+	if p.nodeEnds != nil {
+		p.recordNodeEnd(value.Data)
+	}
 
 	p.lexer.ExpectOrInsertSemicolon()
 
@@ -1358,6 +1723,11 @@ func (p *parser) parseTypeScriptNamespaceStmt(loc logger.Loc, opts parseStmtOpts
 	// "namespace Foo {}"
 	nameLoc := p.lexer.Loc()
 	nameText := p.lexer.Identifier.String
+	nameRange := p.lexer.Range()
+	parentScope := p.currentScope
+	if opts.isTypeScriptDeclare {
+		p.checkForAmbientMerge(parentScope, nameRange, nameText, true)
+	}
 	p.lexer.Next()
 
 	// Generate the namespace object
@@ -1504,6 +1874,9 @@ func (p *parser) parseTypeScriptNamespaceStmt(loc logger.Loc, opts parseStmtOpts
 	}
 
 	if !opts.isTypeScriptDeclare {
+		// Only check namespaces that contain values since others are removed
+		p.checkForAmbientMerge(parentScope, nameRange, nameText, false)
+
 		// Avoid a collision with the namespace closure argument variable if the
 		// namespace exports a symbol with the same name as the namespace itself:
 		//
@@ -5,6 +5,8 @@ import (
 
 	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
 )
 
 func expectParseErrorTS(t *testing.T, contents string, expected string) {
@@ -59,6 +61,47 @@ func expectPrintedTargetTS(t *testing.T, esVersion int, contents string, expecte
 	})
 }
 
+func expectPrintedDecoratorMetadataTS(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expected, config.Options{
+		TS: config.TSOptions{
+			Parse: true,
+		},
+		EmitDecoratorMetadata: true,
+	})
+}
+
+func expectParseErrorIsolatedModulesCheckTS(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectParseErrorCommon(t, contents, expected, config.Options{
+		TS: config.TSOptions{
+			Parse:                true,
+			IsolatedModulesCheck: true,
+		},
+	})
+}
+
+func expectPrintedDeclarationsTS(t *testing.T, contents string, expected string, expectedLog string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		options := config.Options{
+			TS: config.TSOptions{
+				Parse: true,
+			},
+		}
+		decl, _, _ := EmitTSDeclarations(log, test.SourceForTest(contents), OptionsFromConfig(&options))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		test.AssertEqualWithDiff(t, text, expectedLog)
+		test.AssertEqualWithDiff(t, decl, expected)
+	})
+}
+
 func expectParseErrorTSNoAmbiguousLessThan(t *testing.T, contents string, expected string) {
 	t.Helper()
 	expectParseErrorCommon(t, contents, expected, config.Options{
@@ -1502,6 +1545,27 @@ func TestTSDeclare(t *testing.T) {
 	expectParseErrorTS(t, "export as namespace ns.foo", "<stdin>: ERROR: Expected \";\" but found \".\"\n")
 }
 
+func TestTSIsolatedModulesCheck(t *testing.T) {
+	expectParseErrorIsolatedModulesCheckTS(t, "declare const enum Foo { A }", "<stdin>: WARNING: Cannot use the ambient const enum \"Foo\" when compiling each file in isolation\n"+
+		"NOTE: Ambient declarations are removed without being compiled, so the members of \"Foo\" cannot be inlined. "+
+		"References to \"Foo\" will fail at run-time unless a variable with that name exists.\n")
+	expectParseErrorIsolatedModulesCheckTS(t, "const enum Foo { A }", "")
+	expectParseErrorIsolatedModulesCheckTS(t, "declare enum Foo { A }", "")
+	expectParseErrorTS(t, "declare const enum Foo { A }", "")
+
+	mergeWarning := "<stdin>: WARNING: Cannot merge \"Foo\" with an ambient declaration when compiling each file in isolation\n" +
+		"NOTE: Ambient declarations are removed without being compiled, so members that are only declared in the ambient declaration of \"Foo\" may not be compiled correctly.\n"
+	expectParseErrorIsolatedModulesCheckTS(t, "declare namespace Foo { let x } namespace Foo { x }", mergeWarning)
+	expectParseErrorIsolatedModulesCheckTS(t, "namespace Foo { x } declare namespace Foo { let x }", mergeWarning)
+	expectParseErrorIsolatedModulesCheckTS(t, "declare enum Foo { A } enum Foo { B = A }", mergeWarning)
+	expectParseErrorIsolatedModulesCheckTS(t, "declare namespace Foo { let x } namespace Foo { x } namespace Foo { x }", mergeWarning)
+	expectParseErrorIsolatedModulesCheckTS(t, "declare namespace Foo { let x } namespace Foo { type T = x }", "")
+	expectParseErrorIsolatedModulesCheckTS(t, "declare namespace Foo { let x } namespace Bar { x }", "")
+	expectParseErrorIsolatedModulesCheckTS(t, "declare namespace Foo { let x } namespace Bar { namespace Foo { x } }", "")
+	expectParseErrorIsolatedModulesCheckTS(t, "namespace Foo { x } namespace Foo { x }", "")
+	expectParseErrorTS(t, "declare namespace Foo { let x } namespace Foo { x }", "")
+}
+
 func TestTSDecorator(t *testing.T) {
 	// Tests of "declare class"
 	expectPrintedTS(t, "@dec(() => 0) declare class Foo {} {let foo}", "{\n  let foo;\n}\n")
@@ -1625,6 +1689,70 @@ func TestTSDecorator(t *testing.T) {
 	expectParseErrorTS(t, "function foo() { class Foo { @dec(yield x) foo() {} } }", "<stdin>: ERROR: Cannot use \"yield\" outside a generator function\n")
 }
 
+func TestTSDecoratorMetadata(t *testing.T) {
+	// Fields
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: number }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Number)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Object)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: string | null | undefined }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", String)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: 'a' | 1 }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Object)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: readonly number[] }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Array)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: Bar<number> }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", typeof Bar === \"undefined\" ? Object : Bar)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: a.b.C }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", typeof a === \"undefined\" || typeof a.b === \"undefined\" || typeof a.b.C === \"undefined\" ? Object : a.b.C)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { x: number }", "class Foo {\n}\n")
+
+	// Local enums are serialized using the type of their values
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: E } enum E { A, B = 2 }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Number)\n], Foo.prototype, \"x\", 2);\n"+
+			"var E = /* @__PURE__ */ ((E) => {\n  E[E[\"A\"] = 0] = \"A\";\n  E[E[\"B\"] = 2] = \"B\";\n  return E;\n})(E || {});\n")
+	expectPrintedDecoratorMetadataTS(t, "enum E { A = 'a', B = `b` } class Foo { @dec x: E | null }",
+		"var E = /* @__PURE__ */ ((E) => {\n  E[\"A\"] = \"a\";\n  E[\"B\"] = `b`;\n  return E;\n})(E || {});\n"+
+			"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", String)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "enum E { A = 1 } enum E { B = 'b' } class Foo { @dec foo(x: E) {} }",
+		"var E = /* @__PURE__ */ ((E) => {\n  E[E[\"A\"] = 1] = \"A\";\n  return E;\n})(E || {});\n"+
+			"var E = /* @__PURE__ */ ((E) => {\n  E[\"B\"] = \"b\";\n  return E;\n})(E || {});\n"+
+			"class Foo {\n  foo(x) {\n  }\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Function),\n"+
+			"  __metadata(\"design:paramtypes\", [\n    Object\n  ]),\n  __metadata(\"design:returntype\", void 0)\n], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec x: E } function f() { enum E { A } }",
+		"class Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", typeof E === \"undefined\" ? Object : E)\n], Foo.prototype, \"x\", 2);\n"+
+			"function f() {\n  let E;\n  ((E) => {\n    E[E[\"A\"] = 0] = \"A\";\n  })(E || (E = {}));\n}\n")
+
+	// Methods
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec foo(x: number, y?: () => void): boolean {} }",
+		"class Foo {\n  foo(x, y) {\n  }\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Function),\n"+
+			"  __metadata(\"design:paramtypes\", [\n    Number,\n    Function\n  ]),\n  __metadata(\"design:returntype\", Boolean)\n], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { foo(@dec x: bigint, ...y: symbol[]) {} }",
+		"class Foo {\n  foo(x, ...y) {\n  }\n}\n__decorateClass([\n  __decorateParam(0, dec),\n  __metadata(\"design:type\", Function),\n"+
+			"  __metadata(\"design:paramtypes\", [\n    BigInt,\n    Symbol\n  ]),\n  __metadata(\"design:returntype\", void 0)\n], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec async foo() {} }",
+		"class Foo {\n  async foo() {\n  }\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", Function),\n"+
+			"  __metadata(\"design:paramtypes\", []),\n  __metadata(\"design:returntype\", Promise)\n], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec get foo(): string {} }",
+		"class Foo {\n  get foo() {\n  }\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", String)\n], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { @dec set foo(x: string) {} }",
+		"class Foo {\n  set foo(x) {\n  }\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", String),\n"+
+			"  __metadata(\"design:paramtypes\", [\n    String\n  ])\n], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { foo(x: number) {} }", "class Foo {\n  foo(x) {\n  }\n}\n")
+
+	// Constructors
+	expectPrintedDecoratorMetadataTS(t, "@dec class Foo { constructor(x: number, @inject y: Bar) {} }",
+		"let Foo = class {\n  constructor(x, y) {\n  }\n};\nFoo = __decorateClass([\n  dec,\n  __decorateParam(1, inject),\n"+
+			"  __metadata(\"design:paramtypes\", [\n    Number,\n    typeof Bar === \"undefined\" ? Object : Bar\n  ])\n], Foo);\n")
+	expectPrintedDecoratorMetadataTS(t, "@dec class Foo {}", "let Foo = class {\n};\nFoo = __decorateClass([\n  dec\n], Foo);\n")
+	expectPrintedDecoratorMetadataTS(t, "class Foo { constructor(x: number) {} }", "class Foo {\n  constructor(x) {\n  }\n}\n")
+
+	// Imports that are only used in type annotations must be kept
+	expectPrintedDecoratorMetadataTS(t, "import { Bar } from 'bar'; class Foo { @dec x: Bar }",
+		"import { Bar } from \"bar\";\nclass Foo {\n}\n__decorateClass([\n  dec,\n  __metadata(\"design:type\", typeof Bar === \"undefined\" ? Object : Bar)\n], Foo.prototype, \"x\", 2);\n")
+	expectPrintedDecoratorMetadataTS(t, "import { Bar } from 'bar'; class Foo { x: Bar }", "class Foo {\n}\n")
+}
+
 func TestTSTry(t *testing.T) {
 	expectPrintedTS(t, "try {} catch (x: any) {}", "try {\n} catch (x) {\n}\n")
 	expectPrintedTS(t, "try {} catch (x: unknown) {}", "try {\n} catch (x) {\n}\n")
@@ -2377,3 +2505,112 @@ func TestMangleTSStringEnumLength(t *testing.T) {
 	expectPrintedMangleTS(t, "enum x { y = '👯‍♂️' } z = x.y.length",
 		"var x = /* @__PURE__ */ ((x) => (x.y = \"👯‍♂️\", x))(x || {});\nz = 5;\n")
 }
+
+func TestTSDeclarations(t *testing.T) {
+	expectPrintedDeclarationsTS(t, "export let x: number = 1", "export declare let x: number;\n", "")
+	expectPrintedDeclarationsTS(t, "export const x = 1, y = 'y', z = -2n", "export declare const x = 1, y = 'y', z = -2n;\n", "")
+	expectPrintedDeclarationsTS(t, "export let x = 1, y = 'y', z = true", "export declare let x: number, y: string, z: boolean;\n", "")
+	expectPrintedDeclarationsTS(t, "export const x = 1 as const, y = 'y' as const, z = -2n as const", "export declare const x = 1, y = 'y', z = -2n;\n", "")
+	expectPrintedDeclarationsTS(t, "export let x = 1 as const, y = <const>'y', z = 2 as 1 | 2", "export declare let x: 1, y: 'y', z: 1 | 2;\n", "")
+	expectPrintedDeclarationsTS(t, "export class C { static readonly x = 1 as const; y = <const>-1 }", "export declare class C {\n    static readonly x = 1;\n    y: -1;\n}\n", "")
+	expectPrintedDeclarationsTS(t, "export let x = foo()", "export declare let x: any;\n",
+		"<stdin>: WARNING: Using \"any\" for the type of \"x\" in the generated declaration file because it has no type annotation\n")
+	expectPrintedDeclarationsTS(t, "export let {a, b: [c]} = foo()", "export declare let a: any, c: any;\n",
+		"<stdin>: WARNING: Using \"any\" for the type of \"a\" in the generated declaration file because it has no type annotation\n<stdin>: WARNING: Using \"any\" for the type of \"c\" in the generated declaration file because it has no type annotation\n")
+	expectPrintedDeclarationsTS(t, "export const f = (a: number, b = 'x'): string => a + b", "export declare const f: (a: number, b?: string) => string;\n", "")
+	expectPrintedDeclarationsTS(t, "export const f = async (a: number) => { await a }", "export declare const f: (a: number) => Promise<void>;\n", "")
+
+	expectPrintedDeclarationsTS(t, "export function f(a: number, b?: string): void {}", "export declare function f(a: number, b?: string): void;\n", "")
+	expectPrintedDeclarationsTS(t, "export function f(a = 1, ...b: number[]) { return a }", "export declare function f(a?: number, ...b: number[]): any;\n",
+		"<stdin>: WARNING: Using \"any\" for the return type of \"f\" in the generated declaration file because it has no type annotation\n")
+	expectPrintedDeclarationsTS(t, "export function f(a: string): string\nexport function f(a: number): number\nexport function f(a: any) { return a }",
+		"export declare function f(a: string): string;\nexport declare function f(a: number): number;\n", "")
+	expectPrintedDeclarationsTS(t, "export async function f() {}\nexport function* g(): Generator<number> { yield 1 }",
+		"export declare function f(): Promise<void>;\nexport declare function g(): Generator<number>;\n", "")
+	expectPrintedDeclarationsTS(t, "export default function (x: number): number { return x }", "export default function (x: number): number;\n", "")
+	expectPrintedDeclarationsTS(t, "export default 123", "declare const _default: number;\nexport default _default;\n", "")
+
+	expectPrintedDeclarationsTS(t, "export interface I { a: number }\nexport type T<X> = X | { b: `${string}` }",
+		"export interface I { a: number }\nexport type T<X> = X | { b: `${string}` }\n", "")
+	expectPrintedDeclarationsTS(t, "export enum E { A, B = 'b' }\nexport const enum C { X = 1 << 2 }",
+		"export declare enum E { A, B = 'b' }\nexport declare const enum C { X = 1 << 2 }\n", "")
+	expectPrintedDeclarationsTS(t, "declare global { interface Window { x: number } }\nexport {}",
+		"declare global { interface Window { x: number } }\nexport {};\n", "")
+
+	expectPrintedDeclarationsTS(t, `
+export class Foo<T> extends Bar implements Baz {
+	#secret = 1
+	private hidden = 2
+	static readonly count = 3
+	declare x: string
+	constructor(public a: number, private b: T, c: string) { super() }
+	get y(): number { return 1 }
+	set y(v: number) {}
+	[Symbol.iterator](): Iterator<T> { return null! }
+	protected m?(a: T): void
+	static { init() }
+	[key: string]: any
+}
+`, `export declare class Foo<T> extends Bar implements Baz {
+    #private;
+    private hidden;
+    static readonly count = 3;
+    x: string;
+    a: number;
+    private b;
+    constructor(a: number, b: T, c: string);
+    get y(): number;
+    set y(v: number);
+    [Symbol.iterator](): Iterator<T>;
+    protected m?(a: T): void;
+    [key: string]: any;
+}
+`, "")
+	expectPrintedDeclarationsTS(t, "export class Foo extends mixin(Bar) {}", "export declare class Foo extends mixin(Bar) {\n}\n",
+		"<stdin>: WARNING: The base class of \"Foo\" was copied into the generated declaration file as-is because it's not a simple reference to another class\n")
+
+	expectPrintedDeclarationsTS(t, `
+import { A, B, type C } from './types'
+import D, * as E from './other'
+import './side-effect'
+interface Private { a: A }
+type Unused = B
+export const x: Private = null!
+`, `import { A } from './types';
+interface Private { a: A }
+export declare const x: Private;
+export {};
+`, "")
+	expectPrintedDeclarationsTS(t, "export * from './a'\nexport { b as c } from './b'\nexport type { D } from './d'",
+		"export * from './a';\nexport { b as c } from './b';\nexport type { D } from './d';\n", "")
+	expectPrintedDeclarationsTS(t, "const a = 1\nfunction b() {}\nexport { a, b as c }",
+		"declare const a = 1;\ndeclare function b(): void;\nexport { a, b as c };\n", "")
+
+	expectPrintedDeclarationsTS(t, `
+export namespace NS {
+	export const a: number = 1
+	const b = 2
+	export namespace Inner { export function f(): void {} }
+}
+`, `export declare namespace NS {
+    export const a: number;
+    export namespace Inner {
+        export function f(): void;
+    }
+}
+`, "")
+
+	expectPrintedDeclarationsTS(t, `
+/** Docs for f */
+export function f(): void {
+	// Not a doc comment
+}
+`, "/** Docs for f */\nexport declare function f(): void;\n", "")
+
+	// Scripts (files without imports or exports) keep everything
+	expectPrintedDeclarationsTS(t, "let x: number = 1\nfunction f() {}", "declare let x: number;\ndeclare function f(): void;\n", "")
+
+	// Bodies containing syntax that looks like types
+	expectPrintedDeclarationsTS(t, "export function f(): RegExp { return /[}]/ }\nexport const s = `${'}'}`",
+		"export declare function f(): RegExp;\nexport declare const s: string;\n", "")
+}
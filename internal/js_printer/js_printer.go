@@ -292,7 +292,7 @@ func (p *printer) printUnquotedUTF16(text []uint16, quote rune) {
 						i++
 
 						// Escape this character if UTF-8 isn't allowed
-						if p.options.ASCIIOnly {
+						if p.options.ASCIIOnly || p.options.BMPOnly {
 							if !p.options.UnsupportedFeatures.Has(compat.UnicodeEscapes) {
 								js = append(js, fmt.Sprintf("\\u{%X}", r)...)
 							} else {
@@ -315,11 +315,11 @@ func (p *printer) printUnquotedUTF16(text []uint16, quote rune) {
 				js = append(js, '\\', 'u', hexChars[c>>12], hexChars[(c>>8)&15], hexChars[(c>>4)&15], hexChars[c&15])
 
 			// Is this an unpaired low surrogate or four-digit hex escape?
-			case (c >= firstLowSurrogate && c <= lastLowSurrogate) || (p.options.ASCIIOnly && c > 0xFF):
+			case (c >= firstLowSurrogate && c <= lastLowSurrogate) || (c > 0xFF && p.mustEscapeUTF16(c)):
 				js = append(js, '\\', 'u', hexChars[c>>12], hexChars[(c>>8)&15], hexChars[(c>>4)&15], hexChars[c&15])
 
 			// Can this be a two-digit hex escape?
-			case p.options.ASCIIOnly:
+			case p.mustEscapeUTF16(c):
 				js = append(js, '\\', 'x', hexChars[c>>4], hexChars[c&15])
 
 			// Otherwise, just encode to UTF-8
@@ -348,7 +348,7 @@ func (p *printer) canPrintTextAsJSXAttribute(text []uint16) (quote string, ok bo
 		}
 
 		// Use JS strings if we need to escape non-ASCII characters
-		if p.options.ASCIIOnly && c > lastASCII {
+		if p.mustEscapeUTF16(c) {
 			return "", false
 		}
 
@@ -393,7 +393,7 @@ func (p *printer) canPrintTextAsJSXChild(text []uint16) bool {
 		}
 
 		// Use JS strings if we need to escape non-ASCII characters
-		if p.options.ASCIIOnly && c > lastASCII {
+		if p.mustEscapeUTF16(c) {
 			return false
 		}
 
@@ -521,14 +521,25 @@ func CanEscapeIdentifier(name string, UnsupportedFeatures compat.JSFeature, asci
 		!helpers.ContainsNonBMPCodePoint(name))
 }
 
+// Whether a code unit must be escaped depends on the charset. Note that a
+// surrogate pair must be escaped in the "bmp" charset since each half of the
+// pair is escaped.
+func (p *printer) mustEscapeUTF16(c uint16) bool {
+	return c > lastASCII && (p.options.ASCIIOnly || (p.options.BMPOnly && helpers.IsEscapedInBMPCharset(rune(c))))
+}
+
+func (p *printer) mustEscapeRune(c rune) bool {
+	return c > lastASCII && (p.options.ASCIIOnly || (p.options.BMPOnly && helpers.IsEscapedInBMPCharset(c)))
+}
+
 func (p *printer) canPrintIdentifier(name string) bool {
-	return js_lexer.IsIdentifierES5AndESNext(name) && (!p.options.ASCIIOnly ||
+	return js_lexer.IsIdentifierES5AndESNext(name) && (!(p.options.ASCIIOnly || p.options.BMPOnly) ||
 		!p.options.UnsupportedFeatures.Has(compat.UnicodeEscapes) ||
 		!helpers.ContainsNonBMPCodePoint(name))
 }
 
 func (p *printer) canPrintIdentifierUTF16(name []uint16) bool {
-	return js_lexer.IsIdentifierES5AndESNextUTF16(name) && (!p.options.ASCIIOnly ||
+	return js_lexer.IsIdentifierES5AndESNextUTF16(name) && (!(p.options.ASCIIOnly || p.options.BMPOnly) ||
 		!p.options.UnsupportedFeatures.Has(compat.UnicodeEscapes) ||
 		!helpers.ContainsNonBMPCodePointUTF16(name))
 }
@@ -536,6 +547,8 @@ func (p *printer) canPrintIdentifierUTF16(name []uint16) bool {
 func (p *printer) printIdentifier(name string) {
 	if p.options.ASCIIOnly {
 		p.js = QuoteIdentifier(p.js, name, p.options.UnsupportedFeatures)
+	} else if p.options.BMPOnly {
+		p.printIdentifierUTF16(helpers.StringToUTF16(name))
 	} else {
 		p.print(name)
 	}
@@ -557,7 +570,7 @@ func (p *printer) printIdentifierUTF16(name []uint16) {
 			}
 		}
 
-		if p.options.ASCIIOnly && c > lastASCII {
+		if p.mustEscapeRune(c) {
 			if c <= 0xFFFF {
 				p.js = append(p.js, '\\', 'u', hexChars[c>>12], hexChars[(c>>8)&15], hexChars[(c>>4)&15], hexChars[c&15])
 			} else if !p.options.UnsupportedFeatures.Has(compat.UnicodeEscapes) {
@@ -1067,7 +1080,7 @@ func (p *printer) printProperty(item js_ast.Property) {
 				}
 			}
 		} else {
-			p.printQuotedUTF16(key.Value, false /* allowBacktick */)
+			p.printQuotedUTF16WithOriginalQuote(item.Key.Loc, key.Value, false /* allowBacktick */)
 		}
 
 	default:
@@ -1147,6 +1160,49 @@ func (p *printer) printQuotedUTF16(data []uint16, allowBacktick bool) {
 	p.print(c)
 }
 
+// When preserving formatting, a string keeps the quote character it had in the
+// original source code. The location must be the start of the string literal.
+func (p *printer) printQuotedUTF16WithOriginalQuote(loc logger.Loc, data []uint16, allowBacktick bool) {
+	if source := p.options.OriginalSource; source != nil && loc.Start >= 0 && int(loc.Start) < len(source.Contents) {
+		if c := source.Contents[loc.Start]; c == '"' || c == '\'' {
+			p.print(string(c))
+			p.printUnquotedUTF16(data, rune(c))
+			p.print(string(c))
+			return
+		}
+	}
+	p.printQuotedUTF16(data, allowBacktick)
+}
+
+// When preserving formatting, a blank line before a statement in the original
+// source code is kept. This isn't done at the start of a block.
+func (p *printer) printBlankLineIfOriginalHadOne(loc logger.Loc) {
+	source := p.options.OriginalSource
+	if source == nil || loc.Start <= 0 || int(loc.Start) > len(source.Contents) {
+		return
+	}
+
+	// Only statements that start a new line can have a blank line before them
+	n := len(p.js)
+	if n < 2 || p.js[n-1] != '\n' || p.js[n-2] == '\n' || p.js[n-2] == '{' {
+		return
+	}
+
+	newlines := 0
+	for i := int(loc.Start) - 1; i >= 0; i-- {
+		switch source.Contents[i] {
+		case '\n':
+			if newlines++; newlines == 2 {
+				p.print("\n")
+				return
+			}
+		case ' ', '\t', '\r':
+		default:
+			return
+		}
+	}
+}
+
 func (p *printer) printRequireOrImportExpr(
 	importRecordIndex uint32,
 	leadingInteriorComments []js_ast.Comment,
@@ -1181,7 +1237,7 @@ func (p *printer) printRequireOrImportExpr(
 
 			p.print("(")
 			p.addSourceMapping(record.Range.Loc)
-			p.printQuotedUTF8(record.Path.Text, true /* allowBacktick */)
+			p.printQuotedUTF16WithOriginalQuote(record.Range.Loc, helpers.StringToUTF16(record.Path.Text), true /* allowBacktick */)
 			p.print(")")
 
 			// Finish the call to "__toESM()"
@@ -1241,7 +1297,7 @@ func (p *printer) printRequireOrImportExpr(
 			p.printIndent()
 		}
 		p.addSourceMapping(record.Range.Loc)
-		p.printQuotedUTF8(record.Path.Text, true /* allowBacktick */)
+		p.printQuotedUTF16WithOriginalQuote(record.Range.Loc, helpers.StringToUTF16(record.Path.Text), true /* allowBacktick */)
 		if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) {
 			p.printImportCallAssertions(record.Assertions)
 		}
@@ -1867,18 +1923,30 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		p.printRequireOrImportExpr(e.ImportRecordIndex, nil, level, flags)
 
 	case *js_ast.ERequireResolveString:
+		if record := &p.importRecords[e.ImportRecordIndex]; record.Flags.Has(ast.IsResolvedAtBuildTime) {
+			p.printQuotedUTF8(record.Path.Text, true /* allowBacktick */)
+			break
+		}
 		wrap := level >= js_ast.LNew || (flags&forbidCall) != 0
 		if wrap {
 			p.print("(")
 		}
-		p.printSpaceBeforeIdentifier()
-		p.print("require.resolve(")
+		if p.importRecords[e.ImportRecordIndex].Flags.Has(ast.CallRuntimeRequire) {
+			p.printSymbol(p.options.RuntimeRequireRef)
+		} else {
+			p.printSpaceBeforeIdentifier()
+			p.print("require")
+		}
+		p.print(".resolve(")
 		p.printQuotedUTF8(p.importRecords[e.ImportRecordIndex].Path.Text, true /* allowBacktick */)
 		p.print(")")
 		if wrap {
 			p.print(")")
 		}
 
+	case *js_ast.ENewURLString:
+		p.printQuotedUTF8(p.importRecords[e.ImportRecordIndex].Path.Text, true /* allowBacktick */)
+
 	case *js_ast.EImportString:
 		var leadingInteriorComments []js_ast.Comment
 		if !p.options.MinifyWhitespace {
@@ -2258,7 +2326,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 			return
 		}
 
-		p.printQuotedUTF16(e.Value, true /* allowBacktick */)
+		p.printQuotedUTF16WithOriginalQuote(expr.Loc, e.Value, true /* allowBacktick */)
 
 	case *js_ast.ETemplate:
 		// Convert no-substitution template literals into strings if it's smaller
@@ -2973,7 +3041,7 @@ func (p *printer) printIndentedComment(text string) {
 
 func (p *printer) printPath(importRecordIndex uint32) {
 	record := p.importRecords[importRecordIndex]
-	p.printQuotedUTF8(record.Path.Text, false /* allowBacktick */)
+	p.printQuotedUTF16WithOriginalQuote(record.Range.Loc, helpers.StringToUTF16(record.Path.Text), false /* allowBacktick */)
 
 	// Just omit import assertions if they aren't supported
 	if p.options.UnsupportedFeatures.Has(compat.ImportAssertions) {
@@ -3044,6 +3112,7 @@ const (
 )
 
 func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
+	p.printBlankLineIfOriginalHadOne(stmt.Loc)
 	p.addSourceMapping(stmt.Loc)
 
 	switch s := stmt.Data.(type) {
@@ -3057,7 +3126,8 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 
 			case config.LegalCommentsEndOfFile,
 				config.LegalCommentsLinkedWithComment,
-				config.LegalCommentsExternalWithoutComment:
+				config.LegalCommentsExternalWithoutComment,
+				config.LegalCommentsCombined:
 				if p.extractedLegalComments == nil {
 					p.extractedLegalComments = make(map[string]bool)
 				}
@@ -3573,7 +3643,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 	case *js_ast.SDirective:
 		p.printIndent()
 		p.printSpaceBeforeIdentifier()
-		p.printQuotedUTF16(s.Value, false /* allowBacktick */)
+		p.printQuotedUTF16WithOriginalQuote(stmt.Loc, s.Value, false /* allowBacktick */)
 		p.printSemicolonAfterStatement()
 
 	case *js_ast.SBreak:
@@ -3671,8 +3741,13 @@ type Options struct {
 	MinifyIdentifiers   bool
 	MinifySyntax        bool
 	ASCIIOnly           bool
+	BMPOnly             bool
 	LegalComments       config.LegalComments
 	AddSourceMappings   bool
+
+	// If this is present, string literals keep the quote style and statements
+	// keep the blank lines they had in this original source code
+	OriginalSource *logger.Source
 }
 
 type RequireOrImportMeta struct {
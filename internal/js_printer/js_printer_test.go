@@ -24,7 +24,8 @@ func expectPrintedCommon(t *testing.T, name string, contents string, expected st
 	t.Run(name, func(t *testing.T) {
 		t.Helper()
 		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
-		tree, ok := js_parser.Parse(log, test.SourceForTest(contents), js_parser.OptionsFromConfig(&options))
+		source := test.SourceForTest(contents)
+		tree, ok := js_parser.Parse(log, source, js_parser.OptionsFromConfig(&options))
 		msgs := log.Done()
 		text := ""
 		for _, msg := range msgs {
@@ -37,11 +38,17 @@ func expectPrintedCommon(t *testing.T, name string, contents string, expected st
 		symbols := js_ast.NewSymbolMap(1)
 		symbols.SymbolsForSource[0] = tree.Symbols
 		r := renamer.NewNoOpRenamer(symbols)
+		var originalSource *logger.Source
+		if options.PreserveFormatting {
+			originalSource = &source
+		}
 		js := Print(tree, symbols, r, Options{
 			ASCIIOnly:           options.ASCIIOnly,
+			BMPOnly:             options.BMPOnly,
 			MinifySyntax:        options.MinifySyntax,
 			MinifyWhitespace:    options.MinifyWhitespace,
 			UnsupportedFeatures: options.UnsupportedJSFeatures,
+			OriginalSource:      originalSource,
 		}).JS
 		test.AssertEqualWithDiff(t, string(js), expected)
 	})
@@ -81,6 +88,13 @@ func expectPrintedASCII(t *testing.T, contents string, expected string) {
 	})
 }
 
+func expectPrintedBMP(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents+" [bmp]", contents, expected, config.Options{
+		BMPOnly: true,
+	})
+}
+
 func expectPrintedMinifyASCII(t *testing.T, contents string, expected string) {
 	t.Helper()
 	expectPrintedCommon(t, contents+" [ascii]", contents, expected, config.Options{
@@ -89,6 +103,21 @@ func expectPrintedMinifyASCII(t *testing.T, contents string, expected string) {
 	})
 }
 
+func expectPrintedPreserveComments(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents+" [preserve comments]", contents, expected, config.Options{
+		PreserveComments: true,
+	})
+}
+
+func expectPrintedPreserveFormatting(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents+" [preserve formatting]", contents, expected, config.Options{
+		PreserveComments:   true,
+		PreserveFormatting: true,
+	})
+}
+
 func expectPrintedTarget(t *testing.T, esVersion int, contents string, expected string) {
 	t.Helper()
 	expectPrintedCommon(t, contents, contents, expected, config.Options{
@@ -870,6 +899,23 @@ func TestASCIIOnly(t *testing.T) {
 	expectPrintedMinifyASCII(t, "(class 𐀀 extends π {})", "(class \\u{10000} extends \\u03C0{});")
 }
 
+func TestBMPOnly(t *testing.T) {
+	// Characters in the BMP are printed as-is
+	expectPrintedBMP(t, "let π = 'π'", "let π = \"π\";\n")
+	expectPrintedBMP(t, "let 貓 = '貓é'", "let 貓 = \"貓é\";\n")
+
+	// Characters outside the BMP are escaped
+	expectPrintedBMP(t, "let 貓 = '🐈'", "let 貓 = \"\\u{1F408}\";\n")
+	expectPrintedBMP(t, "let x = `🐈${y}`", "let x = `\\u{1F408}${y}`;\n")
+	expectPrintedBMP(t, "var 𐀀貓", "var \\u{10000}貓;\n")
+	expectPrintedBMP(t, "x.𐀀", "x[\"\\u{10000}\"];\n")
+
+	// Invisible formatting characters are escaped
+	expectPrintedBMP(t, "let x = '\u0085\u00AD'", "let x = \"\\x85\\xAD\";\n")
+	expectPrintedBMP(t, "let x = 'a\u200Bb\u202Ec'", "let x = \"a\\u200Bb\\u202Ec\";\n")
+	expectPrintedBMP(t, "let a\u200Cb", "let a\\u200Cb;\n")
+}
+
 func TestJSX(t *testing.T) {
 	expectPrintedJSX(t, "<a/>", "<a />;\n")
 	expectPrintedJSX(t, "<A/>", "<A />;\n")
@@ -907,6 +953,16 @@ func TestJSX(t *testing.T) {
 	expectPrintedJSX(t, "<></>", "<></>;\n")
 	expectPrintedJSX(t, "<>x<y/>z</>", "<>\n  {\"x\"}\n  <y />\n  {\"z\"}\n</>;\n")
 
+	expectPrintedJSX(t, "<a {...b}/>", "<a {...b} />;\n")
+	expectPrintedJSX(t, "<a {...b} c {...d}/>", "<a {...b} c {...d} />;\n")
+	expectPrintedJSX(t, "<a>{...b}</a>", "<a>{...b}</a>;\n")
+	expectPrintedJSX(t, "<a>{/* b */}</a>", "<a />;\n")
+	expectPrintedJSX(t, "<a b={<c/>}/>", "<a b={<c />} />;\n")
+	expectPrintedJSX(t, "<a b={<><c/></>}/>", "<a b={<><c /></>} />;\n")
+	expectPrintedJSX(t, "x = <a/>, <b/>", "x = <a />, <b />;\n")
+	expectPrintedJSX(t, "x = y ? <a/> : <b/>", "x = y ? <a /> : <b />;\n")
+	expectPrintedJSX(t, "(<a/>).b", "<a />.b;\n")
+
 	// These can't be escaped because JSX lacks a syntax for escapes
 	expectPrintedJSXASCII(t, "<π/>", "<π />;\n")
 	expectPrintedJSXASCII(t, "<π.𐀀/>", "<π.𐀀 />;\n")
@@ -1020,3 +1076,33 @@ func TestInfinity(t *testing.T) {
 	expectPrintedMangleMinify(t, "x = y / Infinity", "x=y/(1/0);")
 	expectPrintedMangleMinify(t, "throw Infinity", "throw 1/0;")
 }
+
+func TestPreserveComments(t *testing.T) {
+	expectPrinted(t, "// a\nx()\n/*! b */\ny()", "x();\n/*! b */\ny();\n")
+	expectPrintedPreserveComments(t, "// a\nx()\n/*! b */\ny()", "// a\nx();\n/*! b */\ny();\n")
+	expectPrintedPreserveComments(t, "/**\n   * a\n   */\nx()", "/**\n   * a\n   */\nx();\n")
+	expectPrintedPreserveComments(t, "function f() {\n  // a\n  x()\n  // b\n}", "function f() {\n  // a\n  x();\n  // b\n}\n")
+	expectPrintedPreserveComments(t, "if (x) {\n  // a\n}", "if (x) {\n  // a\n}\n")
+
+	// Comments inside expressions aren't kept
+	expectPrintedPreserveComments(t, "x(/* a */ y, // b\n z)", "x(y, z);\n")
+
+	// Annotations are printed by the printer itself when they still apply
+	expectPrintedPreserveComments(t, "/* @__PURE__ */ x()", "/* @__PURE__ */ x();\n")
+	expectPrintedPreserveComments(t, "x()\n//# sourceMappingURL=x.map", "x();\n")
+}
+
+func TestPreserveFormatting(t *testing.T) {
+	expectPrintedPreserveFormatting(t, "x('a', \"b\", 'c\"', \"d'\")", "x('a', \"b\", 'c\"', \"d'\");\n")
+	expectPrintedPreserveFormatting(t, "x('a\\'b')", "x('a\\'b');\n")
+	expectPrintedPreserveFormatting(t, "x(`a`)", "x(`a`);\n")
+	expectPrintedPreserveFormatting(t, "x({ 'a-b': 1, \"c-d\": 2 })", "x({ 'a-b': 1, \"c-d\": 2 });\n")
+	expectPrintedPreserveFormatting(t, "import 'a'\nimport b from \"b\"\nimport('c')\nrequire('d')",
+		"import 'a';\nimport b from \"b\";\nimport('c');\nrequire('d');\n")
+	expectPrintedPreserveFormatting(t, "function f() { 'use strict' }", "function f() {\n  'use strict';\n}\n")
+
+	// Blank lines between statements are kept, except at the start of a block
+	expectPrintedPreserveFormatting(t, "a()\n\n\nb()\nc()\n  \n// d\n\ne()",
+		"a();\n\nb();\nc();\n\n// d\n\ne();\n")
+	expectPrintedPreserveFormatting(t, "{\n\n  a()\n\n  b()\n}", "{\n  a();\n\n  b();\n}\n")
+}
@@ -151,8 +151,20 @@ type Msg struct {
 	Data       MsgData
 	Kind       MsgKind
 	ID         MsgID
+	Category   MsgCategory
 }
 
+// This describes what caused a message so that API users can tell failures
+// apart without matching on the message text
+type MsgCategory uint8
+
+const (
+	MsgCategoryNone MsgCategory = iota
+	MsgCategorySyntax
+	MsgCategoryResolve
+	MsgCategoryPlugin
+)
+
 type MsgData struct {
 	// Optional user-specified data that is passed through unmodified
 	UserDetail interface{}
@@ -235,6 +247,11 @@ type Path struct {
 	// the output. This is supported by other bundlers, so we also support this.
 	IgnoredSuffix string
 
+	// If non-empty, this is a comma-separated list of additional conditions
+	// that were used to resolve this file and the files it imports. The same
+	// file reached using different conditions is a different module.
+	Conditions string
+
 	Flags PathFlags
 }
 
@@ -253,7 +270,8 @@ func (a Path) ComesBeforeInSortedOrder(b Path) bool {
 	return a.Namespace > b.Namespace ||
 		(a.Namespace == b.Namespace && (a.Text < b.Text ||
 			(a.Text == b.Text && (a.Flags < b.Flags ||
-				(a.Flags == b.Flags && a.IgnoredSuffix < b.IgnoredSuffix)))))
+				(a.Flags == b.Flags && (a.IgnoredSuffix < b.IgnoredSuffix ||
+					(a.IgnoredSuffix == b.IgnoredSuffix && a.Conditions < b.Conditions)))))))
 }
 
 var noColorResult bool
@@ -490,6 +508,17 @@ func NewStderrLog(options OutputOptions) Log {
 	var deferredWarnings []Msg
 	didFinalizeLog := false
 
+	writeMsg := func(msg Msg) {
+		switch {
+		case options.OnMessage != nil:
+			options.OnMessage(msg)
+		case options.Format == LogFormatJSON:
+			os.Stderr.WriteString(msg.JSON() + "\n")
+		default:
+			writeStringWithColor(os.Stderr, msg.String(options, terminalInfo))
+		}
+	}
+
 	finalizeLog := func() {
 		if didFinalizeLog {
 			return
@@ -499,12 +528,16 @@ func NewStderrLog(options OutputOptions) Log {
 		// Print the deferred warning now if there was no error after all
 		for remainingMessagesBeforeLimit > 0 && len(deferredWarnings) > 0 {
 			shownWarnings++
-			writeStringWithColor(os.Stderr, deferredWarnings[0].String(options, terminalInfo))
+			writeMsg(deferredWarnings[0])
 			deferredWarnings = deferredWarnings[1:]
 			remainingMessagesBeforeLimit--
 		}
 
-		// Print out a summary
+		// Print out a summary. This is left out of structured output since it
+		// isn't a message and can be computed from the messages themselves.
+		if options.OnMessage != nil || options.Format == LogFormatJSON {
+			return
+		}
 		if options.MessageLimit > 0 && errors+warnings > options.MessageLimit {
 			writeStringWithColor(os.Stderr, fmt.Sprintf("%s shown (disable the message limit with --log-limit=0)\n",
 				errorAndWarningSummary(errors, warnings, shownErrors, shownWarnings)))
@@ -533,17 +566,17 @@ func NewStderrLog(options OutputOptions) Log {
 			switch msg.Kind {
 			case Verbose:
 				if options.LogLevel <= LevelVerbose {
-					writeStringWithColor(os.Stderr, msg.String(options, terminalInfo))
+					writeMsg(msg)
 				}
 
 			case Debug:
 				if options.LogLevel <= LevelDebug {
-					writeStringWithColor(os.Stderr, msg.String(options, terminalInfo))
+					writeMsg(msg)
 				}
 
 			case Info:
 				if options.LogLevel <= LevelInfo {
-					writeStringWithColor(os.Stderr, msg.String(options, terminalInfo))
+					writeMsg(msg)
 				}
 
 			case Error:
@@ -567,7 +600,7 @@ func NewStderrLog(options OutputOptions) Log {
 			case Error:
 				if options.LogLevel <= LevelError {
 					shownErrors++
-					writeStringWithColor(os.Stderr, msg.String(options, terminalInfo))
+					writeMsg(msg)
 					remainingMessagesBeforeLimit--
 				}
 
@@ -575,7 +608,7 @@ func NewStderrLog(options OutputOptions) Log {
 				if options.LogLevel <= LevelWarning {
 					if remainingMessagesBeforeLimit > (options.MessageLimit+1)/2 {
 						shownWarnings++
-						writeStringWithColor(os.Stderr, msg.String(options, terminalInfo))
+						writeMsg(msg)
 						remainingMessagesBeforeLimit--
 					} else {
 						// If we have less than half of the slots left, wait for potential
@@ -635,6 +668,8 @@ func OutputOptionsForArgs(osArgs []string) OutputOptions {
 			options.LogLevel = LevelError
 		case "--log-level=silent":
 			options.LogLevel = LevelSilent
+		case "--log-format=json":
+			options.Format = LogFormatJSON
 		}
 	}
 
@@ -982,12 +1017,24 @@ const (
 	ColorAlways
 )
 
+type LogFormat uint8
+
+const (
+	LogFormatText LogFormat = iota
+	LogFormatJSON
+)
+
 type OutputOptions struct {
 	MessageLimit  int
 	IncludeSource bool
 	Color         UseColor
 	LogLevel      LogLevel
+	Format        LogFormat
 	Overrides     map[MsgID]LogLevel
+
+	// If present, messages are passed to this callback instead of being written
+	// to stderr. The log level and the message limit still apply.
+	OnMessage func(Msg)
 }
 
 func (msg Msg) String(options OutputOptions, terminalInfo TerminalInfo) string {
@@ -1014,6 +1061,80 @@ func (msg Msg) String(options OutputOptions, terminalInfo TerminalInfo) string {
 // The number of margin characters in addition to the line number
 const extraMarginChars = 9
 
+// This formats the message as a single line of JSON (without a trailing
+// newline) for tools that consume the log instead of a human
+func (msg Msg) JSON() string {
+	sb := strings.Builder{}
+	sb.WriteString(`{"kind":`)
+	sb.WriteString(quoteForJSON(strings.ToLower(msg.Kind.String())))
+	sb.WriteString(`,"id":`)
+	sb.WriteString(quoteForJSON(MsgIDToString(msg.ID)))
+	sb.WriteString(`,"pluginName":`)
+	sb.WriteString(quoteForJSON(msg.PluginName))
+	sb.WriteString(`,"text":`)
+	sb.WriteString(quoteForJSON(msg.Data.Text))
+	sb.WriteString(`,"location":`)
+	writeLocationJSON(&sb, msg.Data.Location)
+	sb.WriteString(`,"notes":[`)
+	for i, note := range msg.Notes {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`{"text":`)
+		sb.WriteString(quoteForJSON(note.Text))
+		sb.WriteString(`,"location":`)
+		writeLocationJSON(&sb, note.Location)
+		sb.WriteByte('}')
+	}
+	sb.WriteString("]}")
+	return sb.String()
+}
+
+func writeLocationJSON(sb *strings.Builder, loc *MsgLocation) {
+	if loc == nil {
+		sb.WriteString("null")
+		return
+	}
+	sb.WriteString(`{"file":`)
+	sb.WriteString(quoteForJSON(loc.File))
+	sb.WriteString(`,"namespace":`)
+	sb.WriteString(quoteForJSON(loc.Namespace))
+	sb.WriteString(fmt.Sprintf(`,"line":%d,"column":%d,"length":%d,"lineText":`, loc.Line, loc.Column, loc.Length))
+	sb.WriteString(quoteForJSON(loc.LineText))
+	sb.WriteString(`,"suggestion":`)
+	sb.WriteString(quoteForJSON(loc.Suggestion))
+	sb.WriteByte('}')
+}
+
+// The logger can't depend on the printer, so this is a minimal JSON string
+// encoder. Invalid UTF-8 is replaced with the replacement character.
+func quoteForJSON(text string) string {
+	sb := strings.Builder{}
+	sb.WriteByte('"')
+	for _, c := range text {
+		switch c {
+		case '"':
+			sb.WriteString("\\\"")
+		case '\\':
+			sb.WriteString("\\\\")
+		case '\n':
+			sb.WriteString("\\n")
+		case '\r':
+			sb.WriteString("\\r")
+		case '\t':
+			sb.WriteString("\\t")
+		default:
+			if c < 0x20 {
+				sb.WriteString(fmt.Sprintf("\\u%04x", c))
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
 func marginWithLineText(maxMargin int, line int) string {
 	number := fmt.Sprintf("%d", line)
 	return fmt.Sprintf("      %s%s │ ", strings.Repeat(" ", maxMargin-len(number)), number)
@@ -1608,6 +1729,19 @@ func renderTabStops(withTabs string, spacesPerTab int) string {
 	return withoutTabs.String()
 }
 
+// This returns a log that assigns the given category to all errors that don't
+// already have one. Warnings are left alone since they aren't failures.
+func (log Log) WithCategory(category MsgCategory) Log {
+	addMsg := log.AddMsg
+	log.AddMsg = func(msg Msg) {
+		if msg.Kind == Error && msg.Category == MsgCategoryNone {
+			msg.Category = category
+		}
+		addMsg(msg)
+	}
+	return log
+}
+
 func (log Log) AddError(tracker *LineColumnTracker, r Range, text string) {
 	log.AddMsg(Msg{
 		Kind: Error,
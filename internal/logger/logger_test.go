@@ -26,3 +26,58 @@ func TestMsgIDs(t *testing.T) {
 		}
 	}
 }
+
+func TestWithCategory(t *testing.T) {
+	log := logger.NewDeferLog(logger.DeferLogAll, nil)
+	syntaxLog := log.WithCategory(logger.MsgCategorySyntax)
+	syntaxLog.AddError(nil, logger.Range{}, "first")
+	syntaxLog.AddMsg(logger.Msg{Kind: logger.Error, Category: logger.MsgCategoryPlugin})
+	syntaxLog.AddMsg(logger.Msg{Kind: logger.Warning})
+	log.AddError(nil, logger.Range{}, "second")
+
+	msgs := log.Done()
+	test.AssertEqual(t, len(msgs), 4)
+	test.AssertEqual(t, msgs[0].Category, logger.MsgCategorySyntax)
+	test.AssertEqual(t, msgs[1].Category, logger.MsgCategoryPlugin)
+	test.AssertEqual(t, msgs[2].Category, logger.MsgCategoryNone)
+	test.AssertEqual(t, msgs[3].Category, logger.MsgCategoryNone)
+}
+
+func TestMsgJSON(t *testing.T) {
+	msg := logger.Msg{
+		ID:         logger.MsgID_JS_AssignToConstant,
+		Kind:       logger.Warning,
+		PluginName: "plugin",
+		Data: logger.MsgData{
+			Text: "quote \" backslash \\ newline \n control \x01",
+			Location: &logger.MsgLocation{
+				File:     "file.js",
+				Line:     2,
+				Column:   4,
+				Length:   3,
+				LineText: "let x = 1",
+			},
+		},
+		Notes: []logger.MsgData{{Text: "note"}},
+	}
+	test.AssertEqual(t, msg.JSON(), `{"kind":"warning","id":"assign-to-constant","pluginName":"plugin",`+
+		`"text":"quote \" backslash \\ newline \n control \u0001",`+
+		`"location":{"file":"file.js","namespace":"","line":2,"column":4,"length":3,"lineText":"let x = 1","suggestion":""},`+
+		`"notes":[{"text":"note","location":null}]}`)
+}
+
+func TestOnMessage(t *testing.T) {
+	var msgs []logger.Msg
+	log := logger.NewStderrLog(logger.OutputOptions{
+		LogLevel:  logger.LevelWarning,
+		OnMessage: func(msg logger.Msg) { msgs = append(msgs, msg) },
+	})
+	log.AddMsg(logger.Msg{Kind: logger.Info, Data: logger.MsgData{Text: "info"}})
+	log.AddMsg(logger.Msg{Kind: logger.Warning, Data: logger.MsgData{Text: "warning"}})
+	log.AddError(nil, logger.Range{}, "error")
+	log.Done()
+
+	test.AssertEqual(t, len(msgs), 2)
+	test.AssertEqual(t, msgs[0].Data.Text, "warning")
+	test.AssertEqual(t, msgs[1].Data.Text, "error")
+}
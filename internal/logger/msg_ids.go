@@ -16,6 +16,7 @@ const (
 	MsgID_JS_AssignToImport
 	MsgID_JS_CallImportNamespace
 	MsgID_JS_CommonJSVariableInESM
+	MsgID_JS_DeclarationInference
 	MsgID_JS_DeleteSuperProperty
 	MsgID_JS_DirectEval
 	MsgID_JS_DuplicateCase
@@ -27,6 +28,7 @@ const (
 	MsgID_JS_HTMLCommentInJS
 	MsgID_JS_ImpossibleTypeof
 	MsgID_JS_IndirectRequire
+	MsgID_JS_IsolatedModules
 	MsgID_JS_PrivateNameWillThrow
 	MsgID_JS_SemicolonAfterReturn
 	MsgID_JS_SuspiciousBooleanNot
@@ -35,6 +37,7 @@ const (
 	MsgID_JS_UnsupportedJSXComment
 	MsgID_JS_UnsupportedRegExp
 	MsgID_JS_UnsupportedRequireCall
+	MsgID_JS_UnsupportedRuntimeFeature
 
 	// CSS
 	MsgID_CSS_CSSSyntaxError
@@ -48,12 +51,21 @@ const (
 	MsgID_CSS_UnsupportedAtNamespace
 	MsgID_CSS_UnsupportedCSSProperty
 
+	// HTML
+	MsgID_HTML_UnterminatedComment
+
 	// Bundler
 	MsgID_Bundler_AmbiguousReexport
+	MsgID_Bundler_BrowserFieldSubstitution
 	MsgID_Bundler_DifferentPathCase
+	MsgID_Bundler_DisallowedLicense
+	MsgID_Bundler_DuplicatePackage
 	MsgID_Bundler_IgnoredBareImport
 	MsgID_Bundler_IgnoredDynamicImport
+	MsgID_Bundler_ImportCycle
 	MsgID_Bundler_ImportIsUndefined
+	MsgID_Bundler_InvalidImportMap
+	MsgID_Bundler_LargeInlineWorker
 	MsgID_Bundler_RequireResolveNotExternal
 
 	// Source maps
@@ -68,6 +80,7 @@ const (
 	MsgID_PackageJSON_InvalidImportsOrExports
 	MsgID_PackageJSON_InvalidSideEffects
 	MsgID_PackageJSON_InvalidType
+	MsgID_PackageJSON_UnmappedPublishPath
 	MsgID_PackageJSON_LAST // Keep this last
 
 	// tsconfig.json
@@ -77,6 +90,7 @@ const (
 	MsgID_TsconfigJSON_InvalidJSX
 	MsgID_TsconfigJSON_InvalidModuleSuffixes
 	MsgID_TsconfigJSON_InvalidPaths
+	MsgID_TsconfigJSON_InvalidReferences
 	MsgID_TsconfigJSON_InvalidTarget
 	MsgID_TsconfigJSON_Missing
 	MsgID_TsconfigJSON_LAST // Keep this last
@@ -95,6 +109,8 @@ func StringToMsgIDs(str string, logLevel LogLevel, overrides map[MsgID]LogLevel)
 		overrides[MsgID_JS_CallImportNamespace] = logLevel
 	case "commonjs-variable-in-esm":
 		overrides[MsgID_JS_CommonJSVariableInESM] = logLevel
+	case "declaration-inference":
+		overrides[MsgID_JS_DeclarationInference] = logLevel
 	case "delete-super-property":
 		overrides[MsgID_JS_DeleteSuperProperty] = logLevel
 	case "direct-eval":
@@ -117,6 +133,8 @@ func StringToMsgIDs(str string, logLevel LogLevel, overrides map[MsgID]LogLevel)
 		overrides[MsgID_JS_ImpossibleTypeof] = logLevel
 	case "indirect-require":
 		overrides[MsgID_JS_IndirectRequire] = logLevel
+	case "isolated-modules":
+		overrides[MsgID_JS_IsolatedModules] = logLevel
 	case "private-name-will-throw":
 		overrides[MsgID_JS_PrivateNameWillThrow] = logLevel
 	case "semicolon-after-return":
@@ -133,6 +151,8 @@ func StringToMsgIDs(str string, logLevel LogLevel, overrides map[MsgID]LogLevel)
 		overrides[MsgID_JS_UnsupportedRegExp] = logLevel
 	case "unsupported-require-call":
 		overrides[MsgID_JS_UnsupportedRequireCall] = logLevel
+	case "unsupported-runtime-feature":
+		overrides[MsgID_JS_UnsupportedRuntimeFeature] = logLevel
 
 	// CSS
 	case "css-syntax-error":
@@ -156,17 +176,33 @@ func StringToMsgIDs(str string, logLevel LogLevel, overrides map[MsgID]LogLevel)
 	case "unsupported-css-property":
 		overrides[MsgID_CSS_UnsupportedCSSProperty] = logLevel
 
+	// HTML
+	case "unterminated-html-comment":
+		overrides[MsgID_HTML_UnterminatedComment] = logLevel
+
 	// Bundler
 	case "ambiguous-reexport":
 		overrides[MsgID_Bundler_AmbiguousReexport] = logLevel
+	case "browser-field-substitution":
+		overrides[MsgID_Bundler_BrowserFieldSubstitution] = logLevel
 	case "different-path-case":
 		overrides[MsgID_Bundler_DifferentPathCase] = logLevel
+	case "disallowed-license":
+		overrides[MsgID_Bundler_DisallowedLicense] = logLevel
+	case "duplicate-package":
+		overrides[MsgID_Bundler_DuplicatePackage] = logLevel
 	case "ignored-bare-import":
 		overrides[MsgID_Bundler_IgnoredBareImport] = logLevel
 	case "ignored-dynamic-import":
 		overrides[MsgID_Bundler_IgnoredDynamicImport] = logLevel
+	case "import-cycle":
+		overrides[MsgID_Bundler_ImportCycle] = logLevel
 	case "import-is-undefined":
 		overrides[MsgID_Bundler_ImportIsUndefined] = logLevel
+	case "invalid-import-map":
+		overrides[MsgID_Bundler_InvalidImportMap] = logLevel
+	case "large-inline-worker":
+		overrides[MsgID_Bundler_LargeInlineWorker] = logLevel
 	case "require-resolve-not-external":
 		overrides[MsgID_Bundler_RequireResolveNotExternal] = logLevel
 
@@ -207,6 +243,8 @@ func MsgIDToString(id MsgID) string {
 		return "call-import-namespace"
 	case MsgID_JS_CommonJSVariableInESM:
 		return "commonjs-variable-in-esm"
+	case MsgID_JS_DeclarationInference:
+		return "declaration-inference"
 	case MsgID_JS_DeleteSuperProperty:
 		return "delete-super-property"
 	case MsgID_JS_DirectEval:
@@ -229,6 +267,8 @@ func MsgIDToString(id MsgID) string {
 		return "impossible-typeof"
 	case MsgID_JS_IndirectRequire:
 		return "indirect-require"
+	case MsgID_JS_IsolatedModules:
+		return "isolated-modules"
 	case MsgID_JS_PrivateNameWillThrow:
 		return "private-name-will-throw"
 	case MsgID_JS_SemicolonAfterReturn:
@@ -245,6 +285,8 @@ func MsgIDToString(id MsgID) string {
 		return "unsupported-regexp"
 	case MsgID_JS_UnsupportedRequireCall:
 		return "unsupported-require-call"
+	case MsgID_JS_UnsupportedRuntimeFeature:
+		return "unsupported-runtime-feature"
 
 	// CSS
 	case MsgID_CSS_CSSSyntaxError:
@@ -268,17 +310,33 @@ func MsgIDToString(id MsgID) string {
 	case MsgID_CSS_UnsupportedCSSProperty:
 		return "unsupported-css-property"
 
+	// HTML
+	case MsgID_HTML_UnterminatedComment:
+		return "unterminated-html-comment"
+
 	// Bundler
 	case MsgID_Bundler_AmbiguousReexport:
 		return "ambiguous-reexport"
+	case MsgID_Bundler_BrowserFieldSubstitution:
+		return "browser-field-substitution"
 	case MsgID_Bundler_DifferentPathCase:
 		return "different-path-case"
+	case MsgID_Bundler_DisallowedLicense:
+		return "disallowed-license"
+	case MsgID_Bundler_DuplicatePackage:
+		return "duplicate-package"
 	case MsgID_Bundler_IgnoredBareImport:
 		return "ignored-bare-import"
 	case MsgID_Bundler_IgnoredDynamicImport:
 		return "ignored-dynamic-import"
+	case MsgID_Bundler_ImportCycle:
+		return "import-cycle"
 	case MsgID_Bundler_ImportIsUndefined:
 		return "import-is-undefined"
+	case MsgID_Bundler_InvalidImportMap:
+		return "invalid-import-map"
+	case MsgID_Bundler_LargeInlineWorker:
+		return "large-inline-worker"
 	case MsgID_Bundler_RequireResolveNotExternal:
 		return "require-resolve-not-external"
 
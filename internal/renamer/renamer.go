@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -85,6 +86,7 @@ type symbolSlot struct {
 	name               string
 	count              uint32
 	needsCapitalForJSX uint32 // This is really a bool but needs to be atomic
+	needsRuntimePrefix bool   // This is only ever set for top-level symbols
 }
 
 type MinifyRenamer struct {
@@ -92,12 +94,19 @@ type MinifyRenamer struct {
 	slots                [4][]symbolSlot
 	topLevelSymbolToSlot map[js_ast.Ref]uint32
 	symbols              js_ast.SymbolMap
+	runtimePrefix        string
 }
 
-func NewMinifyRenamer(symbols js_ast.SymbolMap, firstTopLevelSlots js_ast.SlotCounts, reservedNames map[string]uint32) *MinifyRenamer {
+func NewMinifyRenamer(
+	symbols js_ast.SymbolMap,
+	firstTopLevelSlots js_ast.SlotCounts,
+	reservedNames map[string]uint32,
+	runtimePrefix string,
+) *MinifyRenamer {
 	return &MinifyRenamer{
 		symbols:       symbols,
 		reservedNames: reservedNames,
+		runtimePrefix: runtimePrefix,
 		slots: [4][]symbolSlot{
 			make([]symbolSlot, firstTopLevelSlots[0]),
 			make([]symbolSlot, firstTopLevelSlots[1]),
@@ -251,12 +260,26 @@ func (r *MinifyRenamer) AllocateTopLevelSymbolSlots(topLevelSymbols StableSymbol
 			*slots = append(*slots, symbolSlot{
 				count:              stable.Count,
 				needsCapitalForJSX: needsCapitalForJSX,
+				needsRuntimePrefix: symbol.Flags.Has(js_ast.MustStartWithRuntimePrefix),
 			})
 			r.topLevelSymbolToSlot[stable.Ref] = i
 		}
 	}
 }
 
+// Symbols that need the runtime prefix will have it prepended to their
+// minified name, so all other symbols must avoid names that start with it.
+func (r *MinifyRenamer) isNameUnavailable(name string, slot *symbolSlot) bool {
+	if r.runtimePrefix != "" {
+		if slot.needsRuntimePrefix {
+			name = r.runtimePrefix + name
+		} else if strings.HasPrefix(name, r.runtimePrefix) {
+			return true
+		}
+	}
+	return r.reservedNames[name] != 0
+}
+
 func (r *MinifyRenamer) AssignNamesByFrequency(minifier *js_ast.NameMinifier) {
 	for ns, slots := range r.slots {
 		// Sort symbols by count
@@ -280,7 +303,7 @@ func (r *MinifyRenamer) AssignNamesByFrequency(minifier *js_ast.NameMinifier) {
 			// with a "#" character.
 			switch js_ast.SlotNamespace(ns) {
 			case js_ast.SlotDefault:
-				for r.reservedNames[name] != 0 {
+				for r.isNameUnavailable(name, slot) {
 					name = minifier.NumberToMinifiedName(nextName)
 					nextName++
 				}
@@ -293,6 +316,11 @@ func (r *MinifyRenamer) AssignNamesByFrequency(minifier *js_ast.NameMinifier) {
 					}
 				}
 
+				// Generated runtime symbols keep their prefix even when minified
+				if slot.needsRuntimePrefix {
+					name = r.runtimePrefix + name
+				}
+
 			case js_ast.SlotLabel:
 				for js_lexer.Keywords[name] != 0 {
 					name = minifier.NumberToMinifiedName(nextName)
@@ -0,0 +1,115 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Deno projects configure their dependencies with a "deno.json" file in the
+// project root instead of "package.json". The import map in that file is
+// either inline (the "imports" and "scopes" properties) or in a separate file
+// referenced by the "importMap" property. The first "deno.json" or
+// "deno.jsonc" file found by walking up from the working directory is used.
+func (r *resolver) loadDenoImportMap() *ImportMap {
+	for dir := r.fs.Cwd(); dir != ""; {
+		for _, name := range []string{"deno.json", "deno.jsonc"} {
+			path := r.fs.Join(dir, name)
+			json, source, ok := r.readImportMapJSON(path)
+			if !ok {
+				continue
+			}
+
+			// Handle a reference to a separate import map file
+			if importMapJSON, _, ok := getProperty(json, "importMap"); ok {
+				tracker := logger.MakeLineColumnTracker(&source)
+				importMapPath, ok := getString(importMapJSON)
+				if !ok {
+					r.log.AddID(logger.MsgID_Bundler_InvalidImportMap, logger.Warning, &tracker, logger.Range{Loc: importMapJSON.Loc},
+						"The value for \"importMap\" must be a string")
+					return nil
+				}
+				if !r.fs.IsAbs(importMapPath) {
+					importMapPath = r.fs.Join(dir, importMapPath)
+				}
+				mapJSON, mapSource, ok := r.readImportMapJSON(importMapPath)
+				if !ok {
+					r.log.AddID(logger.MsgID_Bundler_InvalidImportMap, logger.Warning, &tracker, source.RangeOfString(importMapJSON.Loc),
+						fmt.Sprintf("Cannot read import map %q", r.PrettyPath(logger.Path{Text: importMapPath, Namespace: "file"})))
+					return nil
+				}
+				return parseImportMap(r.fs, r.log, mapSource, mapJSON, r.fs.Dir(importMapPath))
+			}
+
+			return parseImportMap(r.fs, r.log, source, json, dir)
+		}
+
+		parent := r.fs.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return nil
+}
+
+// Deno has its own specifiers for packages from the npm and JSR registries.
+// When Deno installs these into a "node_modules" directory, npm packages keep
+// their name and JSR packages use the name from JSR's npm compatibility layer
+// (e.g. "jsr:@std/assert" becomes "@jsr/std__assert"). This converts these
+// specifiers into package paths so they can be found by the normal resolver:
+//
+//	"npm:react@18/jsx-runtime" => "react/jsx-runtime"
+//	"npm:@types/node" => "@types/node"
+//	"jsr:@std/path@^1.0.0/posix" => "@jsr/std__path/posix"
+func parseDenoPackageSpecifier(specifier string) (string, bool) {
+	var rest string
+	isJSR := false
+	if strings.HasPrefix(specifier, "npm:") {
+		rest = specifier[len("npm:"):]
+	} else if strings.HasPrefix(specifier, "jsr:") {
+		rest = specifier[len("jsr:"):]
+		isJSR = true
+	} else {
+		return "", false
+	}
+	rest = strings.TrimPrefix(rest, "/")
+
+	// Split the package name from the subpath, keeping in mind that scoped
+	// package names contain a slash
+	nameEnd := strings.IndexByte(rest, '/')
+	if strings.HasPrefix(rest, "@") {
+		if nameEnd == -1 {
+			return "", false
+		}
+		if slash := strings.IndexByte(rest[nameEnd+1:], '/'); slash != -1 {
+			nameEnd += slash + 1
+		} else {
+			nameEnd = -1
+		}
+	}
+	if nameEnd == -1 {
+		nameEnd = len(rest)
+	}
+	name, subpath := rest[:nameEnd], rest[nameEnd:]
+
+	// Remove the version range, if any
+	if at := strings.LastIndexByte(name, '@'); at > 0 {
+		name = name[:at]
+	}
+	if name == "" || strings.HasSuffix(name, "/") {
+		return "", false
+	}
+
+	if isJSR {
+		// All JSR packages are scoped
+		slash := strings.IndexByte(name, '/')
+		if !strings.HasPrefix(name, "@") || slash == -1 {
+			return "", false
+		}
+		name = fmt.Sprintf("@jsr/%s__%s", name[1:slash], name[slash+1:])
+	}
+
+	return name + subpath, true
+}
@@ -0,0 +1,199 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This implements the parts of the import map specification that make sense
+// for a bundler: https://github.com/WICG/import-maps. Keys and values that are
+// relative paths are resolved relative to the directory containing the file
+// that the import map came from, so they end up as absolute paths. Everything
+// else (package names, URLs, and "npm:" or "jsr:" specifiers) is kept as-is and
+// passed on to the rest of the resolver.
+type ImportMap struct {
+	imports specifierMap
+
+	// These are sorted from most specific to least specific
+	scopes []importMapScope
+}
+
+type importMapScope struct {
+	dir     string
+	imports specifierMap
+}
+
+type specifierMap struct {
+	exact map[string]string
+
+	// These are keys that end in "/". They are stored without the trailing
+	// slash and are sorted from longest to shortest so that the most specific
+	// key is matched first.
+	prefixes []importMapPrefix
+}
+
+type importMapPrefix struct {
+	key   string
+	value string
+}
+
+// The key must either be a package name or an absolute path. Relative paths
+// should be joined with the directory of the importer first.
+func (m *ImportMap) Resolve(importerDir string, key string) (string, bool) {
+	if importerDir != "" {
+		for _, scope := range m.scopes {
+			if hasPathPrefix(importerDir, scope.dir) {
+				if value, ok := scope.imports.resolve(key); ok {
+					return value, true
+				}
+			}
+		}
+	}
+	return m.imports.resolve(key)
+}
+
+func (m specifierMap) resolve(key string) (string, bool) {
+	if value, ok := m.exact[key]; ok {
+		return value, true
+	}
+	for _, prefix := range m.prefixes {
+		if len(key) > len(prefix.key) && strings.HasPrefix(key, prefix.key) && isSlash(key[len(prefix.key)]) {
+			return prefix.value + key[len(prefix.key):], true
+		}
+	}
+	return "", false
+}
+
+func hasPathPrefix(path string, prefix string) bool {
+	return path == prefix || (len(path) > len(prefix) && strings.HasPrefix(path, prefix) && isSlash(path[len(prefix)]))
+}
+
+// This loads the import map from the "ImportMap" API option
+func (r *resolver) loadImportMapFile(absPath string) *ImportMap {
+	json, source, ok := r.readImportMapJSON(absPath)
+	if !ok {
+		// Syntax errors have already been logged by the JSON parser
+		if source.KeyPath.Text == "" {
+			r.log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot read import map %q",
+				r.PrettyPath(logger.Path{Text: absPath, Namespace: "file"})))
+		}
+		return nil
+	}
+	return parseImportMap(r.fs, r.log, source, json, r.fs.Dir(absPath))
+}
+
+func (r *resolver) readImportMapJSON(path string) (json js_ast.Expr, source logger.Source, ok bool) {
+	contents, err, _ := r.caches.FSCache.ReadFile(r.fs, path)
+	if err != nil {
+		return
+	}
+	keyPath := logger.Path{Text: path, Namespace: "file"}
+	source = logger.Source{
+		KeyPath:    keyPath,
+		PrettyPath: r.PrettyPath(keyPath),
+		Contents:   contents,
+	}
+	json, ok = r.caches.JSONCache.Parse(r.log, source, js_parser.JSONOptions{
+		AllowComments:       true,
+		AllowTrailingCommas: true,
+	})
+	return
+}
+
+// This parses the "imports" and "scopes" properties of the given object, which
+// can either be a standalone import map file or a "deno.json" file.
+func parseImportMap(fs fs.FS, log logger.Log, source logger.Source, json js_ast.Expr, absDir string) *ImportMap {
+	tracker := logger.MakeLineColumnTracker(&source)
+	importMap := &ImportMap{}
+
+	if importsJSON, _, ok := getProperty(json, "imports"); ok {
+		importMap.imports = parseSpecifierMap(fs, log, &tracker, source, importsJSON, absDir,
+			"The value for \"imports\" must be an object")
+	}
+
+	if scopesJSON, _, ok := getProperty(json, "scopes"); ok {
+		if obj, ok := scopesJSON.Data.(*js_ast.EObject); ok {
+			for _, prop := range obj.Properties {
+				if key, ok := prop.Key.Data.(*js_ast.EString); ok {
+					dir := strings.TrimRight(resolveImportMapPath(fs, absDir, helpers.UTF16ToString(key.Value)), "/\\")
+					imports := parseSpecifierMap(fs, log, &tracker, source, prop.ValueOrNil, absDir,
+						"The values in \"scopes\" must be objects")
+					importMap.scopes = append(importMap.scopes, importMapScope{dir: dir, imports: imports})
+				}
+			}
+			sort.SliceStable(importMap.scopes, func(i int, j int) bool {
+				return len(importMap.scopes[i].dir) > len(importMap.scopes[j].dir)
+			})
+		} else {
+			log.AddID(logger.MsgID_Bundler_InvalidImportMap, logger.Warning, &tracker, logger.Range{Loc: scopesJSON.Loc},
+				"The value for \"scopes\" must be an object")
+		}
+	}
+
+	return importMap
+}
+
+func parseSpecifierMap(
+	fs fs.FS, log logger.Log, tracker *logger.LineColumnTracker, source logger.Source,
+	json js_ast.Expr, absDir string, invalidText string,
+) (result specifierMap) {
+	obj, ok := json.Data.(*js_ast.EObject)
+	if !ok {
+		log.AddID(logger.MsgID_Bundler_InvalidImportMap, logger.Warning, tracker, logger.Range{Loc: json.Loc}, invalidText)
+		return
+	}
+
+	result.exact = make(map[string]string)
+	for _, prop := range obj.Properties {
+		keyJSON, ok := prop.Key.Data.(*js_ast.EString)
+		if !ok {
+			continue
+		}
+		key := helpers.UTF16ToString(keyJSON.Value)
+		value, ok := getString(prop.ValueOrNil)
+		if !ok {
+			log.AddID(logger.MsgID_Bundler_InvalidImportMap, logger.Warning, tracker, logger.Range{Loc: prop.ValueOrNil.Loc},
+				fmt.Sprintf("The value for %q must be a string", key))
+			continue
+		}
+
+		// Keys that end in "/" map every path inside of them
+		if strings.HasSuffix(key, "/") {
+			if !strings.HasSuffix(value, "/") {
+				log.AddID(logger.MsgID_Bundler_InvalidImportMap, logger.Warning, tracker, source.RangeOfString(prop.ValueOrNil.Loc),
+					fmt.Sprintf("The value for %q must end in \"/\" because the key does", key))
+				continue
+			}
+			result.prefixes = append(result.prefixes, importMapPrefix{
+				key:   strings.TrimRight(resolveImportMapPath(fs, absDir, key), "/\\"),
+				value: strings.TrimRight(resolveImportMapPath(fs, absDir, value), "/\\"),
+			})
+			continue
+		}
+
+		result.exact[resolveImportMapPath(fs, absDir, key)] = resolveImportMapPath(fs, absDir, value)
+	}
+
+	sort.SliceStable(result.prefixes, func(i int, j int) bool {
+		return len(result.prefixes[i].key) > len(result.prefixes[j].key)
+	})
+	return
+}
+
+// Relative paths are relative to the directory containing the import map
+func resolveImportMapPath(fs fs.FS, absDir string, path string) string {
+	if IsPackagePath(path) {
+		return path
+	}
+	if !fs.IsAbs(path) {
+		path = fs.Join(absDir, path)
+	}
+	return path
+}
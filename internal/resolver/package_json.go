@@ -48,6 +48,9 @@ type packageJSON struct {
 	//
 	browserMap map[string]*string
 
+	// The location of each key in "browserMap" for use in log messages
+	browserMapLocs map[string]logger.Loc
+
 	// If this is non-nil, each entry in this map is the absolute path of a file
 	// with side effects. Any entry not in this map should be considered to have
 	// no side effects, which means import statements for these files can be
@@ -226,20 +229,56 @@ func (r resolverQuery) checkBrowserMap(resolveDirInfo *dirInfo, inputPath string
 		}
 	}
 
-	if r.debugLogs != nil {
-		if ok {
+	if !ok {
+		if r.debugLogs != nil {
+			r.debugLogs.addNote(fmt.Sprintf("Failed to find %q", inputPath))
+		}
+		return
+	}
+
+	// Follow chains of mappings within the same "browser" map. This matches
+	// Webpack, which runs the remapped path through the "browser" map again.
+	// For example, "./a.js": "./b.js" and "./b.js": false disables "./a.js".
+	visited := make(map[string]bool)
+	for {
+		visited[inputPath] = true
+		r.recordBrowserRemap(packageJSON, inputPath, remapped)
+		if r.debugLogs != nil {
 			if remapped == nil {
 				r.debugLogs.addNote(fmt.Sprintf("Found %q marked as disabled", inputPath))
 			} else {
 				r.debugLogs.addNote(fmt.Sprintf("Found %q mapping to %q", inputPath, *remapped))
 			}
-		} else {
-			r.debugLogs.addNote(fmt.Sprintf("Failed to find %q", inputPath))
+		}
+		if remapped == nil {
+			break
+		}
+		prevInputPath, prevRemapped := inputPath, remapped
+		if !checkPath(*remapped, includeImplicitExtensions) || visited[inputPath] {
+			inputPath, remapped, ok = prevInputPath, prevRemapped, true
+			break
 		}
 	}
 	return
 }
 
+func (r resolverQuery) recordBrowserRemap(packageJSON *packageJSON, key string, remapped *string) {
+	if r.browserRemaps == nil {
+		return
+	}
+	for _, remap := range *r.browserRemaps {
+		if remap.PackageJSON == &packageJSON.source && remap.Key == key {
+			return
+		}
+	}
+	*r.browserRemaps = append(*r.browserRemaps, BrowserRemap{
+		PackageJSON: &packageJSON.source,
+		KeyLoc:      packageJSON.browserMapLocs[key],
+		Key:         key,
+		Remapped:    remapped,
+	})
+}
+
 func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 	packageJSONPath := r.fs.Join(inputPath, "package.json")
 	contents, err, originalError := r.caches.FSCache.ReadFile(r.fs, packageJSONPath)
@@ -361,6 +400,7 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 		if browser, ok := browserJSON.Data.(*js_ast.EObject); ok {
 			// The value is an object
 			browserMap := make(map[string]*string)
+			browserMapLocs := make(map[string]logger.Loc)
 
 			// Remap all files in the browser field
 			for _, prop := range browser.Properties {
@@ -368,10 +408,12 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 					if value, ok := getString(prop.ValueOrNil); ok {
 						// If this is a string, it's a replacement package
 						browserMap[key] = &value
+						browserMapLocs[key] = prop.Key.Loc
 					} else if value, ok := getBool(prop.ValueOrNil); ok {
 						// If this is false, it means the package is disabled
 						if !value {
 							browserMap[key] = nil
+							browserMapLocs[key] = prop.Key.Loc
 						}
 					} else {
 						r.log.AddID(logger.MsgID_PackageJSON_InvalidBrowser, logger.Warning, &tracker, logger.Range{Loc: prop.ValueOrNil.Loc},
@@ -381,6 +423,7 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 			}
 
 			packageJSON.browserMap = browserMap
+			packageJSON.browserMapLocs = browserMapLocs
 		}
 	}
 
@@ -421,17 +464,27 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 				if !strings.ContainsRune(pattern, '/') {
 					pattern = "**/" + pattern
 				}
-				absPattern := r.fs.Join(inputPath, pattern)
-				re, hadWildcard := globstarToEscapedRegexp(absPattern)
 
-				// Wildcard patterns require more expensive matching
-				if hadWildcard {
-					packageJSON.sideEffectsRegexps = append(packageJSON.sideEffectsRegexps, regexp.MustCompile(re))
-					continue
-				}
+				// Webpack supports "{a,b}" alternatives, so expand those first
+				for _, pattern := range helpers.ExpandGlobBraces(pattern) {
+					absPattern := r.fs.Join(inputPath, pattern)
+					re, hadWildcard := globstarToEscapedRegexp(absPattern)
+
+					// Wildcard patterns require more expensive matching
+					if hadWildcard {
+						compiled, err := regexp.Compile(re)
+						if err != nil {
+							r.log.AddID(logger.MsgID_PackageJSON_InvalidSideEffects, logger.Warning, &tracker, jsonSource.RangeOfString(itemJSON.Loc),
+								fmt.Sprintf("Invalid pattern in \"sideEffects\": %q", helpers.UTF16ToString(item.Value)))
+							break
+						}
+						packageJSON.sideEffectsRegexps = append(packageJSON.sideEffectsRegexps, compiled)
+						continue
+					}
 
-				// Normal strings can be matched with a map lookup
-				packageJSON.sideEffectsMap[absPattern] = true
+					// Normal strings can be matched with a map lookup
+					packageJSON.sideEffectsMap[absPattern] = true
+				}
 			}
 
 		default:
@@ -440,6 +493,26 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 		}
 	}
 
+	// Let the user replace the "sideEffects" field for packages that have a
+	// missing or incorrect value. Nested "package.json" files inside a package
+	// often don't have a name, so use the name from the path when possible.
+	if len(r.options.SideEffectsOverrides) > 0 {
+		name, ok := packageNameFromNodeModulesPath(inputPath)
+		if !ok {
+			name = packageJSON.name
+		}
+		if hasSideEffects, ok := r.options.SideEffectsOverrides[name]; ok && name != "" {
+			packageJSON.sideEffectsRegexps = nil
+			if hasSideEffects {
+				packageJSON.sideEffectsMap = nil
+				packageJSON.sideEffectsData = nil
+			} else {
+				packageJSON.sideEffectsMap = make(map[string]bool)
+				packageJSON.sideEffectsData = &SideEffectsData{OverridePackageName: name}
+			}
+		}
+	}
+
 	// Read the "imports" map
 	if importsJSON, _, ok := getProperty(json, "imports"); ok {
 		if importsMap := parseImportsExportsMap(jsonSource, r.log, importsJSON); importsMap != nil {
@@ -471,10 +544,29 @@ func globstarToEscapedRegexp(glob string) (string, bool) {
 	for i := 0; i < n; i++ {
 		c := glob[i]
 		switch c {
-		case '\\', '^', '$', '.', '+', '|', '(', ')', '[', ']', '{', '}':
+		case '\\', '^', '$', '.', '+', '|', '(', ')', ']', '{', '}':
 			sb.WriteByte('\\')
 			sb.WriteByte(c)
 
+		case '[':
+			// Webpack passes character classes such as "[jt]s" through to the
+			// regular expression. An unterminated "[" is matched literally.
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 1 {
+				sb.WriteString("\\[")
+				break
+			}
+			sb.WriteByte('[')
+			for _, c := range []byte(glob[i+1 : i+1+end]) {
+				if c == '\\' || c == '[' || c == '^' {
+					sb.WriteByte('\\')
+				}
+				sb.WriteByte(c)
+			}
+			sb.WriteByte(']')
+			i += end + 1
+			hadWildcard = true
+
 		case '?':
 			sb.WriteByte('.')
 			hadWildcard = true
@@ -521,6 +613,22 @@ func globstarToEscapedRegexp(glob string) (string, bool) {
 	return sb.String(), hadWildcard
 }
 
+// This returns the name of the package containing a directory inside a
+// "node_modules" directory. For example, the name for the directory
+// "node_modules/@scope/pkg/dist" is "@scope/pkg".
+func packageNameFromNodeModulesPath(path string) (string, bool) {
+	path = strings.ReplaceAll(path, "\\", "/")
+	i := strings.LastIndex(path, "/node_modules/")
+	if i == -1 {
+		return "", false
+	}
+	parts := strings.Split(path[i+len("/node_modules/"):], "/")
+	if strings.HasPrefix(parts[0], "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1], true
+	}
+	return parts[0], parts[0] != ""
+}
+
 // Reference: https://nodejs.org/api/esm.html#esm_resolver_algorithm_specification
 type pjMap struct {
 	root pjEntry
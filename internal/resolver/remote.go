@@ -0,0 +1,27 @@
+package resolver
+
+import (
+	"net/url"
+	"strings"
+)
+
+func IsRemoteURL(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// Relative and root-relative import paths in a remote module are relative to
+// the URL of that module, which is how browsers and Deno resolve them
+func ResolveRelativeRemoteURL(importer string, path string) (string, bool) {
+	if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") && !strings.HasPrefix(path, "/") {
+		return "", false
+	}
+	base, err := url.Parse(importer)
+	if err != nil {
+		return "", false
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", false
+	}
+	return base.ResolveReference(ref).String(), true
+}
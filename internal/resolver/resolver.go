@@ -52,6 +52,9 @@ var defaultMainFields = map[config.Platform][]string{
 	// pick good defaults for their platform. In that case, the list of main
 	// fields is empty by default. You must explicitly configure it yourself.
 	config.PlatformNeutral: {},
+
+	// Deno runs npm packages the same way node does, so the same caveats apply
+	config.PlatformDeno: {"main", "module"},
 }
 
 // These are the main fields to use when the "main fields" setting is configured
@@ -83,12 +86,21 @@ func (pp *PathPair) HasSecondary() bool {
 	return pp.Secondary.Text != ""
 }
 
+func (pp *PathPair) SetConditions(conditions string) {
+	for _, path := range pp.iter() {
+		path.Conditions = conditions
+	}
+}
+
 type SideEffectsData struct {
 	Source *logger.Source
 
 	// If non-empty, this false value came from a plugin
 	PluginName string
 
+	// If non-empty, this false value came from an override for this package
+	OverridePackageName string
+
 	Range logger.Range
 
 	// If true, "sideEffects" was an array. If false, "sideEffects" was false.
@@ -102,8 +114,10 @@ type ResolveResult struct {
 	PluginData interface{}
 
 	// If not empty, these should override the default values
-	JSXFactory  []string // Default if empty: "React.createElement"
-	JSXFragment []string // Default if empty: "React.Fragment"
+	JSXFactory      []string // Default if empty: "React.createElement"
+	JSXFragment     []string // Default if empty: "React.Fragment"
+	JSXImportSource *string  // Default if nil: "react"
+	JSX             config.TSConfigJSX
 
 	DifferentCase *fs.DifferentCase
 
@@ -123,8 +137,23 @@ type ResolveResult struct {
 	// If true, the class field transform should use Object.defineProperty().
 	UseDefineForClassFieldsTS config.MaybeBool
 
+	// If true, decorated class members should have "design:*" metadata
+	EmitDecoratorMetadataTS bool
+
 	// This is the "importsNotUsedAsValues" and "preserveValueImports" fields from "package.json"
 	UnusedImportFlagsTS config.UnusedImportFlagsTS
+
+	// These are the substitutions from "browser" fields in "package.json" files
+	// that were used to arrive at this result, in the order they were applied
+	BrowserRemaps []BrowserRemap
+}
+
+// This is a single use of an entry in the "browser" field in "package.json"
+type BrowserRemap struct {
+	PackageJSON *logger.Source
+	Remapped    *string // This is nil if the module was disabled with "false"
+	Key         string
+	KeyLoc      logger.Loc
 }
 
 type DebugMeta struct {
@@ -163,6 +192,21 @@ type Resolver interface {
 	// This tries to run "Resolve" on a package path as a relative path. If
 	// successful, the user just forgot a leading "./" in front of the path.
 	ProbeResolvePackageAsRelative(sourceDir string, importPath string, kind ast.ImportKind) *ResolveResult
+
+	// This returns a resolver that shares all caches with this one but that
+	// also uses the additional conditions when interpreting the "exports" and
+	// "imports" fields in package.json. The conditions are a comma-separated
+	// list, which is the same format as the "Conditions" field on paths.
+	WithConditions(conditions string) Resolver
+
+	// This returns the relative import paths of all files that match the glob
+	// pattern in sorted order. The pattern must be a relative path.
+	Glob(sourceDir string, pattern string) []string
+
+	// This returns the "name" field from the nearest enclosing "package.json"
+	// file that has one, or the empty string if there isn't one. It's used for
+	// the "[pkg]" placeholder in output path templates.
+	PackageName(absPath string) string
 }
 
 type resolver struct {
@@ -172,9 +216,12 @@ type resolver struct {
 
 	// These are sets that represent various conditions for the "exports" field
 	// in package.json.
-	esmConditionsDefault map[string]bool
-	esmConditionsImport  map[string]bool
-	esmConditionsRequire map[string]bool
+	esmConditions *esmConditionSets
+
+	// These are additional condition sets that are only used for some entry
+	// points (and everything those entry points import). This is guarded by
+	// "mutex".
+	esmConditionsExtra map[string]*esmConditionSets
 
 	// A special filtered import order for CSS "@import" imports.
 	//
@@ -204,6 +251,16 @@ type resolver struct {
 	// all parent directories
 	dirCache map[string]*dirInfo
 
+	// This is either the import map from the "ImportMap" option or the one from
+	// "deno.json" when the platform is "deno". It never changes after the
+	// resolver is created.
+	importMap *ImportMap
+
+	// Directories whose real path is currently being computed. This stops the
+	// recursion if symlinks form a cycle through each other's parent directories.
+	// This is guarded by "mutex".
+	realPathsInProgress map[string]bool
+
 	options config.Options
 
 	// This mutex serves two purposes. First of all, it guards access to "dirCache"
@@ -219,10 +276,111 @@ type resolver struct {
 
 type resolverQuery struct {
 	*resolver
-	moduleSuffixes []string
-	debugMeta      *DebugMeta
-	debugLogs      *debugLogs
-	kind           ast.ImportKind
+	moduleSuffixes     []string
+	tsConfigReferences *TSConfigReferences
+	debugMeta          *DebugMeta
+	debugLogs          *debugLogs
+	esmConditions      *esmConditionSets
+	browserRemaps      *[]BrowserRemap
+	kind               ast.ImportKind
+}
+
+type esmConditionSets struct {
+	defaultSet map[string]bool
+	importSet  map[string]bool
+	requireSet map[string]bool
+}
+
+func makeESMConditionSets(options *config.Options, extra []string) *esmConditionSets {
+	defaultSet := map[string]bool{"default": true}
+	importSet := map[string]bool{"import": true}
+	requireSet := map[string]bool{"require": true}
+	for _, condition := range options.Conditions {
+		defaultSet[condition] = true
+	}
+	for _, condition := range extra {
+		defaultSet[condition] = true
+	}
+	switch options.Platform {
+	case config.PlatformBrowser:
+		defaultSet["browser"] = true
+	case config.PlatformNode:
+		defaultSet["node"] = true
+	case config.PlatformDeno:
+		defaultSet["deno"] = true
+		defaultSet["node"] = true
+	}
+	for key := range defaultSet {
+		importSet[key] = true
+		requireSet[key] = true
+	}
+	return &esmConditionSets{
+		defaultSet: defaultSet,
+		importSet:  importSet,
+		requireSet: requireSet,
+	}
+}
+
+// The condition set is determined by the kind of import
+func (r resolverQuery) esmConditionsForKind() map[string]bool {
+	switch r.kind {
+	case ast.ImportStmt, ast.ImportDynamic:
+		return r.esmConditions.importSet
+	case ast.ImportRequire, ast.ImportRequireResolve:
+		return r.esmConditions.requireSet
+	case ast.ImportEntryPoint:
+		// Treat entry points as imports instead of requires for consistency with
+		// Webpack and Rollup. More information:
+		//
+		// * https://github.com/evanw/esbuild/issues/1956
+		// * https://github.com/nodejs/node/issues/41686
+		// * https://github.com/evanw/entry-point-resolve-test
+		//
+		return r.esmConditions.importSet
+	}
+	return r.esmConditions.defaultSet
+}
+
+// This normalizes a list of conditions into the comma-separated format used
+// for the "Conditions" field on paths. The order doesn't matter.
+func ConditionsKey(conditions []string) string {
+	sorted := make([]string, 0, len(conditions))
+	seen := make(map[string]bool)
+	for _, condition := range conditions {
+		if condition != "" && !seen[condition] {
+			seen[condition] = true
+			sorted = append(sorted, condition)
+		}
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+type resolverWithConditions struct {
+	*resolver
+	esmConditions *esmConditionSets
+}
+
+func (rr *resolver) WithConditions(conditions string) Resolver {
+	if conditions == "" {
+		return rr
+	}
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	sets, ok := rr.esmConditionsExtra[conditions]
+	if !ok {
+		sets = makeESMConditionSets(&rr.options, strings.Split(conditions, ","))
+		rr.esmConditionsExtra[conditions] = sets
+	}
+	return resolverWithConditions{resolver: rr, esmConditions: sets}
+}
+
+func (rr resolverWithConditions) Resolve(sourceDir string, importPath string, kind ast.ImportKind) (*ResolveResult, DebugMeta) {
+	return rr.resolver.resolve(sourceDir, importPath, kind, rr.esmConditions)
+}
+
+func (rr resolverWithConditions) ProbeResolvePackageAsRelative(sourceDir string, importPath string, kind ast.ImportKind) *ResolveResult {
+	return rr.resolver.probeResolvePackageAsRelative(sourceDir, importPath, kind, rr.esmConditions)
 }
 
 func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options config.Options) Resolver {
@@ -235,43 +393,40 @@ func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options confi
 		atImportExtensionOrder = append(atImportExtensionOrder, ext)
 	}
 
-	// Generate the condition sets for interpreting the "exports" field
-	esmConditionsDefault := map[string]bool{"default": true}
-	esmConditionsImport := map[string]bool{"import": true}
-	esmConditionsRequire := map[string]bool{"require": true}
-	for _, condition := range options.Conditions {
-		esmConditionsDefault[condition] = true
-	}
-	switch options.Platform {
-	case config.PlatformBrowser:
-		esmConditionsDefault["browser"] = true
-	case config.PlatformNode:
-		esmConditionsDefault["node"] = true
-	}
-	for key := range esmConditionsDefault {
-		esmConditionsImport[key] = true
-		esmConditionsRequire[key] = true
-	}
-
-	return &resolver{
+	res := &resolver{
 		fs:                     fs,
 		log:                    log,
 		options:                options,
 		caches:                 caches,
 		dirCache:               make(map[string]*dirInfo),
+		realPathsInProgress:    make(map[string]bool),
 		atImportExtensionOrder: atImportExtensionOrder,
-		esmConditionsDefault:   esmConditionsDefault,
-		esmConditionsImport:    esmConditionsImport,
-		esmConditionsRequire:   esmConditionsRequire,
+		esmConditions:          makeESMConditionSets(&options, nil),
+		esmConditionsExtra:     make(map[string]*esmConditionSets),
+	}
+
+	if options.AbsImportMapPath != "" {
+		res.importMap = res.loadImportMapFile(options.AbsImportMapPath)
+	} else if options.Platform == config.PlatformDeno {
+		res.importMap = res.loadDenoImportMap()
 	}
+
+	return res
 }
 
 func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.ImportKind) (*ResolveResult, DebugMeta) {
+	return rr.resolve(sourceDir, importPath, kind, rr.esmConditions)
+}
+
+func (rr *resolver) resolve(sourceDir string, importPath string, kind ast.ImportKind, esmConditions *esmConditionSets) (*ResolveResult, DebugMeta) {
 	var debugMeta DebugMeta
+	var browserRemaps []BrowserRemap
 	r := resolverQuery{
-		resolver:  rr,
-		debugMeta: &debugMeta,
-		kind:      kind,
+		resolver:      rr,
+		debugMeta:     &debugMeta,
+		esmConditions: esmConditions,
+		browserRemaps: &browserRemaps,
+		kind:          kind,
 	}
 	if r.log.Level <= logger.LevelDebug {
 		r.debugLogs = &debugLogs{what: fmt.Sprintf(
@@ -279,6 +434,55 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 			importPath, sourceDir, kind.StringForMetafile())}
 	}
 
+	// "import React from 'react'" with "--external-global:react=React"
+	if _, ok := r.options.ExternalGlobals[importPath]; ok && (kind == ast.ImportStmt || kind == ast.ImportRequire || kind == ast.ImportDynamic) {
+		if r.debugLogs != nil {
+			r.debugLogs.addNote("Putting this path in the \"external-global\" namespace")
+		}
+		r.flushDebugLogs(flushDueToSuccess)
+		return &ResolveResult{
+			PathPair: PathPair{Primary: logger.Path{Text: importPath, Namespace: "external-global"}},
+		}, debugMeta
+	}
+
+	// "import { assert } from '@std/assert'" with an import map
+	if r.importMap != nil && !r.isExternal(r.options.ExternalSettings.PreResolve, importPath) {
+		key := importPath
+		if !IsPackagePath(importPath) && !r.fs.IsAbs(importPath) && sourceDir != "" {
+			key = r.fs.Join(sourceDir, importPath)
+		}
+		if mapped, ok := r.importMap.Resolve(sourceDir, key); ok {
+			// Leave bare specifiers for the browser to map at run-time if requested
+			if r.options.ImportMapExternal && IsPackagePath(importPath) {
+				if r.debugLogs != nil {
+					r.debugLogs.addNote(fmt.Sprintf("Marking this path as external because it's in the import map (which maps it to %q)", mapped))
+				}
+				r.flushDebugLogs(flushDueToSuccess)
+				return &ResolveResult{
+					PathPair:   PathPair{Primary: logger.Path{Text: importPath}},
+					IsExternal: true,
+				}, debugMeta
+			}
+
+			if r.debugLogs != nil {
+				r.debugLogs.addNote(fmt.Sprintf("Rewrote %q to %q using the import map", importPath, mapped))
+			}
+			importPath = mapped
+		}
+	}
+
+	// "import 'https://deno.land/std/path/mod.ts'"
+	if r.options.RemoteImports.Enabled && IsRemoteURL(importPath) && kind != ast.ImportURL && kind != ast.ImportNewURL &&
+		!r.isExternal(r.options.ExternalSettings.PreResolve, importPath) {
+		if r.debugLogs != nil {
+			r.debugLogs.addNote("Putting this path in the \"remote\" namespace")
+		}
+		r.flushDebugLogs(flushDueToSuccess)
+		return &ResolveResult{
+			PathPair: PathPair{Primary: logger.Path{Text: importPath, Namespace: "remote"}},
+		}, debugMeta
+	}
+
 	// Certain types of URLs default to being external for convenience
 	if isExplicitlyExternal := r.isExternal(r.options.ExternalSettings.PreResolve, importPath); isExplicitlyExternal ||
 
@@ -309,6 +513,76 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 		}, debugMeta
 	}
 
+	// "import fs from 'fs'"
+	if r.options.Platform == config.PlatformDeno && BuiltInNodeModules[importPath] {
+		if r.debugLogs != nil {
+			r.debugLogs.addNote("Marking this path as implicitly external due to it being a node built-in")
+		}
+
+		// Deno only provides node's built-in modules with the "node:" prefix
+		r.flushDebugLogs(flushDueToSuccess)
+		return &ResolveResult{
+			PathPair:               PathPair{Primary: logger.Path{Text: "node:" + importPath}},
+			IsExternal:             true,
+			PrimarySideEffectsData: &SideEffectsData{}, // Mark this with "sideEffects: false"
+		}, debugMeta
+	}
+
+	// "import fs from 'node:fs'"
+	if r.options.Platform == config.PlatformDeno && strings.HasPrefix(importPath, "node:") {
+		if r.debugLogs != nil {
+			r.debugLogs.addNote("Marking this path as implicitly external due to the \"node:\" prefix")
+		}
+
+		// If this is a known node built-in module, mark it with "sideEffects: false"
+		var sideEffects *SideEffectsData
+		if BuiltInNodeModules[strings.TrimPrefix(importPath, "node:")] {
+			sideEffects = &SideEffectsData{}
+		}
+
+		r.flushDebugLogs(flushDueToSuccess)
+		return &ResolveResult{
+			PathPair:               PathPair{Primary: logger.Path{Text: importPath}},
+			IsExternal:             true,
+			PrimarySideEffectsData: sideEffects,
+		}, debugMeta
+	}
+
+	// "import express from 'npm:express@4'"
+	// "import { join } from 'jsr:@std/path@1'"
+	if r.options.Platform == config.PlatformDeno {
+		if packagePath, ok := parseDenoPackageSpecifier(importPath); ok {
+			if r.debugLogs != nil {
+				r.debugLogs.addNote(fmt.Sprintf("Resolving %q as the package path %q", importPath, packagePath))
+			}
+
+			// Bundle the package if it has been installed into "node_modules".
+			// Otherwise leave it for Deno to download when the bundle is run.
+			if sourceDir != "" {
+				r.mutex.Lock()
+				sourceDirInfo := r.loadTSConfigSettingsForSourceDir(sourceDir)
+				result := r.resolveWithoutSymlinks(sourceDir, sourceDirInfo, packagePath)
+				if result != nil {
+					r.finalizeResolve(result)
+					result.BrowserRemaps = browserRemaps
+				}
+				r.mutex.Unlock()
+				if result != nil {
+					r.flushDebugLogs(flushDueToSuccess)
+					return result, debugMeta
+				}
+			}
+			if r.debugLogs != nil {
+				r.debugLogs.addNote(fmt.Sprintf("Marking this path as external because %q is not installed", packagePath))
+			}
+			r.flushDebugLogs(flushDueToSuccess)
+			return &ResolveResult{
+				PathPair:   PathPair{Primary: logger.Path{Text: importPath}},
+				IsExternal: true,
+			}, debugMeta
+		}
+	}
+
 	// "import fs from 'fs'"
 	if r.options.Platform == config.PlatformNode && BuiltInNodeModules[importPath] {
 		if r.debugLogs != nil {
@@ -406,7 +680,7 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	sourceDirInfo := r.loadModuleSuffixesForSourceDir(sourceDir)
+	sourceDirInfo := r.loadTSConfigSettingsForSourceDir(sourceDir)
 
 	result := r.resolveWithoutSymlinks(sourceDir, sourceDirInfo, importPath)
 	if result == nil {
@@ -419,6 +693,7 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 		if r.debugLogs != nil {
 			r.debugLogs.addNote(fmt.Sprintf("Retrying resolution after removing the suffix %q", importPath[suffix:]))
 		}
+		browserRemaps = browserRemaps[:0]
 		if result2 := r.resolveWithoutSymlinks(sourceDir, sourceDirInfo, importPath[:suffix]); result2 == nil {
 			r.flushDebugLogs(flushDueToFailure)
 			return nil, debugMeta
@@ -433,11 +708,12 @@ func (rr *resolver) Resolve(sourceDir string, importPath string, kind ast.Import
 
 	// If successful, resolve symlinks using the directory info cache
 	r.finalizeResolve(result)
+	result.BrowserRemaps = browserRemaps
 	r.flushDebugLogs(flushDueToSuccess)
 	return result, debugMeta
 }
 
-func (r *resolverQuery) loadModuleSuffixesForSourceDir(sourceDir string) *dirInfo {
+func (r *resolverQuery) loadTSConfigSettingsForSourceDir(sourceDir string) *dirInfo {
 	// Load TypeScript's "moduleSuffixes" setting from the "tsconfig.json" file
 	// enclosing the source directory if present. Otherwise default to a single
 	// empty string, which means "no suffix".
@@ -445,6 +721,9 @@ func (r *resolverQuery) loadModuleSuffixesForSourceDir(sourceDir string) *dirInf
 	sourceDirInfo := r.dirInfoCached(sourceDir)
 	if sourceDirInfo != nil {
 		if tsConfig := sourceDirInfo.enclosingTSConfigJSON; tsConfig != nil {
+			// Also remember the projects that this project references, if any
+			r.tsConfigReferences = tsConfig.References
+
 			if moduleSuffixes := tsConfig.ModuleSuffixes; moduleSuffixes != nil {
 				if r.debugLogs != nil {
 					r.debugLogs.addNote(fmt.Sprintf("Using \"moduleSuffixes\" value of [%s] from %q",
@@ -477,7 +756,7 @@ func (r resolverQuery) isExternal(matchers config.ExternalMatchers, path string)
 }
 
 func (rr *resolver) ResolveAbs(absPath string) *ResolveResult {
-	r := resolverQuery{resolver: rr}
+	r := resolverQuery{resolver: rr, esmConditions: rr.esmConditions}
 	if r.log.Level <= logger.LevelDebug {
 		r.debugLogs = &debugLogs{what: fmt.Sprintf("Getting metadata for absolute path %s", absPath)}
 	}
@@ -492,16 +771,37 @@ func (rr *resolver) ResolveAbs(absPath string) *ResolveResult {
 	return result
 }
 
+func (rr *resolver) PackageName(absPath string) string {
+	r := resolverQuery{resolver: rr, esmConditions: rr.esmConditions}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Skip over "package.json" files without a name such as the ones that only
+	// exist to set the "type" field for a subdirectory
+	for info := r.dirInfoCached(r.fs.Dir(absPath)); info != nil; info = info.parent {
+		if info.packageJSON != nil && info.packageJSON.name != "" {
+			return info.packageJSON.name
+		}
+	}
+	return ""
+}
+
 func (rr *resolver) ProbeResolvePackageAsRelative(sourceDir string, importPath string, kind ast.ImportKind) *ResolveResult {
+	return rr.probeResolvePackageAsRelative(sourceDir, importPath, kind, rr.esmConditions)
+}
+
+func (rr *resolver) probeResolvePackageAsRelative(sourceDir string, importPath string, kind ast.ImportKind, esmConditions *esmConditionSets) *ResolveResult {
 	r := resolverQuery{
-		resolver: rr,
-		kind:     kind,
+		resolver:      rr,
+		esmConditions: esmConditions,
+		kind:          kind,
 	}
 	absPath := r.fs.Join(sourceDir, importPath)
 
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	r.loadModuleSuffixesForSourceDir(sourceDir)
+	r.loadTSConfigSettingsForSourceDir(sourceDir)
 
 	if pair, ok, diffCase := r.loadAsFileOrDirectory(absPath); ok {
 		result := &ResolveResult{PathPair: pair, DifferentCase: diffCase}
@@ -513,6 +813,90 @@ func (rr *resolver) ProbeResolvePackageAsRelative(sourceDir string, importPath s
 	return nil
 }
 
+func (rr *resolver) Glob(sourceDir string, pattern string) []string {
+	r := resolverQuery{resolver: rr, esmConditions: rr.esmConditions}
+	if r.log.Level <= logger.LevelDebug {
+		r.debugLogs = &debugLogs{what: fmt.Sprintf("Expanding glob pattern %q in directory %q", pattern, sourceDir)}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	seen := make(map[string]bool)
+	var results []string
+	for _, expanded := range helpers.ExpandGlobBraces(pattern) {
+		r.globDir(sourceDir, "", strings.Split(expanded, "/"), func(importPath string) {
+			if !seen[importPath] {
+				seen[importPath] = true
+				results = append(results, importPath)
+			}
+		})
+	}
+	sort.Strings(results)
+
+	if r.debugLogs != nil {
+		r.debugLogs.addNote(fmt.Sprintf("Found %d matching files", len(results)))
+	}
+	r.flushDebugLogs(flushDueToSuccess)
+	return results
+}
+
+// Directories are read using "ReadDirectory" so that this works with any file
+// system and so that watch mode notices when matching files are added. Hidden
+// entries are only matched by pattern segments that start with a ".", and
+// "node_modules" directories are only matched by pattern segments that name
+// them explicitly. Symlinks to directories aren't followed by
+// "**" to avoid infinite loops.
+func (r resolverQuery) globDir(dir string, importPath string, segments []string, visit func(string)) {
+	segment := segments[0]
+	isLast := len(segments) == 1
+
+	// Non-glob segments don't need to look at every entry
+	if !helpers.IsGlobPattern(segment) {
+		if !isLast {
+			r.globDir(r.fs.Join(dir, segment), importPath+segment+"/", segments[1:], visit)
+		} else if entries, err, _ := r.fs.ReadDirectory(dir); err == nil {
+			if entry, _ := entries.Get(segment); entry != nil && entry.Kind(r.fs) == fs.FileEntry {
+				visit(importPath + segment)
+			}
+		}
+		return
+	}
+
+	// A "**" segment matches zero or more directories
+	if segment == "**" {
+		if isLast {
+			return
+		}
+		r.globDir(dir, importPath, segments[1:], visit)
+	}
+
+	entries, err, _ := r.fs.ReadDirectory(dir)
+	if err != nil {
+		return
+	}
+	for _, name := range entries.SortedKeys() {
+		if (strings.HasPrefix(name, ".") && !strings.HasPrefix(segment, ".")) || name == "node_modules" {
+			continue
+		}
+		entry, _ := entries.Get(name)
+		kind := entry.Kind(r.fs)
+		if segment == "**" {
+			if kind == fs.DirEntry && entry.Symlink(r.fs) == "" {
+				r.globDir(r.fs.Join(dir, name), importPath+name+"/", segments, visit)
+			}
+		} else if helpers.GlobMatchSegment(segment, name) {
+			if isLast {
+				if kind == fs.FileEntry {
+					visit(importPath + name)
+				}
+			} else if kind == fs.DirEntry {
+				r.globDir(r.fs.Join(dir, name), importPath+name+"/", segments[1:], visit)
+			}
+		}
+	}
+}
+
 type debugLogs struct {
 	what   string
 	indent string
@@ -627,7 +1011,10 @@ func (r resolverQuery) finalizeResolve(result *ResolveResult) {
 					} else {
 						result.JSXFactory = dirInfo.enclosingTSConfigJSON.JSXFactory
 						result.JSXFragment = dirInfo.enclosingTSConfigJSON.JSXFragmentFactory
+						result.JSXImportSource = dirInfo.enclosingTSConfigJSON.JSXImportSource
+						result.JSX = dirInfo.enclosingTSConfigJSON.JSX
 						result.UseDefineForClassFieldsTS = dirInfo.enclosingTSConfigJSON.UseDefineForClassFields
+						result.EmitDecoratorMetadataTS = dirInfo.enclosingTSConfigJSON.EmitDecoratorMetadata
 						result.UnusedImportFlagsTS = config.UnusedImportFlagsFromTsconfigValues(
 							dirInfo.enclosingTSConfigJSON.PreserveImportsNotUsedAsValues,
 							dirInfo.enclosingTSConfigJSON.PreserveValueImports,
@@ -661,6 +1048,7 @@ func (r resolverQuery) finalizeResolve(result *ResolveResult) {
 					if entry, _ := dirInfo.entries.Get(base); entry != nil {
 						if symlink := entry.Symlink(r.fs); symlink != "" {
 							// Is this entry itself a symlink?
+							symlink = r.realPathOfSymlinkTarget(symlink)
 							if r.debugLogs != nil {
 								r.debugLogs.addNote(fmt.Sprintf("Resolved symlink %q to %q", path.Text, symlink))
 							}
@@ -756,6 +1144,9 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, sourceDirInfo *d
 			if absolute, ok, diffCase := r.loadAsFileOrDirectory(absPath); ok {
 				checkPackage = false
 				result = ResolveResult{PathPair: absolute, DifferentCase: diffCase}
+			} else if absolute, ok, diffCase := r.loadAsFileOrDirectoryInRootDirs(sourceDirInfo, absPath); ok {
+				checkPackage = false
+				result = ResolveResult{PathPair: absolute, DifferentCase: diffCase}
 			} else if !checkPackage {
 				return nil
 			}
@@ -820,7 +1211,16 @@ func (r *resolver) PrettyPath(path logger.Path) string {
 		// operating system it was run. Replace Windows backward slashes with standard
 		// forward slashes.
 		path.Text = strings.ReplaceAll(path.Text, "\\", "/")
+	} else if path.Namespace == "remote" {
+		// Remote paths are URLs, which already say where they come from
 	} else if path.Namespace != "" {
+		// Plugins sometimes use absolute paths in their own namespaces. These
+		// would make the output depend on where the project is located.
+		if r.options.Deterministic && r.fs.IsAbs(path.Text) {
+			if rel, ok := r.fs.Rel(r.fs.Cwd(), path.Text); ok {
+				path.Text = strings.ReplaceAll(rel, "\\", "/")
+			}
+		}
 		path.Text = fmt.Sprintf("%s:%s", path.Namespace, path.Text)
 	}
 
@@ -828,6 +1228,12 @@ func (r *resolver) PrettyPath(path logger.Path) string {
 		path.Text = "(disabled):" + path.Text
 	}
 
+	// Files resolved using additional conditions are separate modules, so
+	// they need a separate name too
+	if path.Conditions != "" {
+		return fmt.Sprintf("%s%s (%s)", path.Text, path.IgnoredSuffix, path.Conditions)
+	}
+
 	return path.Text + path.IgnoredSuffix
 }
 
@@ -883,6 +1289,26 @@ func (r resolverQuery) dirInfoCached(path string) *dirInfo {
 	return cached
 }
 
+// The target of a symlink may itself be inside a symlinked directory. For
+// example, pnpm links packages to each other through its store, and the store
+// may be reached through another link. The directory part of the target must
+// then be resolved too. Otherwise the same file could be reached through two
+// different "real" paths and would be bundled twice. This uses the directory
+// info cache so each directory's real path is only computed once.
+func (r resolverQuery) realPathOfSymlinkTarget(target string) string {
+	dir := r.fs.Dir(target)
+	if dir == target || r.realPathsInProgress[dir] {
+		return target
+	}
+	r.realPathsInProgress[dir] = true
+	dirInfo := r.dirInfoCached(dir)
+	delete(r.realPathsInProgress, dir)
+	if dirInfo != nil && dirInfo.absRealPath != "" {
+		return r.fs.Join(dirInfo.absRealPath, r.fs.Base(target))
+	}
+	return target
+}
+
 var errParseErrorImportCycle = errors.New("(import cycle)")
 var errParseErrorAlreadyLogged = errors.New("(error already logged)")
 
@@ -1002,6 +1428,34 @@ func (r resolverQuery) parseTSConfig(file string, visited map[string]bool) (*TSC
 		result.BaseURLForPaths = r.fs.Join(fileDir, result.BaseURLForPaths)
 	}
 
+	if result.RootDir != nil && !r.fs.IsAbs(*result.RootDir) {
+		*result.RootDir = r.fs.Join(fileDir, *result.RootDir)
+	}
+
+	if result.OutDir != nil && !r.fs.IsAbs(*result.OutDir) {
+		*result.OutDir = r.fs.Join(fileDir, *result.OutDir)
+	}
+
+	for i, rootDir := range result.RootDirs {
+		if !r.fs.IsAbs(rootDir) {
+			result.RootDirs[i] = r.fs.Join(fileDir, rootDir)
+		}
+	}
+
+	// A project reference can either be the path to a "tsconfig.json" file or
+	// the path to a directory containing a file called "tsconfig.json"
+	if result.References != nil {
+		for i, path := range result.References.Paths {
+			if !r.fs.IsAbs(path.Text) {
+				path.Text = r.fs.Join(fileDir, path.Text)
+			}
+			if !strings.HasSuffix(path.Text, ".json") {
+				path.Text = r.fs.Join(path.Text, "tsconfig.json")
+			}
+			result.References.Paths[i] = path
+		}
+	}
+
 	// Now that we have parsed the entire "tsconfig.json" file, filter out any
 	// paths that are invalid due to being a package-style path without a base
 	// URL specified. This must be done here instead of when we're parsing the
@@ -1091,6 +1545,7 @@ func (r resolverQuery) dirInfoUncached(path string) *dirInfo {
 		if !r.options.PreserveSymlinks {
 			if entry, _ := parentInfo.entries.Get(base); entry != nil {
 				if symlink := entry.Symlink(r.fs); symlink != "" {
+					symlink = r.realPathOfSymlinkTarget(symlink)
 					if r.debugLogs != nil {
 						r.debugLogs.addNote(fmt.Sprintf("Resolved symlink %q to %q", path, symlink))
 					}
@@ -1122,7 +1577,7 @@ func (r resolverQuery) dirInfoUncached(path string) *dirInfo {
 	// Record if this directory has a tsconfig.json or jsconfig.json file
 	{
 		var tsConfigPath string
-		if forceTsConfig := r.options.TsConfigOverride; forceTsConfig == "" {
+		if forceTsConfig := r.options.TsConfigOverride; forceTsConfig == "" || (r.options.TsConfigNested && r.isNestedInsideTSConfigOverride(path)) {
 			if entry, _ := entries.Get("tsconfig.json"); entry != nil && entry.Kind(r.fs) == fs.FileEntry {
 				tsConfigPath = r.fs.Join(path, "tsconfig.json")
 			} else if entry, _ := entries.Get("jsconfig.json"); entry != nil && entry.Kind(r.fs) == fs.FileEntry {
@@ -1135,7 +1590,9 @@ func (r resolverQuery) dirInfoUncached(path string) *dirInfo {
 		if tsConfigPath != "" {
 			var err error
 			info.enclosingTSConfigJSON, err = r.parseTSConfig(tsConfigPath, make(map[string]bool))
-			if err != nil {
+			if err == nil {
+				r.loadTSConfigReferences(info.enclosingTSConfigJSON)
+			} else {
 				if err == syscall.ENOENT {
 					r.log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot find tsconfig file %q",
 						r.PrettyPath(logger.Path{Text: tsConfigPath, Namespace: "file"})))
@@ -1151,6 +1608,44 @@ func (r resolverQuery) dirInfoUncached(path string) *dirInfo {
 	return info
 }
 
+// Referenced projects are only loaded one level deep because TypeScript
+// doesn't follow the references of a referenced project either. This means
+// there is no need to worry about cycles here.
+func (r resolverQuery) loadTSConfigReferences(tsConfig *TSConfigJSON) {
+	refs := tsConfig.References
+	if refs == nil {
+		return
+	}
+	tracker := logger.MakeLineColumnTracker(&refs.Source)
+	for _, path := range refs.Paths {
+		project, err := r.parseTSConfig(path.Text, make(map[string]bool))
+		if err == nil {
+			refs.Projects = append(refs.Projects, project)
+		} else if err == syscall.ENOENT {
+			// Suppress warnings about missing referenced projects inside "node_modules"
+			if !helpers.IsInsideNodeModules(tsConfig.AbsPath) {
+				r.log.AddID(logger.MsgID_TsconfigJSON_Missing, logger.Warning, &tracker, refs.Source.RangeOfString(path.Loc),
+					fmt.Sprintf("Cannot find referenced project %q", r.PrettyPath(logger.Path{Text: path.Text, Namespace: "file"})))
+			}
+		} else if err != errParseErrorAlreadyLogged {
+			r.log.AddError(&tracker, refs.Source.RangeOfString(path.Loc),
+				fmt.Sprintf("Cannot read file %q: %s",
+					r.PrettyPath(logger.Path{Text: path.Text, Namespace: "file"}), err.Error()))
+		}
+	}
+}
+
+// When nested "tsconfig.json" files are enabled, directories strictly inside
+// the directory containing the "tsconfig.json" override can have their own
+// "tsconfig.json" files (the way a monorepo has one per package). Directories
+// at or above the override's directory still use the override.
+func (r resolverQuery) isNestedInsideTSConfigOverride(path string) bool {
+	if rel, ok := r.fs.Rel(r.fs.Dir(r.options.TsConfigOverride), path); ok {
+		return rel != "." && isRelInsideDir(rel)
+	}
+	return false
+}
+
 // https://devblogs.microsoft.com/typescript/announcing-typescript-4-7-beta/#resolution-customization-with-modulesuffixes
 // "Note that the empty string '' in moduleSuffixes is necessary for TypeScript to
 // also look-up ./foo.ts. In a sense, the default value for moduleSuffixes is ['']."
@@ -1429,23 +1924,101 @@ func (r resolverQuery) loadAsFileOrDirectory(path string) (PathPair, bool, *fs.D
 		defer r.debugLogs.decreaseIndent()
 	}
 	dirInfo := r.dirInfoCached(path)
-	if dirInfo == nil {
+	if dirInfo != nil {
+		// Try using the main field(s) from "package.json"
+		if absolute, ok, diffCase := r.loadAsMainField(dirInfo, path, extensionOrder); ok {
+			return absolute, true, diffCase
+		}
+
+		// Look for an "index" file with known extensions
+		if absolute, ok, diffCase := r.loadAsIndexWithBrowserRemapping(dirInfo, path, extensionOrder); ok {
+			return absolute, true, diffCase
+		}
+	}
+
+	// Is this an output file from a referenced TypeScript project?
+	return r.loadAsReferencedProjectSource(path)
+}
+
+// With TypeScript project references, one project can import another
+// project's output files (i.e. the files in "outDir") even if that other
+// project hasn't been built yet. The TypeScript compiler handles this by
+// substituting the corresponding source file from "rootDir" instead. We do
+// the same thing here, which lets the bundle be built from source.
+func (r resolverQuery) loadAsReferencedProjectSource(path string) (PathPair, bool, *fs.DifferentCase) {
+	refs := r.tsConfigReferences
+	if refs == nil {
 		return PathPair{}, false, nil
 	}
 
-	// Try using the main field(s) from "package.json"
-	if absolute, ok, diffCase := r.loadAsMainField(dirInfo, path, extensionOrder); ok {
-		return absolute, true, diffCase
+	// Only substitute once to avoid an infinite loop if one project's source
+	// directory is another project's output directory
+	r.tsConfigReferences = nil
+
+	for _, project := range refs.Projects {
+		if project.OutDir == nil {
+			continue
+		}
+		rootDir := r.fs.Dir(project.AbsPath)
+		if project.RootDir != nil {
+			rootDir = *project.RootDir
+		}
+
+		if rel, ok := r.fs.Rel(*project.OutDir, path); ok && isRelInsideDir(rel) {
+			sourcePath := r.fs.Join(rootDir, rel)
+			if r.debugLogs != nil {
+				r.debugLogs.addNote(fmt.Sprintf("Substituting %q for %q from the project %q", sourcePath, path, project.AbsPath))
+			}
+			if absolute, ok, diffCase := r.loadAsFileOrDirectory(sourcePath); ok {
+				return absolute, true, diffCase
+			}
+		}
 	}
 
-	// Look for an "index" file with known extensions
-	if absolute, ok, diffCase := r.loadAsIndexWithBrowserRemapping(dirInfo, path, extensionOrder); ok {
-		return absolute, true, diffCase
+	return PathPair{}, false, nil
+}
+
+// TypeScript's "rootDirs" setting lets a relative import in one of those
+// directories refer to a file in any of the other directories, as if they
+// were all merged together. This is only done for relative imports.
+func (r resolverQuery) loadAsFileOrDirectoryInRootDirs(sourceDirInfo *dirInfo, path string) (PathPair, bool, *fs.DifferentCase) {
+	if sourceDirInfo == nil || sourceDirInfo.enclosingTSConfigJSON == nil {
+		return PathPair{}, false, nil
+	}
+	rootDirs := sourceDirInfo.enclosingTSConfigJSON.RootDirs
+
+	// Find the longest root directory containing the path, which is what TypeScript does
+	var rel string
+	longestMatch := -1
+	for i, rootDir := range rootDirs {
+		if relPath, ok := r.fs.Rel(rootDir, path); ok && isRelInsideDir(relPath) && (longestMatch == -1 || len(rootDir) > len(rootDirs[longestMatch])) {
+			rel = relPath
+			longestMatch = i
+		}
+	}
+	if longestMatch == -1 {
+		return PathPair{}, false, nil
+	}
+
+	for i, rootDir := range rootDirs {
+		if i != longestMatch {
+			otherPath := r.fs.Join(rootDir, rel)
+			if r.debugLogs != nil {
+				r.debugLogs.addNote(fmt.Sprintf("Trying %q from \"rootDirs\" in %q", otherPath, sourceDirInfo.enclosingTSConfigJSON.AbsPath))
+			}
+			if absolute, ok, diffCase := r.loadAsFileOrDirectory(otherPath); ok {
+				return absolute, true, diffCase
+			}
+		}
 	}
 
 	return PathPair{}, false, nil
 }
 
+func isRelInsideDir(rel string) bool {
+	return rel != ".." && !strings.HasPrefix(rel, "../") && !strings.HasPrefix(rel, "..\\")
+}
+
 func (r resolverQuery) loadAsMainField(dirInfo *dirInfo, path string, extensionOrder []string) (PathPair, bool, *fs.DifferentCase) {
 	if dirInfo.packageJSON == nil {
 		return PathPair{}, false, nil
@@ -1743,15 +2316,7 @@ func (r resolverQuery) loadPackageImports(importPath string, dirInfoPackageJSON
 		return PathPair{}, false, nil
 	}
 
-	// The condition set is determined by the kind of import
-	conditions := r.esmConditionsDefault
-	switch r.kind {
-	case ast.ImportStmt, ast.ImportDynamic:
-		conditions = r.esmConditionsImport
-	case ast.ImportRequire, ast.ImportRequireResolve:
-		conditions = r.esmConditionsRequire
-	}
-
+	conditions := r.esmConditionsForKind()
 	resolvedPath, status, debug := r.esmPackageImportsResolve(importPath, packageJSON.importsMap.root, conditions)
 	resolvedPath, status, debug = r.esmHandlePostConditions(resolvedPath, status, debug)
 
@@ -1784,23 +2349,7 @@ func (r resolverQuery) esmResolveAlgorithm(esmPackageName string, esmPackageSubp
 		defer r.debugLogs.decreaseIndent()
 	}
 
-	// The condition set is determined by the kind of import
-	conditions := r.esmConditionsDefault
-	switch r.kind {
-	case ast.ImportStmt, ast.ImportDynamic:
-		conditions = r.esmConditionsImport
-	case ast.ImportRequire, ast.ImportRequireResolve:
-		conditions = r.esmConditionsRequire
-	case ast.ImportEntryPoint:
-		// Treat entry points as imports instead of requires for consistency with
-		// Webpack and Rollup. More information:
-		//
-		// * https://github.com/evanw/esbuild/issues/1956
-		// * https://github.com/nodejs/node/issues/41686
-		// * https://github.com/evanw/entry-point-resolve-test
-		//
-		conditions = r.esmConditionsImport
-	}
+	conditions := r.esmConditionsForKind()
 
 	// Resolve against the path "/", then join it with the absolute
 	// directory path. This is done because ESM package resolution uses
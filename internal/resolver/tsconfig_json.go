@@ -35,15 +35,34 @@ type TSConfigJSON struct {
 	// "baseUrl" value in the "tsconfig.json" file.
 	Paths *TSConfigPaths
 
+	// The absolute paths of "compilerOptions.rootDir" and "compilerOptions.outDir".
+	// These are used to map an import of one of this project's output files
+	// back to the corresponding source file when another project lists this
+	// project in "references".
+	RootDir *string
+	OutDir  *string
+
+	// The absolute paths of "compilerOptions.rootDirs". Relative imports are
+	// resolved as if the contents of all of these directories were merged
+	// together into a single virtual directory.
+	RootDirs []string
+
+	// The values of the top-level "references" property. Unlike everything
+	// else, these are not inherited from another file via "extends".
+	References *TSConfigReferences
+
 	TSTarget                       *config.TSTarget
 	TSStrict                       *config.TSAlwaysStrict
 	TSAlwaysStrict                 *config.TSAlwaysStrict
 	JSXFactory                     []string
 	JSXFragmentFactory             []string
+	JSXImportSource                *string
+	JSX                            config.TSConfigJSX
 	ModuleSuffixes                 []string
 	UseDefineForClassFields        config.MaybeBool
 	PreserveImportsNotUsedAsValues bool
 	PreserveValueImports           bool
+	EmitDecoratorMetadata          bool
 }
 
 type TSConfigPath struct {
@@ -51,6 +70,17 @@ type TSConfigPath struct {
 	Loc  logger.Loc
 }
 
+type TSConfigReferences struct {
+	// The absolute path of each referenced "tsconfig.json" file
+	Paths []TSConfigPath
+
+	// The parsed contents of each file in "Paths" that could be loaded. This
+	// is filled in by the resolver after "Paths" has been made absolute.
+	Projects []*TSConfigJSON
+
+	Source logger.Source
+}
+
 type TSConfigPaths struct {
 	Map map[string][]TSConfigPath
 
@@ -91,6 +121,7 @@ func ParseTSConfigJSON(
 			if value, ok := getString(valueJSON); ok {
 				if base := extends(value, source.RangeOfString(valueJSON.Loc)); base != nil {
 					result = *base
+					result.References = nil
 				}
 			}
 		}
@@ -119,6 +150,27 @@ func ParseTSConfigJSON(
 			}
 		}
 
+		// Parse "jsx"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "jsx"); ok {
+			if value, ok := getString(valueJSON); ok {
+				switch strings.ToLower(value) {
+				case "react":
+					result.JSX = config.TSConfigJSXReact
+				case "react-jsx":
+					result.JSX = config.TSConfigJSXReactJSX
+				case "react-jsxdev":
+					result.JSX = config.TSConfigJSXReactJSXDev
+				}
+			}
+		}
+
+		// Parse "jsxImportSource"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "jsxImportSource"); ok {
+			if value, ok := getString(valueJSON); ok {
+				result.JSXImportSource = &value
+			}
+		}
+
 		// Parse "moduleSuffixes"
 		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "moduleSuffixes"); ok {
 			if value, ok := valueJSON.Data.(*js_ast.EArray); ok {
@@ -146,6 +198,13 @@ func ParseTSConfigJSON(
 			}
 		}
 
+		// Parse "emitDecoratorMetadata"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "emitDecoratorMetadata"); ok {
+			if value, ok := getBool(valueJSON); ok {
+				result.EmitDecoratorMetadata = value
+			}
+		}
+
 		// Parse "target"
 		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "target"); ok {
 			if value, ok := getString(valueJSON); ok {
@@ -247,6 +306,32 @@ func ParseTSConfigJSON(
 			}
 		}
 
+		// Parse "rootDir"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "rootDir"); ok {
+			if value, ok := getString(valueJSON); ok {
+				result.RootDir = &value
+			}
+		}
+
+		// Parse "outDir"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "outDir"); ok {
+			if value, ok := getString(valueJSON); ok {
+				result.OutDir = &value
+			}
+		}
+
+		// Parse "rootDirs"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "rootDirs"); ok {
+			if value, ok := valueJSON.Data.(*js_ast.EArray); ok {
+				result.RootDirs = make([]string, 0, len(value.Items))
+				for _, item := range value.Items {
+					if str, ok := item.Data.(*js_ast.EString); ok {
+						result.RootDirs = append(result.RootDirs, helpers.UTF16ToString(str.Value))
+					}
+				}
+			}
+		}
+
 		// Parse "paths"
 		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "paths"); ok {
 			if paths, ok := valueJSON.Data.(*js_ast.EObject); ok {
@@ -302,6 +387,23 @@ func ParseTSConfigJSON(
 		}
 	}
 
+	// Parse "references"
+	if valueJSON, _, ok := getProperty(json, "references"); ok {
+		if value, ok := valueJSON.Data.(*js_ast.EArray); ok {
+			result.References = &TSConfigReferences{Source: source}
+			for _, item := range value.Items {
+				if pathJSON, _, ok := getProperty(item, "path"); ok {
+					if path, ok := getString(pathJSON); ok {
+						result.References.Paths = append(result.References.Paths, TSConfigPath{Text: path, Loc: pathJSON.Loc})
+						continue
+					}
+				}
+				log.AddID(logger.MsgID_TsconfigJSON_InvalidReferences, logger.Warning, &tracker, source.RangeOfString(item.Loc),
+					"Each project reference must be an object with a \"path\" property")
+			}
+		}
+	}
+
 	return &result
 }
 
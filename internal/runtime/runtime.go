@@ -132,6 +132,62 @@ func code(isES6 bool) string {
 				throw new Error('Dynamic require of "' + x + '" is not supported')
 			})
 
+		// This is used instead of "import.meta.url" in "new URL(path, import.meta.url)"
+		// when "import.meta" isn't available. It must be computed when the code is
+		// first evaluated because "document.currentScript" is only set at that time.
+		export var __importMetaURL = /* @__PURE__ */ (() =>
+			typeof document !== 'undefined' ? document.currentScript && document.currentScript.src || document.baseURI :
+			typeof __filename !== 'undefined' ? __require('url').pathToFileURL(__filename).href :
+			typeof location !== 'undefined' ? location.href : void 0
+		)()
+
+		// For the "wasm-file" loader. Streaming compilation requires the correct
+		// MIME type, so fall back to downloading the whole file first otherwise.
+		export var __instantiateWasmStreaming = (url, imports) => fetch(url).then(response =>
+			typeof WebAssembly.instantiateStreaming === 'function' &&
+				response.headers.get('Content-Type') === 'application/wasm'
+				? WebAssembly.instantiateStreaming(response, imports)
+				: response.arrayBuffer().then(bytes => WebAssembly.instantiate(bytes, imports)))
+
+		// For the "import" option of "import.meta.glob"
+		export var __importGlobName = (promise, name) => promise.then(mod => mod[name])
+
+		// For "require()" calls with a non-constant argument when using the
+		// "context" policy for dynamic requires. Import paths that aren't in the
+		// map are also matched against the end of each key (for absolute paths
+		// built from "__dirname") before falling back to "require()" at run-time.
+		export var __requireContext = modules => path => {
+			var key = (path + '').replace(/\\/g, '/'), suffix
+			if (__hasOwnProp.call(modules, key))
+				return modules[key]()
+			for (var other in modules)
+				if ((suffix = other.replace(/^(\.\.?\/)+/, '/')) && key.slice(-suffix.length) === suffix)
+					return modules[other]()
+			return __require(path)
+		}
+
+		// For modules in the shared scope ("--shared:"). Every bundle registers
+		// its own copy of the module and then uses the highest version in the scope
+		// that is compatible with its copy, following the rules for "^" version
+		// ranges. The copy is only evaluated if it ends up being used.
+		var __semver = version => version.split('.').map(part => parseInt(part, 10) || 0)
+		var __semverCompare = (a, b, n) => {
+			for (var i = 0; i < n; i++)
+				if ((a[i] || 0) !== (b[i] || 0))
+					return (a[i] || 0) - (b[i] || 0)
+			return 0
+		}
+		export var __shared = (scope, name, version, load) => {
+			var versions = scope[name] || (scope[name] = {}), required = __semver(version), best = required, result = version
+			versions[version] || (versions[version] = { get: load })
+			for (var other in versions) {
+				var parts = __semver(other), fixed = required[0] ? 1 : required[1] ? 2 : 3
+				if (!__semverCompare(parts, required, fixed) && __semverCompare(parts, best, 3) > 0)
+					best = parts, result = other
+			}
+			return versions[result].get()
+		}
+
 		// For object rest patterns
 		export var __restKey = key => typeof key === 'symbol' ? key : key + ''
 		export var __objRest = (source, exclude) => {
@@ -254,6 +310,10 @@ func code(isES6 bool) string {
 			return result
 		}
 		export var __decorateParam = (index, decorator) => (target, key) => decorator(target, key, index)
+		export var __metadata = (key, value) => {
+			if (typeof Reflect === 'object' && typeof Reflect.metadata === 'function')
+				return Reflect.metadata(key, value)
+		}
 
 		// For class members
 		export var __publicField = (obj, key, value) => {
@@ -21,6 +21,11 @@ type SourceMap struct {
 	Sources        []string
 	SourcesContent []SourceContent
 	Mappings       []Mapping
+
+	// This is parallel to "Sources" and comes from the "x_google_ignoreList"
+	// extension. Debuggers hide ignored sources (e.g. third-party code), so this
+	// needs to be preserved when this source map is composed with another one.
+	IgnoreList []bool
 }
 
 type SourceContent struct {
@@ -65,6 +70,65 @@ func (sm *SourceMap) Find(line int32, column int32) *Mapping {
 	return nil
 }
 
+// This composes a source map with the source maps of its sources. The array
+// "nested" is parallel to "Sources" and each non-nil entry maps that source
+// back to even earlier sources. Mappings into a nested source are remapped
+// through its source map, and are dropped if there is no corresponding
+// mapping. The resulting source map maps directly to the earliest sources.
+func (sm *SourceMap) Compose(nested []*SourceMap) *SourceMap {
+	result := &SourceMap{}
+	offsets := make([]int32, len(sm.Sources))
+	hasIgnoreList := sm.IgnoreList != nil
+
+	for i, source := range sm.Sources {
+		offsets[i] = int32(len(result.Sources))
+		if inner := nested[i]; inner != nil {
+			result.Sources = append(result.Sources, inner.Sources...)
+			for j := range inner.Sources {
+				var content SourceContent
+				if j < len(inner.SourcesContent) {
+					content = inner.SourcesContent[j]
+				}
+				result.SourcesContent = append(result.SourcesContent, content)
+				result.IgnoreList = append(result.IgnoreList, inner.IgnoreList != nil && inner.IgnoreList[j])
+			}
+			if inner.IgnoreList != nil {
+				hasIgnoreList = true
+			}
+		} else {
+			var content SourceContent
+			if i < len(sm.SourcesContent) {
+				content = sm.SourcesContent[i]
+			}
+			result.Sources = append(result.Sources, source)
+			result.SourcesContent = append(result.SourcesContent, content)
+			result.IgnoreList = append(result.IgnoreList, sm.IgnoreList != nil && sm.IgnoreList[i])
+		}
+	}
+
+	if !hasIgnoreList {
+		result.IgnoreList = nil
+	}
+
+	result.Mappings = make([]Mapping, 0, len(sm.Mappings))
+	for _, mapping := range sm.Mappings {
+		offset := offsets[mapping.SourceIndex]
+		if inner := nested[mapping.SourceIndex]; inner != nil {
+			original := inner.Find(mapping.OriginalLine, mapping.OriginalColumn)
+			if original == nil {
+				continue
+			}
+			mapping.SourceIndex = original.SourceIndex
+			mapping.OriginalLine = original.OriginalLine
+			mapping.OriginalColumn = original.OriginalColumn
+		}
+		mapping.SourceIndex += offset
+		result.Mappings = append(result.Mappings, mapping)
+	}
+
+	return result
+}
+
 var base64 = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/")
 
 // A single base 64 digit can contain 6 bits of data. For the base 64 variable
@@ -0,0 +1,181 @@
+package wasm_parser
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This is not a WebAssembly validator. It only reads the names in the import
+// and export sections, which is all that's needed to link a WebAssembly module
+// into the module graph. Everything else is skipped over without being parsed.
+
+type ExternalKind uint8
+
+const (
+	ExternalFunction ExternalKind = iota
+	ExternalTable
+	ExternalMemory
+	ExternalGlobal
+	ExternalTag
+)
+
+type Import struct {
+	Module string
+	Name   string
+	Kind   ExternalKind
+}
+
+type Export struct {
+	Name string
+	Kind ExternalKind
+}
+
+type Module struct {
+	Imports []Import
+	Exports []Export
+}
+
+const (
+	sectionImport = 2
+	sectionExport = 7
+)
+
+type parser struct {
+	contents string
+	pos      int
+	failed   bool
+}
+
+func Parse(log logger.Log, source logger.Source) (Module, bool) {
+	var module Module
+	contents := source.Contents
+
+	if len(contents) < 8 || contents[:4] != "\x00asm" {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("The file %q is not a WebAssembly module", source.PrettyPath))
+		return module, false
+	}
+	if contents[4:8] != "\x01\x00\x00\x00" {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("The WebAssembly module %q uses an unsupported binary format version", source.PrettyPath))
+		return module, false
+	}
+
+	p := parser{contents: contents, pos: 8}
+	for !p.failed && p.pos < len(contents) {
+		id := p.readByte()
+		size := int(p.readU32())
+		if p.failed || size > len(contents)-p.pos {
+			p.failed = true
+			break
+		}
+		end := p.pos + size
+		section := parser{contents: contents[:end], pos: p.pos}
+
+		switch id {
+		case sectionImport:
+			count := section.readU32()
+			for i := uint32(0); i < count && !section.failed; i++ {
+				var imp Import
+				imp.Module = section.readName()
+				imp.Name = section.readName()
+				imp.Kind = ExternalKind(section.readByte())
+				switch imp.Kind {
+				case ExternalFunction:
+					section.readU32() // Type index
+				case ExternalTable:
+					section.readByte() // Reference type
+					section.readLimits()
+				case ExternalMemory:
+					section.readLimits()
+				case ExternalGlobal:
+					section.readByte() // Value type
+					section.readByte() // Mutability
+				case ExternalTag:
+					section.readByte() // Attribute
+					section.readU32()  // Type index
+				default:
+					section.failed = true
+				}
+				module.Imports = append(module.Imports, imp)
+			}
+
+		case sectionExport:
+			count := section.readU32()
+			for i := uint32(0); i < count && !section.failed; i++ {
+				var exp Export
+				exp.Name = section.readName()
+				exp.Kind = ExternalKind(section.readByte())
+				section.readU32() // Index
+				module.Exports = append(module.Exports, exp)
+			}
+		}
+
+		if section.failed {
+			p.failed = true
+		}
+		p.pos = end
+	}
+
+	if p.failed {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("The WebAssembly module %q is malformed", source.PrettyPath))
+		return Module{}, false
+	}
+	return module, true
+}
+
+func (p *parser) readByte() byte {
+	if p.pos >= len(p.contents) {
+		p.failed = true
+		return 0
+	}
+	c := p.contents[p.pos]
+	p.pos++
+	return c
+}
+
+// Integers are stored in the LEB128 format. This reads up to 64 bits even
+// though most values are 32 bits because memory limits can be 64 bits.
+func (p *parser) readLEB() uint64 {
+	var value uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		c := p.readByte()
+		value |= uint64(c&0x7F) << shift
+		if c&0x80 == 0 {
+			return value
+		}
+	}
+	p.failed = true
+	return 0
+}
+
+func (p *parser) readU32() uint32 {
+	value := p.readLEB()
+	if value > 0xFFFFFFFF {
+		p.failed = true
+		return 0
+	}
+	return uint32(value)
+}
+
+func (p *parser) readName() string {
+	length := int(p.readU32())
+	if p.failed || length > len(p.contents)-p.pos {
+		p.failed = true
+		return ""
+	}
+	name := p.contents[p.pos : p.pos+length]
+	p.pos += length
+	if !utf8.ValidString(name) {
+		p.failed = true
+	}
+	return name
+}
+
+func (p *parser) readLimits() {
+	flags := p.readByte()
+	p.readLEB() // Minimum
+	if (flags & 1) != 0 {
+		p.readLEB() // Maximum
+	}
+}
@@ -0,0 +1,76 @@
+package wasm_parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func expectModule(t *testing.T, name string, contents string, expected string) {
+	t.Helper()
+	t.Run(name, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		module, _ := Parse(log, test.SourceForTest(contents))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		for _, imp := range module.Imports {
+			text += fmt.Sprintf("import %d %s %s\n", imp.Kind, imp.Module, imp.Name)
+		}
+		for _, exp := range module.Exports {
+			text += fmt.Sprintf("export %d %s\n", exp.Kind, exp.Name)
+		}
+		test.AssertEqualWithDiff(t, text, expected)
+	})
+}
+
+const header = "\x00asm\x01\x00\x00\x00"
+
+// Sections in these tests are always less than 128 bytes long
+func section(id byte, payload string) string {
+	return string([]byte{id, byte(len(payload))}) + payload
+}
+
+func TestEmpty(t *testing.T) {
+	expectModule(t, "empty", header, "")
+}
+
+func TestImportsAndExports(t *testing.T) {
+	// A type section, which is skipped, followed by an import section
+	types := section(1, "\x01\x60\x00\x01\x7F")
+	imports := section(2, "\x05"+
+		"\x03env\x03log\x00\x00"+
+		"\x03env\x05table\x01\x70\x00\x01"+
+		"\x03env\x06memory\x02\x01\x01\x02"+
+		"\x03env\x04base\x03\x7F\x00"+
+		"\x02js\x03tag\x04\x00\x00")
+	exports := section(7, "\x02"+
+		"\x03add\x00\x00"+
+		"\x07a-b c\xC3\xA9\x02\x00")
+	expectModule(t, "imports and exports", header+types+imports+exports, `import 0 env log
+import 1 env table
+import 2 env memory
+import 3 env base
+import 4 js tag
+export 0 add
+export 2 a-b cé
+`)
+}
+
+func TestInvalid(t *testing.T) {
+	expectModule(t, "magic", "\x00wasm\x01\x00\x00\x00",
+		"ERROR: The file \"<stdin>\" is not a WebAssembly module\n")
+	expectModule(t, "version", "\x00asm\x02\x00\x00\x00",
+		"ERROR: The WebAssembly module \"<stdin>\" uses an unsupported binary format version\n")
+	expectModule(t, "section size", header+"\x07\x10\x01",
+		"ERROR: The WebAssembly module \"<stdin>\" is malformed\n")
+	expectModule(t, "name size", header+"\x07\x04\x01\x09ab",
+		"ERROR: The WebAssembly module \"<stdin>\" is malformed\n")
+	expectModule(t, "import kind", header+"\x02\x07\x01\x01a\x01b\x09\x00",
+		"ERROR: The WebAssembly module \"<stdin>\" is malformed\n")
+}
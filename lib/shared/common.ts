@@ -112,17 +112,21 @@ function pushLogFlags(flags: string[], options: CommonOptions, keys: OptionKeys,
   let color = getFlag(options, keys, 'color', mustBeBoolean);
   let logLevel = getFlag(options, keys, 'logLevel', mustBeString);
   let logLimit = getFlag(options, keys, 'logLimit', mustBeInteger);
+  let logFormat = getFlag(options, keys, 'logFormat', mustBeString);
 
   if (color !== void 0) flags.push(`--color=${color}`);
   else if (isTTY) flags.push(`--color=true`); // This is needed to fix "execFileSync" which buffers stderr
   flags.push(`--log-level=${logLevel || logLevelDefault}`);
   flags.push(`--log-limit=${logLimit || 0}`);
+  if (logFormat) flags.push(`--log-format=${logFormat}`);
 }
 
 function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKeys): void {
   let legalComments = getFlag(options, keys, 'legalComments', mustBeString);
   let sourceRoot = getFlag(options, keys, 'sourceRoot', mustBeString);
   let sourcesContent = getFlag(options, keys, 'sourcesContent', mustBeBoolean);
+  let sourcesIgnoreList = getFlag(options, keys, 'sourcesIgnoreList', mustBeRegExp);
+  let sourcesRewrite = getFlag(options, keys, 'sourcesRewrite', mustBeString);
   let target = getFlag(options, keys, 'target', mustBeStringOrArray);
   let format = getFlag(options, keys, 'format', mustBeString);
   let globalName = getFlag(options, keys, 'globalName', mustBeString);
@@ -133,22 +137,34 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   let minifySyntax = getFlag(options, keys, 'minifySyntax', mustBeBoolean);
   let minifyWhitespace = getFlag(options, keys, 'minifyWhitespace', mustBeBoolean);
   let minifyIdentifiers = getFlag(options, keys, 'minifyIdentifiers', mustBeBoolean);
+  let nameSeed = getFlag(options, keys, 'nameSeed', mustBeString);
+  let runtimePrefix = getFlag(options, keys, 'runtimePrefix', mustBeString);
   let drop = getFlag(options, keys, 'drop', mustBeArray);
+  let dropCalls = getFlag(options, keys, 'dropCalls', mustBeArray);
   let charset = getFlag(options, keys, 'charset', mustBeString);
   let treeShaking = getFlag(options, keys, 'treeShaking', mustBeBoolean);
   let ignoreAnnotations = getFlag(options, keys, 'ignoreAnnotations', mustBeBoolean);
+  let isolatedModulesCheck = getFlag(options, keys, 'isolatedModulesCheck', mustBeBoolean);
+  let preserveComments = getFlag(options, keys, 'preserveComments', mustBeBoolean);
+  let preserveFormatting = getFlag(options, keys, 'preserveFormatting', mustBeBoolean);
   let jsx = getFlag(options, keys, 'jsx', mustBeString);
   let jsxFactory = getFlag(options, keys, 'jsxFactory', mustBeString);
   let jsxFragment = getFlag(options, keys, 'jsxFragment', mustBeString);
+  let jsxImportSource = getFlag(options, keys, 'jsxImportSource', mustBeString);
+  let jsxDev = getFlag(options, keys, 'jsxDev', mustBeBoolean);
   let define = getFlag(options, keys, 'define', mustBeObject);
   let logOverride = getFlag(options, keys, 'logOverride', mustBeObject);
   let supported = getFlag(options, keys, 'supported', mustBeObject);
   let pure = getFlag(options, keys, 'pure', mustBeArray);
   let keepNames = getFlag(options, keys, 'keepNames', mustBeBoolean);
+  let keepNamesFilter = getFlag(options, keys, 'keepNamesFilter', mustBeRegExp);
+  let keepNamesOnly = getFlag(options, keys, 'keepNamesOnly', mustBeArray);
 
   if (legalComments) flags.push(`--legal-comments=${legalComments}`);
   if (sourceRoot !== void 0) flags.push(`--source-root=${sourceRoot}`);
   if (sourcesContent !== void 0) flags.push(`--sources-content=${sourcesContent}`);
+  if (sourcesIgnoreList) flags.push(`--sources-ignore-list=${sourcesIgnoreList.source}`);
+  if (sourcesRewrite) flags.push(`--sources-rewrite=${sourcesRewrite}`);
   if (target) {
     if (Array.isArray(target)) flags.push(`--target=${Array.from(target).map(validateTarget).join(',')}`)
     else flags.push(`--target=${validateTarget(target)}`)
@@ -160,10 +176,24 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   if (minifySyntax) flags.push('--minify-syntax');
   if (minifyWhitespace) flags.push('--minify-whitespace');
   if (minifyIdentifiers) flags.push('--minify-identifiers');
+  if (nameSeed) flags.push(`--name-seed=${nameSeed}`);
+  if (runtimePrefix) flags.push(`--runtime-prefix=${runtimePrefix}`);
   if (charset) flags.push(`--charset=${charset}`);
   if (treeShaking !== void 0) flags.push(`--tree-shaking=${treeShaking}`);
   if (ignoreAnnotations) flags.push(`--ignore-annotations`);
+  if (isolatedModulesCheck) flags.push(`--isolated-modules-check`);
+  if (preserveComments) flags.push(`--preserve-comments`);
+  if (preserveFormatting) flags.push(`--preserve-formatting`);
   if (drop) for (let what of drop) flags.push(`--drop:${what}`);
+  if (dropCalls) {
+    let values: string[] = [];
+    for (let value of dropCalls) {
+      value += '';
+      if (value.indexOf(',') >= 0) throw new Error(`Invalid call to drop: ${value}`);
+      values.push(value);
+    }
+    flags.push(`--drop-calls=${values.join(',')}`);
+  }
   if (mangleProps) flags.push(`--mangle-props=${mangleProps.source}`);
   if (reserveProps) flags.push(`--reserve-props=${reserveProps.source}`);
   if (mangleQuoted !== void 0) flags.push(`--mangle-quoted=${mangleQuoted}`)
@@ -171,6 +201,8 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   if (jsx) flags.push(`--jsx=${jsx}`);
   if (jsxFactory) flags.push(`--jsx-factory=${jsxFactory}`);
   if (jsxFragment) flags.push(`--jsx-fragment=${jsxFragment}`);
+  if (jsxImportSource) flags.push(`--jsx-import-source=${jsxImportSource}`);
+  if (jsxDev) flags.push(`--jsx-dev`);
 
   if (define) {
     for (let key in define) {
@@ -192,6 +224,8 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   }
   if (pure) for (let fn of pure) flags.push(`--pure:${fn}`);
   if (keepNames) flags.push(`--keep-names`);
+  if (keepNamesFilter) flags.push(`--keep-names-filter=${keepNamesFilter.source}`);
+  if (keepNamesOnly) flags.push(`--keep-names-only=${keepNamesOnly.join(',')}`);
 }
 
 function flagsForBuildOptions(
@@ -201,7 +235,9 @@ function flagsForBuildOptions(
   logLevelDefault: types.LogLevel,
   writeDefault: boolean,
 ): {
-  entries: [string, string][],
+  entries: [string, string, string, string[]][],
+  virtualEntries: [string, string, string, string][],
+  virtualModules: [string, string, string, string][],
   flags: string[],
   write: boolean,
   stdinContents: string | null,
@@ -213,7 +249,9 @@ function flagsForBuildOptions(
   mangleCache: MangleCache | undefined,
 } {
   let flags: string[] = [];
-  let entries: [string, string][] = [];
+  let entries: [string, string, string, string[]][] = [];
+  let virtualEntries: [string, string, string, string][] = [];
+  let virtualModules: [string, string, string, string][] = [];
   let keys: OptionKeys = Object.create(null);
   let stdinContents: string | null = null;
   let stdinResolveDir: string | null = null;
@@ -225,33 +263,87 @@ function flagsForBuildOptions(
   let bundle = getFlag(options, keys, 'bundle', mustBeBoolean);
   let watch = getFlag(options, keys, 'watch', mustBeBooleanOrObject);
   let splitting = getFlag(options, keys, 'splitting', mustBeBoolean);
+  let purgeCSS = getFlag(options, keys, 'purgeCSS', mustBeBoolean);
+  let purgeCSSSafelist = getFlag(options, keys, 'purgeCSSSafelist', mustBeArray);
+  let boundaryPackages = getFlag(options, keys, 'boundaryPackages', mustBeArray);
+  let disallowedLicenses = getFlag(options, keys, 'disallowedLicenses', mustBeArray);
+  let verifyLockfile = getFlag(options, keys, 'verifyLockfile', mustBeBoolean);
   let preserveSymlinks = getFlag(options, keys, 'preserveSymlinks', mustBeBoolean);
   let metafile = getFlag(options, keys, 'metafile', mustBeBoolean);
+  let graphOnly = getFlag(options, keys, 'graphOnly', mustBeBoolean);
+  let timing = getFlag(options, keys, 'timing', mustBeBoolean);
+  let collectLegalComments = getFlag(options, keys, 'collectLegalComments', mustBeBoolean);
+  let nameMap = getFlag(options, keys, 'nameMap', mustBeBoolean);
+  let publishPackageJson = getFlag(options, keys, 'publishPackageJson', mustBeBoolean);
+  let declarations = getFlag(options, keys, 'declarations', mustBeBoolean);
+  let concatReport = getFlag(options, keys, 'concatReport', mustBeBoolean);
+  let scanSecrets = getFlag(options, keys, 'scanSecrets', mustBeBoolean);
+  let refreshMetadata = getFlag(options, keys, 'refreshMetadata', mustBeBoolean);
+  let integrity = getFlag(options, keys, 'integrity', mustBeBoolean);
+  let debugId = getFlag(options, keys, 'debugId', mustBeBoolean);
+  let deterministic = getFlag(options, keys, 'deterministic', mustBeBoolean);
+  let verifyDeterminism = getFlag(options, keys, 'verifyDeterminism', mustBeBoolean);
   let outfile = getFlag(options, keys, 'outfile', mustBeString);
   let outdir = getFlag(options, keys, 'outdir', mustBeString);
   let outbase = getFlag(options, keys, 'outbase', mustBeString);
   let platform = getFlag(options, keys, 'platform', mustBeString);
   let tsconfig = getFlag(options, keys, 'tsconfig', mustBeString);
+  let tsconfigNested = getFlag(options, keys, 'tsconfigNested', mustBeBoolean);
   let resolveExtensions = getFlag(options, keys, 'resolveExtensions', mustBeArray);
   let nodePathsInput = getFlag(options, keys, 'nodePaths', mustBeArray);
   let mainFields = getFlag(options, keys, 'mainFields', mustBeArray);
   let conditions = getFlag(options, keys, 'conditions', mustBeArray);
+  let sideEffectsOverride = getFlag(options, keys, 'sideEffectsOverride', mustBeObject);
+  let externalGlobals = getFlag(options, keys, 'externalGlobals', mustBeObject);
+  let shared = getFlag(options, keys, 'shared', mustBeArray);
+  let sharedScope = getFlag(options, keys, 'sharedScope', mustBeString);
+  let dynamicRequire = getFlag(options, keys, 'dynamicRequire', mustBeString);
+  let remoteImports = getFlag(options, keys, 'remoteImports', mustBeBoolean);
+  let remoteCacheDir = getFlag(options, keys, 'remoteCacheDir', mustBeString);
+  let remoteLockFile = getFlag(options, keys, 'remoteLockFile', mustBeString);
+  let remoteOffline = getFlag(options, keys, 'remoteOffline', mustBeBoolean);
+  let importMap = getFlag(options, keys, 'importMap', mustBeString);
+  let importMapExternal = getFlag(options, keys, 'importMapExternal', mustBeBoolean);
   let external = getFlag(options, keys, 'external', mustBeArray);
+  let jsxOverrides = getFlag(options, keys, 'jsxOverrides', mustBeArray);
   let loader = getFlag(options, keys, 'loader', mustBeObject);
   let outExtension = getFlag(options, keys, 'outExtension', mustBeObject);
   let publicPath = getFlag(options, keys, 'publicPath', mustBeString);
+  let assetPublicPaths = getFlag(options, keys, 'assetPublicPaths', mustBeObject);
+  let assetInlineLimit = getFlag(options, keys, 'assetInlineLimit', mustBeInteger);
   let entryNames = getFlag(options, keys, 'entryNames', mustBeString);
   let chunkNames = getFlag(options, keys, 'chunkNames', mustBeString);
   let assetNames = getFlag(options, keys, 'assetNames', mustBeString);
+  let assetRoot = getFlag(options, keys, 'assetRoot', mustBeString);
+  let assetOutdir = getFlag(options, keys, 'assetOutdir', mustBeString);
+  let copy = getFlag(options, keys, 'copy', mustBeObject);
+  let hashSalt = getFlag(options, keys, 'hashSalt', mustBeString);
+  let manifest = getFlag(options, keys, 'manifest', mustBeString);
+  let polyfillReport = getFlag(options, keys, 'polyfillReport', mustBeString);
+  let treeShakingReport = getFlag(options, keys, 'treeShakingReport', mustBeString);
+  let statusFile = getFlag(options, keys, 'statusFile', mustBeString);
   let inject = getFlag(options, keys, 'inject', mustBeArray);
+  let injectIfUsed = getFlag(options, keys, 'injectIfUsed', mustBeBoolean);
+  let platformInject = getFlag(options, keys, 'platformInject', mustBeObject);
   let banner = getFlag(options, keys, 'banner', mustBeObject);
   let footer = getFlag(options, keys, 'footer', mustBeObject);
   let entryPoints = getFlag(options, keys, 'entryPoints', mustBeArrayOrRecord);
+  let entryConditions = getFlag(options, keys, 'entryConditions', mustBeObject);
+  let entryInject = getFlag(options, keys, 'entryInject', mustBeObject);
+  let virtualEntryPoints = getFlag(options, keys, 'virtualEntryPoints', mustBeArray);
+  let virtualModulesInput = getFlag(options, keys, 'virtualModules', mustBeArray);
   let absWorkingDir = getFlag(options, keys, 'absWorkingDir', mustBeString);
   let stdin = getFlag(options, keys, 'stdin', mustBeObject);
   let write = getFlag(options, keys, 'write', mustBeBoolean) ?? writeDefault; // Default to true if not specified
   let allowOverwrite = getFlag(options, keys, 'allowOverwrite', mustBeBoolean);
+  let clean = getFlag(options, keys, 'clean', mustBeBoolean);
+  let cleanRetain = getFlag(options, keys, 'cleanRetain', mustBeInteger);
+  let atomicWrite = getFlag(options, keys, 'atomicWrite', mustBeBoolean);
   let incremental = getFlag(options, keys, 'incremental', mustBeBoolean) === true;
+  let maxOpenFiles = getFlag(options, keys, 'maxOpenFiles', mustBeInteger);
+  let maxWorkers = getFlag(options, keys, 'maxWorkers', mustBeInteger);
+  let memoryLimit = getFlag(options, keys, 'memoryLimit', mustBeInteger);
+  let lowMemory = getFlag(options, keys, 'lowMemory', mustBeBoolean);
   let mangleCache = getFlag(options, keys, 'mangleCache', mustBeObject);
   keys.plugins = true; // "plugins" has already been read earlier
   checkForInvalidFlags(options, keys, `in ${callName}() call`);
@@ -259,6 +351,9 @@ function flagsForBuildOptions(
   if (sourcemap) flags.push(`--sourcemap${sourcemap === true ? '' : `=${sourcemap}`}`);
   if (bundle) flags.push('--bundle');
   if (allowOverwrite) flags.push('--allow-overwrite');
+  if (clean) flags.push('--clean');
+  if (cleanRetain) flags.push(`--clean-retain=${cleanRetain}`);
+  if (atomicWrite) flags.push('--atomic-write');
   if (watch) {
     flags.push('--watch');
     if (typeof watch === 'boolean') {
@@ -266,18 +361,41 @@ function flagsForBuildOptions(
     } else {
       let watchKeys: OptionKeys = Object.create(null);
       let onRebuild = getFlag(watch, watchKeys, 'onRebuild', mustBeFunction);
+      let interval = getFlag(watch, watchKeys, 'interval', mustBeInteger);
+      let ignore = getFlag(watch, watchKeys, 'ignore', mustBeRegExp);
       checkForInvalidFlags(watch, watchKeys, `on "watch" in ${callName}() call`);
+      if (interval !== void 0) flags.push(`--watch-interval=${interval}`);
+      if (ignore) flags.push(`--watch-ignore=${ignore.source}`);
       watchMode = { onRebuild };
     }
   }
   if (splitting) flags.push('--splitting');
+  if (purgeCSS) flags.push('--purge-css');
+  if (purgeCSSSafelist) for (let regex of purgeCSSSafelist) flags.push(`--purge-css-safelist:${regex instanceof RegExp ? regex.source : regex}`);
+  if (boundaryPackages) for (let name of boundaryPackages) flags.push(`--boundary-package:${name}`);
+  if (disallowedLicenses) for (let license of disallowedLicenses) flags.push(`--disallow-license:${license}`);
+  if (verifyLockfile) flags.push('--verify-lockfile');
   if (preserveSymlinks) flags.push('--preserve-symlinks');
   if (metafile) flags.push(`--metafile`);
+  if (graphOnly) flags.push(`--graph-only`);
+  if (timing) flags.push('--timing');
+  if (collectLegalComments) flags.push(`--collect-legal-comments`);
+  if (nameMap) flags.push(`--name-map`);
+  if (publishPackageJson) flags.push(`--publish-package-json`);
+  if (declarations) flags.push(`--declarations`);
+  if (concatReport) flags.push(`--concat-report`);
+  if (scanSecrets) flags.push(`--scan-secrets`);
+  if (refreshMetadata) flags.push(`--refresh-metadata`);
+  if (integrity) flags.push(`--integrity`);
+  if (debugId) flags.push(`--debug-id`);
+  if (deterministic) flags.push(`--deterministic`);
+  if (verifyDeterminism) flags.push(`--verify-determinism`);
   if (outfile) flags.push(`--outfile=${outfile}`);
   if (outdir) flags.push(`--outdir=${outdir}`);
   if (outbase) flags.push(`--outbase=${outbase}`);
   if (platform) flags.push(`--platform=${platform}`);
   if (tsconfig) flags.push(`--tsconfig=${tsconfig}`);
+  if (tsconfigNested) flags.push(`--tsconfig-nested`);
   if (resolveExtensions) {
     let values: string[] = [];
     for (let value of resolveExtensions) {
@@ -288,9 +406,33 @@ function flagsForBuildOptions(
     flags.push(`--resolve-extensions=${values.join(',')}`);
   }
   if (publicPath) flags.push(`--public-path=${publicPath}`);
+  if (assetPublicPaths) {
+    for (let ext in assetPublicPaths) {
+      if (ext.indexOf('=') >= 0) throw new Error(`Invalid asset public path extension: ${ext}`);
+      flags.push(`--public-path:${ext}=${assetPublicPaths[ext]}`);
+    }
+  }
+  if (assetInlineLimit) flags.push(`--asset-inline-limit=${assetInlineLimit}`);
   if (entryNames) flags.push(`--entry-names=${entryNames}`);
   if (chunkNames) flags.push(`--chunk-names=${chunkNames}`);
   if (assetNames) flags.push(`--asset-names=${assetNames}`);
+  if (assetRoot) flags.push(`--asset-root=${assetRoot}`);
+  if (assetOutdir) flags.push(`--asset-outdir=${assetOutdir}`);
+  if (copy) {
+    for (let source in copy) {
+      if (source.slice(2).indexOf(':') >= 0) throw new Error(`Invalid copy source: ${source}`);
+      flags.push(`--copy=${source}:${copy[source]}`);
+    }
+  }
+  if (hashSalt) flags.push(`--hash-salt=${hashSalt}`);
+  if (manifest) flags.push(`--manifest=${manifest}`);
+  if (polyfillReport) flags.push(`--polyfill-report=${polyfillReport}`);
+  if (treeShakingReport) flags.push(`--tree-shaking-report=${treeShakingReport}`);
+  if (statusFile) flags.push(`--status-file=${statusFile}`);
+  if (maxOpenFiles) flags.push(`--max-open-files=${maxOpenFiles}`);
+  if (maxWorkers) flags.push(`--max-workers=${maxWorkers}`);
+  if (memoryLimit) flags.push(`--memory-limit=${memoryLimit}`);
+  if (lowMemory) flags.push('--low-memory');
   if (mainFields) {
     let values: string[] = [];
     for (let value of mainFields) {
@@ -309,7 +451,42 @@ function flagsForBuildOptions(
     }
     flags.push(`--conditions=${values.join(',')}`);
   }
+  if (sideEffectsOverride) {
+    for (let name in sideEffectsOverride) {
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid side effects override: ${name}`);
+      flags.push(`--side-effects-override:${name}=${!!sideEffectsOverride[name]}`);
+    }
+  }
+  if (externalGlobals) {
+    for (let path in externalGlobals) {
+      if (path.indexOf('=') >= 0) throw new Error(`Invalid external global path: ${path}`);
+      flags.push(`--external-global:${path}=${externalGlobals[path]}`);
+    }
+  }
+  if (shared) for (let name of shared) flags.push(`--shared:${name}`);
+  if (sharedScope) flags.push(`--shared-scope=${sharedScope}`);
+  if (dynamicRequire) flags.push(`--dynamic-require=${dynamicRequire}`);
+  if (remoteImports) flags.push('--remote-imports');
+  if (remoteCacheDir) flags.push(`--remote-cache-dir=${remoteCacheDir}`);
+  if (remoteLockFile) flags.push(`--remote-lock-file=${remoteLockFile}`);
+  if (remoteOffline) flags.push('--remote-offline');
+  if (importMap) flags.push(`--import-map=${importMap}`);
+  if (importMapExternal) flags.push('--import-map-external');
   if (external) for (let name of external) flags.push(`--external:${name}`);
+  if (jsxOverrides) {
+    for (let override of jsxOverrides) {
+      let overrideKeys: OptionKeys = Object.create(null);
+      let path = getFlag(override, overrideKeys, 'path', mustBeString);
+      let factory = getFlag(override, overrideKeys, 'factory', mustBeString);
+      let fragment = getFlag(override, overrideKeys, 'fragment', mustBeString);
+      let importSource = getFlag(override, overrideKeys, 'importSource', mustBeString);
+      checkForInvalidFlags(override, overrideKeys, `in "jsxOverrides" in ${callName}() call`);
+      if (!path || path.indexOf('=') >= 0) throw new Error(`Invalid JSX override path: ${path}`);
+      if (factory) flags.push(`--jsx-factory:${path}=${factory}`);
+      if (fragment) flags.push(`--jsx-fragment:${path}=${fragment}`);
+      if (importSource) flags.push(`--jsx-import-source:${path}=${importSource}`);
+    }
+  }
   if (banner) {
     for (let type in banner) {
       if (type.indexOf('=') >= 0) throw new Error(`Invalid banner file type: ${type}`);
@@ -323,6 +500,15 @@ function flagsForBuildOptions(
     }
   }
   if (inject) for (let path of inject) flags.push(`--inject:${path}`);
+  if (injectIfUsed) flags.push('--inject-if-used');
+  if (platformInject) {
+    for (let platform in platformInject) {
+      if (platform.indexOf('=') >= 0) throw new Error(`Invalid platform: ${platform}`);
+      let paths = platformInject[platform as types.Platform];
+      if (!Array.isArray(paths)) throw new Error(`Expected an array of paths for ${JSON.stringify(platform)} in "platformInject"`);
+      for (let path of paths) flags.push(`--platform-inject:${platform}=${path}`);
+    }
+  }
   if (loader) {
     for (let ext in loader) {
       if (ext.indexOf('=') >= 0) throw new Error(`Invalid loader extension: ${ext}`);
@@ -339,14 +525,63 @@ function flagsForBuildOptions(
   if (entryPoints) {
     if (Array.isArray(entryPoints)) {
       for (let entryPoint of entryPoints) {
-        entries.push(['', entryPoint + '']);
+        entries.push(['', entryPoint + '', '', []]);
       }
     } else {
       for (let [key, value] of Object.entries(entryPoints)) {
-        entries.push([key + '', value + '']);
+        entries.push([key + '', value + '', '', []]);
+      }
+    }
+  }
+  if (entryConditions) {
+    for (let key in entryConditions) {
+      let conditions = entryConditions[key];
+      if (!Array.isArray(conditions)) throw new Error(`Expected an array of conditions for ${JSON.stringify(key)} in "entryConditions"`);
+      let values: string[] = [];
+      for (let value of conditions) {
+        value += '';
+        if (value.indexOf(',') >= 0) throw new Error(`Invalid condition: ${value}`);
+        values.push(value);
+      }
+      let found = false;
+      for (let entry of entries) {
+        if (entry[1] === key) {
+          entry[2] = values.join(',');
+          found = true;
+        }
       }
+      if (!found) throw new Error(`No entry point matches ${JSON.stringify(key)} in "entryConditions"`);
     }
   }
+  if (entryInject) {
+    for (let key in entryInject) {
+      let paths = entryInject[key];
+      if (!Array.isArray(paths)) throw new Error(`Expected an array of paths for ${JSON.stringify(key)} in "entryInject"`);
+      let found = false;
+      for (let entry of entries) {
+        if (entry[1] === key) {
+          entry[3] = paths.map(path => path + '');
+          found = true;
+        }
+      }
+      if (!found) throw new Error(`No entry point matches ${JSON.stringify(key)} in "entryInject"`);
+    }
+  }
+
+  let addVirtualModules = (input: types.VirtualModule[], output: [string, string, string, string][], what: string): void => {
+    for (let virtualModule of input) {
+      let virtualKeys: OptionKeys = Object.create(null);
+      let name = getFlag(virtualModule, virtualKeys, 'name', mustBeString);
+      let contents = getFlag(virtualModule, virtualKeys, 'contents', mustBeString);
+      let resolveDir = getFlag(virtualModule, virtualKeys, 'resolveDir', mustBeString);
+      let loader = getFlag(virtualModule, virtualKeys, 'loader', mustBeString);
+      checkForInvalidFlags(virtualModule, virtualKeys, `in virtual ${what}`);
+      if (name === undefined) throw new Error(`Virtual ${what}s must have a "name"`);
+      output.push([name + '', contents ? contents + '' : '', resolveDir ? resolveDir + '' : '', loader ? loader + '' : '']);
+    }
+  };
+  if (virtualEntryPoints) addVirtualModules(virtualEntryPoints, virtualEntries, 'entry point');
+  if (virtualModulesInput) addVirtualModules(virtualModulesInput, virtualModules, 'module');
 
   if (stdin) {
     let stdinKeys: OptionKeys = Object.create(null);
@@ -372,6 +607,8 @@ function flagsForBuildOptions(
 
   return {
     entries,
+    virtualEntries,
+    virtualModules,
     flags,
     write,
     stdinContents,
@@ -404,6 +641,7 @@ function flagsForTransformOptions(
   let loader = getFlag(options, keys, 'loader', mustBeString);
   let banner = getFlag(options, keys, 'banner', mustBeString);
   let footer = getFlag(options, keys, 'footer', mustBeString);
+  let errorRecovery = getFlag(options, keys, 'errorRecovery', mustBeBoolean);
   let mangleCache = getFlag(options, keys, 'mangleCache', mustBeObject);
   checkForInvalidFlags(options, keys, `in ${callName}() call`);
 
@@ -413,6 +651,7 @@ function flagsForTransformOptions(
   if (loader) flags.push(`--loader=${loader}`);
   if (banner) flags.push(`--banner=${banner}`);
   if (footer) flags.push(`--footer=${footer}`);
+  if (errorRecovery) flags.push(`--error-recovery`);
 
   return {
     flags,
@@ -694,6 +933,7 @@ export function createChannel(streamIn: StreamIn): StreamOut {
   > => {
     let onStartCallbacks: {
       name: string,
+      plugin: number,
       note: () => types.Note | undefined,
       callback: () => (types.OnStartResult | null | void | Promise<types.OnStartResult | null | void>),
     }[] = [];
@@ -742,6 +982,7 @@ export function createChannel(streamIn: StreamIn): StreamOut {
 
         let plugin: protocol.BuildPlugin = {
           name,
+          onStart: false,
           onResolve: [],
           onLoad: [],
         };
@@ -797,7 +1038,8 @@ export function createChannel(streamIn: StreamIn): StreamOut {
           onStart(callback) {
             let registeredText = `This error came from the "onStart" callback registered here:`
             let registeredNote = extractCallerV8(new Error(registeredText), streamIn, 'onStart');
-            onStartCallbacks.push({ name: name!, callback, note: registeredNote });
+            onStartCallbacks.push({ name: name!, plugin: requestPlugins.length, callback, note: registeredNote });
+            plugin.onStart = true;
           },
 
           onEnd(callback) {
@@ -852,7 +1094,8 @@ export function createChannel(streamIn: StreamIn): StreamOut {
       switch (request.command) {
         case 'on-start': {
           let response: protocol.OnStartResponse = { errors: [], warnings: [] };
-          await Promise.all(onStartCallbacks.map(async ({ name, callback, note }) => {
+          let callbacks = request.plugin === undefined ? onStartCallbacks : onStartCallbacks.filter(({ plugin }) => plugin === request.plugin);
+          await Promise.all(callbacks.map(async ({ name, callback, note }) => {
             try {
               let result = await callback();
 
@@ -944,6 +1187,7 @@ export function createChannel(streamIn: StreamIn): StreamOut {
                 let resolveDir = getFlag(result, keys, 'resolveDir', mustBeString);
                 let pluginData = getFlag(result, keys, 'pluginData', canBeAnything);
                 let loader = getFlag(result, keys, 'loader', mustBeString);
+                let sourceMap = getFlag(result, keys, 'sourceMap', mustBeStringOrObject);
                 let errors = getFlag(result, keys, 'errors', mustBeArray);
                 let warnings = getFlag(result, keys, 'warnings', mustBeArray);
                 let watchFiles = getFlag(result, keys, 'watchFiles', mustBeArray);
@@ -957,6 +1201,7 @@ export function createChannel(streamIn: StreamIn): StreamOut {
                 if (resolveDir != null) response.resolveDir = resolveDir;
                 if (pluginData != null) response.pluginData = stash.store(pluginData);
                 if (loader != null) response.loader = loader;
+                if (sourceMap != null) response.sourceMap = typeof sourceMap === 'string' ? sourceMap : JSON.stringify(sourceMap);
                 if (errors != null) response.errors = sanitizeMessages(errors, 'errors', stash, name);
                 if (warnings != null) response.warnings = sanitizeMessages(warnings, 'warnings', stash, name);
                 if (watchFiles != null) response.watchFiles = sanitizeStringArray(watchFiles, 'watchFiles');
@@ -1161,6 +1406,8 @@ export function createChannel(streamIn: StreamIn): StreamOut {
     let writeDefault = !streamIn.isBrowser;
     let {
       entries,
+      virtualEntries,
+      virtualModules,
       flags,
       write,
       stdinContents,
@@ -1185,6 +1432,8 @@ export function createChannel(streamIn: StreamIn): StreamOut {
     };
     if (requestPlugins) request.plugins = requestPlugins;
     if (mangleCache) request.mangleCache = mangleCache;
+    if (virtualEntries.length > 0) request.virtualEntries = virtualEntries;
+    if (virtualModules.length > 0) request.virtualModules = virtualModules;
     let serve = serveOptions && buildServeData(refs, serveOptions, request, key);
 
     // Factor out response handling so it can be reused for rebuilds
@@ -1194,6 +1443,8 @@ export function createChannel(streamIn: StreamIn): StreamOut {
       if (response.outputFiles) result.outputFiles = response!.outputFiles.map(convertOutputFiles);
       if (response.metafile) result.metafile = JSON.parse(response!.metafile);
       if (response.mangleCache) result.mangleCache = response!.mangleCache;
+      if (response.legalComments) result.legalComments = response!.legalComments;
+      if (response.graph) result.graph = JSON.parse(response!.graph);
       if (response.writeToStdout !== void 0) console.log(protocol.decodeUTF8(response!.writeToStdout).replace(/\n$/, ''));
     };
     let buildResponseToResult = (
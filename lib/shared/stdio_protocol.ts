@@ -9,7 +9,9 @@ import * as types from "./types";
 export interface BuildRequest {
   command: 'build';
   key: number;
-  entries: [string, string][]; // Use an array instead of a map to preserve order
+  entries: [string, string, string, string[]][]; // [outputPath, inputPath, conditions, inject] (an array preserves order)
+  virtualEntries?: [string, string, string, string][]; // [name, contents, resolveDir, loader]
+  virtualModules?: [string, string, string, string][]; // [name, contents, resolveDir, loader]
   flags: string[];
   write: boolean;
   stdinContents: string | null;
@@ -40,6 +42,7 @@ export interface ServeStopRequest {
 
 export interface BuildPlugin {
   name: string;
+  onStart: boolean;
   onResolve: { id: number, filter: string, namespace: string }[];
   onLoad: { id: number, filter: string, namespace: string }[];
 }
@@ -52,6 +55,8 @@ export interface BuildResponse {
   outputFiles?: BuildOutputFile[];
   metafile?: string;
   mangleCache?: Record<string, string | false>;
+  legalComments?: types.LegalComment[];
+  graph?: string;
   writeToStdout?: Uint8Array;
 }
 
@@ -144,6 +149,7 @@ export interface AnalyzeMetafileResponse {
 export interface OnStartRequest {
   command: 'on-start';
   key: number;
+  plugin?: number;
 }
 
 export interface OnStartResponse {
@@ -226,6 +232,7 @@ export interface OnLoadResponse {
   resolveDir?: string;
   loader?: string;
   pluginData?: number;
+  sourceMap?: string;
 
   watchFiles?: string[];
   watchDirs?: string[];
@@ -1,19 +1,23 @@
-export type Platform = 'browser' | 'node' | 'neutral';
+export type Platform = 'browser' | 'node' | 'neutral' | 'deno';
 export type Format = 'iife' | 'cjs' | 'esm';
-export type Loader = 'js' | 'jsx' | 'ts' | 'tsx' | 'css' | 'json' | 'text' | 'base64' | 'file' | 'dataurl' | 'binary' | 'copy' | 'default';
+export type Loader = 'js' | 'jsx' | 'ts' | 'tsx' | 'css' | 'json' | 'text' | 'base64' | 'file' | 'dataurl' | 'binary' | 'copy' | 'html' | 'wasm' | 'wasm-file' | 'napi' | 'default';
 export type LogLevel = 'verbose' | 'debug' | 'info' | 'warning' | 'error' | 'silent';
-export type Charset = 'ascii' | 'utf8';
-export type Drop = 'console' | 'debugger';
+export type Charset = 'ascii' | 'utf8' | 'bmp';
+export type Drop = 'console' | 'debugger' | 'dead-imports';
 
 interface CommonOptions {
   /** Documentation: https://esbuild.github.io/api/#sourcemap */
   sourcemap?: boolean | 'linked' | 'inline' | 'external' | 'both';
   /** Documentation: https://esbuild.github.io/api/#legal-comments */
-  legalComments?: 'none' | 'inline' | 'eof' | 'linked' | 'external';
+  legalComments?: 'none' | 'inline' | 'eof' | 'linked' | 'external' | 'combined';
   /** Documentation: https://esbuild.github.io/api/#source-root */
   sourceRoot?: string;
   /** Documentation: https://esbuild.github.io/api/#sources-content */
   sourcesContent?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#sources-ignore-list */
+  sourcesIgnoreList?: RegExp;
+  /** Documentation: https://esbuild.github.io/api/#sources-rewrite */
+  sourcesRewrite?: string;
 
   /** Documentation: https://esbuild.github.io/api/#format */
   format?: Format;
@@ -34,6 +38,8 @@ interface CommonOptions {
   mangleCache?: Record<string, string | false>;
   /** Documentation: https://esbuild.github.io/api/#drop */
   drop?: Drop[];
+  /** Documentation: https://esbuild.github.io/api/#drop-calls */
+  dropCalls?: string[];
   /** Documentation: https://esbuild.github.io/api/#minify */
   minify?: boolean;
   /** Documentation: https://esbuild.github.io/api/#minify */
@@ -42,19 +48,33 @@ interface CommonOptions {
   minifyIdentifiers?: boolean;
   /** Documentation: https://esbuild.github.io/api/#minify */
   minifySyntax?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#name-seed */
+  nameSeed?: string;
+  /** Documentation: https://esbuild.github.io/api/#runtime-prefix */
+  runtimePrefix?: string;
   /** Documentation: https://esbuild.github.io/api/#charset */
   charset?: Charset;
   /** Documentation: https://esbuild.github.io/api/#tree-shaking */
   treeShaking?: boolean;
   /** Documentation: https://esbuild.github.io/api/#ignore-annotations */
   ignoreAnnotations?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#isolated-modules-check */
+  isolatedModulesCheck?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#preserve-comments */
+  preserveComments?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#preserve-formatting */
+  preserveFormatting?: boolean;
 
   /** Documentation: https://esbuild.github.io/api/#jsx */
-  jsx?: 'transform' | 'preserve';
+  jsx?: 'transform' | 'preserve' | 'automatic';
   /** Documentation: https://esbuild.github.io/api/#jsx-factory */
   jsxFactory?: string;
   /** Documentation: https://esbuild.github.io/api/#jsx-fragment */
   jsxFragment?: string;
+  /** Documentation: https://esbuild.github.io/api/#jsx-import-source */
+  jsxImportSource?: string;
+  /** Documentation: https://esbuild.github.io/api/#jsx-development */
+  jsxDev?: boolean;
 
   /** Documentation: https://esbuild.github.io/api/#define */
   define?: { [key: string]: string };
@@ -62,6 +82,10 @@ interface CommonOptions {
   pure?: string[];
   /** Documentation: https://esbuild.github.io/api/#keep-names */
   keepNames?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#keep-names */
+  keepNamesFilter?: RegExp;
+  /** Documentation: https://esbuild.github.io/api/#keep-names */
+  keepNamesOnly?: ('classes' | 'functions' | 'exports')[];
 
   /** Documentation: https://esbuild.github.io/api/#color */
   color?: boolean;
@@ -71,6 +95,16 @@ interface CommonOptions {
   logLimit?: number;
   /** Documentation: https://esbuild.github.io/api/#log-override */
   logOverride?: Record<string, LogLevel>;
+  /** Documentation: https://esbuild.github.io/api/#log-format */
+  logFormat?: 'text' | 'json';
+}
+
+export interface JSXOverride {
+  /** A file, a directory, or a path with a single "*" wildcard */
+  path: string;
+  factory?: string;
+  fragment?: string;
+  importSource?: string;
 }
 
 export interface BuildOptions extends CommonOptions {
@@ -78,12 +112,48 @@ export interface BuildOptions extends CommonOptions {
   bundle?: boolean;
   /** Documentation: https://esbuild.github.io/api/#splitting */
   splitting?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#purge-css */
+  purgeCSS?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#purge-css */
+  purgeCSSSafelist?: RegExp[];
+  /** Documentation: https://esbuild.github.io/api/#boundary-packages */
+  boundaryPackages?: string[];
+  /** Documentation: https://esbuild.github.io/api/#disallowed-licenses */
+  disallowedLicenses?: string[];
+  /** Documentation: https://esbuild.github.io/api/#verify-lockfile */
+  verifyLockfile?: boolean;
   /** Documentation: https://esbuild.github.io/api/#preserve-symlinks */
   preserveSymlinks?: boolean;
   /** Documentation: https://esbuild.github.io/api/#outfile */
   outfile?: string;
   /** Documentation: https://esbuild.github.io/api/#metafile */
   metafile?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#graph-only */
+  graphOnly?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#timing */
+  timing?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#collect-legal-comments */
+  collectLegalComments?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#name-map */
+  nameMap?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#publish-package-json */
+  publishPackageJson?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#declarations */
+  declarations?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#concat-report */
+  concatReport?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#scan-secrets */
+  scanSecrets?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#refresh-metadata */
+  refreshMetadata?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#integrity */
+  integrity?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#debug-id */
+  debugId?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#deterministic */
+  deterministic?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#deterministic */
+  verifyDeterminism?: boolean;
   /** Documentation: https://esbuild.github.io/api/#outdir */
   outdir?: string;
   /** Documentation: https://esbuild.github.io/api/#outbase */
@@ -92,40 +162,112 @@ export interface BuildOptions extends CommonOptions {
   platform?: Platform;
   /** Documentation: https://esbuild.github.io/api/#external */
   external?: string[];
+  /** Documentation: https://esbuild.github.io/api/#external-global */
+  externalGlobals?: Record<string, string>;
+  /** Documentation: https://esbuild.github.io/api/#shared */
+  shared?: string[];
+  /** Documentation: https://esbuild.github.io/api/#shared-scope */
+  sharedScope?: string;
+  /** Documentation: https://esbuild.github.io/api/#dynamic-require */
+  dynamicRequire?: 'keep' | 'error' | 'context';
   /** Documentation: https://esbuild.github.io/api/#loader */
   loader?: { [ext: string]: Loader };
+  /** Documentation: https://esbuild.github.io/api/#jsx-overrides */
+  jsxOverrides?: JSXOverride[];
   /** Documentation: https://esbuild.github.io/api/#resolve-extensions */
   resolveExtensions?: string[];
   /** Documentation: https://esbuild.github.io/api/#mainFields */
   mainFields?: string[];
   /** Documentation: https://esbuild.github.io/api/#conditions */
   conditions?: string[];
+  /** Documentation: https://esbuild.github.io/api/#side-effects-override */
+  sideEffectsOverride?: Record<string, boolean>;
+  /** Documentation: https://esbuild.github.io/api/#remote-imports */
+  remoteImports?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#remote-imports */
+  remoteCacheDir?: string;
+  /** Documentation: https://esbuild.github.io/api/#remote-imports */
+  remoteLockFile?: string;
+  /** Documentation: https://esbuild.github.io/api/#remote-imports */
+  remoteOffline?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#import-map */
+  importMap?: string;
+  /** Documentation: https://esbuild.github.io/api/#import-map */
+  importMapExternal?: boolean;
   /** Documentation: https://esbuild.github.io/api/#write */
   write?: boolean;
   /** Documentation: https://esbuild.github.io/api/#allow-overwrite */
   allowOverwrite?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#clean */
+  clean?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#clean-retain */
+  cleanRetain?: number;
+  /** Documentation: https://esbuild.github.io/api/#atomic-write */
+  atomicWrite?: boolean;
   /** Documentation: https://esbuild.github.io/api/#tsconfig */
   tsconfig?: string;
+  /** Documentation: https://esbuild.github.io/api/#tsconfig-nested */
+  tsconfigNested?: boolean;
   /** Documentation: https://esbuild.github.io/api/#out-extension */
   outExtension?: { [ext: string]: string };
   /** Documentation: https://esbuild.github.io/api/#public-path */
   publicPath?: string;
+  /** Documentation: https://esbuild.github.io/api/#public-path */
+  assetPublicPaths?: { [ext: string]: string };
+  /** Documentation: https://esbuild.github.io/api/#asset-inline-limit */
+  assetInlineLimit?: number;
   /** Documentation: https://esbuild.github.io/api/#entry-names */
   entryNames?: string;
   /** Documentation: https://esbuild.github.io/api/#chunk-names */
   chunkNames?: string;
   /** Documentation: https://esbuild.github.io/api/#asset-names */
   assetNames?: string;
+  /** Documentation: https://esbuild.github.io/api/#asset-root */
+  assetRoot?: string;
+  /** Documentation: https://esbuild.github.io/api/#asset-outdir */
+  assetOutdir?: string;
+  /** Documentation: https://esbuild.github.io/api/#copy */
+  copy?: Record<string, string>;
+  /** Documentation: https://esbuild.github.io/api/#hash-salt */
+  hashSalt?: string;
+  /** Documentation: https://esbuild.github.io/api/#manifest */
+  manifest?: string;
+  /** Documentation: https://esbuild.github.io/api/#polyfill-report */
+  polyfillReport?: string;
+  /** Documentation: https://esbuild.github.io/api/#tree-shaking-report */
+  treeShakingReport?: string;
+  /** Documentation: https://esbuild.github.io/api/#status-file */
+  statusFile?: string;
   /** Documentation: https://esbuild.github.io/api/#inject */
   inject?: string[];
+  /** Documentation: https://esbuild.github.io/api/#inject-if-used */
+  injectIfUsed?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#platform-inject */
+  platformInject?: { [platform in Platform]?: string[] };
   /** Documentation: https://esbuild.github.io/api/#banner */
   banner?: { [type: string]: string };
   /** Documentation: https://esbuild.github.io/api/#footer */
   footer?: { [type: string]: string };
   /** Documentation: https://esbuild.github.io/api/#incremental */
   incremental?: boolean;
+  /** Documentation: https://esbuild.github.io/api/#max-open-files */
+  maxOpenFiles?: number;
+  /** Documentation: https://esbuild.github.io/api/#max-workers */
+  maxWorkers?: number;
+  /** Documentation: https://esbuild.github.io/api/#memory-limit */
+  memoryLimit?: number;
+  /** Documentation: https://esbuild.github.io/api/#low-memory */
+  lowMemory?: boolean;
   /** Documentation: https://esbuild.github.io/api/#entry-points */
   entryPoints?: string[] | Record<string, string>;
+  /** Documentation: https://esbuild.github.io/api/#entry-conditions */
+  entryConditions?: Record<string, string[]>;
+  /** Documentation: https://esbuild.github.io/api/#entry-inject */
+  entryInject?: Record<string, string[]>;
+  /** Documentation: https://esbuild.github.io/api/#virtual-entry-points */
+  virtualEntryPoints?: VirtualEntryPoint[];
+  /** Documentation: https://esbuild.github.io/api/#virtual-modules */
+  virtualModules?: VirtualModule[];
   /** Documentation: https://esbuild.github.io/api/#stdin */
   stdin?: StdinOptions;
   /** Documentation: https://esbuild.github.io/plugins/ */
@@ -140,6 +282,10 @@ export interface BuildOptions extends CommonOptions {
 
 export interface WatchMode {
   onRebuild?: (error: BuildFailure | null, result: BuildResult | null) => void;
+  /** Documentation: https://esbuild.github.io/api/#watch */
+  interval?: number;
+  /** Documentation: https://esbuild.github.io/api/#watch */
+  ignore?: RegExp;
 }
 
 export interface StdinOptions {
@@ -149,6 +295,20 @@ export interface StdinOptions {
   loader?: Loader;
 }
 
+export interface VirtualEntryPoint {
+  name: string;
+  contents: string;
+  resolveDir?: string;
+  loader?: Loader;
+}
+
+export interface VirtualModule {
+  name: string;
+  contents: string;
+  resolveDir?: string;
+  loader?: Loader;
+}
+
 export interface Message {
   id: string;
   pluginName: string;
@@ -211,6 +371,16 @@ export interface BuildResult {
   metafile?: Metafile;
   /** Only when "mangleCache" is present */
   mangleCache?: Record<string, string | false>;
+  /** Only when "collectLegalComments: true" */
+  legalComments?: LegalComment[];
+  /** Only when "graphOnly: true" */
+  graph?: ImportGraph;
+}
+
+export interface LegalComment {
+  text: string;
+  /** The input files that contain this comment */
+  inputs: string[];
 }
 
 export interface BuildFailure extends Error {
@@ -258,6 +428,8 @@ export interface TransformOptions extends CommonOptions {
   loader?: Loader;
   banner?: string;
   footer?: string;
+  /** Documentation: https://esbuild.github.io/api/#error-recovery */
+  errorRecovery?: boolean;
 }
 
 export interface TransformResult {
@@ -355,6 +527,7 @@ export type ImportKind =
   | 'require-call'
   | 'dynamic-import'
   | 'require-resolve'
+  | 'new-url'
 
   // CSS
   | 'import-rule'
@@ -399,6 +572,7 @@ export interface OnLoadResult {
   resolveDir?: string;
   loader?: Loader;
   pluginData?: any;
+  sourceMap?: string | object;
 
   watchFiles?: string[];
   watchDirs?: string[];
@@ -426,6 +600,16 @@ export interface Metafile {
         path: string
         kind: ImportKind
       }[]
+      disabled?: {
+        reason: 'browser-field'
+        packageJson: string
+        key: string
+      }
+      refresh?: {
+        boundary: boolean
+        components: string[]
+        exports: string[]
+      }
     }
   }
   outputs: {
@@ -442,8 +626,44 @@ export interface Metafile {
       }[]
       exports: string[]
       entryPoint?: string
+      copiedFrom?: string
+      priority?: 'critical' | 'lazy'
+      preloadRank?: number
     }
   }
+  timing?: {
+    scan: number
+    parse: number
+    link: number
+    print: number
+    write: number
+    plugins: {
+      name: string
+      onStart: MetafileHookTiming
+      onResolve: MetafileHookTiming
+      onLoad: MetafileHookTiming
+    }[]
+  }
+}
+
+export interface ImportGraph {
+  inputs: {
+    [path: string]: {
+      loader: Loader
+      entryPoint?: boolean
+      imports: {
+        path: string
+        kind: ImportKind
+        original?: string
+        external?: boolean
+      }[]
+    }
+  }
+}
+
+export interface MetafileHookTiming {
+  calls: number
+  duration: number
 }
 
 export interface FormatMessagesOptions {
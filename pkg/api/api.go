@@ -77,6 +77,8 @@
 //
 package api
 
+import "context"
+
 type SourceMap uint8
 
 const (
@@ -94,6 +96,15 @@ const (
 	SourcesContentExclude
 )
 
+// Returning an empty string keeps the default path. This may be called from
+// multiple goroutines at once.
+type SourcesRewriteArgs struct {
+	Path      string // The default path, which is relative to the source map
+	AbsPath   string // The absolute path (only for the "file" namespace)
+	InputPath string // The path relative to the working directory
+	Namespace string
+}
+
 type LegalComments uint8
 
 const (
@@ -103,6 +114,7 @@ const (
 	LegalCommentsEndOfFile
 	LegalCommentsLinked
 	LegalCommentsExternal
+	LegalCommentsCombined
 )
 
 type JSXMode uint8
@@ -110,6 +122,7 @@ type JSXMode uint8
 const (
 	JSXModeTransform JSXMode = iota
 	JSXModePreserve
+	JSXModeAutomatic
 )
 
 type Target uint8
@@ -139,12 +152,16 @@ const (
 	LoaderDataURL
 	LoaderDefault
 	LoaderFile
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderJSX
+	LoaderNAPI
 	LoaderText
 	LoaderTS
 	LoaderTSX
+	LoaderWasm
+	LoaderWasmFile
 )
 
 type Platform uint8
@@ -153,6 +170,7 @@ const (
 	PlatformBrowser Platform = iota
 	PlatformNode
 	PlatformNeutral
+	PlatformDeno
 )
 
 type Format uint8
@@ -198,12 +216,50 @@ type Message struct {
 	Text       string
 	Location   *Location
 	Notes      []Note
+	Category   MessageCategory
 
 	// Optional user-specified data that is passed through unmodified. You can
 	// use this to stash the original error, for example.
 	Detail interface{}
 }
 
+type MessageCategory uint8
+
+const (
+	MessageCategoryOther   MessageCategory = iota
+	MessageCategorySyntax                  // An input file could not be parsed
+	MessageCategoryResolve                 // An import path could not be resolved
+	MessageCategoryPlugin                  // A plugin reported a message or returned an error
+)
+
+// This formats the message with its location (if any) so that a message can
+// be used as a Go error value
+func (msg Message) Error() string {
+	return messageErrorImpl(msg)
+}
+
+// These are the error types returned by "Message.Err". They can be matched
+// using a type switch or "errors.As" to handle each category of failure.
+type SyntaxError struct{ Message }
+type ResolveError struct{ Message }
+type PluginError struct{ Message }
+
+// This returns the message as a Go error with a type that depends on the
+// category of the message. Messages without a more specific category are
+// returned as a "Message" value.
+func (msg Message) Err() error {
+	switch msg.Category {
+	case MessageCategorySyntax:
+		return &SyntaxError{msg}
+	case MessageCategoryResolve:
+		return &ResolveError{msg}
+	case MessageCategoryPlugin:
+		return &PluginError{msg}
+	default:
+		return msg
+	}
+}
+
 type Note struct {
 	Text     string
 	Location *Location
@@ -228,12 +284,23 @@ const (
 	LogLevelError
 )
 
+type LogFormat uint8
+
+const (
+	LogFormatText LogFormat = iota
+	LogFormatJSON
+)
+
 type Charset uint8
 
 const (
 	CharsetDefault Charset = iota
 	CharsetASCII
 	CharsetUTF8
+
+	// This is like "CharsetUTF8" except that characters outside the Basic
+	// Multilingual Plane and invisible formatting characters are escaped
+	CharsetBMP
 )
 
 type TreeShaking uint8
@@ -249,6 +316,23 @@ type Drop uint8
 const (
 	DropConsole Drop = 1 << iota
 	DropDebugger
+	DropDeadImports
+)
+
+type KeepNamesOnly uint8
+
+const (
+	KeepNamesOnlyClasses KeepNamesOnly = 1 << iota
+	KeepNamesOnlyFunctions
+	KeepNamesOnlyExports
+)
+
+type DynamicRequire uint8
+
+const (
+	DynamicRequireKeep DynamicRequire = iota
+	DynamicRequireError
+	DynamicRequireContext
 )
 
 type MangleQuoted uint8
@@ -266,11 +350,17 @@ type BuildOptions struct {
 	LogLevel    LogLevel            // Documentation: https://esbuild.github.io/api/#log-level
 	LogLimit    int                 // Documentation: https://esbuild.github.io/api/#log-limit
 	LogOverride map[string]LogLevel // Documentation: https://esbuild.github.io/api/#log-override
+	LogFormat   LogFormat           // Documentation: https://esbuild.github.io/api/#log-format
+
+	OnLogMessage func(level LogLevel, msg Message) // Documentation: https://esbuild.github.io/api/#on-log-message
 
 	Sourcemap      SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
 	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
 	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
 
+	SourcesIgnoreList string                               // Documentation: https://esbuild.github.io/api/#sources-ignore-list
+	SourcesRewrite    func(args SourcesRewriteArgs) string // Documentation: https://esbuild.github.io/api/#sources-rewrite
+
 	Target    Target          // Documentation: https://esbuild.github.io/api/#target
 	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
 	Supported map[string]bool // Documentation: https://esbuild.github.io/api/#supported
@@ -280,69 +370,274 @@ type BuildOptions struct {
 	MangleQuoted      MangleQuoted           // Documentation: https://esbuild.github.io/api/#mangle-props
 	MangleCache       map[string]interface{} // Documentation: https://esbuild.github.io/api/#mangle-props
 	Drop              Drop                   // Documentation: https://esbuild.github.io/api/#drop
+	DropCalls         []string               // Documentation: https://esbuild.github.io/api/#drop-calls
 	MinifyWhitespace  bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifyIdentifiers bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifySyntax      bool                   // Documentation: https://esbuild.github.io/api/#minify
+	NameSeed          string                 // Documentation: https://esbuild.github.io/api/#name-seed
+	RuntimePrefix     string                 // Documentation: https://esbuild.github.io/api/#runtime-prefix
 	Charset           Charset                // Documentation: https://esbuild.github.io/api/#charset
 	TreeShaking       TreeShaking            // Documentation: https://esbuild.github.io/api/#tree-shaking
 	IgnoreAnnotations bool                   // Documentation: https://esbuild.github.io/api/#ignore-annotations
 	LegalComments     LegalComments          // Documentation: https://esbuild.github.io/api/#legal-comments
 
-	JSXMode     JSXMode // Documentation: https://esbuild.github.io/api/#jsx-mode
-	JSXFactory  string  // Documentation: https://esbuild.github.io/api/#jsx-factory
-	JSXFragment string  // Documentation: https://esbuild.github.io/api/#jsx-fragment
+	PreserveComments   bool // Documentation: https://esbuild.github.io/api/#preserve-comments
+	PreserveFormatting bool // Documentation: https://esbuild.github.io/api/#preserve-formatting
+
+	JSXMode         JSXMode       // Documentation: https://esbuild.github.io/api/#jsx-mode
+	JSXFactory      string        // Documentation: https://esbuild.github.io/api/#jsx-factory
+	JSXFragment     string        // Documentation: https://esbuild.github.io/api/#jsx-fragment
+	JSXImportSource string        // Documentation: https://esbuild.github.io/api/#jsx-import-source
+	JSXDev          bool          // Documentation: https://esbuild.github.io/api/#jsx-dev
+	JSXOverrides    []JSXOverride // Documentation: https://esbuild.github.io/api/#jsx-overrides
+
+	IsolatedModulesCheck bool // Documentation: https://esbuild.github.io/api/#isolated-modules-check
+	CollectLegalComments bool // Documentation: https://esbuild.github.io/api/#collect-legal-comments
 
 	Define    map[string]string // Documentation: https://esbuild.github.io/api/#define
 	Pure      []string          // Documentation: https://esbuild.github.io/api/#pure
 	KeepNames bool              // Documentation: https://esbuild.github.io/api/#keep-names
 
-	GlobalName        string            // Documentation: https://esbuild.github.io/api/#global-name
-	Bundle            bool              // Documentation: https://esbuild.github.io/api/#bundle
-	PreserveSymlinks  bool              // Documentation: https://esbuild.github.io/api/#preserve-symlinks
-	Splitting         bool              // Documentation: https://esbuild.github.io/api/#splitting
-	Outfile           string            // Documentation: https://esbuild.github.io/api/#outfile
-	Metafile          bool              // Documentation: https://esbuild.github.io/api/#metafile
-	Outdir            string            // Documentation: https://esbuild.github.io/api/#outdir
-	Outbase           string            // Documentation: https://esbuild.github.io/api/#outbase
-	AbsWorkingDir     string            // Documentation: https://esbuild.github.io/api/#working-directory
-	Platform          Platform          // Documentation: https://esbuild.github.io/api/#platform
-	Format            Format            // Documentation: https://esbuild.github.io/api/#format
-	External          []string          // Documentation: https://esbuild.github.io/api/#external
-	MainFields        []string          // Documentation: https://esbuild.github.io/api/#main-fields
-	Conditions        []string          // Documentation: https://esbuild.github.io/api/#conditions
-	Loader            map[string]Loader // Documentation: https://esbuild.github.io/api/#loader
-	ResolveExtensions []string          // Documentation: https://esbuild.github.io/api/#resolve-extensions
-	Tsconfig          string            // Documentation: https://esbuild.github.io/api/#tsconfig
-	OutExtensions     map[string]string // Documentation: https://esbuild.github.io/api/#out-extension
-	PublicPath        string            // Documentation: https://esbuild.github.io/api/#public-path
-	Inject            []string          // Documentation: https://esbuild.github.io/api/#inject
-	Banner            map[string]string // Documentation: https://esbuild.github.io/api/#banner
-	Footer            map[string]string // Documentation: https://esbuild.github.io/api/#footer
-	NodePaths         []string          // Documentation: https://esbuild.github.io/api/#node-paths
-
-	EntryNames string // Documentation: https://esbuild.github.io/api/#entry-names
-	ChunkNames string // Documentation: https://esbuild.github.io/api/#chunk-names
-	AssetNames string // Documentation: https://esbuild.github.io/api/#asset-names
-
-	EntryPoints         []string     // Documentation: https://esbuild.github.io/api/#entry-points
-	EntryPointsAdvanced []EntryPoint // Documentation: https://esbuild.github.io/api/#entry-points
+	KeepNamesFilter string        // Documentation: https://esbuild.github.io/api/#keep-names
+	KeepNamesOnly   KeepNamesOnly // Documentation: https://esbuild.github.io/api/#keep-names
+
+	GlobalName         string            // Documentation: https://esbuild.github.io/api/#global-name
+	Bundle             bool              // Documentation: https://esbuild.github.io/api/#bundle
+	PreserveSymlinks   bool              // Documentation: https://esbuild.github.io/api/#preserve-symlinks
+	Splitting          bool              // Documentation: https://esbuild.github.io/api/#splitting
+	PurgeCSS           bool              // Documentation: https://esbuild.github.io/api/#purge-css
+	PurgeCSSSafelist   []string          // Documentation: https://esbuild.github.io/api/#purge-css
+	BoundaryPackages   []string          // Documentation: https://esbuild.github.io/api/#boundary-packages
+	DisallowedLicenses []string          // Documentation: https://esbuild.github.io/api/#disallowed-licenses
+	VerifyLockfile     bool              // Documentation: https://esbuild.github.io/api/#verify-lockfile
+	Outfile            string            // Documentation: https://esbuild.github.io/api/#outfile
+	Metafile           bool              // Documentation: https://esbuild.github.io/api/#metafile
+	GraphOnly          bool              // Documentation: https://esbuild.github.io/api/#graph-only
+	Timing             bool              // Documentation: https://esbuild.github.io/api/#timing
+	NameMap            bool              // Documentation: https://esbuild.github.io/api/#name-map
+	PublishPackageJSON bool              // Documentation: https://esbuild.github.io/api/#publish-package-json
+	Declarations       bool              // Documentation: https://esbuild.github.io/api/#declarations
+	ConcatReport       bool              // Documentation: https://esbuild.github.io/api/#concat-report
+	ScanSecrets        bool              // Documentation: https://esbuild.github.io/api/#scan-secrets
+	RefreshMetadata    bool              // Documentation: https://esbuild.github.io/api/#refresh-metadata
+	Integrity          bool              // Documentation: https://esbuild.github.io/api/#integrity
+	DebugID            bool              // Documentation: https://esbuild.github.io/api/#debug-id
+	Deterministic      bool              // Documentation: https://esbuild.github.io/api/#deterministic
+	VerifyDeterminism  bool              // Documentation: https://esbuild.github.io/api/#deterministic
+	Outdir             string            // Documentation: https://esbuild.github.io/api/#outdir
+	Outbase            string            // Documentation: https://esbuild.github.io/api/#outbase
+	Copy               map[string]string // Documentation: https://esbuild.github.io/api/#copy
+	AbsWorkingDir      string            // Documentation: https://esbuild.github.io/api/#working-directory
+	Platform           Platform          // Documentation: https://esbuild.github.io/api/#platform
+	Format             Format            // Documentation: https://esbuild.github.io/api/#format
+	External           []string          // Documentation: https://esbuild.github.io/api/#external
+	ExternalGlobals    map[string]string // Documentation: https://esbuild.github.io/api/#external-global
+	Shared             []string          // Documentation: https://esbuild.github.io/api/#shared
+	SharedScope        string            // Documentation: https://esbuild.github.io/api/#shared-scope
+	DynamicRequire     DynamicRequire    // Documentation: https://esbuild.github.io/api/#dynamic-require
+	MainFields         []string          // Documentation: https://esbuild.github.io/api/#main-fields
+	Conditions         []string          // Documentation: https://esbuild.github.io/api/#conditions
+	Loader             map[string]Loader // Documentation: https://esbuild.github.io/api/#loader
+	ResolveExtensions  []string          // Documentation: https://esbuild.github.io/api/#resolve-extensions
+	Tsconfig           string            // Documentation: https://esbuild.github.io/api/#tsconfig
+	TsconfigNested     bool              // Documentation: https://esbuild.github.io/api/#tsconfig-nested
+	OutExtensions      map[string]string // Documentation: https://esbuild.github.io/api/#out-extension
+	PublicPath         string            // Documentation: https://esbuild.github.io/api/#public-path
+	AssetPublicPaths   map[string]string // Documentation: https://esbuild.github.io/api/#public-path
+	AssetInlineLimit   int               // Documentation: https://esbuild.github.io/api/#asset-inline-limit
+	Inject             []string          // Documentation: https://esbuild.github.io/api/#inject
+	InjectIfUsed       bool              // Documentation: https://esbuild.github.io/api/#inject-if-used
+	Banner             map[string]string // Documentation: https://esbuild.github.io/api/#banner
+	Footer             map[string]string // Documentation: https://esbuild.github.io/api/#footer
+	NodePaths          []string          // Documentation: https://esbuild.github.io/api/#node-paths
+
+	// These files are only injected when building for the given platform. They
+	// are injected after the files in "Inject". The platform of an entry point
+	// with a platform override is used for that entry point.
+	PlatformInject map[Platform][]string // Documentation: https://esbuild.github.io/api/#platform-inject
+
+	// This replaces the "sideEffects" field in "package.json" for the packages
+	// with these names. Use it to fix packages with a missing or incorrect field.
+	SideEffectsOverrides map[string]bool // Documentation: https://esbuild.github.io/api/#side-effects-override
+
+	// These control downloading and bundling "http://" and "https://" imports.
+	// Downloaded modules are cached on disk (in the user's cache directory by
+	// default) and their integrity hashes are recorded in the lock file.
+	RemoteImports  bool   // Documentation: https://esbuild.github.io/api/#remote-imports
+	RemoteCacheDir string // Documentation: https://esbuild.github.io/api/#remote-imports
+	RemoteLockFile string // Documentation: https://esbuild.github.io/api/#remote-imports
+	RemoteOffline  bool   // Documentation: https://esbuild.github.io/api/#remote-imports
+
+	// This is a standard import map that is applied before "node_modules" is
+	// searched. With "ImportMapExternal", bare specifiers in the map are left
+	// external and the map is written to the output directory instead.
+	ImportMap         string // Documentation: https://esbuild.github.io/api/#import-map
+	ImportMapExternal bool   // Documentation: https://esbuild.github.io/api/#import-map
+
+	// If present, this is called for each output file and the result is used
+	// instead of "Banner" and "Footer". It may be called concurrently.
+	BannerCallback func(args BannerArgs) BannerResult // Documentation: https://esbuild.github.io/api/#banner
+
+	// These convert stylesheets in other languages such as Sass into CSS before
+	// they are parsed. Each one is used for the files that match its filter.
+	StylePreprocessors []StylePreprocessor // Documentation: https://esbuild.github.io/api/#style-preprocessors
+
+	EntryNames        string // Documentation: https://esbuild.github.io/api/#entry-names
+	ChunkNames        string // Documentation: https://esbuild.github.io/api/#chunk-names
+	AssetNames        string // Documentation: https://esbuild.github.io/api/#asset-names
+	AssetRoot         string // Documentation: https://esbuild.github.io/api/#asset-root
+	AssetOutdir       string // Documentation: https://esbuild.github.io/api/#asset-outdir
+	HashSalt          string // Documentation: https://esbuild.github.io/api/#hash-salt
+	Manifest          string // Documentation: https://esbuild.github.io/api/#manifest
+	PolyfillReport    string // Documentation: https://esbuild.github.io/api/#polyfill-report
+	TreeShakingReport string // Documentation: https://esbuild.github.io/api/#tree-shaking-report
+	StatusFile        string // Documentation: https://esbuild.github.io/api/#status-file
+
+	EntryPoints         []string            // Documentation: https://esbuild.github.io/api/#entry-points
+	EntryPointsAdvanced []EntryPoint        // Documentation: https://esbuild.github.io/api/#entry-points
+	VirtualEntryPoints  []VirtualEntryPoint // Documentation: https://esbuild.github.io/api/#virtual-entry-points
+	VirtualModules      []VirtualModule     // Documentation: https://esbuild.github.io/api/#virtual-modules
 
 	Stdin          *StdinOptions // Documentation: https://esbuild.github.io/api/#stdin
 	Write          bool          // Documentation: https://esbuild.github.io/api/#write
 	AllowOverwrite bool          // Documentation: https://esbuild.github.io/api/#allow-overwrite
+	Clean          bool          // Documentation: https://esbuild.github.io/api/#clean
+	CleanRetain    int           // Documentation: https://esbuild.github.io/api/#clean-retain
+	AtomicWrite    bool          // Documentation: https://esbuild.github.io/api/#atomic-write
 	Incremental    bool          // Documentation: https://esbuild.github.io/api/#incremental
 	Plugins        []Plugin      // Documentation: https://esbuild.github.io/plugins/
 
+	// If present, this is called as the output files are written to the file
+	// system. Calls are never concurrent, but they can come from any goroutine.
+	OnWriteProgress func(progress WriteProgress) // Documentation: https://esbuild.github.io/api/#write
+
+	MaxOpenFiles int  // Documentation: https://esbuild.github.io/api/#max-open-files
+	MaxWorkers   int  // Documentation: https://esbuild.github.io/api/#max-workers
+	MemoryLimit  int  // Documentation: https://esbuild.github.io/api/#memory-limit
+	LowMemory    bool // Documentation: https://esbuild.github.io/api/#low-memory
+
 	Watch *WatchMode // Documentation: https://esbuild.github.io/api/#watch
 }
 
+type WriteProgress struct {
+	FilesWritten int
+	TotalFiles   int
+	BytesWritten int
+	TotalBytes   int
+}
+
+// The banner and footer returned by the callback can contain the "[name]" and
+// "[hash]" placeholders, which are the same as for the output path.
+type BannerArgs struct {
+	EntryPoint string // The entry point for this output file, or empty for shared chunks
+	Kind       string // Either "js" or "css"
+	Name       string // The substitution for "[name]" for this output file
+}
+
+type BannerResult struct {
+	Banner string
+	Footer string
+}
+
+type StylePreprocessor struct {
+	Name      string
+	Filter    string // A regular expression for the paths of the files to convert
+	Transform func(args StylePreprocessorArgs) (StylePreprocessorResult, error)
+}
+
+type StylePreprocessorArgs struct {
+	Path      string
+	Namespace string
+	Contents  string
+
+	// This resolves and loads a file imported with "@use" or "@import". Paths
+	// are relative to "importer" if present (for imports from other imported
+	// files) or to the file being converted otherwise. Sass partials and index
+	// files are found too, and plugins and packages are taken into account.
+	Import func(path string, importer string) (StyleImport, error)
+}
+
+type StyleImport struct {
+	Path     string
+	Contents string
+}
+
+type StylePreprocessorResult struct {
+	Errors   []Message
+	Warnings []Message
+
+	Contents  string // The generated CSS
+	SourceMap string // Maps "Contents" back to the original files (optional)
+
+	WatchFiles []string
+}
+
 type EntryPoint struct {
 	InputPath  string
 	OutputPath string
+
+	// These override the corresponding build options for this entry point only.
+	// Entry points with overrides are bundled separately from the rest of the
+	// build, but parsed modules are still shared when the settings that affect
+	// parsing are the same. Maps are merged with the build-level maps.
+	Format   Format
+	Platform *Platform
+	Define   map[string]string
+	Banner   map[string]string
+	Loader   map[string]Loader
+
+	// These files are injected into this entry point and everything it imports
+	// in addition to the build-level injected files
+	Inject []string
+
+	// These conditions are used in addition to the build-level conditions when
+	// resolving this entry point and everything it imports. This doesn't cause
+	// the entry point to be bundled separately, but modules reached using
+	// different conditions are separate modules.
+	Conditions []string
+}
+
+// A virtual entry point is an entry point whose contents are provided directly
+// instead of being read from the file system. The name is a relative path that
+// determines the output path and the loader. Imports are resolved relative to
+// the resolve directory, which defaults to the working directory. Other files
+// can also import a virtual entry point using its name as the import path.
+type VirtualEntryPoint struct {
+	Name       string
+	Contents   string
+	ResolveDir string
+	Loader     Loader
+}
+
+// A virtual module is like a virtual entry point except that it's only part
+// of the build if another file imports it. Import paths that are exactly equal
+// to the name of a virtual module resolve to it without running plugins.
+type VirtualModule struct {
+	Name       string
+	Contents   string
+	ResolveDir string
+	Loader     Loader
+}
+
+type JSXOverride struct {
+	Path         string // A file, a directory, or a path with a single "*" wildcard
+	Factory      string
+	Fragment     string
+	ImportSource string
 }
 
 type WatchMode struct {
 	OnRebuild func(BuildResult)
+
+	Interval int    // The time in milliseconds between polls (default: 100)
+	Ignore   string // Paths matching this regular expression aren't watched
+
+	// If this is present, esbuild won't poll the file system for changes.
+	// Instead, each value sent on this channel is a list of absolute paths
+	// that were changed (e.g. from Watchman or a container's file watcher).
+	// A rebuild happens if one of them (or its parent directory) was used by
+	// the previous build. Closing this channel stops watching.
+	Changes <-chan []string
 }
 
 type StdinOptions struct {
@@ -356,14 +651,23 @@ type BuildResult struct {
 	Errors   []Message
 	Warnings []Message
 
-	OutputFiles []OutputFile
-	Metafile    string
-	MangleCache map[string]interface{}
+	OutputFiles   []OutputFile
+	Metafile      string
+	MangleCache   map[string]interface{}
+	LegalComments []LegalComment // Only when "CollectLegalComments: true"
+	Graph         string         // Only when "GraphOnly: true"
 
 	Rebuild func() BuildResult // Only when "Incremental: true"
 	Stop    func()             // Only when "Watch: true"
 }
 
+// Each unique legal comment is only reported once, along with the paths of
+// all input files that it was extracted from
+type LegalComment struct {
+	Text   string
+	Inputs []string
+}
+
 type OutputFile struct {
 	Path     string
 	Contents []byte
@@ -371,7 +675,16 @@ type OutputFile struct {
 
 // Documentation: https://esbuild.github.io/api/#build-api
 func Build(options BuildOptions) BuildResult {
-	return buildImpl(options).result
+	return buildImpl(context.Background(), options).result
+}
+
+// This is the same as "Build" except that the build stops early with an error
+// if the context is cancelled before the output files are written. This is
+// useful for editors and language servers that start a new build before the
+// previous one has finished. Rebuilds from "Rebuild" and watch mode are not
+// affected by the context.
+func BuildWithContext(ctx context.Context, options BuildOptions) BuildResult {
+	return buildImpl(ctx, options).result
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -382,11 +695,17 @@ type TransformOptions struct {
 	LogLevel    LogLevel            // Documentation: https://esbuild.github.io/api/#log-level
 	LogLimit    int                 // Documentation: https://esbuild.github.io/api/#log-limit
 	LogOverride map[string]LogLevel // Documentation: https://esbuild.github.io/api/#log-override
+	LogFormat   LogFormat           // Documentation: https://esbuild.github.io/api/#log-format
+
+	OnLogMessage func(level LogLevel, msg Message) // Documentation: https://esbuild.github.io/api/#on-log-message
 
 	Sourcemap      SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
 	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
 	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
 
+	SourcesIgnoreList string                               // Documentation: https://esbuild.github.io/api/#sources-ignore-list
+	SourcesRewrite    func(args SourcesRewriteArgs) string // Documentation: https://esbuild.github.io/api/#sources-rewrite
+
 	Target    Target          // Documentation: https://esbuild.github.io/api/#target
 	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
 	Supported map[string]bool // Documentation: https://esbuild.github.io/api/#supported
@@ -399,17 +718,29 @@ type TransformOptions struct {
 	MangleQuoted      MangleQuoted           // Documentation: https://esbuild.github.io/api/#mangle-props
 	MangleCache       map[string]interface{} // Documentation: https://esbuild.github.io/api/#mangle-props
 	Drop              Drop                   // Documentation: https://esbuild.github.io/api/#drop
+	DropCalls         []string               // Documentation: https://esbuild.github.io/api/#drop-calls
 	MinifyWhitespace  bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifyIdentifiers bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifySyntax      bool                   // Documentation: https://esbuild.github.io/api/#minify
+	NameSeed          string                 // Documentation: https://esbuild.github.io/api/#name-seed
+	RuntimePrefix     string                 // Documentation: https://esbuild.github.io/api/#runtime-prefix
 	Charset           Charset                // Documentation: https://esbuild.github.io/api/#charset
 	TreeShaking       TreeShaking            // Documentation: https://esbuild.github.io/api/#tree-shaking
 	IgnoreAnnotations bool                   // Documentation: https://esbuild.github.io/api/#ignore-annotations
 	LegalComments     LegalComments          // Documentation: https://esbuild.github.io/api/#legal-comments
 
-	JSXMode     JSXMode // Documentation: https://esbuild.github.io/api/#jsx
-	JSXFactory  string  // Documentation: https://esbuild.github.io/api/#jsx-factory
-	JSXFragment string  // Documentation: https://esbuild.github.io/api/#jsx-fragment
+	PreserveComments   bool // Documentation: https://esbuild.github.io/api/#preserve-comments
+	PreserveFormatting bool // Documentation: https://esbuild.github.io/api/#preserve-formatting
+
+	ErrorRecovery bool // Documentation: https://esbuild.github.io/api/#error-recovery
+
+	JSXMode         JSXMode // Documentation: https://esbuild.github.io/api/#jsx
+	JSXFactory      string  // Documentation: https://esbuild.github.io/api/#jsx-factory
+	JSXFragment     string  // Documentation: https://esbuild.github.io/api/#jsx-fragment
+	JSXImportSource string  // Documentation: https://esbuild.github.io/api/#jsx-import-source
+	JSXDev          bool    // Documentation: https://esbuild.github.io/api/#jsx-dev
+
+	IsolatedModulesCheck bool // Documentation: https://esbuild.github.io/api/#isolated-modules-check
 
 	TsconfigRaw string // Documentation: https://esbuild.github.io/api/#tsconfig-raw
 	Banner      string // Documentation: https://esbuild.github.io/api/#banner
@@ -419,6 +750,9 @@ type TransformOptions struct {
 	Pure      []string          // Documentation: https://esbuild.github.io/api/#pure
 	KeepNames bool              // Documentation: https://esbuild.github.io/api/#keep-names
 
+	KeepNamesFilter string        // Documentation: https://esbuild.github.io/api/#keep-names
+	KeepNamesOnly   KeepNamesOnly // Documentation: https://esbuild.github.io/api/#keep-names
+
 	Sourcefile string // Documentation: https://esbuild.github.io/api/#sourcefile
 	Loader     Loader // Documentation: https://esbuild.github.io/api/#loader
 }
@@ -435,7 +769,78 @@ type TransformResult struct {
 
 // Documentation: https://esbuild.github.io/api/#transform-api
 func Transform(input string, options TransformOptions) TransformResult {
-	return transformImpl(input, options)
+	return transformImpl(context.Background(), input, options)
+}
+
+// This is the same as "Transform" except that it stops early with an error if
+// the context is cancelled before the transform has finished
+func TransformWithContext(ctx context.Context, input string, options TransformOptions) TransformResult {
+	return transformImpl(ctx, input, options)
+}
+
+type TransformBatchInput struct {
+	Contents string
+
+	// These override the "Sourcefile" and "Loader" transform options for this
+	// input if they are present
+	Sourcefile string
+	Loader     Loader
+}
+
+// This transforms many independent inputs with the same options. The options
+// are only validated once and the inputs are transformed in parallel, which
+// is faster than calling "Transform" once per input. The results are in the
+// same order as the inputs. Each input is transformed separately, so mangled
+// property names are not shared between inputs even if "MangleCache" is set.
+// Note that "OnLogMessage" may be called from multiple goroutines at once.
+func TransformBatch(inputs []TransformBatchInput, options TransformOptions) []TransformResult {
+	return transformBatchImpl(context.Background(), inputs, options)
+}
+
+// This is the same as "TransformBatch" except that it stops early with an
+// error if the context is cancelled before the transforms have finished
+func TransformBatchWithContext(ctx context.Context, inputs []TransformBatchInput, options TransformOptions) []TransformResult {
+	return transformBatchImpl(ctx, inputs, options)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Parse API
+
+type ParseOptions struct {
+	Color       StderrColor         // Documentation: https://esbuild.github.io/api/#color
+	LogLevel    LogLevel            // Documentation: https://esbuild.github.io/api/#log-level
+	LogLimit    int                 // Documentation: https://esbuild.github.io/api/#log-limit
+	LogOverride map[string]LogLevel // Documentation: https://esbuild.github.io/api/#log-override
+
+	Sourcefile string // Documentation: https://esbuild.github.io/api/#sourcefile
+	Loader     Loader // Only "js", "jsx", "ts", and "tsx" are supported
+
+	// If true, statements with syntax errors are skipped instead of stopping
+	// the parse. All syntax errors are reported and the AST of the remaining
+	// code is returned even though there were errors.
+	ErrorRecovery bool
+}
+
+type ParseResult struct {
+	Errors   []Message
+	Warnings []Message
+
+	// This is the AST in JSON format, which is empty if there were errors unless
+	// "ErrorRecovery" is enabled. It follows the ESTree specification
+	// (https://github.com/estree/estree) with a few differences. It's the code
+	// as written without any constant folding or lowering. Nodes have "start"
+	// and "end" byte offsets, and TypeScript types are not included. Identifiers
+	// have a "symbol" index into the "symbols" array on the "Program" node,
+	// which also has a "scope" tree and an "imports" list of the paths used by
+	// import statements, "import()" calls, and "require()" calls.
+	AST []byte
+}
+
+// This parses JavaScript or TypeScript code using esbuild's parser without
+// transforming it. It's meant for tools such as linters and dependency
+// analyzers that need a syntax tree.
+func Parse(input string, options ParseOptions) ParseResult {
+	return parseImpl(input, options)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -573,6 +978,7 @@ type OnLoadResult struct {
 	ResolveDir string
 	Loader     Loader
 	PluginData interface{}
+	SourceMap  *string // Maps "Contents" back to the original file (optional)
 
 	WatchFiles []string
 	WatchDirs  []string
@@ -588,6 +994,7 @@ const (
 	ResolveJSRequireResolve
 	ResolveCSSImportRule
 	ResolveCSSURLToken
+	ResolveJSNewURL
 )
 
 ////////////////////////////////////////////////////////////////////////////////
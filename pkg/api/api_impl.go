@@ -1,17 +1,25 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 	"unicode/utf8"
 
@@ -25,8 +33,10 @@ import (
 	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_estree"
 	"github.com/evanw/esbuild/internal/js_lexer"
 	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/js_printer"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/resolver"
 )
@@ -69,6 +79,10 @@ func validatePathTemplate(template string) []config.PathTemplate {
 			placeholder = config.ExtPlaceholder
 			search += len("[ext]")
 
+		case strings.HasPrefix(tail, "[pkg]"):
+			placeholder = config.PkgPlaceholder
+			search += len("[pkg]")
+
 		default:
 			// Skip past the "[" so we don't find it again
 			search++
@@ -105,6 +119,8 @@ func validatePlatform(value Platform) config.Platform {
 		return config.PlatformNode
 	case PlatformNeutral:
 		return config.PlatformNeutral
+	case PlatformDeno:
+		return config.PlatformDeno
 	default:
 		panic("Invalid platform")
 	}
@@ -160,6 +176,8 @@ func validateLegalComments(value LegalComments, bundle bool) config.LegalComment
 		return config.LegalCommentsLinkedWithComment
 	case LegalCommentsExternal:
 		return config.LegalCommentsExternalWithoutComment
+	case LegalCommentsCombined:
+		return config.LegalCommentsCombined
 	default:
 		panic("Invalid source map")
 	}
@@ -197,11 +215,44 @@ func validateLogLevel(value LogLevel) logger.LogLevel {
 	}
 }
 
+func validateLogFormat(value LogFormat) logger.LogFormat {
+	switch value {
+	case LogFormatText:
+		return logger.LogFormatText
+	case LogFormatJSON:
+		return logger.LogFormatJSON
+	default:
+		panic("Invalid log format")
+	}
+}
+
+func validateOnLogMessage(callback func(level LogLevel, msg Message)) func(logger.Msg) {
+	if callback == nil {
+		return nil
+	}
+	return func(msg logger.Msg) {
+		var level LogLevel
+		switch msg.Kind {
+		case logger.Error:
+			level = LogLevelError
+		case logger.Warning:
+			level = LogLevelWarning
+		case logger.Info:
+			level = LogLevelInfo
+		case logger.Debug:
+			level = LogLevelDebug
+		default:
+			level = LogLevelVerbose
+		}
+		callback(level, convertMessageToPublic(msg))
+	}
+}
+
 func validateASCIIOnly(value Charset) bool {
 	switch value {
 	case CharsetDefault, CharsetASCII:
 		return true
-	case CharsetUTF8:
+	case CharsetUTF8, CharsetBMP:
 		return false
 	default:
 		panic("Invalid charset")
@@ -241,12 +292,16 @@ func validateLoader(value Loader) config.Loader {
 		return config.LoaderDataURL
 	case LoaderFile:
 		return config.LoaderFile
+	case LoaderHTML:
+		return config.LoaderHTML
 	case LoaderJS:
 		return config.LoaderJS
 	case LoaderJSON:
 		return config.LoaderJSON
 	case LoaderJSX:
 		return config.LoaderJSX
+	case LoaderNAPI:
+		return config.LoaderNAPI
 	case LoaderNone:
 		return config.LoaderNone
 	case LoaderText:
@@ -255,6 +310,10 @@ func validateLoader(value Loader) config.Loader {
 		return config.LoaderTS
 	case LoaderTSX:
 		return config.LoaderTSX
+	case LoaderWasm:
+		return config.LoaderWasm
+	case LoaderWasmFile:
+		return config.LoaderWasmFile
 	case LoaderDefault:
 		return config.LoaderDefault
 	default:
@@ -283,9 +342,9 @@ func validateEngine(value EngineName) compat.Engine {
 
 var versionRegex = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?$`)
 
-func validateFeatures(log logger.Log, target Target, engines []Engine) (config.TargetFromAPI, compat.JSFeature, compat.CSSFeature, string) {
+func validateFeatures(log logger.Log, target Target, engines []Engine) (config.TargetFromAPI, compat.JSFeature, compat.CSSFeature, compat.RuntimeFeature, string) {
 	if target == DefaultTarget && len(engines) == 0 {
-		return config.TargetWasUnconfigured, 0, 0, ""
+		return config.TargetWasUnconfigured, 0, 0, 0, ""
 	}
 
 	constraints := make(map[compat.Engine][]int)
@@ -372,7 +431,8 @@ func validateFeatures(log logger.Log, target Target, engines []Engine) (config.T
 	sort.Strings(targets)
 	targetEnv := helpers.StringArrayToQuotedCommaSeparatedString(targets)
 
-	return targetFromAPI, compat.UnsupportedJSFeatures(constraints), compat.UnsupportedCSSFeatures(constraints), targetEnv
+	return targetFromAPI, compat.UnsupportedJSFeatures(constraints), compat.UnsupportedCSSFeatures(constraints),
+		compat.UnsupportedRuntimeFeatures(constraints), targetEnv
 }
 
 func validateSupported(log logger.Log, supported map[string]bool) (
@@ -415,6 +475,85 @@ func validateGlobalName(log logger.Log, text string) []string {
 	return nil
 }
 
+func validateDynamicRequire(value DynamicRequire) config.DynamicRequire {
+	switch value {
+	case DynamicRequireKeep:
+		return config.DynamicRequireKeep
+	case DynamicRequireError:
+		return config.DynamicRequireError
+	case DynamicRequireContext:
+		return config.DynamicRequireContext
+	default:
+		panic("Invalid dynamic require")
+	}
+}
+
+// Each value must be a global name like "React" or "window.React", which is
+// the same syntax as the "GlobalName" option
+func validateExternalGlobals(log logger.Log, externalGlobals map[string]string) map[string][]string {
+	if len(externalGlobals) == 0 {
+		return nil
+	}
+
+	// Validate in sorted order for determinism
+	paths := make([]string, 0, len(externalGlobals))
+	for path := range externalGlobals {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	result := make(map[string][]string)
+	for _, path := range paths {
+		text := externalGlobals[path]
+		source := logger.Source{
+			KeyPath:    logger.Path{Text: "(external global)"},
+			PrettyPath: fmt.Sprintf("(external global for %q)", path),
+			Contents:   text,
+		}
+		if text == "" {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Missing the global name for the external module %q", path))
+		} else if parts, ok := js_parser.ParseGlobalName(log, source); ok {
+			result[path] = parts
+		}
+	}
+	return result
+}
+
+func validateRuntimePrefix(log logger.Log, text string) string {
+	if text != "" && !js_lexer.IsIdentifier(text) {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("The runtime prefix %q must be a valid identifier", text))
+		return ""
+	}
+	return text
+}
+
+func validateSourcesRewrite(callback func(SourcesRewriteArgs) string) func(config.SourcesRewriteArgs) string {
+	if callback == nil {
+		return nil
+	}
+	return func(args config.SourcesRewriteArgs) string {
+		return callback(SourcesRewriteArgs{
+			Path:      args.Path,
+			AbsPath:   args.AbsPath,
+			InputPath: args.InputPath,
+			Namespace: args.Namespace,
+		})
+	}
+}
+
+func validateKeepNamesOnly(value KeepNamesOnly) (only config.KeepNamesOnly) {
+	if (value & KeepNamesOnlyClasses) != 0 {
+		only |= config.KeepNamesOnlyClasses
+	}
+	if (value & KeepNamesOnlyFunctions) != 0 {
+		only |= config.KeepNamesOnlyFunctions
+	}
+	if (value & KeepNamesOnlyExports) != 0 {
+		only |= config.KeepNamesOnlyExports
+	}
+	return
+}
+
 func validateRegex(log logger.Log, what string, value string) *regexp.Regexp {
 	if value == "" {
 		return nil
@@ -428,6 +567,16 @@ func validateRegex(log logger.Log, what string, value string) *regexp.Regexp {
 	return regex
 }
 
+func validatePurgeCSSSafelist(log logger.Log, patterns []string) []*regexp.Regexp {
+	var safelist []*regexp.Regexp
+	for _, pattern := range patterns {
+		if regex := validateRegex(log, "purge css safelist", pattern); regex != nil {
+			safelist = append(safelist, regex)
+		}
+	}
+	return safelist
+}
+
 func validateExternals(log logger.Log, fs fs.FS, paths []string) config.ExternalSettings {
 	result := config.ExternalSettings{
 		PreResolve:  config.ExternalMatchers{Exact: make(map[string]bool)},
@@ -492,6 +641,102 @@ func validateLoaders(log logger.Log, loaders map[string]Loader) map[string]confi
 	return result
 }
 
+func validateAssetPublicPaths(log logger.Log, publicPaths map[string]string) map[string]string {
+	var result map[string]string
+	for ext, publicPath := range publicPaths {
+		if !isValidExtension(ext) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid file extension: %q", ext))
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[ext] = publicPath
+	}
+	return result
+}
+
+func validateCopy(log logger.Log, fs fs.FS, paths map[string]string) []config.CopyPath {
+	// Sort the sources for determinism
+	sources := make([]string, 0, len(paths))
+	for source := range paths {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var result []config.CopyPath
+	for _, source := range sources {
+		dest := strings.ReplaceAll(paths[source], "\\", "/")
+		if fs.IsAbs(dest) || strings.HasPrefix(dest, "/") {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("The copy destination %q must be relative to the output directory", paths[source]))
+			continue
+		}
+		result = append(result, config.CopyPath{
+			AbsSource: validatePath(log, fs, source, "copy source path"),
+			RelDest:   dest,
+		})
+	}
+	return result
+}
+
+func validateStylePreprocessors(log logger.Log, fs fs.FS, preprocessors []StylePreprocessor) []config.StylePreprocessor {
+	var result []config.StylePreprocessor
+	for _, preprocessor := range preprocessors {
+		filter, err := config.CompileFilterForPlugin(preprocessor.Name, "StylePreprocessor", preprocessor.Filter)
+		if filter == nil {
+			log.AddError(nil, logger.Range{}, err.Error())
+			continue
+		}
+		transform := preprocessor.Transform
+		if transform == nil {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("The style preprocessor %q is missing a transform function", preprocessor.Name))
+			continue
+		}
+		pathKind := fmt.Sprintf("watch file path for style preprocessor %q", preprocessor.Name)
+		result = append(result, config.StylePreprocessor{
+			Name:   preprocessor.Name,
+			Filter: filter,
+			Transform: func(args config.StylePreprocessorArgs) (result config.StylePreprocessorResult) {
+				response, err := transform(StylePreprocessorArgs{
+					Path:      args.Path.Text,
+					Namespace: args.Path.Namespace,
+					Contents:  args.Contents,
+					Import: func(path string, importer string) (StyleImport, error) {
+						imported, err := args.Import(path, importer)
+						return StyleImport{Path: imported.Path, Contents: imported.Contents}, err
+					},
+				})
+				for _, relPath := range response.WatchFiles {
+					if absPath := validatePath(log, fs, relPath, pathKind); absPath != "" {
+						result.AbsWatchFiles = append(result.AbsWatchFiles, absPath)
+					}
+				}
+
+				if err != nil {
+					result.ThrownError = err
+					return
+				}
+
+				result.Contents = response.Contents
+				if response.SourceMap != "" {
+					result.SourceMap = &response.SourceMap
+				}
+
+				// Convert log messages
+				if len(response.Errors)+len(response.Warnings) > 0 {
+					msgs := make(logger.SortableMsgs, 0, len(response.Errors)+len(response.Warnings))
+					msgs = convertMessagesToInternal(msgs, logger.Error, response.Errors)
+					msgs = convertMessagesToInternal(msgs, logger.Warning, response.Warnings)
+					sort.Stable(msgs)
+					result.Msgs = msgs
+				}
+				return
+			},
+		})
+	}
+	return result
+}
+
 func validateJSXExpr(log logger.Log, text string, name string) config.DefineExpr {
 	if text != "" {
 		if expr, _ := js_parser.ParseDefineExprOrJSON(text); len(expr.Parts) > 0 || (name == "fragment" && expr.Constant != nil) {
@@ -502,6 +747,32 @@ func validateJSXExpr(log logger.Log, text string, name string) config.DefineExpr
 	return config.DefineExpr{}
 }
 
+func validateJSXOverrides(log logger.Log, fs fs.FS, overrides []JSXOverride) (result []config.JSXPathOverride) {
+	for _, override := range overrides {
+		if override.Path == "" {
+			log.AddError(nil, logger.Range{}, "JSX override path cannot be empty")
+			continue
+		}
+		absPath := validatePath(log, fs, override.Path, "JSX override path")
+		item := config.JSXPathOverride{
+			Factory:      validateJSXExpr(log, override.Factory, "factory"),
+			Fragment:     validateJSXExpr(log, override.Fragment, "fragment"),
+			ImportSource: override.ImportSource,
+		}
+		if index := strings.IndexByte(absPath, '*'); index != -1 {
+			if strings.ContainsRune(absPath[index+1:], '*') {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("JSX override path %q cannot have more than one \"*\" wildcard", override.Path))
+				continue
+			}
+			item.Pattern = config.WildcardPattern{Prefix: absPath[:index], Suffix: absPath[index+1:]}
+		} else {
+			item.Exact = absPath
+		}
+		result = append(result, item)
+	}
+	return
+}
+
 func validateDefines(
 	log logger.Log,
 	defines map[string]string,
@@ -509,6 +780,7 @@ func validateDefines(
 	platform Platform,
 	minify bool,
 	drop Drop,
+	dropCalls []string,
 ) (*config.ProcessedDefines, []config.InjectedDefine) {
 	rawDefines := make(map[string]config.DefineData)
 	var valueToInject map[string]config.InjectedDefine
@@ -610,12 +882,42 @@ func validateDefines(
 		rawDefines[key] = define
 	}
 
+	for _, key := range dropCalls {
+		// The key must be a dot-separated identifier list
+		for _, part := range strings.Split(key, ".") {
+			if !js_lexer.IsIdentifier(part) {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid call to drop: %q", key))
+				continue
+			}
+		}
+
+		// Merge with any previously-specified defines
+		define := rawDefines[key]
+		define.CallMustBeDropped = true
+		rawDefines[key] = define
+	}
+
 	// Processing defines is expensive. Process them once here so the same object
 	// can be shared between all parsers we create using these arguments.
 	processed := config.ProcessDefines(rawDefines)
+	processed.Key = definesKey(defines, pureFns, platform, minify, drop, dropCalls)
 	return &processed, injectedDefines
 }
 
+func definesKey(defines map[string]string, pureFns []string, platform Platform, minify bool, drop Drop, dropCalls []string) string {
+	keys := make([]string, 0, len(defines))
+	for key := range defines {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sb := strings.Builder{}
+	for _, key := range keys {
+		sb.WriteString(fmt.Sprintf("%q=%q\n", key, defines[key]))
+	}
+	sb.WriteString(fmt.Sprintf("pure=%q\nplatform=%d\nminify=%v\ndrop=%d\ndropCalls=%q\n", pureFns, platform, minify, drop, dropCalls))
+	return sb.String()
+}
+
 func validateLogOverrides(input map[string]LogLevel) (output map[logger.MsgID]logger.LogLevel) {
 	output = make(map[uint8]logger.LogLevel)
 	for k, v := range input {
@@ -685,26 +987,64 @@ func convertMessagesToPublic(kind logger.MsgKind, msgs []logger.Msg) []Message {
 	var filtered []Message
 	for _, msg := range msgs {
 		if msg.Kind == kind {
-			var notes []Note
-			for _, note := range msg.Notes {
-				notes = append(notes, Note{
-					Text:     note.Text,
-					Location: convertLocationToPublic(note.Location),
-				})
-			}
-			filtered = append(filtered, Message{
-				ID:         logger.MsgIDToString(msg.ID),
-				PluginName: msg.PluginName,
-				Text:       msg.Data.Text,
-				Location:   convertLocationToPublic(msg.Data.Location),
-				Notes:      notes,
-				Detail:     msg.Data.UserDetail,
-			})
+			filtered = append(filtered, convertMessageToPublic(msg))
 		}
 	}
 	return filtered
 }
 
+func convertMessageToPublic(msg logger.Msg) Message {
+	var notes []Note
+	for _, note := range msg.Notes {
+		notes = append(notes, Note{
+			Text:     note.Text,
+			Location: convertLocationToPublic(note.Location),
+		})
+	}
+	return Message{
+		ID:         logger.MsgIDToString(msg.ID),
+		PluginName: msg.PluginName,
+		Text:       msg.Data.Text,
+		Location:   convertLocationToPublic(msg.Data.Location),
+		Notes:      notes,
+		Category:   convertMessageCategoryToPublic(msg.Category),
+		Detail:     msg.Data.UserDetail,
+	}
+}
+
+func messageErrorImpl(msg Message) string {
+	if loc := msg.Location; loc != nil {
+		return fmt.Sprintf("%s:%d:%d: %s", loc.File, loc.Line, loc.Column, msg.Text)
+	}
+	return msg.Text
+}
+
+func convertMessageCategoryToPublic(category logger.MsgCategory) MessageCategory {
+	switch category {
+	case logger.MsgCategorySyntax:
+		return MessageCategorySyntax
+	case logger.MsgCategoryResolve:
+		return MessageCategoryResolve
+	case logger.MsgCategoryPlugin:
+		return MessageCategoryPlugin
+	default:
+		return MessageCategoryOther
+	}
+}
+
+func convertMessageCategoryToInternal(category MessageCategory) logger.MsgCategory {
+	switch category {
+	case MessageCategorySyntax:
+		return logger.MsgCategorySyntax
+	case MessageCategoryResolve:
+		return logger.MsgCategoryResolve
+	case MessageCategoryPlugin:
+		return logger.MsgCategoryPlugin
+	default:
+		return logger.MsgCategoryNone
+	}
+}
+
 func convertLocationToInternal(loc *Location) *logger.MsgLocation {
 	if loc != nil {
 		namespace := loc.Namespace
@@ -737,6 +1077,7 @@ func convertMessagesToInternal(msgs []logger.Msg, kind logger.MsgKind, messages
 			ID:         logger.StringToMaximumMsgID(message.ID),
 			PluginName: message.PluginName,
 			Kind:       kind,
+			Category:   convertMessageCategoryToInternal(message.Category),
 			Data: logger.MsgData{
 				Text:       message.Text,
 				Location:   convertLocationToInternal(message.Location),
@@ -772,13 +1113,42 @@ func cloneMangleCache(log logger.Log, mangleCache map[string]interface{}) map[st
 ////////////////////////////////////////////////////////////////////////////////
 // Build API
 
+// The context is turned into a flag that can be checked cheaply from the many
+// goroutines that parse and print files. The returned function must be called
+// when the build is done to stop watching the context.
+func cancelFlagForContext(ctx context.Context) (*config.CancelFlag, func()) {
+	if ctx == nil || ctx.Done() == nil {
+		return nil, func() {}
+	}
+	cancelFlag := &config.CancelFlag{}
+	if ctx.Err() != nil {
+		cancelFlag.Cancel()
+		return cancelFlag, func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelFlag.Cancel()
+		case <-done:
+		}
+	}()
+	return cancelFlag, func() { close(done) }
+}
+
+func checkForCancellation(log logger.Log, cancelFlag *config.CancelFlag) {
+	if cancelFlag.DidCancel() && !log.HasErrors() {
+		log.AddError(nil, logger.Range{}, "The build was cancelled")
+	}
+}
+
 type internalBuildResult struct {
 	result    BuildResult
 	watchData fs.WatchData
 	options   config.Options
 }
 
-func buildImpl(buildOpts BuildOptions) internalBuildResult {
+func buildImpl(ctx context.Context, buildOpts BuildOptions) internalBuildResult {
 	start := time.Now()
 	logOptions := logger.OutputOptions{
 		IncludeSource: true,
@@ -786,6 +1156,8 @@ func buildImpl(buildOpts BuildOptions) internalBuildResult {
 		Color:         validateColor(buildOpts.Color),
 		LogLevel:      validateLogLevel(buildOpts.LogLevel),
 		Overrides:     validateLogOverrides(buildOpts.LogOverride),
+		Format:        validateLogFormat(buildOpts.LogFormat),
+		OnMessage:     validateOnLogMessage(buildOpts.OnLogMessage),
 	}
 	log := logger.NewStderrLog(logOptions)
 
@@ -797,6 +1169,8 @@ func buildImpl(buildOpts BuildOptions) internalBuildResult {
 		// ReadDirectory() (they are normally cached for the duration of a build
 		// for performance).
 		DoNotCache: true,
+
+		MaxOpenFiles: buildOpts.MaxOpenFiles,
 	})
 	if err != nil {
 		log.AddError(nil, logger.Range{}, err.Error())
@@ -813,110 +1187,1062 @@ func buildImpl(buildOpts BuildOptions) internalBuildResult {
 		panic("Mutating \"AbsWorkingDir\" is not allowed")
 	}
 
-	internalResult := rebuildImpl(buildOpts, caches, plugins, finalizeBuildOptions, onEndCallbacks, logOptions, log, false /* isRebuild */)
+	internalResult := rebuildImpl(ctx, buildOpts, caches, plugins, finalizeBuildOptions, onEndCallbacks, logOptions, log, false /* isRebuild */)
 
 	// Print a summary of the generated files to stderr. Except don't do
 	// this if the terminal is already being used for something else.
-	if logOptions.LogLevel <= logger.LevelInfo && len(internalResult.result.OutputFiles) > 0 &&
-		buildOpts.Watch == nil && !buildOpts.Incremental && !internalResult.options.WriteToStdout {
+	if logOptions.LogLevel <= logger.LevelInfo && logOptions.Format == logger.LogFormatText && len(internalResult.result.OutputFiles) > 0 &&
+		buildOpts.Watch == nil && !buildOpts.Incremental && (!internalResult.options.WriteToStdout || !buildOpts.Write) {
 		printSummary(logOptions, internalResult.result.OutputFiles, start)
 	}
 
 	return internalResult
 }
 
-func prettyPrintByteCount(n int) string {
-	var size string
-	if n < 1024 {
-		size = fmt.Sprintf("%db ", n)
-	} else if n < 1024*1024 {
-		size = fmt.Sprintf("%.1fkb", float64(n)/(1024))
-	} else if n < 1024*1024*1024 {
-		size = fmt.Sprintf("%.1fmb", float64(n)/(1024*1024))
-	} else {
-		size = fmt.Sprintf("%.1fgb", float64(n)/(1024*1024*1024))
+// This builds everything again from scratch and reports output files that
+// are different the second time. Nothing is shared with the first build
+// except for plugins, so this can catch output that depends on the order in
+// which files happened to be parsed.
+func verifyDeterminism(log logger.Log, realFS fs.FS, buildOpts BuildOptions, groups []entryPointGroup, results []graph.OutputFile, metafile string) {
+	verifyLog := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, log.Overrides)
+	caches := cache.MakeCacheSet()
+	mangleCache := cloneMangleCache(verifyLog, buildOpts.MangleCache)
+	verifyGroups := make([]entryPointGroup, len(groups))
+	for i, group := range groups {
+		verifyGroups[i] = entryPointGroup{
+			entryPoints: group.entryPoints,
+			options:     group.options,
+			resolver:    resolver.NewResolver(realFS, verifyLog, caches, group.options),
+		}
+	}
+	var verifyResults []graph.OutputFile
+	var verifyMetafile string
+	scanEntryPointGroups(verifyLog, realFS, caches, verifyGroups, nil)
+	if !verifyLog.HasErrors() {
+		verifyResults, verifyMetafile = compileEntryPointGroups(verifyLog, realFS, verifyGroups, nil, mangleCache)
+	}
+	if verifyLog.HasErrors() {
+		log.AddError(nil, logger.Range{}, "Failed to verify determinism because the second build had errors")
+		return
+	}
+
+	prettyPath := func(absPath string) string {
+		if relPath, ok := realFS.Rel(realFS.Cwd(), absPath); ok {
+			return strings.ReplaceAll(relPath, "\\", "/")
+		}
+		return absPath
+	}
+	contentsForPath := make(map[string][]byte, len(verifyResults))
+	for _, result := range verifyResults {
+		contentsForPath[result.AbsPath] = result.Contents
+	}
+	for _, result := range results {
+		if contents, ok := contentsForPath[result.AbsPath]; !ok {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("The output file %q was not generated when building a second time", prettyPath(result.AbsPath)))
+		} else if !bytes.Equal(contents, result.Contents) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("The output file %q was different when building a second time", prettyPath(result.AbsPath)))
+		}
+		delete(contentsForPath, result.AbsPath)
+	}
+	if len(contentsForPath) > 0 {
+		var extraPaths []string
+		for absPath := range contentsForPath {
+			extraPaths = append(extraPaths, absPath)
+		}
+		sort.Strings(extraPaths)
+		for _, absPath := range extraPaths {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("The output file %q was only generated when building a second time", prettyPath(absPath)))
+		}
+	}
+	if buildOpts.Metafile && verifyMetafile != metafile {
+		log.AddError(nil, logger.Range{}, "The metafile was different when building a second time")
 	}
-	return size
 }
 
-func printSummary(logOptions logger.OutputOptions, outputFiles []OutputFile, start time.Time) {
-	var table logger.SummaryTable = make([]logger.SummaryTableEntry, len(outputFiles))
+// The memory limit is approximate. It's only checked once per build after
+// parsing has finished, since the caches are still being filled in by the
+// build before then. Memory that's still in use also can't be reclaimed.
+func flushCachesIfOverMemoryLimit(log logger.Log, caches *cache.CacheSet, limitInMegabytes int) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > uint64(limitInMegabytes)*1024*1024 {
+		log.AddID(logger.MsgID_None, logger.Debug, nil, logger.Range{}, fmt.Sprintf(
+			"Flushing caches because memory usage (%s) is over the memory limit", strings.TrimSpace(prettyPrintByteCount(int(stats.HeapAlloc)))))
+		caches.Flush()
+		debug.FreeOSMemory()
+	}
+}
 
-	if len(outputFiles) > 0 {
-		if cwd, err := os.Getwd(); err == nil {
-			if realFS, err := fs.RealFS(fs.RealFSOptions{AbsWorkingDir: cwd}); err == nil {
-				for i, file := range outputFiles {
-					path, ok := realFS.Rel(realFS.Cwd(), file.Path)
-					if !ok {
-						path = file.Path
-					}
-					base := realFS.Base(path)
-					n := len(file.Contents)
-					table[i] = logger.SummaryTableEntry{
-						Dir:         path[:len(path)-len(base)],
-						Base:        base,
-						Size:        prettyPrintByteCount(n),
-						Bytes:       n,
-						IsSourceMap: strings.HasSuffix(base, ".map"),
-					}
-				}
+// Deleting files is dangerous, so only clean a directory that was explicitly
+// specified and that can't contain the project's own files
+func validateCleanOutputDirectory(log logger.Log, realFS fs.FS, buildOpts BuildOptions, absOutputDir string) {
+	if buildOpts.Outdir == "" {
+		log.AddError(nil, logger.Range{}, "Cannot use \"clean\" without \"outdir\"")
+		return
+	}
+	if buildOpts.CleanRetain < 0 {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid clean retain count: %d", buildOpts.CleanRetain))
+	}
+	if realFS.Dir(absOutputDir) == absOutputDir {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf(
+			"Refusing to clean the output directory %q because it's the root of the file system", absOutputDir))
+		return
+	}
+	if isInsideDirectory(realFS, absOutputDir, realFS.Cwd()) {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf(
+			"Refusing to clean the output directory %q because it contains the working directory", absOutputDir))
+		return
+	}
+	entryPoints := append([]string{}, buildOpts.EntryPoints...)
+	for _, entryPoint := range buildOpts.EntryPointsAdvanced {
+		entryPoints = append(entryPoints, entryPoint.InputPath)
+	}
+	for _, entryPoint := range entryPoints {
+		if absPath, ok := realFS.Abs(entryPoint); ok && isInsideDirectory(realFS, absOutputDir, absPath) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf(
+				"Refusing to clean the output directory %q because it contains the entry point %q", absOutputDir, entryPoint))
+			return
+		}
+	}
+
+	// Only clean directories that are empty or that were cleaned by esbuild
+	// before, which is indicated by a marker file. Otherwise a mistake in the
+	// "outdir" setting could delete files that esbuild didn't create.
+	if buildOpts.Write {
+		if entries, err := ioutil.ReadDir(absOutputDir); err == nil && len(entries) > 0 {
+			if _, err := os.Stat(filepath.Join(absOutputDir, cleanMarkerFile)); err != nil {
+				log.AddErrorWithNotes(nil, logger.Range{}, fmt.Sprintf(
+					"Refusing to clean the output directory %q because it wasn't created by esbuild", absOutputDir),
+					[]logger.MsgData{{Text: fmt.Sprintf("Empty the output directory once to let esbuild clean it from now on, "+
+						"or create an empty file called %q inside it.", cleanMarkerFile)}})
 			}
 		}
 	}
+}
 
-	// Don't print the time taken by the build if we're running under Yarn 1
-	// since Yarn 1 always prints its own copy of the time taken by each command
-	for _, env := range os.Environ() {
-		if strings.HasPrefix(env, "npm_config_user_agent=") && strings.Contains(env, "yarn/1.") {
-			logger.PrintSummary(logOptions.Color, table, nil)
-			return
+// This file is written to the output directory when cleaning it so that later
+// builds know that esbuild is allowed to remove files from that directory
+const cleanMarkerFile = ".esbuild-clean"
+
+// Input files are only known after scanning. Cleaning or replacing the output
+// directory must never remove them (e.g. with "outdir" set to a directory of
+// source files that are imported but aren't entry points).
+func validateOutputDirectoryInputFiles(log logger.Log, realFS fs.FS, absOutputDir string, groups []entryPointGroup, action string) {
+	for _, group := range groups {
+		for _, absPath := range group.bundle.InputFilePaths() {
+			if isInsideDirectory(realFS, absOutputDir, absPath) {
+				prettyPath := absPath
+				if relPath, ok := realFS.Rel(realFS.Cwd(), absPath); ok {
+					prettyPath = relPath
+				}
+				log.AddError(nil, logger.Range{}, fmt.Sprintf(
+					"Refusing to %s the output directory %q because it contains the input file %q", action, absOutputDir, prettyPath))
+				return
+			}
 		}
 	}
+}
 
-	logger.PrintSummary(logOptions.Color, table, &start)
+func isInsideDirectory(fs fs.FS, absDir string, absPath string) bool {
+	relPath, ok := fs.Rel(absDir, absPath)
+	return ok && relPath != ".." && !strings.HasPrefix(relPath, "../") && !strings.HasPrefix(relPath, "..\\")
 }
 
-func rebuildImpl(
-	buildOpts BuildOptions,
-	caches *cache.CacheSet,
-	plugins []config.Plugin,
-	finalizeBuildOptions func(*config.Options),
-	onEndCallbacks []func(*BuildResult),
-	logOptions logger.OutputOptions,
-	log logger.Log,
-	isRebuild bool,
-) internalBuildResult {
-	// Convert and validate the buildOpts
-	realFS, err := fs.RealFS(fs.RealFSOptions{
-		AbsWorkingDir: buildOpts.AbsWorkingDir,
-		WantWatchData: buildOpts.Watch != nil,
+// This matches the "[hash]" placeholder in an output path, which is always
+// 8 characters of base32 surrounded by non-alphanumeric characters
+var outputHashRegexp = regexp.MustCompile(`(^|[^A-Za-z0-9])[A-Z2-7]{8}([^A-Za-z0-9]|$)`)
+
+// This removes every file in the output directory that wasn't generated by
+// the current build. Older versions of hashed output files can optionally be
+// kept around so that clients of a running server that still reference them
+// don't break. Symbolic links are never followed or removed.
+func cleanOutputDirectory(log logger.Log, absOutputDir string, keep map[string]bool, retain int) {
+	absMarkerPath := filepath.Join(absOutputDir, cleanMarkerFile)
+	if err := os.MkdirAll(absOutputDir, 0755); err != nil {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to create output directory: %s", err.Error()))
+		return
+	}
+	if err := ioutil.WriteFile(absMarkerPath, nil, 0644); err != nil {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to write %q: %s", absMarkerPath, err.Error()))
+		return
+	}
+	keep[absMarkerPath] = true
+
+	versionKey := func(absPath string) string {
+		return outputHashRegexp.ReplaceAllString(absPath, "${1}[hash]${2}")
+	}
+	currentVersions := make(map[string]bool)
+	for absPath := range keep {
+		if key := versionKey(absPath); key != absPath {
+			currentVersions[key] = true
+		}
+	}
+
+	type staleFile struct {
+		absPath string
+		modTime time.Time
+	}
+	var staleFiles []staleFile
+	filepath.Walk(absOutputDir, func(absPath string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() && !keep[absPath] {
+			staleFiles = append(staleFiles, staleFile{absPath: absPath, modTime: info.ModTime()})
+		}
+		return nil
 	})
-	if err != nil {
-		// This should already have been checked above
-		panic(err.Error())
+
+	// Keep the most recent older versions of each output file that still exists
+	sort.SliceStable(staleFiles, func(i int, j int) bool {
+		return staleFiles[i].modTime.After(staleFiles[j].modTime)
+	})
+	retained := make(map[string]int)
+	dirs := make(map[string]bool)
+	for _, file := range staleFiles {
+		if key := versionKey(file.absPath); key != file.absPath && currentVersions[key] && retained[key] < retain {
+			retained[key]++
+			continue
+		}
+		if err := os.Remove(file.absPath); err != nil {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to remove stale output file: %s", err.Error()))
+			continue
+		}
+		log.AddID(logger.MsgID_None, logger.Verbose, nil, logger.Range{}, fmt.Sprintf("Removed stale output file %q", file.absPath))
+		dirs[filepath.Dir(file.absPath)] = true
 	}
-	targetFromAPI, jsFeatures, cssFeatures, targetEnv := validateFeatures(log, buildOpts.Target, buildOpts.Engines)
-	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, buildOpts.Supported)
-	outJS, outCSS := validateOutputExtensions(log, buildOpts.OutExtensions)
+
+	// Remove directories that are now empty, deepest first
+	sortedDirs := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Slice(sortedDirs, func(i int, j int) bool {
+		return len(sortedDirs[i]) > len(sortedDirs[j])
+	})
+	for _, dir := range sortedDirs {
+		for dir != absOutputDir && strings.HasPrefix(dir, absOutputDir) && os.Remove(dir) == nil {
+			dir = filepath.Dir(dir)
+		}
+	}
+}
+
+// This writes out the output files in parallel. Each file is written in
+// chunks so that progress can be reported for large files too.
+func writeOutputFiles(log logger.Log, realFS fs.FS, results []graph.OutputFile, onProgress func(WriteProgress)) {
+	progress := WriteProgress{TotalFiles: len(results)}
+	for _, result := range results {
+		progress.TotalBytes += len(result.Contents)
+	}
+	progressMutex := sync.Mutex{}
+	reportProgress := func(bytes int, isDone bool) {
+		if onProgress != nil {
+			progressMutex.Lock()
+			defer progressMutex.Unlock()
+			progress.BytesWritten += bytes
+			if isDone {
+				progress.FilesWritten++
+			}
+			onProgress(progress)
+		}
+	}
+
+	openFiles := fs.OpenFileLimitOf(realFS)
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(results))
+	for _, result := range results {
+		go func(result graph.OutputFile) {
+			openFiles.BeforeFileOpen()
+			defer openFiles.AfterFileClose()
+			if err := fs.MkdirAll(realFS, realFS.Dir(result.AbsPath), 0755); err != nil {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf(
+					"Failed to create output directory: %s", err.Error()))
+			} else {
+				var mode os.FileMode = 0644
+				if result.IsExecutable {
+					mode = 0755
+				}
+				if err := writeFileInChunks(result.AbsPath, result.Contents, mode, reportProgress); err != nil {
+					log.AddError(nil, logger.Range{}, fmt.Sprintf(
+						"Failed to write to output file: %s", err.Error()))
+				}
+			}
+			waitGroup.Done()
+		}(result)
+	}
+	waitGroup.Wait()
+}
+
+const writeChunkSize = 1024 * 1024
+
+func writeFileInChunks(absPath string, contents []byte, mode os.FileMode, reportProgress func(bytes int, isDone bool)) error {
+	f, err := os.OpenFile(absPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	for len(contents) > writeChunkSize {
+		if _, err := f.Write(contents[:writeChunkSize]); err != nil {
+			f.Close()
+			return err
+		}
+		contents = contents[writeChunkSize:]
+		reportProgress(writeChunkSize, false)
+	}
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	reportProgress(len(contents), true)
+	return nil
+}
+
+func validateAtomicWriteOutputDirectory(log logger.Log, realFS fs.FS, buildOpts BuildOptions, absOutputDir string) {
+	if buildOpts.Outdir == "" {
+		log.AddError(nil, logger.Range{}, "Cannot use \"atomic write\" without \"outdir\"")
+		return
+	}
+	if realFS.Dir(absOutputDir) == absOutputDir {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf(
+			"Refusing to replace the output directory %q because it's the root of the file system", absOutputDir))
+		return
+	}
+	if isInsideDirectory(realFS, absOutputDir, realFS.Cwd()) {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf(
+			"Refusing to replace the output directory %q because it contains the working directory", absOutputDir))
+	}
+}
+
+// This creates a staging directory next to the output directory that starts
+// off with the files that are already in the output directory, and returns
+// the output files with their paths moved into the staging directory. Existing
+// files are hard-linked instead of copied when possible. The staging directory
+// is a sibling of the output directory so that it's on the same file system,
+// which means it can be moved into place with a rename instead of a copy.
+func stageAtomicWrite(log logger.Log, realFS fs.FS, absOutputDir string, results []graph.OutputFile) (string, []graph.OutputFile) {
+	absRealOutputDir := resolveAtomicWriteOutputDirectory(absOutputDir)
+	absStagingDir := fmt.Sprintf("%s.%d.tmp", absRealOutputDir, os.Getpid())
+	staged := make([]graph.OutputFile, len(results))
+	overwritten := make(map[string]bool, len(results))
+	for i, result := range results {
+		relPath, ok := realFS.Rel(absOutputDir, result.AbsPath)
+		if !ok || !isInsideDirectory(realFS, absOutputDir, result.AbsPath) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf(
+				"Cannot use \"atomic write\" because the output file %q is outside the output directory %q", result.AbsPath, absOutputDir))
+			return absStagingDir, nil
+		}
+		result.AbsPath = realFS.Join(absStagingDir, relPath)
+		overwritten[result.AbsPath] = true
+		staged[i] = result
+	}
+
+	// Remove anything left over from a previous build that was interrupted
+	os.RemoveAll(absStagingDir)
+	if err := fs.MkdirAll(realFS, absStagingDir, 0755); err != nil {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to create staging directory: %s", err.Error()))
+		return absStagingDir, nil
+	}
+
+	filepath.Walk(absRealOutputDir, func(absPath string, info os.FileInfo, err error) error {
+		if err != nil || absPath == absRealOutputDir {
+			return nil
+		}
+		relPath, _ := filepath.Rel(absRealOutputDir, absPath)
+		absStagedPath := filepath.Join(absStagingDir, relPath)
+		if overwritten[absStagedPath] {
+			return nil
+		}
+		mode := info.Mode()
+		switch {
+		case mode.IsDir():
+			err = os.MkdirAll(absStagedPath, mode.Perm())
+		case mode&os.ModeSymlink != 0:
+			var target string
+			if target, err = os.Readlink(absPath); err == nil {
+				err = os.Symlink(target, absStagedPath)
+			}
+		case mode.IsRegular():
+			if os.Link(absPath, absStagedPath) != nil {
+				err = copyFileForAtomicWrite(absPath, absStagedPath, info)
+			}
+		}
+		if err != nil {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to copy %q to the staging directory: %s", absPath, err.Error()))
+		}
+		return nil
+	})
+
+	return absStagingDir, staged
+}
+
+// Renaming a symlink would replace the symlink itself instead of the directory
+// that it points to, so the output directory is replaced at its real location
+func resolveAtomicWriteOutputDirectory(absOutputDir string) string {
+	if absPath, err := filepath.EvalSymlinks(absOutputDir); err == nil {
+		return absPath
+	}
+	return absOutputDir
+}
+
+func copyFileForAtomicWrite(absFrom string, absTo string, info os.FileInfo) error {
+	contents, err := ioutil.ReadFile(absFrom)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(absTo, contents, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Preserve the modification time since "clean retain" depends on it
+	return os.Chtimes(absTo, info.ModTime(), info.ModTime())
+}
+
+// This replaces the output directory with the staging directory. If the
+// build failed, the staging directory is removed and the output directory is
+// left alone instead.
+//
+// Note that this isn't a single atomic operation. A directory can't be renamed
+// over another non-empty directory, so the old output directory is moved out
+// of the way first. Between the two renames there is no output directory at
+// all, so anything reading from it at that moment (e.g. a development server)
+// will see it as missing. What is guaranteed is that the output directory is
+// never observed with a mix of old and new files. If the process is killed in
+// this window, the old output directory is left behind with the ".old" suffix.
+func finishAtomicWrite(log logger.Log, absStagingDir string, absOutputDir string) {
+	if log.HasErrors() {
+		os.RemoveAll(absStagingDir)
+		return
+	}
+
+	absOutputDir = resolveAtomicWriteOutputDirectory(absOutputDir)
+	absOldDir := fmt.Sprintf("%s.%d.old", absOutputDir, os.Getpid())
+	os.RemoveAll(absOldDir)
+
+	// The staging directory replaces the output directory, so it should have
+	// the same permissions (this is done last so that writing to it works)
+	if info, err := os.Stat(absOutputDir); err == nil {
+		os.Chmod(absStagingDir, info.Mode().Perm())
+	}
+
+	hasOldDir := os.Rename(absOutputDir, absOldDir) == nil
+	if err := os.Rename(absStagingDir, absOutputDir); err != nil {
+		if hasOldDir {
+			os.Rename(absOldDir, absOutputDir)
+		}
+		os.RemoveAll(absStagingDir)
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to replace the output directory: %s", err.Error()))
+		return
+	}
+	if hasOldDir {
+		os.RemoveAll(absOldDir)
+	}
+}
+
+// This merges the legal comments from all output files. Each comment is only
+// reported once even if it appears in several output files.
+func collectLegalComments(results []graph.OutputFile) []LegalComment {
+	legalComments := []LegalComment{}
+	indexForText := make(map[string]int)
+	seenInputs := make(map[[2]string]bool)
+	for _, result := range results {
+		for _, comment := range result.LegalComments {
+			index, ok := indexForText[comment.Text]
+			if !ok {
+				index = len(legalComments)
+				indexForText[comment.Text] = index
+				legalComments = append(legalComments, LegalComment{Text: comment.Text})
+			}
+			for _, inputPath := range comment.InputPaths {
+				if key := [2]string{comment.Text, inputPath}; !seenInputs[key] {
+					seenInputs[key] = true
+					legalComments[index].Inputs = append(legalComments[index].Inputs, inputPath)
+				}
+			}
+		}
+	}
+	sort.SliceStable(legalComments, func(i int, j int) bool {
+		return legalComments[i].Text < legalComments[j].Text
+	})
+	return legalComments
+}
+
+type statusFileDurations struct {
+	scan    time.Duration
+	compile time.Duration
+	write   time.Duration
+	total   time.Duration
+}
+
+// The status file is a machine-readable summary of a build for tools that
+// orchestrate builds. It's written to a temporary file first and then renamed
+// so that readers never observe a partially-written file.
+func writeStatusFile(realFS fs.FS, absPath string, result *BuildResult, durations statusFileDurations) error {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("{\n  \"success\": %v,\n  \"errors\": %d,\n  \"warnings\": %d,\n",
+		len(result.Errors) == 0, len(result.Errors), len(result.Warnings)))
+	sb.WriteString(fmt.Sprintf("  \"durations\": {\n    \"scan\": %d,\n    \"compile\": %d,\n    \"write\": %d,\n    \"total\": %d\n  },\n",
+		durations.scan.Milliseconds(), durations.compile.Milliseconds(), durations.write.Milliseconds(), durations.total.Milliseconds()))
+	sb.WriteString("  \"outputs\": [")
+	for i, file := range result.OutputFiles {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		path := file.Path
+		if relPath, ok := realFS.Rel(realFS.Cwd(), path); ok {
+			path = strings.ReplaceAll(relPath, "\\", "/")
+		}
+		hash := sha256.Sum256(file.Contents)
+		sb.WriteString(fmt.Sprintf("\n    {\n      \"path\": %s,\n      \"bytes\": %d,\n      \"sha256\": \"%s\"\n    }",
+			js_printer.QuoteForJSON(path, false), len(file.Contents), hex.EncodeToString(hash[:])))
+	}
+	if len(result.OutputFiles) > 0 {
+		sb.WriteString("\n  ")
+	}
+	sb.WriteString("]\n}\n")
+
+	if err := fs.MkdirAll(realFS, realFS.Dir(absPath), 0755); err != nil {
+		return err
+	}
+	tempPath := fmt.Sprintf("%s.%d.tmp", absPath, os.Getpid())
+	if err := ioutil.WriteFile(tempPath, []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, absPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+func logBuildTiming(log logger.Log, timing *config.BuildTiming) {
+	notes := []logger.MsgData{
+		{Text: fmt.Sprintf("Scan: %dms", timing.Scan.Milliseconds())},
+		{Text: fmt.Sprintf("Parse: %dms (summed over all threads)", timing.Parse().Milliseconds())},
+		{Text: fmt.Sprintf("Link: %dms (summed over all threads)", timing.Link().Milliseconds())},
+		{Text: fmt.Sprintf("Print: %dms (summed over all threads)", timing.Print().Milliseconds())},
+		{Text: fmt.Sprintf("Write: %dms", timing.Write.Milliseconds())},
+	}
+
+	for _, plugin := range timing.Plugins() {
+		total := plugin.OnStart.Duration + plugin.OnResolve.Duration + plugin.OnLoad.Duration
+		var hooks []string
+		for _, hook := range []struct {
+			name   string
+			timing config.HookTiming
+		}{
+			{"onStart", plugin.OnStart},
+			{"onResolve", plugin.OnResolve},
+			{"onLoad", plugin.OnLoad},
+		} {
+			if hook.timing.Calls > 0 {
+				calls := "calls"
+				if hook.timing.Calls == 1 {
+					calls = "call"
+				}
+				hooks = append(hooks, fmt.Sprintf("%s %dms in %d %s", hook.name, hook.timing.Duration.Milliseconds(), hook.timing.Calls, calls))
+			}
+		}
+		text := fmt.Sprintf("Plugin %q: %dms", plugin.Name, total.Milliseconds())
+		if len(hooks) > 0 {
+			text += fmt.Sprintf(" (%s)", strings.Join(hooks, ", "))
+		}
+		notes = append(notes, logger.MsgData{Text: text})
+	}
+
+	for i := range notes {
+		notes[i].DisableMaximumWidth = true
+	}
+	log.AddIDWithNotes(logger.MsgID_None, logger.Info, nil, logger.Range{}, "Build timing", notes)
+}
+
+// The timing information is added to the metafile as a "timing" section after
+// the output files have been written so that it can include the write phase
+func addTimingToMetafile(metafile string, timing *config.BuildTiming) string {
+	end := strings.LastIndexByte(metafile, '}')
+	if end == -1 {
+		return metafile
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(strings.TrimRight(metafile[:end], "\n"))
+	sb.WriteString(fmt.Sprintf(",\n  \"timing\": {\n    \"scan\": %d,\n    \"parse\": %d,\n    \"link\": %d,\n    \"print\": %d,\n    \"write\": %d,\n    \"plugins\": [",
+		timing.Scan.Milliseconds(), timing.Parse().Milliseconds(), timing.Link().Milliseconds(),
+		timing.Print().Milliseconds(), timing.Write.Milliseconds()))
+	plugins := timing.Plugins()
+	for i, plugin := range plugins {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(fmt.Sprintf("\n      {\n        \"name\": %s,\n        \"onStart\": { \"calls\": %d, \"duration\": %d },\n        \"onResolve\": { \"calls\": %d, \"duration\": %d },\n        \"onLoad\": { \"calls\": %d, \"duration\": %d }\n      }",
+			js_printer.QuoteForJSON(plugin.Name, false),
+			plugin.OnStart.Calls, plugin.OnStart.Duration.Milliseconds(),
+			plugin.OnResolve.Calls, plugin.OnResolve.Duration.Milliseconds(),
+			plugin.OnLoad.Calls, plugin.OnLoad.Duration.Milliseconds()))
+	}
+	if len(plugins) > 0 {
+		sb.WriteString("\n    ")
+	}
+	sb.WriteString("]\n  }\n}\n")
+	return sb.String()
+}
+
+func validateRemoteImports(log logger.Log, realFS fs.FS, buildOpts BuildOptions) (result config.RemoteImports, absLockFile string) {
+	if !buildOpts.RemoteImports {
+		return
+	}
+	result.Enabled = true
+	result.Offline = buildOpts.RemoteOffline
+	result.DoNotWriteCache = !buildOpts.Write
+
+	// Share downloaded modules between projects by default
+	if buildOpts.RemoteCacheDir != "" {
+		result.AbsCacheDir = validatePath(log, realFS, buildOpts.RemoteCacheDir, "remote cache directory")
+	} else if dir, err := os.UserCacheDir(); err == nil {
+		result.AbsCacheDir = realFS.Join(dir, "esbuild", "remote")
+	}
+
+	// A missing lock file is fine since it will be created
+	if buildOpts.RemoteLockFile != "" {
+		absLockFile = validatePath(log, realFS, buildOpts.RemoteLockFile, "lock file path")
+		result.Integrity = make(map[string]string)
+		if contents, err, _ := realFS.ReadFile(absLockFile); err == nil {
+			path := logger.Path{Text: absLockFile, Namespace: "file"}
+			source := logger.Source{KeyPath: path, PrettyPath: absLockFile, Contents: contents}
+			if json, ok := js_parser.ParseJSON(log, source, js_parser.JSONOptions{}); ok {
+				tracker := logger.MakeLineColumnTracker(&source)
+				if obj, ok := json.Data.(*js_ast.EObject); ok {
+					for _, prop := range obj.Properties {
+						key, _ := prop.Key.Data.(*js_ast.EString)
+						value, ok := prop.ValueOrNil.Data.(*js_ast.EString)
+						if key == nil || !ok {
+							log.AddError(&tracker, source.RangeOfString(prop.Key.Loc), "Expected a URL mapped to an integrity hash")
+							continue
+						}
+						result.Integrity[helpers.UTF16ToString(key.Value)] = helpers.UTF16ToString(value.Value)
+					}
+				} else {
+					log.AddError(&tracker, logger.Range{Loc: json.Loc}, "The lock file must contain an object")
+				}
+			}
+		} else if err != syscall.ENOENT {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot read lock file %q: %s", absLockFile, err.Error()))
+		}
+	}
+	return
+}
+
+func writeRemoteLockFile(realFS fs.FS, absPath string, oldIntegrity map[string]string, newIntegrity map[string]string) error {
+	urls := make([]string, 0, len(oldIntegrity)+len(newIntegrity))
+	for url := range oldIntegrity {
+		urls = append(urls, url)
+	}
+	for url := range newIntegrity {
+		if _, ok := oldIntegrity[url]; !ok {
+			urls = append(urls, url)
+		}
+	}
+	sort.Strings(urls)
+
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, url := range urls {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		integrity, ok := oldIntegrity[url]
+		if !ok {
+			integrity = newIntegrity[url]
+		}
+		sb.WriteString(fmt.Sprintf("\n  %s: %s", js_printer.QuoteForJSON(url, false), js_printer.QuoteForJSON(integrity, false)))
+	}
+	if len(urls) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}\n")
+
+	if err := fs.MkdirAll(realFS, realFS.Dir(absPath), 0755); err != nil {
+		return err
+	}
+	tempPath := fmt.Sprintf("%s.%d.tmp", absPath, os.Getpid())
+	if err := ioutil.WriteFile(tempPath, []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, absPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+type entryPointGroup struct {
+	entryPoints []bundler.EntryPoint
+	overrides   EntryPoint
+	options     config.Options
+	resolver    resolver.Resolver
+	bundle      bundler.Bundle
+}
+
+func hasEntryPointOverrides(ep EntryPoint) bool {
+	return ep.Format != FormatDefault || ep.Platform != nil || ep.Define != nil || ep.Banner != nil || ep.Loader != nil || ep.Inject != nil
+}
+
+// Entry points with overrides are built as separate bundles with one entry
+// point each. This returns the remaining entry points, which are all built
+// together, followed by one group for each entry point with overrides.
+func splitEntryPointGroups(buildOpts BuildOptions) ([]bundler.EntryPoint, []entryPointGroup) {
+	entryPoints := make([]bundler.EntryPoint, 0, len(buildOpts.EntryPoints)+len(buildOpts.EntryPointsAdvanced))
+	for _, ep := range buildOpts.EntryPoints {
+		entryPoints = append(entryPoints, bundler.EntryPoint{InputPath: ep})
+	}
+	var overrideGroups []entryPointGroup
+	for _, ep := range buildOpts.EntryPointsAdvanced {
+		entryPoint := bundler.EntryPoint{InputPath: ep.InputPath, OutputPath: ep.OutputPath, Conditions: ep.Conditions}
+		if hasEntryPointOverrides(ep) {
+			overrideGroups = append(overrideGroups, entryPointGroup{entryPoints: []bundler.EntryPoint{entryPoint}, overrides: ep})
+			continue
+		}
+		entryPoints = append(entryPoints, entryPoint)
+	}
+	return entryPoints, overrideGroups
+}
+
+// Each entry point with overrides gets a copy of the options with the
+// overrides applied, and its own resolver since it may resolve paths
+// differently (e.g. for a different platform). This must be called before
+// the format and mode are set in the shared options because those depend on
+// the platform.
+func configureOverrideGroups(log logger.Log, realFS fs.FS, caches *cache.CacheSet, buildOpts BuildOptions, overrideGroups []entryPointGroup, options config.Options) {
+	for i := range overrideGroups {
+		group := &overrideGroups[i]
+		group.options = applyEntryPointOverrides(log, realFS, buildOpts, group.overrides, options)
+		setOutputFormatAndMode(log, &group.options, buildOpts.Bundle)
+		group.resolver = resolver.NewResolver(realFS, log, caches, group.options)
+	}
+}
+
+// Each group is scanned as a separate bundle, but they share the same caches
+// so files that are parsed the same way in each bundle are only parsed once
+func scanEntryPointGroups(log logger.Log, realFS fs.FS, caches *cache.CacheSet, groups []entryPointGroup, timer *helpers.Timer) {
+	for i := range groups {
+		group := &groups[i]
+		group.bundle = bundler.ScanBundle(log, realFS, group.resolver, caches, group.entryPoints, group.options, timer)
+	}
+	if len(groups) > 1 {
+		bundles := make([]*bundler.Bundle, len(groups))
+		for i := range groups {
+			bundles[i] = &groups[i].bundle
+		}
+		bundler.ShareOutputBase(realFS, bundles)
+	}
+}
+
+// This compiles each group and combines their output files and metafiles
+func compileEntryPointGroups(
+	log logger.Log,
+	realFS fs.FS,
+	groups []entryPointGroup,
+	timer *helpers.Timer,
+	mangleCache map[string]interface{},
+) ([]graph.OutputFile, string) {
+	var results []graph.OutputFile
+	var metafiles []string
+	for _, group := range groups {
+		groupResults, groupMetafile := group.bundle.Compile(log, group.options, timer, mangleCache)
+		results = append(results, groupResults...)
+		metafiles = append(metafiles, groupMetafile)
+	}
+	if len(groups) > 1 {
+		results = removeDuplicateOutputFiles(log, realFS, results)
+	}
+	return results, mergeMetafiles(metafiles)
+}
+
+func applyEntryPointOverrides(log logger.Log, realFS fs.FS, buildOpts BuildOptions, ep EntryPoint, options config.Options) config.Options {
+	platform := buildOpts.Platform
+	if ep.Platform != nil {
+		platform = *ep.Platform
+	}
+	format := buildOpts.Format
+	if ep.Format != FormatDefault {
+		format = ep.Format
+	}
+	options.Platform = validatePlatform(platform)
+	options.OutputFormat = validateFormat(format)
+	options.TreeShaking = validateTreeShaking(buildOpts.TreeShaking, buildOpts.Bundle, format)
+
+	// Stdin and virtual entry points belong to the main bundle, but virtual
+	// modules must still be importable from this entry point
+	options.Stdin = nil
+	if len(options.VirtualModules) > 0 {
+		virtualModules := make([]config.VirtualModule, len(options.VirtualModules))
+		for i, virtual := range options.VirtualModules {
+			virtual.IsEntryPoint = false
+			virtualModules[i] = virtual
+		}
+		options.VirtualModules = virtualModules
+	}
+
+	// The default defines depend on the platform, so they must be regenerated
+	// if either the defines or the platform are overridden
+	if ep.Define != nil || ep.Platform != nil {
+		minify := buildOpts.MinifyWhitespace && buildOpts.MinifyIdentifiers && buildOpts.MinifySyntax
+		define := mergeEntryPointOverrideMap(buildOpts.Define, ep.Define)
+		options.Defines, options.InjectedDefines = validateDefines(log, define, buildOpts.Pure, platform, minify, buildOpts.Drop, buildOpts.DropCalls)
+	}
+	if ep.Inject != nil || (ep.Platform != nil && len(buildOpts.PlatformInject) > 0) {
+		options.InjectAbsPaths = validateInjectPaths(log, realFS, buildOpts, platform, ep.Inject)
+	}
+	if ep.Banner != nil {
+		options.JSBanner, options.CSSBanner = validateBannerOrFooter(log, "banner", mergeEntryPointOverrideMap(buildOpts.Banner, ep.Banner))
+	}
+	if ep.Loader != nil {
+		loaders := make(map[string]Loader)
+		for ext, loader := range buildOpts.Loader {
+			loaders[ext] = loader
+		}
+		for ext, loader := range ep.Loader {
+			loaders[ext] = loader
+		}
+		options.ExtensionToLoader = validateLoaders(log, loaders)
+	}
+	return options
+}
+
+// Files injected for a specific platform come after the files injected for
+// every platform, and files injected for a specific entry point come last
+func validateInjectPaths(log logger.Log, fs fs.FS, buildOpts BuildOptions, platform Platform, entryPointInject []string) []string {
+	platformInject := buildOpts.PlatformInject[platform]
+	absPaths := make([]string, 0, len(buildOpts.Inject)+len(platformInject)+len(entryPointInject))
+	for _, paths := range [][]string{buildOpts.Inject, platformInject, entryPointInject} {
+		for _, path := range paths {
+			absPaths = append(absPaths, validatePath(log, fs, path, "inject path"))
+		}
+	}
+	return absPaths
+}
+
+func mergeEntryPointOverrideMap(base map[string]string, overrides map[string]string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range overrides {
+		result[key] = value
+	}
+	return result
+}
+
+func setOutputFormatAndMode(log logger.Log, options *config.Options, bundle bool) {
+	if bundle && options.OutputFormat == config.FormatPreserve {
+		// If the format isn't specified, set the default format using the platform
+		switch options.Platform {
+		case config.PlatformBrowser:
+			options.OutputFormat = config.FormatIIFE
+		case config.PlatformNode:
+			options.OutputFormat = config.FormatCommonJS
+		case config.PlatformNeutral, config.PlatformDeno:
+			options.OutputFormat = config.FormatESModule
+		}
+	}
+
+	// Set the output mode using other settings
+	if bundle {
+		options.Mode = config.ModeBundle
+	} else if options.OutputFormat != config.FormatPreserve {
+		options.Mode = config.ModeConvertFormat
+	}
+
+	// Code splitting is experimental and currently only enabled for ES6 modules
+	if options.CodeSplitting && options.OutputFormat != config.FormatESModule {
+		log.AddError(nil, logger.Range{}, "Splitting currently only works with the \"esm\" format")
+	}
+}
+
+// Each bundle already filters out duplicate output files, but bundles for
+// entry points with overrides may still generate the same file as each other
+// (e.g. an asset that's used by both). Identical files are only kept once.
+func removeDuplicateOutputFiles(log logger.Log, realFS fs.FS, results []graph.OutputFile) []graph.OutputFile {
+	outputFileMap := make(map[string][]byte)
+	end := 0
+	for _, result := range results {
+		contents, ok := outputFileMap[result.AbsPath]
+		if !ok {
+			outputFileMap[result.AbsPath] = result.Contents
+			results[end] = result
+			end++
+			continue
+		}
+		if !bytes.Equal(contents, result.Contents) {
+			outputPath := result.AbsPath
+			if relPath, ok := realFS.Rel(realFS.Cwd(), outputPath); ok {
+				outputPath = relPath
+			}
+			log.AddError(nil, logger.Range{}, "Two output files share the same path but have different contents: "+outputPath)
+		}
+	}
+	return results[:end]
+}
+
+// Each bundle generates its own metafile, so these need to be combined when
+// there are entry points with overrides. Inputs that are shared between
+// bundles are only listed once.
+func mergeMetafiles(metafiles []string) string {
+	return mergeJSONSections(metafiles, []string{"inputs", "outputs"})
+}
+
+// This combines JSON files that each contain the given top-level objects,
+// such as metafiles. Properties that appear in more than one file are only
+// included once.
+func mergeJSONSections(files []string, sections []string) string {
+	if len(files) == 1 || files[0] == "" {
+		return files[0]
+	}
+	texts := make([][]string, len(sections))
+	seen := make(map[string]bool)
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	for _, file := range files {
+		result, ok := js_parser.ParseJSON(log, logger.Source{Contents: file}, js_parser.JSONOptions{})
+		if !ok {
+			continue
+		}
+		for i, section := range sections {
+			if object := getObjectPropertyObject(result, section); object != nil {
+				for _, prop := range object.Properties {
+					value, ok := prop.ValueOrNil.Data.(*js_ast.EObject)
+					if !ok {
+						continue
+					}
+					key := section + ":" + helpers.UTF16ToString(prop.Key.Data.(*js_ast.EString).Value)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					texts[i] = append(texts[i], file[prop.Key.Loc.Start:value.CloseBraceLoc.Start+1])
+				}
+			}
+		}
+	}
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, section := range sections {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(fmt.Sprintf("\n  %q: {", section))
+		for j, text := range texts[i] {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n    ")
+			sb.WriteString(text)
+		}
+		sb.WriteString("\n  }")
+	}
+	sb.WriteString("\n}\n")
+	return sb.String()
+}
+
+func prettyPrintByteCount(n int) string {
+	var size string
+	if n < 1024 {
+		size = fmt.Sprintf("%db ", n)
+	} else if n < 1024*1024 {
+		size = fmt.Sprintf("%.1fkb", float64(n)/(1024))
+	} else if n < 1024*1024*1024 {
+		size = fmt.Sprintf("%.1fmb", float64(n)/(1024*1024))
+	} else {
+		size = fmt.Sprintf("%.1fgb", float64(n)/(1024*1024*1024))
+	}
+	return size
+}
+
+func printSummary(logOptions logger.OutputOptions, outputFiles []OutputFile, start time.Time) {
+	var table logger.SummaryTable = make([]logger.SummaryTableEntry, len(outputFiles))
+
+	if len(outputFiles) > 0 {
+		if cwd, err := os.Getwd(); err == nil {
+			if realFS, err := fs.RealFS(fs.RealFSOptions{AbsWorkingDir: cwd}); err == nil {
+				for i, file := range outputFiles {
+					path, ok := realFS.Rel(realFS.Cwd(), file.Path)
+					if !ok {
+						path = file.Path
+					}
+					base := realFS.Base(path)
+					n := len(file.Contents)
+					table[i] = logger.SummaryTableEntry{
+						Dir:         path[:len(path)-len(base)],
+						Base:        base,
+						Size:        prettyPrintByteCount(n),
+						Bytes:       n,
+						IsSourceMap: strings.HasSuffix(base, ".map"),
+					}
+				}
+			}
+		}
+	}
+
+	// Don't print the time taken by the build if we're running under Yarn 1
+	// since Yarn 1 always prints its own copy of the time taken by each command
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, "npm_config_user_agent=") && strings.Contains(env, "yarn/1.") {
+			logger.PrintSummary(logOptions.Color, table, nil)
+			return
+		}
+	}
+
+	logger.PrintSummary(logOptions.Color, table, &start)
+}
+
+func rebuildImpl(
+	ctx context.Context,
+	buildOpts BuildOptions,
+	caches *cache.CacheSet,
+	plugins []config.Plugin,
+	finalizeBuildOptions func(*config.Options),
+	onEndCallbacks []func(*BuildResult),
+	logOptions logger.OutputOptions,
+	log logger.Log,
+	isRebuild bool,
+) internalBuildResult {
+	buildStart := time.Now()
+
+	// Convert and validate the buildOpts
+	realFS, err := fs.RealFS(fs.RealFSOptions{
+		AbsWorkingDir: buildOpts.AbsWorkingDir,
+		WantWatchData: buildOpts.Watch != nil,
+		MaxOpenFiles:  buildOpts.MaxOpenFiles,
+	})
+	if err != nil {
+		// This should already have been checked above
+		panic(err.Error())
+	}
+	targetFromAPI, jsFeatures, cssFeatures, runtimeFeatures, targetEnv := validateFeatures(log, buildOpts.Target, buildOpts.Engines)
+	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, buildOpts.Supported)
+	outJS, outCSS := validateOutputExtensions(log, buildOpts.OutExtensions)
 	bannerJS, bannerCSS := validateBannerOrFooter(log, "banner", buildOpts.Banner)
 	footerJS, footerCSS := validateBannerOrFooter(log, "footer", buildOpts.Footer)
 	minify := buildOpts.MinifyWhitespace && buildOpts.MinifyIdentifiers && buildOpts.MinifySyntax
-	defines, injectedDefines := validateDefines(log, buildOpts.Define, buildOpts.Pure, buildOpts.Platform, minify, buildOpts.Drop)
+	defines, injectedDefines := validateDefines(log, buildOpts.Define, buildOpts.Pure, buildOpts.Platform, minify, buildOpts.Drop, buildOpts.DropCalls)
 	mangleCache := cloneMangleCache(log, buildOpts.MangleCache)
+	remoteImports, absRemoteLockFile := validateRemoteImports(log, realFS, buildOpts)
 	options := config.Options{
 		TargetFromAPI:                      targetFromAPI,
 		UnsupportedJSFeatures:              jsFeatures.ApplyOverrides(jsOverrides, jsMask),
 		UnsupportedCSSFeatures:             cssFeatures.ApplyOverrides(cssOverrides, cssMask),
+		UnsupportedRuntimeFeatures:         runtimeFeatures,
 		UnsupportedJSFeatureOverrides:      jsOverrides,
 		UnsupportedJSFeatureOverridesMask:  jsMask,
 		UnsupportedCSSFeatureOverrides:     cssOverrides,
 		UnsupportedCSSFeatureOverridesMask: cssMask,
 		OriginalTargetEnv:                  targetEnv,
 		JSX: config.JSXOptions{
-			Preserve: buildOpts.JSXMode == JSXModePreserve,
-			Factory:  validateJSXExpr(log, buildOpts.JSXFactory, "factory"),
-			Fragment: validateJSXExpr(log, buildOpts.JSXFragment, "fragment"),
+			Preserve:         buildOpts.JSXMode == JSXModePreserve,
+			AutomaticRuntime: buildOpts.JSXMode == JSXModeAutomatic,
+			Factory:          validateJSXExpr(log, buildOpts.JSXFactory, "factory"),
+			Fragment:         validateJSXExpr(log, buildOpts.JSXFragment, "fragment"),
+			ImportSource:     buildOpts.JSXImportSource,
+			Development:      buildOpts.JSXDev,
 		},
+		JSXPathOverrides:      validateJSXOverrides(log, realFS, buildOpts.JSXOverrides),
 		Defines:               defines,
 		InjectedDefines:       injectedDefines,
 		Platform:              validatePlatform(buildOpts.Platform),
@@ -924,38 +2250,83 @@ func rebuildImpl(
 		LegalComments:         validateLegalComments(buildOpts.LegalComments, buildOpts.Bundle),
 		SourceRoot:            buildOpts.SourceRoot,
 		ExcludeSourcesContent: buildOpts.SourcesContent == SourcesContentExclude,
+		SourcesIgnoreList:     validateRegex(log, "sources ignore list", buildOpts.SourcesIgnoreList),
+		SourcesRewrite:        validateSourcesRewrite(buildOpts.SourcesRewrite),
 		MinifySyntax:          buildOpts.MinifySyntax,
 		MinifyWhitespace:      buildOpts.MinifyWhitespace,
 		MinifyIdentifiers:     buildOpts.MinifyIdentifiers,
+		NameSeed:              buildOpts.NameSeed,
+		RuntimePrefix:         validateRuntimePrefix(log, buildOpts.RuntimePrefix),
+		HashSalt:              buildOpts.HashSalt,
+		ManifestPath:          buildOpts.Manifest,
+		PolyfillReportPath:    buildOpts.PolyfillReport,
+		TreeShakingReportPath: buildOpts.TreeShakingReport,
+		Integrity:             buildOpts.Integrity,
+		DebugID:               buildOpts.DebugID,
 		MangleProps:           validateRegex(log, "mangle props", buildOpts.MangleProps),
 		ReserveProps:          validateRegex(log, "reserve props", buildOpts.ReserveProps),
 		MangleQuoted:          buildOpts.MangleQuoted == MangleQuotedTrue,
 		DropDebugger:          (buildOpts.Drop & DropDebugger) != 0,
+		DropDeadImports:       (buildOpts.Drop & DropDeadImports) != 0,
 		AllowOverwrite:        buildOpts.AllowOverwrite,
 		ASCIIOnly:             validateASCIIOnly(buildOpts.Charset),
+		BMPOnly:               buildOpts.Charset == CharsetBMP,
 		IgnoreDCEAnnotations:  buildOpts.IgnoreAnnotations,
+		PreserveComments:      buildOpts.PreserveComments,
+		PreserveFormatting:    buildOpts.PreserveFormatting,
+		TS:                    config.TSOptions{IsolatedModulesCheck: buildOpts.IsolatedModulesCheck},
 		TreeShaking:           validateTreeShaking(buildOpts.TreeShaking, buildOpts.Bundle, buildOpts.Format),
 		GlobalName:            validateGlobalName(log, buildOpts.GlobalName),
 		CodeSplitting:         buildOpts.Splitting,
+		BoundaryPackages:      append([]string{}, buildOpts.BoundaryPackages...),
+		DisallowedLicenses:    append([]string{}, buildOpts.DisallowedLicenses...),
+		VerifyLockfile:        buildOpts.VerifyLockfile,
 		OutputFormat:          validateFormat(buildOpts.Format),
 		AbsOutputFile:         validatePath(log, realFS, buildOpts.Outfile, "outfile path"),
 		AbsOutputDir:          validatePath(log, realFS, buildOpts.Outdir, "outdir path"),
 		AbsOutputBase:         validatePath(log, realFS, buildOpts.Outbase, "outbase path"),
 		NeedsMetafile:         buildOpts.Metafile,
+		NameMap:               buildOpts.NameMap,
+		PublishPackageJSON:    buildOpts.PublishPackageJSON,
+		TSDeclarations:        buildOpts.Declarations,
+		ConcatReport:          buildOpts.ConcatReport,
+		ScanSecrets:           buildOpts.ScanSecrets,
+		RefreshMetadata:       buildOpts.RefreshMetadata,
+		LowMemory:             buildOpts.LowMemory,
+		Deterministic:         buildOpts.Deterministic,
+		PurgeCSS:              buildOpts.PurgeCSS,
+		PurgeCSSSafelist:      validatePurgeCSSSafelist(log, buildOpts.PurgeCSSSafelist),
 		EntryPathTemplate:     validatePathTemplate(buildOpts.EntryNames),
 		ChunkPathTemplate:     validatePathTemplate(buildOpts.ChunkNames),
 		AssetPathTemplate:     validatePathTemplate(buildOpts.AssetNames),
+		AbsAssetRoot:          validatePath(log, realFS, buildOpts.AssetRoot, "asset root path"),
+		AbsAssetOutputDir:     validatePath(log, realFS, buildOpts.AssetOutdir, "asset outdir path"),
+		Copy:                  validateCopy(log, realFS, buildOpts.Copy),
 		OutputExtensionJS:     outJS,
 		OutputExtensionCSS:    outCSS,
 		ExtensionToLoader:     validateLoaders(log, buildOpts.Loader),
 		ExtensionOrder:        validateResolveExtensions(log, buildOpts.ResolveExtensions),
 		ExternalSettings:      validateExternals(log, realFS, buildOpts.External),
 		TsConfigOverride:      validatePath(log, realFS, buildOpts.Tsconfig, "tsconfig path"),
+		TsConfigNested:        buildOpts.TsconfigNested,
 		MainFields:            buildOpts.MainFields,
 		Conditions:            append([]string{}, buildOpts.Conditions...),
+		SideEffectsOverrides:  buildOpts.SideEffectsOverrides,
+		RemoteImports:         remoteImports,
+		ExternalGlobals:       validateExternalGlobals(log, buildOpts.ExternalGlobals),
+		SharedModules:         append([]string{}, buildOpts.Shared...),
+		SharedScope:           buildOpts.SharedScope,
+		DynamicRequire:        validateDynamicRequire(buildOpts.DynamicRequire),
+		AbsImportMapPath:      validatePath(log, realFS, buildOpts.ImportMap, "import map path"),
+		ImportMapExternal:     buildOpts.ImportMapExternal,
 		PublicPath:            buildOpts.PublicPath,
+		AssetPublicPaths:      validateAssetPublicPaths(log, buildOpts.AssetPublicPaths),
+		AssetInlineLimit:      buildOpts.AssetInlineLimit,
 		KeepNames:             buildOpts.KeepNames,
-		InjectAbsPaths:        make([]string, len(buildOpts.Inject)),
+		KeepNamesFilter:       validateRegex(log, "keep names filter", buildOpts.KeepNamesFilter),
+		KeepNamesOnly:         validateKeepNamesOnly(buildOpts.KeepNamesOnly),
+		InjectAbsPaths:        validateInjectPaths(log, realFS, buildOpts, buildOpts.Platform, nil),
+		InjectIfUsed:          buildOpts.InjectIfUsed,
 		AbsNodePaths:          make([]string, len(buildOpts.NodePaths)),
 		JSBanner:              bannerJS,
 		JSFooter:              footerJS,
@@ -965,23 +2336,33 @@ func rebuildImpl(
 		WatchMode:             buildOpts.Watch != nil,
 		Plugins:               plugins,
 	}
+	cancelFlag, stopCancelFlag := cancelFlagForContext(ctx)
+	defer stopCancelFlag()
+	options.CancelFlag = cancelFlag
+	options.Workers = helpers.MakeWorkerLimiter(buildOpts.MaxWorkers)
+	if buildOpts.Timing {
+		options.Timing = &config.BuildTiming{}
+		options.Plugins = options.Timing.WrapPlugins(plugins)
+	}
 	if options.MainFields != nil {
 		options.MainFields = append([]string{}, options.MainFields...)
 	}
-	for i, path := range buildOpts.Inject {
-		options.InjectAbsPaths[i] = validatePath(log, realFS, path, "inject path")
+	if callback := buildOpts.BannerCallback; callback != nil {
+		options.BannerCallback = func(args config.BannerArgs) (string, string) {
+			result := callback(BannerArgs{
+				EntryPoint: args.EntryPoint,
+				Kind:       args.Kind,
+				Name:       args.Name,
+			})
+			return result.Banner, result.Footer
+		}
 	}
+	options.StylePreprocessors = validateStylePreprocessors(log, realFS, buildOpts.StylePreprocessors)
 	for i, path := range buildOpts.NodePaths {
 		options.AbsNodePaths[i] = validatePath(log, realFS, path, "node path")
 	}
-	entryPoints := make([]bundler.EntryPoint, 0, len(buildOpts.EntryPoints)+len(buildOpts.EntryPointsAdvanced))
-	for _, ep := range buildOpts.EntryPoints {
-		entryPoints = append(entryPoints, bundler.EntryPoint{InputPath: ep})
-	}
-	for _, ep := range buildOpts.EntryPointsAdvanced {
-		entryPoints = append(entryPoints, bundler.EntryPoint{InputPath: ep.InputPath, OutputPath: ep.OutputPath})
-	}
-	entryPointCount := len(entryPoints)
+	entryPoints, overrideGroups := splitEntryPointGroups(buildOpts)
+	entryPointCount := len(entryPoints) + len(overrideGroups)
 	if buildOpts.Stdin != nil {
 		entryPointCount++
 		options.Stdin = &config.StdinInfo{
@@ -991,6 +2372,38 @@ func rebuildImpl(
 			AbsResolveDir: validatePath(log, realFS, buildOpts.Stdin.ResolveDir, "resolve directory path"),
 		}
 	}
+	if len(buildOpts.VirtualEntryPoints) > 0 || len(buildOpts.VirtualModules) > 0 {
+		seen := make(map[string]bool)
+		addVirtualModule := func(kind string, name string, contents string, resolveDir string, loader Loader, isEntryPoint bool) {
+			if name == "" || realFS.IsAbs(name) {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid virtual %s name: %q (must be a relative path)", kind, name))
+				return
+			}
+			if seen[name] {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Duplicate virtual %s name: %q", kind, name))
+				return
+			}
+			seen[name] = true
+			absResolveDir := realFS.Cwd()
+			if resolveDir != "" {
+				absResolveDir = validatePath(log, realFS, resolveDir, "resolve directory path")
+			}
+			options.VirtualModules = append(options.VirtualModules, config.VirtualModule{
+				Name:          name,
+				Contents:      contents,
+				AbsResolveDir: absResolveDir,
+				Loader:        validateLoader(loader),
+				IsEntryPoint:  isEntryPoint,
+			})
+		}
+		for _, ep := range buildOpts.VirtualEntryPoints {
+			addVirtualModule("entry point", ep.Name, ep.Contents, ep.ResolveDir, ep.Loader, true)
+		}
+		for _, module := range buildOpts.VirtualModules {
+			addVirtualModule("module", module.Name, module.Contents, module.ResolveDir, module.Loader, false)
+		}
+		entryPointCount += len(buildOpts.VirtualEntryPoints)
+	}
 
 	if options.AbsOutputDir == "" && entryPointCount > 1 {
 		log.AddError(nil, logger.Range{},
@@ -1003,6 +2416,11 @@ func rebuildImpl(
 	} else if options.AbsOutputFile != "" {
 		// If the output file is specified, use it to derive the output directory
 		options.AbsOutputDir = realFS.Dir(options.AbsOutputFile)
+	} else if options.AbsOutputDir == "" && buildOpts.GraphOnly {
+		// Nothing is written when only generating the import graph, so there's no
+		// need for an output path. External paths are relative to the current
+		// directory instead.
+		options.AbsOutputDir = realFS.Cwd()
 	} else if options.AbsOutputDir == "" {
 		options.WriteToStdout = true
 
@@ -1013,11 +2431,34 @@ func rebuildImpl(
 		if options.LegalComments.HasExternalFile() {
 			log.AddError(nil, logger.Range{}, "Cannot use linked or external legal comments without an output path")
 		}
+		if options.NameMap {
+			log.AddError(nil, logger.Range{}, "Cannot use a name map without an output path")
+		}
+		if options.PublishPackageJSON {
+			log.AddError(nil, logger.Range{}, "Cannot generate a publishable \"package.json\" file without an output path")
+		}
+		if options.ImportMapExternal {
+			log.AddError(nil, logger.Range{}, "Cannot write an external import map without an output path")
+		}
+		if options.TSDeclarations {
+			log.AddError(nil, logger.Range{}, "Cannot generate declaration files without an output path")
+		}
+		if options.ConcatReport {
+			log.AddError(nil, logger.Range{}, "Cannot generate a concatenation report without an output path")
+		}
 		for _, loader := range options.ExtensionToLoader {
 			if loader == config.LoaderFile {
 				log.AddError(nil, logger.Range{}, "Cannot use the \"file\" loader without an output path")
 				break
 			}
+			if loader == config.LoaderWasmFile {
+				log.AddError(nil, logger.Range{}, "Cannot use the \"wasm-file\" loader without an output path")
+				break
+			}
+			if loader == config.LoaderNAPI {
+				log.AddError(nil, logger.Range{}, "Cannot use the \"napi\" loader without an output path")
+				break
+			}
 			if loader == config.LoaderCopy {
 				log.AddError(nil, logger.Range{}, "Cannot use the \"copy\" loader without an output path")
 				break
@@ -1029,38 +2470,49 @@ func rebuildImpl(
 		options.AbsOutputDir = realFS.Cwd()
 	}
 
+	if len(options.BoundaryPackages) > 0 && !options.CodeSplitting {
+		log.AddError(nil, logger.Range{}, "Cannot use boundary packages without code splitting")
+	}
+
 	if !buildOpts.Bundle {
 		// Disallow bundle-only options when not bundling
 		if options.ExternalSettings.PreResolve.HasMatchers() || options.ExternalSettings.PostResolve.HasMatchers() {
 			log.AddError(nil, logger.Range{}, "Cannot use \"external\" without \"bundle\"")
 		}
-	} else if options.OutputFormat == config.FormatPreserve {
-		// If the format isn't specified, set the default format using the platform
-		switch options.Platform {
-		case config.PlatformBrowser:
-			options.OutputFormat = config.FormatIIFE
-		case config.PlatformNode:
-			options.OutputFormat = config.FormatCommonJS
-		case config.PlatformNeutral:
-			options.OutputFormat = config.FormatESModule
+		if options.PurgeCSS {
+			log.AddError(nil, logger.Range{}, "Cannot use \"purge css\" without \"bundle\"")
+		}
+		if buildOpts.GraphOnly {
+			log.AddError(nil, logger.Range{}, "Cannot use \"graph only\" without \"bundle\"")
 		}
 	}
 
-	// Set the output mode using other settings
-	if buildOpts.Bundle {
-		options.Mode = config.ModeBundle
-	} else if options.OutputFormat != config.FormatPreserve {
-		options.Mode = config.ModeConvertFormat
+	// The metafile describes the output files, but no output files are
+	// generated when only the import graph is needed
+	if buildOpts.GraphOnly && buildOpts.Metafile {
+		log.AddError(nil, logger.Range{}, "Cannot use \"metafile\" with \"graph only\"")
 	}
 
-	// Code splitting is experimental and currently only enabled for ES6 modules
-	if options.CodeSplitting && options.OutputFormat != config.FormatESModule {
-		log.AddError(nil, logger.Range{}, "Splitting currently only works with the \"esm\" format")
-	}
+	configureOverrideGroups(log, realFS, caches, buildOpts, overrideGroups, options)
+	setOutputFormatAndMode(log, &options, buildOpts.Bundle)
 
 	var outputFiles []OutputFile
 	var metafileJSON string
+	var graphJSON string
+	var legalComments []LegalComment
 	var watchData fs.WatchData
+	var durations statusFileDurations
+
+	var absStatusFile string
+	if buildOpts.StatusFile != "" {
+		absStatusFile = validatePath(log, realFS, buildOpts.StatusFile, "status file path")
+	}
+	if buildOpts.Clean {
+		validateCleanOutputDirectory(log, realFS, buildOpts, options.AbsOutputDir)
+	}
+	if buildOpts.AtomicWrite {
+		validateAtomicWriteOutputDirectory(log, realFS, buildOpts, options.AbsOutputDir)
+	}
 
 	// Stop now if there were errors
 	resolver := resolver.NewResolver(realFS, log, caches, options)
@@ -1075,14 +2527,70 @@ func rebuildImpl(
 			finalizeBuildOptions(&options)
 		}
 
-		// Scan over the bundle
-		bundle := bundler.ScanBundle(log, realFS, resolver, caches, entryPoints, options, timer)
+		// Scan over the bundle. Entry points without overrides are built together
+		// in the first group, which is left out if every entry point has overrides.
+		groups := overrideGroups
+		if len(entryPoints) > 0 || len(overrideGroups) == 0 || options.Stdin != nil || len(buildOpts.VirtualEntryPoints) > 0 {
+			groups = append([]entryPointGroup{{entryPoints: entryPoints, options: options, resolver: resolver}}, groups...)
+		}
+		phaseStart := time.Now()
+		scanEntryPointGroups(log, realFS, caches, groups, timer)
 		watchData = realFS.WatchData()
+		durations.scan = time.Since(phaseStart)
+		if buildOpts.Clean && buildOpts.Write && !log.HasErrors() {
+			validateOutputDirectoryInputFiles(log, realFS, options.AbsOutputDir, groups, "clean")
+		}
+		if buildOpts.AtomicWrite && buildOpts.Write && !log.HasErrors() {
+			validateOutputDirectoryInputFiles(log, realFS, resolveAtomicWriteOutputDirectory(options.AbsOutputDir), groups, "replace")
+		}
+		checkForCancellation(log, cancelFlag)
+		if options.Timing != nil {
+			options.Timing.Scan = durations.scan
+		}
 
-		// Stop now if there were errors
-		if !log.HasErrors() {
+		// Add the integrity of newly-downloaded remote modules to the lock file.
+		// This is skipped when nothing is being written to the file system.
+		if absRemoteLockFile != "" && buildOpts.Write && !log.HasErrors() {
+			if newIntegrity := caches.RemoteCache.TakeNewIntegrity(); len(newIntegrity) > 0 {
+				if err := writeRemoteLockFile(realFS, absRemoteLockFile, options.RemoteImports.Integrity, newIntegrity); err != nil {
+					log.AddError(nil, logger.Range{}, fmt.Sprintf("Failed to write lock file: %s", err.Error()))
+				}
+			}
+		}
+
+		// Parsing is done at this point, so the caches are only useful for
+		// future builds. Drop them now if memory is tight so that the memory
+		// can be reused for linking instead.
+		if buildOpts.LowMemory {
+			caches.Flush()
+			debug.FreeOSMemory()
+		} else if buildOpts.MemoryLimit > 0 {
+			flushCachesIfOverMemoryLimit(log, caches, buildOpts.MemoryLimit)
+		}
+
+		// Linking and printing are skipped if only the import graph is needed
+		if buildOpts.GraphOnly {
+			if !log.HasErrors() {
+				graphs := make([]string, len(groups))
+				for i, group := range groups {
+					graphs[i] = group.bundle.GenerateImportGraphJSON(options.ASCIIOnly)
+				}
+				graphJSON = mergeJSONSections(graphs, []string{"inputs"})
+				log.AlmostDone()
+			}
+		} else if !log.HasErrors() {
 			// Compile the bundle
-			results, metafile := bundle.Compile(log, options, timer, mangleCache)
+			phaseStart = time.Now()
+			results, metafile := compileEntryPointGroups(log, realFS, groups, timer, mangleCache)
+			durations.compile = time.Since(phaseStart)
+			checkForCancellation(log, cancelFlag)
+
+			// Build everything a second time and check that nothing changed
+			if buildOpts.VerifyDeterminism && !log.HasErrors() {
+				timer.Begin("Verify determinism")
+				verifyDeterminism(log, realFS, buildOpts, groups, results, metafile)
+				timer.End("Verify determinism")
+			}
 
 			// Stop now if there were errors
 			if !log.HasErrors() {
@@ -1092,6 +2600,7 @@ func rebuildImpl(
 				log.AlmostDone()
 
 				if buildOpts.Write {
+					phaseStart = time.Now()
 					timer.Begin("Write output files")
 					if options.WriteToStdout {
 						// Special-case writing to stdout
@@ -1103,35 +2612,59 @@ func rebuildImpl(
 								"Failed to write to stdout: %s", err.Error()))
 						}
 					} else {
-						// Write out files in parallel
-						waitGroup := sync.WaitGroup{}
-						waitGroup.Add(len(results))
-						for _, result := range results {
-							go func(result graph.OutputFile) {
-								fs.BeforeFileOpen()
-								defer fs.AfterFileClose()
-								if err := fs.MkdirAll(realFS, realFS.Dir(result.AbsPath), 0755); err != nil {
-									log.AddError(nil, logger.Range{}, fmt.Sprintf(
-										"Failed to create output directory: %s", err.Error()))
-								} else {
-									var mode os.FileMode = 0644
-									if result.IsExecutable {
-										mode = 0755
-									}
-									if err := ioutil.WriteFile(result.AbsPath, result.Contents, mode); err != nil {
-										log.AddError(nil, logger.Range{}, fmt.Sprintf(
-											"Failed to write to output file: %s", err.Error()))
-									}
-								}
-								waitGroup.Done()
-							}(result)
+						// With atomic writes, everything is written to a staging directory
+						// first which then replaces the output directory all at once
+						writeResults := results
+						absWriteDir := options.AbsOutputDir
+						keepStatusFile := absStatusFile
+						if buildOpts.AtomicWrite {
+							absWriteDir, writeResults = stageAtomicWrite(log, realFS, options.AbsOutputDir, results)
+							if keepStatusFile != "" && isInsideDirectory(realFS, options.AbsOutputDir, keepStatusFile) {
+								relPath, _ := realFS.Rel(options.AbsOutputDir, keepStatusFile)
+								keepStatusFile = realFS.Join(absWriteDir, relPath)
+							}
+						}
+
+						if !log.HasErrors() {
+							writeOutputFiles(log, realFS, writeResults, buildOpts.OnWriteProgress)
+						}
+
+						// Only remove stale files once the new ones are in place
+						if buildOpts.Clean && !log.HasErrors() {
+							keep := make(map[string]bool, len(writeResults)+1)
+							for _, result := range writeResults {
+								keep[result.AbsPath] = true
+							}
+							if keepStatusFile != "" {
+								keep[keepStatusFile] = true
+							}
+							cleanOutputDirectory(log, absWriteDir, keep, buildOpts.CleanRetain)
+						}
+
+						if buildOpts.AtomicWrite {
+							finishAtomicWrite(log, absWriteDir, options.AbsOutputDir)
 						}
-						waitGroup.Wait()
 					}
 					timer.End("Write output files")
+					durations.write = time.Since(phaseStart)
+					if options.Timing != nil {
+						options.Timing.Write = durations.write
+					}
+				}
+
+				if options.Timing != nil {
+					logBuildTiming(log, options.Timing)
+
+					// Durations are different for every build
+					if metafileJSON != "" && !options.Deterministic {
+						metafileJSON = addTimingToMetafile(metafileJSON, options.Timing)
+					}
 				}
 
 				// Return the results
+				if buildOpts.CollectLegalComments {
+					legalComments = collectLegalComments(results)
+				}
 				outputFiles = make([]OutputFile, len(results))
 				for i, result := range results {
 					if options.WriteToStdout {
@@ -1148,19 +2681,39 @@ func rebuildImpl(
 		timer.Log(log)
 	}
 
+	// Validate the watch options before ending the log
+	var watchIgnore *regexp.Regexp
+	shouldWatch := buildOpts.Watch != nil && !isRebuild
+	if shouldWatch {
+		watchIgnore = validateRegex(log, "watch ignore", buildOpts.Watch.Ignore)
+		if buildOpts.Watch.Ignore != "" && watchIgnore == nil {
+			shouldWatch = false
+		}
+		if buildOpts.Watch.Interval < 0 {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid watch interval: %d", buildOpts.Watch.Interval))
+			shouldWatch = false
+		}
+	}
+
 	// End the log now, which may print a message
 	msgs := log.Done()
 
 	// Start watching, but only for the top-level build
 	var watch *watcher
 	var stop func()
-	if buildOpts.Watch != nil && !isRebuild {
+	if shouldWatch {
 		onRebuild := buildOpts.Watch.OnRebuild
+		interval := watchIntervalSleep
+		if buildOpts.Watch.Interval > 0 {
+			interval = time.Duration(buildOpts.Watch.Interval) * time.Millisecond
+		}
 		watch = &watcher{
 			data:     watchData,
 			resolver: resolver,
+			interval: interval,
+			ignore:   watchIgnore,
 			rebuild: func() fs.WatchData {
-				value := rebuildImpl(buildOpts, caches, plugins, nil, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
+				value := rebuildImpl(context.Background(), buildOpts, caches, plugins, nil, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
 				if onRebuild != nil {
 					go onRebuild(value.result)
 				}
@@ -1177,7 +2730,7 @@ func rebuildImpl(
 	var rebuild func() BuildResult
 	if buildOpts.Incremental {
 		rebuild = func() BuildResult {
-			value := rebuildImpl(buildOpts, caches, plugins, nil, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
+			value := rebuildImpl(context.Background(), buildOpts, caches, plugins, nil, onEndCallbacks, logOptions, logger.NewStderrLog(logOptions), true /* isRebuild */)
 			if watch != nil {
 				watch.setWatchData(value.watchData)
 			}
@@ -1191,13 +2744,25 @@ func rebuildImpl(
 	}
 
 	result := BuildResult{
-		Errors:      convertMessagesToPublic(logger.Error, msgs),
-		Warnings:    convertMessagesToPublic(logger.Warning, msgs),
-		OutputFiles: outputFiles,
-		Metafile:    metafileJSON,
-		Rebuild:     rebuild,
-		Stop:        stop,
-		MangleCache: mangleCache,
+		Errors:        convertMessagesToPublic(logger.Error, msgs),
+		Warnings:      convertMessagesToPublic(logger.Warning, msgs),
+		OutputFiles:   outputFiles,
+		Metafile:      metafileJSON,
+		Graph:         graphJSON,
+		Rebuild:       rebuild,
+		Stop:          stop,
+		MangleCache:   mangleCache,
+		LegalComments: legalComments,
+	}
+
+	// Write the status file last so that it describes the whole build
+	if absStatusFile != "" {
+		durations.total = time.Since(buildStart)
+		if err := writeStatusFile(realFS, absStatusFile, &result, durations); err != nil {
+			result.Errors = append(result.Errors, Message{
+				Text: fmt.Sprintf("Failed to write status file: %s", err.Error()),
+			})
+		}
 	}
 
 	for _, onEnd := range onEndCallbacks {
@@ -1215,6 +2780,8 @@ type watcher struct {
 	data              fs.WatchData
 	resolver          resolver.Resolver
 	rebuild           func() fs.WatchData
+	ignore            *regexp.Regexp
+	interval          time.Duration
 	recentItems       []string
 	itemsToScan       []string
 	mutex             sync.Mutex
@@ -1239,7 +2806,7 @@ func (w *watcher) setWatchData(data fs.WatchData) {
 	w.recentItems = w.recentItems[:end]
 }
 
-// The time to wait between watch intervals
+// The default time to wait between watch intervals
 const watchIntervalSleep = 100 * time.Millisecond
 
 // The maximum number of recently-edited items to check every interval
@@ -1267,32 +2834,68 @@ func (w *watcher) start(logLevel LogLevel, color StderrColor, mode WatchMode) {
 			})
 		}
 
+		rebuildForChange := func(absPath string) {
+			if shouldLog {
+				logger.PrintTextWithColor(os.Stderr, useColor, func(colors logger.Colors) string {
+					prettyPath := w.resolver.PrettyPath(logger.Path{Text: absPath, Namespace: "file"})
+					return fmt.Sprintf("%s[watch] build started (change: %q)%s\n", colors.Dim, prettyPath, colors.Reset)
+				})
+			}
+
+			// Run the build
+			w.setWatchData(w.rebuild())
+
+			if shouldLog {
+				logger.PrintTextWithColor(os.Stderr, useColor, func(colors logger.Colors) string {
+					return fmt.Sprintf("%s[watch] build finished%s\n", colors.Dim, colors.Reset)
+				})
+			}
+		}
+
+		// Use the external change feed instead of polling if there is one
+		if mode.Changes != nil {
+			for changes := range mode.Changes {
+				if atomic.LoadInt32(&w.shouldStop) != 0 {
+					break
+				}
+				if absPath := w.findChangedPath(changes); absPath != "" {
+					rebuildForChange(absPath)
+				}
+			}
+			return
+		}
+
 		for atomic.LoadInt32(&w.shouldStop) == 0 {
 			// Sleep for the watch interval
-			time.Sleep(watchIntervalSleep)
+			time.Sleep(w.interval)
 
 			// Rebuild if we're dirty
 			if absPath := w.tryToFindDirtyPath(); absPath != "" {
-				if shouldLog {
-					logger.PrintTextWithColor(os.Stderr, useColor, func(colors logger.Colors) string {
-						prettyPath := w.resolver.PrettyPath(logger.Path{Text: absPath, Namespace: "file"})
-						return fmt.Sprintf("%s[watch] build started (change: %q)%s\n", colors.Dim, prettyPath, colors.Reset)
-					})
-				}
-
-				// Run the build
-				w.setWatchData(w.rebuild())
-
-				if shouldLog {
-					logger.PrintTextWithColor(os.Stderr, useColor, func(colors logger.Colors) string {
-						return fmt.Sprintf("%s[watch] build finished%s\n", colors.Dim, colors.Reset)
-					})
-				}
+				rebuildForChange(absPath)
 			}
 		}
 	}()
 }
 
+// Changes from an external feed are trusted instead of being checked against
+// the file system, since the feed may know about changes that polling misses
+// (e.g. on network file systems). A change to a file also counts as a change
+// to its parent directory since the directory listing may now be different.
+func (w *watcher) findChangedPath(changes []string) string {
+	defer w.mutex.Unlock()
+	w.mutex.Lock()
+
+	for _, absPath := range changes {
+		if w.ignore != nil && w.ignore.MatchString(absPath) {
+			continue
+		}
+		if w.data.Paths[absPath] != nil || w.data.Paths[filepath.Dir(absPath)] != nil {
+			return absPath
+		}
+	}
+	return ""
+}
+
 func (w *watcher) stop() {
 	atomic.StoreInt32(&w.shouldStop, 1)
 }
@@ -1305,6 +2908,9 @@ func (w *watcher) tryToFindDirtyPath() string {
 	if len(w.itemsToScan) == 0 {
 		items := w.itemsToScan[:0] // Reuse memory
 		for path := range w.data.Paths {
+			if w.ignore != nil && w.ignore.MatchString(path) {
+				continue
+			}
 			items = append(items, path)
 		}
 		rand.Seed(time.Now().UnixNano())
@@ -1359,29 +2965,95 @@ func (w *watcher) tryToFindDirtyPath() string {
 ////////////////////////////////////////////////////////////////////////////////
 // Transform API
 
-func transformImpl(input string, transformOpts TransformOptions) TransformResult {
-	log := logger.NewStderrLog(logger.OutputOptions{
+func transformLogOptions(transformOpts *TransformOptions) logger.OutputOptions {
+	return logger.OutputOptions{
 		IncludeSource: true,
 		MessageLimit:  transformOpts.LogLimit,
 		Color:         validateColor(transformOpts.Color),
 		LogLevel:      validateLogLevel(transformOpts.LogLevel),
 		Overrides:     validateLogOverrides(transformOpts.LogOverride),
-	})
+		Format:        validateLogFormat(transformOpts.LogFormat),
+		OnMessage:     validateOnLogMessage(transformOpts.OnLogMessage),
+	}
+}
+
+func transformImpl(ctx context.Context, input string, transformOpts TransformOptions) TransformResult {
+	log := logger.NewStderrLog(transformLogOptions(&transformOpts))
+	options, mangleCache := validateTransformOptions(log, &transformOpts)
+	return transformInput(ctx, log, options, &transformOpts, TransformBatchInput{Contents: input}, mangleCache)
+}
+
+func transformBatchImpl(ctx context.Context, inputs []TransformBatchInput, transformOpts TransformOptions) []TransformResult {
+	results := make([]TransformResult, len(inputs))
+
+	// The shared options are only validated once. Messages about them are only
+	// logged once but are included in the result for every input.
+	logOptions := transformLogOptions(&transformOpts)
+	optionsLog := logger.NewStderrLog(logOptions)
+	options, mangleCache := validateTransformOptions(optionsLog, &transformOpts)
+	hasErrors := optionsLog.HasErrors()
+	msgs := optionsLog.Done()
+	optionErrors := convertMessagesToPublic(logger.Error, msgs)
+	optionWarnings := convertMessagesToPublic(logger.Warning, msgs)
+	if hasErrors {
+		for i := range results {
+			results[i] = TransformResult{Errors: optionErrors, Warnings: optionWarnings}
+		}
+		return results
+	}
+
+	// Transform the inputs in parallel. Each input starts off with its own copy
+	// of the mangle cache since the inputs are independent of each other.
+	workers := helpers.MakeWorkerLimiter(runtime.GOMAXPROCS(0))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(inputs))
+	for i, input := range inputs {
+		go func(i int, input TransformBatchInput) {
+			workers.Acquire()
+			var inputMangleCache map[string]interface{}
+			if mangleCache != nil {
+				inputMangleCache = make(map[string]interface{}, len(mangleCache))
+				for k, v := range mangleCache {
+					inputMangleCache[k] = v
+				}
+			}
+			result := transformInput(ctx, logger.NewStderrLog(logOptions), options, &transformOpts, input, inputMangleCache)
+			if len(optionErrors) > 0 {
+				result.Errors = append(append([]Message{}, optionErrors...), result.Errors...)
+			}
+			if len(optionWarnings) > 0 {
+				result.Warnings = append(append([]Message{}, optionWarnings...), result.Warnings...)
+			}
+			results[i] = result
+			workers.Release()
+			waitGroup.Done()
+		}(i, input)
+	}
+	waitGroup.Wait()
+	return results
+}
 
+// This converts the transform options into the internal options shared by all
+// inputs. The input-specific options are filled in later by "transformInput".
+func validateTransformOptions(log logger.Log, transformOpts *TransformOptions) (config.Options, map[string]interface{}) {
 	// Settings from the user come first
 	var unusedImportFlagsTS config.UnusedImportFlagsTS
 	useDefineForClassFieldsTS := config.Unspecified
 	jsx := config.JSXOptions{
-		Preserve: transformOpts.JSXMode == JSXModePreserve,
-		Factory:  validateJSXExpr(log, transformOpts.JSXFactory, "factory"),
-		Fragment: validateJSXExpr(log, transformOpts.JSXFragment, "fragment"),
+		Preserve:         transformOpts.JSXMode == JSXModePreserve,
+		AutomaticRuntime: transformOpts.JSXMode == JSXModeAutomatic,
+		Factory:          validateJSXExpr(log, transformOpts.JSXFactory, "factory"),
+		Fragment:         validateJSXExpr(log, transformOpts.JSXFragment, "fragment"),
+		ImportSource:     transformOpts.JSXImportSource,
+		Development:      transformOpts.JSXDev,
 	}
 
 	// Settings from "tsconfig.json" override those
 	var tsTarget *config.TSTarget
 	var tsAlwaysStrict *config.TSAlwaysStrict
-	caches := cache.MakeCacheSet()
+	var emitDecoratorMetadata bool
 	if transformOpts.TsconfigRaw != "" {
+		caches := cache.MakeCacheSet()
 		source := logger.Source{
 			KeyPath:    logger.Path{Text: "tsconfig.json"},
 			PrettyPath: "tsconfig.json",
@@ -1394,6 +3066,12 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 			if len(result.JSXFragmentFactory) > 0 {
 				jsx.Fragment = config.DefineExpr{Parts: result.JSXFragmentFactory}
 			}
+			if !jsx.Preserve {
+				result.JSX.ApplyTo(&jsx)
+			}
+			if result.JSXImportSource != nil {
+				jsx.ImportSource = *result.JSXImportSource
+			}
 			if result.UseDefineForClassFields != config.Unspecified {
 				useDefineForClassFieldsTS = result.UseDefineForClassFields
 			}
@@ -1401,28 +3079,22 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 				result.PreserveImportsNotUsedAsValues,
 				result.PreserveValueImports,
 			)
+			emitDecoratorMetadata = result.EmitDecoratorMetadata
 			tsTarget = result.TSTarget
 			tsAlwaysStrict = result.TSAlwaysStrict
 		}
 	}
 
-	// Apply default values
-	if transformOpts.Sourcefile == "" {
-		transformOpts.Sourcefile = "<stdin>"
-	}
-	if transformOpts.Loader == LoaderNone {
-		transformOpts.Loader = LoaderJS
-	}
-
 	// Convert and validate the transformOpts
-	targetFromAPI, jsFeatures, cssFeatures, targetEnv := validateFeatures(log, transformOpts.Target, transformOpts.Engines)
+	targetFromAPI, jsFeatures, cssFeatures, runtimeFeatures, targetEnv := validateFeatures(log, transformOpts.Target, transformOpts.Engines)
 	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, transformOpts.Supported)
-	defines, injectedDefines := validateDefines(log, transformOpts.Define, transformOpts.Pure, PlatformNeutral, false /* minify */, transformOpts.Drop)
+	defines, injectedDefines := validateDefines(log, transformOpts.Define, transformOpts.Pure, PlatformNeutral, false /* minify */, transformOpts.Drop, transformOpts.DropCalls)
 	mangleCache := cloneMangleCache(log, transformOpts.MangleCache)
 	options := config.Options{
 		TargetFromAPI:                      targetFromAPI,
 		UnsupportedJSFeatures:              jsFeatures.ApplyOverrides(jsOverrides, jsMask),
 		UnsupportedCSSFeatures:             cssFeatures.ApplyOverrides(cssOverrides, cssMask),
+		UnsupportedRuntimeFeatures:         runtimeFeatures,
 		UnsupportedJSFeatureOverrides:      jsOverrides,
 		UnsupportedJSFeatureOverridesMask:  jsMask,
 		UnsupportedCSSFeatureOverrides:     cssOverrides,
@@ -1437,43 +3109,38 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		LegalComments:                      validateLegalComments(transformOpts.LegalComments, false /* bundle */),
 		SourceRoot:                         transformOpts.SourceRoot,
 		ExcludeSourcesContent:              transformOpts.SourcesContent == SourcesContentExclude,
+		SourcesIgnoreList:                  validateRegex(log, "sources ignore list", transformOpts.SourcesIgnoreList),
+		SourcesRewrite:                     validateSourcesRewrite(transformOpts.SourcesRewrite),
 		OutputFormat:                       validateFormat(transformOpts.Format),
 		GlobalName:                         validateGlobalName(log, transformOpts.GlobalName),
 		MinifySyntax:                       transformOpts.MinifySyntax,
 		MinifyWhitespace:                   transformOpts.MinifyWhitespace,
 		MinifyIdentifiers:                  transformOpts.MinifyIdentifiers,
+		NameSeed:                           transformOpts.NameSeed,
+		RuntimePrefix:                      validateRuntimePrefix(log, transformOpts.RuntimePrefix),
 		MangleProps:                        validateRegex(log, "mangle props", transformOpts.MangleProps),
 		ReserveProps:                       validateRegex(log, "reserve props", transformOpts.ReserveProps),
 		MangleQuoted:                       transformOpts.MangleQuoted == MangleQuotedTrue,
 		DropDebugger:                       (transformOpts.Drop & DropDebugger) != 0,
 		ASCIIOnly:                          validateASCIIOnly(transformOpts.Charset),
+		BMPOnly:                            transformOpts.Charset == CharsetBMP,
 		IgnoreDCEAnnotations:               transformOpts.IgnoreAnnotations,
+		PreserveComments:                   transformOpts.PreserveComments,
+		PreserveFormatting:                 transformOpts.PreserveFormatting,
+		ErrorRecovery:                      transformOpts.ErrorRecovery,
+		TS:                                 config.TSOptions{IsolatedModulesCheck: transformOpts.IsolatedModulesCheck},
 		TreeShaking:                        validateTreeShaking(transformOpts.TreeShaking, false /* bundle */, transformOpts.Format),
-		AbsOutputFile:                      transformOpts.Sourcefile + "-out",
 		KeepNames:                          transformOpts.KeepNames,
+		KeepNamesFilter:                    validateRegex(log, "keep names filter", transformOpts.KeepNamesFilter),
+		KeepNamesOnly:                      validateKeepNamesOnly(transformOpts.KeepNamesOnly),
 		UseDefineForClassFields:            useDefineForClassFieldsTS,
+		EmitDecoratorMetadata:              emitDecoratorMetadata,
 		UnusedImportFlagsTS:                unusedImportFlagsTS,
-		Stdin: &config.StdinInfo{
-			Loader:     validateLoader(transformOpts.Loader),
-			Contents:   input,
-			SourceFile: transformOpts.Sourcefile,
-		},
-	}
-	if options.Stdin.Loader == config.LoaderCSS {
-		options.CSSBanner = transformOpts.Banner
-		options.CSSFooter = transformOpts.Footer
-	} else {
-		options.JSBanner = transformOpts.Banner
-		options.JSFooter = transformOpts.Footer
 	}
 	if options.SourceMap == config.SourceMapLinkedWithComment {
 		// Linked source maps don't make sense because there's no output file name
 		log.AddError(nil, logger.Range{}, "Cannot transform with linked source maps")
 	}
-	if options.SourceMap != config.SourceMapNone && options.Stdin.SourceFile == "" {
-		log.AddError(nil, logger.Range{},
-			"Must use \"sourcefile\" with \"sourcemap\" to set the original file name")
-	}
 	if options.LegalComments.HasExternalFile() {
 		log.AddError(nil, logger.Range{}, "Cannot transform with linked or external legal comments")
 	}
@@ -1483,6 +3150,53 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		options.Mode = config.ModeConvertFormat
 	}
 
+	return options, mangleCache
+}
+
+func transformInput(
+	ctx context.Context,
+	log logger.Log,
+	options config.Options,
+	transformOpts *TransformOptions,
+	input TransformBatchInput,
+	mangleCache map[string]interface{},
+) TransformResult {
+	// Apply default values
+	if input.Sourcefile == "" {
+		input.Sourcefile = transformOpts.Sourcefile
+		if input.Sourcefile == "" {
+			input.Sourcefile = "<stdin>"
+		}
+	}
+	if input.Loader == LoaderNone {
+		input.Loader = transformOpts.Loader
+		if input.Loader == LoaderNone {
+			input.Loader = LoaderJS
+		}
+	}
+
+	options.AbsOutputFile = input.Sourcefile + "-out"
+	options.Stdin = &config.StdinInfo{
+		Loader:     validateLoader(input.Loader),
+		Contents:   input.Contents,
+		SourceFile: input.Sourcefile,
+	}
+	if options.Stdin.Loader == config.LoaderCSS {
+		options.CSSBanner = transformOpts.Banner
+		options.CSSFooter = transformOpts.Footer
+	} else {
+		options.JSBanner = transformOpts.Banner
+		options.JSFooter = transformOpts.Footer
+	}
+	if options.SourceMap != config.SourceMapNone && options.Stdin.SourceFile == "" {
+		log.AddError(nil, logger.Range{},
+			"Must use \"sourcefile\" with \"sourcemap\" to set the original file name")
+	}
+
+	cancelFlag, stopCancelFlag := cancelFlagForContext(ctx)
+	defer stopCancelFlag()
+	options.CancelFlag = cancelFlag
+
 	var results []graph.OutputFile
 
 	// Stop now if there were errors
@@ -1493,14 +3207,17 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		}
 
 		// Scan over the bundle
+		caches := cache.MakeCacheSet()
 		mockFS := fs.MockFS(make(map[string]string))
 		resolver := resolver.NewResolver(mockFS, log, caches, options)
 		bundle := bundler.ScanBundle(log, mockFS, resolver, caches, nil, options, timer)
+		checkForCancellation(log, cancelFlag)
 
 		// Stop now if there were errors
 		if !log.HasErrors() {
 			// Compile the bundle
 			results, _ = bundle.Compile(log, options, timer, mangleCache)
+			checkForCancellation(log, cancelFlag)
 		}
 
 		timer.Log(log)
@@ -1537,6 +3254,65 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Parse API
+
+func parseImpl(input string, parseOpts ParseOptions) ParseResult {
+	log := logger.NewStderrLog(logger.OutputOptions{
+		IncludeSource: true,
+		MessageLimit:  parseOpts.LogLimit,
+		Color:         validateColor(parseOpts.Color),
+		LogLevel:      validateLogLevel(parseOpts.LogLevel),
+		Overrides:     validateLogOverrides(parseOpts.LogOverride),
+	})
+
+	// Apply default values
+	if parseOpts.Sourcefile == "" {
+		parseOpts.Sourcefile = "<stdin>"
+	}
+	if parseOpts.Loader == LoaderNone {
+		parseOpts.Loader = LoaderJS
+	}
+
+	// Only the parser's first pass is run so that the AST is exactly what was
+	// written in the source code, without any constant folding or lowering
+	options := config.Options{
+		JSX:           config.JSXOptions{Preserve: true},
+		ErrorRecovery: parseOpts.ErrorRecovery,
+	}
+	switch validateLoader(parseOpts.Loader) {
+	case config.LoaderJS:
+	case config.LoaderJSX:
+		options.JSX.Parse = true
+	case config.LoaderTS:
+		options.TS.Parse = true
+	case config.LoaderTSX:
+		options.TS.Parse = true
+		options.JSX.Parse = true
+	default:
+		log.AddError(nil, logger.Range{}, "Only the \"js\", \"jsx\", \"ts\", and \"tsx\" loaders can be used with the parse API")
+	}
+
+	var ast []byte
+	if !log.HasErrors() {
+		source := logger.Source{
+			KeyPath:    logger.Path{Text: parseOpts.Sourcefile},
+			PrettyPath: parseOpts.Sourcefile,
+			Contents:   input,
+		}
+		if tree, ok := js_parser.ParseSyntaxTree(log, source, js_parser.OptionsFromConfig(&options)); ok && (parseOpts.ErrorRecovery || !log.HasErrors()) {
+			ast = js_estree.Print(&tree, &source)
+		}
+	}
+
+	msgs := log.Done()
+	return ParseResult{
+		Errors:   convertMessagesToPublic(logger.Error, msgs),
+		Warnings: convertMessagesToPublic(logger.Warning, msgs),
+		AST:      ast,
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Plugin API
 
@@ -1586,6 +3362,8 @@ func importKindToResolveKind(kind ast.ImportKind) ResolveKind {
 		return ResolveCSSImportRule
 	case ast.ImportURL:
 		return ResolveCSSURLToken
+	case ast.ImportNewURL:
+		return ResolveJSNewURL
 	default:
 		panic("Internal error")
 	}
@@ -1607,6 +3385,8 @@ func resolveKindToImportKind(kind ResolveKind) ast.ImportKind {
 		return ast.ImportAt
 	case ResolveCSSURLToken:
 		return ast.ImportURL
+	case ResolveJSNewURL:
+		return ast.ImportNewURL
 	default:
 		panic("Internal error")
 	}
@@ -1697,6 +3477,7 @@ func (impl *pluginImpl) onLoad(options OnLoadOptions, callback func(OnLoadArgs)
 			result.Contents = response.Contents
 			result.Loader = validateLoader(response.Loader)
 			result.PluginData = response.PluginData
+			result.SourceMap = response.SourceMap
 			pathKind := fmt.Sprintf("resolve directory path for plugin %q", impl.plugin.Name)
 			if absPath := validatePath(impl.log, impl.fs, response.ResolveDir, pathKind); absPath != "" {
 				result.AbsResolveDir = absPath
@@ -0,0 +1,507 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// This writes the files to a new temporary directory and returns its path
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "esbuild-api-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	for path, contents := range files {
+		absPath := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(absPath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func assertNoMessages(t *testing.T, result api.BuildResult) {
+	t.Helper()
+	for _, msg := range append(result.Errors, result.Warnings...) {
+		t.Errorf("Unexpected message: %s", msg.Text)
+	}
+}
+
+// This maps each output path relative to the directory to its contents
+func outputFilesByPath(t *testing.T, dir string, outputFiles []api.OutputFile) map[string]string {
+	t.Helper()
+	result := make(map[string]string)
+	for _, file := range outputFiles {
+		relPath, err := filepath.Rel(dir, file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result[filepath.ToSlash(relPath)] = string(file.Contents)
+	}
+	return result
+}
+
+func assertOutputPaths(t *testing.T, outputs map[string]string, expected ...string) {
+	t.Helper()
+	var paths []string
+	for path := range outputs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	sort.Strings(expected)
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected output paths %v but got %v", expected, paths)
+	}
+}
+
+func assertContains(t *testing.T, text string, substring string) {
+	t.Helper()
+	if !strings.Contains(text, substring) {
+		t.Errorf("Expected %q to contain %q", text, substring)
+	}
+}
+
+func assertNotContains(t *testing.T, text string, substring string) {
+	t.Helper()
+	if strings.Contains(text, substring) {
+		t.Errorf("Expected %q to not contain %q", text, substring)
+	}
+}
+
+func TestEntryPointOverridesShareOutbase(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"src/a/index.js": `console.log('a')`,
+		"src/b/index.js": `console.log('b')`,
+	})
+	defer os.RemoveAll(dir)
+
+	result := api.Build(api.BuildOptions{
+		AbsWorkingDir: dir,
+		Bundle:        true,
+		Outdir:        "out",
+		EntryPointsAdvanced: []api.EntryPoint{
+			{InputPath: "src/a/index.js"},
+			{InputPath: "src/b/index.js", Format: api.FormatESModule},
+		},
+	})
+	assertNoMessages(t, result)
+	assertOutputPaths(t, outputFilesByPath(t, dir, result.OutputFiles), "out/a/index.js", "out/b/index.js")
+}
+
+func TestEntryPointOverridesFormatAndPlatform(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"shared.js": `export let env = process.env.NODE_ENV`,
+		"client.js": `export { env } from './shared'`,
+		"server.js": `import fs from 'fs'; export { env } from './shared'; export let read = fs.readFileSync`,
+	})
+	defer os.RemoveAll(dir)
+
+	node := api.PlatformNode
+	result := api.Build(api.BuildOptions{
+		AbsWorkingDir: dir,
+		Bundle:        true,
+		Outdir:        "out",
+		Format:        api.FormatIIFE,
+		Platform:      api.PlatformBrowser,
+		EntryPointsAdvanced: []api.EntryPoint{
+			{InputPath: "client.js"},
+			{InputPath: "server.js", Format: api.FormatCommonJS, Platform: &node},
+		},
+	})
+	assertNoMessages(t, result)
+	outputs := outputFilesByPath(t, dir, result.OutputFiles)
+	assertOutputPaths(t, outputs, "out/client.js", "out/server.js")
+	assertContains(t, outputs["out/client.js"], "(() => {")
+	assertContains(t, outputs["out/client.js"], `var env = "development";`)
+	assertContains(t, outputs["out/server.js"], "module.exports = ")
+	assertContains(t, outputs["out/server.js"], `require("fs")`)
+	assertContains(t, outputs["out/server.js"], "var env = process.env.NODE_ENV;")
+}
+
+func TestEntryPointOverridesDefineAndBanner(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"shared.js": `export let mode = MODE`,
+		"a.js":      `import { mode } from './shared'; console.log(mode, DEBUG)`,
+		"b.js":      `import { mode } from './shared'; console.log(mode, DEBUG)`,
+	})
+	defer os.RemoveAll(dir)
+
+	result := api.Build(api.BuildOptions{
+		AbsWorkingDir: dir,
+		Bundle:        true,
+		Outdir:        "out",
+		Define:        map[string]string{"MODE": `"base"`, "DEBUG": "false"},
+		Banner:        map[string]string{"js": "/* base */"},
+		EntryPointsAdvanced: []api.EntryPoint{
+			{InputPath: "a.js"},
+			{
+				InputPath: "b.js",
+				Define:    map[string]string{"MODE": `"override"`},
+				Banner:    map[string]string{"js": "/* override */"},
+			},
+		},
+	})
+	assertNoMessages(t, result)
+	outputs := outputFilesByPath(t, dir, result.OutputFiles)
+	assertOutputPaths(t, outputs, "out/a.js", "out/b.js")
+	assertContains(t, outputs["out/a.js"], "/* base */")
+	assertContains(t, outputs["out/a.js"], `var mode = "base";`)
+	assertContains(t, outputs["out/a.js"], "console.log(mode, false)")
+	assertContains(t, outputs["out/b.js"], "/* override */")
+	assertNotContains(t, outputs["out/b.js"], "/* base */")
+	assertContains(t, outputs["out/b.js"], `var mode = "override";`)
+	assertContains(t, outputs["out/b.js"], "console.log(mode, false)")
+}
+
+func TestEntryPointOverridesLoaderAndSharedAsset(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.js":     `import text from './data.txt'; import logo from './logo.png'; console.log(text, logo)`,
+		"b.js":     `import text from './data.txt'; import logo from './logo.png'; console.log(text, logo)`,
+		"data.txt": `some data`,
+		"logo.png": `not really a png`,
+	})
+	defer os.RemoveAll(dir)
+
+	result := api.Build(api.BuildOptions{
+		AbsWorkingDir: dir,
+		Bundle:        true,
+		Outdir:        "out",
+		AssetNames:    "[name]",
+		Loader:        map[string]api.Loader{".txt": api.LoaderFile, ".png": api.LoaderFile},
+		Metafile:      true,
+		EntryPointsAdvanced: []api.EntryPoint{
+			{InputPath: "a.js"},
+			{InputPath: "b.js", Loader: map[string]api.Loader{".txt": api.LoaderText}},
+		},
+	})
+	assertNoMessages(t, result)
+	outputs := outputFilesByPath(t, dir, result.OutputFiles)
+
+	// The asset used by both bundles is only written once
+	assertOutputPaths(t, outputs, "out/a.js", "out/b.js", "out/data.txt", "out/logo.png")
+	assertContains(t, outputs["out/a.js"], `"./data.txt"`)
+	assertContains(t, outputs["out/b.js"], `"some data"`)
+	assertContains(t, outputs["out/a.js"], `"./logo.png"`)
+	assertContains(t, outputs["out/b.js"], `"./logo.png"`)
+
+	// The metafile covers the outputs and inputs of all bundles
+	var metafile struct {
+		Inputs  map[string]interface{}
+		Outputs map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(result.Metafile), &metafile); err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	for input := range metafile.Inputs {
+		inputs = append(inputs, input)
+	}
+	var metafileOutputs []string
+	for output := range metafile.Outputs {
+		metafileOutputs = append(metafileOutputs, output)
+	}
+	sort.Strings(inputs)
+	sort.Strings(metafileOutputs)
+	if strings.Join(inputs, ",") != "a.js,b.js,data.txt,logo.png" {
+		t.Errorf("Unexpected metafile inputs: %v", inputs)
+	}
+	if strings.Join(metafileOutputs, ",") != "out/a.js,out/b.js,out/data.txt,out/logo.png" {
+		t.Errorf("Unexpected metafile outputs: %v", metafileOutputs)
+	}
+}
+
+func TestRemoteImportsWithoutWrite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/javascript")
+		res.Write([]byte(`export default 'remote'`))
+	}))
+	defer server.Close()
+
+	dir := writeTestFiles(t, map[string]string{
+		"entry.js": `import x from '` + server.URL + `/mod.js'; console.log(x)`,
+	})
+	defer os.RemoveAll(dir)
+
+	options := api.BuildOptions{
+		AbsWorkingDir:  dir,
+		EntryPoints:    []string{"entry.js"},
+		Bundle:         true,
+		Outfile:        "out.js",
+		RemoteImports:  true,
+		RemoteCacheDir: "cache",
+		RemoteLockFile: "deps.lock.json",
+	}
+
+	// Nothing is added to the cache or the lock file without "Write"
+	result := api.Build(options)
+	assertNoMessages(t, result)
+	assertContains(t, outputFilesByPath(t, dir, result.OutputFiles)["out.js"], `"remote"`)
+	for _, path := range []string{"cache", "deps.lock.json"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); !os.IsNotExist(err) {
+			t.Errorf("Expected %q to not exist", path)
+		}
+	}
+
+	// Both are written by a build that writes its output
+	options.Write = true
+	result = api.Build(options)
+	assertNoMessages(t, result)
+	if entries, err := ioutil.ReadDir(filepath.Join(dir, "cache")); err != nil || len(entries) == 0 {
+		t.Errorf("Expected the cache directory to contain the downloaded module")
+	}
+	lockFile, err := ioutil.ReadFile(filepath.Join(dir, "deps.lock.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, string(lockFile), server.URL+"/mod.js")
+}
+
+func TestRemoteImportsFollowRedirects(t *testing.T) {
+	requests := make(map[string]int)
+	mutex := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		requests[req.URL.Path]++
+		mutex.Unlock()
+		switch req.URL.Path {
+		case "/mod":
+			http.Redirect(res, req, "/lib/mod.js", http.StatusFound)
+		case "/lib/mod.js":
+			res.Write([]byte(`export { util as default } from './util.js'`))
+		case "/lib/util.js":
+			res.Write([]byte(`import './mod.js'; export let util = 'util'`))
+		default:
+			http.NotFound(res, req)
+		}
+	}))
+	defer server.Close()
+
+	dir := writeTestFiles(t, map[string]string{
+		"entry.js": `import x from '` + server.URL + `/mod'; console.log(x)`,
+	})
+	defer os.RemoveAll(dir)
+
+	options := api.BuildOptions{
+		AbsWorkingDir:  dir,
+		EntryPoints:    []string{"entry.js"},
+		Bundle:         true,
+		Outfile:        "out.js",
+		Write:          true,
+		RemoteImports:  true,
+		RemoteCacheDir: "cache",
+	}
+
+	// Relative imports are relative to the URL after the redirect, and the
+	// module isn't downloaded again when it's imported using that URL
+	result := api.Build(options)
+	assertNoMessages(t, result)
+	assertContains(t, outputFilesByPath(t, dir, result.OutputFiles)["out.js"], `"util"`)
+	if requests["/lib/mod.js"] != 1 {
+		t.Errorf("Expected one request for the redirected module but got %d", requests["/lib/mod.js"])
+	}
+
+	// The URL after the redirect is also remembered in the download cache
+	options.RemoteOffline = true
+	result = api.Build(options)
+	assertNoMessages(t, result)
+	assertContains(t, outputFilesByPath(t, dir, result.OutputFiles)["out.js"], `"util"`)
+}
+
+func expectCancelledBuild(t *testing.T, result api.BuildResult, outdir string) {
+	t.Helper()
+	if len(result.Errors) != 1 || result.Errors[0].Text != "The build was cancelled" {
+		t.Fatalf("Expected the build to be cancelled but got %v", result.Errors)
+	}
+	if len(result.OutputFiles) != 0 {
+		t.Errorf("Expected no output files but got %d", len(result.OutputFiles))
+	}
+	if _, err := os.Stat(outdir); !os.IsNotExist(err) {
+		t.Errorf("Expected %q to not exist", outdir)
+	}
+}
+
+func TestBuildWithContextCancelledBeforeBuild(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"entry.js": `console.log('entry')`,
+	})
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := api.BuildWithContext(ctx, api.BuildOptions{
+		AbsWorkingDir: dir,
+		EntryPoints:   []string{"entry.js"},
+		Bundle:        true,
+		Outdir:        "out",
+		Write:         true,
+	})
+	expectCancelledBuild(t, result, filepath.Join(dir, "out"))
+}
+
+func TestBuildWithContextCancelledDuringBuild(t *testing.T) {
+	files := map[string]string{}
+	entry := strings.Builder{}
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("file%d.js", i)
+		files[name] = strings.Repeat(fmt.Sprintf("export let x%d = () => [1, 2, 3].map(y => y * %d);\n", i, i), 1000)
+		entry.WriteString(fmt.Sprintf("import * as ns%d from './%s'; console.log(ns%d)\n", i, name, i))
+	}
+	files["entry.js"] = entry.String()
+	dir := writeTestFiles(t, files)
+	defer os.RemoveAll(dir)
+
+	// Cancel the build once the entry point has been loaded, while the other
+	// files are still being loaded and parsed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := api.BuildWithContext(ctx, api.BuildOptions{
+		AbsWorkingDir: dir,
+		EntryPoints:   []string{"entry.js"},
+		Bundle:        true,
+		Outdir:        "out",
+		Write:         true,
+		Plugins: []api.Plugin{{
+			Name: "cancel",
+			Setup: func(build api.PluginBuild) {
+				build.OnLoad(api.OnLoadOptions{Filter: `entry\.js$`}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+					cancel()
+
+					// The context is watched from another goroutine
+					time.Sleep(10 * time.Millisecond)
+					contents := files["entry.js"]
+					return api.OnLoadResult{Contents: &contents}, nil
+				})
+			},
+		}},
+	})
+	expectCancelledBuild(t, result, filepath.Join(dir, "out"))
+}
+
+func TestTransformBatchOrderAndOverrides(t *testing.T) {
+	var inputs []api.TransformBatchInput
+	for i := 0; i < 50; i++ {
+		inputs = append(inputs, api.TransformBatchInput{Contents: fmt.Sprintf("let x = %d", i)})
+	}
+	inputs = append(inputs,
+		api.TransformBatchInput{Contents: "let y: number = 1", Loader: api.LoaderTS},
+		api.TransformBatchInput{Contents: "let z = (", Sourcefile: "bad.js"},
+	)
+
+	results := api.TransformBatch(inputs, api.TransformOptions{Loader: api.LoaderJS})
+	if len(results) != len(inputs) {
+		t.Fatalf("Expected %d results but got %d", len(inputs), len(results))
+	}
+
+	// The results are in the same order as the inputs
+	for i := 0; i < 50; i++ {
+		if code := string(results[i].Code); code != fmt.Sprintf("let x = %d;\n", i) {
+			t.Errorf("Unexpected code for input %d: %q", i, code)
+		}
+	}
+
+	// Each input can override the loader and the source file name
+	if code := string(results[50].Code); code != "let y = 1;\n" || len(results[50].Errors) != 0 {
+		t.Errorf("Unexpected result for the TypeScript input: %q %v", code, results[50].Errors)
+	}
+	if errors := results[51].Errors; len(errors) != 1 || errors[0].Location == nil || errors[0].Location.File != "bad.js" {
+		t.Errorf("Expected one error in \"bad.js\" but got %v", errors)
+	}
+}
+
+func TestTransformBatchOptionErrors(t *testing.T) {
+	inputs := []api.TransformBatchInput{{Contents: "a"}, {Contents: "b"}, {Contents: "c"}}
+	results := api.TransformBatch(inputs, api.TransformOptions{JSXFactory: "not valid!"})
+
+	// Problems with the shared options are reported for every input
+	for i, result := range results {
+		if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Text, "Invalid JSX factory") {
+			t.Errorf("Expected an invalid JSX factory error for input %d but got %v", i, result.Errors)
+		}
+		if len(result.Code) != 0 {
+			t.Errorf("Expected no code for input %d but got %q", i, result.Code)
+		}
+	}
+}
+
+func TestTransformBatchMangleCache(t *testing.T) {
+	mangleCache := map[string]interface{}{"shared_": "s"}
+	inputs := []api.TransformBatchInput{
+		{Contents: "x.shared_ = x.first_"},
+		{Contents: "x.shared_ = x.second_"},
+	}
+	results := api.TransformBatch(inputs, api.TransformOptions{MangleProps: "_$", MangleCache: mangleCache})
+
+	// Each input starts off with its own copy of the mangle cache
+	for i, other := range []string{"second_", "first_"} {
+		result := results[i]
+		if len(result.Errors) != 0 {
+			t.Fatalf("Unexpected errors for input %d: %v", i, result.Errors)
+		}
+		if result.MangleCache["shared_"] != "s" {
+			t.Errorf("Expected input %d to use the shared mangle cache but got %v", i, result.MangleCache)
+		}
+		if _, ok := result.MangleCache[other]; ok {
+			t.Errorf("Expected input %d to not see %q in its mangle cache but got %v", i, other, result.MangleCache)
+		}
+	}
+	if _, ok := results[0].MangleCache["first_"]; !ok {
+		t.Errorf("Expected \"first_\" in the mangle cache of the first input but got %v", results[0].MangleCache)
+	}
+	if _, ok := results[1].MangleCache["second_"]; !ok {
+		t.Errorf("Expected \"second_\" in the mangle cache of the second input but got %v", results[1].MangleCache)
+	}
+
+	// The mangle cache that was passed in isn't modified
+	if len(mangleCache) != 1 {
+		t.Errorf("Expected the original mangle cache to be unchanged but got %v", mangleCache)
+	}
+}
+
+func TestMessageCategories(t *testing.T) {
+	// Syntax that can't be parsed is a syntax error
+	result := api.Transform("let x = (", api.TransformOptions{})
+	if len(result.Errors) != 1 || result.Errors[0].Category != api.MessageCategorySyntax {
+		t.Fatalf("Expected one syntax error but got %v", result.Errors)
+	}
+	if _, ok := result.Errors[0].Err().(*api.SyntaxError); !ok {
+		t.Errorf("Expected a \"*api.SyntaxError\" but got %T", result.Errors[0].Err())
+	}
+
+	// Valid syntax that isn't supported by the target is not a syntax error
+	result = api.Transform("x = 1n", api.TransformOptions{Target: api.ES2015})
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Text, "Big integer literals are not available") {
+		t.Fatalf("Expected one big integer error but got %v", result.Errors)
+	}
+	if category := result.Errors[0].Category; category != api.MessageCategoryOther {
+		t.Errorf("Expected the big integer error to not be a syntax error but got category %d", category)
+	}
+
+	// Warnings are never syntax errors
+	result = api.Transform("x === -0", api.TransformOptions{})
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0].Text, "Comparison with -0") {
+		t.Fatalf("Expected one warning about -0 but got %v", result.Warnings)
+	}
+	if category := result.Warnings[0].Category; category != api.MessageCategoryOther {
+		t.Errorf("Expected the warning to not be a syntax error but got category %d", category)
+	}
+}
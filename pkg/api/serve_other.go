@@ -4,6 +4,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
@@ -567,7 +568,7 @@ func serveImpl(serveOptions ServeOptions, buildOptions BuildOptions) (ServeResul
 				return BuildResult{}
 			}
 
-			build := buildImpl(buildOptions)
+			build := buildImpl(context.Background(), buildOptions)
 			if handler.options == nil {
 				handler.options = &build.options
 			}
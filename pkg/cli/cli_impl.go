@@ -1,8 +1,10 @@
 package cli
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
@@ -48,6 +50,22 @@ const (
 type parseOptionsExtras struct {
 	metafile    *string
 	mangleCache *string
+	watchStdin  bool
+	dryRun      bool
+}
+
+// The source path may start with a Windows drive letter such as "C:\" that
+// shouldn't be mistaken for the separator between the source and destination
+func splitCopyPaths(value string) (string, string) {
+	start := 0
+	if len(value) >= 3 && value[1] == ':' && (value[2] == '\\' || value[2] == '/') &&
+		((value[0] >= 'a' && value[0] <= 'z') || (value[0] >= 'A' && value[0] <= 'Z')) {
+		start = 2
+	}
+	if colon := strings.IndexByte(value[start:], ':'); colon != -1 {
+		return value[:start+colon], value[start+colon+1:]
+	}
+	return value, ""
 }
 
 func isBoolFlag(arg string, flag string) bool {
@@ -83,6 +101,9 @@ func parseOptionsImpl(
 	kind parseOptionsKind,
 ) (extras parseOptionsExtras, err *cli_helpers.ErrorWithNote) {
 	hasBareSourceMapFlag := false
+	var entryPointFlags []entryPointFlag
+	var watchInterval int
+	var watchIgnore string
 
 	// Parse the arguments now that we know what we're parsing
 	for _, arg := range osArgs {
@@ -101,6 +122,86 @@ func parseOptionsImpl(
 				buildOpts.PreserveSymlinks = value
 			}
 
+		case isBoolFlag(arg, "--name-map") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.NameMap = value
+			}
+
+		case isBoolFlag(arg, "--debug-id") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.DebugID = value
+			}
+
+		case isBoolFlag(arg, "--deterministic") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.Deterministic = value
+			}
+
+		case isBoolFlag(arg, "--verify-determinism") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.VerifyDeterminism = value
+			}
+
+		case isBoolFlag(arg, "--publish-package-json") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.PublishPackageJSON = value
+			}
+
+		case isBoolFlag(arg, "--integrity") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.Integrity = value
+			}
+
+		case isBoolFlag(arg, "--declarations") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.Declarations = value
+			}
+
+		case isBoolFlag(arg, "--concat-report") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.ConcatReport = value
+			}
+
+		case isBoolFlag(arg, "--scan-secrets") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.ScanSecrets = value
+			}
+
+		case isBoolFlag(arg, "--refresh-metadata") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.RefreshMetadata = value
+			}
+
+		case isBoolFlag(arg, "--purge-css") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.PurgeCSS = value
+			}
+
+		case strings.HasPrefix(arg, "--purge-css-safelist:") && buildOpts != nil:
+			buildOpts.PurgeCSSSafelist = append(buildOpts.PurgeCSSSafelist, arg[len("--purge-css-safelist:"):])
+
 		case isBoolFlag(arg, "--splitting") && buildOpts != nil:
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -108,6 +209,34 @@ func parseOptionsImpl(
 				buildOpts.Splitting = value
 			}
 
+		case isBoolFlag(arg, "--clean") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.Clean = value
+			}
+
+		case isBoolFlag(arg, "--timing") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.Timing = value
+			}
+
+		case isBoolFlag(arg, "--low-memory") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.LowMemory = value
+			}
+
+		case isBoolFlag(arg, "--atomic-write") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.AtomicWrite = value
+			}
+
 		case isBoolFlag(arg, "--allow-overwrite") && buildOpts != nil:
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -115,6 +244,24 @@ func parseOptionsImpl(
 				buildOpts.AllowOverwrite = value
 			}
 
+		case isBoolFlag(arg, "--graph-only") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.GraphOnly = value
+			}
+
+		case isBoolFlag(arg, "--dry-run") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				extras.dryRun = value
+			}
+
+		case arg == "--watch=stdin" && buildOpts != nil:
+			buildOpts.Watch = nil
+			extras.watchStdin = true
+
 		case isBoolFlag(arg, "--watch") && buildOpts != nil:
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -124,6 +271,20 @@ func parseOptionsImpl(
 				buildOpts.Watch = nil
 			}
 
+		case strings.HasPrefix(arg, "--watch-interval=") && buildOpts != nil:
+			value := arg[len("--watch-interval="):]
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval <= 0 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The watch interval must be a positive integer number of milliseconds.",
+				)
+			}
+			watchInterval = interval
+
+		case strings.HasPrefix(arg, "--watch-ignore=") && buildOpts != nil:
+			watchIgnore = arg[len("--watch-ignore="):]
+
 		case isBoolFlag(arg, "--minify"):
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -216,10 +377,40 @@ func parseOptionsImpl(
 				} else {
 					transformOpts.Drop |= api.DropDebugger
 				}
+			case "dead-imports":
+				if buildOpts != nil {
+					buildOpts.Drop |= api.DropDeadImports
+				} else {
+					transformOpts.Drop |= api.DropDeadImports
+				}
 			default:
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("Invalid value %q in %q", value, arg),
-					"Valid values are \"console\" or \"debugger\".",
+					"Valid values are \"console\", \"debugger\", or \"dead-imports\".",
+				)
+			}
+
+		case strings.HasPrefix(arg, "--drop-calls="):
+			value := splitWithEmptyCheck(arg[len("--drop-calls="):], ",")
+			if buildOpts != nil {
+				buildOpts.DropCalls = append(buildOpts.DropCalls, value...)
+			} else {
+				transformOpts.DropCalls = append(transformOpts.DropCalls, value...)
+			}
+
+		case strings.HasPrefix(arg, "--dynamic-require=") && buildOpts != nil:
+			value := arg[len("--dynamic-require="):]
+			switch value {
+			case "keep":
+				buildOpts.DynamicRequire = api.DynamicRequireKeep
+			case "error":
+				buildOpts.DynamicRequire = api.DynamicRequireError
+			case "context":
+				buildOpts.DynamicRequire = api.DynamicRequireContext
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"keep\", \"error\", or \"context\".",
 				)
 			}
 
@@ -237,10 +428,12 @@ func parseOptionsImpl(
 				legalComments = api.LegalCommentsLinked
 			case "external":
 				legalComments = api.LegalCommentsExternal
+			case "combined":
+				legalComments = api.LegalCommentsCombined
 			default:
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("Invalid value %q in %q", value, arg),
-					"Valid values are \"none\", \"inline\", \"eof\", \"linked\", or \"external\".",
+					"Valid values are \"none\", \"inline\", \"eof\", \"linked\", \"external\", or \"combined\".",
 				)
 			}
 			if buildOpts != nil {
@@ -262,10 +455,12 @@ func parseOptionsImpl(
 				*value = api.CharsetASCII
 			case "utf8":
 				*value = api.CharsetUTF8
+			case "bmp":
+				*value = api.CharsetBMP
 			default:
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("Invalid value %q in %q", name, arg),
-					"Valid values are \"ascii\" or \"utf8\".",
+					"Valid values are \"ascii\", \"utf8\", or \"bmp\".",
 				)
 			}
 
@@ -295,6 +490,40 @@ func parseOptionsImpl(
 				transformOpts.IgnoreAnnotations = value
 			}
 
+		case isBoolFlag(arg, "--isolated-modules-check"):
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else if buildOpts != nil {
+				buildOpts.IsolatedModulesCheck = value
+			} else {
+				transformOpts.IsolatedModulesCheck = value
+			}
+
+		case isBoolFlag(arg, "--preserve-comments"):
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else if buildOpts != nil {
+				buildOpts.PreserveComments = value
+			} else {
+				transformOpts.PreserveComments = value
+			}
+
+		case isBoolFlag(arg, "--error-recovery") && transformOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				transformOpts.ErrorRecovery = value
+			}
+
+		case isBoolFlag(arg, "--preserve-formatting"):
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else if buildOpts != nil {
+				buildOpts.PreserveFormatting = value
+			} else {
+				transformOpts.PreserveFormatting = value
+			}
+
 		case isBoolFlag(arg, "--keep-names"):
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -304,6 +533,37 @@ func parseOptionsImpl(
 				transformOpts.KeepNames = value
 			}
 
+		case strings.HasPrefix(arg, "--keep-names-filter="):
+			value := arg[len("--keep-names-filter="):]
+			if buildOpts != nil {
+				buildOpts.KeepNamesFilter = value
+			} else {
+				transformOpts.KeepNamesFilter = value
+			}
+
+		case strings.HasPrefix(arg, "--keep-names-only="):
+			var only api.KeepNamesOnly
+			for _, value := range splitWithEmptyCheck(arg[len("--keep-names-only="):], ",") {
+				switch value {
+				case "classes":
+					only |= api.KeepNamesOnlyClasses
+				case "functions":
+					only |= api.KeepNamesOnlyFunctions
+				case "exports":
+					only |= api.KeepNamesOnlyExports
+				default:
+					return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+						fmt.Sprintf("Invalid value %q in %q", value, arg),
+						"Valid values are \"classes\", \"functions\", or \"exports\".",
+					)
+				}
+			}
+			if buildOpts != nil {
+				buildOpts.KeepNamesOnly = only
+			} else {
+				transformOpts.KeepNamesOnly = only
+			}
+
 		case arg == "--sourcemap":
 			if buildOpts != nil {
 				buildOpts.Sourcemap = api.SourceMapLinked
@@ -345,6 +605,22 @@ func parseOptionsImpl(
 				transformOpts.SourceRoot = sourceRoot
 			}
 
+		case strings.HasPrefix(arg, "--sources-ignore-list="):
+			value := arg[len("--sources-ignore-list="):]
+			if buildOpts != nil {
+				buildOpts.SourcesIgnoreList = value
+			} else {
+				transformOpts.SourcesIgnoreList = value
+			}
+
+		case strings.HasPrefix(arg, "--sources-rewrite="):
+			callback := sourcesRewriteFromTemplate(arg[len("--sources-rewrite="):])
+			if buildOpts != nil {
+				buildOpts.SourcesRewrite = callback
+			} else {
+				transformOpts.SourcesRewrite = callback
+			}
+
 		case isBoolFlag(arg, "--sources-content"):
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -384,6 +660,32 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--public-path=") && buildOpts != nil:
 			buildOpts.PublicPath = arg[len("--public-path="):]
 
+		case strings.HasPrefix(arg, "--public-path:") && buildOpts != nil:
+			value := arg[len("--public-path:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to specify the file extension that the public path applies to. "+
+						"For example, \"--public-path:.woff2=https://fonts.example.com/\" applies to files with the \".woff2\" extension.",
+				)
+			}
+			if buildOpts.AssetPublicPaths == nil {
+				buildOpts.AssetPublicPaths = make(map[string]string)
+			}
+			buildOpts.AssetPublicPaths[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--asset-inline-limit=") && buildOpts != nil:
+			value := arg[len("--asset-inline-limit="):]
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The asset inline limit must be a non-negative number of bytes.",
+				)
+			}
+			buildOpts.AssetInlineLimit = limit
+
 		case strings.HasPrefix(arg, "--global-name="):
 			if buildOpts != nil {
 				buildOpts.GlobalName = arg[len("--global-name="):]
@@ -391,6 +693,9 @@ func parseOptionsImpl(
 				transformOpts.GlobalName = arg[len("--global-name="):]
 			}
 
+		case arg == "--collect-legal-comments" && buildOpts != nil && kind == kindExternal:
+			buildOpts.CollectLegalComments = true
+
 		case arg == "--metafile" && buildOpts != nil && kind == kindExternal:
 			buildOpts.Metafile = true
 
@@ -405,12 +710,40 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--outdir=") && buildOpts != nil:
 			buildOpts.Outdir = arg[len("--outdir="):]
 
+		case strings.HasPrefix(arg, "--copy=") && buildOpts != nil:
+			value := arg[len("--copy="):]
+			source, dest := splitCopyPaths(value)
+			if source == "" {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing source path in %q", arg),
+					"You need to use \"--copy=src:dest\" to copy \"src\" to \"dest\" in the output directory, or \"--copy=src\" to copy it into the output directory itself.",
+				)
+			}
+			if buildOpts.Copy == nil {
+				buildOpts.Copy = make(map[string]string)
+			}
+			buildOpts.Copy[source] = dest
+
 		case strings.HasPrefix(arg, "--outbase=") && buildOpts != nil:
 			buildOpts.Outbase = arg[len("--outbase="):]
 
 		case strings.HasPrefix(arg, "--tsconfig=") && buildOpts != nil:
 			buildOpts.Tsconfig = arg[len("--tsconfig="):]
 
+		case isBoolFlag(arg, "--tsconfig-nested") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.TsconfigNested = value
+			}
+
+		case isBoolFlag(arg, "--verify-lockfile") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.VerifyLockfile = value
+			}
+
 		case strings.HasPrefix(arg, "--tsconfig-raw=") && transformOpts != nil:
 			transformOpts.TsconfigRaw = arg[len("--tsconfig-raw="):]
 
@@ -423,6 +756,73 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--asset-names=") && buildOpts != nil:
 			buildOpts.AssetNames = arg[len("--asset-names="):]
 
+		case strings.HasPrefix(arg, "--asset-root=") && buildOpts != nil:
+			buildOpts.AssetRoot = arg[len("--asset-root="):]
+
+		case strings.HasPrefix(arg, "--asset-outdir=") && buildOpts != nil:
+			buildOpts.AssetOutdir = arg[len("--asset-outdir="):]
+
+		case strings.HasPrefix(arg, "--hash-salt=") && buildOpts != nil:
+			buildOpts.HashSalt = arg[len("--hash-salt="):]
+
+		case strings.HasPrefix(arg, "--manifest=") && buildOpts != nil:
+			buildOpts.Manifest = arg[len("--manifest="):]
+
+		case strings.HasPrefix(arg, "--polyfill-report=") && buildOpts != nil:
+			buildOpts.PolyfillReport = arg[len("--polyfill-report="):]
+
+		case strings.HasPrefix(arg, "--tree-shaking-report=") && buildOpts != nil:
+			buildOpts.TreeShakingReport = arg[len("--tree-shaking-report="):]
+
+		case strings.HasPrefix(arg, "--import-map=") && buildOpts != nil:
+			buildOpts.ImportMap = arg[len("--import-map="):]
+
+		case isBoolFlag(arg, "--import-map-external") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.ImportMapExternal = value
+			}
+
+		case isBoolFlag(arg, "--remote-imports") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.RemoteImports = value
+			}
+
+		case strings.HasPrefix(arg, "--remote-cache-dir=") && buildOpts != nil:
+			buildOpts.RemoteCacheDir = arg[len("--remote-cache-dir="):]
+
+		case strings.HasPrefix(arg, "--remote-lock-file=") && buildOpts != nil:
+			buildOpts.RemoteLockFile = arg[len("--remote-lock-file="):]
+
+		case isBoolFlag(arg, "--remote-offline") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.RemoteOffline = value
+			}
+
+		case strings.HasPrefix(arg, "--status-file=") && buildOpts != nil:
+			buildOpts.StatusFile = arg[len("--status-file="):]
+
+		case strings.HasPrefix(arg, "--name-seed="):
+			value := arg[len("--name-seed="):]
+			if buildOpts != nil {
+				buildOpts.NameSeed = value
+			} else {
+				transformOpts.NameSeed = value
+			}
+
+		case strings.HasPrefix(arg, "--runtime-prefix="):
+			value := arg[len("--runtime-prefix="):]
+			if buildOpts != nil {
+				buildOpts.RuntimePrefix = value
+			} else {
+				transformOpts.RuntimePrefix = value
+			}
+
 		case strings.HasPrefix(arg, "--define:"):
 			value := arg[len("--define:"):]
 			equals := strings.IndexByte(value, '=')
@@ -477,6 +877,25 @@ func parseOptionsImpl(
 				transformOpts.Supported[value[:equals]] = isSupported
 			}
 
+		case strings.HasPrefix(arg, "--side-effects-override:") && buildOpts != nil:
+			value := arg[len("--side-effects-override:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the name of the package and whether it has side effects or not. "+
+						"For example, \"--side-effects-override:pkg=false\" marks all files in the package \"pkg\" as having no side effects.",
+				)
+			}
+			if hasSideEffects, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				if buildOpts.SideEffectsOverrides == nil {
+					buildOpts.SideEffectsOverrides = make(map[string]bool)
+				}
+				buildOpts.SideEffectsOverrides[value[:equals]] = hasSideEffects
+			}
+
 		case strings.HasPrefix(arg, "--pure:"):
 			value := arg[len("--pure:"):]
 			if buildOpts != nil {
@@ -508,7 +927,7 @@ func parseOptionsImpl(
 			if err != nil {
 				return parseOptionsExtras{}, err
 			}
-			if loader == api.LoaderFile || loader == api.LoaderCopy {
+			if loader == api.LoaderFile || loader == api.LoaderCopy || loader == api.LoaderWasmFile || loader == api.LoaderNAPI {
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("%q is not supported when transforming stdin", arg),
 					fmt.Sprintf("Using esbuild to transform stdin only generates one output file, so you cannot use the %q loader "+
@@ -552,21 +971,63 @@ func parseOptionsImpl(
 			}
 			buildOpts.OutExtensions[value[:equals]] = value[equals+1:]
 
+		case strings.HasPrefix(arg, "--entry-conditions:") && buildOpts != nil:
+			value := arg[len("--entry-conditions:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"--entry-conditions:ENTRY=...\" to specify the entry point that the conditions apply to.",
+				)
+			}
+			conditions := splitWithEmptyCheck(value[equals+1:], ",")
+			entryPointFlags = append(entryPointFlags, entryPointFlag{
+				arg:       arg,
+				inputPath: value[:equals],
+				apply:     func(ep *api.EntryPoint) { ep.Conditions = conditions },
+			})
+
+		case strings.HasPrefix(arg, "--entry-inject:") && buildOpts != nil:
+			value := arg[len("--entry-inject:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"--entry-inject:ENTRY=FILE\" to specify the entry point that the file is injected into.",
+				)
+			}
+			path := value[equals+1:]
+			entryPointFlags = append(entryPointFlags, entryPointFlag{
+				arg:       arg,
+				inputPath: value[:equals],
+				apply:     func(ep *api.EntryPoint) { ep.Inject = append(ep.Inject, path) },
+			})
+
 		case strings.HasPrefix(arg, "--platform=") && buildOpts != nil:
 			value := arg[len("--platform="):]
-			switch value {
-			case "browser":
-				buildOpts.Platform = api.PlatformBrowser
-			case "node":
-				buildOpts.Platform = api.PlatformNode
-			case "neutral":
-				buildOpts.Platform = api.PlatformNeutral
-			default:
+			platform, err := parsePlatform(value, arg)
+			if err != nil {
+				return parseOptionsExtras{}, err
+			}
+			buildOpts.Platform = platform
+
+		case strings.HasPrefix(arg, "--platform-inject:") && buildOpts != nil:
+			value := arg[len("--platform-inject:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
-					fmt.Sprintf("Invalid value %q in %q", value, arg),
-					"Valid values are \"browser\", \"node\", or \"neutral\".",
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"--platform-inject:PLATFORM=FILE\" to specify the platform that the file is injected for.",
 				)
 			}
+			platform, err := parsePlatform(value[:equals], arg)
+			if err != nil {
+				return parseOptionsExtras{}, err
+			}
+			if buildOpts.PlatformInject == nil {
+				buildOpts.PlatformInject = make(map[api.Platform][]string)
+			}
+			buildOpts.PlatformInject[platform] = append(buildOpts.PlatformInject[platform], value[equals+1:])
 
 		case strings.HasPrefix(arg, "--format="):
 			value := arg[len("--format="):]
@@ -599,9 +1060,43 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--external:") && buildOpts != nil:
 			buildOpts.External = append(buildOpts.External, arg[len("--external:"):])
 
+		case strings.HasPrefix(arg, "--external-global:") && buildOpts != nil:
+			value := arg[len("--external-global:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the import path and the global variable that provides it. "+
+						"For example, \"--external-global:react=React\" replaces imports of \"react\" with the global variable \"React\".",
+				)
+			}
+			if buildOpts.ExternalGlobals == nil {
+				buildOpts.ExternalGlobals = make(map[string]string)
+			}
+			buildOpts.ExternalGlobals[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--shared:") && buildOpts != nil:
+			buildOpts.Shared = append(buildOpts.Shared, arg[len("--shared:"):])
+
+		case strings.HasPrefix(arg, "--shared-scope=") && buildOpts != nil:
+			buildOpts.SharedScope = arg[len("--shared-scope="):]
+
+		case strings.HasPrefix(arg, "--boundary-package:") && buildOpts != nil:
+			buildOpts.BoundaryPackages = append(buildOpts.BoundaryPackages, arg[len("--boundary-package:"):])
+
+		case strings.HasPrefix(arg, "--disallow-license:") && buildOpts != nil:
+			buildOpts.DisallowedLicenses = append(buildOpts.DisallowedLicenses, arg[len("--disallow-license:"):])
+
 		case strings.HasPrefix(arg, "--inject:") && buildOpts != nil:
 			buildOpts.Inject = append(buildOpts.Inject, arg[len("--inject:"):])
 
+		case isBoolFlag(arg, "--inject-if-used") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.InjectIfUsed = value
+			}
+
 		case strings.HasPrefix(arg, "--jsx="):
 			value := arg[len("--jsx="):]
 			var mode api.JSXMode
@@ -610,10 +1105,12 @@ func parseOptionsImpl(
 				mode = api.JSXModeTransform
 			case "preserve":
 				mode = api.JSXModePreserve
+			case "automatic":
+				mode = api.JSXModeAutomatic
 			default:
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("Invalid value %q in %q", value, arg),
-					"Valid values are \"transform\" or \"preserve\".",
+					"Valid values are \"transform\", \"preserve\", or \"automatic\".",
 				)
 			}
 			if buildOpts != nil {
@@ -622,6 +1119,39 @@ func parseOptionsImpl(
 				transformOpts.JSXMode = mode
 			}
 
+		case (strings.HasPrefix(arg, "--jsx-factory:") || strings.HasPrefix(arg, "--jsx-fragment:") ||
+			strings.HasPrefix(arg, "--jsx-import-source:")) && buildOpts != nil:
+			colon := strings.IndexByte(arg, ':')
+			value := arg[colon+1:]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					fmt.Sprintf("You need to specify the path that the setting applies to. "+
+						"For example, \"%s:src/preact=h\" applies to all files in the \"src/preact\" directory.", arg[:colon]),
+				)
+			}
+			path, text := value[:equals], value[equals+1:]
+			index := -1
+			for i, override := range buildOpts.JSXOverrides {
+				if override.Path == path {
+					index = i
+					break
+				}
+			}
+			if index == -1 {
+				index = len(buildOpts.JSXOverrides)
+				buildOpts.JSXOverrides = append(buildOpts.JSXOverrides, api.JSXOverride{Path: path})
+			}
+			switch arg[:colon] {
+			case "--jsx-factory":
+				buildOpts.JSXOverrides[index].Factory = text
+			case "--jsx-fragment":
+				buildOpts.JSXOverrides[index].Fragment = text
+			default:
+				buildOpts.JSXOverrides[index].ImportSource = text
+			}
+
 		case strings.HasPrefix(arg, "--jsx-factory="):
 			value := arg[len("--jsx-factory="):]
 			if buildOpts != nil {
@@ -638,6 +1168,23 @@ func parseOptionsImpl(
 				transformOpts.JSXFragment = value
 			}
 
+		case strings.HasPrefix(arg, "--jsx-import-source="):
+			value := arg[len("--jsx-import-source="):]
+			if buildOpts != nil {
+				buildOpts.JSXImportSource = value
+			} else {
+				transformOpts.JSXImportSource = value
+			}
+
+		case isBoolFlag(arg, "--jsx-dev"):
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else if buildOpts != nil {
+				buildOpts.JSXDev = value
+			} else {
+				transformOpts.JSXDev = value
+			}
+
 		case strings.HasPrefix(arg, "--banner=") && transformOpts != nil:
 			transformOpts.Banner = arg[len("--banner="):]
 
@@ -666,6 +1213,50 @@ func parseOptionsImpl(
 			}
 			buildOpts.Footer[value[:equals]] = value[equals+1:]
 
+		case strings.HasPrefix(arg, "--max-open-files=") && buildOpts != nil:
+			value := arg[len("--max-open-files="):]
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The maximum number of open files must be a positive integer.",
+				)
+			}
+			buildOpts.MaxOpenFiles = limit
+
+		case strings.HasPrefix(arg, "--max-workers=") && buildOpts != nil:
+			value := arg[len("--max-workers="):]
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The maximum number of workers must be a positive integer.",
+				)
+			}
+			buildOpts.MaxWorkers = limit
+
+		case strings.HasPrefix(arg, "--clean-retain=") && buildOpts != nil:
+			value := arg[len("--clean-retain="):]
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 0 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The number of old versions to keep must be a non-negative integer.",
+				)
+			}
+			buildOpts.CleanRetain = count
+
+		case strings.HasPrefix(arg, "--memory-limit=") && buildOpts != nil:
+			value := arg[len("--memory-limit="):]
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The memory limit must be a non-negative integer number of megabytes.",
+				)
+			}
+			buildOpts.MemoryLimit = limit
+
 		case strings.HasPrefix(arg, "--log-limit="):
 			value := arg[len("--log-limit="):]
 			limit, err := strconv.Atoi(value)
@@ -712,6 +1303,26 @@ func parseOptionsImpl(
 				transformOpts.LogLevel = logLevel
 			}
 
+		// Make sure this stays in sync with "PrintErrorToStderr"
+		case strings.HasPrefix(arg, "--log-format="):
+			var logFormat api.LogFormat
+			switch value := arg[len("--log-format="):]; value {
+			case "text":
+				logFormat = api.LogFormatText
+			case "json":
+				logFormat = api.LogFormatJSON
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"text\" or \"json\".",
+				)
+			}
+			if buildOpts != nil {
+				buildOpts.LogFormat = logFormat
+			} else {
+				transformOpts.LogFormat = logFormat
+			}
+
 		case strings.HasPrefix(arg, "'--"):
 			return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 				fmt.Sprintf("Unexpected single quote character before flag: %s", arg),
@@ -731,83 +1342,181 @@ func parseOptionsImpl(
 
 		default:
 			bare := map[string]bool{
-				"allow-overwrite":    true,
-				"bundle":             true,
-				"ignore-annotations": true,
-				"keep-names":         true,
-				"minify-identifiers": true,
-				"minify-syntax":      true,
-				"minify-whitespace":  true,
-				"minify":             true,
-				"preserve-symlinks":  true,
-				"sourcemap":          true,
-				"splitting":          true,
-				"watch":              true,
+				"allow-overwrite":        true,
+				"atomic-write":           true,
+				"bundle":                 true,
+				"clean":                  true,
+				"concat-report":          true,
+				"declarations":           true,
+				"debug-id":               true,
+				"deterministic":          true,
+				"dry-run":                true,
+				"error-recovery":         true,
+				"graph-only":             true,
+				"ignore-annotations":     true,
+				"import-map-external":    true,
+				"inject-if-used":         true,
+				"integrity":              true,
+				"isolated-modules-check": true,
+				"jsx-dev":                true,
+				"keep-names":             true,
+				"low-memory":             true,
+				"minify-identifiers":     true,
+				"minify-syntax":          true,
+				"minify-whitespace":      true,
+				"minify":                 true,
+				"name-map":               true,
+				"preserve-comments":      true,
+				"preserve-formatting":    true,
+				"preserve-symlinks":      true,
+				"publish-package-json":   true,
+				"purge-css":              true,
+				"refresh-metadata":       true,
+				"remote-imports":         true,
+				"remote-offline":         true,
+				"scan-secrets":           true,
+				"sourcemap":              true,
+				"splitting":              true,
+				"timing":                 true,
+				"tsconfig-nested":        true,
+				"verify-determinism":     true,
+				"verify-lockfile":        true,
+				"watch":                  true,
 			}
 
 			equals := map[string]bool{
-				"allow-overwrite":    true,
-				"asset-names":        true,
-				"banner":             true,
-				"bundle":             true,
-				"charset":            true,
-				"chunk-names":        true,
-				"color":              true,
-				"conditions":         true,
-				"entry-names":        true,
-				"footer":             true,
-				"format":             true,
-				"global-name":        true,
-				"ignore-annotations": true,
-				"jsx-factory":        true,
-				"jsx-fragment":       true,
-				"jsx":                true,
-				"keep-names":         true,
-				"legal-comments":     true,
-				"loader":             true,
-				"log-level":          true,
-				"log-limit":          true,
-				"main-fields":        true,
-				"mangle-cache":       true,
-				"mangle-props":       true,
-				"mangle-quoted":      true,
-				"metafile":           true,
-				"minify-identifiers": true,
-				"minify-syntax":      true,
-				"minify-whitespace":  true,
-				"minify":             true,
-				"outbase":            true,
-				"outdir":             true,
-				"outfile":            true,
-				"platform":           true,
-				"preserve-symlinks":  true,
-				"public-path":        true,
-				"reserve-props":      true,
-				"resolve-extensions": true,
-				"source-root":        true,
-				"sourcefile":         true,
-				"sourcemap":          true,
-				"sources-content":    true,
-				"splitting":          true,
-				"target":             true,
-				"tree-shaking":       true,
-				"tsconfig-raw":       true,
-				"tsconfig":           true,
-				"watch":              true,
+				"allow-overwrite":        true,
+				"asset-inline-limit":     true,
+				"asset-names":            true,
+				"asset-outdir":           true,
+				"asset-root":             true,
+				"atomic-write":           true,
+				"banner":                 true,
+				"bundle":                 true,
+				"charset":                true,
+				"chunk-names":            true,
+				"clean":                  true,
+				"clean-retain":           true,
+				"color":                  true,
+				"concat-report":          true,
+				"conditions":             true,
+				"copy":                   true,
+				"declarations":           true,
+				"debug-id":               true,
+				"deterministic":          true,
+				"drop-calls":             true,
+				"dry-run":                true,
+				"dynamic-require":        true,
+				"entry-names":            true,
+				"error-recovery":         true,
+				"footer":                 true,
+				"format":                 true,
+				"global-name":            true,
+				"graph-only":             true,
+				"hash-salt":              true,
+				"ignore-annotations":     true,
+				"import-map":             true,
+				"import-map-external":    true,
+				"inject-if-used":         true,
+				"integrity":              true,
+				"isolated-modules-check": true,
+				"jsx-dev":                true,
+				"jsx-factory":            true,
+				"jsx-fragment":           true,
+				"jsx-import-source":      true,
+				"jsx":                    true,
+				"keep-names":             true,
+				"keep-names-filter":      true,
+				"keep-names-only":        true,
+				"legal-comments":         true,
+				"loader":                 true,
+				"log-format":             true,
+				"log-level":              true,
+				"log-limit":              true,
+				"low-memory":             true,
+				"main-fields":            true,
+				"mangle-cache":           true,
+				"mangle-props":           true,
+				"mangle-quoted":          true,
+				"manifest":               true,
+				"max-open-files":         true,
+				"max-workers":            true,
+				"memory-limit":           true,
+				"metafile":               true,
+				"minify-identifiers":     true,
+				"minify-syntax":          true,
+				"minify-whitespace":      true,
+				"minify":                 true,
+				"name-map":               true,
+				"name-seed":              true,
+				"outbase":                true,
+				"outdir":                 true,
+				"outfile":                true,
+				"platform":               true,
+				"polyfill-report":        true,
+				"preserve-comments":      true,
+				"preserve-formatting":    true,
+				"preserve-symlinks":      true,
+				"publish-package-json":   true,
+				"public-path":            true,
+				"purge-css":              true,
+				"refresh-metadata":       true,
+				"remote-cache-dir":       true,
+				"remote-imports":         true,
+				"remote-lock-file":       true,
+				"remote-offline":         true,
+				"reserve-props":          true,
+				"resolve-extensions":     true,
+				"runtime-prefix":         true,
+				"scan-secrets":           true,
+				"shared-scope":           true,
+				"source-root":            true,
+				"sourcefile":             true,
+				"sourcemap":              true,
+				"sources-content":        true,
+				"sources-ignore-list":    true,
+				"sources-rewrite":        true,
+				"splitting":              true,
+				"status-file":            true,
+				"target":                 true,
+				"timing":                 true,
+				"tree-shaking":           true,
+				"tree-shaking-report":    true,
+				"tsconfig-nested":        true,
+				"tsconfig-raw":           true,
+				"tsconfig":               true,
+				"verify-determinism":     true,
+				"verify-lockfile":        true,
+				"watch":                  true,
+				"watch-ignore":           true,
+				"watch-interval":         true,
 			}
 
 			colon := map[string]bool{
-				"banner":        true,
-				"define":        true,
-				"drop":          true,
-				"external":      true,
-				"footer":        true,
-				"inject":        true,
-				"loader":        true,
-				"log-override":  true,
-				"out-extension": true,
-				"pure":          true,
-				"supported":     true,
+				"banner":                true,
+				"boundary-package":      true,
+				"define":                true,
+				"disallow-license":      true,
+				"drop":                  true,
+				"entry-conditions":      true,
+				"entry-inject":          true,
+				"external":              true,
+				"external-global":       true,
+				"footer":                true,
+				"inject":                true,
+				"jsx-factory":           true,
+				"jsx-fragment":          true,
+				"jsx-import-source":     true,
+				"loader":                true,
+				"log-override":          true,
+				"out-extension":         true,
+				"platform-inject":       true,
+				"public-path":           true,
+				"pure":                  true,
+				"purge-css-safelist":    true,
+				"shared":                true,
+				"side-effects-override": true,
+				"supported":             true,
 			}
 
 			note := ""
@@ -820,6 +1529,11 @@ func parseOptionsImpl(
 			case arg == "-v":
 				note = "Use \"--log-level=verbose\" to generate verbose logs instead of \"-v\"."
 
+			case strings.HasPrefix(arg, "--side-effects-override=") && strings.Contains(arg, ":"):
+				value := arg[len("--side-effects-override="):]
+				i := strings.LastIndexByte(value, ':')
+				note = fmt.Sprintf("Use %q instead of %q.", "--side-effects-override:"+value[:i]+"="+value[i+1:], arg)
+
 			case strings.HasPrefix(arg, "--"):
 				if i := strings.IndexByte(arg, '='); i != -1 && colon[arg[2:i]] {
 					note = fmt.Sprintf("Use %q instead of %q. Flags that can be re-specified multiple times use \":\" instead of \"=\".",
@@ -868,9 +1582,79 @@ func parseOptionsImpl(
 		buildOpts.Sourcemap = api.SourceMapInline
 	}
 
+	// Watch settings can be specified before "--watch" itself
+	if buildOpts != nil && buildOpts.Watch != nil {
+		buildOpts.Watch.Interval = watchInterval
+		buildOpts.Watch.Ignore = watchIgnore
+	}
+
+	// Entry point settings can only be applied once all entry points are known
+	if buildOpts != nil && len(entryPointFlags) > 0 {
+		if err := applyEntryPointFlags(buildOpts, entryPointFlags); err != nil {
+			return parseOptionsExtras{}, err
+		}
+	}
+
 	return
 }
 
+type entryPointFlag struct {
+	arg       string
+	inputPath string
+	apply     func(*api.EntryPoint)
+}
+
+// Entry points with per-entry settings need to be moved over to the advanced
+// form since the simple form is just a list of input paths
+func applyEntryPointFlags(buildOpts *api.BuildOptions, flags []entryPointFlag) *cli_helpers.ErrorWithNote {
+	for _, flag := range flags {
+		found := false
+		for i := range buildOpts.EntryPointsAdvanced {
+			if entryPoint := &buildOpts.EntryPointsAdvanced[i]; entryPoint.InputPath == flag.inputPath {
+				flag.apply(entryPoint)
+				found = true
+			}
+		}
+		entryPoints := buildOpts.EntryPoints[:0]
+		for _, inputPath := range buildOpts.EntryPoints {
+			if inputPath == flag.inputPath {
+				entryPoint := api.EntryPoint{InputPath: inputPath}
+				flag.apply(&entryPoint)
+				buildOpts.EntryPointsAdvanced = append(buildOpts.EntryPointsAdvanced, entryPoint)
+				found = true
+			} else {
+				entryPoints = append(entryPoints, inputPath)
+			}
+		}
+		buildOpts.EntryPoints = entryPoints
+		if !found {
+			return cli_helpers.MakeErrorWithNote(
+				fmt.Sprintf("No entry point matches %q in %q", flag.inputPath, flag.arg),
+				"The part before the \"=\" must be exactly the same as the input path of one of the entry points.",
+			)
+		}
+	}
+	return nil
+}
+
+func parsePlatform(value string, arg string) (api.Platform, *cli_helpers.ErrorWithNote) {
+	switch value {
+	case "browser":
+		return api.PlatformBrowser, nil
+	case "node":
+		return api.PlatformNode, nil
+	case "neutral":
+		return api.PlatformNeutral, nil
+	case "deno":
+		return api.PlatformDeno, nil
+	default:
+		return 0, cli_helpers.MakeErrorWithNote(
+			fmt.Sprintf("Invalid value %q in %q", value, arg),
+			"Valid values are \"browser\", \"node\", \"neutral\", or \"deno\".",
+		)
+	}
+}
+
 func parseTargets(targets []string, arg string) (target api.Target, engines []api.Engine, err *cli_helpers.ErrorWithNote) {
 	validTargets := map[string]api.Target{
 		"esnext": api.ESNext,
@@ -935,9 +1719,10 @@ outer:
 
 // This returns either BuildOptions, TransformOptions, or an error
 func parseOptionsForRun(osArgs []string) (*api.BuildOptions, *api.TransformOptions, parseOptionsExtras, *cli_helpers.ErrorWithNote) {
-	// If there's an entry point or we're bundling, then we're building
+	// If there's an entry point or we're bundling, then we're building. Watching
+	// stdin is only supported when building, so that also means we're building.
 	for _, arg := range osArgs {
-		if !strings.HasPrefix(arg, "-") || arg == "--bundle" {
+		if !strings.HasPrefix(arg, "-") || arg == "--bundle" || arg == "--watch=stdin" {
 			options := newBuildOptions()
 
 			// Apply defaults appropriate for the CLI
@@ -983,6 +1768,18 @@ func parseOptionsForRun(osArgs []string) (*api.BuildOptions, *api.TransformOptio
 	return nil, &options, parseOptionsExtras{}, nil
 }
 
+// The template can contain "[path]" for the default path, "[input]" for the
+// path relative to the working directory, and "[namespace]" for the namespace
+func sourcesRewriteFromTemplate(template string) func(api.SourcesRewriteArgs) string {
+	return func(args api.SourcesRewriteArgs) string {
+		return strings.NewReplacer(
+			"[path]", args.Path,
+			"[input]", args.InputPath,
+			"[namespace]", args.Namespace,
+		).Replace(template)
+	}
+}
+
 func splitWithEmptyCheck(s string, sep string) []string {
 	// Special-case the empty string to return [] instead of [""]
 	if s == "" {
@@ -1045,11 +1842,24 @@ func runImpl(osArgs []string) int {
 		}
 
 		// Read from stdin when there are no entry points
+		if extras.watchStdin && len(buildOptions.EntryPoints)+len(buildOptions.EntryPointsAdvanced) > 0 {
+			logger.PrintErrorToStderr(osArgs, "Cannot use \"--watch=stdin\" with entry points")
+			return 1
+		}
 		if len(buildOptions.EntryPoints)+len(buildOptions.EntryPointsAdvanced) == 0 {
 			if buildOptions.Stdin == nil {
 				buildOptions.Stdin = &api.StdinOptions{}
 			}
-			bytes, err := ioutil.ReadAll(os.Stdin)
+			var bytes []byte
+			var err error
+			if extras.watchStdin {
+				var ok bool
+				if bytes, ok, err = readStdinFrame(os.Stdin); err == nil && !ok {
+					return 0 // Stdin was closed before the first input
+				}
+			} else {
+				bytes, err = ioutil.ReadAll(os.Stdin)
+			}
 			if err != nil {
 				logger.PrintErrorToStderr(osArgs, fmt.Sprintf(
 					"Could not read from stdin: %s", err.Error()))
@@ -1068,6 +1878,26 @@ func runImpl(osArgs []string) int {
 			return 1
 		}
 
+		// A dry run does everything a normal build does except write to the file
+		// system, so it doesn't make sense to combine it with modes that keep
+		// writing output
+		if extras.dryRun {
+			if buildOptions.Watch != nil || extras.watchStdin {
+				logger.PrintErrorToStderr(osArgs, "Cannot use \"--dry-run\" with \"--watch\"")
+				return 1
+			}
+			buildOptions.Write = false
+			buildOptions.StatusFile = ""
+			extras.metafile = nil
+		}
+
+		// The import graph is written to stdout once, so it doesn't make sense to
+		// keep rebuilding
+		if buildOptions.GraphOnly && (buildOptions.Watch != nil || extras.watchStdin) {
+			logger.PrintErrorToStderr(osArgs, "Cannot use \"--graph-only\" with \"--watch\"")
+			return 1
+		}
+
 		// Validate the metafile absolute path and directory ahead of time so we
 		// don't write any output files if it's incorrect. That makes this API
 		// option consistent with how we handle all other API options.
@@ -1170,6 +2000,18 @@ func runImpl(osArgs []string) int {
 			}
 		}
 
+		// In stdin watch mode, each output is written to stdout as a frame so that
+		// the other end of the pipe can tell where one output ends and the next
+		// one starts
+		writeFramesToStdout := false
+		if extras.watchStdin {
+			buildOptions.Incremental = true
+			if buildOptions.Outfile == "" && buildOptions.Outdir == "" {
+				buildOptions.Write = false
+				writeFramesToStdout = true
+			}
+		}
+
 		// Always generate a metafile if we're analyzing, even if it won't be written out
 		if analyze {
 			buildOptions.Metafile = true
@@ -1194,11 +2036,51 @@ func runImpl(osArgs []string) int {
 			writeMetafile(result.Metafile)
 		}
 
+		// Write the import graph to stdout
+		if buildOptions.GraphOnly && result.Graph != "" {
+			os.Stdout.WriteString(result.Graph)
+		}
+
 		// Write the mangle cache to the file system
-		if writeMangleCache != nil {
+		if writeMangleCache != nil && !extras.dryRun {
 			writeMangleCache(result.MangleCache)
 		}
 
+		// Rebuild for each new input on stdin until stdin is closed. Rebuilds are
+		// incremental, so files that haven't changed aren't parsed again.
+		if extras.watchStdin {
+			for {
+				if writeFramesToStdout {
+					writeStdoutFrame(osArgs, result.OutputFiles)
+				}
+				bytes, ok, err := readStdinFrame(os.Stdin)
+				if err != nil {
+					logger.PrintErrorToStderr(osArgs, fmt.Sprintf(
+						"Could not read from stdin: %s", err.Error()))
+					return 1
+				}
+				if !ok || result.Rebuild == nil {
+					break
+				}
+				buildOptions.Stdin.Contents = string(bytes)
+				result = result.Rebuild()
+				if writeMetafile != nil {
+					writeMetafile(result.Metafile)
+				}
+				if writeMangleCache != nil {
+					writeMangleCache(result.MangleCache)
+				}
+			}
+		}
+
+		// Make it clear that the summary above only lists the files that would
+		// have been generated
+		if extras.dryRun && buildOptions.LogLevel <= api.LogLevelInfo && buildOptions.LogLevel != api.LogLevelSilent {
+			logger.PrintTextWithColor(os.Stderr, logger.OutputOptionsForArgs(osArgs).Color, func(colors logger.Colors) string {
+				return fmt.Sprintf("%sDry run: no files were written%s\n", colors.Dim, colors.Reset)
+			})
+		}
+
 		// Do not exit if we're in watch mode
 		if buildOptions.Watch != nil {
 			<-make(chan bool)
@@ -1242,6 +2124,42 @@ func runImpl(osArgs []string) int {
 	return 0
 }
 
+// Inputs for "--watch=stdin" are framed with a 32-bit little-endian length
+// followed by that many bytes of contents. Closing stdin between frames ends
+// watch mode.
+func readStdinFrame(r io.Reader) ([]byte, bool, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	bytes := make([]byte, binary.LittleEndian.Uint32(header[:]))
+	if _, err := io.ReadFull(r, bytes); err != nil {
+		return nil, false, err
+	}
+	return bytes, true, nil
+}
+
+// Outputs use the same framing as inputs. A build with errors writes an empty
+// frame so that there is always exactly one output frame per input frame. A
+// frame can only hold one file, so a build that generates more than one file
+// is treated like a build with errors instead of gluing the files together.
+func writeStdoutFrame(osArgs []string, outputFiles []api.OutputFile) {
+	var contents []byte
+	if len(outputFiles) > 1 {
+		logger.PrintErrorToStderr(osArgs, fmt.Sprintf(
+			"Cannot write %d output files to stdout with \"--watch=stdin\" (use \"--outdir\" instead)", len(outputFiles)))
+	} else if len(outputFiles) == 1 {
+		contents = outputFiles[0].Contents
+	}
+	var header [4]byte
+	binary.LittleEndian.PutUint32(header[:], uint32(len(contents)))
+	os.Stdout.Write(header[:])
+	os.Stdout.Write(contents)
+}
+
 func parseServeOptionsImpl(osArgs []string) (api.ServeOptions, []string, error) {
 	host := ""
 	portText := "0"
@@ -455,6 +455,24 @@
           export function fn() { return foo(); }
         `,
       }),
+
+      // A symlink whose target goes through another symlinked directory must
+      // not cause the same package to be bundled twice (e.g. a pnpm store)
+      test(['--bundle', 'in.js', '--outfile=node.js'], {
+        'in.js': `
+          import { foo as a } from 'a'
+          import { foo as b } from 'b'
+          import { foo as c } from 'c'
+          if (a !== b || b !== c) throw 'fail'
+        `,
+        'store/.pnpm/foo@1.0.0/node_modules/foo/index.js': `export const foo = {}`,
+        'store/.pnpm/c@1.0.0/node_modules/c/index.js': `export { foo } from 'foo'`,
+        'store/.pnpm/c@1.0.0/node_modules/foo': { symlink: `../../foo@1.0.0/node_modules/foo` },
+        'linked-store': { symlink: `store` },
+        'node_modules/a': { symlink: `../linked-store/.pnpm/foo@1.0.0/node_modules/foo` },
+        'node_modules/b': { symlink: `../store/.pnpm/foo@1.0.0/node_modules/foo` },
+        'node_modules/c': { symlink: `../linked-store/.pnpm/c@1.0.0/node_modules/c` },
+      }),
     )
   }
 
@@ -6221,6 +6239,57 @@
     }),
   )
 
+  // Test the shims for "require", "__dirname", and "__filename" in node ESM
+  // output, including with a banner that already defines "require"
+  tests.push(
+    test(['in.js', '--bundle', '--outfile=node.js', '--format=esm', '--platform=node'], {
+      'in.js': `
+        if (typeof require('fs').readFileSync !== 'function') throw 'fail: require'
+        if (require('path').basename(__filename) !== 'node.js') throw 'fail: __filename'
+        if (__dirname !== require('path').dirname(__filename)) throw 'fail: __dirname'
+      `,
+    }),
+    test(['in.js', '--bundle', '--outfile=node.js', '--format=esm', '--platform=node',
+      `--banner:js=import {createRequire} from 'module';const require=createRequire(import.meta.url);`], {
+      'in.js': `
+        if (typeof require('fs').readFileSync !== 'function') throw 'fail: require'
+        if (require('path').basename(__filename) !== 'node.js') throw 'fail: __filename'
+      `,
+    }),
+  )
+
+  // Test for "--watch=stdin", which reads and writes length-prefixed frames
+  tests.push(
+    testWatchStdin(['--bundle', '--format=esm'], [
+      `import { lib } from './lib.js'; console.log(lib, 1)`,
+      `console.log(2`,
+      `import { lib } from './lib.js'; console.log(lib, 3)`,
+    ], { 'lib.js': `export let lib = 'lib'` }, outputs => {
+      assert.strictEqual(outputs.length, 3)
+      assert.strictEqual(outputs[0], `// lib.js\nvar lib = "lib";\n\n// <stdin>\nconsole.log(lib, 1);\n`)
+      assert.strictEqual(outputs[1], ``)
+      assert.strictEqual(outputs[2], `// lib.js\nvar lib = "lib";\n\n// <stdin>\nconsole.log(lib, 3);\n`)
+    }),
+
+    // "--watch=stdin" alone must be enough to select build mode
+    testWatchStdin([], [`let x = 1`, `let y = 2`], {}, outputs => {
+      assert.deepStrictEqual(outputs, [`let x = 1;\n`, `let y = 2;\n`])
+    }),
+  )
+
+  // Test for "--dry-run", which must not write or change any files
+  tests.push(
+    testDryRun(['in.js', '--bundle', '--outdir=out', '--metafile=meta.json', '--mangle-cache=cache.json', '--mangle-props=_$'], {
+      'in.js': `export let foo_ = 1; console.log(foo_)`,
+      'cache.json': `{"foo_": "x"}`,
+    }),
+    testDryRun(['in.js', '--outdir=out', '--metafile=out/meta.json', '--status-file=out/status.json'], {
+      'in.js': `console.log(1)`,
+      'out/in.js': `old output`,
+      'out/meta.json': `old metafile`,
+    }),
+  )
+
   // Test for a Windows-specific issue where paths starting with "/" could be
   // treated as relative paths, leading to inconvenient cross-platform failures:
   // https://github.com/evanw/esbuild/issues/822
@@ -6462,6 +6531,94 @@
     }
   }
 
+  // Each input is written to stdin as a frame, and each output frame from stdout
+  // is passed to the callback
+  function testWatchStdin(args, inputs, files, callback) {
+    return async () => {
+      const thisTestDir = path.join(testDir, '' + testCount++)
+
+      try {
+        await fs.mkdir(thisTestDir, { recursive: true })
+        for (const file in files) {
+          const filePath = path.join(thisTestDir, file)
+          await fs.mkdir(path.dirname(filePath), { recursive: true })
+          await fs.writeFile(filePath, files[file])
+        }
+
+        const child = childProcess.spawn(esbuildPath, args.concat('--watch=stdin', '--log-level=silent'), { cwd: thisTestDir, stdio: 'pipe' })
+        const chunks = []
+        child.stdout.on('data', chunk => chunks.push(chunk))
+        const exit = new Promise(resolve => child.on('close', resolve))
+        for (const input of inputs) {
+          const contents = Buffer.from(input)
+          const header = Buffer.alloc(4)
+          header.writeUInt32LE(contents.length, 0)
+          child.stdin.write(Buffer.concat([header, contents]))
+        }
+        child.stdin.end()
+        await exit
+
+        const stdout = Buffer.concat(chunks)
+        const outputs = []
+        for (let i = 0; i < stdout.length;) {
+          const length = stdout.readUInt32LE(i)
+          outputs.push(stdout.slice(i + 4, i + 4 + length).toString())
+          i += 4 + length
+        }
+        callback(outputs)
+
+        // Clean up test output
+        removeRecursiveSync(thisTestDir)
+      } catch (e) {
+        console.error(`❌ test failed: ${e && e.message || e}
+  dir: ${path.relative(dirname, thisTestDir)}`)
+        return false
+      }
+
+      return true
+    }
+  }
+
+  // Runs a build with "--dry-run" and checks that the files in the test
+  // directory are exactly the same afterward
+  function testDryRun(args, files) {
+    return async () => {
+      const thisTestDir = path.join(testDir, '' + testCount++)
+
+      try {
+        for (const file in files) {
+          const filePath = path.join(thisTestDir, file)
+          await fs.mkdir(path.dirname(filePath), { recursive: true })
+          await fs.writeFile(filePath, files[file])
+        }
+
+        const { stderr } = await execFileAsync(esbuildPath, args.concat('--dry-run'), { cwd: thisTestDir, stdio: 'pipe' })
+        assert.ok(stderr.includes('Dry run: no files were written'), stderr)
+
+        const filesAfter = {}
+        const visit = async dir => {
+          for (const entry of await fs.readdir(path.join(thisTestDir, dir), { withFileTypes: true })) {
+            const relPath = dir ? dir + '/' + entry.name : entry.name
+            if (entry.isDirectory()) await visit(relPath)
+            else filesAfter[relPath] = await fs.readFile(path.join(thisTestDir, relPath), 'utf8')
+          }
+        }
+        await visit('')
+        assert.deepStrictEqual(filesAfter, files)
+
+        // Clean up test output
+        removeRecursiveSync(thisTestDir)
+      } catch (e) {
+        console.error(`❌ test failed: ${e && e.message || e}
+  dir: ${path.relative(dirname, thisTestDir)}
+  args: ${args.join(' ')}`)
+        return false
+      }
+
+      return true
+    }
+  }
+
   // Create a fresh test directory
   removeRecursiveSync(testDir)
   await fs.mkdir(testDir, { recursive: true })
@@ -451,6 +451,89 @@ let buildTests = {
     assert.strictEqual(json.sourceRoot, 'https://example.com/')
   },
 
+  async sourceMapNestedComposition({ esbuild, testDir }) {
+    const srcDir = path.join(testDir, 'src')
+    const mapDir = path.join(testDir, 'maps')
+    const input = path.join(srcDir, 'in.js')
+    const inject = path.join(srcDir, 'inject.js')
+    const output = path.join(testDir, 'out', 'out.js')
+    await mkdirAsync(srcDir)
+    await mkdirAsync(mapDir)
+    await writeFileAsync(input, 'exports.foo = 123\n//# sourceMappingURL=../maps/in.js.map\n')
+    await writeFileAsync(path.join(mapDir, 'in.js.map'), JSON.stringify({
+      version: 3,
+      sourceRoot: 'original',
+      sources: ['in.ts', 'vendor/lib.ts', 'webpack://pkg/util.ts'],
+      x_google_ignoreList: [1],
+      mappings: 'AAAA,QCAA,GCAA',
+    }))
+    const injectMap = { version: 3, sources: ['inject.ts'], ignoreList: [0], mappings: 'AAAA' }
+    await writeFileAsync(inject, 'console.log("inject")\n//# sourceMappingURL=data:application/json;base64,' +
+      Buffer.from(JSON.stringify(injectMap)).toString('base64'))
+    await esbuild.build({
+      entryPoints: [input],
+      outfile: output,
+      sourcemap: true,
+      inject: [inject],
+      banner: { js: '// banner' },
+    })
+    const json = JSON.parse(await readFileAsync(output + '.map', 'utf8'))
+    assert.deepStrictEqual(json.sources, [
+      '../src/inject.ts',
+      '../maps/original/in.ts',
+      '../maps/original/vendor/lib.ts',
+      'webpack://pkg/util.ts',
+    ])
+    assert.deepStrictEqual(json.x_google_ignoreList, [0, 2])
+  },
+
+  async sourceMapSourcesIgnoreList({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const pkgDir = path.join(testDir, 'node_modules', 'pkg')
+    const vendorDir = path.join(testDir, 'vendor')
+    const output = path.join(testDir, 'out.js')
+    await mkdirAsync(pkgDir, { recursive: true })
+    await mkdirAsync(vendorDir)
+    await writeFileAsync(input, 'import { x } from "pkg"; import { y } from "./vendor/y"; console.log(x, y)')
+    await writeFileAsync(path.join(pkgDir, 'index.js'), 'export let x = 1')
+    await writeFileAsync(path.join(vendorDir, 'y.js'), 'export let y = 2')
+
+    await esbuild.build({ entryPoints: [input], bundle: true, outfile: output, sourcemap: true })
+    let json = JSON.parse(await readFileAsync(output + '.map', 'utf8'))
+    assert.deepStrictEqual(json.sources, ['node_modules/pkg/index.js', 'vendor/y.js', 'in.js'])
+    assert.deepStrictEqual(json.x_google_ignoreList, [0])
+
+    await esbuild.build({ entryPoints: [input], bundle: true, outfile: output, sourcemap: true, sourcesIgnoreList: /^vendor\// })
+    json = JSON.parse(await readFileAsync(output + '.map', 'utf8'))
+    assert.deepStrictEqual(json.x_google_ignoreList, [1])
+  },
+
+  async sourceMapDebugId({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(input, 'console.log(123)')
+    const result = await esbuild.build({ entryPoints: [input], outdir, sourcemap: true, debugId: true, metafile: true })
+    const output = path.join(outdir, 'in.js')
+    const js = await readFileAsync(output, 'utf8')
+    const match = /\/\/# debugId=([0-9a-f-]+)\n\/\/# sourceMappingURL=in\.js\.map\n$/.exec(js)
+    assert(match, js)
+    assert(/^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$/.test(match[1]), match[1])
+    const json = JSON.parse(await readFileAsync(output + '.map', 'utf8'))
+    assert.strictEqual(json.debug_id, match[1])
+    assert.strictEqual(result.metafile.outputs[path.relative(process.cwd(), output).split(path.sep).join('/')].debugId, match[1])
+  },
+
+  async sourceMapSourcesRewrite({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const other = path.join(testDir, 'other.js')
+    const output = path.join(testDir, 'out', 'out.js')
+    await writeFileAsync(input, 'import { x } from "./other"; console.log(x)')
+    await writeFileAsync(other, 'export let x = 1')
+    await esbuild.build({ entryPoints: [input], bundle: true, outfile: output, sourcemap: true, absWorkingDir: testDir, sourcesRewrite: 'webpack://app/[input]' })
+    const json = JSON.parse(await readFileAsync(output + '.map', 'utf8'))
+    assert.deepStrictEqual(json.sources, ['webpack://app/other.js', 'webpack://app/in.js'])
+  },
+
   async sourceMapWithDisabledFile({ esbuild, testDir }) {
     const input = path.join(testDir, 'in.js')
     const disabled = path.join(testDir, 'disabled.js')
@@ -581,6 +664,127 @@ let buildTests = {
     assert.strictEqual(result.foo, 'b')
   },
 
+  async sideEffectsOverride({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const pkgDir = path.join(testDir, 'node_modules', 'pkg')
+    await mkdirAsync(pkgDir, { recursive: true })
+    await writeFileAsync(input, 'import "pkg"')
+    await writeFileAsync(path.join(pkgDir, 'package.json'), '{ "name": "pkg" }')
+    await writeFileAsync(path.join(pkgDir, 'index.js'), 'console.log("side effect")')
+    const result1 = await esbuild.build({ entryPoints: [input], bundle: true, write: false, logLevel: 'silent' })
+    assert(result1.outputFiles[0].text.includes('side effect'))
+    const result2 = await esbuild.build({ entryPoints: [input], bundle: true, write: false, logLevel: 'silent', absWorkingDir: testDir, sideEffectsOverride: { pkg: false } })
+    assert(!result2.outputFiles[0].text.includes('side effect'))
+    assert.strictEqual(result2.warnings.length, 1)
+    assert.strictEqual(result2.warnings[0].text, 'Ignoring this import because "node_modules/pkg/index.js" was marked as having no side effects by the side effects override for "pkg"')
+  },
+
+  async entryConditions({ esbuild, testDir }) {
+    const server = path.join(testDir, 'server.js')
+    const client = path.join(testDir, 'client.js')
+    const outdir = path.join(testDir, 'out')
+    const pkgDir = path.join(testDir, 'node_modules', 'pkg')
+    await mkdirAsync(pkgDir, { recursive: true })
+    await writeFileAsync(server, 'export { default } from "pkg"')
+    await writeFileAsync(client, 'export { default } from "pkg"')
+    await writeFileAsync(path.join(pkgDir, 'package.json'), '{ "exports": { "react-server": "./server.js", "default": "./client.js" } }')
+    await writeFileAsync(path.join(pkgDir, 'server.js'), 'export default "server"')
+    await writeFileAsync(path.join(pkgDir, 'client.js'), 'export default "client"')
+    await esbuild.build({
+      entryPoints: [server, client],
+      entryConditions: { [server]: ['react-server'] },
+      outdir,
+      bundle: true,
+      format: 'cjs',
+    })
+    assert.strictEqual(require(path.join(outdir, 'server.js')).default, 'server')
+    assert.strictEqual(require(path.join(outdir, 'client.js')).default, 'client')
+    try {
+      await esbuild.build({ entryPoints: [client], entryConditions: { [server]: ['react-server'] }, logLevel: 'silent', write: false })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== `No entry point matches ${JSON.stringify(server)} in "entryConditions"`) {
+        throw e;
+      }
+    }
+  },
+
+  async entryAndPlatformInject({ esbuild, testDir }) {
+    const server = path.join(testDir, 'server.js')
+    const client = path.join(testDir, 'client.js')
+    const serverShim = path.join(testDir, 'server-shim.js')
+    const nodeShim = path.join(testDir, 'node-shim.js')
+    const browserShim = path.join(testDir, 'browser-shim.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(server, 'module.exports = [typeof who, typeof where]')
+    await writeFileAsync(client, 'module.exports = [typeof who, typeof where]')
+    await writeFileAsync(serverShim, 'export let who = "server"')
+    await writeFileAsync(nodeShim, 'export let where = "node"')
+    await writeFileAsync(browserShim, 'export let where = "browser"')
+    await esbuild.build({
+      entryPoints: [server, client],
+      entryInject: { [server]: [serverShim] },
+      platformInject: { node: [nodeShim], browser: [browserShim] },
+      platform: 'node',
+      outdir,
+      bundle: true,
+      format: 'cjs',
+    })
+    assert.deepStrictEqual(require(path.join(outdir, 'server.js')), ['string', 'string'])
+    assert.deepStrictEqual(require(path.join(outdir, 'client.js')), ['undefined', 'string'])
+    const result = await esbuild.build({
+      entryPoints: [client],
+      platformInject: { node: [nodeShim], browser: [browserShim] },
+      bundle: true,
+      write: false,
+    })
+    assert(result.outputFiles[0].text.includes('"browser"'))
+    assert(!result.outputFiles[0].text.includes('"node"'))
+  },
+
+  async deterministicAndVerifyDeterminism({ esbuild, testDir }) {
+    let counter = 0
+    const plugin = {
+      name: 'virtual',
+      setup(build) {
+        build.onResolve({ filter: /^virtual$/ }, () => ({ path: path.join(testDir, 'virtual.js'), namespace: 'ns' }))
+        build.onLoad({ filter: /.*/, namespace: 'ns' }, () => ({ contents: `console.log(${counter++})` }))
+      },
+    }
+    const entry = path.join(testDir, 'entry.js')
+    await writeFileAsync(entry, `import 'virtual'`)
+
+    // Absolute paths in other namespaces are made relative
+    const result = await esbuild.build({
+      entryPoints: [entry],
+      absWorkingDir: testDir,
+      plugins: [plugin],
+      bundle: true,
+      write: false,
+      deterministic: true,
+    })
+    assert(result.outputFiles[0].text.includes('// ns:virtual.js\n'))
+    assert(!result.outputFiles[0].text.includes(testDir))
+
+    // Output that's different the second time is an error
+    try {
+      await esbuild.build({
+        entryPoints: [entry],
+        absWorkingDir: testDir,
+        plugins: [plugin],
+        bundle: true,
+        write: false,
+        outdir: path.join(testDir, 'out'),
+        logLevel: 'silent',
+        verifyDeterminism: true,
+      })
+      throw new Error('Expected an error to be thrown')
+    } catch (e) {
+      assert.strictEqual(e.errors.length, 1)
+      assert.strictEqual(e.errors[0].text, 'The output file "out/entry.js" was different when building a second time')
+    }
+  },
+
   async requireAbsolutePath({ esbuild, testDir }) {
     const input = path.join(testDir, 'in.js')
     const dependency = path.join(testDir, 'dep.js')
@@ -2536,10 +2740,10 @@ require("/assets/file.png");
 
     assert.strictEqual(await tryTargetESM('node14.13.1'), `// <stdin>\nimport fs from "node:fs";\nimport("node:fs");\nfs();\n`)
     assert.strictEqual(await tryTargetESM('node14.13.0'), `// <stdin>\nimport fs from "fs";\nimport("fs");\nfs();\n`)
-    assert.strictEqual(await tryTargetESM('node13'), `// <stdin>\nimport fs from "fs";\nPromise.resolve().then(() => __toESM(__require("fs")));\nfs();\n`)
+    assert.strictEqual(await tryTargetESM('node13'), `// <stdin>\nimport fs from "fs";\nPromise.resolve().then(() => __toESM(require("fs")));\nfs();\n`)
     assert.strictEqual(await tryTargetESM('node12.99'), `// <stdin>\nimport fs from "node:fs";\nimport("node:fs");\nfs();\n`)
     assert.strictEqual(await tryTargetESM('node12.20'), `// <stdin>\nimport fs from "node:fs";\nimport("node:fs");\nfs();\n`)
-    assert.strictEqual(await tryTargetESM('node12.19'), `// <stdin>\nimport fs from "fs";\nPromise.resolve().then(() => __toESM(__require("fs")));\nfs();\n`)
+    assert.strictEqual(await tryTargetESM('node12.19'), `// <stdin>\nimport fs from "fs";\nPromise.resolve().then(() => __toESM(require("fs")));\nfs();\n`)
   },
 
   async nodeColonPrefixRequire({ esbuild }) {
@@ -2585,6 +2789,299 @@ require("/assets/file.png");
     assert.strictEqual(await tryTargetESM('node14.18'), `// <stdin>\nvar import_node_fs = __toESM(require("node:fs"));\nimport("node:fs");\n(0, import_node_fs.default)();\n`)
     assert.strictEqual(await tryTargetESM('node14.17'), `// <stdin>\nvar import_node_fs = __toESM(require("fs"));\nimport("fs");\n(0, import_node_fs.default)();\n`)
   },
+
+  async cleanRemovesStaleFilesAndEmptyDirectories({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(input, `console.log('in')`)
+    await esbuild.build({ entryPoints: [input], outdir, clean: true, logLevel: 'silent' })
+    assert.strictEqual(fs.existsSync(path.join(outdir, '.esbuild-clean')), true)
+
+    await mkdirAsync(path.join(outdir, 'old', 'nested'), { recursive: true })
+    await writeFileAsync(path.join(outdir, 'old', 'nested', 'stale.js'), `stale`)
+    await writeFileAsync(path.join(outdir, 'stale.js'), `stale`)
+    await esbuild.build({ entryPoints: [input], outdir, clean: true, logLevel: 'silent' })
+    assert.deepStrictEqual(fs.readdirSync(outdir).sort(), ['.esbuild-clean', 'in.js'])
+  },
+
+  async cleanRetainKeepsNewestOldVersions({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    const now = Date.now() / 1000
+    const outputs = []
+    for (let i = 0; i < 3; i++) {
+      await writeFileAsync(input, `console.log(${i})`)
+      const result = await esbuild.build({ entryPoints: [input], outdir, entryNames: '[name]-[hash]', clean: true, cleanRetain: 1, metafile: true, absWorkingDir: testDir, logLevel: 'silent' })
+      const output = path.join(testDir, Object.keys(result.metafile.outputs)[0])
+      outputs.push(path.basename(output))
+
+      // Make sure the modification times are ordered even on coarse file systems
+      fs.utimesSync(output, now - 100 + i, now - 100 + i)
+    }
+
+    // Only the most recent older version is retained
+    assert.deepStrictEqual(fs.readdirSync(outdir).sort(), ['.esbuild-clean', outputs[1], outputs[2]].sort())
+  },
+
+  async cleanKeepsStatusFile({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    const statusFile = path.join(outdir, 'status.json')
+    await writeFileAsync(input, `console.log('in')`)
+    await esbuild.build({ entryPoints: [input], outdir, clean: true, statusFile, logLevel: 'silent' })
+    await esbuild.build({ entryPoints: [input], outdir, clean: true, statusFile, logLevel: 'silent' })
+    assert.deepStrictEqual(fs.readdirSync(outdir).sort(), ['.esbuild-clean', 'in.js', 'status.json'])
+    assert.strictEqual(JSON.parse(await readFileAsync(statusFile, 'utf8')).errors, 0)
+  },
+
+  async cleanRefusesDirectoryNotCreatedByEsbuild({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    const other = path.join(outdir, 'other.txt')
+    await writeFileAsync(input, `console.log('in')`)
+    await mkdirAsync(outdir)
+    await writeFileAsync(other, `other`)
+    try {
+      await esbuild.build({ entryPoints: [input], outdir, clean: true, logLevel: 'silent' })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== `Refusing to clean the output directory ${JSON.stringify(outdir)} because it wasn't created by esbuild`) {
+        throw e;
+      }
+    }
+    assert.deepStrictEqual(fs.readdirSync(outdir), ['other.txt'])
+  },
+
+  async cleanRefusesDirectoryWithInputFiles({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const libDir = path.join(testDir, 'lib')
+    const lib = path.join(libDir, 'lib.js')
+    await writeFileAsync(input, `import { lib } from './lib/lib.js'; console.log(lib)`)
+    await mkdirAsync(libDir)
+    await writeFileAsync(lib, `export let lib = 123`)
+    await writeFileAsync(path.join(libDir, '.esbuild-clean'), ``)
+    try {
+      await esbuild.build({ entryPoints: [input], outdir: libDir, bundle: true, clean: true, absWorkingDir: testDir, logLevel: 'silent' })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== `Refusing to clean the output directory ${JSON.stringify(libDir)} because it contains the input file ${JSON.stringify(path.join('lib', 'lib.js'))}`) {
+        throw e;
+      }
+    }
+    assert.strictEqual(await readFileAsync(lib, 'utf8'), `export let lib = 123`)
+    try {
+      await esbuild.build({ entryPoints: [lib], outdir: libDir, clean: true, logLevel: 'silent' })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== `Refusing to clean the output directory ${JSON.stringify(libDir)} because it contains the entry point ${JSON.stringify(lib)}`) {
+        throw e;
+      }
+    }
+  },
+
+  async atomicWriteReplacesOutputDirectory({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(input, `console.log('new')`)
+    await mkdirAsync(outdir)
+    await writeFileAsync(path.join(outdir, 'in.js'), `console.log('old')`)
+    await writeFileAsync(path.join(outdir, 'other.txt'), `other`)
+    await esbuild.build({ entryPoints: [input], outdir, atomicWrite: true, logLevel: 'silent' })
+
+    // Files that weren't overwritten are carried over, and nothing is left behind next to the output directory
+    assert.strictEqual(await readFileAsync(path.join(outdir, 'in.js'), 'utf8'), `console.log("new");\n`)
+    assert.strictEqual(await readFileAsync(path.join(outdir, 'other.txt'), 'utf8'), `other`)
+    assert.deepStrictEqual(fs.readdirSync(testDir).sort(), ['in.js', 'out'])
+  },
+
+  async atomicWriteFailedBuildLeavesOutputDirectoryUntouched({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(input, `console.log('first')`)
+    await esbuild.build({ entryPoints: [input], outdir, atomicWrite: true, logLevel: 'silent' })
+
+    await writeFileAsync(input, `console.log('second'`)
+    try {
+      await esbuild.build({ entryPoints: [input], outdir, atomicWrite: true, logLevel: 'silent' })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== `Expected ")" but found end of file`) {
+        throw e;
+      }
+    }
+    assert.strictEqual(await readFileAsync(path.join(outdir, 'in.js'), 'utf8'), `console.log("first");\n`)
+    assert.deepStrictEqual(fs.readdirSync(testDir).sort(), ['in.js', 'out'])
+  },
+
+  async atomicWriteWithClean({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(input, `console.log('in')`)
+    await esbuild.build({ entryPoints: [input], outdir, atomicWrite: true, clean: true, logLevel: 'silent' })
+    await writeFileAsync(path.join(outdir, 'stale.js'), `stale`)
+
+    // Stale files are removed from the new output directory, not the old one
+    await esbuild.build({ entryPoints: [input], outdir, atomicWrite: true, clean: true, logLevel: 'silent' })
+    assert.deepStrictEqual(fs.readdirSync(outdir).sort(), ['.esbuild-clean', 'in.js'])
+    assert.deepStrictEqual(fs.readdirSync(testDir).sort(), ['in.js', 'out'])
+  },
+
+  async atomicWriteSymlinkedOutputDirectory({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const realOutdir = path.join(testDir, 'real')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(input, `console.log('in')`)
+    await mkdirAsync(realOutdir)
+    await writeFileAsync(path.join(realOutdir, 'other.txt'), `other`)
+    fs.symlinkSync(realOutdir, outdir, 'dir')
+    await esbuild.build({ entryPoints: [input], outdir, atomicWrite: true, logLevel: 'silent' })
+
+    // The symlink is kept and the directory that it points to is replaced
+    assert.ok(fs.lstatSync(outdir).isSymbolicLink())
+    assert.deepStrictEqual(fs.readdirSync(realOutdir).sort(), ['in.js', 'other.txt'])
+    assert.deepStrictEqual(fs.readdirSync(testDir).sort(), ['in.js', 'out', 'real'])
+  },
+
+  async atomicWriteKeepsOutputDirectoryMode({ esbuild, testDir }) {
+    if (process.platform === 'win32') return
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    await writeFileAsync(input, `console.log('in')`)
+    await mkdirAsync(outdir)
+    fs.chmodSync(outdir, 0o701)
+    await esbuild.build({ entryPoints: [input], outdir, atomicWrite: true, logLevel: 'silent' })
+    assert.strictEqual(fs.statSync(outdir).mode & 0o777, 0o701)
+  },
+
+  async atomicWriteOutputDirectoryWithInputFiles({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'src')
+    await mkdirAsync(outdir)
+    await writeFileAsync(input, `import './src/lib.js'`)
+    await writeFileAsync(path.join(outdir, 'lib.js'), `console.log('lib')`)
+    try {
+      await esbuild.build({ entryPoints: [input], bundle: true, outdir, atomicWrite: true, logLevel: 'silent' })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || !e.errors[0].text.startsWith('Refusing to replace the output directory')) {
+        throw e;
+      }
+    }
+    assert.deepStrictEqual(fs.readdirSync(outdir), ['lib.js'])
+    assert.deepStrictEqual(fs.readdirSync(testDir).sort(), ['in.js', 'src'])
+  },
+
+  async maxWorkersWithFailingPlugin({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    let imports = ''
+    for (let i = 0; i < 20; i++) {
+      await writeFileAsync(path.join(testDir, `file${i}.js`), i === 10 ? `export default (` : `export default ${i}`)
+      imports += `import x${i} from './file${i}.js'; console.log(x${i})\n`
+    }
+    await writeFileAsync(input, imports)
+
+    // A plugin failure and a syntax error must both release their worker slot,
+    // or the other files would never be parsed with only one slot
+    const build = () => esbuild.build({
+      entryPoints: [input],
+      bundle: true,
+      write: false,
+      maxWorkers: 1,
+      logLevel: 'silent',
+      plugins: [{
+        name: 'fail',
+        setup(build) {
+          build.onLoad({ filter: /file5\.js$/ }, () => { throw new Error('plugin failure') })
+        },
+      }],
+    })
+    const result = await Promise.race([
+      build().then(() => { throw new Error('Expected build failure') }, e => e),
+      new Promise((_, reject) => setTimeout(() => reject(new Error('Timeout after 30 seconds')), 30 * 1000)),
+    ])
+    if (!result.errors) throw result
+    assert.deepStrictEqual(result.errors.map(e => e.text).sort(), ['Unexpected end of file', 'plugin failure'])
+  },
+
+  async statusFileReportsOutputs({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    const statusFile = path.join(testDir, 'status.json')
+    await writeFileAsync(input, `console.log('in')`)
+    await esbuild.build({ entryPoints: [input], outdir, statusFile, logLevel: 'silent' })
+
+    const status = JSON.parse(await readFileAsync(statusFile, 'utf8'))
+    assert.strictEqual(status.success, true)
+    assert.strictEqual(status.errors, 0)
+    assert.strictEqual(status.warnings, 0)
+    for (const key of ['scan', 'compile', 'write', 'total']) assert.strictEqual(typeof status.durations[key], 'number')
+    assert.strictEqual(status.outputs.length, 1)
+    const contents = await readFileAsync(path.join(outdir, 'in.js'))
+    assert.strictEqual(path.resolve(status.outputs[0].path), path.join(outdir, 'in.js'))
+    assert.strictEqual(status.outputs[0].bytes, contents.length)
+    assert.strictEqual(status.outputs[0].sha256, require('crypto').createHash('sha256').update(contents).digest('hex'))
+  },
+
+  async statusFileReportsFailedBuild({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    const outdir = path.join(testDir, 'out')
+    const statusFile = path.join(testDir, 'status.json')
+    await writeFileAsync(input, `console.log(`)
+    try {
+      await esbuild.build({ entryPoints: [input], outdir, statusFile, logLevel: 'silent' })
+      throw new Error('Expected build failure')
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== 'Unexpected end of file') throw e
+    }
+
+    const status = JSON.parse(await readFileAsync(statusFile, 'utf8'))
+    assert.strictEqual(status.success, false)
+    assert.strictEqual(status.errors, 1)
+    assert.deepStrictEqual(status.outputs, [])
+  },
+
+  async timingInMetafile({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    await writeFileAsync(input, `import 'virtual'`)
+    let onStartCalls = 0
+    const result = await esbuild.build({
+      entryPoints: [input],
+      bundle: true,
+      write: false,
+      metafile: true,
+      timing: true,
+      logLevel: 'silent',
+      plugins: [{
+        name: 'start',
+        setup(build) {
+          build.onStart(() => { onStartCalls++ })
+        },
+      }, {
+        name: 'virtual',
+        setup(build) {
+          build.onResolve({ filter: /^virtual$/ }, args => ({ path: args.path, namespace: 'virtual' }))
+          build.onLoad({ filter: /.*/, namespace: 'virtual' }, () => ({ contents: `console.log('virtual')` }))
+        },
+      }],
+    })
+
+    // Each JavaScript plugin is reported separately
+    const timing = result.metafile.timing
+    for (const key of ['scan', 'parse', 'link', 'print', 'write']) assert.strictEqual(typeof timing[key], 'number')
+    assert.deepStrictEqual(timing.plugins.map(plugin => [plugin.name, plugin.onStart.calls, plugin.onResolve.calls, plugin.onLoad.calls]), [
+      ['start', 1, 0, 0],
+      ['virtual', 0, 1, 1],
+    ])
+    assert.strictEqual(typeof timing.plugins[1].onLoad.duration, 'number')
+    assert.strictEqual(onStartCalls, 1)
+  },
+
+  async timingIsOmittedByDefault({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js')
+    await writeFileAsync(input, `console.log('in')`)
+    const result = await esbuild.build({ entryPoints: [input], write: false, metafile: true, logLevel: 'silent' })
+    assert.strictEqual(result.metafile.timing, undefined)
+  },
 }
 
 function fetch(host, port, path, headers) {
@@ -2778,6 +3275,49 @@ let watchTests = {
       result.stop()
     }
   },
+
+  async watchIntervalAndIgnore({ esbuild, testDir }) {
+    const srcDir = path.join(testDir, 'src')
+    const outfile = path.join(testDir, 'out.js')
+    const input = path.join(srcDir, 'in.js')
+    const ignored = path.join(srcDir, 'ignored.js')
+    await mkdirAsync(srcDir, { recursive: true })
+    await writeFileAsync(input, `import x from './ignored.js'; console.log(x, 'first')`)
+    await writeFileAsync(ignored, `export default 1`)
+
+    let rebuildCount = 0
+    let onRebuild = () => { }
+    const result = await esbuild.build({
+      entryPoints: [input],
+      outfile,
+      bundle: true,
+      format: 'esm',
+      logLevel: 'silent',
+      watch: {
+        interval: 10,
+        ignore: /ignored\.js$/,
+        onRebuild: (...args) => (rebuildCount++, onRebuild(args)),
+      },
+    })
+
+    try {
+      // Edits to ignored files don't trigger a rebuild
+      await writeFileAtomic(ignored, `export default 2`)
+      await new Promise(r => setTimeout(r, 500))
+      assert.strictEqual(rebuildCount, 0)
+
+      // Edits to other files still do
+      await new Promise((resolve, reject) => {
+        const timeout = setTimeout(() => reject(new Error('Timeout after 30 seconds')), 30 * 1000)
+        onRebuild = () => {
+          if (fs.readFileSync(outfile, 'utf8').includes('second')) clearTimeout(timeout), resolve()
+        }
+        writeFileAtomic(input, `import x from './ignored.js'; console.log(x, 'second')`)
+      })
+    } finally {
+      result.stop()
+    }
+  },
 }
 
 let serveTests = {
@@ -3870,6 +4410,31 @@ let transformTests = {
     assert.strictEqual(code, `if (x)\n  ;\n`)
   },
 
+  async dropCalls({ esbuild }) {
+    const { code } = await esbuild.transform(`
+      invariant(1, 'foo')
+      invariant(check(), 'foo')
+      x = invariant(check())
+      log.debug('foo')
+      log.info('foo')
+      function f(invariant) { invariant('foo') }
+    `, { dropCalls: ['invariant', 'log.debug'] })
+    assert.strictEqual(code, `check();\nx = (check(), void 0);\nlog.info("foo");\nfunction f(invariant2) {\n  invariant2("foo");\n}\n`)
+  },
+
+  async errorRecovery({ esbuild }) {
+    try {
+      await esbuild.transform(`let a = ;\nfoo(1 2)\nb c`, { errorRecovery: true, logLevel: 'silent' })
+      throw new Error('Expected an error to be thrown')
+    } catch (e) {
+      assert.deepStrictEqual(e.errors.map(msg => msg.text), [
+        'Unexpected ";"',
+        'Expected ")" but found "2"',
+        'Expected ";" but found "c"',
+      ])
+    }
+  },
+
   async define({ esbuild }) {
     const define = { 'process.env.NODE_ENV': '"production"' }
 
@@ -349,6 +349,42 @@ let pluginTests = {
     assert.strictEqual(result.default, 'this is custom')
   },
 
+  async loaderSourceMap({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.custom')
+    const intermediate = path.join(testDir, 'intermediate.js')
+    const output = path.join(testDir, 'out.js')
+    await writeFileAsync(input, ``)
+    await writeFileAsync(intermediate, `console.log(x)\n//# sourceMappingURL=intermediate.js.map\n`)
+    await writeFileAsync(intermediate + '.map', JSON.stringify({
+      version: 3,
+      sources: ['original.ts'],
+      sourcesContent: ['// original\nconsole.log(x as any)\n'],
+      mappings: 'AACA',
+    }))
+    await esbuild.build({
+      entryPoints: [input],
+      bundle: true,
+      outfile: output,
+      sourcemap: true,
+      plugins: [{
+        name: 'name',
+        setup(build) {
+          build.onLoad({ filter: /\.custom$/ }, () => {
+            return {
+              contents: '// generated\nconsole.log(1)\n',
+              loader: 'js',
+              sourceMap: { version: 3, sources: ['intermediate.js'], mappings: ';AAAA' },
+            }
+          })
+        },
+      }],
+    })
+    const map = JSON.parse(await readFileAsync(output + '.map', 'utf8'))
+    assert.deepStrictEqual(map.sources, ['original.ts'])
+    assert.deepStrictEqual(map.sourcesContent, ['// original\nconsole.log(x as any)\n'])
+    assert.strictEqual(map.mappings, ';;AACA,UAAA,IAAA,CAAA;')
+  },
+
   async basicResolver({ esbuild, testDir }) {
     const input = path.join(testDir, 'in.js')
     const custom = path.join(testDir, 'example.txt')